@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS `ai_proxy_batch_file`
+(
+    `id`              VARCHAR(64)  NOT NULL COMMENT 'primary key',
+    `created_at`      DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
+    `updated_at`      DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '更新时间',
+    `deleted_at`      DATETIME(3)  NULL COMMENT '删除时间',
+
+    `client_id`       CHAR(36)     NOT NULL COMMENT '客户端 ID',
+    `client_token_id` CHAR(36)     NOT NULL DEFAULT '' COMMENT '客户端 Token ID',
+    `filename`        VARCHAR(255) NOT NULL COMMENT '文件名',
+    `purpose`         VARCHAR(32)  NOT NULL COMMENT '用途: batch / batch_output',
+    `bytes`           BIGINT(20)   NOT NULL DEFAULT 0 COMMENT '文件大小',
+    `storage_key`     VARCHAR(255) NOT NULL COMMENT '存储路径',
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_client_id_created_at` (`client_id`, `created_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT 'AI-Proxy 本地批处理文件表';
+
+CREATE TABLE IF NOT EXISTS `ai_proxy_batch`
+(
+    `id`                VARCHAR(64)  NOT NULL COMMENT 'primary key',
+    `created_at`        DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
+    `updated_at`        DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '更新时间',
+
+    `client_id`         CHAR(36)     NOT NULL COMMENT '客户端 ID',
+    `client_token_id`   CHAR(36)     NOT NULL DEFAULT '' COMMENT '客户端 Token ID',
+    `endpoint`          VARCHAR(128) NOT NULL COMMENT '请求的 API, 如 /v1/chat/completions',
+    `completion_window` VARCHAR(16)  NOT NULL COMMENT '完成时间窗口',
+    `input_file_id`     VARCHAR(64)  NOT NULL COMMENT '输入文件 ID',
+    `output_file_id`    VARCHAR(64)  NOT NULL DEFAULT '' COMMENT '输出文件 ID',
+    `error_file_id`     VARCHAR(64)  NOT NULL DEFAULT '' COMMENT '错误文件 ID',
+    `status`            VARCHAR(32)  NOT NULL COMMENT '状态',
+    `request_total`     INT(11)      NOT NULL DEFAULT 0 COMMENT '请求总数',
+    `request_completed` INT(11)      NOT NULL DEFAULT 0 COMMENT '成功请求数',
+    `request_failed`    INT(11)      NOT NULL DEFAULT 0 COMMENT '失败请求数',
+    `errors`            TEXT         NULL COMMENT '批处理级别错误',
+    `metadata`          TEXT         NULL COMMENT '用户元数据',
+    `worker`            VARCHAR(191) NOT NULL DEFAULT '' COMMENT '执行实例',
+    `heartbeat_at`      DATETIME(3)  NULL COMMENT '执行心跳时间',
+
+    `expires_at`        DATETIME(3)  NOT NULL COMMENT '过期时间',
+    `in_progress_at`    DATETIME(3)  NULL COMMENT '开始执行时间',
+    `finalizing_at`     DATETIME(3)  NULL COMMENT '开始汇总时间',
+    `completed_at`      DATETIME(3)  NULL COMMENT '完成时间',
+    `failed_at`         DATETIME(3)  NULL COMMENT '失败时间',
+    `expired_at`        DATETIME(3)  NULL COMMENT '实际过期时间',
+    `cancelling_at`     DATETIME(3)  NULL COMMENT '请求取消时间',
+    `cancelled_at`      DATETIME(3)  NULL COMMENT '取消完成时间',
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_client_id_created_at` (`client_id`, `created_at`),
+    INDEX `idx_status_heartbeat_at` (`status`, `heartbeat_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT 'AI-Proxy 本地批处理任务表';
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS `ai_proxy_mcp_tool_policy`
+(
+    `id`                 CHAR(36)     NOT NULL COMMENT 'Primary Key',
+    `created_at`         DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`         DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `deleted_at`         DATETIME     NOT NULL DEFAULT '1970-01-01 00:00:00' COMMENT '删除时间, 1970-01-01 00:00:00 表示未删除',
+
+    `client_id`          CHAR(36)     NOT NULL COMMENT '客户端 id',
+    `mcp_name`           VARCHAR(255) NOT NULL COMMENT 'MCP 名称',
+    `allow_tools`        JSON         NULL COMMENT '允许调用的 tool, 支持通配符, 为空表示全部允许',
+    `deny_tools`         JSON         NULL COMMENT '禁止调用的 tool, 支持通配符, 优先于 allow_tools',
+    `validate_arguments` TINYINT(1)   NOT NULL DEFAULT 0 COMMENT '是否按 tool 的 input schema 校验参数',
+
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `unique_client_id_mcp_name` (`client_id`, `mcp_name`, `deleted_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT 'ai-proxy MCP tool 调用权限表';
+
+CREATE TABLE IF NOT EXISTS `ai_proxy_mcp_tool_call`
+(
+    `id`              CHAR(36)     NOT NULL COMMENT 'Primary Key',
+    `created_at`      DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT '调用时间',
+    `updated_at`      DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '更新时间',
+    `deleted_at`      DATETIME     NOT NULL DEFAULT '1970-01-01 00:00:00' COMMENT '删除时间, 1970-01-01 00:00:00 表示未删除',
+
+    `client_id`       CHAR(36)     NOT NULL DEFAULT '' COMMENT '客户端 id',
+    `client_token_id` CHAR(36)     NOT NULL DEFAULT '' COMMENT '客户端 Token id',
+    `mcp_name`        VARCHAR(255) NOT NULL COMMENT 'MCP 名称',
+    `mcp_version`     VARCHAR(128) NOT NULL COMMENT 'MCP 版本',
+    `mcp_session_id`  VARCHAR(191) NOT NULL DEFAULT '' COMMENT 'MCP 会话 id',
+    `rpc_id`          VARCHAR(191) NOT NULL DEFAULT '' COMMENT 'JSON-RPC 请求 id',
+    `tool_name`       VARCHAR(191) NOT NULL COMMENT 'tool 名称',
+    `arguments`       MEDIUMTEXT   NULL COMMENT '调用参数, 超长时截断',
+    `status`          VARCHAR(32)  NOT NULL COMMENT '状态: pending / success / error / denied / invalid',
+    `error`           TEXT         NULL COMMENT '错误信息',
+    `latency_ms`      BIGINT(20)   NOT NULL DEFAULT 0 COMMENT '耗时, 毫秒',
+    `result_bytes`    BIGINT(20)   NOT NULL DEFAULT 0 COMMENT '返回结果大小',
+    `finished_at`     DATETIME(3)  NULL COMMENT '完成时间',
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_client_id_created_at` (`client_id`, `created_at`),
+    INDEX `idx_mcp_name_tool_name_created_at` (`mcp_name`, `tool_name`, `created_at`),
+    INDEX `idx_mcp_session_id_rpc_id` (`mcp_session_id`, `rpc_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT 'ai-proxy MCP tool 调用审计表';
@@ -0,0 +1,2 @@
+ALTER TABLE `ai_proxy_policy_group`
+    ADD COLUMN `fallback` JSON NULL COMMENT '失败重试与降级策略';
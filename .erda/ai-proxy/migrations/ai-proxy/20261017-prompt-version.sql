@@ -0,0 +1,21 @@
+ALTER TABLE `ai_proxy_prompt`
+    ADD COLUMN `published_version` INT(11) NOT NULL DEFAULT 0 COMMENT '已发布版本, 0 表示未发布',
+    ADD COLUMN `experiment`        JSON    NULL COMMENT '版本间按权重分流的实验配置';
+
+CREATE TABLE IF NOT EXISTS `ai_proxy_prompt_version`
+(
+    `id`         CHAR(36)      NOT NULL COMMENT 'primary key',
+    `created_at` DATETIME      NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME      NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `deleted_at` DATETIME      NOT NULL DEFAULT '1970-01-01 00:00:00' COMMENT '删除时间, 1970-01-01 00:00:00 表示未删除',
+
+    `prompt_id`  CHAR(36)      NOT NULL COMMENT 'prompt ID',
+    `version`    INT(11)       NOT NULL COMMENT '版本号, 从 1 开始递增',
+    `messages`   LONGTEXT      NOT NULL COMMENT '该版本的 messages, 创建后不可修改',
+    `variables`  JSON          NULL COMMENT '变量定义',
+    `changelog`  VARCHAR(1024) NOT NULL DEFAULT '' COMMENT '变更说明',
+
+    PRIMARY KEY (`id`),
+    UNIQUE `unique_prompt_id_version` (`prompt_id`, `version`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT 'AI-Proxy prompt 版本表';
@@ -0,0 +1,5 @@
+ALTER TABLE `ai_proxy_session`
+    ADD COLUMN `context_strategy` VARCHAR(32)  NOT NULL DEFAULT '' COMMENT '服务端会话记忆的上下文窗口策略: truncate_oldest / keep_last_n / summarize, 为空表示不启用',
+    ADD COLUMN `summary_model`    VARCHAR(191) NOT NULL DEFAULT '' COMMENT '摘要使用的模型',
+    ADD COLUMN `summary`          MEDIUMTEXT   NULL COMMENT '较早对话的摘要',
+    ADD COLUMN `summarized_at`    DATETIME     NULL COMMENT '已摘要的最后一条对话的请求时间';
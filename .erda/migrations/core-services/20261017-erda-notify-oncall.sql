@@ -0,0 +1,70 @@
+CREATE TABLE `erda_notify_oncall_schedule`
+(
+    `id`          varchar(64)  NOT NULL COMMENT 'id',
+    `org_id`      bigint(20)   NOT NULL DEFAULT '0' COMMENT '组织id',
+    `scope_type`  varchar(20)  NOT NULL DEFAULT '' COMMENT '域类型',
+    `scope_id`    varchar(64)  NOT NULL DEFAULT '' COMMENT '域id',
+    `name`        varchar(150) NOT NULL DEFAULT '' COMMENT '值班表名称',
+    `timezone`    varchar(64)  NOT NULL DEFAULT '' COMMENT '时区，为空时使用服务所在时区',
+    `users`       text         NOT NULL COMMENT '轮值用户id，按轮值顺序的 json 数组',
+    `start_time`  datetime     NOT NULL COMMENT '第一次交接班时间，之后按该时区的同一时刻交接',
+    `shift_days`  int(11)      NOT NULL DEFAULT '7' COMMENT '每班天数',
+    `creator`     varchar(100) NOT NULL DEFAULT '' COMMENT '创建人id',
+    `created_at`  datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`  datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_scope` (`scope_type`, `scope_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT '通知值班表';
+
+CREATE TABLE `erda_notify_oncall_override`
+(
+    `id`          varchar(64)  NOT NULL COMMENT 'id',
+    `schedule_id` varchar(64)  NOT NULL COMMENT '值班表id',
+    `user_id`     varchar(100) NOT NULL COMMENT '替班用户id',
+    `start_time`  datetime     NOT NULL COMMENT '替班开始时间',
+    `end_time`    datetime     NOT NULL COMMENT '替班结束时间',
+    `creator`     varchar(100) NOT NULL DEFAULT '' COMMENT '创建人id',
+    `created_at`  datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`  datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_schedule_end_time` (`schedule_id`, `end_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT '通知值班替班记录';
+
+CREATE TABLE `erda_notify_escalation_policy`
+(
+    `id`              varchar(64)  NOT NULL COMMENT 'id',
+    `org_id`          bigint(20)   NOT NULL DEFAULT '0' COMMENT '组织id',
+    `scope_type`      varchar(20)  NOT NULL DEFAULT '' COMMENT '域类型',
+    `scope_id`        varchar(64)  NOT NULL DEFAULT '' COMMENT '域id',
+    `name`            varchar(150) NOT NULL DEFAULT '' COMMENT '升级策略名称',
+    `notify_group_id` bigint(20)   NOT NULL COMMENT '第一级通知组id，发往该通知组的通知会触发升级',
+    `steps`           text         NOT NULL COMMENT '后续升级步骤，json 格式',
+    `enabled`         tinyint(1)   NOT NULL DEFAULT '1' COMMENT '是否启用',
+    `creator`         varchar(100) NOT NULL DEFAULT '' COMMENT '创建人id',
+    `created_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_scope` (`scope_type`, `scope_id`),
+    KEY `idx_notify_group_id` (`notify_group_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT '通知升级策略';
+
+CREATE TABLE `erda_notify_escalation`
+(
+    `id`               varchar(64)  NOT NULL COMMENT 'id',
+    `org_id`           bigint(20)   NOT NULL DEFAULT '0' COMMENT '组织id',
+    `policy_id`        varchar(64)  NOT NULL COMMENT '升级策略id',
+    `dedup_key`        varchar(191) NOT NULL DEFAULT '' COMMENT '去重键，同一告警只有一个进行中的升级',
+    `title`            varchar(512) NOT NULL DEFAULT '' COMMENT '通知标题',
+    `status`           varchar(20)  NOT NULL DEFAULT '' COMMENT '状态: open, acknowledged, resolved, stopped',
+    `step`             int(11)      NOT NULL DEFAULT '0' COMMENT '已通知的后续升级步骤数',
+    `next_escalate_at` datetime              DEFAULT NULL COMMENT '下一次升级时间，为空表示没有后续步骤',
+    `sender`           varchar(100) NOT NULL DEFAULT '' COMMENT '原通知的发送者',
+    `content`          mediumtext   NOT NULL COMMENT '原通知内容，json 格式',
+    `acknowledged_by`  varchar(100) NOT NULL DEFAULT '' COMMENT '确认人id',
+    `acknowledged_at`  datetime              DEFAULT NULL COMMENT '确认时间',
+    `created_at`       datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`       datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_policy_dedup_key` (`policy_id`, `dedup_key`, `status`),
+    KEY `idx_status_next_escalate_at` (`status`, `next_escalate_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT '通知升级记录';
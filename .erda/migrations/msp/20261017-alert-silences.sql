@@ -0,0 +1,56 @@
+CREATE TABLE `sp_alert_silence` (
+  `id` varchar(64) NOT NULL COMMENT '主键',
+  `org_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '组织ID',
+  `scope` varchar(20) NOT NULL DEFAULT '' COMMENT '域',
+  `scope_id` varchar(64) NOT NULL DEFAULT '' COMMENT '域ID',
+  `matchers` text NOT NULL COMMENT '标签匹配器，json 格式',
+  `start_time` datetime NOT NULL COMMENT '静默开始时间',
+  `end_time` datetime NOT NULL COMMENT '静默结束时间',
+  `creator` varchar(191) NOT NULL DEFAULT '' COMMENT '创建人',
+  `comment` varchar(1024) NOT NULL DEFAULT '' COMMENT '备注',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_scope_end_time` (`scope`, `scope_id`, `end_time`),
+  KEY `idx_end_time` (`end_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='告警静默表';
+
+CREATE TABLE `sp_alert_maintenance_window` (
+  `id` varchar(64) NOT NULL COMMENT '主键',
+  `org_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '组织ID',
+  `scope` varchar(20) NOT NULL DEFAULT '' COMMENT '域',
+  `scope_id` varchar(64) NOT NULL DEFAULT '' COMMENT '域ID',
+  `name` varchar(191) NOT NULL DEFAULT '' COMMENT '维护窗口名称',
+  `matchers` text NOT NULL COMMENT '标签匹配器，json 格式',
+  `timezone` varchar(64) NOT NULL DEFAULT '' COMMENT '时区，为空时使用服务所在时区',
+  `weekdays` varchar(32) NOT NULL DEFAULT '' COMMENT '生效的星期，逗号分隔，0 为周日，为空表示每天',
+  `start_time` varchar(8) NOT NULL DEFAULT '' COMMENT '每次开始的时间，HH:MM',
+  `duration` int(11) NOT NULL DEFAULT '0' COMMENT '每次持续的分钟数',
+  `effective_from` datetime DEFAULT NULL COMMENT '生效开始时间',
+  `effective_until` datetime DEFAULT NULL COMMENT '生效结束时间',
+  `enabled` tinyint(1) NOT NULL DEFAULT '1' COMMENT '是否启用',
+  `creator` varchar(191) NOT NULL DEFAULT '' COMMENT '创建人',
+  `comment` varchar(1024) NOT NULL DEFAULT '' COMMENT '备注',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_scope` (`scope`, `scope_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='告警周期性维护窗口表';
+
+CREATE TABLE `sp_alert_inhibit_rule` (
+  `id` varchar(64) NOT NULL COMMENT '主键',
+  `org_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '组织ID',
+  `scope` varchar(20) NOT NULL DEFAULT '' COMMENT '域',
+  `scope_id` varchar(64) NOT NULL DEFAULT '' COMMENT '域ID',
+  `name` varchar(191) NOT NULL DEFAULT '' COMMENT '抑制规则名称',
+  `source_matchers` text NOT NULL COMMENT '源告警的标签匹配器，json 格式',
+  `target_matchers` text NOT NULL COMMENT '被抑制告警的标签匹配器，json 格式',
+  `equal_labels` varchar(1024) NOT NULL DEFAULT '' COMMENT '源告警与被抑制告警取值必须相同的标签，逗号分隔',
+  `enabled` tinyint(1) NOT NULL DEFAULT '1' COMMENT '是否启用',
+  `creator` varchar(191) NOT NULL DEFAULT '' COMMENT '创建人',
+  `comment` varchar(1024) NOT NULL DEFAULT '' COMMENT '备注',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`id`),
+  KEY `idx_scope` (`scope`, `scope_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='告警抑制规则表';
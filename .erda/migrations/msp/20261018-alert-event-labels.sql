@@ -0,0 +1 @@
+ALTER TABLE `sp_alert_event` ADD COLUMN `labels` TEXT NOT NULL COMMENT '最近一次告警的标签，json 格式';
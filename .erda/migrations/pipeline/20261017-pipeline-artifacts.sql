@@ -0,0 +1,20 @@
+CREATE TABLE `pipeline_artifacts` (
+  `id` varchar(36) NOT NULL DEFAULT '' COMMENT '主键',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  `soft_deleted_at` bigint(20) NOT NULL DEFAULT '0' COMMENT '删除时间，0 表示未删除',
+  `org_id` bigint(20) NOT NULL DEFAULT '0' COMMENT 'org id',
+  `org_name` varchar(50) NOT NULL DEFAULT '' COMMENT 'org name',
+  `app_id` bigint(20) NOT NULL DEFAULT '0' COMMENT 'app id',
+  `branch` varchar(255) NOT NULL DEFAULT '' COMMENT '分支',
+  `pipeline_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '流水线 id',
+  `task_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '任务 id',
+  `task_name` varchar(255) NOT NULL DEFAULT '' COMMENT '任务名',
+  `name` varchar(128) NOT NULL DEFAULT '' COMMENT 'artifact 名称，流水线内唯一',
+  `object_name` varchar(512) NOT NULL DEFAULT '' COMMENT '对象存储中的 object name',
+  `byte_size` bigint(20) NOT NULL DEFAULT '0' COMMENT '大小，单位 byte',
+  `expired_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '过期时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_pipeline_name` (`pipeline_id`, `name`, `soft_deleted_at`),
+  KEY `idx_expired_at` (`expired_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='流水线 artifact 表';
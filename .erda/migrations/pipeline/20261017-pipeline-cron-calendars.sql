@@ -0,0 +1,27 @@
+CREATE TABLE `pipeline_cron_calendars` (
+  `id` varchar(36) NOT NULL DEFAULT '' COMMENT '主键',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  `soft_deleted_at` bigint(20) NOT NULL DEFAULT '0' COMMENT '删除时间，0 表示未删除',
+  `org_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '企业 id',
+  `name` varchar(191) NOT NULL DEFAULT '' COMMENT '日历名称，定时流水线通过名称引用',
+  `time_zone` varchar(64) NOT NULL DEFAULT '' COMMENT '排除时段所在的 IANA 时区，为空表示服务端本地时区',
+  `description` varchar(1024) NOT NULL DEFAULT '' COMMENT '描述',
+  `exclusions` text COMMENT '排除时段列表，json 格式',
+  `creator` varchar(191) NOT NULL DEFAULT '' COMMENT '创建人',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_org_name` (`org_id`, `name`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='定时流水线排除日历表';
+
+CREATE TABLE `pipeline_cron_skipped_triggers` (
+  `id` varchar(36) NOT NULL DEFAULT '' COMMENT '主键',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  `cron_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '定时 id',
+  `trigger_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '被跳过的触发时间',
+  `calendar` varchar(191) NOT NULL DEFAULT '' COMMENT '命中的日历名称',
+  `exclusion` varchar(191) NOT NULL DEFAULT '' COMMENT '命中的排除时段名称',
+  `source` varchar(32) NOT NULL DEFAULT '' COMMENT '跳过来源: daemon, compensator',
+  PRIMARY KEY (`id`),
+  KEY `idx_cron_trigger_time` (`cron_id`, `trigger_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='定时流水线被跳过的触发记录表';
@@ -0,0 +1,29 @@
+CREATE TABLE `pipeline_webhooks` (
+  `id` varchar(36) NOT NULL DEFAULT '' COMMENT '主键',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  `soft_deleted_at` bigint(20) NOT NULL DEFAULT '0' COMMENT '删除时间，0 表示未删除',
+  `definition_id` varchar(36) NOT NULL DEFAULT '' COMMENT '流水线定义 id',
+  `access_key_id` varchar(64) NOT NULL DEFAULT '' COMMENT 'erda 签名使用的 access key id',
+  `secret_key` varchar(64) NOT NULL DEFAULT '' COMMENT '签名 secret',
+  `creator` varchar(191) NOT NULL DEFAULT '' COMMENT '创建人，webhook 触发的流水线以该用户运行',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_definition_id` (`definition_id`, `soft_deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='流水线 webhook 表';
+
+CREATE TABLE `pipeline_webhook_deliveries` (
+  `id` varchar(36) NOT NULL DEFAULT '' COMMENT '主键',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  `definition_id` varchar(36) NOT NULL DEFAULT '' COMMENT '流水线定义 id',
+  `webhook_id` varchar(36) NOT NULL DEFAULT '' COMMENT 'webhook id',
+  `status` varchar(32) NOT NULL DEFAULT '' COMMENT '投递结果: Success, Skipped, Rejected, Failed',
+  `message` text COMMENT '结果说明',
+  `event` varchar(191) NOT NULL DEFAULT '' COMMENT '事件类型，取自请求头',
+  `signature` varchar(32) NOT NULL DEFAULT '' COMMENT '签名方式: erda, github, gitlab',
+  `remote_addr` varchar(191) NOT NULL DEFAULT '' COMMENT '请求来源地址',
+  `params` text COMMENT '从 payload 映射得到的流水线参数，json 格式',
+  `pipeline_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '触发的流水线 id',
+  PRIMARY KEY (`id`),
+  KEY `idx_definition_created` (`definition_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='流水线 webhook 投递记录表';
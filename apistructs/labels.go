@@ -43,6 +43,8 @@ const (
 	LabelBindPipelineQueueCustomPriority   = "__bind_queue_custom_priority"
 	LabelBindPipelineQueueEnqueueCondition = "__bind_queue_enqueue_condition"
 
+	LabelPipelineConcurrencyGroup = "__concurrency_group"
+
 	LabelUserID = "userID"
 
 	LabelRunUserID    = "runUserID"
@@ -34,6 +34,8 @@ const (
 	DingdingNotifyTarget           NotifyTargetType = "dingding"
 	DingdingWorkNoticeNotifyTarget NotifyTargetType = "dingding_worknotice"
 	WebhookNotifyTarget            NotifyTargetType = "webhook"
+	// OnCallNotifyTarget 值班表，receiver 为值班表 id，通知时发送给当前值班的用户
+	OnCallNotifyTarget NotifyTargetType = "oncall"
 )
 
 var ValidateNotifyChannel = map[string]bool{
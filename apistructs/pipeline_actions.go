@@ -36,6 +36,9 @@ type ActionCallback struct {
 	// machine stat
 	MachineStat *taskinspect.PipelineTaskMachineStat `json:"machineStat,omitempty"`
 
+	// ExitCode is reported by the last callback when the action exits
+	ExitCode *int `json:"exitCode,omitempty"`
+
 	// behind
 	PipelineID     uint64 `json:"pipelineID"`
 	PipelineTaskID uint64 `json:"pipelineTaskID"`
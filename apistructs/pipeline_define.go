@@ -136,17 +136,18 @@ func (s PipelineYmlSource) String() string {
 	return string(s)
 }
 
-// PipelineTriggerMode 流水线触发方式，手动 or 定时
+// PipelineTriggerMode 流水线触发方式，手动 or 定时 or webhook
 type PipelineTriggerMode string
 
 var (
-	PipelineTriggerModeManual PipelineTriggerMode = "manual" // 手动触发
-	PipelineTriggerModeCron   PipelineTriggerMode = "cron"   // 定时触发
+	PipelineTriggerModeManual  PipelineTriggerMode = "manual"  // 手动触发
+	PipelineTriggerModeCron    PipelineTriggerMode = "cron"    // 定时触发
+	PipelineTriggerModeWebhook PipelineTriggerMode = "webhook" // webhook 触发
 )
 
 // Valid 返回 PipelineTriggerMode 是否有效
 func (m PipelineTriggerMode) Valid() bool {
-	if m == PipelineTriggerModeManual || m == PipelineTriggerModeCron {
+	if m == PipelineTriggerModeManual || m == PipelineTriggerModeCron || m == PipelineTriggerModeWebhook {
 		return true
 	}
 	return false
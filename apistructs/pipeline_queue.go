@@ -52,6 +52,9 @@ type ScheduleStrategyInsidePipelineQueue string
 
 var (
 	ScheduleStrategyInsidePipelineQueueOfFIFO ScheduleStrategyInsidePipelineQueue = "FIFO"
+	// ScheduleStrategyInsidePipelineQueueOfFairShare divides queue capacity between tenants by weights,
+	// pipelines of the same tenant are still FIFO.
+	ScheduleStrategyInsidePipelineQueueOfFairShare ScheduleStrategyInsidePipelineQueue = "FAIR_SHARE"
 )
 
 func (strategy ScheduleStrategyInsidePipelineQueue) String() string {
@@ -60,7 +63,29 @@ func (strategy ScheduleStrategyInsidePipelineQueue) String() string {
 
 func (strategy ScheduleStrategyInsidePipelineQueue) IsValid() bool {
 	switch strategy {
-	case ScheduleStrategyInsidePipelineQueueOfFIFO:
+	case ScheduleStrategyInsidePipelineQueueOfFIFO, ScheduleStrategyInsidePipelineQueueOfFairShare:
+		return true
+	default:
+		return false
+	}
+}
+
+func (strategy ScheduleStrategyInsidePipelineQueue) IsFairShare() bool {
+	return strategy == ScheduleStrategyInsidePipelineQueueOfFairShare
+}
+
+// PipelineQueueTenantBy decides which tenant a pipeline belongs to in fair-share queue.
+type PipelineQueueTenantBy string
+
+var (
+	PipelineQueueTenantByProject PipelineQueueTenantBy = "PROJECT"
+	PipelineQueueTenantByOwner   PipelineQueueTenantBy = "OWNER"
+)
+
+func (t PipelineQueueTenantBy) String() string { return string(t) }
+func (t PipelineQueueTenantBy) IsValid() bool {
+	switch t {
+	case PipelineQueueTenantByProject, PipelineQueueTenantByOwner:
 		return true
 	default:
 		return false
@@ -72,6 +97,10 @@ var (
 	PipelineQueueDefaultScheduleStrategy       = ScheduleStrategyInsidePipelineQueueOfFIFO
 	PipelineQueueDefaultMode                   = PipelineQueueModeLoose
 	PipelineQueueDefaultConcurrency      int64 = 1
+
+	PipelineQueueDefaultTenantBy                       = PipelineQueueTenantByProject
+	PipelineQueueDefaultTenantWeight           float64 = 1
+	PipelineQueueDefaultFairShareStarvationSec int64   = 1800
 )
 
 // PipelineQueueValidateResult represents queue validate result.
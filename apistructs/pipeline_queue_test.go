@@ -41,6 +41,11 @@ func TestScheduleStrategyInsidePipelineQueue_IsValid(t *testing.T) {
 			strategy: ScheduleStrategyInsidePipelineQueueOfFIFO,
 			want:     true,
 		},
+		{
+			name:     "valid fair share strategy",
+			strategy: ScheduleStrategyInsidePipelineQueueOfFairShare,
+			want:     true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
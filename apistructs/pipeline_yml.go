@@ -55,6 +55,8 @@ type PipelineYml struct {
 
 	Outputs []*PipelineOutput `json:"outputs,omitempty"` // 流水线输出
 
+	Concurrency *PipelineYmlConcurrency `json:"concurrency,omitempty" yaml:"concurrency,omitempty"` // 并发组
+
 	// --- 以下字段与构造 pipeline yml 无关 ---
 
 	// 1.0 升级相关
@@ -72,6 +74,12 @@ type PipelineYml struct {
 	Lifecycle []*NetworkHookInfo `json:"lifecycle"`
 }
 
+// PipelineYmlConcurrency makes pipelines in the same group run one at a time.
+type PipelineYmlConcurrency struct {
+	Group            string `json:"group" yaml:"group"`
+	CancelInProgress bool   `json:"cancel_in_progress,omitempty" yaml:"cancel_in_progress,omitempty"`
+}
+
 type NetworkHookInfo struct {
 	Hook   string                 `json:"hook"`   // hook type
 	Client string                 `json:"client"` // use network client
@@ -113,6 +121,24 @@ type PipelineYmlAction struct {
 	Loop          *PipelineTaskLoop      `json:"loop,omitempty"`                                           // 循环执行
 	SnippetStages *SnippetStages         `json:"snippetStages,omitempty"`                                  // snippetStages snippet 展开
 	Policy        *Policy                `json:"policy,omitempty"`                                         // action execution strategy
+	Matrix        *PipelineYmlMatrix     `json:"matrix,omitempty" yaml:"matrix,omitempty"`                 // 矩阵执行
+
+	// MatrixInstances 为 matrix 展开后的实例，仅用于展示
+	MatrixInstances []PipelineYmlMatrixInstance `json:"matrixInstances,omitempty" yaml:"matrixInstances,omitempty"`
+}
+
+// PipelineYmlMatrix runs an action once per combination of axes.
+type PipelineYmlMatrix struct {
+	Axes        map[string][]interface{} `json:"axes,omitempty" yaml:"axes,omitempty"`
+	Include     []map[string]interface{} `json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude     []map[string]interface{} `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	MaxParallel int                      `json:"maxParallel,omitempty" yaml:"maxParallel,omitempty"`
+}
+
+// PipelineYmlMatrixInstance is an expanded instance of a matrix action.
+type PipelineYmlMatrixInstance struct {
+	Alias  string            `json:"alias"`
+	Values map[string]string `json:"values"`
 }
 
 func (p *PipelineYmlAction) Convert2StructValue() (*structpb.Value, error) {
@@ -42,3 +42,35 @@ func (b *Bundle) GetMonitorReportTasksByID(id int64) (*apistructs.ReportTask, er
 
 	return fetchResp.Data, nil
 }
+
+// MuteAlert returns the type of the silence, maintenance window or inhibit rule which mutes the alert of labels now,
+// empty if the alert is not muted.
+func (b *Bundle) MuteAlert(labels map[string]string) (string, error) {
+	host, err := b.urls.Monitor()
+	if err != nil {
+		return "", err
+	}
+	hc := b.hc
+
+	var muteResp struct {
+		apistructs.Header
+		Data *struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+	}
+	resp, err := hc.Post(host).Path("/api/alert-silences/actions/mute").
+		Header(httputil.InternalHeader, "bundle").
+		JSONBody(map[string]interface{}{"labels": labels}).
+		Do().JSON(&muteResp)
+	if err != nil {
+		return "", apierrors.ErrInvoke.InternalError(err)
+	}
+	if !resp.IsOK() || !muteResp.Success {
+		return "", toAPIError(resp.StatusCode(), muteResp.Error)
+	}
+	if muteResp.Data == nil {
+		return "", nil
+	}
+	return muteResp.Data.Type, nil
+}
@@ -216,3 +216,31 @@ func (b *Bundle) GetNotifyConfig(orgIDstr, userID string) (*apistructs.NotifyCon
 	}
 	return &resp.Data, nil
 }
+
+// TriggerNotifyEscalation 通知组发送通知后，触发该通知组的升级策略
+func (b *Bundle) TriggerNotifyEscalation(groupID int64, orgID int64, sender string, content string) error {
+	host, err := b.urls.ErdaServer()
+	if err != nil {
+		return err
+	}
+	hc := b.hc
+
+	var resp apistructs.Header
+	r, err := hc.Post(host).Path("/api/notify-escalations/actions/trigger").
+		Header(httputil.InternalHeader, "bundle").
+		Header("Org-ID", strconv.FormatInt(orgID, 10)).
+		JSONBody(map[string]interface{}{
+			"notifyGroupId": groupID,
+			"sender":        sender,
+			"content":       content,
+		}).
+		Do().
+		JSON(&resp)
+	if err != nil {
+		return apierrors.ErrInvoke.InternalError(err)
+	}
+	if !r.IsOK() || !resp.Success {
+		return toAPIError(r.StatusCode(), resp.Error)
+	}
+	return nil
+}
@@ -36,6 +36,7 @@ import (
 	_ "github.com/erda-project/erda/internal/tools/monitor/apm/topology"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/alert/details-apis"
+	_ "github.com/erda-project/erda/internal/tools/monitor/core/alert/evaluator"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/alert/jobs/unrecover-alerts"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/dataview"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/dataview/v1-chart-block"
@@ -56,6 +57,7 @@ import (
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/query"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/query-example"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/query/metricq"
+	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/query/promql-apis"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/storage/clickhouse"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/storage/elasticsearch"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/profile/query"
@@ -33,7 +33,9 @@ import (
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/action_runner_scheduler"
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/actionagent"
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/actionmgr"
+	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/analytics"
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/app"
+	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/artifact"
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/build"
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/clusterinfo"
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/cms"
@@ -54,6 +56,8 @@ import (
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/resourcegc"
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/source"
 	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/task"
+	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/tracing"
+	_ "github.com/erda-project/erda/internal/tools/pipeline/providers/webhook"
 	"github.com/erda-project/erda/pkg/common"
 )
 
@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda/pkg/storage"
+)
+
+type OSSConfig struct {
+	Endpoint        string `file:"endpoint" env:"AI_PROXY_BATCH_OSS_ENDPOINT"`
+	AccessKeyID     string `file:"access_key_id" env:"AI_PROXY_BATCH_OSS_ACCESS_KEY_ID"`
+	AccessKeySecret string `file:"access_key_secret" env:"AI_PROXY_BATCH_OSS_ACCESS_KEY_SECRET"`
+	Bucket          string `file:"bucket" env:"AI_PROXY_BATCH_OSS_BUCKET"`
+}
+
+type StorageConfig struct {
+	// Type is `fs` or `oss`. With more than one replica, use oss or a shared volume for fs.
+	Type string `file:"type" env:"AI_PROXY_BATCH_STORAGE_TYPE" default:"fs"`
+	// Dir is the directory of fs, or the object key prefix of oss.
+	Dir string    `file:"dir" env:"AI_PROXY_BATCH_STORAGE_DIR" default:"/tmp/ai-proxy/batch"`
+	OSS OSSConfig `file:"oss"`
+}
+
+type Config struct {
+	Enable bool `file:"enable" env:"AI_PROXY_BATCH_ENABLE" default:"false"`
+	// BaseURL is the address of ai-proxy itself, each request line is sent to it,
+	// so it goes through the normal auth, routing, policy groups, rate limits and audit.
+	BaseURL string `file:"base_url" env:"AI_PROXY_BATCH_BASE_URL"`
+	// Concurrency is the max number of in-flight requests of a batch.
+	Concurrency    int           `file:"concurrency" env:"AI_PROXY_BATCH_CONCURRENCY" default:"4"`
+	LoopInterval   time.Duration `file:"loop_interval" env:"AI_PROXY_BATCH_LOOP_INTERVAL" default:"5s"`
+	RequestTimeout time.Duration `file:"request_timeout" env:"AI_PROXY_BATCH_REQUEST_TIMEOUT" default:"10m"`
+	// StaleTimeout is how long a batch can go without heartbeat before another replica takes it over.
+	StaleTimeout time.Duration `file:"stale_timeout" env:"AI_PROXY_BATCH_STALE_TIMEOUT" default:"5m"`
+	MaxFileBytes int64         `file:"max_file_bytes" env:"AI_PROXY_BATCH_MAX_FILE_BYTES" default:"104857600"`
+	MaxRequests  int           `file:"max_requests" env:"AI_PROXY_BATCH_MAX_REQUESTS" default:"50000"`
+	Storage      StorageConfig `file:"storage"`
+}
+
+func (cfg *Config) Normalize() error {
+	if !cfg.Enable {
+		return nil
+	}
+	cfg.BaseURL = strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if cfg.BaseURL == "" {
+		return fmt.Errorf("AI_PROXY_BATCH_BASE_URL is required when batch is enabled")
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.LoopInterval <= 0 {
+		cfg.LoopInterval = 5 * time.Second
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = 10 * time.Minute
+	}
+	if cfg.StaleTimeout <= cfg.LoopInterval {
+		cfg.StaleTimeout = 5 * time.Minute
+	}
+	if cfg.MaxFileBytes <= 0 {
+		cfg.MaxFileBytes = 100 << 20
+	}
+	if cfg.MaxRequests <= 0 {
+		cfg.MaxRequests = 50000
+	}
+	switch storage.Type(strings.ToLower(cfg.Storage.Type)) {
+	case "", storage.TypeFileSystem:
+		cfg.Storage.Type = string(storage.TypeFileSystem)
+		if strings.TrimSpace(cfg.Storage.Dir) == "" {
+			cfg.Storage.Dir = "/tmp/ai-proxy/batch"
+		}
+	case storage.TypeOSS:
+		cfg.Storage.Type = string(storage.TypeOSS)
+		if cfg.Storage.OSS.Endpoint == "" || cfg.Storage.OSS.Bucket == "" {
+			return fmt.Errorf("oss endpoint and bucket are required for batch storage")
+		}
+	default:
+		return fmt.Errorf("invalid batch storage type: %s", cfg.Storage.Type)
+	}
+	return nil
+}
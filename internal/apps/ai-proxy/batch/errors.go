@@ -0,0 +1,28 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+// Error is an error to be returned to the user as is, other errors are internal.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func NewError(statusCode int, code, message string) *Error {
+	return &Error{StatusCode: statusCode, Code: code, Message: message}
+}
+
+func (e *Error) Error() string { return e.Message }
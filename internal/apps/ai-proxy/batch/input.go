@@ -0,0 +1,114 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+)
+
+// SupportedEndpoints are the APIs a local batch can call, the same as OpenAI's.
+var SupportedEndpoints = map[string]bool{
+	vars.RequestPathPrefixV1ChatCompletions: true,
+	vars.RequestPathPrefixV1Embeddings:      true,
+	vars.RequestPathPrefixV1Responses:       true,
+}
+
+// CompletionWindow24h is the only completion window supported, like OpenAI.
+const CompletionWindow24h = "24h"
+
+// RequestLine is a line of the input file.
+type RequestLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// ParseInput parses and validates all lines of an input file.
+// All problems are returned as batch errors with line numbers, so the user can fix them at once.
+func ParseInput(content []byte, endpoint string, maxRequests int) ([]RequestLine, []BatchError) {
+	var (
+		lines     []RequestLine
+		errs      []BatchError
+		customIDs = map[string]bool{}
+		lineNo    int
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64<<10), len(content)+1)
+	for scanner.Scan() {
+		lineNo++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var line RequestLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			errs = append(errs, BatchError{Code: "invalid_json_line", Message: fmt.Sprintf("invalid json: %v", err), Line: lineNo})
+			continue
+		}
+		if err := validateLine(line, endpoint); err != nil {
+			errs = append(errs, BatchError{Code: err.code, Message: err.message, Param: err.param, Line: lineNo})
+			continue
+		}
+		if customIDs[line.CustomID] {
+			errs = append(errs, BatchError{Code: "duplicate_custom_id", Message: fmt.Sprintf("duplicate custom_id: %s", line.CustomID), Param: "custom_id", Line: lineNo})
+			continue
+		}
+		customIDs[line.CustomID] = true
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, BatchError{Code: "invalid_file", Message: fmt.Sprintf("failed to read input file: %v", err)})
+	}
+	if len(lines)+len(errs) == 0 {
+		errs = append(errs, BatchError{Code: "empty_file", Message: "input file has no request"})
+	}
+	if maxRequests > 0 && len(lines) > maxRequests {
+		errs = append(errs, BatchError{Code: "too_many_requests", Message: fmt.Sprintf("input file has %d requests, at most %d allowed", len(lines), maxRequests)})
+	}
+	return lines, errs
+}
+
+type lineError struct {
+	code    string
+	message string
+	param   string
+}
+
+func validateLine(line RequestLine, endpoint string) *lineError {
+	if line.CustomID == "" {
+		return &lineError{code: "missing_required_parameter", message: "custom_id is required", param: "custom_id"}
+	}
+	if line.Method != http.MethodPost {
+		return &lineError{code: "invalid_value", message: "method must be POST", param: "method"}
+	}
+	if line.URL != endpoint {
+		return &lineError{code: "mismatched_url", message: fmt.Sprintf("url must be the endpoint of the batch: %s", endpoint), param: "url"}
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(line.Body, &body); err != nil || body == nil {
+		return &lineError{code: "invalid_value", message: "body must be a json object", param: "body"}
+	}
+	if stream, ok := body["stream"]; ok && string(bytes.TrimSpace(stream)) == "true" {
+		return &lineError{code: "invalid_value", message: "streaming is not supported in batch", param: "body.stream"}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInput(t *testing.T) {
+	const endpoint = "/v1/chat/completions"
+	line := func(customID, url, body string) string {
+		return fmt.Sprintf(`{"custom_id":%q,"method":"POST","url":%q,"body":%s}`, customID, url, body)
+	}
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+
+	tests := []struct {
+		name      string
+		content   string
+		max       int
+		wantLines int
+		wantCodes []string
+	}{
+		{
+			name:      "valid",
+			content:   line("a", endpoint, body) + "\n\n" + line("b", endpoint, body) + "\n",
+			wantLines: 2,
+		},
+		{
+			name:      "invalid json",
+			content:   line("a", endpoint, body) + "\n{not json}",
+			wantLines: 1,
+			wantCodes: []string{"invalid_json_line"},
+		},
+		{
+			name:      "mismatched url",
+			content:   line("a", "/v1/embeddings", body),
+			wantCodes: []string{"mismatched_url"},
+		},
+		{
+			name:      "stream not supported",
+			content:   line("a", endpoint, `{"model":"gpt-4o","stream":true}`),
+			wantCodes: []string{"invalid_value"},
+		},
+		{
+			name:      "missing custom_id",
+			content:   line("", endpoint, body),
+			wantCodes: []string{"missing_required_parameter"},
+		},
+		{
+			name:      "duplicate custom_id",
+			content:   line("a", endpoint, body) + "\n" + line("a", endpoint, body),
+			wantLines: 1,
+			wantCodes: []string{"duplicate_custom_id"},
+		},
+		{
+			name:      "empty file",
+			content:   "\n \n",
+			wantCodes: []string{"empty_file"},
+		},
+		{
+			name:      "too many requests",
+			content:   line("a", endpoint, body) + "\n" + line("b", endpoint, body),
+			max:       1,
+			wantLines: 2,
+			wantCodes: []string{"too_many_requests"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, errs := ParseInput([]byte(tt.content), endpoint, tt.max)
+			require.Len(t, lines, tt.wantLines)
+			var codes []string
+			for _, e := range errs {
+				codes = append(codes, e.Code)
+			}
+			require.Equal(t, tt.wantCodes, codes)
+		})
+	}
+}
+
+func TestParseInput_LineNumber(t *testing.T) {
+	content := strings.Join([]string{
+		`{"custom_id":"a","method":"POST","url":"/v1/embeddings","body":{"model":"m","input":"x"}}`,
+		``,
+		`{"custom_id":"b","method":"GET","url":"/v1/embeddings","body":{"model":"m","input":"x"}}`,
+	}, "\n")
+	_, errs := ParseInput([]byte(content), "/v1/embeddings", 0)
+	require.Len(t, errs, 1)
+	require.Equal(t, 3, errs[0].Line)
+	require.Equal(t, "method", errs[0].Param)
+}
@@ -0,0 +1,337 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/batch"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+	"github.com/erda-project/erda/pkg/storage"
+)
+
+const (
+	FileIDPrefix  = "file-local-"
+	BatchIDPrefix = "batch_local_"
+)
+
+// IsLocalFileID reports whether the file is managed by ai-proxy itself rather than a provider.
+func IsLocalFileID(id string) bool { return strings.HasPrefix(id, FileIDPrefix) }
+
+// IsLocalBatchID reports whether the batch is executed by ai-proxy itself rather than a provider.
+func IsLocalBatchID(id string) bool { return strings.HasPrefix(id, BatchIDPrefix) }
+
+// CredentialFunc returns the access key or token used to send the request lines of a batch,
+// which is the one the batch was created with.
+type CredentialFunc func(ctx context.Context, clientID, clientTokenID string) (string, error)
+
+// Service implements the OpenAI Files and Batch APIs for all models: input files are kept in storage,
+// and the request lines are executed asynchronously through ai-proxy itself.
+type Service struct {
+	Config     Config
+	DBClient   *batch.DBClient
+	Credential CredentialFunc
+	Storage    storage.Storager
+	Logger     logs.Logger
+
+	// Worker identifies this replica in claimed batches.
+	Worker     string
+	httpClient *http.Client
+	nowFunc    func() time.Time
+}
+
+func NewService(cfg Config, dbClient *batch.DBClient, credential CredentialFunc, logger logs.Logger) (*Service, error) {
+	var s storage.Storager
+	switch storage.Type(cfg.Storage.Type) {
+	case storage.TypeOSS:
+		s = storage.NewOSS(cfg.Storage.OSS.Endpoint, cfg.Storage.OSS.AccessKeyID, cfg.Storage.OSS.AccessKeySecret, cfg.Storage.OSS.Bucket, nil, nil)
+	default:
+		if err := os.MkdirAll(cfg.Storage.Dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create batch storage dir: %w", err)
+		}
+		s = storage.NewFS()
+	}
+	hostname, _ := os.Hostname()
+	return &Service{
+		Config:     cfg,
+		DBClient:   dbClient,
+		Credential: credential,
+		Storage:    s,
+		Logger:     logger,
+		Worker:     fmt.Sprintf("%s/%s", hostname, uuid.New()),
+		httpClient: &http.Client{Timeout: cfg.RequestTimeout},
+		nowFunc:    time.Now,
+	}, nil
+}
+
+var (
+	serviceMu sync.RWMutex
+	service   *Service
+)
+
+// GetService returns the service set by SetService, nil if local batch is disabled.
+func GetService() *Service {
+	serviceMu.RLock()
+	defer serviceMu.RUnlock()
+	return service
+}
+
+func SetService(s *Service) {
+	serviceMu.Lock()
+	defer serviceMu.Unlock()
+	service = s
+}
+
+// Caller is the client calling the APIs, batches are executed with its credential.
+type Caller struct {
+	ClientID      string
+	ClientTokenID string
+}
+
+// UploadFile stores an input file, only purpose `batch` is supported.
+func (s *Service) UploadFile(ctx context.Context, caller Caller, filename, purpose string, r io.Reader) (*FileObject, error) {
+	if purpose != batch.FilePurposeBatch {
+		return nil, NewError(http.StatusBadRequest, "invalid_value", fmt.Sprintf("purpose %q is not supported, only %q", purpose, batch.FilePurposeBatch))
+	}
+	content, err := io.ReadAll(io.LimitReader(r, s.Config.MaxFileBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(content)) > s.Config.MaxFileBytes {
+		return nil, NewError(http.StatusRequestEntityTooLarge, "file_too_large", fmt.Sprintf("file exceeds %d bytes", s.Config.MaxFileBytes))
+	}
+	file, err := s.saveFile(ctx, caller, filename, purpose, content)
+	if err != nil {
+		return nil, err
+	}
+	obj := ToFileObject(file)
+	return &obj, nil
+}
+
+func (s *Service) saveFile(ctx context.Context, caller Caller, filename, purpose string, content []byte) (*batch.File, error) {
+	id := FileIDPrefix + strings.ReplaceAll(uuid.New(), "-", "")
+	file := &batch.File{
+		ID:            id,
+		ClientID:      caller.ClientID,
+		ClientTokenID: caller.ClientTokenID,
+		Filename:      filename,
+		Purpose:       purpose,
+		Bytes:         int64(len(content)),
+		StorageKey:    s.storageKey(id),
+	}
+	if err := s.Storage.Write(file.StorageKey, bytes.NewReader(content)); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+	if err := s.DBClient.CreateFile(ctx, file); err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	return file, nil
+}
+
+func (s *Service) storageKey(id string) string {
+	if storage.Type(s.Config.Storage.Type) == storage.TypeOSS {
+		return path.Join(s.Config.Storage.Dir, id+".jsonl")
+	}
+	return filepath.Join(s.Config.Storage.Dir, id+".jsonl")
+}
+
+func (s *Service) GetFile(ctx context.Context, caller Caller, id string) (*FileObject, error) {
+	file, err := s.getFile(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	obj := ToFileObject(file)
+	return &obj, nil
+}
+
+func (s *Service) getFile(ctx context.Context, caller Caller, id string) (*batch.File, error) {
+	file, err := s.DBClient.GetFile(ctx, caller.ClientID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	if file == nil {
+		return nil, NewError(http.StatusNotFound, "not_found", fmt.Sprintf("no such file: %s", id))
+	}
+	return file, nil
+}
+
+func (s *Service) ListFiles(ctx context.Context, caller Caller, purpose, after string, limit int) (*ListObject[FileObject], error) {
+	limit = normalizeLimit(limit)
+	files, err := s.DBClient.ListFiles(ctx, batch.ListOptions{ClientID: caller.ClientID, Purpose: purpose, After: after, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	var items []FileObject
+	for _, f := range files {
+		items = append(items, ToFileObject(f))
+	}
+	return newList(items, limit, func(o FileObject) string { return o.ID }), nil
+}
+
+func (s *Service) DeleteFile(ctx context.Context, caller Caller, id string) (*FileDeleted, error) {
+	file, err := s.getFile(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.DBClient.DeleteFile(ctx, caller.ClientID, id); err != nil {
+		return nil, fmt.Errorf("failed to delete file: %w", err)
+	}
+	if err := s.Storage.Delete(file.StorageKey); err != nil {
+		s.Logger.Warnf("failed to delete batch file from storage, id: %s, err: %v", id, err)
+	}
+	return &FileDeleted{ID: id, Object: "file", Deleted: true}, nil
+}
+
+// FileContent returns the content of a file, the caller must close it.
+func (s *Service) FileContent(ctx context.Context, caller Caller, id string) (io.ReadCloser, *FileObject, error) {
+	file, err := s.getFile(ctx, caller, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := s.readStorage(file.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj := ToFileObject(file)
+	return r, &obj, nil
+}
+
+func (s *Service) readStorage(key string) (io.ReadCloser, error) {
+	r, err := s.Storage.Read(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file from storage: %w", err)
+	}
+	if rc, ok := r.(io.ReadCloser); ok {
+		return rc, nil
+	}
+	return io.NopCloser(r), nil
+}
+
+func (s *Service) CreateBatch(ctx context.Context, caller Caller, req CreateBatchRequest) (*BatchObject, error) {
+	if !SupportedEndpoints[req.Endpoint] {
+		return nil, NewError(http.StatusBadRequest, "invalid_value", fmt.Sprintf("endpoint %q is not supported", req.Endpoint))
+	}
+	if req.CompletionWindow != CompletionWindow24h {
+		return nil, NewError(http.StatusBadRequest, "invalid_value", fmt.Sprintf("completion_window must be %q", CompletionWindow24h))
+	}
+	file, err := s.getFile(ctx, caller, req.InputFileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.Purpose != batch.FilePurposeBatch {
+		return nil, NewError(http.StatusBadRequest, "invalid_value", fmt.Sprintf("file %s must have purpose %q", file.ID, batch.FilePurposeBatch))
+	}
+	var metadata string
+	if len(req.Metadata) > 0 {
+		b, err := json.Marshal(req.Metadata)
+		if err != nil {
+			return nil, NewError(http.StatusBadRequest, "invalid_value", "invalid metadata")
+		}
+		metadata = string(b)
+	}
+	now := s.nowFunc()
+	rec := &batch.Batch{
+		ID:               BatchIDPrefix + strings.ReplaceAll(uuid.New(), "-", ""),
+		CreatedAt:        now,
+		ClientID:         caller.ClientID,
+		ClientTokenID:    caller.ClientTokenID,
+		Endpoint:         req.Endpoint,
+		CompletionWindow: req.CompletionWindow,
+		InputFileID:      req.InputFileID,
+		Status:           batch.StatusValidating,
+		Metadata:         metadata,
+		ExpiresAt:        now.Add(24 * time.Hour),
+	}
+	if err := s.DBClient.CreateBatch(ctx, rec); err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+	obj := ToBatchObject(rec)
+	return &obj, nil
+}
+
+func (s *Service) GetBatch(ctx context.Context, caller Caller, id string) (*BatchObject, error) {
+	rec, err := s.DBClient.GetBatch(ctx, caller.ClientID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	if rec == nil {
+		return nil, NewError(http.StatusNotFound, "not_found", fmt.Sprintf("no such batch: %s", id))
+	}
+	obj := ToBatchObject(rec)
+	return &obj, nil
+}
+
+func (s *Service) ListBatches(ctx context.Context, caller Caller, after string, limit int) (*ListObject[BatchObject], error) {
+	limit = normalizeLimit(limit)
+	batches, err := s.DBClient.ListBatches(ctx, batch.ListOptions{ClientID: caller.ClientID, After: after, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batches: %w", err)
+	}
+	var items []BatchObject
+	for _, b := range batches {
+		items = append(items, ToBatchObject(b))
+	}
+	return newList(items, limit, func(o BatchObject) string { return o.ID }), nil
+}
+
+// CancelBatch requests cancellation, requests in flight are completed and written to the output file.
+func (s *Service) CancelBatch(ctx context.Context, caller Caller, id string) (*BatchObject, error) {
+	if _, err := s.GetBatch(ctx, caller, id); err != nil {
+		return nil, err
+	}
+	if err := s.DBClient.CancelBatch(ctx, caller.ClientID, id, s.nowFunc()); err != nil {
+		return nil, fmt.Errorf("failed to cancel batch: %w", err)
+	}
+	return s.GetBatch(ctx, caller, id)
+}
+
+// newList builds a page from items fetched with one extra item, which tells whether there are more.
+func newList[T any](items []T, limit int, id func(T) string) *ListObject[T] {
+	list := &ListObject[T]{Object: "list", Data: items}
+	if list.Data == nil {
+		list.Data = []T{}
+	}
+	if len(list.Data) > limit {
+		list.Data = list.Data[:limit]
+		list.HasMore = true
+	}
+	if len(list.Data) > 0 {
+		list.FirstID = id(list.Data[0])
+		list.LastID = id(list.Data[len(list.Data)-1])
+	}
+	return list
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return 20
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
+}
@@ -0,0 +1,368 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/batch"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+const (
+	// SourceBatch is sent as X-AI-Proxy-Source of request lines, so they can be told apart in audit.
+	SourceBatch = "batch"
+
+	maxRateLimitedAttempts = 3
+	maxRetryAfter          = time.Minute
+)
+
+// OutputLine is a line of the output or error file.
+type OutputLine struct {
+	ID       string        `json:"id"`
+	CustomID string        `json:"custom_id"`
+	Response *LineResponse `json:"response"`
+	Error    *LineError    `json:"error"`
+}
+
+type LineResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type LineError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Succeeded reports whether the line goes to the output file rather than the error file.
+func (l OutputLine) Succeeded() bool {
+	return l.Error == nil && l.Response != nil && l.Response.StatusCode >= 200 && l.Response.StatusCode < 300
+}
+
+func (s *Service) AsyncRun(ctx context.Context) {
+	go func() {
+		if err := s.Run(ctx); err != nil {
+			s.Logger.Errorf("batch worker exited with error: %v", err)
+		}
+	}()
+}
+
+// Run executes batches one by one until ctx is done, the requests of a batch run concurrently.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.Config.LoopInterval)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) tick(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := s.nowFunc()
+		b, err := s.DBClient.ClaimBatch(ctx, s.Worker, now, now.Add(-s.Config.StaleTimeout))
+		if err != nil {
+			s.Logger.Errorf("failed to claim batch: %v", err)
+			return
+		}
+		if b == nil {
+			return
+		}
+		s.Logger.Infof("batch claimed, id: %s, status: %s", b.ID, b.Status)
+		if err := s.execute(ctx, b); err != nil {
+			s.Logger.Errorf("failed to execute batch, id: %s, err: %v", b.ID, err)
+			s.fail(ctx, b, []BatchError{{Code: "internal_error", Message: err.Error()}})
+		}
+	}
+}
+
+// execute runs all request lines of a claimed batch and writes the output and error files.
+func (s *Service) execute(ctx context.Context, b *batch.Batch) error {
+	if b.Status == batch.StatusCancelling {
+		return s.finish(ctx, b, batch.StatusCancelled, nil, nil)
+	}
+	if !s.nowFunc().Before(b.ExpiresAt) {
+		return s.finish(ctx, b, batch.StatusExpired, nil, nil)
+	}
+
+	input, err := s.DBClient.GetFile(ctx, b.ClientID, b.InputFileID)
+	if err != nil {
+		return fmt.Errorf("failed to get input file: %w", err)
+	}
+	if input == nil {
+		s.fail(ctx, b, []BatchError{{Code: "invalid_file", Message: fmt.Sprintf("input file %s not found", b.InputFileID), Param: "input_file_id"}})
+		return nil
+	}
+	r, err := s.readStorage(input.StorageKey)
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+	lines, errs := ParseInput(content, b.Endpoint, s.Config.MaxRequests)
+	if len(errs) > 0 {
+		s.fail(ctx, b, errs)
+		return nil
+	}
+	credential, err := s.Credential(ctx, b.ClientID, b.ClientTokenID)
+	if err != nil {
+		return fmt.Errorf("failed to get credential: %w", err)
+	}
+
+	// watch cancellation and expiration, and keep the heartbeat while running
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	var (
+		completed, failed atomic.Int64
+		finalStatus       = batch.StatusCompleted
+		statusMu          sync.Mutex
+	)
+	setFinalStatus := func(status string) {
+		statusMu.Lock()
+		defer statusMu.Unlock()
+		finalStatus = status
+	}
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		ticker := time.NewTicker(s.Config.LoopInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			now := s.nowFunc()
+			if err := s.DBClient.UpdateProgress(ctx, b.ID, s.Worker, int64(len(lines)), completed.Load(), failed.Load(), now); err != nil {
+				s.Logger.Warnf("failed to update batch progress, id: %s, err: %v", b.ID, err)
+			}
+			if status, err := s.DBClient.GetBatchStatus(ctx, b.ID); err == nil && status == batch.StatusCancelling {
+				setFinalStatus(batch.StatusCancelled)
+				stop()
+				return
+			}
+			if !now.Before(b.ExpiresAt) {
+				setFinalStatus(batch.StatusExpired)
+				stop()
+				return
+			}
+		}
+	}()
+
+	results := s.runLines(runCtx, lines, func(ctx context.Context, line RequestLine) OutputLine {
+		out := s.sendLine(ctx, credential, line)
+		if out.Succeeded() {
+			completed.Add(1)
+		} else {
+			failed.Add(1)
+		}
+		return out
+	})
+	stop()
+	<-watchDone
+
+	statusMu.Lock()
+	status := finalStatus
+	statusMu.Unlock()
+	if status == batch.StatusCompleted {
+		now := s.nowFunc()
+		_ = s.DBClient.UpdateBatch(ctx, b.ID, s.Worker, map[string]any{"status": batch.StatusFinalizing, "finalizing_at": now})
+	}
+	b.RequestTotal = int64(len(lines))
+	return s.finish(ctx, b, status, results, nil)
+}
+
+// runLines sends lines with bounded concurrency, no more line is started once ctx is done.
+// Results keep the order of lines, lines not started are left out.
+func (s *Service) runLines(ctx context.Context, lines []RequestLine, send func(context.Context, RequestLine) OutputLine) []OutputLine {
+	results := make([]*OutputLine, len(lines))
+	sem := make(chan struct{}, s.Config.Concurrency)
+	var wg sync.WaitGroup
+dispatch:
+	for i := range lines {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// requests in flight are not interrupted by cancellation
+			out := send(context.WithoutCancel(ctx), lines[i])
+			results[i] = &out
+		}(i)
+	}
+	wg.Wait()
+
+	var outputs []OutputLine
+	for _, out := range results {
+		if out != nil {
+			outputs = append(outputs, *out)
+		}
+	}
+	return outputs
+}
+
+// sendLine calls ai-proxy itself with the credential of the batch, requests rate limited are retried after a while.
+func (s *Service) sendLine(ctx context.Context, credential string, line RequestLine) OutputLine {
+	out := OutputLine{ID: "batch_req_" + strings.ReplaceAll(uuid.New(), "-", ""), CustomID: line.CustomID}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Config.BaseURL+line.URL, bytes.NewReader(line.Body))
+		if err != nil {
+			out.Error = &LineError{Code: "invalid_request", Message: err.Error()}
+			return out
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", vars.ConcatBearer(credential))
+		req.Header.Set(vars.XAIProxySource, SourceBatch)
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			out.Error = &LineError{Code: "request_failed", Message: err.Error()}
+			return out
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			out.Error = &LineError{Code: "request_failed", Message: fmt.Sprintf("failed to read response: %v", err)}
+			return out
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitedAttempts {
+			timer := time.NewTimer(retryAfter(resp.Header, attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				out.Error = &LineError{Code: "request_failed", Message: ctx.Err().Error()}
+				return out
+			case <-timer.C:
+			}
+			continue
+		}
+		if !json.Valid(body) {
+			body, _ = json.Marshal(string(body))
+		}
+		out.Response = &LineResponse{StatusCode: resp.StatusCode, RequestID: resp.Header.Get(vars.XRequestId), Body: body}
+		return out
+	}
+}
+
+func retryAfter(header http.Header, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header.Get("Retry-After"))); err == nil && seconds >= 0 {
+		d := time.Duration(seconds) * time.Second
+		if d > maxRetryAfter {
+			d = maxRetryAfter
+		}
+		return d
+	}
+	return time.Duration(attempt) * 5 * time.Second
+}
+
+// finish writes the output and error files and moves the batch to the final status.
+func (s *Service) finish(ctx context.Context, b *batch.Batch, status string, results []OutputLine, errs []BatchError) error {
+	var (
+		output, errOutput bytes.Buffer
+		completed, failed int64
+	)
+	for _, out := range results {
+		buf := &output
+		if out.Succeeded() {
+			completed++
+		} else {
+			failed++
+			buf = &errOutput
+		}
+		line, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("failed to marshal output line: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	now := s.nowFunc()
+	updates := map[string]any{
+		"status":            status,
+		"request_total":     b.RequestTotal,
+		"request_completed": completed,
+		"request_failed":    failed,
+		"heartbeat_at":      now,
+	}
+	caller := Caller{ClientID: b.ClientID, ClientTokenID: b.ClientTokenID}
+	if output.Len() > 0 {
+		file, err := s.saveFile(ctx, caller, b.ID+"_output.jsonl", batch.FilePurposeBatchOutput, output.Bytes())
+		if err != nil {
+			return err
+		}
+		updates["output_file_id"] = file.ID
+	}
+	if errOutput.Len() > 0 {
+		file, err := s.saveFile(ctx, caller, b.ID+"_error.jsonl", batch.FilePurposeBatchOutput, errOutput.Bytes())
+		if err != nil {
+			return err
+		}
+		updates["error_file_id"] = file.ID
+	}
+	if len(errs) > 0 {
+		errsJSON, _ := json.Marshal(errs)
+		updates["errors"] = string(errsJSON)
+	}
+	switch status {
+	case batch.StatusCompleted:
+		updates["completed_at"] = now
+	case batch.StatusFailed:
+		updates["failed_at"] = now
+	case batch.StatusExpired:
+		updates["expired_at"] = now
+	case batch.StatusCancelled:
+		updates["cancelled_at"] = now
+	}
+	if err := s.DBClient.UpdateBatch(ctx, b.ID, s.Worker, updates); err != nil {
+		return fmt.Errorf("failed to update batch: %w", err)
+	}
+	s.Logger.Infof("batch finished, id: %s, status: %s, completed: %d, failed: %d", b.ID, status, completed, failed)
+	return nil
+}
+
+func (s *Service) fail(ctx context.Context, b *batch.Batch, errs []BatchError) {
+	if err := s.finish(ctx, b, batch.StatusFailed, nil, errs); err != nil {
+		s.Logger.Errorf("failed to mark batch failed, id: %s, err: %v", b.ID, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+)
+
+func TestService_runLines(t *testing.T) {
+	s := &Service{Config: Config{Concurrency: 2}}
+	var lines []RequestLine
+	for i := 0; i < 10; i++ {
+		lines = append(lines, RequestLine{CustomID: fmt.Sprintf("%d", i)})
+	}
+
+	var inFlight, maxInFlight atomic.Int64
+	outputs := s.runLines(context.Background(), lines, func(_ context.Context, line RequestLine) OutputLine {
+		n := inFlight.Add(1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		defer inFlight.Add(-1)
+		return OutputLine{CustomID: line.CustomID}
+	})
+	require.Len(t, outputs, 10)
+	for i, out := range outputs {
+		require.Equal(t, fmt.Sprintf("%d", i), out.CustomID, "outputs keep the order of lines")
+	}
+	require.LessOrEqual(t, maxInFlight.Load(), int64(2))
+}
+
+func TestService_runLines_Cancelled(t *testing.T) {
+	s := &Service{Config: Config{Concurrency: 1}}
+	lines := []RequestLine{{CustomID: "0"}, {CustomID: "1"}, {CustomID: "2"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	outputs := s.runLines(ctx, lines, func(ctx context.Context, line RequestLine) OutputLine {
+		cancel()
+		require.NoError(t, ctx.Err(), "requests in flight are not interrupted")
+		return OutputLine{CustomID: line.CustomID}
+	})
+	require.Len(t, outputs, 1)
+	require.Equal(t, "0", outputs[0].CustomID)
+}
+
+func TestService_sendLine(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer t_token", r.Header.Get("Authorization"))
+		require.Equal(t, SourceBatch, r.Header.Get(vars.XAIProxySource))
+		w.Header().Set(vars.XRequestId, "req-1")
+		switch r.URL.Path {
+		case "/v1/chat/completions":
+			// rate limited once, then succeeds
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"chatcmpl-1"}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("bad gateway"))
+		}
+	}))
+	defer server.Close()
+	s := &Service{Config: Config{BaseURL: server.URL}, httpClient: server.Client()}
+
+	out := s.sendLine(context.Background(), "t_token", RequestLine{CustomID: "a", URL: "/v1/chat/completions", Body: []byte(`{}`)})
+	require.True(t, out.Succeeded())
+	require.Equal(t, "a", out.CustomID)
+	require.Equal(t, "req-1", out.Response.RequestID)
+	require.JSONEq(t, `{"id":"chatcmpl-1"}`, string(out.Response.Body))
+	require.Equal(t, int64(2), calls.Load())
+
+	out = s.sendLine(context.Background(), "t_token", RequestLine{CustomID: "b", URL: "/v1/embeddings", Body: []byte(`{}`)})
+	require.False(t, out.Succeeded())
+	require.Equal(t, http.StatusBadGateway, out.Response.StatusCode)
+	require.JSONEq(t, `"bad gateway"`, string(out.Response.Body), "a non-json body is kept as a json string")
+}
+
+func TestService_sendLine_CancelledWhileWaitingRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	s := &Service{Config: Config{BaseURL: server.URL}, httpClient: server.Client()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	out := s.sendLine(ctx, "t_token", RequestLine{CustomID: "a", URL: "/v1/chat/completions", Body: []byte(`{}`)})
+	require.Less(t, time.Since(started), 10*time.Second)
+	require.False(t, out.Succeeded())
+	require.Equal(t, "request_failed", out.Error.Code)
+}
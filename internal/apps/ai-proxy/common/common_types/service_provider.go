@@ -25,6 +25,8 @@ const (
 	ServiceProviderTypeAWSBedrock       ServiceProviderType = "aws-bedrock"
 	ServiceProviderTypeOpenAICompatible ServiceProviderType = "openai-compatible"
 	ServiceProviderTypeGoogleVertexAI   ServiceProviderType = "google-vertex-ai"
+	ServiceProviderTypeGoogleGemini     ServiceProviderType = "google-gemini"
+	ServiceProviderTypeOllama           ServiceProviderType = "ollama"
 )
 
 func (m ServiceProviderType) String() string { return string(m) }
@@ -37,7 +39,9 @@ func (m ServiceProviderType) IsValid() bool {
 		ServiceProviderTypeVolcengineViking,
 		ServiceProviderTypeAWSBedrock,
 		ServiceProviderTypeOpenAICompatible,
-		ServiceProviderTypeGoogleVertexAI:
+		ServiceProviderTypeGoogleVertexAI,
+		ServiceProviderTypeGoogleGemini,
+		ServiceProviderTypeOllama:
 		return true
 	}
 	return false
@@ -56,12 +56,14 @@ type (
 	mapKeyMcpInfo struct {
 		McpInfo
 	}
+	mapKeyMcpToolCall struct{ any }
 
 	// Keys for response processing
 	mapKeyRespBodyChunkSplitter struct {
 		filter_define.RespBodyChunkSplitter
 	}
-	mapKeyResponseContentEncoding struct{ string }
+	mapKeyResponseContentEncoding     struct{ string }
+	mapKeyResponseUpstreamContentType struct{ string }
 
 	// Keys for filter-generated responses
 	mapKeyRequestFilterGeneratedResponse struct{ *http.Response }
@@ -77,6 +79,8 @@ type (
 	mapKeyModelRetryExcludedModelIDs          struct{ any }
 	mapKeyModelRetryResponseHeaderMetaEnabled struct{ bool }
 	mapKeyModelRetrySessionUnhealthyMarks     struct{ any }
+	mapKeyModelRetryExcludedBranchNames       struct{ any }
+	mapKeyModelRetryPolicyGroupFallback       struct{ any }
 
 	mapKeyReverseProxyWholeHandledResponseBodyStr struct{ string }
 
@@ -105,6 +109,15 @@ type (
 	mapKeyModelMarkUnhealthyInstanceID struct{ string }
 	mapKeyTrustedHealthProbe           struct{ bool }
 	mapKeyPolicyGroupHealthMeta        struct{ any }
+
+	mapKeyRateLimitHeaders       struct{ http.Header }
+	mapKeyRateLimitTokenCounters struct{ any }
+	mapKeyBudgets                struct{ any }
+
+	mapKeyResponseCache    struct{ any }
+	mapKeyResponseCacheHit struct{ bool }
+
+	mapKeyPromptSelection struct{ any }
 )
 
 // KeysWithCustomMustGet defines keys with custom MustGet implementations (should not generate default MustGet)
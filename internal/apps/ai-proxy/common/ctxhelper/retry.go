@@ -48,9 +48,10 @@ func ResetForRetry(ctx context.Context) context.Context {
 		reflect.TypeOf(mapKeyClientToken{}),
 		reflect.TypeOf(mapKeyIsAdmin{}),
 		reflect.TypeOf(mapKeyAccessLang{}),
-		// retry needs body copy and excluded models across attempts
+		// retry needs body copy and excluded models and branches across attempts
 		reflect.TypeOf(mapKeyReverseProxyRequestBodyBytes{}),
 		reflect.TypeOf(mapKeyModelRetryExcludedModelIDs{}),
+		reflect.TypeOf(mapKeyModelRetryExcludedBranchNames{}),
 		reflect.TypeOf(mapKeyModelRetrySessionUnhealthyMarks{}),
 	}
 
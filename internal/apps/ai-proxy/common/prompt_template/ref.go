@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompt_template
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionLatest is the version of `name@latest`, the latest created version whether published or not.
+const VersionLatest = "latest"
+
+// Ref references a prompt by `name`, `name@<version>` or `name@latest`.
+type Ref struct {
+	Name string
+	// Version is 0 if not specified, then the experiment or the published version is used.
+	Version uint64
+	Latest  bool
+}
+
+func ParseRef(s string) (Ref, error) {
+	name, version, found := strings.Cut(strings.TrimSpace(s), "@")
+	if name == "" {
+		return Ref{}, fmt.Errorf("invalid prompt reference: %q", s)
+	}
+	ref := Ref{Name: name}
+	if !found {
+		return ref, nil
+	}
+	if version == VersionLatest {
+		ref.Latest = true
+		return ref, nil
+	}
+	v, err := strconv.ParseUint(strings.TrimPrefix(version, "v"), 10, 64)
+	if err != nil || v == 0 {
+		return Ref{}, fmt.Errorf("invalid version of prompt reference: %q", s)
+	}
+	ref.Version = v
+	return ref, nil
+}
+
+func (r Ref) String() string {
+	switch {
+	case r.Latest:
+		return r.Name + "@" + VersionLatest
+	case r.Version > 0:
+		return r.Name + "@" + strconv.FormatUint(r.Version, 10)
+	default:
+		return r.Name
+	}
+}
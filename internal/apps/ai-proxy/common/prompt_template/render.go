@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompt_template
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+var variableRe = regexp.MustCompile(`\$\{@prompt\.variables\.([^{}]+)\}`)
+
+// ReferencedVariables returns names of variables referenced by text.
+func ReferencedVariables(text string) []string {
+	var names []string
+	for _, match := range variableRe.FindAllStringSubmatch(text, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// Render replaces `${@prompt.variables.<name>}` in text, non-string values are json encoded.
+func Render(text string, values map[string]any) (string, error) {
+	var firstErr error
+	out := variableRe.ReplaceAllStringFunc(text, func(tok string) string {
+		if firstErr != nil {
+			return tok
+		}
+		name := variableRe.FindStringSubmatch(tok)[1]
+		value, ok := values[name]
+		if !ok {
+			firstErr = fmt.Errorf("undefined variable: %s", name)
+			return tok
+		}
+		if s, ok := value.(string); ok {
+			return s
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			firstErr = fmt.Errorf("failed to encode variable %s: %w", name, err)
+			return tok
+		}
+		return string(b)
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return out, nil
+}
+
+// RenderMessages renders text contents of messages, messages are copied.
+func RenderMessages(msgs []openai.ChatCompletionMessage, values map[string]any) ([]openai.ChatCompletionMessage, error) {
+	result := make([]openai.ChatCompletionMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		content, err := Render(msg.Content, values)
+		if err != nil {
+			return nil, err
+		}
+		msg.Content = content
+		if len(msg.MultiContent) > 0 {
+			parts := make([]openai.ChatMessagePart, 0, len(msg.MultiContent))
+			for _, part := range msg.MultiContent {
+				if part.Type == openai.ChatMessagePartTypeText {
+					if part.Text, err = Render(part.Text, values); err != nil {
+						return nil, err
+					}
+				}
+				parts = append(parts, part)
+			}
+			msg.MultiContent = parts
+		}
+		result = append(result, msg)
+	}
+	return result, nil
+}
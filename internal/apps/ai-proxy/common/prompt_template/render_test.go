@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompt_template
+
+import (
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	values := map[string]any{
+		"lang": "en",
+		"n":    float64(3),
+		"user": map[string]any{"name": "tom"},
+	}
+	got, err := Render("answer in ${@prompt.variables.lang}, top ${@prompt.variables.n}, user: ${@prompt.variables.user}, keep ${@template.name}", values)
+	require.NoError(t, err)
+	assert.Equal(t, `answer in en, top 3, user: {"name":"tom"}, keep ${@template.name}`, got)
+
+	_, err = Render("${@prompt.variables.missing}", values)
+	assert.ErrorContains(t, err, "undefined variable: missing")
+}
+
+func TestReferencedVariables(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, ReferencedVariables("${@prompt.variables.a} and ${@prompt.variables.b}"))
+	assert.Empty(t, ReferencedVariables("no variables"))
+}
+
+func TestRenderMessages(t *testing.T) {
+	msgs := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "answer in ${@prompt.variables.lang}"},
+		{Role: openai.ChatMessageRoleUser, MultiContent: []openai.ChatMessagePart{
+			{Type: openai.ChatMessagePartTypeText, Text: "${@prompt.variables.lang} please"},
+		}},
+	}
+	got, err := RenderMessages(msgs, map[string]any{"lang": "fr"})
+	require.NoError(t, err)
+	assert.Equal(t, "answer in fr", got[0].Content)
+	assert.Equal(t, "fr please", got[1].MultiContent[0].Text)
+	// the original messages are not changed
+	assert.Equal(t, "${@prompt.variables.lang} please", msgs[1].MultiContent[0].Text)
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prompt_template renders versioned prompts referenced by chat requests.
+package prompt_template
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+const (
+	VariableTypeString  = "string"
+	VariableTypeNumber  = "number"
+	VariableTypeBoolean = "boolean"
+	VariableTypeObject  = "object"
+	VariableTypeArray   = "array"
+)
+
+// Variable is a typed variable referenced in messages as `${@prompt.variables.<name>}`.
+type Variable struct {
+	Name string `json:"name"`
+	// Type is one of: string, number, boolean, object, array; default string.
+	Type     string `json:"type,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Default  any    `json:"default,omitempty"`
+	Desc     string `json:"desc,omitempty"`
+}
+
+func (v Variable) GetType() string {
+	if v.Type == "" {
+		return VariableTypeString
+	}
+	return v.Type
+}
+
+var variableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateVariables checks variable definitions, and that all variables referenced by texts are defined.
+func ValidateVariables(defines []Variable, texts ...string) error {
+	defined := make(map[string]struct{}, len(defines))
+	for _, v := range defines {
+		if !variableNameRe.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name: %q", v.Name)
+		}
+		if _, ok := defined[v.Name]; ok {
+			return fmt.Errorf("duplicate variable: %s", v.Name)
+		}
+		defined[v.Name] = struct{}{}
+		switch v.GetType() {
+		case VariableTypeString, VariableTypeNumber, VariableTypeBoolean, VariableTypeObject, VariableTypeArray:
+		default:
+			return fmt.Errorf("invalid type of variable %s: %s", v.Name, v.Type)
+		}
+		if v.Default != nil {
+			if err := checkType(v, v.Default); err != nil {
+				return fmt.Errorf("invalid default value: %w", err)
+			}
+		}
+	}
+	for _, text := range texts {
+		for _, name := range ReferencedVariables(text) {
+			if _, ok := defined[name]; !ok {
+				return fmt.Errorf("undefined variable: %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveVariables checks values against definitions and fills defaults.
+// Optional variables without value and default are resolved to empty string.
+func ResolveVariables(defines []Variable, values map[string]any) (map[string]any, error) {
+	defined := make(map[string]struct{}, len(defines))
+	resolved := make(map[string]any, len(defines))
+	for _, v := range defines {
+		defined[v.Name] = struct{}{}
+		value, ok := values[v.Name]
+		if !ok || value == nil {
+			switch {
+			case v.Default != nil:
+				value = v.Default
+			case v.Required:
+				return nil, fmt.Errorf("missing required variable: %s", v.Name)
+			default:
+				value = ""
+			}
+		} else if err := checkType(v, value); err != nil {
+			return nil, err
+		}
+		resolved[v.Name] = value
+	}
+	for name := range values {
+		if _, ok := defined[name]; !ok {
+			return nil, fmt.Errorf("undefined variable: %s", name)
+		}
+	}
+	return resolved, nil
+}
+
+// checkType checks a value decoded from json.
+func checkType(v Variable, value any) error {
+	var ok bool
+	switch v.GetType() {
+	case VariableTypeString:
+		_, ok = value.(string)
+	case VariableTypeNumber:
+		switch value.(type) {
+		case float64, float32, int, int32, int64, uint, uint32, uint64, json.Number:
+			ok = true
+		}
+	case VariableTypeBoolean:
+		_, ok = value.(bool)
+	case VariableTypeObject:
+		_, ok = value.(map[string]any)
+	case VariableTypeArray:
+		_, ok = value.([]any)
+	}
+	if !ok {
+		return fmt.Errorf("variable %s should be %s, got %T", v.Name, v.GetType(), value)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompt_template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateVariables(t *testing.T) {
+	defines := []Variable{
+		{Name: "lang", Required: true},
+		{Name: "max_words", Type: VariableTypeNumber, Default: float64(100)},
+	}
+	assert.NoError(t, ValidateVariables(defines, "reply in ${@prompt.variables.lang} within ${@prompt.variables.max_words} words"))
+	assert.ErrorContains(t, ValidateVariables(defines, "${@prompt.variables.tone}"), "undefined variable: tone")
+	assert.ErrorContains(t, ValidateVariables([]Variable{{Name: "1a"}}), "invalid variable name")
+	assert.ErrorContains(t, ValidateVariables([]Variable{{Name: "a"}, {Name: "a"}}), "duplicate variable")
+	assert.ErrorContains(t, ValidateVariables([]Variable{{Name: "a", Type: "date"}}), "invalid type")
+	assert.ErrorContains(t, ValidateVariables([]Variable{{Name: "a", Type: VariableTypeBoolean, Default: "yes"}}), "invalid default value")
+}
+
+func TestResolveVariables(t *testing.T) {
+	defines := []Variable{
+		{Name: "lang", Required: true},
+		{Name: "max_words", Type: VariableTypeNumber, Default: float64(100)},
+		{Name: "tags", Type: VariableTypeArray},
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		got, err := ResolveVariables(defines, map[string]any{"lang": "en"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"lang": "en", "max_words": float64(100), "tags": ""}, got)
+	})
+	t.Run("missing required", func(t *testing.T) {
+		_, err := ResolveVariables(defines, nil)
+		assert.ErrorContains(t, err, "missing required variable: lang")
+	})
+	t.Run("type mismatch", func(t *testing.T) {
+		_, err := ResolveVariables(defines, map[string]any{"lang": "en", "max_words": "100"})
+		assert.ErrorContains(t, err, "variable max_words should be number")
+	})
+	t.Run("undefined", func(t *testing.T) {
+		_, err := ResolveVariables(defines, map[string]any{"lang": "en", "tone": "formal"})
+		assert.ErrorContains(t, err, "undefined variable: tone")
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompt_template
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+type Variant struct {
+	Version uint64
+	Weight  uint32
+}
+
+// PickVariant picks a variant by weight.
+// Callers with the same non-empty stickyValue always get the same variant as long as variants don't change.
+func PickVariant(variants []Variant, stickyValue string) (Variant, bool) {
+	var total uint64
+	for _, v := range variants {
+		total += uint64(v.Weight)
+	}
+	if total == 0 {
+		return Variant{}, false
+	}
+	var point uint64
+	if stickyValue != "" {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(stickyValue))
+		point = h.Sum64() % total
+	} else {
+		point = uint64(rand.Int63n(int64(total)))
+	}
+	for _, v := range variants {
+		if point < uint64(v.Weight) {
+			return v, true
+		}
+		point -= uint64(v.Weight)
+	}
+	return Variant{}, false
+}
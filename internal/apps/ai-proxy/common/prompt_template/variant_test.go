@@ -0,0 +1,51 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompt_template
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants := []Variant{{Version: 1, Weight: 90}, {Version: 2, Weight: 10}}
+
+	t.Run("sticky", func(t *testing.T) {
+		first, ok := PickVariant(variants, "user-1")
+		assert.True(t, ok)
+		for i := 0; i < 10; i++ {
+			got, _ := PickVariant(variants, "user-1")
+			assert.Equal(t, first, got)
+		}
+	})
+	t.Run("weight", func(t *testing.T) {
+		counts := map[uint64]int{}
+		for i := 0; i < 1000; i++ {
+			got, _ := PickVariant(variants, fmt.Sprintf("user-%d", i))
+			counts[got.Version]++
+		}
+		assert.InDelta(t, 900, counts[1], 60)
+		assert.InDelta(t, 100, counts[2], 60)
+	})
+	t.Run("zero weight", func(t *testing.T) {
+		_, ok := PickVariant([]Variant{{Version: 1}}, "")
+		assert.False(t, ok)
+		got, ok := PickVariant([]Variant{{Version: 1}, {Version: 2, Weight: 1}}, "")
+		assert.True(t, ok)
+		assert.Equal(t, uint64(2), got.Version)
+	})
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pricing
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	modelpb "github.com/erda-project/erda-proto-go/apps/aiproxy/model/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/pkg/valueutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata"
+)
+
+// Pricing is the per-token and per-request price of a model.
+type Pricing struct {
+	Currency          string
+	Input             float64
+	Output            float64
+	Request           float64
+	InputCacheRead    float64
+	HasInputCacheRead bool
+}
+
+// Calculate returns the price of one call and whether the price is estimated,
+// usageDetails is the raw usage json returned by provider, used to find cached input tokens.
+func (p Pricing) Calculate(inputTokens, outputTokens uint64, usageDetails string) (float64, bool) {
+	price := p.Request
+	cachedInputTokens, hasCachedInput := extractCachedInputTokens(usageDetails)
+	if cachedInputTokens > inputTokens {
+		cachedInputTokens = inputTokens
+	}
+
+	regularInputTokens := inputTokens
+	if hasCachedInput && cachedInputTokens > 0 {
+		regularInputTokens -= cachedInputTokens
+	}
+
+	if p.Input != 0 && regularInputTokens > 0 {
+		price += float64(regularInputTokens) * p.Input
+	}
+
+	isEstimated := false
+	if hasCachedInput && cachedInputTokens > 0 {
+		cacheReadPrice := p.InputCacheRead
+		if !p.HasInputCacheRead {
+			cacheReadPrice = p.Input / 5
+			isEstimated = true
+		}
+		if cacheReadPrice != 0 {
+			price += float64(cachedInputTokens) * cacheReadPrice
+		}
+	}
+
+	if p.Output != 0 && outputTokens > 0 {
+		price += float64(outputTokens) * p.Output
+	}
+	return price, isEstimated
+}
+
+// FromModel extracts pricing from `pricing` in model public metadata.
+func FromModel(model *modelpb.Model) Pricing {
+	if model == nil || model.Metadata == nil {
+		return Pricing{}
+	}
+	meta := metadata.FromProtobuf(model.Metadata)
+	rawPricing, ok := meta.Public["pricing"]
+	if !ok {
+		return Pricing{}
+	}
+	pricingMap, ok := rawPricing.(map[string]any)
+	if !ok {
+		return Pricing{}
+	}
+
+	normalized := make(map[string]any, len(pricingMap))
+	for k, v := range pricingMap {
+		normalized[strings.ToLower(k)] = v
+	}
+
+	currency := stringValue(normalized["unit"])
+	promptPrice, _ := parsePriceValue(normalized["prompt"])
+	completionPrice, _ := parsePriceValue(normalized["completion"])
+	requestPrice, _ := parsePriceValue(normalized["request"])
+	cacheReadPrice, hasCacheReadPrice := parsePriceValue(normalized["input_cache_read"])
+
+	return Pricing{
+		Currency:          currency,
+		Input:             promptPrice,
+		Output:            completionPrice,
+		Request:           requestPrice,
+		InputCacheRead:    cacheReadPrice,
+		HasInputCacheRead: hasCacheReadPrice,
+	}
+}
+
+func extractCachedInputTokens(usageDetails string) (uint64, bool) {
+	usageDetails = strings.TrimSpace(usageDetails)
+	if usageDetails == "" || usageDetails == "{}" {
+		return 0, false
+	}
+
+	var payload any
+	if err := json.Unmarshal([]byte(usageDetails), &payload); err != nil {
+		return 0, false
+	}
+	return findCachedInputTokens(payload)
+}
+
+func findCachedInputTokens(v any) (uint64, bool) {
+	switch val := v.(type) {
+	case map[string]any:
+		if cached, ok := getNestedUint(val, "input_tokens_details", "cached_tokens"); ok {
+			return cached, true
+		}
+		if cached, ok := getNestedUint(val, "prompt_tokens_details", "cached_tokens"); ok {
+			return cached, true
+		}
+		for _, nested := range val {
+			if cached, ok := findCachedInputTokens(nested); ok {
+				return cached, true
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if cached, ok := findCachedInputTokens(item); ok {
+				return cached, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func getNestedUint(m map[string]any, key, nestedKey string) (uint64, bool) {
+	if m == nil {
+		return 0, false
+	}
+	nested, ok := m[key].(map[string]any)
+	if !ok {
+		return 0, false
+	}
+	return valueutil.GetUint64(nested[nestedKey])
+}
+
+func parsePriceValue(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	case string:
+		if v == "" {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
+
+func stringValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	if str, ok := v.(string); ok {
+		return str
+	}
+	return ""
+}
@@ -34,6 +34,16 @@ type usageCollector struct {
 
 var defaultCollector *usageCollector
 
+// CollectedHook is invoked with every token usage record right before it is persisted.
+type CollectedHook func(ctx context.Context, usage *usagepb.TokenUsageCreateRequest)
+
+var collectedHooks []CollectedHook
+
+// RegisterCollectedHook registers a hook, should be called in init().
+func RegisterCollectedHook(hook CollectedHook) {
+	collectedHooks = append(collectedHooks, hook)
+}
+
 func InitUsageCollector(dao dao.DAO) {
 	defaultCollector = &usageCollector{dbClient: dao}
 }
@@ -65,6 +75,10 @@ func Collect(resp *http.Response) {
 	// fulfill token related fields
 	calculateTokens(resp, &createReq)
 
+	for _, hook := range collectedHooks {
+		hook(ctx, &createReq)
+	}
+
 	if _, err := defaultCollector.dbClient.TokenUsageClient().Create(context.Background(), &createReq); err != nil {
 		ctxhelper.MustGetLogger(ctx).Errorf("fail to create token usage record: %v", err)
 	}
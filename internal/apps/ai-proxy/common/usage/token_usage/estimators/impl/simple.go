@@ -298,6 +298,12 @@ var (
 	}
 )
 
+// CountTextTokens estimates the token count of text for model, e.g., to fit messages into the context window.
+func CountTextTokens(model, text string) uint64 {
+	tokens, _ := countTokensFallback(model, []byte(text))
+	return tokens
+}
+
 func countTokensFallback(model string, data []byte) (uint64, string) {
 	if tokens, name := tryCountByOpenaiTokenizer(model, data); name != "" {
 		return tokens, "openai: " + name
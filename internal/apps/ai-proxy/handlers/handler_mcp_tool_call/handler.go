@@ -0,0 +1,82 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler_mcp_tool_call
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_tool_call/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/auth"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_tool_call"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/providers/dao"
+)
+
+type MCPToolCallHandler struct {
+	dao dao.DAO
+}
+
+func NewMCPToolCallHandler(dao dao.DAO) *MCPToolCallHandler {
+	return &MCPToolCallHandler{dao: dao}
+}
+
+func (m *MCPToolCallHandler) Paging(ctx context.Context, request *pb.MCPToolCallPagingRequest) (*pb.MCPToolCallPagingResponse, error) {
+	logger := ctxhelper.MustGetLogger(ctx)
+
+	// client can only see its own calls
+	var clientId *string
+	if auth.IsAdmin(ctx) {
+		clientId = request.ClientId
+	} else {
+		id, ok := ctxhelper.GetClientId(ctx)
+		if !ok {
+			logger.Errorf("client id or client-id should not be empty")
+			return nil, errors.New("failed to get clientId")
+		}
+		clientId = &id
+	}
+
+	options := &mcp_tool_call.ListOptions{
+		ClientId: clientId,
+		McpName:  request.McpName,
+		ToolName: request.ToolName,
+		Status:   request.Status,
+		PageNum:  int(request.PageNum),
+		PageSize: int(request.PageSize),
+	}
+	if request.StartTime > 0 {
+		options.StartTime = time.UnixMilli(request.StartTime)
+	}
+	if request.EndTime > 0 {
+		options.EndTime = time.UnixMilli(request.EndTime)
+	}
+
+	list, total, err := m.dao.MCPToolCallClient().Paging(ctx, options)
+	if err != nil {
+		logger.Errorf("failed to paging mcp tool calls: %v", err)
+		return nil, err
+	}
+	var calls = make([]*pb.MCPToolCall, 0, len(list))
+	for _, call := range list {
+		calls = append(calls, call.ToProtobuf())
+	}
+	return &pb.MCPToolCallPagingResponse{
+		Total: uint64(total),
+		List:  calls,
+	}, nil
+}
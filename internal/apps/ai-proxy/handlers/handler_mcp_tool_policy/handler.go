@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handler_mcp_tool_policy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	pb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_tool_policy/pb"
+	commonpb "github.com/erda-project/erda-proto-go/common/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/auth"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/handlers"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_tool_policy"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/providers/dao"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/mcp-tool/mcptoolutil"
+)
+
+type MCPToolPolicyHandler struct {
+	dao dao.DAO
+}
+
+func NewMCPToolPolicyHandler(dao dao.DAO) *MCPToolPolicyHandler {
+	return &MCPToolPolicyHandler{dao: dao}
+}
+
+func (m *MCPToolPolicyHandler) Set(ctx context.Context, request *pb.MCPToolPolicySetRequest) (*pb.MCPToolPolicyGetResponse, error) {
+	logger := ctxhelper.MustGetLogger(ctx)
+
+	clientId, err := getClientId(ctx, request.ClientId)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if err := mcptoolutil.ValidatePatterns(request.AllowTools); err != nil {
+		return nil, handlers.HTTPError(errors.Wrap(err, "invalid allow_tools"), http.StatusBadRequest)
+	}
+	if err := mcptoolutil.ValidatePatterns(request.DenyTools); err != nil {
+		return nil, handlers.HTTPError(errors.Wrap(err, "invalid deny_tools"), http.StatusBadRequest)
+	}
+
+	policy, err := m.dao.MCPToolPolicyClient().CreateOrUpdate(ctx, &mcp_tool_policy.McpToolPolicy{
+		ClientID:          clientId,
+		McpName:           request.McpName,
+		AllowTools:        request.AllowTools,
+		DenyTools:         request.DenyTools,
+		ValidateArguments: request.ValidateArguments,
+	})
+	if err != nil {
+		logger.Errorf("failed to set mcp tool policy: %v", err)
+		return nil, err
+	}
+	return &pb.MCPToolPolicyGetResponse{Data: policy.ToProtobuf()}, nil
+}
+
+func (m *MCPToolPolicyHandler) Get(ctx context.Context, request *pb.MCPToolPolicyGetRequest) (*pb.MCPToolPolicyGetResponse, error) {
+	logger := ctxhelper.MustGetLogger(ctx)
+
+	clientId, err := getClientId(ctx, request.ClientId)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	policy, err := m.dao.MCPToolPolicyClient().Get(ctx, clientId, request.McpName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, handlers.HTTPError(errors.New("mcp tool policy not found"), http.StatusNotFound)
+		}
+		logger.Errorf("failed to get mcp tool policy: %v", err)
+		return nil, err
+	}
+	return &pb.MCPToolPolicyGetResponse{Data: policy.ToProtobuf()}, nil
+}
+
+func (m *MCPToolPolicyHandler) Delete(ctx context.Context, request *pb.MCPToolPolicyDeleteRequest) (*commonpb.VoidResponse, error) {
+	logger := ctxhelper.MustGetLogger(ctx)
+
+	clientId, err := getClientId(ctx, request.ClientId)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	if err := m.dao.MCPToolPolicyClient().Delete(ctx, clientId, request.McpName); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, handlers.HTTPError(errors.New("mcp tool policy not found"), http.StatusNotFound)
+		}
+		logger.Errorf("failed to delete mcp tool policy: %v", err)
+		return nil, err
+	}
+	return new(commonpb.VoidResponse), nil
+}
+
+func (m *MCPToolPolicyHandler) List(ctx context.Context, request *pb.MCPToolPolicyListRequest) (*pb.MCPToolPolicyListResponse, error) {
+	logger := ctxhelper.MustGetLogger(ctx)
+
+	var clientId *string
+	if auth.IsAdmin(ctx) {
+		clientId = request.ClientId
+	} else {
+		id, ok := ctxhelper.GetClientId(ctx)
+		if !ok {
+			logger.Errorf("client id or client-id should not be empty")
+			return nil, errors.New("failed to get clientId")
+		}
+		clientId = &id
+	}
+
+	list, total, err := m.dao.MCPToolPolicyClient().List(ctx, &mcp_tool_policy.ListOptions{
+		ClientId: clientId,
+		PageNum:  int(request.PageNum),
+		PageSize: int(request.PageSize),
+	})
+	if err != nil {
+		logger.Errorf("failed to list mcp tool policies: %v", err)
+		return nil, err
+	}
+	var policies = make([]*pb.MCPToolPolicy, 0, len(list))
+	for _, policy := range list {
+		policies = append(policies, policy.ToProtobuf())
+	}
+	return &pb.MCPToolPolicyListResponse{
+		Total: uint64(total),
+		List:  policies,
+	}, nil
+}
+
+// getClientId returns the client id of the request, admin must specify it.
+func getClientId(ctx context.Context, requestClientId *string) (string, error) {
+	if auth.IsAdmin(ctx) {
+		if requestClientId == nil || *requestClientId == "" {
+			return "", handlers.HTTPError(errors.New("client id should not be empty for admin"), http.StatusBadRequest)
+		}
+		return *requestClientId, nil
+	}
+	id, ok := ctxhelper.GetClientId(ctx)
+	if !ok {
+		return "", errors.New("failed to get clientId")
+	}
+	return id, nil
+}
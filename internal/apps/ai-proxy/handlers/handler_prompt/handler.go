@@ -45,3 +45,23 @@ func (h *PromptHandler) Update(ctx context.Context, req *pb.PromptUpdateRequest)
 func (h *PromptHandler) Paging(ctx context.Context, req *pb.PromptPagingRequest) (*pb.PromptPagingResponse, error) {
 	return h.DAO.PromptClient().Paging(ctx, req)
 }
+
+func (h *PromptHandler) CreateVersion(ctx context.Context, req *pb.PromptVersionCreateRequest) (*pb.PromptVersion, error) {
+	return h.DAO.PromptClient().CreateVersion(ctx, req)
+}
+
+func (h *PromptHandler) GetVersion(ctx context.Context, req *pb.PromptVersionGetRequest) (*pb.PromptVersion, error) {
+	return h.DAO.PromptClient().GetVersion(ctx, req)
+}
+
+func (h *PromptHandler) ListVersions(ctx context.Context, req *pb.PromptVersionListRequest) (*pb.PromptVersionListResponse, error) {
+	return h.DAO.PromptClient().ListVersions(ctx, req)
+}
+
+func (h *PromptHandler) PublishVersion(ctx context.Context, req *pb.PromptVersionPublishRequest) (*pb.Prompt, error) {
+	return h.DAO.PromptClient().PublishVersion(ctx, req)
+}
+
+func (h *PromptHandler) SetExperiment(ctx context.Context, req *pb.PromptExperimentSetRequest) (*pb.Prompt, error) {
+	return h.DAO.PromptClient().SetExperiment(ctx, req)
+}
@@ -16,10 +16,7 @@ package handler_token_usage
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"strconv"
-	"strings"
 
 	"github.com/shopspring/decimal"
 
@@ -27,22 +24,12 @@ import (
 	usagepb "github.com/erda-project/erda-proto-go/apps/aiproxy/usage/token/pb"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/cache/cachehelpers"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
-	"github.com/erda-project/erda/internal/apps/ai-proxy/common/pkg/valueutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/usage/pricing"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/handlers/handler_i18n/i18n_services"
-	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata"
 )
 
-type tokenPricing struct {
-	Currency          string
-	Input             float64
-	Output            float64
-	Request           float64
-	InputCacheRead    float64
-	HasInputCacheRead bool
-}
-
 func (h *TokenUsageHandler) aggregateTokenUsages(ctx context.Context, records []*usagepb.TokenUsage, locale string) (*usagepb.TokenUsageAggregateResponse, error) {
-	pricingCache := make(map[string]tokenPricing)
+	pricingCache := make(map[string]pricing.Pricing)
 	var currency string
 	totalCost := decimal.Zero
 
@@ -61,22 +48,22 @@ func (h *TokenUsageHandler) aggregateTokenUsages(ctx context.Context, records []
 		resp.TotalOutputTokens += usage.OutputTokens
 		resp.TotalTokens += usage.TotalTokens
 
-		pricing, err := h.resolveModelPricing(ctx, usage.ModelId, locale, pricingCache)
+		modelPricing, err := h.resolveModelPricing(ctx, usage.ModelId, locale, pricingCache)
 		if err != nil {
 			if logger, ok := ctxhelper.GetLogger(ctx); ok {
 				logger.Errorf("failed to resolve pricing for model %s: %v", usage.ModelId, err)
 			}
 		}
 
-		price, priceEstimated := calculateUsagePrice(usage, pricing)
+		price, priceEstimated := modelPricing.Calculate(usage.InputTokens, usage.OutputTokens, usage.UsageDetails)
 		priceDec := decimal.NewFromFloat(price)
 		isEstimated := usage.IsEstimated || priceEstimated
 
-		if pricing.Currency != "" {
+		if modelPricing.Currency != "" {
 			if currency == "" {
-				currency = pricing.Currency
-			} else if currency != pricing.Currency {
-				return nil, fmt.Errorf("mixed pricing currencies detected: %s vs %s", currency, pricing.Currency)
+				currency = modelPricing.Currency
+			} else if currency != modelPricing.Currency {
+				return nil, fmt.Errorf("mixed pricing currencies detected: %s vs %s", currency, modelPricing.Currency)
 			}
 		}
 
@@ -91,7 +78,7 @@ func (h *TokenUsageHandler) aggregateTokenUsages(ctx context.Context, records []
 		detailCost := priceDec.Round(4)
 		resp.Details = append(resp.Details, &usagepb.TokenUsageDetail{
 			Cost:         detailCost.InexactFloat64(),
-			Currency:     pricing.Currency,
+			Currency:     modelPricing.Currency,
 			RecordId:     usage.Id,
 			InputTokens:  usage.InputTokens,
 			OutputTokens: usage.OutputTokens,
@@ -107,21 +94,21 @@ func (h *TokenUsageHandler) aggregateTokenUsages(ctx context.Context, records []
 	return resp, nil
 }
 
-func (h *TokenUsageHandler) resolveModelPricing(ctx context.Context, modelID, locale string, pricingCache map[string]tokenPricing) (tokenPricing, error) {
+func (h *TokenUsageHandler) resolveModelPricing(ctx context.Context, modelID, locale string, pricingCache map[string]pricing.Pricing) (pricing.Pricing, error) {
 	if modelID == "" {
-		return tokenPricing{}, nil
+		return pricing.Pricing{}, nil
 	}
-	if pricing, ok := pricingCache[modelID]; ok {
-		return pricing, nil
+	if cached, ok := pricingCache[modelID]; ok {
+		return cached, nil
 	}
 	model, err := h.resolveModel(ctx, modelID, locale)
 	if err != nil {
-		pricingCache[modelID] = tokenPricing{}
-		return tokenPricing{}, err
+		pricingCache[modelID] = pricing.Pricing{}
+		return pricing.Pricing{}, err
 	}
-	pricing := extractPricingFromModel(model)
-	pricingCache[modelID] = pricing
-	return pricing, nil
+	modelPricing := pricing.FromModel(model)
+	pricingCache[modelID] = modelPricing
+	return modelPricing, nil
 }
 
 func (h *TokenUsageHandler) resolveModel(ctx context.Context, modelID, locale string) (*modelpb.Model, error) {
@@ -139,174 +126,3 @@ func (h *TokenUsageHandler) resolveModel(ctx context.Context, modelID, locale st
 	}
 	return model, nil
 }
-
-func calculateUsagePrice(usage *usagepb.TokenUsage, pricing tokenPricing) (float64, bool) {
-	price := pricing.Request
-	if usage == nil {
-		return price, false
-	}
-
-	inputTokens := usage.InputTokens
-	outputTokens := usage.OutputTokens
-	cachedInputTokens, hasCachedInput := extractCachedInputTokens(usage.UsageDetails)
-	if cachedInputTokens > inputTokens {
-		cachedInputTokens = inputTokens
-	}
-
-	regularInputTokens := inputTokens
-	if hasCachedInput && cachedInputTokens > 0 {
-		regularInputTokens -= cachedInputTokens
-	}
-
-	if pricing.Input != 0 && regularInputTokens > 0 {
-		price += float64(regularInputTokens) * pricing.Input
-	}
-
-	isEstimated := false
-	if hasCachedInput && cachedInputTokens > 0 {
-		cacheReadPrice := pricing.InputCacheRead
-		if !pricing.HasInputCacheRead {
-			cacheReadPrice = pricing.Input / 5
-			isEstimated = true
-		}
-		if cacheReadPrice != 0 {
-			price += float64(cachedInputTokens) * cacheReadPrice
-		}
-	}
-
-	if pricing.Output != 0 && outputTokens > 0 {
-		price += float64(outputTokens) * pricing.Output
-	}
-	return price, isEstimated
-}
-
-func extractPricingFromModel(model *modelpb.Model) tokenPricing {
-	if model == nil || model.Metadata == nil {
-		return tokenPricing{}
-	}
-	meta := metadata.FromProtobuf(model.Metadata)
-	rawPricing, ok := meta.Public["pricing"]
-	if !ok {
-		return tokenPricing{}
-	}
-	pricingMap, ok := rawPricing.(map[string]any)
-	if !ok {
-		return tokenPricing{}
-	}
-
-	normalized := make(map[string]any, len(pricingMap))
-	for k, v := range pricingMap {
-		normalized[strings.ToLower(k)] = v
-	}
-
-	currency := stringValue(normalized["unit"])
-	promptPrice, _ := parsePriceValue(normalized["prompt"])
-	completionPrice, _ := parsePriceValue(normalized["completion"])
-	requestPrice, _ := parsePriceValue(normalized["request"])
-	cacheReadPrice, hasCacheReadPrice := parsePriceValue(normalized["input_cache_read"])
-
-	return tokenPricing{
-		Currency:          currency,
-		Input:             promptPrice,
-		Output:            completionPrice,
-		Request:           requestPrice,
-		InputCacheRead:    cacheReadPrice,
-		HasInputCacheRead: hasCacheReadPrice,
-	}
-}
-
-func extractCachedInputTokens(usageDetails string) (uint64, bool) {
-	usageDetails = strings.TrimSpace(usageDetails)
-	if usageDetails == "" || usageDetails == "{}" {
-		return 0, false
-	}
-
-	var payload any
-	if err := json.Unmarshal([]byte(usageDetails), &payload); err != nil {
-		return 0, false
-	}
-	return findCachedInputTokens(payload)
-}
-
-func findCachedInputTokens(v any) (uint64, bool) {
-	switch val := v.(type) {
-	case map[string]any:
-		if cached, ok := getNestedUint(val, "input_tokens_details", "cached_tokens"); ok {
-			return cached, true
-		}
-		if cached, ok := getNestedUint(val, "prompt_tokens_details", "cached_tokens"); ok {
-			return cached, true
-		}
-		for _, nested := range val {
-			if cached, ok := findCachedInputTokens(nested); ok {
-				return cached, true
-			}
-		}
-	case []any:
-		for _, item := range val {
-			if cached, ok := findCachedInputTokens(item); ok {
-				return cached, true
-			}
-		}
-	}
-	return 0, false
-}
-
-func getNestedUint(m map[string]any, key, nestedKey string) (uint64, bool) {
-	if m == nil {
-		return 0, false
-	}
-	nested, ok := m[key].(map[string]any)
-	if !ok {
-		return 0, false
-	}
-	return valueutil.GetUint64(nested[nestedKey])
-}
-
-func parsePriceValue(value any) (float64, bool) {
-	switch v := value.(type) {
-	case float64:
-		return v, true
-	case float32:
-		return float64(v), true
-	case int:
-		return float64(v), true
-	case int32:
-		return float64(v), true
-	case int64:
-		return float64(v), true
-	case uint:
-		return float64(v), true
-	case uint32:
-		return float64(v), true
-	case uint64:
-		return float64(v), true
-	case json.Number:
-		f, err := v.Float64()
-		if err != nil {
-			return 0, false
-		}
-		return f, true
-	case string:
-		if v == "" {
-			return 0, false
-		}
-		f, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return 0, false
-		}
-		return f, true
-	default:
-		return 0, false
-	}
-}
-
-func stringValue(v any) string {
-	if v == nil {
-		return ""
-	}
-	if str, ok := v.(string); ok {
-		return str
-	}
-	return ""
-}
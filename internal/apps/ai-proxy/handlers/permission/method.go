@@ -25,6 +25,8 @@ import (
 	mcppb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_server/pb"
 	mcipb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_server_config_instance/pb"
 	mtpb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_server_template/pb"
+	mtcpb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_tool_call/pb"
+	mtppb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_tool_policy/pb"
 	modelpb "github.com/erda-project/erda-proto-go/apps/aiproxy/model/pb"
 	policypb "github.com/erda-project/erda-proto-go/apps/aiproxy/policy_group/pb"
 	promptpb "github.com/erda-project/erda-proto-go/apps/aiproxy/prompt/pb"
@@ -75,6 +77,11 @@ var CheckPromptPerm = CheckPermissions(
 	&MethodPermission{Method: promptpb.PromptServiceServer.Update, LoggedIn: true},
 	&MethodPermission{Method: promptpb.PromptServiceServer.Delete, LoggedIn: true},
 	&MethodPermission{Method: promptpb.PromptServiceServer.Paging, LoggedIn: true},
+	&MethodPermission{Method: promptpb.PromptServiceServer.CreateVersion, AdminOrClient: true},
+	&MethodPermission{Method: promptpb.PromptServiceServer.GetVersion, LoggedIn: true},
+	&MethodPermission{Method: promptpb.PromptServiceServer.ListVersions, LoggedIn: true},
+	&MethodPermission{Method: promptpb.PromptServiceServer.PublishVersion, AdminOrClient: true},
+	&MethodPermission{Method: promptpb.PromptServiceServer.SetExperiment, AdminOrClient: true},
 )
 
 var CheckSessionPerm = CheckPermissions(
@@ -124,6 +131,17 @@ var CheckMcpConfigInstancePerm = CheckPermissions(
 	&MethodPermission{Method: mcipb.MCPServerConfigInstanceServiceServer.Delete, AdminOrClient: true},
 )
 
+var CheckMcpToolPolicyPerm = CheckPermissions(
+	&MethodPermission{Method: mtppb.MCPToolPolicyServiceServer.Set, OnlyAdmin: true},
+	&MethodPermission{Method: mtppb.MCPToolPolicyServiceServer.Get, AdminOrClient: true},
+	&MethodPermission{Method: mtppb.MCPToolPolicyServiceServer.Delete, OnlyAdmin: true},
+	&MethodPermission{Method: mtppb.MCPToolPolicyServiceServer.List, AdminOrClient: true},
+)
+
+var CheckMcpToolCallPerm = CheckPermissions(
+	&MethodPermission{Method: mtcpb.MCPToolCallServiceServer.Paging, AdminOrClient: true},
+)
+
 var CheckI18nPerm = CheckPermissions(
 	&MethodPermission{Method: i18npb.I18NServiceServer.Create, OnlyAdmin: true},
 	&MethodPermission{Method: i18npb.I18NServiceServer.Get, OnlyAdmin: true},
@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DBClient struct {
+	DB *gorm.DB
+}
+
+// ListOptions pages by cursor like the OpenAI list APIs: items created before the `After` item, newest first.
+type ListOptions struct {
+	ClientID string
+	Purpose  string
+	After    string
+	Limit    int
+}
+
+func (opt *ListOptions) normalize() {
+	if opt.Limit <= 0 {
+		opt.Limit = 20
+	}
+	if opt.Limit > 100 {
+		opt.Limit = 100
+	}
+}
+
+func (dbClient *DBClient) CreateFile(ctx context.Context, file *File) error {
+	return dbClient.DB.WithContext(ctx).Create(file).Error
+}
+
+// GetFile returns nil if the file is not found or not owned by the client.
+func (dbClient *DBClient) GetFile(ctx context.Context, clientID, id string) (*File, error) {
+	rec := &File{}
+	err := dbClient.DB.WithContext(ctx).Where("id = ? AND client_id = ?", id, clientID).First(rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// ListFiles returns one more item than the limit if there are more.
+func (dbClient *DBClient) ListFiles(ctx context.Context, opt ListOptions) (Files, error) {
+	opt.normalize()
+	sql := dbClient.DB.WithContext(ctx).Model(&File{}).Where("client_id = ?", opt.ClientID)
+	if opt.Purpose != "" {
+		sql = sql.Where("purpose = ?", opt.Purpose)
+	}
+	if opt.After != "" {
+		after, err := dbClient.GetFile(ctx, opt.ClientID, opt.After)
+		if err != nil {
+			return nil, err
+		}
+		if after != nil {
+			sql = sql.Where("(created_at < ? OR (created_at = ? AND id < ?))", after.CreatedAt, after.CreatedAt, after.ID)
+		}
+	}
+	var list Files
+	if err := sql.Order("created_at DESC, id DESC").Limit(opt.Limit + 1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (dbClient *DBClient) DeleteFile(ctx context.Context, clientID, id string) error {
+	return dbClient.DB.WithContext(ctx).Where("id = ? AND client_id = ?", id, clientID).Delete(&File{}).Error
+}
+
+func (dbClient *DBClient) CreateBatch(ctx context.Context, batch *Batch) error {
+	return dbClient.DB.WithContext(ctx).Create(batch).Error
+}
+
+// GetBatch returns nil if the batch is not found or not owned by the client.
+func (dbClient *DBClient) GetBatch(ctx context.Context, clientID, id string) (*Batch, error) {
+	rec := &Batch{}
+	err := dbClient.DB.WithContext(ctx).Where("id = ? AND client_id = ?", id, clientID).First(rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// GetBatchStatus is used by the executing worker to watch cancellation.
+func (dbClient *DBClient) GetBatchStatus(ctx context.Context, id string) (string, error) {
+	var status string
+	err := dbClient.DB.WithContext(ctx).Model(&Batch{}).Where("id = ?", id).Pluck("status", &status).Error
+	return status, err
+}
+
+// ListBatches returns one more item than the limit if there are more.
+func (dbClient *DBClient) ListBatches(ctx context.Context, opt ListOptions) (Batches, error) {
+	opt.normalize()
+	sql := dbClient.DB.WithContext(ctx).Model(&Batch{}).Where("client_id = ?", opt.ClientID)
+	if opt.After != "" {
+		after, err := dbClient.GetBatch(ctx, opt.ClientID, opt.After)
+		if err != nil {
+			return nil, err
+		}
+		if after != nil {
+			sql = sql.Where("(created_at < ? OR (created_at = ? AND id < ?))", after.CreatedAt, after.CreatedAt, after.ID)
+		}
+	}
+	var list Batches
+	if err := sql.Order("created_at DESC, id DESC").Limit(opt.Limit + 1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// CancelBatch moves a not yet finished batch to cancelling, the worker then stops it.
+// A batch not started yet is cancelled directly.
+func (dbClient *DBClient) CancelBatch(ctx context.Context, clientID, id string, now time.Time) error {
+	if err := dbClient.DB.WithContext(ctx).Model(&Batch{}).
+		Where("id = ? AND client_id = ? AND status = ?", id, clientID, StatusValidating).
+		Updates(map[string]any{"status": StatusCancelled, "cancelling_at": now, "cancelled_at": now}).Error; err != nil {
+		return err
+	}
+	return dbClient.DB.WithContext(ctx).Model(&Batch{}).
+		Where("id = ? AND client_id = ? AND status = ?", id, clientID, StatusInProgress).
+		Updates(map[string]any{"status": StatusCancelling, "cancelling_at": now}).Error
+}
+
+// ClaimBatch takes the oldest batch waiting for execution, or a running batch whose worker stopped heartbeating.
+// The claim is an optimistic update on the status and heartbeat, so only one replica wins.
+// A claimed cancelling batch keeps its status, the worker just finishes it.
+// Returns nil if there is nothing to claim.
+func (dbClient *DBClient) ClaimBatch(ctx context.Context, worker string, now time.Time, staleBefore time.Time) (*Batch, error) {
+	rec := &Batch{}
+	err := dbClient.DB.WithContext(ctx).
+		Where("status = ? OR (status IN ? AND heartbeat_at < ?)", StatusValidating, []string{StatusInProgress, StatusCancelling}, staleBefore).
+		Order("created_at ASC, id ASC").
+		First(rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sql := dbClient.DB.WithContext(ctx).Model(&Batch{}).Where("id = ? AND status = ?", rec.ID, rec.Status)
+	if rec.HeartbeatAt != nil {
+		sql = sql.Where("heartbeat_at = ?", *rec.HeartbeatAt)
+	} else {
+		sql = sql.Where("heartbeat_at IS NULL")
+	}
+	updates := map[string]any{
+		"worker":            worker,
+		"heartbeat_at":      now,
+		"request_completed": 0,
+		"request_failed":    0,
+	}
+	if rec.Status != StatusCancelling {
+		updates["status"] = StatusInProgress
+	}
+	if rec.InProgressAt == nil {
+		updates["in_progress_at"] = now
+	}
+	result := sql.Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return dbClient.GetBatch(ctx, rec.ClientID, rec.ID)
+}
+
+// UpdateProgress refreshes the request counts and the heartbeat of a batch owned by the worker.
+func (dbClient *DBClient) UpdateProgress(ctx context.Context, id, worker string, total, completed, failed int64, now time.Time) error {
+	return dbClient.DB.WithContext(ctx).Model(&Batch{}).
+		Where("id = ? AND worker = ? AND status IN ?", id, worker, []string{StatusInProgress, StatusCancelling}).
+		Updates(map[string]any{
+			"request_total":     total,
+			"request_completed": completed,
+			"request_failed":    failed,
+			"heartbeat_at":      now,
+		}).Error
+}
+
+// UpdateBatch updates the given columns of a batch owned by the worker.
+func (dbClient *DBClient) UpdateBatch(ctx context.Context, id, worker string, updates map[string]any) error {
+	return dbClient.DB.WithContext(ctx).Model(&Batch{}).Where("id = ? AND worker = ?", id, worker).Updates(updates).Error
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDBClient(t *testing.T) *DBClient {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, prepareSQLiteBatchTables(db))
+	return &DBClient{DB: db}
+}
+
+func prepareSQLiteBatchTables(db *gorm.DB) error {
+	if err := db.Exec(`
+CREATE TABLE ai_proxy_batch_file (
+	id VARCHAR(64) PRIMARY KEY,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	deleted_at DATETIME NULL,
+	client_id CHAR(36) NOT NULL,
+	client_token_id CHAR(36) NOT NULL DEFAULT '',
+	filename VARCHAR(255) NOT NULL DEFAULT '',
+	purpose VARCHAR(32) NOT NULL,
+	bytes BIGINT NOT NULL DEFAULT 0,
+	storage_key VARCHAR(255) NOT NULL DEFAULT ''
+);`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`
+CREATE TABLE ai_proxy_batch (
+	id VARCHAR(64) PRIMARY KEY,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	client_id CHAR(36) NOT NULL,
+	client_token_id CHAR(36) NOT NULL DEFAULT '',
+	endpoint VARCHAR(128) NOT NULL DEFAULT '',
+	completion_window VARCHAR(16) NOT NULL DEFAULT '',
+	input_file_id VARCHAR(64) NOT NULL DEFAULT '',
+	output_file_id VARCHAR(64) NOT NULL DEFAULT '',
+	error_file_id VARCHAR(64) NOT NULL DEFAULT '',
+	status VARCHAR(32) NOT NULL,
+	request_total INTEGER NOT NULL DEFAULT 0,
+	request_completed INTEGER NOT NULL DEFAULT 0,
+	request_failed INTEGER NOT NULL DEFAULT 0,
+	errors TEXT NULL,
+	metadata TEXT NULL,
+	worker VARCHAR(191) NOT NULL DEFAULT '',
+	heartbeat_at DATETIME NULL,
+	expires_at DATETIME NOT NULL,
+	in_progress_at DATETIME NULL,
+	finalizing_at DATETIME NULL,
+	completed_at DATETIME NULL,
+	failed_at DATETIME NULL,
+	expired_at DATETIME NULL,
+	cancelling_at DATETIME NULL,
+	cancelled_at DATETIME NULL
+);`).Error
+}
+
+func TestDBClient_ListFiles(t *testing.T) {
+	client := newTestDBClient(t)
+	ctx := context.Background()
+	base := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"f1", "f2", "f3"} {
+		require.NoError(t, client.CreateFile(ctx, &File{ID: id, ClientID: "c1", Purpose: FilePurposeBatch, CreatedAt: base.Add(time.Duration(i) * time.Second)}))
+	}
+	require.NoError(t, client.CreateFile(ctx, &File{ID: "other", ClientID: "c2", Purpose: FilePurposeBatch, CreatedAt: base}))
+
+	// newest first, one more than the limit to tell whether there are more
+	files, err := client.ListFiles(ctx, ListOptions{ClientID: "c1", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, "f3", files[0].ID)
+
+	files, err = client.ListFiles(ctx, ListOptions{ClientID: "c1", After: "f2", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "f1", files[0].ID)
+
+	require.NoError(t, client.DeleteFile(ctx, "c1", "f1"))
+	f, err := client.GetFile(ctx, "c1", "f1")
+	require.NoError(t, err)
+	require.Nil(t, f)
+	f, err = client.GetFile(ctx, "c1", "other")
+	require.NoError(t, err)
+	require.Nil(t, f, "files of other clients are invisible")
+}
+
+func TestDBClient_CancelBatch(t *testing.T) {
+	client := newTestDBClient(t)
+	ctx := context.Background()
+	now := time.Now()
+	require.NoError(t, client.CreateBatch(ctx, &Batch{ID: "b1", ClientID: "c1", Status: StatusValidating}))
+	require.NoError(t, client.CreateBatch(ctx, &Batch{ID: "b2", ClientID: "c1", Status: StatusInProgress}))
+	require.NoError(t, client.CreateBatch(ctx, &Batch{ID: "b3", ClientID: "c1", Status: StatusCompleted}))
+
+	for id, want := range map[string]string{"b1": StatusCancelled, "b2": StatusCancelling, "b3": StatusCompleted} {
+		require.NoError(t, client.CancelBatch(ctx, "c1", id, now))
+		b, err := client.GetBatch(ctx, "c1", id)
+		require.NoError(t, err)
+		require.Equal(t, want, b.Status, id)
+	}
+}
+
+func TestDBClient_ClaimBatch(t *testing.T) {
+	client := newTestDBClient(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	fresh := now.Add(-time.Second)
+	stale := now.Add(-time.Hour)
+	require.NoError(t, client.CreateBatch(ctx, &Batch{ID: "running", ClientID: "c1", Status: StatusInProgress, Worker: "w0", HeartbeatAt: &fresh, CreatedAt: now.Add(-3 * time.Minute)}))
+	require.NoError(t, client.CreateBatch(ctx, &Batch{ID: "stale", ClientID: "c1", Status: StatusCancelling, Worker: "w0", HeartbeatAt: &stale, CreatedAt: now.Add(-2 * time.Minute)}))
+	require.NoError(t, client.CreateBatch(ctx, &Batch{ID: "new", ClientID: "c1", Status: StatusValidating, CreatedAt: now.Add(-time.Minute)}))
+
+	staleBefore := now.Add(-5 * time.Minute)
+	b, err := client.ClaimBatch(ctx, "w1", now, staleBefore)
+	require.NoError(t, err)
+	require.NotNil(t, b)
+	require.Equal(t, "stale", b.ID)
+	require.Equal(t, StatusCancelling, b.Status, "a cancelling batch keeps its status")
+	require.Equal(t, "w1", b.Worker)
+
+	b, err = client.ClaimBatch(ctx, "w2", now, staleBefore)
+	require.NoError(t, err)
+	require.NotNil(t, b)
+	require.Equal(t, "new", b.ID)
+	require.Equal(t, StatusInProgress, b.Status)
+	require.NotNil(t, b.InProgressAt)
+
+	b, err = client.ClaimBatch(ctx, "w3", now, staleBefore)
+	require.NoError(t, err)
+	require.Nil(t, b, "a batch with fresh heartbeat is not taken over")
+
+	// only the owner can update
+	require.NoError(t, client.UpdateBatch(ctx, "new", "w1", map[string]any{"status": StatusFailed}))
+	status, err := client.GetBatchStatus(ctx, "new")
+	require.NoError(t, err)
+	require.Equal(t, StatusInProgress, status)
+}
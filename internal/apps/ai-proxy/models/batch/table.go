@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	FilePurposeBatch       = "batch"
+	FilePurposeBatchOutput = "batch_output"
+)
+
+// Batch statuses, see https://platform.openai.com/docs/api-reference/batch/object
+const (
+	StatusValidating = "validating"
+	StatusFailed     = "failed"
+	StatusInProgress = "in_progress"
+	StatusFinalizing = "finalizing"
+	StatusCompleted  = "completed"
+	StatusExpired    = "expired"
+	StatusCancelling = "cancelling"
+	StatusCancelled  = "cancelled"
+)
+
+// File is a file uploaded to or produced by ai-proxy itself, the content is kept in storage by StorageKey.
+type File struct {
+	ID            string         `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	CreatedAt     time.Time      `gorm:"column:created_at;type:datetime(3)" json:"createdAt"`
+	UpdatedAt     time.Time      `gorm:"column:updated_at;type:datetime(3)" json:"updatedAt"`
+	DeletedAt     gorm.DeletedAt `gorm:"column:deleted_at;type:datetime(3)" json:"deletedAt"`
+	ClientID      string         `gorm:"column:client_id;type:char(36)" json:"clientID"`
+	ClientTokenID string         `gorm:"column:client_token_id;type:char(36)" json:"clientTokenID"`
+	Filename      string         `gorm:"column:filename;type:varchar(255)" json:"filename"`
+	Purpose       string         `gorm:"column:purpose;type:varchar(32)" json:"purpose"`
+	Bytes         int64          `gorm:"column:bytes;type:bigint(20)" json:"bytes"`
+	StorageKey    string         `gorm:"column:storage_key;type:varchar(255)" json:"storageKey"`
+}
+
+func (*File) TableName() string { return "ai_proxy_batch_file" }
+
+type Files []*File
+
+// Batch is a batch executed by ai-proxy itself.
+type Batch struct {
+	ID               string    `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	CreatedAt        time.Time `gorm:"column:created_at;type:datetime(3)" json:"createdAt"`
+	UpdatedAt        time.Time `gorm:"column:updated_at;type:datetime(3)" json:"updatedAt"`
+	ClientID         string    `gorm:"column:client_id;type:char(36)" json:"clientID"`
+	ClientTokenID    string    `gorm:"column:client_token_id;type:char(36)" json:"clientTokenID"`
+	Endpoint         string    `gorm:"column:endpoint;type:varchar(128)" json:"endpoint"`
+	CompletionWindow string    `gorm:"column:completion_window;type:varchar(16)" json:"completionWindow"`
+	InputFileID      string    `gorm:"column:input_file_id;type:varchar(64)" json:"inputFileID"`
+	OutputFileID     string    `gorm:"column:output_file_id;type:varchar(64)" json:"outputFileID"`
+	ErrorFileID      string    `gorm:"column:error_file_id;type:varchar(64)" json:"errorFileID"`
+	Status           string    `gorm:"column:status;type:varchar(32)" json:"status"`
+	RequestTotal     int64     `gorm:"column:request_total;type:int(11)" json:"requestTotal"`
+	RequestCompleted int64     `gorm:"column:request_completed;type:int(11)" json:"requestCompleted"`
+	RequestFailed    int64     `gorm:"column:request_failed;type:int(11)" json:"requestFailed"`
+	// Errors is a JSON array of batch-level errors, e.g. invalid input lines.
+	Errors   string `gorm:"column:errors;type:text" json:"errors"`
+	Metadata string `gorm:"column:metadata;type:text" json:"metadata"`
+	// Worker is the replica executing the batch, HeartbeatAt is refreshed while executing.
+	Worker      string     `gorm:"column:worker;type:varchar(191)" json:"worker"`
+	HeartbeatAt *time.Time `gorm:"column:heartbeat_at;type:datetime(3)" json:"heartbeatAt"`
+
+	ExpiresAt    time.Time  `gorm:"column:expires_at;type:datetime(3)" json:"expiresAt"`
+	InProgressAt *time.Time `gorm:"column:in_progress_at;type:datetime(3)" json:"inProgressAt"`
+	FinalizingAt *time.Time `gorm:"column:finalizing_at;type:datetime(3)" json:"finalizingAt"`
+	CompletedAt  *time.Time `gorm:"column:completed_at;type:datetime(3)" json:"completedAt"`
+	FailedAt     *time.Time `gorm:"column:failed_at;type:datetime(3)" json:"failedAt"`
+	ExpiredAt    *time.Time `gorm:"column:expired_at;type:datetime(3)" json:"expiredAt"`
+	CancellingAt *time.Time `gorm:"column:cancelling_at;type:datetime(3)" json:"cancellingAt"`
+	CancelledAt  *time.Time `gorm:"column:cancelled_at;type:datetime(3)" json:"cancelledAt"`
+}
+
+func (*Batch) TableName() string { return "ai_proxy_batch" }
+
+type Batches []*Batch
+
+// IsTerminal reports whether the batch won't change anymore.
+func (b *Batch) IsTerminal() bool {
+	switch b.Status {
+	case StatusFailed, StatusCompleted, StatusExpired, StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
@@ -89,6 +89,15 @@ func (dbClient *DBClient) Get(ctx context.Context, req *pb.ClientTokenGetRequest
 	return c.ToProtobuf(), nil
 }
 
+// GetByID is used by background jobs which only keep the id of the token.
+func (dbClient *DBClient) GetByID(ctx context.Context, id string) (*pb.ClientToken, error) {
+	c := &ClientToken{BaseModel: common.BaseModelWithID(id)}
+	if err := dbClient.DB.Model(c).First(c).Error; err != nil {
+		return nil, err
+	}
+	return c.ToProtobuf(), nil
+}
+
 func (dbClient *DBClient) Delete(ctx context.Context, req *pb.ClientTokenDeleteRequest) (*commonpb.VoidResponse, error) {
 	tokenResp, err := dbClient.Get(ctx, &pb.ClientTokenGetRequest{ClientId: req.ClientId, Token: req.Token})
 	if err != nil {
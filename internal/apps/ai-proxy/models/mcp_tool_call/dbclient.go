@@ -0,0 +1,136 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp_tool_call
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const statusPending = "pending"
+
+type DBClient struct {
+	db *gorm.DB
+}
+
+func NewDBClient(db *gorm.DB) *DBClient {
+	return &DBClient{db}
+}
+
+func (c *DBClient) Create(ctx context.Context, model *McpToolCall) error {
+	return c.db.WithContext(ctx).Model(&McpToolCall{}).Create(model).Error
+}
+
+// Finish completes a pending tool call, calls already finished are left as they are.
+func (c *DBClient) Finish(ctx context.Context, id, status, errMsg string, resultBytes int64) error {
+	var call McpToolCall
+	if err := c.db.WithContext(ctx).Model(&McpToolCall{}).Where("id = ?", id).First(&call).Error; err != nil {
+		return err
+	}
+	return c.finish(ctx, &call, status, errMsg, resultBytes)
+}
+
+// FinishBySession completes the pending tool call of a mcp session by the JSON-RPC id.
+// It's used for sse transport, as results are sent in the event stream rather than the response of calls.
+// Returns false if there is no such pending call.
+func (c *DBClient) FinishBySession(ctx context.Context, mcpSessionID, rpcID, status, errMsg string, resultBytes int64) (bool, error) {
+	var call McpToolCall
+	err := c.db.WithContext(ctx).Model(&McpToolCall{}).
+		Where("mcp_session_id = ?", mcpSessionID).
+		Where("rpc_id = ?", rpcID).
+		Where("status = ?", statusPending).
+		Order("created_at DESC").
+		First(&call).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, c.finish(ctx, &call, status, errMsg, resultBytes)
+}
+
+func (c *DBClient) finish(ctx context.Context, call *McpToolCall, status, errMsg string, resultBytes int64) error {
+	if call.Status != statusPending {
+		return nil
+	}
+	now := time.Now()
+	return c.db.WithContext(ctx).Model(&McpToolCall{}).
+		Where("id = ?", call.ID.String).
+		Where("status = ?", statusPending).
+		Updates(map[string]any{
+			"status":       status,
+			"error":        errMsg,
+			"result_bytes": resultBytes,
+			"latency_ms":   now.Sub(call.CreatedAt).Milliseconds(),
+			"finished_at":  now,
+		}).Error
+}
+
+type ListOptions struct {
+	ClientId  *string
+	McpName   string
+	ToolName  string
+	Status    string
+	StartTime time.Time
+	EndTime   time.Time
+	PageNum   int
+	PageSize  int
+}
+
+func (c *DBClient) Paging(ctx context.Context, options *ListOptions) ([]*McpToolCall, int64, error) {
+	var (
+		pageNum  = options.PageNum
+		pageSize = options.PageSize
+		calls    []*McpToolCall
+		total    int64
+	)
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+
+	tx := c.db.WithContext(ctx).Model(&McpToolCall{})
+	if options.ClientId != nil {
+		tx = tx.Where("client_id = ?", *options.ClientId)
+	}
+	if options.McpName != "" {
+		tx = tx.Where("mcp_name = ?", options.McpName)
+	}
+	if options.ToolName != "" {
+		tx = tx.Where("tool_name = ?", options.ToolName)
+	}
+	if options.Status != "" {
+		tx = tx.Where("status = ?", options.Status)
+	}
+	if !options.StartTime.IsZero() {
+		tx = tx.Where("created_at >= ?", options.StartTime)
+	}
+	if !options.EndTime.IsZero() {
+		tx = tx.Where("created_at < ?", options.EndTime)
+	}
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := tx.Order("created_at DESC").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&calls).Error; err != nil {
+		return nil, 0, err
+	}
+	return calls, total, nil
+}
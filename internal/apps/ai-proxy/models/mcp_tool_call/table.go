@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp_tool_call
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_tool_call/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/common"
+)
+
+type McpToolCall struct {
+	common.BaseModel
+	ClientID      string     `gorm:"column:client_id;type:char(36)" json:"client_id"`
+	ClientTokenID string     `gorm:"column:client_token_id;type:char(36)" json:"client_token_id"`
+	McpName       string     `gorm:"column:mcp_name;type:varchar(255);not null" json:"mcp_name"`
+	McpVersion    string     `gorm:"column:mcp_version;type:varchar(128);not null" json:"mcp_version"`
+	McpSessionID  string     `gorm:"column:mcp_session_id;type:varchar(191)" json:"mcp_session_id"`
+	RpcID         string     `gorm:"column:rpc_id;type:varchar(191)" json:"rpc_id"`
+	ToolName      string     `gorm:"column:tool_name;type:varchar(191);not null" json:"tool_name"`
+	Arguments     string     `gorm:"column:arguments;type:mediumtext" json:"arguments"`
+	Status        string     `gorm:"column:status;type:varchar(32);not null" json:"status"`
+	Error         string     `gorm:"column:error;type:text" json:"error"`
+	LatencyMs     int64      `gorm:"column:latency_ms;type:bigint" json:"latency_ms"`
+	ResultBytes   int64      `gorm:"column:result_bytes;type:bigint" json:"result_bytes"`
+	FinishedAt    *time.Time `gorm:"column:finished_at;type:datetime" json:"finished_at"`
+}
+
+func (*McpToolCall) TableName() string {
+	return "ai_proxy_mcp_tool_call"
+}
+
+func (m *McpToolCall) ToProtobuf() *pb.MCPToolCall {
+	call := &pb.MCPToolCall{
+		Id:            m.ID.String,
+		CreatedAt:     timestamppb.New(m.CreatedAt),
+		ClientId:      m.ClientID,
+		ClientTokenId: m.ClientTokenID,
+		McpName:       m.McpName,
+		McpVersion:    m.McpVersion,
+		McpSessionId:  m.McpSessionID,
+		RpcId:         m.RpcID,
+		ToolName:      m.ToolName,
+		Arguments:     m.Arguments,
+		Status:        m.Status,
+		Error:         m.Error,
+		LatencyMs:     m.LatencyMs,
+		ResultBytes:   m.ResultBytes,
+	}
+	if m.FinishedAt != nil {
+		call.FinishedAt = timestamppb.New(*m.FinishedAt)
+	}
+	return call
+}
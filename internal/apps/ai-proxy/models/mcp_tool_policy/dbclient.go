@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp_tool_policy
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type DBClient struct {
+	db *gorm.DB
+}
+
+func NewDBClient(db *gorm.DB) *DBClient {
+	return &DBClient{db}
+}
+
+func (c *DBClient) Get(ctx context.Context, clientId, mcpName string) (*McpToolPolicy, error) {
+	var policy McpToolPolicy
+	if err := c.db.WithContext(ctx).Model(&McpToolPolicy{}).
+		Where("client_id = ?", clientId).
+		Where("mcp_name = ?", mcpName).
+		First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Find returns nil if the client has no policy of the mcp server.
+func (c *DBClient) Find(ctx context.Context, clientId, mcpName string) (*McpToolPolicy, error) {
+	policy, err := c.Get(ctx, clientId, mcpName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return policy, err
+}
+
+func (c *DBClient) CreateOrUpdate(ctx context.Context, model *McpToolPolicy) (*McpToolPolicy, error) {
+	exist, err := c.Find(ctx, model.ClientID, model.McpName)
+	if err != nil {
+		return nil, err
+	}
+	if exist == nil {
+		if err := c.db.WithContext(ctx).Model(&McpToolPolicy{}).Create(model).Error; err != nil {
+			return nil, err
+		}
+		return model, nil
+	}
+	if err := c.db.WithContext(ctx).Model(exist).
+		Select("allow_tools", "deny_tools", "validate_arguments").
+		Updates(&McpToolPolicy{
+			AllowTools:        model.AllowTools,
+			DenyTools:         model.DenyTools,
+			ValidateArguments: model.ValidateArguments,
+		}).Error; err != nil {
+		return nil, err
+	}
+	return c.Get(ctx, model.ClientID, model.McpName)
+}
+
+func (c *DBClient) Delete(ctx context.Context, clientId, mcpName string) error {
+	sql := c.db.WithContext(ctx).
+		Where("client_id = ?", clientId).
+		Where("mcp_name = ?", mcpName).
+		Delete(&McpToolPolicy{})
+	if sql.Error != nil {
+		return sql.Error
+	}
+	if sql.RowsAffected < 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+type ListOptions struct {
+	ClientId *string
+	PageNum  int
+	PageSize int
+}
+
+func (c *DBClient) List(ctx context.Context, options *ListOptions) ([]*McpToolPolicy, int64, error) {
+	var (
+		pageNum  = options.PageNum
+		pageSize = options.PageSize
+		policies []*McpToolPolicy
+		total    int64
+	)
+
+	tx := c.db.WithContext(ctx).Model(&McpToolPolicy{})
+	if options.ClientId != nil {
+		tx = tx.Where("client_id = ?", *options.ClientId)
+	}
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if pageNum > 0 && pageSize > 0 {
+		tx = tx.Offset((pageNum - 1) * pageSize).Limit(pageSize)
+	}
+	if err := tx.Order("client_id, mcp_name").Find(&policies).Error; err != nil {
+		return nil, 0, err
+	}
+	return policies, total, nil
+}
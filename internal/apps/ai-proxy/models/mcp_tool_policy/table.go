@@ -0,0 +1,48 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp_tool_policy
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_tool_policy/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/common"
+)
+
+type McpToolPolicy struct {
+	common.BaseModel
+	ClientID          string   `gorm:"column:client_id;type:char(36);not null" json:"client_id"`
+	McpName           string   `gorm:"column:mcp_name;type:varchar(255);not null" json:"mcp_name"`
+	AllowTools        []string `gorm:"column:allow_tools;type:json;serializer:json" json:"allow_tools"`
+	DenyTools         []string `gorm:"column:deny_tools;type:json;serializer:json" json:"deny_tools"`
+	ValidateArguments bool     `gorm:"column:validate_arguments;type:boolean;not null;default:false" json:"validate_arguments"`
+}
+
+func (*McpToolPolicy) TableName() string {
+	return "ai_proxy_mcp_tool_policy"
+}
+
+func (m *McpToolPolicy) ToProtobuf() *pb.MCPToolPolicy {
+	return &pb.MCPToolPolicy{
+		Id:                m.ID.String,
+		CreatedAt:         timestamppb.New(m.CreatedAt),
+		UpdatedAt:         timestamppb.New(m.UpdatedAt),
+		ClientId:          m.ClientID,
+		McpName:           m.McpName,
+		AllowTools:        m.AllowTools,
+		DenyTools:         m.DenyTools,
+		ValidateArguments: m.ValidateArguments,
+	}
+}
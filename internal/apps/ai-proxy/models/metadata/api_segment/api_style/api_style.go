@@ -28,11 +28,17 @@ const (
 
 	// see: https://docs.cloud.google.com/vertex-ai/generative-ai/docs/start/quickstart
 	APIStyleGoogleVertexAI APIStyle = "Google-Vertex-AI"
+
+	// see: https://ai.google.dev/api/generate-content
+	APIStyleGoogleGemini APIStyle = "Google-Gemini"
+
+	// see: https://github.com/ollama/ollama/blob/main/docs/api.md
+	APIStyleOllama APIStyle = "Ollama"
 )
 
 func (s APIStyle) IsValid() bool {
 	switch s {
-	case APIStyleOpenAICompatible, APIStyleAnthropicCompatible, APIStyleGoogleVertexAI:
+	case APIStyleOpenAICompatible, APIStyleAnthropicCompatible, APIStyleGoogleVertexAI, APIStyleGoogleGemini, APIStyleOllama:
 		return true
 	default:
 		return false
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PublicKeyBudgets is the key of budgets in public metadata of client and client token.
+const PublicKeyBudgets = "budgets"
+
+type BudgetPeriod string
+
+const (
+	BudgetPeriodDaily   BudgetPeriod = "daily"
+	BudgetPeriodMonthly BudgetPeriod = "monthly"
+)
+
+type (
+	// Budget limits the spend of a client or client token in a period.
+	Budget struct {
+		Name     string       `json:"name,omitempty"`
+		Period   BudgetPeriod `json:"period"`
+		Amount   float64      `json:"amount"`
+		Currency string       `json:"currency"`
+		// TimeZone is the IANA name used to split periods, server local time if empty.
+		TimeZone string `json:"time_zone,omitempty"`
+		// SoftThreshold is a ratio of Amount in (0, 1], a notification is sent when spend crosses it.
+		// It is omitted or 0 when no soft notification is wanted.
+		SoftThreshold float64 `json:"soft_threshold,omitempty"`
+		// HardCap rejects calls once Amount is spent.
+		HardCap bool          `json:"hard_cap,omitempty"`
+		Notify  *BudgetNotify `json:"notify,omitempty"`
+	}
+	BudgetNotify struct {
+		DingTalkWebhooks []string `json:"dingtalk_webhooks,omitempty"`
+		HTTPWebhooks     []string `json:"http_webhooks,omitempty"`
+	}
+)
+
+func (b *Budget) Validate() error {
+	switch b.Period {
+	case BudgetPeriodDaily, BudgetPeriodMonthly:
+	default:
+		return fmt.Errorf("invalid budget period: %q", b.Period)
+	}
+	if b.Amount <= 0 {
+		return fmt.Errorf("budget amount must be positive")
+	}
+	if b.Currency == "" {
+		return fmt.Errorf("budget currency is required")
+	}
+	if b.SoftThreshold < 0 || b.SoftThreshold > 1 {
+		return fmt.Errorf("budget soft_threshold must be in (0, 1], or 0 to disable it")
+	}
+	return nil
+}
+
+// GetBudgets parses budgets from public metadata.
+func (m *Metadata) GetBudgets() ([]Budget, error) {
+	if m == nil || m.Public == nil {
+		return nil, nil
+	}
+	raw, ok := m.Public[PublicKeyBudgets]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal budgets: %v", err)
+	}
+	var budgets []Budget
+	if err := json.Unmarshal(b, &budgets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal budgets: %v", err)
+	}
+	for i := range budgets {
+		if err := budgets[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid budget at index %d: %v", i, err)
+		}
+	}
+	return budgets, nil
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -34,6 +35,9 @@ type DBClient struct {
 }
 
 func (db *DBClient) Create(ctx context.Context, req *pb.PolicyGroupCreateRequest) (*pb.PolicyGroup, error) {
+	if err := checkFallback(req.Fallback); err != nil {
+		return nil, err
+	}
 	pg := &PolicyGroup{
 		ClientID:  req.ClientId,
 		Name:      req.Name,
@@ -42,6 +46,7 @@ func (db *DBClient) Create(ctx context.Context, req *pb.PolicyGroupCreateRequest
 		StickyKey: req.StickyKey,
 		Branches:  req.Branches,
 		Source:    req.Source,
+		Fallback:  req.Fallback,
 	}
 	if err := db.DB.WithContext(ctx).Model(pg).Create(pg).Error; err != nil {
 		return nil, err
@@ -96,6 +101,17 @@ func (db *DBClient) Update(ctx context.Context, req *pb.PolicyGroupUpdateRequest
 		m["source"] = *req.Source
 		needUpdate = true
 	}
+	if req.Fallback != nil {
+		if err := checkFallback(req.Fallback); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(req.Fallback)
+		if err != nil {
+			return nil, err
+		}
+		m["fallback"] = string(b)
+		needUpdate = true
+	}
 	if !needUpdate {
 		return nil, fmt.Errorf("nothing need update")
 	}
@@ -183,3 +199,13 @@ func checkBranches(branches []*pb.PolicyBranch) error {
 func checkBranch(branch *pb.PolicyBranch) error {
 	return nil
 }
+
+func checkFallback(fallback *pb.PolicyFallback) error {
+	if fallback == nil || fallback.Deadline == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(fallback.Deadline); err != nil || d < 0 {
+		return fmt.Errorf("invalid fallback deadline: %q", fallback.Deadline)
+	}
+	return nil
+}
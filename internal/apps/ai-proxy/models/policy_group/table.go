@@ -33,6 +33,7 @@ type PolicyGroup struct {
 	StickyKey string                       `gorm:"column:sticky_key;type:varchar(191)" json:"stickyKey" yaml:"stickyKey"`
 	Branches  []*pb.PolicyBranch           `gorm:"column:branches;type:json;serializer:json" json:"branches" yaml:"branches"`
 	Source    string                       `gorm:"column:source;type:varchar(191)" json:"source,omitempty" yaml:"source,omitempty"`
+	Fallback  *pb.PolicyFallback           `gorm:"column:fallback;type:json;serializer:json" json:"fallback,omitempty" yaml:"fallback,omitempty"`
 }
 
 func (*PolicyGroup) TableName() string { return "ai_proxy_policy_group" }
@@ -50,5 +51,6 @@ func (pg *PolicyGroup) ToProtobuf() *pb.PolicyGroup {
 		StickyKey: pg.StickyKey,
 		Branches:  pg.Branches,
 		Source:    pg.Source,
+		Fallback:  pg.Fallback,
 	}
 }
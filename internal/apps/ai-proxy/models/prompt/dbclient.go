@@ -16,11 +16,14 @@ package prompt
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"gorm.io/gorm"
 
 	"github.com/erda-project/erda-proto-go/apps/aiproxy/prompt/pb"
 	commonpb "github.com/erda-project/erda-proto-go/common/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/prompt_template"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/client_model_relation"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/common"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/message"
@@ -52,8 +55,15 @@ func (dbClient *DBClient) Create(ctx context.Context, req *pb.PromptCreateReques
 		tx.Rollback()
 		return nil, err
 	}
+	// the first version is published at once
+	if _, err := txCreateVersion(tx, c, c.Messages, VariablesFromProtobuf(req.Variables), "", true); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 	// commit
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
 	return c.ToProtobuf(), nil
 }
 
@@ -130,3 +140,172 @@ func (dbClient *DBClient) Paging(ctx context.Context, req *pb.PromptPagingReques
 		List:  list.ToProtobuf(),
 	}, nil
 }
+
+// GetByName gets the prompt of the client, or the system builtin one if the client has no prompt of the name.
+func (dbClient *DBClient) GetByName(ctx context.Context, clientID, name string) (*pb.Prompt, error) {
+	var c Prompt
+	if clientID != "" {
+		err := dbClient.DB.WithContext(ctx).Model(&c).Where("name = ? AND client_id = ?", name, clientID).First(&c).Error
+		if err == nil {
+			return c.ToProtobuf(), nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	if err := dbClient.DB.WithContext(ctx).Model(&c).Where(`name = ? AND (client_id IS NULL OR client_id = '')`, name).First(&c).Error; err != nil {
+		return nil, err
+	}
+	return c.ToProtobuf(), nil
+}
+
+func (dbClient *DBClient) CreateVersion(ctx context.Context, req *pb.PromptVersionCreateRequest) (*pb.PromptVersion, error) {
+	tx := dbClient.DB.Begin()
+	c, err := txGetOwnedPrompt(tx, req.PromptId, req.ClientId)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	msgs := message.FromProtobuf(req.Messages)
+	if len(msgs) == 0 {
+		msgs = c.Messages
+	}
+	v, err := txCreateVersion(tx, c, msgs, VariablesFromProtobuf(req.Variables), req.Changelog, req.Publish)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return v.ToProtobuf(), nil
+}
+
+func (dbClient *DBClient) GetVersion(ctx context.Context, req *pb.PromptVersionGetRequest) (*pb.PromptVersion, error) {
+	if err := dbClient.checkReadable(ctx, req.PromptId, req.ClientId); err != nil {
+		return nil, err
+	}
+	return dbClient.FindVersion(ctx, req.PromptId, req.Version)
+}
+
+// FindVersion gets a version of the prompt, the latest one if version is 0.
+func (dbClient *DBClient) FindVersion(ctx context.Context, promptID string, version uint64) (*pb.PromptVersion, error) {
+	var v PromptVersion
+	sql := dbClient.DB.WithContext(ctx).Model(&v).Where("prompt_id = ?", promptID)
+	if version > 0 {
+		sql = sql.Where("version = ?", version)
+	} else {
+		sql = sql.Order("version DESC")
+	}
+	if err := sql.First(&v).Error; err != nil {
+		return nil, err
+	}
+	return v.ToProtobuf(), nil
+}
+
+func (dbClient *DBClient) ListVersions(ctx context.Context, req *pb.PromptVersionListRequest) (*pb.PromptVersionListResponse, error) {
+	if err := dbClient.checkReadable(ctx, req.PromptId, req.ClientId); err != nil {
+		return nil, err
+	}
+	var list PromptVersions
+	if err := dbClient.DB.WithContext(ctx).Model(&PromptVersion{}).Where("prompt_id = ?", req.PromptId).Order("version DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return &pb.PromptVersionListResponse{
+		Total: int64(len(list)),
+		List:  list.ToProtobuf(),
+	}, nil
+}
+
+func (dbClient *DBClient) PublishVersion(ctx context.Context, req *pb.PromptVersionPublishRequest) (*pb.Prompt, error) {
+	c, err := txGetOwnedPrompt(dbClient.DB.WithContext(ctx), req.PromptId, req.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dbClient.FindVersion(ctx, req.PromptId, req.Version); err != nil {
+		return nil, fmt.Errorf("failed to get version %d: %w", req.Version, err)
+	}
+	if err := dbClient.DB.WithContext(ctx).Model(c).Update("published_version", req.Version).Error; err != nil {
+		return nil, err
+	}
+	return dbClient.Get(ctx, &pb.PromptGetRequest{Id: req.PromptId})
+}
+
+// SetExperiment sets or stops (no variants) the experiment, all versions of variants must exist.
+func (dbClient *DBClient) SetExperiment(ctx context.Context, req *pb.PromptExperimentSetRequest) (*pb.Prompt, error) {
+	c, err := txGetOwnedPrompt(dbClient.DB.WithContext(ctx), req.PromptId, req.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	experiment := req.Experiment
+	if len(experiment.GetVariants()) == 0 {
+		experiment = nil
+	}
+	seen := make(map[uint64]struct{})
+	for _, variant := range experiment.GetVariants() {
+		if _, ok := seen[variant.Version]; ok {
+			return nil, fmt.Errorf("duplicate version %d in variants", variant.Version)
+		}
+		seen[variant.Version] = struct{}{}
+		if _, err := dbClient.FindVersion(ctx, req.PromptId, variant.Version); err != nil {
+			return nil, fmt.Errorf("failed to get version %d: %w", variant.Version, err)
+		}
+	}
+	if err := dbClient.DB.WithContext(ctx).Model(c).Select("experiment").Updates(&Prompt{Experiment: experiment}).Error; err != nil {
+		return nil, err
+	}
+	return dbClient.Get(ctx, &pb.PromptGetRequest{Id: req.PromptId})
+}
+
+// txGetOwnedPrompt gets the prompt to modify, the prompt must belong to the client if clientID is set.
+func txGetOwnedPrompt(tx *gorm.DB, promptID, clientID string) (*Prompt, error) {
+	c := &Prompt{BaseModel: common.BaseModelWithID(promptID)}
+	if err := tx.Model(c).Where(&Prompt{ClientID: clientID}).First(c).Error; err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkReadable checks the prompt belongs to the client or is system builtin.
+func (dbClient *DBClient) checkReadable(ctx context.Context, promptID, clientID string) error {
+	c := &Prompt{BaseModel: common.BaseModelWithID(promptID)}
+	sql := dbClient.DB.WithContext(ctx).Model(c)
+	if clientID != "" {
+		sql = sql.Where(`client_id = ? OR client_id IS NULL OR client_id = ''`, clientID)
+	}
+	return sql.First(c).Error
+}
+
+func txCreateVersion(tx *gorm.DB, c *Prompt, msgs message.Messages, vars Variables, changelog string, publish bool) (*PromptVersion, error) {
+	var texts []string
+	for _, msg := range msgs {
+		texts = append(texts, msg.Content)
+		for _, part := range msg.MultiContent {
+			texts = append(texts, part.Text)
+		}
+	}
+	if err := prompt_template.ValidateVariables(vars, texts...); err != nil {
+		return nil, err
+	}
+	var latest uint64
+	if err := tx.Model(&PromptVersion{}).Where("prompt_id = ?", c.ID.String).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+	v := &PromptVersion{
+		PromptID:  c.ID.String,
+		Version:   latest + 1,
+		Messages:  msgs,
+		Variables: vars,
+		Changelog: changelog,
+	}
+	if err := tx.Model(v).Create(v).Error; err != nil {
+		return nil, err
+	}
+	if publish {
+		if err := tx.Model(c).Update("published_version", v.Version).Error; err != nil {
+			return nil, err
+		}
+		c.PublishedVersion = v.Version
+	}
+	return v, nil
+}
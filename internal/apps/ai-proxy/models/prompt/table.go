@@ -30,6 +30,9 @@ type Prompt struct {
 	ClientID string            `gorm:"column:client_id;type:char(36)" json:"clientID" yaml:"clientID"`
 	Messages message.Messages  `gorm:"column:messages;type:longtext" json:"messages" yaml:"messages"`
 	Metadata metadata.Metadata `gorm:"column:metadata;type:mediumtext" json:"metadata" yaml:"metadata"`
+
+	PublishedVersion uint64               `gorm:"column:published_version;type:int" json:"publishedVersion" yaml:"publishedVersion"`
+	Experiment       *pb.PromptExperiment `gorm:"column:experiment;type:json;serializer:json" json:"experiment,omitempty" yaml:"experiment,omitempty"`
 }
 
 func (*Prompt) TableName() string { return "ai_proxy_prompt" }
@@ -45,6 +48,9 @@ func (p *Prompt) ToProtobuf() *pb.Prompt {
 		ClientId:  p.ClientID,
 		Messages:  p.Messages.ToProtobuf(),
 		Metadata:  p.Metadata.ToProtobuf(),
+
+		PublishedVersion: p.PublishedVersion,
+		Experiment:       p.Experiment,
 	}
 }
 
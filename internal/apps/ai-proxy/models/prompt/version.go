@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompt
+
+import (
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-proto-go/apps/aiproxy/prompt/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/prompt_template"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/common"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/message"
+)
+
+// PromptVersion is an immutable snapshot of prompt messages, only the published pointer of prompt moves.
+type PromptVersion struct {
+	common.BaseModel
+	PromptID  string           `gorm:"column:prompt_id;type:char(36)" json:"promptID" yaml:"promptID"`
+	Version   uint64           `gorm:"column:version;type:int" json:"version" yaml:"version"`
+	Messages  message.Messages `gorm:"column:messages;type:longtext" json:"messages" yaml:"messages"`
+	Variables Variables        `gorm:"column:variables;type:json;serializer:json" json:"variables" yaml:"variables"`
+	Changelog string           `gorm:"column:changelog;type:varchar(1024)" json:"changelog" yaml:"changelog"`
+}
+
+func (*PromptVersion) TableName() string { return "ai_proxy_prompt_version" }
+
+func (v *PromptVersion) ToProtobuf() *pb.PromptVersion {
+	return &pb.PromptVersion{
+		Id:        v.ID.String,
+		CreatedAt: timestamppb.New(v.CreatedAt),
+		PromptId:  v.PromptID,
+		Version:   v.Version,
+		Messages:  v.Messages.ToProtobuf(),
+		Variables: v.Variables.ToProtobuf(),
+		Changelog: v.Changelog,
+	}
+}
+
+type PromptVersions []PromptVersion
+
+func (versions *PromptVersions) ToProtobuf() []*pb.PromptVersion {
+	var result []*pb.PromptVersion
+	for _, v := range *versions {
+		result = append(result, v.ToProtobuf())
+	}
+	return result
+}
+
+type Variables []prompt_template.Variable
+
+func VariablesFromProtobuf(pbVars []*pb.PromptVariable) Variables {
+	var result Variables
+	for _, v := range pbVars {
+		variable := prompt_template.Variable{
+			Name:     v.Name,
+			Type:     v.Type,
+			Required: v.Required,
+			Desc:     v.Desc,
+		}
+		if v.Default != nil {
+			if _, isNull := v.Default.Kind.(*structpb.Value_NullValue); !isNull {
+				variable.Default = v.Default.AsInterface()
+			}
+		}
+		result = append(result, variable)
+	}
+	return result
+}
+
+func (vars Variables) ToProtobuf() []*pb.PromptVariable {
+	var result []*pb.PromptVariable
+	for _, v := range vars {
+		pbVar := &pb.PromptVariable{
+			Name:     v.Name,
+			Type:     v.GetType(),
+			Required: v.Required,
+			Desc:     v.Desc,
+		}
+		if v.Default != nil {
+			pbVar.Default, _ = structpb.NewValue(v.Default)
+		}
+		result = append(result, pbVar)
+	}
+	return result
+}
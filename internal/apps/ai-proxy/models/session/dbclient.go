@@ -21,7 +21,6 @@ import (
 
 	"gorm.io/gorm"
 
-	"github.com/erda-project/erda-infra/providers/mysql/v2/plugins/fields"
 	"github.com/erda-project/erda-proto-go/apps/aiproxy/session/pb"
 	commonpb "github.com/erda-project/erda-proto-go/common/pb"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/audit"
@@ -53,6 +52,9 @@ func (dbClient *DBClient) Create(ctx context.Context, req *pb.SessionCreateReque
 		IsArchived:  false,
 		Temperature: req.Temperature,
 		Metadata:    metadata.FromProtobuf(req.Metadata),
+
+		ContextStrategy: req.ContextStrategy,
+		SummaryModel:    req.SummaryModel,
 	}
 	if err := dbClient.DB.Model(c).Create(c).Error; err != nil {
 		return nil, err
@@ -93,6 +95,9 @@ func (dbClient *DBClient) Update(ctx context.Context, req *pb.SessionUpdateReque
 		NumOfCtxMsg: int64(req.NumOfCtxMsg),
 		Temperature: req.Temperature,
 		Metadata:    metadata.FromProtobuf(req.Metadata),
+
+		ContextStrategy: req.ContextStrategy,
+		SummaryModel:    req.SummaryModel,
 	}).Error; err != nil {
 		return nil, err
 	}
@@ -147,8 +152,13 @@ func (dbClient *DBClient) UnArchive(ctx context.Context, req *pb.SessionUnArchiv
 }
 
 func (dbClient *DBClient) Reset(ctx context.Context, req *pb.SessionResetRequest) (*pb.Session, error) {
-	c := &Session{BaseModel: common.BaseModelWithID(req.Id), ResetAt: fields.DeletedAt{Time: time.Now(), Valid: true}}
-	if err := dbClient.DB.Model(c).Updates(c).Error; err != nil {
+	c := &Session{BaseModel: common.BaseModelWithID(req.Id)}
+	// the summary of turns before reset is dropped too
+	if err := dbClient.DB.Model(c).Updates(map[string]any{
+		"reset_at":      time.Now(),
+		"summary":       "",
+		"summarized_at": nil,
+	}).Error; err != nil {
 		return nil, err
 	}
 	return dbClient.Get(ctx, &pb.SessionGetRequest{Id: req.Id})
@@ -188,3 +198,31 @@ func (dbClient *DBClient) GetChatLogs(ctx context.Context, req *pb.SessionChatLo
 		List:  chatLogs.ToChatLogsProtobuf(),
 	}, nil
 }
+
+// ListHistory returns the last limit chat logs requested after since, oldest first.
+// Chat logs without completion, e.g., failed requests, are skipped.
+func (dbClient *DBClient) ListHistory(ctx context.Context, sessionID string, since time.Time, limit int) (audit.Audits, error) {
+	var chatLogs audit.Audits
+	sql := dbClient.DB.Model(&audit.Audit{}).
+		Where("session_id = ?", sessionID).
+		Where("completion != ''")
+	if !since.IsZero() {
+		sql = sql.Where("request_at > ?", since)
+	}
+	if err := sql.Order("request_at DESC").Limit(limit).Find(&chatLogs).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(chatLogs)-1; i < j; i, j = i+1, j-1 {
+		chatLogs[i], chatLogs[j] = chatLogs[j], chatLogs[i]
+	}
+	return chatLogs, nil
+}
+
+// SaveSummary stores the summary of turns requested until summarizedAt.
+func (dbClient *DBClient) SaveSummary(ctx context.Context, id, summary string, summarizedAt time.Time) error {
+	c := &Session{BaseModel: common.BaseModelWithID(id)}
+	return dbClient.DB.Model(c).Updates(map[string]any{
+		"summary":       summary,
+		"summarized_at": summarizedAt,
+	}).Error
+}
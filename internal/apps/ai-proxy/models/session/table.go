@@ -40,6 +40,11 @@ type Session struct {
 	Temperature float64          `gorm:"column:temperature;type:decimal(11,0)" json:"temperature" yaml:"temperature"`
 
 	Metadata metadata.Metadata `gorm:"column:metadata;type:mediumtext" json:"metadata" yaml:"metadata"`
+
+	ContextStrategy string           `gorm:"column:context_strategy;type:varchar(32)" json:"contextStrategy" yaml:"contextStrategy"`
+	SummaryModel    string           `gorm:"column:summary_model;type:varchar(191)" json:"summaryModel" yaml:"summaryModel"`
+	Summary         string           `gorm:"column:summary;type:mediumtext" json:"summary" yaml:"summary"`
+	SummarizedAt    fields.DeletedAt `gorm:"column:summarized_at;type:datetime" json:"summarizedAt" yaml:"summarizedAt"`
 }
 
 func (*Session) TableName() string { return "ai_proxy_session" }
@@ -62,6 +67,11 @@ func (s *Session) ToProtobuf() *pb.Session {
 		ResetAt:     timestamppb.New(s.ResetAt.Time),
 		Temperature: s.Temperature,
 		Metadata:    s.Metadata.ToProtobuf(),
+
+		ContextStrategy: s.ContextStrategy,
+		SummaryModel:    s.SummaryModel,
+		Summary:         s.Summary,
+		SummarizedAt:    timestamppb.New(s.SummarizedAt.Time),
 	}
 }
 
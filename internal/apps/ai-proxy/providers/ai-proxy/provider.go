@@ -25,7 +25,10 @@ import (
 
 	"github.com/erda-project/erda-infra/base/logs"
 	"github.com/erda-project/erda-infra/base/servicehub"
+	clientpb "github.com/erda-project/erda-proto-go/apps/aiproxy/client/pb"
+	eventboxpb "github.com/erda-project/erda-proto-go/core/messenger/eventbox/pb"
 	archivepkg "github.com/erda-project/erda/internal/apps/ai-proxy/archive"
+	batchpkg "github.com/erda-project/erda/internal/apps/ai-proxy/batch"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/cache"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/cache/cachetypes"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/audithelper"
@@ -33,10 +36,14 @@ import (
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/template"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/usage/token_usage"
+	batchmodel "github.com/erda-project/erda/internal/apps/ai-proxy/models/batch"
 	eventmodel "github.com/erda-project/erda/internal/apps/ai-proxy/models/event"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/providers/ai-proxy/aiproxytypes"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/providers/dao"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/providers/reverseproxy"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/response-cache/cacheutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/session-memory/memoryutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/budget"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/route/lb/state_store"
 	pgengine "github.com/erda-project/erda/internal/apps/ai-proxy/route/policy_group/engine"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/route/policy_group/health"
@@ -52,6 +59,12 @@ type Config struct {
 	LBStateStoreStickyTTL time.Duration `file:"lb_state_store_sticky_ttl" env:"LB_STATE_STORE_STICKY_TTL" default:"10m"`
 	ModelHealth           health.Config `file:"model_health"`
 
+	ResponseCache cacheutil.Config `file:"response_cache"`
+
+	SessionMemory memoryutil.Config `file:"session_memory"`
+
+	Batch batchpkg.Config `file:"batch"`
+
 	// Redis settings (standalone or sentinel via redis.UniversalOptions)
 	RedisAddr          string `file:"redis_addr" env:"REDIS_ADDR"`
 	RedisSentinelsAddr string `file:"redis_sentinels_addr" env:"REDIS_SENTINELS_ADDR"`
@@ -72,11 +85,13 @@ type provider struct {
 	L      logs.Logger
 	Dao    dao.DAO `autowired:"erda.apps.ai-proxy.dao"`
 
-	ReverseProxy reverseproxy.Interface `autowired:"erda.app.reverse-proxy"`
+	ReverseProxy reverseproxy.Interface           `autowired:"erda.app.reverse-proxy"`
+	EventBox     eventboxpb.EventBoxServiceServer `autowired:"erda.core.messenger.eventbox.EventBoxService" optional:"true"`
 
 	cache cachetypes.Manager
 
 	archiveService *archivepkg.Service
+	batchService   *batchpkg.Service
 
 	handlers           *aiproxytypes.Handlers
 	ctxhelperFunctions []func(context.Context)
@@ -89,6 +104,9 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	if err := p.Config.Audit.Archive.Normalize(); err != nil {
 		return err
 	}
+	if err := p.Config.Batch.Normalize(); err != nil {
+		return err
+	}
 
 	// load templates
 	templatesByType, err := template.LoadTemplatesFromEmbeddedFS(p.L, reverseproxy.EmbedTemplatesFS)
@@ -114,6 +132,21 @@ func (p *provider) Init(ctx servicehub.Context) error {
 		engineOpts...,
 	))
 
+	// budget notifications are sent through messenger when available
+	if p.EventBox != nil {
+		budget.SetNotifier(budget.NewEventBoxNotifier(p.EventBox))
+	}
+
+	p.initResponseCache()
+	// semantic response cache embeds prompts through ai-proxy itself
+	if p.Config.ResponseCache.SemanticBaseURL != "" {
+		cacheutil.SetEmbedder(cacheutil.NewHTTPEmbedder(p.Config.ResponseCache))
+	}
+	// session memory summarizes older turns through ai-proxy itself
+	if p.Config.SessionMemory.SummaryBaseURL != "" {
+		memoryutil.SetSummarizer(memoryutil.NewHTTPSummarizer(p.Config.SessionMemory))
+	}
+
 	// initialize cache manager
 	p.cache = cache.NewCacheManager(p.Dao, p.L, templatesByType, false)
 	p.ReverseProxy.SetCacheManager(p.cache)
@@ -128,6 +161,20 @@ func (p *provider) Init(ctx servicehub.Context) error {
 		p.L,
 	)
 
+	// local batch executes request lines through ai-proxy itself
+	if p.Config.Batch.Enable {
+		p.batchService, err = batchpkg.NewService(
+			p.Config.Batch,
+			&batchmodel.DBClient{DB: p.Dao.Q()},
+			p.batchCredential,
+			p.L,
+		)
+		if err != nil {
+			return err
+		}
+		batchpkg.SetService(p.batchService)
+	}
+
 	p.initHandlers(templatesByType)
 
 	p.registerAIProxyManageAPI()
@@ -143,10 +190,29 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	))
 
 	p.archiveService.AsyncRun(ctx)
+	if p.batchService != nil {
+		p.batchService.AsyncRun(ctx)
+	}
 
 	return nil
 }
 
+// batchCredential returns the token or the access key a local batch was created with.
+func (p *provider) batchCredential(ctx context.Context, clientID, clientTokenID string) (string, error) {
+	if clientTokenID != "" {
+		token, err := p.Dao.ClientTokenClient().GetByID(ctx, clientTokenID)
+		if err != nil {
+			return "", err
+		}
+		return token.Token, nil
+	}
+	client, err := p.Dao.ClientClient().Get(ctx, &clientpb.ClientGetRequest{ClientId: clientID})
+	if err != nil {
+		return "", err
+	}
+	return client.AccessKeyId, nil
+}
+
 func (p *provider) initPolicyGroupStateStore() (store state_store.LBStateStore, desc string, err error) {
 	defer func() {
 		if store != nil {
@@ -201,6 +267,21 @@ func (p *provider) buildEtcdStateStore() (state_store.LBStateStore, string, erro
 	return state_store.NewEtcdStateStore(cli, "ai-proxy:lb"), desc, nil
 }
 
+// initResponseCache sets the backend of the response cache, it is kept apart from the lb state store.
+func (p *provider) initResponseCache() {
+	cfg := p.Config.ResponseCache
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case "redis":
+		client := redis.NewUniversalClient(p.buildRedisOptions())
+		cacheutil.SetStore(cacheutil.NewRedisStore(client, ""))
+		cacheutil.SetSemanticIndex(cacheutil.NewRedisSemanticIndex(client, ""))
+		p.L.Infof("response cache backend: redis")
+	default:
+		cacheutil.SetStore(cacheutil.NewMemoryStore(cfg.MaxBytes))
+		p.L.Infof("response cache backend: memory, max bytes: %d", cfg.MaxBytes)
+	}
+}
+
 func (p *provider) buildRedisOptions() *redis.UniversalOptions {
 	sentinels := splitAndTrim(p.Config.RedisSentinelsAddr)
 	addrs := splitAndTrim(p.Config.RedisAddr)
@@ -28,6 +28,8 @@ import (
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_server"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_server_config_instance"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_server_template"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_tool_call"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_tool_policy"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/model"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/policy_group"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/prompt"
@@ -79,6 +81,8 @@ type DAO interface {
 	TokenUsageClient() *usage_token.DBClient
 	MCPServerTemplateClient() *mcp_server_template.DBClient
 	MCPServerConfigInstanceClient() *mcp_server_config_instance.DBClient
+	MCPToolPolicyClient() *mcp_tool_policy.DBClient
+	MCPToolCallClient() *mcp_tool_call.DBClient
 	PolicyGroupClient() *policy_group.DBClient
 	SettingClient() *setting.DBClient
 }
@@ -155,6 +159,14 @@ func (p *provider) MCPServerConfigInstanceClient() *mcp_server_config_instance.D
 	return mcp_server_config_instance.NewDBClient(p.DB)
 }
 
+func (p *provider) MCPToolPolicyClient() *mcp_tool_policy.DBClient {
+	return mcp_tool_policy.NewDBClient(p.DB)
+}
+
+func (p *provider) MCPToolCallClient() *mcp_tool_call.DBClient {
+	return mcp_tool_call.NewDBClient(p.DB)
+}
+
 func (p *provider) PolicyGroupClient() *policy_group.DBClient {
 	return &policy_group.DBClient{DB: p.DB}
 }
@@ -32,12 +32,16 @@ import (
 	mcppb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_server/pb"
 	mcipb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_server_config_instance/pb"
 	mtpb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_server_template/pb"
+	mtcpb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_tool_call/pb"
+	mtppb "github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_tool_policy/pb"
 	clusterpb "github.com/erda-project/erda-proto-go/core/clustermanager/cluster/pb"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/cache"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/cache/cachetypes"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/handlers/handler_mcp_server"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/handlers/handler_mcp_server_config_instance"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/handlers/handler_mcp_server_template"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/handlers/handler_mcp_tool_call"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/handlers/handler_mcp_tool_policy"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/handlers/permission"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/mcp"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/providers/dao"
@@ -86,7 +90,8 @@ func (p *provider) Init(ctx servicehub.Context) error {
 
 	p.registerMcpProxyManageAPI()
 
-	p.ServeReverseProxyV2(reverseproxy.WithTransport(transports.NewMcpTransport()))
+	// tool calls rejected by request filters are answered by generated responses
+	p.ServeReverseProxyV2(reverseproxy.WithTransport(transports.NewRequestFilterGeneratedResponseTransport(transports.NewMcpTransport())))
 	return nil
 }
 
@@ -97,6 +102,10 @@ func (p *provider) registerMcpProxyManageAPI() {
 	mtpb.RegisterMCPServerTemplateServiceImp(p, handler_mcp_server_template.NewMcpTemplateHandler(p.Dao), p.getProtoOptions(permission.CheckMcpTemplatePerm)...)
 
 	mcipb.RegisterMCPServerConfigInstanceServiceImp(p, handler_mcp_server_config_instance.NewMCPConfigInstanceHandler(p.Dao), p.getProtoOptions(permission.CheckMcpConfigInstancePerm)...)
+
+	mtppb.RegisterMCPToolPolicyServiceImp(p, handler_mcp_tool_policy.NewMCPToolPolicyHandler(p.Dao), p.getProtoOptions(permission.CheckMcpToolPolicyPerm)...)
+
+	mtcpb.RegisterMCPToolCallServiceImp(p, handler_mcp_tool_call.NewMCPToolCallHandler(p.Dao), p.getProtoOptions(permission.CheckMcpToolCallPerm)...)
 }
 
 func (p *provider) Run(ctx context.Context) error {
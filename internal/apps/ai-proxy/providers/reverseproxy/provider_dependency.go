@@ -22,6 +22,7 @@ import (
 	_ "github.com/erda-project/erda-infra/providers/mysql/v2"
 
 	// gRPC
+	_ "github.com/erda-project/erda-proto-go/core/messenger/eventbox/client"
 	_ "github.com/erda-project/erda-proto-go/core/openapi/dynamic-register/client"
 
 	// ai-proxy
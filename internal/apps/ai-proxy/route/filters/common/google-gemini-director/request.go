@@ -0,0 +1,317 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package google_gemini_director converts OpenAI chat completions to and from the Gemini API `generateContent`.
+// see: https://ai.google.dev/api/generate-content
+package google_gemini_director
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/thinking-handler/types"
+)
+
+const (
+	// ExtraContentKey is the key of tool call `extra_content`, the same as Gemini's OpenAI compatible API.
+	ExtraContentKey     = "google"
+	ThoughtSignatureKey = "thought_signature"
+)
+
+type GenerateContentRequest struct {
+	Contents          []*genai.Content  `json:"contents"`
+	SystemInstruction *genai.Content    `json:"systemInstruction,omitempty"`
+	Tools             []Tool            `json:"tools,omitempty"`
+	ToolConfig        *ToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type Tool struct {
+	FunctionDeclarations []FunctionDeclaration `json:"functionDeclarations"`
+}
+
+type FunctionDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// ParametersJSONSchema accepts JSON Schema as is, unlike `parameters` only accepting an OpenAPI subset.
+	ParametersJSONSchema json.RawMessage `json:"parametersJsonSchema,omitempty"`
+}
+
+type ToolConfig struct {
+	FunctionCallingConfig FunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type FunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type GenerationConfig struct {
+	Temperature        *float64        `json:"temperature,omitempty"`
+	TopP               *float64        `json:"topP,omitempty"`
+	MaxOutputTokens    int             `json:"maxOutputTokens,omitempty"`
+	StopSequences      []string        `json:"stopSequences,omitempty"`
+	CandidateCount     int             `json:"candidateCount,omitempty"`
+	Seed               *int64          `json:"seed,omitempty"`
+	PresencePenalty    *float64        `json:"presencePenalty,omitempty"`
+	FrequencyPenalty   *float64        `json:"frequencyPenalty,omitempty"`
+	ResponseMIMEType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+	ThinkingConfig     map[string]any  `json:"thinkingConfig,omitempty"`
+}
+
+// ConvertRequest converts an OpenAI chat completion request to a Gemini generateContent request.
+func ConvertRequest(ctx context.Context, req openai_chat.Request) (*GenerateContentRequest, error) {
+	out := &GenerateContentRequest{}
+	if err := convertMessages(ctx, req.Messages, out); err != nil {
+		return nil, err
+	}
+	if len(out.Contents) == 0 {
+		return nil, fmt.Errorf("no user or assistant message")
+	}
+
+	// tools
+	var decls []FunctionDeclaration
+	for _, tool := range req.Tools {
+		if tool.Type != "function" || tool.Function == nil {
+			return nil, fmt.Errorf("unsupported tool type: %s", tool.Type)
+		}
+		decls = append(decls, FunctionDeclaration{
+			Name:                 tool.Function.Name,
+			Description:          tool.Function.Description,
+			ParametersJSONSchema: tool.Function.Parameters,
+		})
+	}
+	if len(decls) > 0 {
+		out.Tools = []Tool{{FunctionDeclarations: decls}}
+	}
+	toolChoice, err := openai_chat.ParseToolChoice(req.ToolChoice)
+	if err != nil {
+		return nil, err
+	}
+	if toolChoice != nil {
+		cfg := FunctionCallingConfig{}
+		switch toolChoice.Mode {
+		case openai_chat.ToolChoiceNone:
+			cfg.Mode = "NONE"
+		case openai_chat.ToolChoiceRequired:
+			cfg.Mode = "ANY"
+			if toolChoice.FunctionName != "" {
+				cfg.AllowedFunctionNames = []string{toolChoice.FunctionName}
+			}
+		default:
+			cfg.Mode = "AUTO"
+		}
+		out.ToolConfig = &ToolConfig{FunctionCallingConfig: cfg}
+	}
+
+	// generation config
+	cfg := GenerationConfig{
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		MaxOutputTokens:  req.GetMaxTokens(),
+		StopSequences:    req.Stop,
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		ThinkingConfig:   thinkingConfig(req.ExtraBody),
+	}
+	if req.N > 1 {
+		cfg.CandidateCount = req.N
+	}
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case "json_object":
+			cfg.ResponseMIMEType = "application/json"
+		case "json_schema":
+			cfg.ResponseMIMEType = "application/json"
+			if req.ResponseFormat.JSONSchema != nil {
+				cfg.ResponseJSONSchema = req.ResponseFormat.JSONSchema.Schema
+			}
+		}
+	}
+	out.GenerationConfig = &cfg
+	return out, nil
+}
+
+func convertMessages(ctx context.Context, messages []openai_chat.Message, out *GenerateContentRequest) error {
+	// function responses of gemini are matched by name, while tool messages of openai refer to the tool call id
+	toolCallNames := make(map[string]string)
+	for i, msg := range messages {
+		switch msg.Role {
+		case "system", "developer":
+			text, err := msg.Text()
+			if err != nil {
+				return err
+			}
+			if out.SystemInstruction == nil {
+				out.SystemInstruction = &genai.Content{}
+			}
+			out.SystemInstruction.Parts = append(out.SystemInstruction.Parts, &genai.Part{Text: text})
+		case "user":
+			parts, err := convertContentParts(ctx, msg)
+			if err != nil {
+				return fmt.Errorf("failed to convert message %d: %v", i, err)
+			}
+			appendContent(out, genai.RoleUser, parts...)
+		case "assistant":
+			parts, err := convertContentParts(ctx, msg)
+			if err != nil {
+				return fmt.Errorf("failed to convert message %d: %v", i, err)
+			}
+			for _, toolCall := range msg.ToolCalls {
+				toolCallNames[toolCall.ID] = toolCall.Function.Name
+				part, err := convertToolCall(toolCall)
+				if err != nil {
+					return fmt.Errorf("failed to convert tool call of message %d: %v", i, err)
+				}
+				parts = append(parts, part)
+			}
+			appendContent(out, genai.RoleModel, parts...)
+		case "tool":
+			name, ok := toolCallNames[msg.ToolCallID]
+			if !ok {
+				return fmt.Errorf("no tool call found for tool message %d, tool_call_id: %s", i, msg.ToolCallID)
+			}
+			text, err := msg.Text()
+			if err != nil {
+				return err
+			}
+			appendContent(out, genai.RoleUser, &genai.Part{FunctionResponse: &genai.FunctionResponse{
+				Name:     name,
+				Response: functionResponse(text),
+			}})
+		default:
+			return fmt.Errorf("unsupported message role: %s, index: %d", msg.Role, i)
+		}
+	}
+	return nil
+}
+
+// appendContent merges consecutive parts of the same role into one content, e.g., responses of parallel tool calls.
+func appendContent(out *GenerateContentRequest, role string, parts ...*genai.Part) {
+	if len(parts) == 0 {
+		return
+	}
+	if n := len(out.Contents); n > 0 && out.Contents[n-1].Role == role {
+		out.Contents[n-1].Parts = append(out.Contents[n-1].Parts, parts...)
+		return
+	}
+	out.Contents = append(out.Contents, &genai.Content{Role: role, Parts: parts})
+}
+
+func convertContentParts(ctx context.Context, msg openai_chat.Message) ([]*genai.Part, error) {
+	parts, err := msg.Parts()
+	if err != nil {
+		return nil, err
+	}
+	var result []*genai.Part
+	for _, part := range parts {
+		switch part.Type {
+		case openai_chat.PartTypeText:
+			result = append(result, &genai.Part{Text: part.Text})
+		case openai_chat.PartTypeImageURL:
+			if part.ImageURL == nil {
+				return nil, fmt.Errorf("missing image_url")
+			}
+			url := part.ImageURL.URL
+			if !isInlineImageURL(url) {
+				result = append(result, &genai.Part{FileData: &genai.FileData{FileURI: url, MIMEType: openai_chat.GuessMimeType(url)}})
+				continue
+			}
+			mimeType, data, err := openai_chat.FetchImage(ctx, url)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, &genai.Part{InlineData: &genai.Blob{MIMEType: mimeType, Data: data}})
+		default:
+			return nil, fmt.Errorf("unsupported content part type: %s", part.Type)
+		}
+	}
+	return result, nil
+}
+
+// isInlineImageURL reports whether the image is sent as inline data,
+// files uploaded by the Files API or in google cloud storage are referred as file data.
+func isInlineImageURL(url string) bool {
+	if strings.HasPrefix(url, "data:") {
+		return true
+	}
+	if strings.HasPrefix(url, "https://generativelanguage.googleapis.com/") {
+		return false
+	}
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+func convertToolCall(toolCall openai_chat.ToolCall) (*genai.Part, error) {
+	args := map[string]any{}
+	if toolCall.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments of tool call %s: %v", toolCall.ID, err)
+		}
+	}
+	part := &genai.Part{FunctionCall: &genai.FunctionCall{Name: toolCall.Function.Name, Args: args}}
+	// thought signatures must be sent back for function calling of thinking models
+	if google, ok := toolCall.ExtraContent[ExtraContentKey].(map[string]any); ok {
+		if signature, ok := google[ThoughtSignatureKey].(string); ok && signature != "" {
+			b, err := base64.StdEncoding.DecodeString(signature)
+			if err != nil {
+				return nil, fmt.Errorf("invalid thought signature of tool call %s: %v", toolCall.ID, err)
+			}
+			part.ThoughtSignature = b
+		}
+	}
+	return part, nil
+}
+
+// functionResponse must be a json object, a text result is wrapped as {"content": text}.
+func functionResponse(text string) map[string]any {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(text), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]any{"content": text}
+}
+
+// thinkingConfig gets the thinking config encoded by the thinking-handler, i.e., extra_body.google.thinking_config,
+// and converts it to the native camelCase form.
+func thinkingConfig(extraBody map[string]any) map[string]any {
+	google, ok := extraBody[types.FieldGoogle].(map[string]any)
+	if !ok {
+		return nil
+	}
+	cfg, ok := google[types.FieldThinkingConfig].(map[string]any)
+	if !ok || len(cfg) == 0 {
+		return nil
+	}
+	keys := map[string]string{
+		types.FieldIncludeThoughts: "includeThoughts",
+		types.FieldThinkingBudget:  "thinkingBudget",
+		types.FieldThinkingLevel:   "thinkingLevel",
+	}
+	result := make(map[string]any, len(cfg))
+	for k, v := range cfg {
+		if camel, ok := keys[k]; ok {
+			k = camel
+		}
+		result[k] = v
+	}
+	return result
+}
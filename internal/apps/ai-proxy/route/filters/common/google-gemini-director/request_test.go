@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google_gemini_director
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+)
+
+func TestConvertRequest(t *testing.T) {
+	body := `{
+		"model": "gemini-2.5-flash",
+		"messages": [
+			{"role": "system", "content": "You are helpful."},
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is in the image?"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,aGVsbG8="}},
+				{"type": "image_url", "image_url": {"url": "gs://bucket/cat.jpg"}}
+			]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Hangzhou\"}"},
+				 "extra_content": {"google": {"thought_signature": "c2ln"}}},
+				{"id": "call_2", "type": "function", "function": {"name": "get_time", "arguments": "{}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "{\"weather\":\"sunny\"}"},
+			{"role": "tool", "tool_call_id": "call_2", "content": "10:00"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}],
+		"tool_choice": {"type": "function", "function": {"name": "get_weather"}},
+		"max_tokens": 100,
+		"temperature": 0.5,
+		"stop": "END",
+		"response_format": {"type": "json_schema", "json_schema": {"name": "x", "schema": {"type": "object"}}},
+		"extra_body": {"google": {"thinking_config": {"include_thoughts": true, "thinking_budget": 1024}}}
+	}`
+	var req openai_chat.Request
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+
+	out, err := ConvertRequest(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "You are helpful.", out.SystemInstruction.Parts[0].Text)
+	require.Len(t, out.Contents, 3)
+
+	user := out.Contents[0]
+	assert.Equal(t, "user", user.Role)
+	require.Len(t, user.Parts, 3)
+	assert.Equal(t, "image/png", user.Parts[1].InlineData.MIMEType)
+	assert.Equal(t, []byte("hello"), user.Parts[1].InlineData.Data)
+	assert.Equal(t, "gs://bucket/cat.jpg", user.Parts[2].FileData.FileURI)
+	assert.Equal(t, "image/jpeg", user.Parts[2].FileData.MIMEType)
+
+	model := out.Contents[1]
+	assert.Equal(t, "model", model.Role)
+	require.Len(t, model.Parts, 2)
+	assert.Equal(t, "get_weather", model.Parts[0].FunctionCall.Name)
+	assert.Equal(t, map[string]any{"city": "Hangzhou"}, model.Parts[0].FunctionCall.Args)
+	assert.Equal(t, []byte("sig"), model.Parts[0].ThoughtSignature)
+
+	// responses of parallel tool calls are merged into one content
+	toolResults := out.Contents[2]
+	assert.Equal(t, "user", toolResults.Role)
+	require.Len(t, toolResults.Parts, 2)
+	assert.Equal(t, "get_weather", toolResults.Parts[0].FunctionResponse.Name)
+	assert.Equal(t, map[string]any{"weather": "sunny"}, toolResults.Parts[0].FunctionResponse.Response)
+	assert.Equal(t, map[string]any{"content": "10:00"}, toolResults.Parts[1].FunctionResponse.Response)
+
+	assert.Equal(t, "get_weather", out.Tools[0].FunctionDeclarations[0].Name)
+	assert.Equal(t, "ANY", out.ToolConfig.FunctionCallingConfig.Mode)
+	assert.Equal(t, []string{"get_weather"}, out.ToolConfig.FunctionCallingConfig.AllowedFunctionNames)
+
+	cfg := out.GenerationConfig
+	assert.Equal(t, 100, cfg.MaxOutputTokens)
+	assert.Equal(t, 0.5, *cfg.Temperature)
+	assert.Equal(t, []string{"END"}, cfg.StopSequences)
+	assert.Equal(t, "application/json", cfg.ResponseMIMEType)
+	assert.JSONEq(t, `{"type":"object"}`, string(cfg.ResponseJSONSchema))
+	assert.Equal(t, map[string]any{"includeThoughts": true, "thinkingBudget": float64(1024)}, cfg.ThinkingConfig)
+}
+
+func TestConvertRequest_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "no content",
+			body: `{"messages": [{"role": "system", "content": "hi"}]}`,
+		},
+		{
+			name: "tool message without tool call",
+			body: `{"messages": [{"role": "user", "content": "hi"}, {"role": "tool", "tool_call_id": "call_x", "content": "ok"}]}`,
+		},
+		{
+			name: "invalid tool call arguments",
+			body: `{"messages": [{"role": "assistant", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "f", "arguments": "{"}}]}]}`,
+		},
+		{
+			name: "unsupported role",
+			body: `{"messages": [{"role": "function", "content": "hi"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req openai_chat.Request
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &req))
+			_, err := ConvertRequest(context.Background(), req)
+			assert.Error(t, err)
+		})
+	}
+}
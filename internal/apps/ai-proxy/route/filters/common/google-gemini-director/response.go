@@ -0,0 +1,291 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google_gemini_director
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+)
+
+// ConvertResponse converts a Gemini generateContent response body to an OpenAI chat completion response body.
+func ConvertResponse(body []byte, model string) ([]byte, error) {
+	var geminiResp genai.GenerateContentResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal gemini response: %v", err)
+	}
+	resp := openai_chat.Response{
+		ID:      openai_chat.NewID(),
+		Object:  openai_chat.ObjectChatCompletion,
+		Created: time.Now().Unix(),
+		Model:   model,
+		Usage:   convertUsage(geminiResp.UsageMetadata),
+	}
+	for _, candidate := range geminiResp.Candidates {
+		var (
+			texts, thoughts []string
+			toolCalls       []openai_chat.ToolCall
+		)
+		if candidate.Content != nil {
+			for _, part := range candidate.Content.Parts {
+				switch {
+				case part.FunctionCall != nil:
+					toolCall, err := convertFunctionCall(part)
+					if err != nil {
+						return nil, err
+					}
+					toolCalls = append(toolCalls, toolCall)
+				case part.Thought:
+					thoughts = append(thoughts, part.Text)
+				case part.Text != "":
+					texts = append(texts, part.Text)
+				}
+			}
+		}
+		resp.Choices = append(resp.Choices, openai_chat.Choice{
+			Index: int(candidate.Index),
+			Message: openai_chat.Message{
+				Role:             "assistant",
+				Content:          openai_chat.StringContent(strings.Join(texts, "")),
+				ReasoningContent: strings.Join(thoughts, ""),
+				ToolCalls:        toolCalls,
+			},
+			FinishReason: convertFinishReason(candidate.FinishReason, len(toolCalls) > 0),
+		})
+	}
+	// the prompt is blocked, there is no candidate
+	if len(resp.Choices) == 0 && geminiResp.PromptFeedback != nil && geminiResp.PromptFeedback.BlockReason != "" {
+		resp.Choices = append(resp.Choices, openai_chat.Choice{
+			Message:      openai_chat.Message{Role: "assistant", Content: openai_chat.StringContent("")},
+			FinishReason: openai_chat.FinishReasonContentFilter,
+		})
+	}
+	return json.Marshal(resp)
+}
+
+// StreamConverter converts a Gemini `streamGenerateContent?alt=sse` stream to an OpenAI chat completion stream.
+// It is stateful, one converter for one stream.
+type StreamConverter struct {
+	id      string
+	model   string
+	created int64
+
+	lines openai_chat.LineBuffer
+	// started candidates, the first chunk of a candidate carries the role
+	started map[int32]bool
+	// the next tool call index of candidates
+	toolCallIndexes map[int32]int
+	// usage is sent in a separate chunk at last, like OpenAI with `stream_options.include_usage`
+	usage *openai.Usage
+}
+
+func NewStreamConverter(model string) *StreamConverter {
+	return &StreamConverter{
+		id:              openai_chat.NewID(),
+		model:           model,
+		created:         time.Now().Unix(),
+		started:         make(map[int32]bool),
+		toolCallIndexes: make(map[int32]int),
+	}
+}
+
+// Convert converts a chunk of the Gemini stream, returns converted events, may be empty.
+func (c *StreamConverter) Convert(chunk []byte) ([]byte, error) {
+	return c.convertLines(c.lines.Lines(chunk, false))
+}
+
+// Finish converts the rest of the stream, and returns the usage chunk and `[DONE]`.
+func (c *StreamConverter) Finish() ([]byte, error) {
+	out, err := c.convertLines(c.lines.Lines(nil, true))
+	if err != nil {
+		return nil, err
+	}
+	if c.usage != nil {
+		usageChunk, err := openai_chat.SSEChunk(c.newChunk(nil, c.usage))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, usageChunk...)
+	}
+	return append(out, vars.ConcatChunkDataPrefix([]byte("[DONE]"))...), nil
+}
+
+func (c *StreamConverter) convertLines(lines []string) ([]byte, error) {
+	var out []byte
+	for _, line := range lines {
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" {
+			continue
+		}
+		var geminiResp genai.GenerateContentResponse
+		if err := json.Unmarshal([]byte(data), &geminiResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal gemini stream chunk: %v", err)
+		}
+		if usage := convertUsage(geminiResp.UsageMetadata); usage != nil {
+			c.usage = usage
+		}
+		chunk, err := c.convertChunk(&geminiResp)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		event, err := openai_chat.SSEChunk(chunk)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, event...)
+	}
+	return out, nil
+}
+
+func (c *StreamConverter) convertChunk(geminiResp *genai.GenerateContentResponse) (openai_chat.StreamResponse, error) {
+	var choices []openai_chat.StreamChoice
+	for _, candidate := range geminiResp.Candidates {
+		var (
+			delta     openai_chat.Delta
+			texts     []string
+			thoughts  []string
+			toolCalls []openai_chat.ToolCall
+		)
+		if !c.started[candidate.Index] {
+			c.started[candidate.Index] = true
+			delta.Role = "assistant"
+		}
+		if candidate.Content != nil {
+			for _, part := range candidate.Content.Parts {
+				switch {
+				case part.FunctionCall != nil:
+					toolCall, err := convertFunctionCall(part)
+					if err != nil {
+						return openai_chat.StreamResponse{}, err
+					}
+					index := c.toolCallIndexes[candidate.Index]
+					c.toolCallIndexes[candidate.Index] = index + 1
+					toolCall.Index = &index
+					toolCalls = append(toolCalls, toolCall)
+				case part.Thought:
+					thoughts = append(thoughts, part.Text)
+				case part.Text != "":
+					texts = append(texts, part.Text)
+				}
+			}
+		}
+		delta.Content = strings.Join(texts, "")
+		delta.ReasoningContent = strings.Join(thoughts, "")
+		delta.ToolCalls = toolCalls
+		choice := openai_chat.StreamChoice{Index: int(candidate.Index), Delta: delta}
+		if candidate.FinishReason != "" {
+			finishReason := convertFinishReason(candidate.FinishReason, c.toolCallIndexes[candidate.Index] > 0)
+			choice.FinishReason = &finishReason
+		}
+		choices = append(choices, choice)
+	}
+	if len(choices) == 0 && geminiResp.PromptFeedback != nil && geminiResp.PromptFeedback.BlockReason != "" {
+		finishReason := openai_chat.FinishReasonContentFilter
+		choices = append(choices, openai_chat.StreamChoice{Delta: openai_chat.Delta{Role: "assistant"}, FinishReason: &finishReason})
+	}
+	return c.newChunk(choices, nil), nil
+}
+
+func (c *StreamConverter) newChunk(choices []openai_chat.StreamChoice, usage *openai.Usage) openai_chat.StreamResponse {
+	if choices == nil {
+		choices = []openai_chat.StreamChoice{}
+	}
+	return openai_chat.StreamResponse{
+		ID:      c.id,
+		Object:  openai_chat.ObjectChatCompletionChunk,
+		Created: c.created,
+		Model:   c.model,
+		Choices: choices,
+		Usage:   usage,
+	}
+}
+
+func convertFunctionCall(part *genai.Part) (openai_chat.ToolCall, error) {
+	args := part.FunctionCall.Args
+	if args == nil {
+		args = map[string]any{}
+	}
+	arguments, err := json.Marshal(args)
+	if err != nil {
+		return openai_chat.ToolCall{}, fmt.Errorf("failed to marshal function call args: %v", err)
+	}
+	id := part.FunctionCall.ID
+	if id == "" {
+		id = openai_chat.NewToolCallID()
+	}
+	toolCall := openai_chat.ToolCall{
+		ID:       id,
+		Type:     "function",
+		Function: openai_chat.FunctionCall{Name: part.FunctionCall.Name, Arguments: string(arguments)},
+	}
+	// the thought signature is sent back by clients with the tool call in the next turn
+	if len(part.ThoughtSignature) > 0 {
+		toolCall.ExtraContent = map[string]any{
+			ExtraContentKey: map[string]any{ThoughtSignatureKey: base64.StdEncoding.EncodeToString(part.ThoughtSignature)},
+		}
+	}
+	return toolCall, nil
+}
+
+func convertFinishReason(reason genai.FinishReason, hasToolCalls bool) string {
+	if hasToolCalls {
+		return openai_chat.FinishReasonToolCalls
+	}
+	switch reason {
+	case genai.FinishReasonStop, "":
+		return openai_chat.FinishReasonStop
+	case genai.FinishReasonMaxTokens:
+		return openai_chat.FinishReasonLength
+	case genai.FinishReasonSafety, genai.FinishReasonRecitation, genai.FinishReasonBlocklist,
+		genai.FinishReasonProhibitedContent, genai.FinishReasonSPII, genai.FinishReasonImageSafety:
+		return openai_chat.FinishReasonContentFilter
+	default:
+		return openai_chat.FinishReasonStop
+	}
+}
+
+// convertUsage converts usage metadata to OpenAI usage, thoughts are counted as completion tokens, the same as OpenAI reasoning tokens.
+func convertUsage(metadata *genai.GenerateContentResponseUsageMetadata) *openai.Usage {
+	if metadata == nil || metadata.TotalTokenCount == 0 {
+		return nil
+	}
+	usage := &openai.Usage{
+		PromptTokens:     int(metadata.PromptTokenCount + metadata.ToolUsePromptTokenCount),
+		CompletionTokens: int(metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount),
+		TotalTokens:      int(metadata.TotalTokenCount),
+	}
+	if metadata.CachedContentTokenCount > 0 {
+		usage.PromptTokensDetails = &openai.PromptTokensDetails{CachedTokens: int(metadata.CachedContentTokenCount)}
+	}
+	if metadata.ThoughtsTokenCount > 0 {
+		usage.CompletionTokensDetails = &openai.CompletionTokensDetails{ReasoningTokens: int(metadata.ThoughtsTokenCount)}
+	}
+	return usage
+}
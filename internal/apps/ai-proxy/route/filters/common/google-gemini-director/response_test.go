@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google_gemini_director
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+)
+
+func TestConvertResponse(t *testing.T) {
+	body := `{
+		"candidates": [{
+			"content": {"role": "model", "parts": [
+				{"text": "Let me think.", "thought": true},
+				{"text": "Checking "},
+				{"text": "the weather."},
+				{"functionCall": {"name": "get_weather", "args": {"city": "Hangzhou"}}, "thoughtSignature": "c2ln"}
+			]},
+			"finishReason": "STOP"
+		}],
+		"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5, "thoughtsTokenCount": 3, "cachedContentTokenCount": 4, "totalTokenCount": 18}
+	}`
+	out, err := ConvertResponse([]byte(body), "gemini-2.5-flash")
+	require.NoError(t, err)
+
+	var resp openai_chat.Response
+	require.NoError(t, json.Unmarshal(out, &resp))
+	assert.Equal(t, "gemini-2.5-flash", resp.Model)
+	require.Len(t, resp.Choices, 1)
+	choice := resp.Choices[0]
+	assert.Equal(t, openai_chat.FinishReasonToolCalls, choice.FinishReason)
+	text, err := choice.Message.Text()
+	require.NoError(t, err)
+	assert.Equal(t, "Checking the weather.", text)
+	assert.Equal(t, "Let me think.", choice.Message.ReasoningContent)
+	require.Len(t, choice.Message.ToolCalls, 1)
+	toolCall := choice.Message.ToolCalls[0]
+	assert.True(t, strings.HasPrefix(toolCall.ID, "call_"))
+	assert.JSONEq(t, `{"city":"Hangzhou"}`, toolCall.Function.Arguments)
+	assert.Equal(t, map[string]any{"google": map[string]any{"thought_signature": "c2ln"}}, toolCall.ExtraContent)
+
+	assert.Equal(t, 10, resp.Usage.PromptTokens)
+	assert.Equal(t, 8, resp.Usage.CompletionTokens)
+	assert.Equal(t, 18, resp.Usage.TotalTokens)
+	assert.Equal(t, 4, resp.Usage.PromptTokensDetails.CachedTokens)
+	assert.Equal(t, 3, resp.Usage.CompletionTokensDetails.ReasoningTokens)
+}
+
+func TestConvertResponse_FinishReason(t *testing.T) {
+	for reason, expected := range map[string]string{
+		"STOP":       openai_chat.FinishReasonStop,
+		"MAX_TOKENS": openai_chat.FinishReasonLength,
+		"SAFETY":     openai_chat.FinishReasonContentFilter,
+		"RECITATION": openai_chat.FinishReasonContentFilter,
+	} {
+		body := `{"candidates": [{"content": {"role": "model", "parts": [{"text": "hi"}]}, "finishReason": "` + reason + `"}]}`
+		out, err := ConvertResponse([]byte(body), "m")
+		require.NoError(t, err)
+		var resp openai_chat.Response
+		require.NoError(t, json.Unmarshal(out, &resp))
+		assert.Equal(t, expected, resp.Choices[0].FinishReason, reason)
+	}
+}
+
+func TestStreamConverter(t *testing.T) {
+	stream := "data: {\"candidates\": [{\"content\": {\"role\": \"model\", \"parts\": [{\"text\": \"hmm\", \"thought\": true}]}}]}\r\n\r\n" +
+		"data: {\"candidates\": [{\"content\": {\"role\": \"model\", \"parts\": [{\"text\": \"Hello\"}]}}], \"usageMetadata\": {\"promptTokenCount\": 2, \"totalTokenCount\": 2}}\r\n\r\n" +
+		"data: {\"candidates\": [{\"content\": {\"role\": \"model\", \"parts\": [{\"functionCall\": {\"name\": \"f\", \"args\": {}}}]}, \"finishReason\": \"STOP\"}], " +
+		"\"usageMetadata\": {\"promptTokenCount\": 2, \"candidatesTokenCount\": 3, \"totalTokenCount\": 5}}\r\n\r\n"
+
+	c := NewStreamConverter("gemini-2.5-flash")
+	var out []byte
+	// a line may be split into several chunks
+	for i := 0; i < len(stream); i += 7 {
+		end := min(i+7, len(stream))
+		b, err := c.Convert([]byte(stream[i:end]))
+		require.NoError(t, err)
+		out = append(out, b...)
+	}
+	b, err := c.Finish()
+	require.NoError(t, err)
+	out = append(out, b...)
+
+	var chunks []openai_chat.StreamResponse
+	events := strings.Split(strings.TrimSpace(string(out)), "\n\n")
+	require.Len(t, events, 5)
+	assert.Equal(t, "data: [DONE]", events[4])
+	for _, event := range events[:4] {
+		var chunk openai_chat.StreamResponse
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(event, "data: ")), &chunk))
+		chunks = append(chunks, chunk)
+	}
+
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "hmm", chunks[0].Choices[0].Delta.ReasoningContent)
+	assert.Empty(t, chunks[1].Choices[0].Delta.Role)
+	assert.Equal(t, "Hello", chunks[1].Choices[0].Delta.Content)
+	assert.Nil(t, chunks[1].Usage)
+	toolCall := chunks[2].Choices[0].Delta.ToolCalls[0]
+	assert.Equal(t, 0, *toolCall.Index)
+	assert.Equal(t, "f", toolCall.Function.Name)
+	assert.Equal(t, openai_chat.FinishReasonToolCalls, *chunks[2].Choices[0].FinishReason)
+
+	// the last usage is sent with empty choices
+	assert.Empty(t, chunks[3].Choices)
+	assert.Equal(t, 5, chunks[3].Usage.TotalTokens)
+	assert.Equal(t, 3, chunks[3].Usage.CompletionTokens)
+	for _, chunk := range chunks {
+		assert.Equal(t, chunks[0].ID, chunk.ID)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guardrailutil
+
+import (
+	"github.com/erda-project/erda/pkg/desensitize"
+)
+
+// promptFields are top-level fields of OpenAI-compatible request bodies carrying user input.
+var promptFields = []string{"messages", "prompt", "input", "instructions", "system"}
+
+// skipFields hold identifiers or binary data instead of text, they are never scanned.
+var skipFields = map[string]bool{
+	"role":         true,
+	"type":         true,
+	"name":         true,
+	"id":           true,
+	"tool_call_id": true,
+	"image_url":    true,
+	"input_audio":  true,
+	"file":         true,
+	"url":          true,
+	"data":         true,
+	"format":       true,
+	"media_type":   true,
+	"mime_type":    true,
+	"detail":       true,
+}
+
+// ScanRequestBody finds sensitive data in text of prompt fields of the decoded JSON body.
+// Found data is masked in place if mask is true.
+func ScanRequestBody(body map[string]any, kinds []desensitize.Kind, mask bool) []desensitize.Finding {
+	var findings []desensitize.Finding
+	for _, field := range promptFields {
+		v, ok := body[field]
+		if !ok {
+			continue
+		}
+		body[field] = walk(v, kinds, mask, &findings)
+	}
+	return findings
+}
+
+func walk(v any, kinds []desensitize.Kind, mask bool, findings *[]desensitize.Finding) any {
+	switch vv := v.(type) {
+	case string:
+		masked, found := desensitize.MaskText(vv, kinds...)
+		*findings = append(*findings, found...)
+		if mask {
+			return masked
+		}
+		return vv
+	case map[string]any:
+		for k, item := range vv {
+			if skipFields[k] {
+				continue
+			}
+			vv[k] = walk(item, kinds, mask, findings)
+		}
+		return vv
+	case []any:
+		for i, item := range vv {
+			vv[i] = walk(item, kinds, mask, findings)
+		}
+		return vv
+	default:
+		return v
+	}
+}
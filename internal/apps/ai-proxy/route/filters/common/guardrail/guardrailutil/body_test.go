@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guardrailutil
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/pkg/desensitize"
+)
+
+func TestScanRequestBody(t *testing.T) {
+	var body map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "gpt-4o",
+		"messages": [
+			{"role": "system", "content": "reply to foo@example.com"},
+			{"role": "user", "name": "13812345678", "content": [
+				{"type": "text", "text": "my phone is 13812345678"},
+				{"type": "image_url", "image_url": {"url": "https://example.com/13812345678.png"}}
+			]}
+		],
+		"user": "13812345678"
+	}`), &body))
+
+	findings := ScanRequestBody(body, nil, false)
+	require.Equal(t, "email:1,mobile:1", Summarize(findings))
+	require.Equal(t, "reply to foo@example.com", body["messages"].([]any)[0].(map[string]any)["content"])
+
+	findings = ScanRequestBody(body, []desensitize.Kind{desensitize.KindMobile}, true)
+	require.Equal(t, "mobile:1", Summarize(findings))
+	messages := body["messages"].([]any)
+	require.Equal(t, "reply to foo@example.com", messages[0].(map[string]any)["content"])
+	parts := messages[1].(map[string]any)["content"].([]any)
+	require.Equal(t, "my phone is 138****5678", parts[0].(map[string]any)["text"])
+	require.Equal(t, "https://example.com/13812345678.png", parts[1].(map[string]any)["image_url"].(map[string]any)["url"])
+	require.Equal(t, "13812345678", messages[1].(map[string]any)["name"])
+}
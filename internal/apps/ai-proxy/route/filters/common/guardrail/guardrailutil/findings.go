@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guardrailutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/audithelper"
+	"github.com/erda-project/erda/pkg/desensitize"
+)
+
+// Summarize counts findings by kind, e.g. "email:1,mobile:2".
+// Raw values are never part of the summary, so it is safe to persist.
+func Summarize(findings []desensitize.Finding) string {
+	counts := make(map[desensitize.Kind]int)
+	for _, f := range findings {
+		counts[f.Kind]++
+	}
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s:%d", kind, counts[desensitize.Kind(kind)]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// NoteFindings records findings of the stage into the audit metadata.
+func NoteFindings(ctx context.Context, stage Stage, action Action, findings []desensitize.Finding) {
+	if len(findings) == 0 {
+		return
+	}
+	audithelper.Note(ctx, "guardrail."+string(stage)+".action", string(action))
+	audithelper.Note(ctx, "guardrail."+string(stage)+".findings", Summarize(findings))
+}
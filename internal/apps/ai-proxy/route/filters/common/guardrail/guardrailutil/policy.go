@@ -0,0 +1,187 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guardrailutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	settingpb "github.com/erda-project/erda-proto-go/apps/aiproxy/setting/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/cache/cachetypes"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/pkg/desensitize"
+)
+
+const (
+	SettingNamespace = "guardrail"
+	// SettingKeyPolicies holds a JSON array of Policy.
+	SettingKeyPolicies = "policies"
+)
+
+const matchAll = "*"
+
+type Action string
+
+const (
+	// ActionMask replaces sensitive data with masked text and lets the call go on.
+	ActionMask Action = "mask"
+	// ActionBlock rejects the prompt, or stops the completion with finish_reason `content_filter`.
+	ActionBlock Action = "block"
+	// ActionAudit only records findings.
+	ActionAudit Action = "audit"
+)
+
+type Stage string
+
+const (
+	StageRequest  Stage = "request"
+	StageResponse Stage = "response"
+)
+
+// Policy opts a client in to the guardrail.
+//
+// ClientID is required, "*" applies the policy to every client without its own policy.
+// Kinds limits detected data kinds, all kinds of pkg/desensitize if empty.
+// Stages limits the guardrail to prompts or completions, both if empty.
+type Policy struct {
+	ClientID string             `json:"client_id"`
+	Action   Action             `json:"action"`
+	Kinds    []desensitize.Kind `json:"kinds,omitempty"`
+	Stages   []Stage            `json:"stages,omitempty"`
+}
+
+func (p *Policy) Validate() error {
+	if strings.TrimSpace(p.ClientID) == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	switch p.Action {
+	case ActionMask, ActionBlock, ActionAudit:
+	default:
+		return fmt.Errorf("invalid action: %q", p.Action)
+	}
+	for _, kind := range p.Kinds {
+		if !knownKind(kind) {
+			return fmt.Errorf("invalid kind: %q", kind)
+		}
+	}
+	for _, stage := range p.Stages {
+		if stage != StageRequest && stage != StageResponse {
+			return fmt.Errorf("invalid stage: %q", stage)
+		}
+	}
+	return nil
+}
+
+// AppliesTo reports whether the policy guards the stage.
+func (p *Policy) AppliesTo(stage Stage) bool {
+	if len(p.Stages) == 0 {
+		return true
+	}
+	for _, s := range p.Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+func knownKind(kind desensitize.Kind) bool {
+	for _, rule := range desensitize.Rules {
+		if rule.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolvePolicy returns the policy of the client guarding the stage, a client's own policy wins over the "*" one.
+func ResolvePolicy(ctx context.Context, clientID string, stage Stage) (*Policy, bool) {
+	var fallback *Policy
+	for _, p := range resolvePolicies(ctx) {
+		switch strings.TrimSpace(p.ClientID) {
+		case clientID:
+			return p, p.AppliesTo(stage)
+		case matchAll:
+			if fallback == nil {
+				fallback = p
+			}
+		}
+	}
+	return fallback, fallback != nil && fallback.AppliesTo(stage)
+}
+
+func resolvePolicies(ctx context.Context) []*Policy {
+	if cache, ok := ctxhelper.GetCacheManager(ctx); ok && cache != nil {
+		if manager, ok := cache.(cachetypes.Manager); ok && manager != nil {
+			if _, settingsV, err := manager.ListAll(ctx, cachetypes.ItemTypeSetting); err == nil {
+				if list, ok := settingsV.([]*settingpb.Setting); ok {
+					return buildPoliciesFromList(ctx, list)
+				}
+			}
+		}
+	}
+
+	dbClient, ok := ctxhelper.GetDBClient(ctx)
+	if !ok || dbClient == nil {
+		return nil
+	}
+	item, err := dbClient.SettingClient().GetByNamespaceKey(ctx, SettingNamespace, SettingKeyPolicies)
+	if err != nil {
+		return nil
+	}
+	return buildPoliciesFromList(ctx, []*settingpb.Setting{item.ToProtobuf()})
+}
+
+func buildPoliciesFromList(ctx context.Context, items []*settingpb.Setting) []*Policy {
+	var policies []*Policy
+	for _, item := range items {
+		if item == nil || item.Namespace != SettingNamespace || item.Key != SettingKeyPolicies {
+			continue
+		}
+		policies = append(policies, ParsePolicies(ctx, item.Value)...)
+	}
+	return policies
+}
+
+// ParsePolicies parses the setting value, invalid policies are logged and ignored.
+func ParsePolicies(ctx context.Context, value string) []*Policy {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	var policies []*Policy
+	if err := json.Unmarshal([]byte(value), &policies); err != nil {
+		if l, ok := ctxhelper.GetLogger(ctx); ok {
+			l.Warnf("invalid guardrail policies setting, ignored: %v", err)
+		}
+		return nil
+	}
+	valid := policies[:0]
+	for _, p := range policies {
+		if p == nil {
+			continue
+		}
+		if err := p.Validate(); err != nil {
+			if l, ok := ctxhelper.GetLogger(ctx); ok {
+				l.Warnf("invalid guardrail policy %+v, ignored: %v", *p, err)
+			}
+			continue
+		}
+		valid = append(valid, p)
+	}
+	return valid
+}
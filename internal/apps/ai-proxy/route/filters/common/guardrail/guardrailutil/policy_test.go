@@ -0,0 +1,44 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guardrailutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/pkg/desensitize"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies := ParsePolicies(context.Background(), `[
+		{"client_id":"client-1","action":"mask","kinds":["mobile","email"],"stages":["response"]},
+		{"client_id":"*","action":"audit"},
+		{"action":"block"},
+		{"client_id":"client-2","action":"drop"},
+		{"client_id":"client-3","action":"mask","kinds":["ssn"]},
+		{"client_id":"client-4","action":"mask","stages":["both"]}
+	]`)
+	require.Len(t, policies, 2)
+
+	require.Equal(t, []desensitize.Kind{desensitize.KindMobile, desensitize.KindEmail}, policies[0].Kinds)
+	require.False(t, policies[0].AppliesTo(StageRequest))
+	require.True(t, policies[0].AppliesTo(StageResponse))
+	require.True(t, policies[1].AppliesTo(StageRequest))
+	require.True(t, policies[1].AppliesTo(StageResponse))
+
+	require.Nil(t, ParsePolicies(context.Background(), `not-json`))
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mcptoolutil has the tool level access control and audit helpers of mcp-proxy.
+package mcptoolutil
+
+import (
+	"fmt"
+	"path"
+)
+
+// Policy controls which tools of a mcp server a client can call.
+//
+// Tools not matching AllowTools (all tools if empty) or matching DenyTools are denied, DenyTools wins.
+// Patterns are matched by path.Match, e.g. `delete_*`.
+type Policy struct {
+	AllowTools        []string
+	DenyTools         []string
+	ValidateArguments bool
+}
+
+// Allows reports whether the tool can be called, a nil policy allows all tools.
+func (p *Policy) Allows(tool string) bool {
+	if p == nil {
+		return true
+	}
+	if matchAny(p.DenyTools, tool) {
+		return false
+	}
+	return len(p.AllowTools) == 0 || matchAny(p.AllowTools, tool)
+}
+
+func matchAny(patterns []string, tool string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, tool); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePatterns checks tool name patterns.
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return fmt.Errorf("empty tool name pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool name pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcptoolutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Allows(t *testing.T) {
+	var nilPolicy *Policy
+	assert.True(t, nilPolicy.Allows("delete_user"))
+
+	p := &Policy{DenyTools: []string{"delete_*"}}
+	assert.True(t, p.Allows("get_user"))
+	assert.False(t, p.Allows("delete_user"))
+
+	p = &Policy{AllowTools: []string{"get_*", "list_users"}, DenyTools: []string{"get_secret"}}
+	assert.True(t, p.Allows("get_user"))
+	assert.True(t, p.Allows("list_users"))
+	assert.False(t, p.Allows("update_user"))
+	assert.False(t, p.Allows("get_secret"))
+}
+
+func TestValidatePatterns(t *testing.T) {
+	assert.NoError(t, ValidatePatterns([]string{"get_*", "list_users"}))
+	assert.Error(t, ValidatePatterns([]string{""}))
+	assert.Error(t, ValidatePatterns([]string{"get_["}))
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcptoolutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// Status of tool calls
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	// StatusError is set for both JSON-RPC errors and tool results with `isError`.
+	StatusError   = "error"
+	StatusDenied  = "denied"
+	StatusInvalid = "invalid"
+)
+
+// JSON-RPC error codes of rejected tool calls
+const (
+	CodeToolDenied     = -32001
+	CodeInvalidRequest = -32600
+	CodeInvalidParams  = -32602
+)
+
+// MaxArgumentsBytes limits arguments kept in audit records.
+const MaxArgumentsBytes = 16 << 10
+
+const maxErrorBytes = 1 << 10
+
+// RPCID returns the JSON of a JSON-RPC id, so ids of requests and responses can be compared.
+func RPCID(id any) string {
+	if raw, ok := id.(json.RawMessage); ok {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err == nil {
+			return buf.String()
+		}
+		return string(raw)
+	}
+	b, _ := json.Marshal(id)
+	return string(b)
+}
+
+// Outcome is the result of a finished tool call.
+type Outcome struct {
+	RPCID       string
+	Status      string
+	Error       string
+	ResultBytes int64
+	// IsToolResult is false for results of other methods, e.g., `tools/list`; errors may be of any method.
+	IsToolResult bool
+}
+
+// ParseResponse parses a JSON-RPC response of a JSON body or a `data:` line of server-sent events.
+// Returns false if the message is not a response, e.g., an event line, a request or a notification.
+func ParseResponse(chunk []byte) (*Outcome, bool) {
+	line := bytes.TrimSpace(chunk)
+	if bytes.HasPrefix(line, []byte("data:")) {
+		line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	}
+	if len(line) == 0 || line[0] != '{' {
+		return nil, false
+	}
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(line, &msg); err != nil || len(msg.ID) == 0 || string(msg.ID) == "null" {
+		return nil, false
+	}
+	outcome := &Outcome{RPCID: RPCID(msg.ID)}
+	switch {
+	case msg.Error != nil:
+		outcome.Status = StatusError
+		outcome.Error = truncate(msg.Error.Message, maxErrorBytes)
+	case len(msg.Result) > 0:
+		outcome.Status = StatusSuccess
+		outcome.ResultBytes = int64(len(msg.Result))
+		var result struct {
+			IsError bool `json:"isError"`
+			Content []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		}
+		if err := json.Unmarshal(msg.Result, &result); err != nil {
+			return outcome, true
+		}
+		outcome.IsToolResult = result.Content != nil
+		if result.IsError {
+			outcome.Status = StatusError
+			var texts []string
+			for _, c := range result.Content {
+				if c.Type == "text" {
+					texts = append(texts, c.Text)
+				}
+			}
+			outcome.Error = truncate(strings.Join(texts, "\n"), maxErrorBytes)
+		}
+	default:
+		return nil, false
+	}
+	return outcome, true
+}
+
+// ErrorResponse builds a JSON-RPC error response.
+func ErrorResponse(id any, code int, message string) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error": map[string]any{
+			"code":    code,
+			"message": message,
+		},
+	})
+	return b
+}
+
+// MarshalArguments encodes arguments for audit, truncated to MaxArgumentsBytes.
+func MarshalArguments(args map[string]any) string {
+	if len(args) == 0 {
+		return "{}"
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	return truncate(string(b), MaxArgumentsBytes)
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "...(truncated)"
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcptoolutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCID(t *testing.T) {
+	var req struct {
+		ID any `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 7}`), &req))
+	assert.Equal(t, "7", RPCID(req.ID))
+	assert.Equal(t, RPCID(req.ID), RPCID(json.RawMessage(` 7 `)))
+	assert.Equal(t, `"a-1"`, RPCID("a-1"))
+}
+
+func TestParseResponse(t *testing.T) {
+	t.Run("success in sse", func(t *testing.T) {
+		got, ok := ParseResponse([]byte(`data: {"jsonrpc":"2.0","id":3,"result":{"content":[{"type":"text","text":"ok"}]}}` + "\n"))
+		require.True(t, ok)
+		assert.Equal(t, "3", got.RPCID)
+		assert.Equal(t, StatusSuccess, got.Status)
+		assert.Equal(t, int64(len(`{"content":[{"type":"text","text":"ok"}]}`)), got.ResultBytes)
+		assert.True(t, got.IsToolResult)
+	})
+	t.Run("tool error", func(t *testing.T) {
+		got, ok := ParseResponse([]byte(`{"jsonrpc":"2.0","id":"x","result":{"isError":true,"content":[{"type":"text","text":"no such user"}]}}`))
+		require.True(t, ok)
+		assert.Equal(t, StatusError, got.Status)
+		assert.Equal(t, "no such user", got.Error)
+	})
+	t.Run("rpc error", func(t *testing.T) {
+		got, ok := ParseResponse([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"bad params"}}`))
+		require.True(t, ok)
+		assert.Equal(t, StatusError, got.Status)
+		assert.Equal(t, "bad params", got.Error)
+	})
+	t.Run("result of other methods", func(t *testing.T) {
+		got, ok := ParseResponse([]byte(`{"jsonrpc":"2.0","id":2,"result":{"tools":[]}}`))
+		require.True(t, ok)
+		assert.False(t, got.IsToolResult)
+	})
+	t.Run("not a response", func(t *testing.T) {
+		for _, chunk := range []string{
+			"event: message",
+			"",
+			`data: {"jsonrpc":"2.0","method":"notifications/progress","params":{}}`,
+			`data: /message?sessionId=abc`,
+		} {
+			_, ok := ParseResponse([]byte(chunk))
+			assert.False(t, ok, chunk)
+		}
+	})
+}
+
+func TestErrorResponse(t *testing.T) {
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"denied"}}`, string(ErrorResponse(1, CodeToolDenied, "denied")))
+}
+
+func TestMarshalArguments(t *testing.T) {
+	assert.Equal(t, "{}", MarshalArguments(nil))
+	assert.Equal(t, `{"id":1}`, MarshalArguments(map[string]any{"id": 1}))
+	long := MarshalArguments(map[string]any{"text": strings.Repeat("文", MaxArgumentsBytes)})
+	assert.True(t, strings.HasSuffix(long, "...(truncated)"))
+	assert.LessOrEqual(t, len(long), MaxArgumentsBytes+len("...(truncated)"))
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ollama_director
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+)
+
+// EmbedRequest is the request of `/api/embed`.
+type EmbedRequest struct {
+	Model string `json:"model"`
+	// Input is a string or an array of strings.
+	Input      json.RawMessage `json:"input"`
+	Dimensions int             `json:"dimensions,omitempty"`
+}
+
+type EmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float64 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+// ConvertEmbeddingRequest converts an OpenAI embedding request to an Ollama `/api/embed` request.
+// Only text input is supported, token arrays are rejected.
+func ConvertEmbeddingRequest(body []byte, model string) (*EmbedRequest, error) {
+	var req struct {
+		Input          json.RawMessage `json:"input"`
+		Dimensions     int             `json:"dimensions"`
+		EncodingFormat string          `json:"encoding_format"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal embedding request: %v", err)
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" {
+		return nil, fmt.Errorf("unsupported encoding_format: %s", req.EncodingFormat)
+	}
+	var (
+		text  string
+		texts []string
+	)
+	if json.Unmarshal(req.Input, &text) != nil && json.Unmarshal(req.Input, &texts) != nil {
+		return nil, fmt.Errorf("invalid input, only a string or an array of strings is supported")
+	}
+	return &EmbedRequest{Model: model, Input: req.Input, Dimensions: req.Dimensions}, nil
+}
+
+// ConvertEmbeddingResponse converts an Ollama `/api/embed` response body to an OpenAI embedding response body.
+func ConvertEmbeddingResponse(body []byte, model string) ([]byte, error) {
+	var ollamaResp EmbedResponse
+	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ollama embed response: %v", err)
+	}
+	resp := openai_chat.EmbeddingResponse{
+		Object: "list",
+		Model:  model,
+		Data:   []openai_chat.EmbeddingData{},
+		Usage: &openai.Usage{
+			PromptTokens: ollamaResp.PromptEvalCount,
+			TotalTokens:  ollamaResp.PromptEvalCount,
+		},
+	}
+	for i, embedding := range ollamaResp.Embeddings {
+		resp.Data = append(resp.Data, openai_chat.EmbeddingData{Object: "embedding", Index: i, Embedding: embedding})
+	}
+	return json.Marshal(resp)
+}
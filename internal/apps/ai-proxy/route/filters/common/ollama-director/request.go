@@ -0,0 +1,185 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ollama_director converts OpenAI chat completions and embeddings to and from the Ollama native API.
+// see: https://github.com/ollama/ollama/blob/main/docs/api.md
+package ollama_director
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+)
+
+type ChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []Message       `json:"messages"`
+	Tools    []any           `json:"tools,omitempty"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  *Options        `json:"options,omitempty"`
+	// Stream must be set explicitly, ollama streams by default.
+	Stream bool `json:"stream"`
+	// Think is a bool, or "low", "medium", "high" for gpt-oss models.
+	Think any `json:"think,omitempty"`
+}
+
+type Message struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	Thinking  string     `json:"thinking,omitempty"`
+	Images    []string   `json:"images,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolName is the name of the tool, which the result of a tool message belongs to.
+	ToolName string `json:"tool_name,omitempty"`
+}
+
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Index     int            `json:"index,omitempty"`
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+type Options struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	NumPredict       int      `json:"num_predict,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+}
+
+// OpenAIChatRequest is an OpenAI chat completion request with `think` set by the thinking-handler.
+type OpenAIChatRequest struct {
+	openai_chat.Request
+	Think any `json:"think,omitempty"`
+}
+
+// ConvertChatRequest converts an OpenAI chat completion request to an Ollama `/api/chat` request.
+func ConvertChatRequest(ctx context.Context, req OpenAIChatRequest, model string) (*ChatRequest, error) {
+	out := &ChatRequest{
+		Model:  model,
+		Stream: req.Stream,
+		Think:  req.Think,
+	}
+	toolCallNames := make(map[string]string)
+	for i, msg := range req.Messages {
+		m, err := convertMessage(ctx, msg, toolCallNames)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert message %d: %v", i, err)
+		}
+		out.Messages = append(out.Messages, m)
+	}
+
+	for _, tool := range req.Tools {
+		if tool.Type != "function" || tool.Function == nil {
+			return nil, fmt.Errorf("unsupported tool type: %s", tool.Type)
+		}
+		// ollama accepts tools in the openai format
+		out.Tools = append(out.Tools, tool)
+	}
+	// ollama has no tool_choice, tools are just not sent if none
+	toolChoice, err := openai_chat.ParseToolChoice(req.ToolChoice)
+	if err != nil {
+		return nil, err
+	}
+	if toolChoice != nil && toolChoice.Mode == openai_chat.ToolChoiceNone {
+		out.Tools = nil
+	}
+
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case "json_object":
+			out.Format = json.RawMessage(`"json"`)
+		case "json_schema":
+			if req.ResponseFormat.JSONSchema != nil && len(req.ResponseFormat.JSONSchema.Schema) > 0 {
+				out.Format = req.ResponseFormat.JSONSchema.Schema
+			} else {
+				out.Format = json.RawMessage(`"json"`)
+			}
+		}
+	}
+
+	out.Options = &Options{
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		NumPredict:       req.GetMaxTokens(),
+		Stop:             req.Stop,
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+	}
+	return out, nil
+}
+
+func convertMessage(ctx context.Context, msg openai_chat.Message, toolCallNames map[string]string) (Message, error) {
+	out := Message{Role: msg.Role, Thinking: msg.ReasoningContent}
+	switch msg.Role {
+	case "system", "user", "assistant":
+	case "developer":
+		out.Role = "system"
+	case "tool":
+		name, ok := toolCallNames[msg.ToolCallID]
+		if !ok {
+			return Message{}, fmt.Errorf("no tool call found for tool_call_id: %s", msg.ToolCallID)
+		}
+		out.ToolName = name
+	default:
+		return Message{}, fmt.Errorf("unsupported message role: %s", msg.Role)
+	}
+
+	parts, err := msg.Parts()
+	if err != nil {
+		return Message{}, err
+	}
+	for _, part := range parts {
+		switch part.Type {
+		case openai_chat.PartTypeText:
+			if out.Content != "" {
+				out.Content += "\n"
+			}
+			out.Content += part.Text
+		case openai_chat.PartTypeImageURL:
+			if part.ImageURL == nil {
+				return Message{}, fmt.Errorf("missing image_url")
+			}
+			_, data, err := openai_chat.FetchImage(ctx, part.ImageURL.URL)
+			if err != nil {
+				return Message{}, err
+			}
+			out.Images = append(out.Images, base64.StdEncoding.EncodeToString(data))
+		default:
+			return Message{}, fmt.Errorf("unsupported content part type: %s", part.Type)
+		}
+	}
+
+	for _, toolCall := range msg.ToolCalls {
+		toolCallNames[toolCall.ID] = toolCall.Function.Name
+		args := map[string]any{}
+		if toolCall.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+				return Message{}, fmt.Errorf("invalid arguments of tool call %s: %v", toolCall.ID, err)
+			}
+		}
+		out.ToolCalls = append(out.ToolCalls, ToolCall{Function: ToolCallFunction{Name: toolCall.Function.Name, Arguments: args}})
+	}
+	return out, nil
+}
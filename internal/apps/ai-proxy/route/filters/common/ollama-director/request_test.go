@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ollama_director
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertChatRequest(t *testing.T) {
+	body := `{
+		"model": "qwen3",
+		"messages": [
+			{"role": "developer", "content": "Be brief."},
+			{"role": "user", "content": [
+				{"type": "text", "text": "Describe"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,aGVsbG8="}}
+			]},
+			{"role": "assistant", "content": "", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Hangzhou\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "sunny"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}],
+		"stream": true,
+		"max_completion_tokens": 64,
+		"stop": ["END"],
+		"response_format": {"type": "json_object"},
+		"think": true
+	}`
+	var req OpenAIChatRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+
+	out, err := ConvertChatRequest(context.Background(), req, "qwen3:8b")
+	require.NoError(t, err)
+
+	b, err := json.Marshal(out)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"model": "qwen3:8b",
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": "Describe", "images": ["aGVsbG8="]},
+			{"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "get_weather", "arguments": {"city": "Hangzhou"}}}]},
+			{"role": "tool", "content": "sunny", "tool_name": "get_weather"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}],
+		"format": "json",
+		"options": {"num_predict": 64, "stop": ["END"]},
+		"stream": true,
+		"think": true
+	}`, string(b))
+}
+
+func TestConvertChatRequest_StreamFalse(t *testing.T) {
+	var req OpenAIChatRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"messages": [{"role": "user", "content": "hi"}], "tool_choice": "none",
+		"tools": [{"type": "function", "function": {"name": "f"}}]}`), &req))
+	out, err := ConvertChatRequest(context.Background(), req, "llama3")
+	require.NoError(t, err)
+	b, err := json.Marshal(out)
+	require.NoError(t, err)
+	// ollama streams by default
+	assert.Contains(t, string(b), `"stream":false`)
+	assert.NotContains(t, string(b), `"tools"`)
+}
+
+func TestConvertEmbeddingRequest(t *testing.T) {
+	out, err := ConvertEmbeddingRequest([]byte(`{"model": "x", "input": ["a", "b"], "dimensions": 256}`), "nomic-embed-text")
+	require.NoError(t, err)
+	b, err := json.Marshal(out)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"model": "nomic-embed-text", "input": ["a", "b"], "dimensions": 256}`, string(b))
+
+	_, err = ConvertEmbeddingRequest([]byte(`{"input": [1, 2, 3]}`), "nomic-embed-text")
+	assert.Error(t, err)
+	_, err = ConvertEmbeddingRequest([]byte(`{"input": "a", "encoding_format": "base64"}`), "nomic-embed-text")
+	assert.Error(t, err)
+}
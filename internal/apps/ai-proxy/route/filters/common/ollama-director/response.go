@@ -0,0 +1,198 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ollama_director
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+)
+
+// ChatResponse is the response of `/api/chat`, or a line of the stream.
+type ChatResponse struct {
+	Model           string  `json:"model"`
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	DoneReason      string  `json:"done_reason"`
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+	Error           string  `json:"error"`
+}
+
+// ConvertChatResponse converts an Ollama `/api/chat` response body to an OpenAI chat completion response body.
+func ConvertChatResponse(body []byte, model string) ([]byte, error) {
+	var ollamaResp ChatResponse
+	if err := json.Unmarshal(body, &ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ollama chat response: %v", err)
+	}
+	if ollamaResp.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", ollamaResp.Error)
+	}
+	toolCalls := convertToolCalls(ollamaResp.Message.ToolCalls)
+	resp := openai_chat.Response{
+		ID:      openai_chat.NewID(),
+		Object:  openai_chat.ObjectChatCompletion,
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []openai_chat.Choice{{
+			Message: openai_chat.Message{
+				Role:             "assistant",
+				Content:          openai_chat.StringContent(ollamaResp.Message.Content),
+				ReasoningContent: ollamaResp.Message.Thinking,
+				ToolCalls:        toolCalls,
+			},
+			FinishReason: convertDoneReason(ollamaResp.DoneReason, len(toolCalls) > 0),
+		}},
+		Usage: convertUsage(&ollamaResp),
+	}
+	return json.Marshal(resp)
+}
+
+// StreamConverter converts an Ollama ndjson stream to an OpenAI chat completion stream.
+// It is stateful, one converter for one stream.
+type StreamConverter struct {
+	id      string
+	model   string
+	created int64
+
+	lines         openai_chat.LineBuffer
+	started       bool
+	toolCallIndex int
+	usage         *openai.Usage
+}
+
+func NewStreamConverter(model string) *StreamConverter {
+	return &StreamConverter{id: openai_chat.NewID(), model: model, created: time.Now().Unix()}
+}
+
+// Convert converts a chunk of the Ollama stream, returns converted events, may be empty.
+func (c *StreamConverter) Convert(chunk []byte) ([]byte, error) {
+	return c.convertLines(c.lines.Lines(chunk, false))
+}
+
+// Finish converts the rest of the stream, and returns the usage chunk and `[DONE]`.
+func (c *StreamConverter) Finish() ([]byte, error) {
+	out, err := c.convertLines(c.lines.Lines(nil, true))
+	if err != nil {
+		return nil, err
+	}
+	if c.usage != nil {
+		usageChunk, err := openai_chat.SSEChunk(c.newChunk([]openai_chat.StreamChoice{}, c.usage))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, usageChunk...)
+	}
+	return append(out, vars.ConcatChunkDataPrefix([]byte("[DONE]"))...), nil
+}
+
+func (c *StreamConverter) convertLines(lines []string) ([]byte, error) {
+	var out []byte
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var ollamaResp ChatResponse
+		if err := json.Unmarshal([]byte(line), &ollamaResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal ollama stream line: %v", err)
+		}
+		if ollamaResp.Error != "" {
+			return nil, fmt.Errorf("ollama error: %s", ollamaResp.Error)
+		}
+		delta := openai_chat.Delta{
+			Content:          ollamaResp.Message.Content,
+			ReasoningContent: ollamaResp.Message.Thinking,
+		}
+		if !c.started {
+			c.started = true
+			delta.Role = "assistant"
+		}
+		for _, toolCall := range convertToolCalls(ollamaResp.Message.ToolCalls) {
+			index := c.toolCallIndex
+			c.toolCallIndex++
+			toolCall.Index = &index
+			delta.ToolCalls = append(delta.ToolCalls, toolCall)
+		}
+		choice := openai_chat.StreamChoice{Delta: delta}
+		if ollamaResp.Done {
+			finishReason := convertDoneReason(ollamaResp.DoneReason, c.toolCallIndex > 0)
+			choice.FinishReason = &finishReason
+			c.usage = convertUsage(&ollamaResp)
+		}
+		event, err := openai_chat.SSEChunk(c.newChunk([]openai_chat.StreamChoice{choice}, nil))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, event...)
+	}
+	return out, nil
+}
+
+func (c *StreamConverter) newChunk(choices []openai_chat.StreamChoice, usage *openai.Usage) openai_chat.StreamResponse {
+	return openai_chat.StreamResponse{
+		ID:      c.id,
+		Object:  openai_chat.ObjectChatCompletionChunk,
+		Created: c.created,
+		Model:   c.model,
+		Choices: choices,
+		Usage:   usage,
+	}
+}
+
+// convertToolCalls generates ids for tool calls, ollama doesn't have them.
+func convertToolCalls(toolCalls []ToolCall) []openai_chat.ToolCall {
+	var result []openai_chat.ToolCall
+	for _, toolCall := range toolCalls {
+		args := toolCall.Function.Arguments
+		if args == nil {
+			args = map[string]any{}
+		}
+		arguments, _ := json.Marshal(args)
+		result = append(result, openai_chat.ToolCall{
+			ID:       openai_chat.NewToolCallID(),
+			Type:     "function",
+			Function: openai_chat.FunctionCall{Name: toolCall.Function.Name, Arguments: string(arguments)},
+		})
+	}
+	return result
+}
+
+func convertDoneReason(reason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return openai_chat.FinishReasonToolCalls
+	}
+	if reason == "length" {
+		return openai_chat.FinishReasonLength
+	}
+	return openai_chat.FinishReasonStop
+}
+
+func convertUsage(resp *ChatResponse) *openai.Usage {
+	if resp.PromptEvalCount == 0 && resp.EvalCount == 0 {
+		return nil
+	}
+	return &openai.Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ollama_director
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+)
+
+func TestConvertChatResponse(t *testing.T) {
+	body := `{"model": "qwen3:8b", "message": {"role": "assistant", "content": "", "thinking": "hmm",
+		"tool_calls": [{"function": {"name": "get_weather", "arguments": {"city": "Hangzhou"}}}]},
+		"done": true, "done_reason": "stop", "prompt_eval_count": 12, "eval_count": 7}`
+	out, err := ConvertChatResponse([]byte(body), "qwen3")
+	require.NoError(t, err)
+
+	var resp openai_chat.Response
+	require.NoError(t, json.Unmarshal(out, &resp))
+	assert.Equal(t, "qwen3", resp.Model)
+	choice := resp.Choices[0]
+	assert.Equal(t, openai_chat.FinishReasonToolCalls, choice.FinishReason)
+	assert.Equal(t, "null", string(choice.Message.Content))
+	assert.Equal(t, "hmm", choice.Message.ReasoningContent)
+	assert.True(t, strings.HasPrefix(choice.Message.ToolCalls[0].ID, "call_"))
+	assert.JSONEq(t, `{"city":"Hangzhou"}`, choice.Message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, 12, resp.Usage.PromptTokens)
+	assert.Equal(t, 7, resp.Usage.CompletionTokens)
+	assert.Equal(t, 19, resp.Usage.TotalTokens)
+
+	_, err = ConvertChatResponse([]byte(`{"error": "model not found"}`), "qwen3")
+	assert.Error(t, err)
+}
+
+func TestStreamConverter(t *testing.T) {
+	stream := `{"message": {"role": "assistant", "content": "", "thinking": "hmm"}, "done": false}
+{"message": {"role": "assistant", "content": "Hel"}, "done": false}
+{"message": {"role": "assistant", "content": "lo"}, "done": false}
+{"message": {"role": "assistant", "content": ""}, "done": true, "done_reason": "length", "prompt_eval_count": 3, "eval_count": 4}
+`
+	c := NewStreamConverter("qwen3")
+	var out []byte
+	for i := 0; i < len(stream); i += 10 {
+		b, err := c.Convert([]byte(stream[i:min(i+10, len(stream))]))
+		require.NoError(t, err)
+		out = append(out, b...)
+	}
+	b, err := c.Finish()
+	require.NoError(t, err)
+	out = append(out, b...)
+
+	events := strings.Split(strings.TrimSpace(string(out)), "\n\n")
+	require.Len(t, events, 6)
+	assert.Equal(t, "data: [DONE]", events[5])
+	var chunks []openai_chat.StreamResponse
+	for _, event := range events[:5] {
+		var chunk openai_chat.StreamResponse
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(event, "data: ")), &chunk))
+		chunks = append(chunks, chunk)
+	}
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "hmm", chunks[0].Choices[0].Delta.ReasoningContent)
+	assert.Equal(t, "Hel", chunks[1].Choices[0].Delta.Content)
+	assert.Nil(t, chunks[1].Choices[0].FinishReason)
+	assert.Equal(t, openai_chat.FinishReasonLength, *chunks[3].Choices[0].FinishReason)
+	assert.Empty(t, chunks[4].Choices)
+	assert.Equal(t, 7, chunks[4].Usage.TotalTokens)
+}
+
+func TestConvertEmbeddingResponse(t *testing.T) {
+	out, err := ConvertEmbeddingResponse([]byte(`{"model": "nomic-embed-text", "embeddings": [[0.1, 0.2], [0.3, 0.4]], "prompt_eval_count": 4}`), "embed")
+	require.NoError(t, err)
+
+	var resp openai_chat.EmbeddingResponse
+	require.NoError(t, json.Unmarshal(out, &resp))
+	assert.Equal(t, "list", resp.Object)
+	assert.Equal(t, "embed", resp.Model)
+	assert.Equal(t, []openai_chat.EmbeddingData{
+		{Object: "embedding", Index: 0, Embedding: []float64{0.1, 0.2}},
+		{Object: "embedding", Index: 1, Embedding: []float64{0.3, 0.4}},
+	}, resp.Data)
+	assert.Equal(t, 4, resp.Usage.PromptTokens)
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+}
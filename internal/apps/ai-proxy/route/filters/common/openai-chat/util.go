@@ -0,0 +1,137 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openai_chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+// MaxImageBytes limits images downloaded for providers accepting inline images only.
+const MaxImageBytes = 20 << 20
+
+// NewID generates a chat completion id.
+func NewID() string {
+	return "chatcmpl-" + strings.ReplaceAll(uuid.New(), "-", "")
+}
+
+// NewToolCallID generates a tool call id for providers don't return one.
+func NewToolCallID() string {
+	return "call_" + strings.ReplaceAll(uuid.New(), "-", "")
+}
+
+// ParseDataURL parses `data:${mime};base64,${data}`.
+func ParseDataURL(url string) (mimeType string, data []byte, ok bool, err error) {
+	if !strings.HasPrefix(url, "data:") {
+		return "", nil, false, nil
+	}
+	meta, encoded, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !found || !strings.HasSuffix(meta, ";base64") {
+		return "", nil, true, fmt.Errorf("only base64 data url is supported")
+	}
+	data, err = base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, true, fmt.Errorf("invalid base64 data url: %v", err)
+	}
+	return strings.TrimSuffix(meta, ";base64"), data, true, nil
+}
+
+// FetchImage gets the image of a data url, or downloads it from a http(s) url.
+func FetchImage(ctx context.Context, url string) (mimeType string, data []byte, err error) {
+	if mimeType, data, ok, err := ParseDataURL(url); ok {
+		return mimeType, data, err
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return "", nil, fmt.Errorf("unsupported image url: %s", url)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to download image: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("failed to download image, status: %d, url: %s", resp.StatusCode, url)
+	}
+	data, err = io.ReadAll(io.LimitReader(resp.Body, MaxImageBytes+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read image: %v", err)
+	}
+	if len(data) > MaxImageBytes {
+		return "", nil, fmt.Errorf("image is larger than %d bytes: %s", MaxImageBytes, url)
+	}
+	mimeType = resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = GuessMimeType(url)
+	}
+	return mimeType, data, nil
+}
+
+// GuessMimeType guesses the mime type by the extension of url.
+func GuessMimeType(url string) string {
+	ext := path.Ext(strings.SplitN(url, "?", 2)[0])
+	if t := mime.TypeByExtension(ext); t != "" {
+		return strings.SplitN(t, ";", 2)[0]
+	}
+	return "application/octet-stream"
+}
+
+// SSEChunk encodes a stream chunk as a server-sent event.
+func SSEChunk(chunk StreamResponse) ([]byte, error) {
+	b, err := json.Marshal(chunk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal openai chunk: %v", err)
+	}
+	return vars.ConcatChunkDataPrefix(b), nil
+}
+
+// LineBuffer splits a stream into lines across chunks, a line may be split into several chunks.
+type LineBuffer struct {
+	pending []byte
+}
+
+// Lines returns complete lines in chunk, the incomplete tail is kept for the next chunk.
+// The last call should set eof to get the tail.
+func (b *LineBuffer) Lines(chunk []byte, eof bool) []string {
+	b.pending = append(b.pending, chunk...)
+	var lines []string
+	for {
+		idx := bytes.IndexByte(b.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		lines = append(lines, strings.TrimRight(string(b.pending[:idx]), "\r"))
+		b.pending = b.pending[idx+1:]
+	}
+	if eof && len(b.pending) > 0 {
+		lines = append(lines, strings.TrimRight(string(b.pending), "\r"))
+		b.pending = nil
+	}
+	return lines
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Backend is where cached responses and the semantic index are kept: memory or redis.
+	Backend string `file:"backend" env:"RESPONSE_CACHE_BACKEND" default:"memory"`
+	// MaxBytes caps the response bodies kept by the memory backend.
+	MaxBytes int64 `file:"max_bytes" env:"RESPONSE_CACHE_MAX_BYTES" default:"268435456"`
+	// SemanticBaseURL is the address of ai-proxy itself, prompts are embedded by calling its /v1/embeddings.
+	SemanticBaseURL string        `file:"semantic_base_url" env:"RESPONSE_CACHE_SEMANTIC_BASE_URL"`
+	SemanticTimeout time.Duration `file:"semantic_timeout" env:"RESPONSE_CACHE_SEMANTIC_TIMEOUT" default:"5s"`
+}
+
+// Embedder turns a prompt into a vector for semantic caching.
+type Embedder interface {
+	// Embed embeds input with model, header carries the credential of the calling client.
+	Embed(ctx context.Context, header http.Header, model, input string) ([]float64, error)
+}
+
+// HTTPEmbedder embeds prompts through ai-proxy's OpenAI-compatible embeddings API,
+// so the embedding call is routed, audited and accounted to the same client like any other call.
+type HTTPEmbedder struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTPEmbedder(cfg Config) *HTTPEmbedder {
+	timeout := cfg.SemanticTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &HTTPEmbedder{
+		baseURL: strings.TrimRight(cfg.SemanticBaseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type embeddingRequest struct {
+	Model          string `json:"model"`
+	Input          string `json:"input"`
+	EncodingFormat string `json:"encoding_format"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *HTTPEmbedder) Embed(ctx context.Context, header http.Header, model, input string) ([]float64, error) {
+	body, err := json.Marshal(embeddingRequest{Model: model, Input: input, EncodingFormat: "float"})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/v1/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if auth := header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding response: %w", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("embedding request got non-2xx status=%d body=%q", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	var embeddingResp embeddingResponse
+	if err := json.Unmarshal(respBody, &embeddingResp); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding response: %w", err)
+	}
+	if len(embeddingResp.Data) == 0 || len(embeddingResp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("empty embedding in response")
+	}
+	return embeddingResp.Data[0].Embedding, nil
+}
+
+var (
+	embedderMu sync.RWMutex
+	embedder   Embedder
+)
+
+// GetEmbedder returns the embedder set by SetEmbedder, semantic caching is skipped without one.
+func GetEmbedder() (Embedder, bool) {
+	embedderMu.RLock()
+	defer embedderMu.RUnlock()
+	return embedder, embedder != nil
+}
+
+func SetEmbedder(e Embedder) {
+	embedderMu.Lock()
+	defer embedderMu.Unlock()
+	embedder = e
+}
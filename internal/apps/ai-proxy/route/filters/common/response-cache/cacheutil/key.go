@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ignoredBodyFields don't change what the model answers, requests only differing in them share cache entries.
+var ignoredBodyFields = []string{"user", "metadata", "store", "stream_options"}
+
+// Scope isolates cache entries, a response is never shared across clients or model instances.
+type Scope struct {
+	ClientID string `json:"client_id"`
+	ModelID  string `json:"model_id"`
+	Path     string `json:"path"`
+	// Prompt is the version of the prompt template rendered into the request, e.g. summary@3
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// RequestBody is a normalized JSON request body.
+type RequestBody map[string]any
+
+// ParseRequestBody parses a JSON object body and drops fields that don't affect the answer.
+func ParseRequestBody(body []byte) (RequestBody, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var m map[string]any
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse request body: %w", err)
+	}
+	if m == nil {
+		return nil, fmt.Errorf("request body is not a json object")
+	}
+	for _, field := range ignoredBodyFields {
+		delete(m, field)
+	}
+	return m, nil
+}
+
+// ExactKey identifies the request: same scope and same normalized body (model, messages, tools, temperature, stream, etc.).
+func ExactKey(scope Scope, body RequestBody) string {
+	return hashOf(scope, body)
+}
+
+// SemanticScope identifies requests that may share an answer by prompt similarity.
+// All fields except messages must be equal, e.g. a streaming request never reuses a non-streaming answer.
+func SemanticScope(scope Scope, body RequestBody) string {
+	rest := make(RequestBody, len(body))
+	for k, v := range body {
+		if k == "messages" {
+			continue
+		}
+		rest[k] = v
+	}
+	return hashOf(scope, rest)
+}
+
+// PromptText extracts the text of chat messages for embedding, empty if body has no text message.
+func PromptText(body RequestBody) string {
+	messages, ok := body["messages"].([]any)
+	if !ok {
+		return ""
+	}
+	var sb strings.Builder
+	for _, item := range messages {
+		msg, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		text := contentText(msg["content"])
+		if text == "" {
+			continue
+		}
+		role, _ := msg["role"].(string)
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(role)
+		sb.WriteString(": ")
+		sb.WriteString(text)
+	}
+	return sb.String()
+}
+
+func contentText(content any) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []any:
+		var parts []string
+		for _, item := range c {
+			part, ok := item.(map[string]any)
+			if !ok || part["type"] != "text" {
+				continue
+			}
+			if text, ok := part["text"].(string); ok && text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	default:
+		return ""
+	}
+}
+
+// hashOf hashes the canonical json of scope and body, json.Marshal sorts map keys.
+func hashOf(scope Scope, body RequestBody) string {
+	b, _ := json.Marshal(struct {
+		Scope Scope       `json:"scope"`
+		Body  RequestBody `json:"body"`
+	}{scope, body})
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExactKey_Normalize(t *testing.T) {
+	scope := Scope{ClientID: "client-1", ModelID: "model-1", Path: "/v1/chat/completions"}
+	parse := func(s string) RequestBody {
+		body, err := ParseRequestBody([]byte(s))
+		require.NoError(t, err)
+		return body
+	}
+
+	a := parse(`{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hi"}],"user":"u1"}`)
+	b := parse(`{"messages":[{"role":"user","content":"hi"}],"user":"u2","model":"gpt-4o","temperature":0}`)
+	require.Equal(t, ExactKey(scope, a), ExactKey(scope, b), "field order and user should not matter")
+
+	c := parse(`{"model":"gpt-4o","temperature":0.7,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NotEqual(t, ExactKey(scope, a), ExactKey(scope, c), "temperature should matter")
+
+	d := parse(`{"model":"gpt-4o","temperature":0,"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.NotEqual(t, ExactKey(scope, a), ExactKey(scope, d), "stream should matter")
+
+	otherClient := scope
+	otherClient.ClientID = "client-2"
+	require.NotEqual(t, ExactKey(scope, a), ExactKey(otherClient, a), "clients never share entries")
+}
+
+func TestSemanticScope_IgnoreMessages(t *testing.T) {
+	scope := Scope{ClientID: "client-1", ModelID: "model-1", Path: "/v1/chat/completions"}
+	a, err := ParseRequestBody([]byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`))
+	require.NoError(t, err)
+	b, err := ParseRequestBody([]byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hello"}]}`))
+	require.NoError(t, err)
+	c, err := ParseRequestBody([]byte(`{"model":"gpt-4o","temperature":1,"messages":[{"role":"user","content":"hi"}]}`))
+	require.NoError(t, err)
+
+	require.Equal(t, SemanticScope(scope, a), SemanticScope(scope, b))
+	require.NotEqual(t, SemanticScope(scope, a), SemanticScope(scope, c))
+}
+
+func TestPromptText(t *testing.T) {
+	body, err := ParseRequestBody([]byte(`{"messages":[
+		{"role":"system","content":"be brief"},
+		{"role":"user","content":[{"type":"text","text":"what is this"},{"type":"image_url","image_url":{"url":"http://x"}}]}
+	]}`))
+	require.NoError(t, err)
+	require.Equal(t, "system: be brief\nuser: what is this", PromptText(body))
+
+	body, err = ParseRequestBody([]byte(`{"input":"embed me"}`))
+	require.NoError(t, err)
+	require.Equal(t, "", PromptText(body))
+}
+
+func TestParseRequestBody_NotObject(t *testing.T) {
+	_, err := ParseRequestBody([]byte(`[1,2]`))
+	require.Error(t, err)
+	_, err = ParseRequestBody([]byte(`null`))
+	require.Error(t, err)
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	settingpb "github.com/erda-project/erda-proto-go/apps/aiproxy/setting/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/cache/cachetypes"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+)
+
+const (
+	SettingNamespace = "response_cache"
+	// SettingKeyPolicies holds a JSON array of Policy.
+	SettingKeyPolicies = "policies"
+)
+
+const (
+	matchAll = "*"
+
+	defaultTTL                = time.Hour
+	defaultSemanticThreshold  = 0.95
+	defaultSemanticMaxEntries = 1000
+)
+
+// Policy opts a client in to response caching.
+//
+// ClientID is required, "*" applies the policy to every client without its own policy.
+// TTL is the default lifetime of cached responses, ModelTTLs overrides it by model name;
+// a TTL of "0" disables caching for that model.
+type Policy struct {
+	ClientID  string            `json:"client_id"`
+	TTL       string            `json:"ttl,omitempty"`
+	ModelTTLs map[string]string `json:"model_ttls,omitempty"`
+	Semantic  *SemanticConfig   `json:"semantic,omitempty"`
+
+	ttl       time.Duration
+	modelTTLs map[string]time.Duration
+}
+
+// SemanticConfig enables reusing an answer when the embedding of the prompt is close enough to a cached one.
+type SemanticConfig struct {
+	// EmbeddingModel is the model name used to embed prompts, called through ai-proxy itself with the client's credential.
+	EmbeddingModel string `json:"embedding_model"`
+	// Threshold is the minimum cosine similarity to reuse an answer, default 0.95.
+	Threshold float64 `json:"threshold,omitempty"`
+	// MaxEntries limits indexed prompts per client and model, default 1000.
+	MaxEntries int `json:"max_entries,omitempty"`
+}
+
+func (p *Policy) Validate() error {
+	if strings.TrimSpace(p.ClientID) == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	p.ttl = defaultTTL
+	if p.TTL != "" {
+		ttl, err := time.ParseDuration(p.TTL)
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("invalid ttl: %q", p.TTL)
+		}
+		p.ttl = ttl
+	}
+	p.modelTTLs = make(map[string]time.Duration, len(p.ModelTTLs))
+	for model, v := range p.ModelTTLs {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("invalid ttl of model %s: %q", model, v)
+		}
+		p.modelTTLs[model] = ttl
+	}
+	if p.Semantic != nil {
+		if strings.TrimSpace(p.Semantic.EmbeddingModel) == "" {
+			return fmt.Errorf("semantic.embedding_model is required")
+		}
+		if p.Semantic.Threshold == 0 {
+			p.Semantic.Threshold = defaultSemanticThreshold
+		}
+		if p.Semantic.Threshold <= 0 || p.Semantic.Threshold > 1 {
+			return fmt.Errorf("semantic.threshold must be in (0, 1]")
+		}
+		if p.Semantic.MaxEntries <= 0 {
+			p.Semantic.MaxEntries = defaultSemanticMaxEntries
+		}
+	}
+	return nil
+}
+
+// TTLFor returns how long responses of the model are cached, false if caching is disabled for it.
+func (p *Policy) TTLFor(model string) (time.Duration, bool) {
+	ttl := p.ttl
+	if v, ok := p.modelTTLs[model]; ok {
+		ttl = v
+	}
+	return ttl, ttl > 0
+}
+
+// ResolvePolicy returns the policy of the client, a client's own policy wins over the "*" one.
+func ResolvePolicy(ctx context.Context, clientID string) (*Policy, bool) {
+	var fallback *Policy
+	for _, p := range resolvePolicies(ctx) {
+		switch strings.TrimSpace(p.ClientID) {
+		case clientID:
+			return p, true
+		case matchAll:
+			if fallback == nil {
+				fallback = p
+			}
+		}
+	}
+	return fallback, fallback != nil
+}
+
+func resolvePolicies(ctx context.Context) []*Policy {
+	if cache, ok := ctxhelper.GetCacheManager(ctx); ok && cache != nil {
+		if manager, ok := cache.(cachetypes.Manager); ok && manager != nil {
+			if _, settingsV, err := manager.ListAll(ctx, cachetypes.ItemTypeSetting); err == nil {
+				if list, ok := settingsV.([]*settingpb.Setting); ok {
+					return buildPoliciesFromList(ctx, list)
+				}
+			}
+		}
+	}
+
+	dbClient, ok := ctxhelper.GetDBClient(ctx)
+	if !ok || dbClient == nil {
+		return nil
+	}
+	item, err := dbClient.SettingClient().GetByNamespaceKey(ctx, SettingNamespace, SettingKeyPolicies)
+	if err != nil {
+		return nil
+	}
+	return buildPoliciesFromList(ctx, []*settingpb.Setting{item.ToProtobuf()})
+}
+
+func buildPoliciesFromList(ctx context.Context, items []*settingpb.Setting) []*Policy {
+	var policies []*Policy
+	for _, item := range items {
+		if item == nil || item.Namespace != SettingNamespace || item.Key != SettingKeyPolicies {
+			continue
+		}
+		policies = append(policies, ParsePolicies(ctx, item.Value)...)
+	}
+	return policies
+}
+
+// ParsePolicies parses the setting value, invalid policies are logged and ignored.
+func ParsePolicies(ctx context.Context, value string) []*Policy {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	var policies []*Policy
+	if err := json.Unmarshal([]byte(value), &policies); err != nil {
+		if l, ok := ctxhelper.GetLogger(ctx); ok {
+			l.Warnf("invalid response cache policies setting, ignored: %v", err)
+		}
+		return nil
+	}
+	valid := policies[:0]
+	for _, p := range policies {
+		if p == nil {
+			continue
+		}
+		if err := p.Validate(); err != nil {
+			if l, ok := ctxhelper.GetLogger(ctx); ok {
+				l.Warnf("invalid response cache policy %+v, ignored: %v", *p, err)
+			}
+			continue
+		}
+		valid = append(valid, p)
+	}
+	return valid
+}
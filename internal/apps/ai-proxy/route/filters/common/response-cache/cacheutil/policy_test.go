@@ -0,0 +1,51 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies := ParsePolicies(context.Background(), `[
+		{"client_id":"client-1","ttl":"10m","model_ttls":{"gpt-4o":"1h","o1":"0"}},
+		{"client_id":"*","semantic":{"embedding_model":"text-embedding-3-small"}},
+		{"ttl":"1m"},
+		{"client_id":"client-2","ttl":"bad"},
+		{"client_id":"client-3","semantic":{}}
+	]`)
+	require.Len(t, policies, 2)
+
+	ttl, ok := policies[0].TTLFor("gpt-4o")
+	require.True(t, ok)
+	require.Equal(t, time.Hour, ttl)
+	ttl, ok = policies[0].TTLFor("gpt-4o-mini")
+	require.True(t, ok)
+	require.Equal(t, 10*time.Minute, ttl)
+	_, ok = policies[0].TTLFor("o1")
+	require.False(t, ok, "zero ttl disables caching")
+
+	ttl, ok = policies[1].TTLFor("gpt-4o")
+	require.True(t, ok)
+	require.Equal(t, defaultTTL, ttl)
+	require.Equal(t, defaultSemanticThreshold, policies[1].Semantic.Threshold)
+	require.Equal(t, defaultSemanticMaxEntries, policies[1].Semantic.MaxEntries)
+
+	require.Nil(t, ParsePolicies(context.Background(), `not-json`))
+}
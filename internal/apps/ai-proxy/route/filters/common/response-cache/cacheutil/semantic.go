@@ -0,0 +1,259 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis"
+)
+
+// SemanticIndex maps prompt embeddings to exact cache keys.
+type SemanticIndex interface {
+	// Lookup returns the key of the most similar prompt in scope whose similarity reaches threshold.
+	Lookup(ctx context.Context, scope string, vector []float64, threshold float64, now time.Time) (string, float64, bool, error)
+	// Add indexes the prompt embedding of key, the oldest entries are evicted beyond maxEntries.
+	Add(ctx context.Context, scope string, vector []float64, key string, expireAt time.Time, maxEntries int, now time.Time) error
+	// Remove drops key from scope, e.g. when its entry has been evicted from the Store.
+	Remove(ctx context.Context, scope, key string) error
+}
+
+// MemorySemanticIndex keeps the index in memory of the replica, it goes with MemoryStore.
+type MemorySemanticIndex struct {
+	mu     sync.Mutex
+	scopes map[string][]semanticEntry
+}
+
+type semanticEntry struct {
+	Vector   []float64 `json:"vector"`
+	Key      string    `json:"-"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+func NewMemorySemanticIndex() *MemorySemanticIndex {
+	return &MemorySemanticIndex{scopes: make(map[string][]semanticEntry)}
+}
+
+func (idx *MemorySemanticIndex) Lookup(_ context.Context, scope string, vector []float64, threshold float64, now time.Time) (string, float64, bool, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	key, score, ok := mostSimilar(idx.pruneLocked(scope, now), vector, threshold)
+	return key, score, ok, nil
+}
+
+func (idx *MemorySemanticIndex) Add(_ context.Context, scope string, vector []float64, key string, expireAt time.Time, maxEntries int, now time.Time) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entries := idx.pruneLocked(scope, now)
+	entries = append(entries, semanticEntry{Vector: vector, Key: key, ExpireAt: expireAt})
+	if maxEntries > 0 && len(entries) > maxEntries {
+		entries = append([]semanticEntry(nil), entries[len(entries)-maxEntries:]...)
+	}
+	idx.scopes[scope] = entries
+	return nil
+}
+
+func (idx *MemorySemanticIndex) Remove(_ context.Context, scope, key string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entries := idx.scopes[scope]
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.Key != key {
+			kept = append(kept, e)
+		}
+	}
+	idx.setLocked(scope, kept)
+	return nil
+}
+
+func (idx *MemorySemanticIndex) pruneLocked(scope string, now time.Time) []semanticEntry {
+	entries := idx.scopes[scope]
+	kept := entries[:0]
+	for _, e := range entries {
+		if now.Before(e.ExpireAt) {
+			kept = append(kept, e)
+		}
+	}
+	idx.setLocked(scope, kept)
+	return kept
+}
+
+func (idx *MemorySemanticIndex) setLocked(scope string, entries []semanticEntry) {
+	if len(entries) == 0 {
+		delete(idx.scopes, scope)
+		return
+	}
+	idx.scopes[scope] = entries
+}
+
+// RedisSemanticIndex keeps the index of a scope in a redis hash from cache key to embedding,
+// so that all replicas reuse answers of similar prompts indexed by any of them. It goes with RedisStore.
+type RedisSemanticIndex struct {
+	client redis.Cmdable
+	prefix string
+}
+
+func NewRedisSemanticIndex(client redis.Cmdable, prefix string) *RedisSemanticIndex {
+	if prefix == "" {
+		prefix = "ai-proxy:response-cache"
+	}
+	return &RedisSemanticIndex{client: client, prefix: prefix}
+}
+
+func (idx *RedisSemanticIndex) Lookup(_ context.Context, scope string, vector []float64, threshold float64, now time.Time) (string, float64, bool, error) {
+	entries, err := idx.load(scope, now)
+	if err != nil {
+		return "", 0, false, err
+	}
+	key, score, ok := mostSimilar(entries, vector, threshold)
+	return key, score, ok, nil
+}
+
+func (idx *RedisSemanticIndex) Add(_ context.Context, scope string, vector []float64, key string, expireAt time.Time, maxEntries int, now time.Time) error {
+	b, err := json.Marshal(semanticEntry{Vector: vector, ExpireAt: expireAt})
+	if err != nil {
+		return err
+	}
+	scopeKey := idx.scopeKey(scope)
+	if err := idx.client.HSet(scopeKey, key, b).Err(); err != nil {
+		return err
+	}
+	// the hash lives as long as its latest entry
+	ttl, err := idx.client.PTTL(scopeKey).Result()
+	if err != nil {
+		return err
+	}
+	if ttl < 0 || now.Add(ttl).Before(expireAt) {
+		if err := idx.client.ExpireAt(scopeKey, expireAt).Err(); err != nil {
+			return err
+		}
+	}
+	if maxEntries <= 0 {
+		return nil
+	}
+	n, err := idx.client.HLen(scopeKey).Result()
+	if err != nil || n <= int64(maxEntries) {
+		return err
+	}
+	entries, err := idx.load(scope, now)
+	if err != nil || len(entries) <= maxEntries {
+		return err
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ExpireAt.Before(entries[j].ExpireAt) })
+	var evicted []string
+	for _, e := range entries[:len(entries)-maxEntries] {
+		evicted = append(evicted, e.Key)
+	}
+	return idx.client.HDel(scopeKey, evicted...).Err()
+}
+
+func (idx *RedisSemanticIndex) Remove(_ context.Context, scope, key string) error {
+	return idx.client.HDel(idx.scopeKey(scope), key).Err()
+}
+
+// load returns the live entries of scope and drops the expired ones.
+func (idx *RedisSemanticIndex) load(scope string, now time.Time) ([]semanticEntry, error) {
+	scopeKey := idx.scopeKey(scope)
+	all, err := idx.client.HGetAll(scopeKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		entries []semanticEntry
+		expired []string
+	)
+	for key, value := range all {
+		var e semanticEntry
+		if err := json.Unmarshal([]byte(value), &e); err != nil || !now.Before(e.ExpireAt) {
+			expired = append(expired, key)
+			continue
+		}
+		e.Key = key
+		entries = append(entries, e)
+	}
+	if len(expired) > 0 {
+		if err := idx.client.HDel(scopeKey, expired...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func (idx *RedisSemanticIndex) scopeKey(scope string) string {
+	return fmt.Sprintf("%s:semantic:%s", idx.prefix, scope)
+}
+
+func mostSimilar(entries []semanticEntry, vector []float64, threshold float64) (string, float64, bool) {
+	var (
+		bestKey   string
+		bestScore float64
+	)
+	for _, e := range entries {
+		score := CosineSimilarity(vector, e.Vector)
+		if score >= threshold && score > bestScore {
+			bestKey, bestScore = e.Key, score
+		}
+	}
+	return bestKey, bestScore, bestKey != ""
+}
+
+var (
+	semanticIndexMu      sync.RWMutex
+	semanticIndex        SemanticIndex
+	defaultSemanticIndex = NewMemorySemanticIndex()
+)
+
+// GetSemanticIndex returns the index set by SetSemanticIndex, or an in-memory one by default.
+func GetSemanticIndex() SemanticIndex {
+	semanticIndexMu.RLock()
+	defer semanticIndexMu.RUnlock()
+	if semanticIndex != nil {
+		return semanticIndex
+	}
+	return defaultSemanticIndex
+}
+
+func SetSemanticIndex(idx SemanticIndex) {
+	semanticIndexMu.Lock()
+	defer semanticIndexMu.Unlock()
+	semanticIndex = idx
+}
+
+// CosineSimilarity returns the cosine similarity of a and b, 0 if they can't be compared.
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
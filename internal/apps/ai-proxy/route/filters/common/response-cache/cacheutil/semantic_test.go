@@ -0,0 +1,104 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	redis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func semanticIndexesForTest(t *testing.T) map[string]SemanticIndex {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return map[string]SemanticIndex{
+		"memory": NewMemorySemanticIndex(),
+		"redis":  NewRedisSemanticIndex(client, "test"),
+	}
+}
+
+func TestSemanticIndex_Lookup(t *testing.T) {
+	for name, idx := range semanticIndexesForTest(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			require.NoError(t, idx.Add(ctx, "scope", []float64{1, 0}, "key-x", now.Add(time.Minute), 10, now))
+			require.NoError(t, idx.Add(ctx, "scope", []float64{0, 1}, "key-y", now.Add(time.Minute), 10, now))
+
+			key, score, ok, err := idx.Lookup(ctx, "scope", []float64{0.99, 0.1}, 0.95, now)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, "key-x", key)
+			require.Greater(t, score, 0.95)
+
+			_, _, ok, _ = idx.Lookup(ctx, "scope", []float64{1, 1}, 0.95, now)
+			require.False(t, ok, "below threshold")
+
+			_, _, ok, _ = idx.Lookup(ctx, "other", []float64{1, 0}, 0.95, now)
+			require.False(t, ok, "scopes are isolated")
+
+			_, _, ok, _ = idx.Lookup(ctx, "scope", []float64{1, 0}, 0.95, now.Add(2*time.Minute))
+			require.False(t, ok, "expired")
+		})
+	}
+}
+
+func TestSemanticIndex_EvictAndRemove(t *testing.T) {
+	for name, idx := range semanticIndexesForTest(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			require.NoError(t, idx.Add(ctx, "scope", []float64{1, 0}, "key-1", now.Add(time.Minute), 1, now))
+			require.NoError(t, idx.Add(ctx, "scope", []float64{0, 1}, "key-2", now.Add(2*time.Minute), 1, now))
+
+			_, _, ok, _ := idx.Lookup(ctx, "scope", []float64{1, 0}, 0.95, now)
+			require.False(t, ok, "oldest entry should be evicted")
+
+			require.NoError(t, idx.Remove(ctx, "scope", "key-2"))
+			_, _, ok, _ = idx.Lookup(ctx, "scope", []float64{0, 1}, 0.95, now)
+			require.False(t, ok)
+		})
+	}
+}
+
+func TestSemanticIndex_SharedByReplicas(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	ctx := context.Background()
+	now := time.Now()
+
+	replica1 := NewRedisSemanticIndex(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test")
+	replica2 := NewRedisSemanticIndex(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test")
+	require.NoError(t, replica1.Add(ctx, "scope", []float64{1, 0}, "key-x", now.Add(time.Minute), 10, now))
+
+	key, _, ok, err := replica2.Lookup(ctx, "scope", []float64{1, 0}, 0.95, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "key-x", key)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	require.InDelta(t, 1, CosineSimilarity([]float64{1, 2}, []float64{2, 4}), 1e-9)
+	require.InDelta(t, 0, CosineSimilarity([]float64{1, 0}, []float64{0, 1}), 1e-9)
+	require.Equal(t, float64(0), CosineSimilarity([]float64{1}, []float64{1, 2}))
+	require.Equal(t, float64(0), CosineSimilarity([]float64{0, 0}, []float64{1, 2}))
+}
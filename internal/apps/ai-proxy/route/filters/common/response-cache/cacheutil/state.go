@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+)
+
+type HitType string
+
+const (
+	HitTypeExact    HitType = "exact"
+	HitTypeSemantic HitType = "semantic"
+)
+
+// State is decided by the request filter and carried to the response filter and token usage hook.
+type State struct {
+	Key string
+	TTL time.Duration
+
+	// HitType is empty on cache miss.
+	HitType HitType
+	// Similarity of the semantic hit.
+	Similarity float64
+	// NoStore skips storing the response, e.g. requested by `Cache-Control: no-store`.
+	NoStore bool
+
+	// semantic indexing, empty if semantic mode is off
+	SemanticScope      string
+	Vector             []float64
+	SemanticMaxEntries int
+}
+
+func (s *State) Hit() bool {
+	return s.HitType != ""
+}
+
+func PutState(ctx context.Context, state *State) {
+	ctxhelper.PutResponseCache(ctx, state)
+	ctxhelper.PutResponseCacheHit(ctx, state.Hit())
+}
+
+func GetState(ctx context.Context) (*State, bool) {
+	v, ok := ctxhelper.GetResponseCache(ctx)
+	if !ok || v == nil {
+		return nil, false
+	}
+	state, ok := v.(*State)
+	return state, ok && state != nil
+}
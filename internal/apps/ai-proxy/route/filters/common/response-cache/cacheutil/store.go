@@ -0,0 +1,180 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	redis "github.com/go-redis/redis"
+)
+
+// MaxEntryBodyBytes limits the size of a cached response body, larger responses are not cached.
+const MaxEntryBodyBytes = 1 << 20 // 1MiB
+
+// DefaultMaxBytes is the default size cap of the memory store.
+const DefaultMaxBytes = 256 << 20 // 256MiB
+
+// Entry is a cached upstream response.
+// Body is the raw upstream body (decompressed) as received before any response filter,
+// so replaying it runs through the same response filters as a real call.
+type Entry struct {
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Store interface {
+	Get(ctx context.Context, key string) (*Entry, bool, error)
+	Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) error
+}
+
+// MemoryStore keeps entries in memory of the replica, the least recently used ones are evicted beyond maxBytes.
+type MemoryStore struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	lru      *list.List // front is the most recently used
+	items    map[string]*list.Element
+	now      func() time.Time
+}
+
+type memoryItem struct {
+	key      string
+	entry    *Entry
+	expireAt time.Time
+}
+
+func NewMemoryStore(maxBytes int64) *MemoryStore {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &MemoryStore{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (*Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	item := elem.Value.(*memoryItem)
+	if !s.now().Before(item.expireAt) {
+		s.removeLocked(elem)
+		return nil, false, nil
+	}
+	s.lru.MoveToFront(elem)
+	return item.entry, true, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, entry *Entry, ttl time.Duration) error {
+	size := int64(len(entry.Body))
+	if size > MaxEntryBodyBytes || size > s.maxBytes {
+		return fmt.Errorf("cache entry too large: %d bytes", size)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.items[key]; ok {
+		s.removeLocked(elem)
+	}
+	s.items[key] = s.lru.PushFront(&memoryItem{key: key, entry: entry, expireAt: s.now().Add(ttl)})
+	s.bytes += size
+	for s.bytes > s.maxBytes {
+		s.removeLocked(s.lru.Back())
+	}
+	return nil
+}
+
+func (s *MemoryStore) removeLocked(elem *list.Element) {
+	item := s.lru.Remove(elem).(*memoryItem)
+	delete(s.items, item.key)
+	s.bytes -= int64(len(item.entry.Body))
+}
+
+// RedisStore keeps entries in redis, so they are shared by all replicas.
+// Redis evicts them by ttl and by its own maxmemory policy.
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+func NewRedisStore(client redis.Cmdable, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "ai-proxy:response-cache"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Get(_ context.Context, key string) (*Entry, bool, error) {
+	b, err := s.client.Get(s.entryKey(key)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal cache entry: %w", err)
+	}
+	return &entry, true, nil
+}
+
+func (s *RedisStore) Set(_ context.Context, key string, entry *Entry, ttl time.Duration) error {
+	if len(entry.Body) > MaxEntryBodyBytes {
+		return fmt.Errorf("cache entry too large: %d bytes", len(entry.Body))
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	return s.client.Set(s.entryKey(key), b, ttl).Err()
+}
+
+func (s *RedisStore) entryKey(key string) string {
+	return fmt.Sprintf("%s:entry:%s", s.prefix, key)
+}
+
+var (
+	storeMu      sync.RWMutex
+	store        Store
+	defaultStore = NewMemoryStore(DefaultMaxBytes)
+)
+
+// GetStore returns the store set by SetStore, or a memory store with the default size cap.
+func GetStore() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	if store != nil {
+		return store
+	}
+	return defaultStore
+}
+
+func SetStore(s Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
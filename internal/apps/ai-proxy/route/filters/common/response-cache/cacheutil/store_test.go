@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	redis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_EvictBySize(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(10)
+	require.NoError(t, store.Set(ctx, "a", &Entry{Body: []byte("12345")}, time.Minute))
+	require.NoError(t, store.Set(ctx, "b", &Entry{Body: []byte("12345")}, time.Minute))
+	// a is used recently, so b is evicted
+	_, ok, _ := store.Get(ctx, "a")
+	require.True(t, ok)
+	require.NoError(t, store.Set(ctx, "c", &Entry{Body: []byte("12345")}, time.Minute))
+
+	_, ok, _ = store.Get(ctx, "b")
+	require.False(t, ok)
+	_, ok, _ = store.Get(ctx, "a")
+	require.True(t, ok)
+	require.Equal(t, int64(10), store.bytes)
+
+	require.Error(t, store.Set(ctx, "d", &Entry{Body: make([]byte, 11)}, time.Minute))
+}
+
+func TestMemoryStore_Expire(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore(0)
+	store.now = func() time.Time { return now }
+	require.NoError(t, store.Set(ctx, "a", &Entry{Body: []byte("x")}, time.Minute))
+	now = now.Add(time.Minute)
+	_, ok, _ := store.Get(ctx, "a")
+	require.False(t, ok)
+	require.Equal(t, int64(0), store.bytes)
+}
+
+func TestRedisStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	ctx := context.Background()
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test")
+
+	_, ok, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, store.Set(ctx, "a", &Entry{ContentType: "application/json", Body: []byte("{}")}, time.Minute))
+	entry, ok, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "{}", string(entry.Body))
+
+	mr.FastForward(2 * time.Minute)
+	_, ok, _ = store.Get(ctx, "a")
+	require.False(t, ok)
+
+	require.Error(t, store.Set(ctx, "b", &Entry{Body: make([]byte, MaxEntryBodyBytes+1)}, time.Minute))
+}
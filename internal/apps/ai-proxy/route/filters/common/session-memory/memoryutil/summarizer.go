@@ -0,0 +1,187 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memoryutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+)
+
+// SourceSessionSummary is sent as X-AI-Proxy-Source of summary requests, so they can be told apart in audit.
+const SourceSessionSummary = "session-summary"
+
+type Config struct {
+	// SummaryBaseURL is the address of ai-proxy itself, older turns are summarized by calling its /v1/chat/completions.
+	SummaryBaseURL string        `file:"summary_base_url" env:"SESSION_MEMORY_SUMMARY_BASE_URL"`
+	SummaryTimeout time.Duration `file:"summary_timeout" env:"SESSION_MEMORY_SUMMARY_TIMEOUT" default:"30s"`
+	// SummaryModel is used by sessions without their own summary model, it should be a cheap one.
+	SummaryModel     string `file:"summary_model" env:"SESSION_MEMORY_SUMMARY_MODEL"`
+	SummaryMaxTokens int    `file:"summary_max_tokens" env:"SESSION_MEMORY_SUMMARY_MAX_TOKENS" default:"512"`
+}
+
+// Summarizer summarizes older turns of a session.
+type Summarizer interface {
+	// Summarize merges turns into the previous summary, header carries the credential of the calling client.
+	// The default summary model is used if model is empty.
+	Summarize(ctx context.Context, header http.Header, model, previous string, turns []Turn) (string, error)
+	// MaxTokens is the max tokens of a summary, reserved in the context window.
+	MaxTokens() int
+}
+
+const summaryInstruction = `You maintain the memory of a conversation between a user and an assistant.
+Merge the previous summary and the new turns into a concise summary in the language of the conversation.
+Keep facts, decisions, preferences and open questions the assistant needs to continue the conversation. Reply with the summary only.`
+
+// HTTPSummarizer summarizes through ai-proxy's OpenAI-compatible chat completions API,
+// so the summary call is routed, audited and accounted to the same client like any other call.
+type HTTPSummarizer struct {
+	baseURL   string
+	model     string
+	maxTokens int
+	client    *http.Client
+}
+
+func NewHTTPSummarizer(cfg Config) *HTTPSummarizer {
+	timeout := cfg.SummaryTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	maxTokens := cfg.SummaryMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 512
+	}
+	return &HTTPSummarizer{
+		baseURL:   strings.TrimRight(cfg.SummaryBaseURL, "/"),
+		model:     cfg.SummaryModel,
+		maxTokens: maxTokens,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (s *HTTPSummarizer) MaxTokens() int {
+	return s.maxTokens
+}
+
+func (s *HTTPSummarizer) Summarize(ctx context.Context, header http.Header, model, previous string, turns []Turn) (string, error) {
+	if model == "" {
+		model = s.model
+	}
+	if model == "" {
+		return "", fmt.Errorf("no summary model configured")
+	}
+	body, err := json.Marshal(chatRequest{
+		Model: model,
+		Messages: []chatMessage{
+			{Role: "system", Content: summaryInstruction},
+			{Role: "user", Content: Transcript(previous, turns)},
+		},
+		MaxTokens: s.maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+vars.RequestPathPrefixV1ChatCompletions, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to build summary request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(vars.XAIProxySource, SourceSessionSummary)
+	if auth := header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("summary request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read summary response: %w", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return "", fmt.Errorf("summary request got non-2xx status=%d body=%q", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	var chatResp chatResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return "", fmt.Errorf("failed to parse summary response: %w", err)
+	}
+	if len(chatResp.Choices) == 0 || strings.TrimSpace(chatResp.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("empty summary in response")
+	}
+	return strings.TrimSpace(chatResp.Choices[0].Message.Content), nil
+}
+
+// Transcript renders the previous summary and turns as the input of summarization.
+func Transcript(previous string, turns []Turn) string {
+	var sb strings.Builder
+	if previous != "" {
+		sb.WriteString("Previous summary:\n")
+		sb.WriteString(previous)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("New turns:\n")
+	for _, turn := range turns {
+		sb.WriteString("User: ")
+		sb.WriteString(turn.Prompt)
+		sb.WriteString("\nAssistant: ")
+		sb.WriteString(turn.Completion)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+var (
+	summarizerMu sync.RWMutex
+	summarizer   Summarizer
+)
+
+// GetSummarizer returns the summarizer set by SetSummarizer, the summarize strategy falls back to truncate_oldest without one.
+func GetSummarizer() (Summarizer, bool) {
+	summarizerMu.RLock()
+	defer summarizerMu.RUnlock()
+	return summarizer, summarizer != nil
+}
+
+func SetSummarizer(s Summarizer) {
+	summarizerMu.Lock()
+	defer summarizerMu.Unlock()
+	summarizer = s
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memoryutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+)
+
+func TestHTTPSummarizer_Summarize(t *testing.T) {
+	var got chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, vars.RequestPathPrefixV1ChatCompletions, r.URL.Path)
+		assert.Equal(t, "Bearer ak", r.Header.Get("Authorization"))
+		assert.Equal(t, SourceSessionSummary, r.Header.Get(vars.XAIProxySource))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":" user likes go "}}]}`))
+	}))
+	defer server.Close()
+
+	s := NewHTTPSummarizer(Config{SummaryBaseURL: server.URL + "/", SummaryModel: "cheap"})
+	header := http.Header{}
+	header.Set("Authorization", "Bearer ak")
+	summary, err := s.Summarize(context.Background(), header, "", "user is a developer", []Turn{{Prompt: "hi", Completion: "hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, "user likes go", summary)
+	assert.Equal(t, "cheap", got.Model)
+	assert.Equal(t, 512, got.MaxTokens)
+	require.Len(t, got.Messages, 2)
+	assert.Equal(t, "Previous summary:\nuser is a developer\n\nNew turns:\nUser: hi\nAssistant: hello\n", got.Messages[1].Content)
+}
+
+func TestHTTPSummarizer_Summarize_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	s := NewHTTPSummarizer(Config{SummaryBaseURL: server.URL})
+	_, err := s.Summarize(context.Background(), http.Header{}, "", "", nil)
+	assert.ErrorContains(t, err, "no summary model")
+	_, err = s.Summarize(context.Background(), http.Header{}, "cheap", "", nil)
+	assert.ErrorContains(t, err, "status=429")
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memoryutil fits the stored history of a session into the context window of the model.
+package memoryutil
+
+import (
+	"time"
+)
+
+// Strategies applied when the context window would be exceeded.
+const (
+	// StrategyTruncateOldest drops the oldest turns.
+	StrategyTruncateOldest = "truncate_oldest"
+	// StrategyKeepLastN keeps system messages and the last N history messages, older turns are dropped.
+	StrategyKeepLastN = "keep_last_n"
+	// StrategySummarize summarizes the oldest turns by the summary model, the summary is stored back in the session.
+	StrategySummarize = "summarize"
+)
+
+// MessageOverheadTokens is the tokens taken by role and separators of a message.
+const MessageOverheadTokens = 4
+
+func IsValidStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyTruncateOldest, StrategyKeepLastN, StrategySummarize:
+		return true
+	default:
+		return false
+	}
+}
+
+// Turn is a stored request of the session, a user prompt and the assistant completion.
+type Turn struct {
+	Prompt     string
+	Completion string
+	RequestAt  time.Time
+}
+
+// TokenCounter estimates the token count of text.
+type TokenCounter func(text string) int
+
+func (t Turn) tokens(count TokenCounter) int {
+	return count(t.Prompt) + count(t.Completion) + 2*MessageOverheadTokens
+}
+
+// Window is the context window of a request.
+type Window struct {
+	Strategy string
+	// MaxTokens is the token budget of all messages sent to the model.
+	MaxTokens int
+	// KeepLastN is the max number of history messages of StrategyKeepLastN.
+	KeepLastN int
+}
+
+// Fitted is the history split by Window.Fit.
+type Fitted struct {
+	// Kept turns are sent to the model, oldest first.
+	Kept []Turn
+	// Evicted turns don't fit into the window, oldest first.
+	Evicted []Turn
+}
+
+// Fit keeps the latest turns of history which fit into the window together with fixedTokens,
+// which are the tokens of messages never dropped, e.g., the requested messages.
+// Turns are kept or evicted as a whole, so a completion is never sent without its prompt.
+func (w Window) Fit(history []Turn, fixedTokens int, count TokenCounter) Fitted {
+	start := 0
+	if w.Strategy == StrategyKeepLastN {
+		keepTurns := (max(w.KeepLastN, 0) + 1) / 2
+		start = max(len(history)-keepTurns, 0)
+	}
+	tokens := make([]int, len(history))
+	used := fixedTokens
+	for i := start; i < len(history); i++ {
+		tokens[i] = history[i].tokens(count)
+		used += tokens[i]
+	}
+	for start < len(history) && used > w.MaxTokens {
+		used -= tokens[start]
+		start++
+	}
+	return Fitted{Kept: history[start:], Evicted: history[:start]}
+}
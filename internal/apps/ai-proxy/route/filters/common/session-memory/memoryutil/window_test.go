@@ -0,0 +1,107 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memoryutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countWords counts a word as a token.
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+func turns(n int) []Turn {
+	var history []Turn
+	for i := 0; i < n; i++ {
+		history = append(history, Turn{Prompt: "one two", Completion: "three four five six"})
+	}
+	return history
+}
+
+func TestWindow_Fit(t *testing.T) {
+	// each turn takes 2 + 4 + 2*4 = 14 tokens
+	tests := []struct {
+		name        string
+		window      Window
+		history     []Turn
+		fixedTokens int
+		wantKept    int
+		wantEvicted int
+	}{
+		{
+			name:        "all fit",
+			window:      Window{Strategy: StrategyTruncateOldest, MaxTokens: 100},
+			history:     turns(3),
+			fixedTokens: 10,
+			wantKept:    3,
+		},
+		{
+			name:        "truncate oldest",
+			window:      Window{Strategy: StrategyTruncateOldest, MaxTokens: 40},
+			history:     turns(3),
+			fixedTokens: 10,
+			wantKept:    2,
+			wantEvicted: 1,
+		},
+		{
+			name:        "requested messages exceed the window",
+			window:      Window{Strategy: StrategySummarize, MaxTokens: 40},
+			history:     turns(3),
+			fixedTokens: 50,
+			wantEvicted: 3,
+		},
+		{
+			name:        "keep last n messages",
+			window:      Window{Strategy: StrategyKeepLastN, MaxTokens: 100, KeepLastN: 3},
+			history:     turns(5),
+			wantKept:    2,
+			wantEvicted: 3,
+		},
+		{
+			name:        "keep last n still truncated by window",
+			window:      Window{Strategy: StrategyKeepLastN, MaxTokens: 20, KeepLastN: 4},
+			history:     turns(5),
+			wantKept:    1,
+			wantEvicted: 4,
+		},
+		{
+			name:        "keep none",
+			window:      Window{Strategy: StrategyKeepLastN, MaxTokens: 100},
+			history:     turns(2),
+			wantEvicted: 2,
+		},
+		{
+			name:   "no history",
+			window: Window{Strategy: StrategyTruncateOldest, MaxTokens: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fitted := tt.window.Fit(tt.history, tt.fixedTokens, countWords)
+			assert.Len(t, fitted.Kept, tt.wantKept)
+			assert.Len(t, fitted.Evicted, tt.wantEvicted)
+		})
+	}
+}
+
+func TestIsValidStrategy(t *testing.T) {
+	assert.True(t, IsValidStrategy(StrategySummarize))
+	assert.False(t, IsValidStrategy(""))
+	assert.False(t, IsValidStrategy("drop_all"))
+}
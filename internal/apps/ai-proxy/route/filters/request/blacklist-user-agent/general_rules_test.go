@@ -35,6 +35,8 @@ import (
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_server"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_server_config_instance"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_server_template"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_tool_call"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_tool_policy"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/model"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/policy_group"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/prompt"
@@ -200,6 +202,8 @@ func (d testDAO) ClientMCPRelationClient() *client_mcp_relation.DBClient { retur
 func (d testDAO) TokenUsageClient() *usage_token.DBClient { return nil }
 func (d testDAO) MCPServerTemplateClient() *mcp_server_template.DBClient { return nil }
 func (d testDAO) MCPServerConfigInstanceClient() *mcp_server_config_instance.DBClient { return nil }
+func (d testDAO) MCPToolPolicyClient() *mcp_tool_policy.DBClient { return nil }
+func (d testDAO) MCPToolCallClient() *mcp_tool_call.DBClient { return nil }
 func (d testDAO) PolicyGroupClient() *policy_group.DBClient { return nil }
 func (d testDAO) SettingClient() *setting.DBClient { return &setting.DBClient{DB: d.db} }
 
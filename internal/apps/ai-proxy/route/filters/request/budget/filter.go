@@ -0,0 +1,119 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/audithelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/usage/token_usage"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/lb/state_store"
+)
+
+const Name = "budget"
+
+var (
+	_ filter_define.ProxyRequestRewriter = (*Filter)(nil)
+)
+
+var (
+	// getStore is a var for testing.
+	getStore = state_store.GetStore
+	// nowFunc is a var for testing.
+	nowFunc = time.Now
+)
+
+func init() {
+	filter_define.RegisterFilterCreator(Name, Creator)
+	token_usage.RegisterCollectedHook(recordSpend)
+}
+
+// Filter enforces spend budgets declared in public metadata (key: budgets) of client and client token.
+// Spend is accumulated in the lb state store after each call, priced by the model's pricing metadata,
+// and calls are rejected once a hard-capped budget is used up.
+type Filter struct{}
+
+var Creator filter_define.RequestRewriterCreator = func(_ string, _ json.RawMessage) filter_define.ProxyRequestRewriter {
+	return &Filter{}
+}
+
+func (f *Filter) OnProxyRequest(pr *httputil.ProxyRequest) error {
+	ctx := pr.In.Context()
+	l := ctxhelper.MustGetLogger(ctx)
+	if trusted, ok := ctxhelper.GetTrustedHealthProbe(ctx); ok && trusted {
+		return nil
+	}
+
+	budgets := collectBudgets(ctx, nowFunc())
+	if len(budgets) == 0 {
+		return nil
+	}
+	store := getStore()
+	for _, b := range budgets {
+		if !b.HardCap {
+			continue
+		}
+		spent, err := store.GetCounter(ctx, b.key)
+		if err != nil {
+			state_store.FailOpen(l, err, "failed to get budget spend, key: %s", b.key)
+			continue
+		}
+		if spent < toMicros(b.Amount) {
+			continue
+		}
+		audithelper.Note(ctx, "budget.exceeded", b.owner.String()+":"+b.id())
+		l.Warnf("budget exceeded, owner: %s, budget: %s, amount: %v %s", b.owner, b.id(), b.Amount, b.Currency)
+		msg := fmt.Sprintf("You exceeded your %s budget of %s %s for %s, please check your plan and billing details.",
+			b.Period, formatAmount(b.Amount), b.Currency, b.owner)
+		return http_error.NewHTTPErrorWithCtx(ctx, http.StatusTooManyRequests, msg, map[string]any{
+			"code":    "insufficient_quota",
+			"message": msg,
+			"type":    "insufficient_quota",
+		})
+	}
+	ctxhelper.PutBudgets(ctx, budgets)
+	return nil
+}
+
+// collectBudgets gathers budgets of client and client token, bound to the period containing now.
+func collectBudgets(ctx context.Context, now time.Time) []*periodBudget {
+	var result []*periodBudget
+	add := func(o owner, meta metadata.Metadata) {
+		budgets, err := meta.GetBudgets()
+		if err != nil {
+			ctxhelper.MustGetLogger(ctx).Warnf("invalid budgets of %s, ignored: %v", o, err)
+			return
+		}
+		for _, b := range budgets {
+			result = append(result, newPeriodBudget(o, b, now))
+		}
+	}
+	if client, ok := ctxhelper.GetClient(ctx); ok && client != nil {
+		add(owner{kind: ownerKindClient, id: client.Id}, metadata.FromProtobuf(client.Metadata))
+	}
+	if clientToken, ok := ctxhelper.GetClientToken(ctx); ok && clientToken != nil {
+		add(owner{kind: ownerKindClientToken, id: clientToken.Id}, metadata.FromProtobuf(clientToken.Metadata))
+	}
+	return result
+}
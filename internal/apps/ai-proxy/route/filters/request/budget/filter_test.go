@@ -0,0 +1,125 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda-infra/base/logs/logrusx"
+	clientpb "github.com/erda-project/erda-proto-go/apps/aiproxy/client/pb"
+	modelpb "github.com/erda-project/erda-proto-go/apps/aiproxy/model/pb"
+	usagepb "github.com/erda-project/erda-proto-go/apps/aiproxy/usage/token/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/lb/state_store"
+)
+
+func TestFilter_RejectWhenHardCapReached(t *testing.T) {
+	useStoreForTest(t, state_store.NewMemoryStateStore())
+	useNowForTest(t, time.Date(2024, 5, 20, 10, 0, 0, 0, time.UTC))
+
+	f := &Filter{}
+	pr := newProxyRequestForTest(map[string]any{
+		"period": "monthly", "amount": 1, "currency": "USD", "time_zone": "UTC", "hard_cap": true,
+	})
+	ctx := pr.In.Context()
+
+	require.NoError(t, f.OnProxyRequest(pr))
+	// 0.6 USD
+	recordSpend(ctx, &usagepb.TokenUsageCreateRequest{InputTokens: 100_000, OutputTokens: 100_000})
+	require.NoError(t, f.OnProxyRequest(pr))
+	recordSpend(ctx, &usagepb.TokenUsageCreateRequest{InputTokens: 100_000, OutputTokens: 100_000})
+
+	err := f.OnProxyRequest(pr)
+	var httpErr *http_error.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, http.StatusTooManyRequests, httpErr.StatusCode)
+	require.Equal(t, "insufficient_quota", httpErr.ErrorCtx["code"])
+
+	// next month
+	useNowForTest(t, time.Date(2024, 6, 1, 0, 0, 1, 0, time.UTC))
+	require.NoError(t, f.OnProxyRequest(pr))
+}
+
+func TestFilter_SoftOnlyBudgetNeverRejects(t *testing.T) {
+	useStoreForTest(t, state_store.NewMemoryStateStore())
+
+	f := &Filter{}
+	pr := newProxyRequestForTest(map[string]any{
+		"period": "daily", "amount": 0.1, "currency": "USD", "soft_threshold": 0.5,
+	})
+	for i := 0; i < 3; i++ {
+		require.NoError(t, f.OnProxyRequest(pr))
+		recordSpend(pr.In.Context(), &usagepb.TokenUsageCreateRequest{InputTokens: 100_000, OutputTokens: 100_000})
+	}
+}
+
+func TestPeriodOf(t *testing.T) {
+	now := time.Date(2024, 12, 31, 20, 0, 0, 0, time.UTC)
+
+	key, end := periodOf(metadata.Budget{Period: metadata.BudgetPeriodDaily, TimeZone: "Asia/Shanghai"}, now)
+	require.Equal(t, "20250101", key)
+	require.Equal(t, "2025-01-02T00:00:00+08:00", end.Format(time.RFC3339))
+
+	key, end = periodOf(metadata.Budget{Period: metadata.BudgetPeriodMonthly, TimeZone: "UTC"}, now)
+	require.Equal(t, "202412", key)
+	require.Equal(t, "2025-01-01T00:00:00Z", end.Format(time.RFC3339))
+}
+
+func TestCrossedEvent(t *testing.T) {
+	b := newPeriodBudget(owner{kind: ownerKindClient, id: "c1"}, metadata.Budget{
+		Period: metadata.BudgetPeriodDaily, Amount: 10, Currency: "USD", SoftThreshold: 0.8,
+	}, time.Now())
+
+	require.Nil(t, crossedEvent(b, toMicros(1), toMicros(2), "m"))
+	require.Equal(t, EventKindSoftThreshold, crossedEvent(b, toMicros(7), toMicros(8), "m").Kind)
+	require.Nil(t, crossedEvent(b, toMicros(8), toMicros(9), "m"))
+	require.Equal(t, EventKindExhausted, crossedEvent(b, toMicros(7), toMicros(11), "m").Kind)
+}
+
+func newProxyRequestForTest(budget map[string]any) *httputil.ProxyRequest {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	ctxhelper.PutLogger(ctx, logrusx.New())
+	clientMeta := metadata.Metadata{Public: map[string]any{metadata.PublicKeyBudgets: []any{budget}}}
+	ctxhelper.PutClient(ctx, &clientpb.Client{Id: "client-1", Metadata: clientMeta.ToProtobuf()})
+	modelMeta := metadata.Metadata{Public: map[string]any{"pricing": map[string]any{
+		"unit": "USD", "prompt": "0.000002", "completion": "0.000004",
+	}}}
+	ctxhelper.PutModel(ctx, &modelpb.Model{Id: "model-1", Name: "gpt-4o", Metadata: modelMeta.ToProtobuf()})
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/v1/chat/completions", nil).WithContext(ctx)
+	return &httputil.ProxyRequest{In: req, Out: req.Clone(ctx)}
+}
+
+func useStoreForTest(t *testing.T, store state_store.LBStateStore) {
+	previous := getStore
+	getStore = func() state_store.LBStateStore { return store }
+	t.Cleanup(func() { getStore = previous })
+}
+
+func useNowForTest(t *testing.T, now time.Time) {
+	previous := nowFunc
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = previous })
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	eventboxpb "github.com/erda-project/erda-proto-go/core/messenger/eventbox/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+)
+
+type EventKind string
+
+const (
+	EventKindSoftThreshold EventKind = "soft_threshold"
+	EventKindExhausted     EventKind = "exhausted"
+)
+
+// Event describes a budget line crossed by spend.
+type Event struct {
+	Kind      EventKind
+	Owner     string
+	Budget    string
+	Period    string
+	Amount    float64
+	Currency  string
+	Spent     float64
+	Model     string
+	PeriodEnd time.Time
+}
+
+// Notifier sends budget events to humans.
+type Notifier interface {
+	Notify(ctx context.Context, targets BudgetTargets, event *Event) error
+}
+
+// BudgetTargets are the receivers declared by a budget.
+type BudgetTargets struct {
+	DingTalkWebhooks []string
+	HTTPWebhooks     []string
+}
+
+var notifier Notifier
+
+// SetNotifier sets the notifier used by all budgets, budgets are enforced without notification if not set.
+func SetNotifier(n Notifier) {
+	notifier = n
+}
+
+func notify(ctx context.Context, b *periodBudget, event *Event) {
+	l := ctxhelper.MustGetLogger(ctx)
+	l.Infof("budget %s of %s crossed %s, spent: %v/%v %s", event.Budget, event.Owner, event.Kind, event.Spent, event.Amount, event.Currency)
+	if notifier == nil || b.Notify == nil {
+		return
+	}
+	targets := BudgetTargets{DingTalkWebhooks: b.Notify.DingTalkWebhooks, HTTPWebhooks: b.Notify.HTTPWebhooks}
+	if len(targets.DingTalkWebhooks) == 0 && len(targets.HTTPWebhooks) == 0 {
+		return
+	}
+	go func() {
+		if err := notifier.Notify(context.Background(), targets, event); err != nil {
+			l.Errorf("failed to send budget notification, owner: %s, budget: %s, err: %v", event.Owner, event.Budget, err)
+		}
+	}()
+}
+
+// EventBoxNotifier sends budget events through the messenger eventbox.
+type EventBoxNotifier struct {
+	eventBox eventboxpb.EventBoxServiceServer
+}
+
+func NewEventBoxNotifier(eventBox eventboxpb.EventBoxServiceServer) *EventBoxNotifier {
+	return &EventBoxNotifier{eventBox: eventBox}
+}
+
+func (n *EventBoxNotifier) Notify(ctx context.Context, targets BudgetTargets, event *Event) error {
+	title := event.Title()
+	labels := make(map[string]*structpb.Value)
+	if len(targets.DingTalkWebhooks) > 0 {
+		labels["DINGDING"] = stringListValue(targets.DingTalkWebhooks)
+		labels["MARKDOWN"] = structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+			"title": structpb.NewStringValue(title),
+		}})
+	}
+	if len(targets.HTTPWebhooks) > 0 {
+		labels["HTTP"] = stringListValue(targets.HTTPWebhooks)
+	}
+	_, err := n.eventBox.CreateMessage(ctx, &eventboxpb.CreateMessageRequest{
+		Sender:  "ai-proxy-budget",
+		Content: structpb.NewStringValue(event.Markdown()),
+		Labels:  labels,
+		Time:    time.Now().UnixNano(),
+	})
+	return err
+}
+
+func (e *Event) Title() string {
+	switch e.Kind {
+	case EventKindExhausted:
+		return fmt.Sprintf("[AI Proxy] Budget %s of %s is used up", e.Budget, e.Owner)
+	default:
+		return fmt.Sprintf("[AI Proxy] Budget %s of %s crossed soft threshold", e.Budget, e.Owner)
+	}
+}
+
+func (e *Event) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("### " + e.Title() + "\n\n")
+	sb.WriteString(fmt.Sprintf("- Owner: %s\n", e.Owner))
+	sb.WriteString(fmt.Sprintf("- Budget: %s (%s)\n", e.Budget, e.Period))
+	sb.WriteString(fmt.Sprintf("- Spent: %s / %s %s\n", formatAmount(e.Spent), formatAmount(e.Amount), e.Currency))
+	sb.WriteString(fmt.Sprintf("- Last model: %s\n", e.Model))
+	sb.WriteString(fmt.Sprintf("- Period ends at: %s\n", e.PeriodEnd.Format(time.RFC3339)))
+	return sb.String()
+}
+
+func stringListValue(list []string) *structpb.Value {
+	values := make([]*structpb.Value, 0, len(list))
+	for _, v := range list {
+		values = append(values, structpb.NewStringValue(v))
+	}
+	return structpb.NewListValue(&structpb.ListValue{Values: values})
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	usagepb "github.com/erda-project/erda-proto-go/apps/aiproxy/usage/token/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/usage/pricing"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata"
+)
+
+// spend is stored as integer micros of the budget currency.
+const microsPerUnit = 1_000_000
+
+// keep counters for a while after the period ends, so late usage of long calls is not lost.
+const counterGracePeriod = 24 * time.Hour
+
+type ownerKind string
+
+const (
+	ownerKindClient      ownerKind = "client"
+	ownerKindClientToken ownerKind = "client_token"
+)
+
+type owner struct {
+	kind ownerKind
+	id   string
+}
+
+func (o owner) String() string { return string(o.kind) + ":" + o.id }
+
+// periodBudget is a budget bound to the period it is evaluated in.
+type periodBudget struct {
+	metadata.Budget
+	owner     owner
+	key       string
+	periodEnd time.Time
+}
+
+func newPeriodBudget(o owner, b metadata.Budget, now time.Time) *periodBudget {
+	periodKey, periodEnd := periodOf(b, now)
+	result := &periodBudget{Budget: b, owner: o, periodEnd: periodEnd}
+	result.key = fmt.Sprintf("budget:%s:%s:%s", o, result.id(), periodKey)
+	return result
+}
+
+// id identifies a budget of an owner, stable when amount or thresholds are changed.
+func (b *periodBudget) id() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return fmt.Sprintf("%s-%s", b.Period, strings.ToUpper(b.Currency))
+}
+
+func (b *periodBudget) ttl(now time.Time) time.Duration {
+	return b.periodEnd.Sub(now) + counterGracePeriod
+}
+
+// periodOf returns the key and end time of the budget period containing now.
+func periodOf(b metadata.Budget, now time.Time) (string, time.Time) {
+	loc := time.Local
+	if b.TimeZone != "" {
+		if l, err := time.LoadLocation(b.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	now = now.In(loc)
+	switch b.Period {
+	case metadata.BudgetPeriodDaily:
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		return start.Format("20060102"), start.AddDate(0, 0, 1)
+	default:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		return start.Format("200601"), start.AddDate(0, 1, 0)
+	}
+}
+
+// recordSpend prices a finished call and adds it to the spend of every budget selected by the filter.
+func recordSpend(ctx context.Context, usage *usagepb.TokenUsageCreateRequest) {
+	v, ok := ctxhelper.GetBudgets(ctx)
+	if !ok || v == nil {
+		return
+	}
+	budgets, ok := v.([]*periodBudget)
+	if !ok || len(budgets) == 0 {
+		return
+	}
+	if hit, ok := ctxhelper.GetResponseCacheHit(ctx); ok && hit {
+		// served from response cache, nothing is paid to the provider
+		return
+	}
+	model, ok := ctxhelper.GetModel(ctx)
+	if !ok || model == nil {
+		return
+	}
+	l := ctxhelper.MustGetLogger(ctx)
+	modelPricing := pricing.FromModel(model)
+	cost, _ := modelPricing.Calculate(usage.InputTokens, usage.OutputTokens, usage.UsageDetails)
+	delta := toMicros(cost)
+	if delta <= 0 {
+		return
+	}
+
+	store := getStore()
+	now := nowFunc()
+	for _, b := range budgets {
+		if !strings.EqualFold(b.Currency, modelPricing.Currency) {
+			l.Warnf("budget currency %s mismatches model pricing currency %q, spend not recorded, owner: %s, model: %s",
+				b.Currency, modelPricing.Currency, b.owner, model.Name)
+			continue
+		}
+		spent, err := store.IncrCounterBy(context.Background(), b.key, delta, b.ttl(now))
+		if err != nil {
+			l.Warnf("failed to record budget spend, key: %s, err: %v", b.key, err)
+			continue
+		}
+		if event := crossedEvent(b, spent-delta, spent, model.Name); event != nil {
+			notify(ctx, b, event)
+		}
+	}
+}
+
+// crossedEvent returns an event when the spend crosses the soft threshold or the amount.
+// Only the call that crosses a line triggers it, so each line is notified once per period.
+func crossedEvent(b *periodBudget, before, after int64, modelName string) *Event {
+	amount := toMicros(b.Amount)
+	event := &Event{
+		Owner:     b.owner.String(),
+		Budget:    b.id(),
+		Period:    string(b.Period),
+		Amount:    b.Amount,
+		Currency:  b.Currency,
+		Spent:     fromMicros(after),
+		Model:     modelName,
+		PeriodEnd: b.periodEnd,
+	}
+	switch {
+	case before < amount && after >= amount:
+		event.Kind = EventKindExhausted
+	case b.SoftThreshold > 0 && crossed(before, after, int64(math.Ceil(float64(amount)*b.SoftThreshold))):
+		event.Kind = EventKindSoftThreshold
+	default:
+		return nil
+	}
+	return event
+}
+
+func crossed(before, after, line int64) bool {
+	return before < line && after >= line
+}
+
+func toMicros(v float64) int64 {
+	return int64(math.Round(v * microsPerUnit))
+}
+
+func fromMicros(v int64) float64 {
+	return float64(v) / microsPerUnit
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
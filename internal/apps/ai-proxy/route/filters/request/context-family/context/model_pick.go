@@ -21,6 +21,8 @@ import (
 	"net/http"
 	"time"
 
+	"google.golang.org/protobuf/proto"
+
 	policypb "github.com/erda-project/erda-proto-go/apps/aiproxy/policy_group/pb"
 	policygroup "github.com/erda-project/erda/internal/apps/ai-proxy/route/policy_group"
 	pgengine "github.com/erda-project/erda/internal/apps/ai-proxy/route/policy_group/engine"
@@ -71,6 +73,8 @@ func pickModelInstance(
 	if err != nil {
 		return nil, nil, err
 	}
+	// the retry layer reads the fallback policy of the group after the attempt
+	modelretry.PutPolicyGroupFallback(ctx, group.GetFallback())
 
 	routingInstances, err := policygroup.BuildRoutingInstancesForClient(ctx, clientID)
 	if err != nil {
@@ -118,7 +122,7 @@ func pickModelInstanceFromPolicyGroup(
 	routingInstances := filterRetryExcludedInstances(ctx, attempt.routingInstances)
 	instance, trace, err := attempt.routeEngine.Route(ctx, policygroup.RouteRequest{
 		ClientID:  attempt.clientID,
-		Group:     attempt.group,
+		Group:     filterRetryExcludedBranches(ctx, attempt.group),
 		Instances: routingInstances,
 		Meta:      attempt.meta,
 		Ctx:       ctx,
@@ -129,6 +133,30 @@ func pickModelInstanceFromPolicyGroup(
 	return trace, instance, nil
 }
 
+// filterRetryExcludedBranches returns a copy of the group without branches failed in previous attempts.
+func filterRetryExcludedBranches(ctx context.Context, group *policypb.PolicyGroup) *policypb.PolicyGroup {
+	excluded, ok := modelretry.GetExcludedBranchNames(ctx)
+	if !ok || len(excluded) == 0 || group == nil {
+		return group
+	}
+	filtered := proto.Clone(group).(*policypb.PolicyGroup)
+	filtered.Branches = filtered.Branches[:0]
+	for _, branch := range group.Branches {
+		if branch == nil {
+			continue
+		}
+		if _, hit := excluded[branch.Name]; hit {
+			continue
+		}
+		filtered.Branches = append(filtered.Branches, proto.Clone(branch).(*policypb.PolicyBranch))
+	}
+	// all branches failed, fall back to the excluded instances only
+	if len(filtered.Branches) == 0 {
+		return group
+	}
+	return filtered
+}
+
 func filterRetryExcludedInstances(ctx context.Context, instances []*policygroup.RoutingModelInstance) []*policygroup.RoutingModelInstance {
 	excluded, ok := modelretry.GetExcludedModelIDs(ctx)
 	if !ok || len(excluded) == 0 {
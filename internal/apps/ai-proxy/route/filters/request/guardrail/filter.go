@@ -0,0 +1,123 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guardrail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/audithelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/body_util"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/guardrail/guardrailutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	"github.com/erda-project/erda/pkg/desensitize"
+	httperrorutil "github.com/erda-project/erda/pkg/http/httputil"
+)
+
+const Name = "guardrail"
+
+var (
+	_ filter_define.ProxyRequestRewriter = (*Filter)(nil)
+)
+
+func init() {
+	filter_define.RegisterFilterCreator(Name, Creator)
+}
+
+// Filter detects PII and secrets in prompts for clients opted in via setting namespace `guardrail`,
+// and masks them, rejects the request, or only records findings according to the client's policy.
+//
+// It must run before the service provider directors, so the body is still in the OpenAI-compatible format.
+type Filter struct{}
+
+var Creator filter_define.RequestRewriterCreator = func(_ string, _ json.RawMessage) filter_define.ProxyRequestRewriter {
+	return &Filter{}
+}
+
+func (f *Filter) OnProxyRequest(pr *httputil.ProxyRequest) error {
+	ctx := pr.In.Context()
+	if trusted, ok := ctxhelper.GetTrustedHealthProbe(ctx); ok && trusted {
+		return nil
+	}
+	clientID, ok := ctxhelper.GetClientId(ctx)
+	if !ok || clientID == "" {
+		return nil
+	}
+	policy, ok := guardrailutil.ResolvePolicy(ctx, clientID, guardrailutil.StageRequest)
+	if !ok {
+		return nil
+	}
+	if !strings.HasPrefix(pr.Out.Header.Get(httperrorutil.HeaderKeyContentType), string(httperrorutil.ApplicationJson)) {
+		return nil
+	}
+
+	bodyCopy, err := body_util.SmartCloneBody(&pr.Out.Body, body_util.MaxSample)
+	if err != nil {
+		return fmt.Errorf("failed to clone request body: %w", err)
+	}
+	var body map[string]any
+	decoder := json.NewDecoder(bodyCopy)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		// not a JSON object, nothing to guard
+		return nil
+	}
+	findings := guardrailutil.ScanRequestBody(body, policy.Kinds, policy.Action == guardrailutil.ActionMask)
+	if len(findings) == 0 {
+		return nil
+	}
+	guardrailutil.NoteFindings(ctx, guardrailutil.StageRequest, policy.Action, findings)
+
+	switch policy.Action {
+	case guardrailutil.ActionBlock:
+		maskAuditPrompt(ctx, policy.Kinds)
+		msg := fmt.Sprintf("Your request was rejected as the prompt contains sensitive data: %s.", guardrailutil.Summarize(findings))
+		return http_error.NewHTTPErrorWithCtx(ctx, http.StatusBadRequest, msg, map[string]any{
+			"code":    "content_filter",
+			"message": msg,
+			"type":    "invalid_request_error",
+		})
+	case guardrailutil.ActionMask:
+		maskAuditPrompt(ctx, policy.Kinds)
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		if err := body_util.SetBody(pr.Out, b); err != nil {
+			return fmt.Errorf("failed to set request body: %w", err)
+		}
+	}
+	return nil
+}
+
+// maskAuditPrompt keeps sensitive data of the prompt noted by context filters out of the audit table.
+func maskAuditPrompt(ctx context.Context, kinds []desensitize.Kind) {
+	sink, ok := ctxhelper.GetAuditSink(ctx)
+	if !ok || sink == nil {
+		return
+	}
+	prompt, ok := sink.Snapshot()["prompt"].(string)
+	if !ok || prompt == "" {
+		return
+	}
+	masked, _ := desensitize.MaskText(prompt, kinds...)
+	audithelper.Note(ctx, "prompt", masked)
+}
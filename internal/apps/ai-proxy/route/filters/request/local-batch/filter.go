@@ -0,0 +1,214 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local_batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/batch"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/audithelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/transports"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+	httperrorutil "github.com/erda-project/erda/pkg/http/httputil"
+)
+
+const (
+	Name = "local-batch"
+
+	maxMultipartMemory = 32 << 20
+)
+
+var (
+	_ filter_define.ProxyRequestRewriter = (*Filter)(nil)
+)
+
+func init() {
+	filter_define.RegisterFilterCreator(Name, Creator)
+}
+
+// Filter serves the OpenAI Files and Batch APIs by ai-proxy itself, see package batch.
+//
+// A request is served locally when it addresses a local file or batch by id,
+// or lists/creates without specifying a model; otherwise it goes on to the provider's native API.
+// It must be placed right after `auth`, as the later filters require a model.
+type Filter struct{}
+
+var Creator filter_define.RequestRewriterCreator = func(_ string, _ json.RawMessage) filter_define.ProxyRequestRewriter {
+	return &Filter{}
+}
+
+func (f *Filter) OnProxyRequest(pr *httputil.ProxyRequest) error {
+	ctx := pr.In.Context()
+	svc := batch.GetService()
+	if svc == nil || !isLocal(pr) {
+		return nil
+	}
+	caller := batch.Caller{ClientID: ctxhelper.MustGetClientId(ctx)}
+	if token, ok := ctxhelper.GetClientToken(ctx); ok && token != nil {
+		caller.ClientTokenID = token.Id
+	}
+
+	var (
+		v   any
+		err error
+	)
+	path := ctxhelper.MustGetPathMatcher(ctx).Pattern
+	query := pr.In.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	switch {
+	case path == vars.RequestPathPrefixV1Files && pr.In.Method == http.MethodPost:
+		v, err = uploadFile(pr, svc, caller)
+	case path == vars.RequestPathPrefixV1Files && pr.In.Method == http.MethodGet:
+		audithelper.Note(ctx, "prompt", "local file list request")
+		v, err = svc.ListFiles(ctx, caller, query.Get("purpose"), query.Get("after"), limit)
+	case path == vars.RequestPathPrefixV1FilesByID && pr.In.Method == http.MethodGet:
+		audithelper.Note(ctx, "prompt", fmt.Sprintf("local file retrieve request, file_id: %s", pathParam(pr, "file_id")))
+		v, err = svc.GetFile(ctx, caller, pathParam(pr, "file_id"))
+	case path == vars.RequestPathPrefixV1FilesByID && pr.In.Method == http.MethodDelete:
+		audithelper.Note(ctx, "prompt", fmt.Sprintf("local file delete request, file_id: %s", pathParam(pr, "file_id")))
+		v, err = svc.DeleteFile(ctx, caller, pathParam(pr, "file_id"))
+	case path == vars.RequestPathPrefixV1FilesContent && pr.In.Method == http.MethodGet:
+		audithelper.Note(ctx, "prompt", fmt.Sprintf("local file content request, file_id: %s", pathParam(pr, "file_id")))
+		return fileContent(pr, svc, caller)
+	case path == vars.RequestPathPrefixV1Batches && pr.In.Method == http.MethodPost:
+		v, err = createBatch(pr, svc, caller)
+	case path == vars.RequestPathPrefixV1Batches && pr.In.Method == http.MethodGet:
+		audithelper.Note(ctx, "prompt", "local batch list request")
+		v, err = svc.ListBatches(ctx, caller, query.Get("after"), limit)
+	case path == vars.RequestPathPrefixV1BatchesByID && pr.In.Method == http.MethodGet:
+		audithelper.Note(ctx, "prompt", fmt.Sprintf("local batch retrieve request, batch_id: %s", pathParam(pr, "batch_id")))
+		v, err = svc.GetBatch(ctx, caller, pathParam(pr, "batch_id"))
+	case path == vars.RequestPathPrefixV1BatchesCancel && pr.In.Method == http.MethodPost:
+		audithelper.Note(ctx, "prompt", fmt.Sprintf("local batch cancel request, batch_id: %s", pathParam(pr, "batch_id")))
+		v, err = svc.CancelBatch(ctx, caller, pathParam(pr, "batch_id"))
+	default:
+		return nil
+	}
+	if err != nil {
+		return toHTTPError(pr, err)
+	}
+	return respondJSON(pr, v)
+}
+
+// isLocal decides between the local implementation and the provider's native API.
+func isLocal(pr *httputil.ProxyRequest) bool {
+	if id := pathParam(pr, "batch_id"); id != "" {
+		return batch.IsLocalBatchID(id)
+	}
+	if id := pathParam(pr, "file_id"); id != "" {
+		return batch.IsLocalFileID(id)
+	}
+	for _, key := range []string{vars.XAIProxyModelId, vars.XAIProxyModel, vars.XAIProxyModelName} {
+		if pr.In.Header.Get(key) != "" {
+			return false
+		}
+	}
+	if pr.In.URL.Query().Get("model") != "" {
+		return false
+	}
+	if pr.In.Method == http.MethodPost && ctxhelper.MustGetPathMatcher(pr.In.Context()).Pattern == vars.RequestPathPrefixV1Files {
+		if err := pr.In.ParseMultipartForm(maxMultipartMemory); err == nil && pr.In.FormValue("model") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func uploadFile(pr *httputil.ProxyRequest, svc *batch.Service, caller batch.Caller) (any, error) {
+	if err := pr.In.ParseMultipartForm(maxMultipartMemory); err != nil {
+		return nil, batch.NewError(http.StatusBadRequest, "invalid_request", fmt.Sprintf("failed to parse multipart form: %v", err))
+	}
+	file, fileHeader, err := pr.In.FormFile("file")
+	if err != nil {
+		return nil, batch.NewError(http.StatusBadRequest, "missing_required_parameter", fmt.Sprintf("failed to parse file field, err: %v", err))
+	}
+	defer file.Close()
+	purpose := pr.In.FormValue("purpose")
+	audithelper.Note(pr.In.Context(), "prompt", fmt.Sprintf("local file upload, filename: %s, purpose: %s", fileHeader.Filename, purpose))
+	return svc.UploadFile(pr.In.Context(), caller, fileHeader.Filename, purpose, file)
+}
+
+func createBatch(pr *httputil.ProxyRequest, svc *batch.Service, caller batch.Caller) (any, error) {
+	var req batch.CreateBatchRequest
+	if err := json.NewDecoder(pr.In.Body).Decode(&req); err != nil {
+		return nil, batch.NewError(http.StatusBadRequest, "invalid_request", fmt.Sprintf("failed to decode request body: %v", err))
+	}
+	audithelper.Note(pr.In.Context(), "prompt", fmt.Sprintf("local batch endpoint: %s, completion_window: %s, input_file_id: %s", req.Endpoint, req.CompletionWindow, req.InputFileID))
+	return svc.CreateBatch(pr.In.Context(), caller, req)
+}
+
+func fileContent(pr *httputil.ProxyRequest, svc *batch.Service, caller batch.Caller) error {
+	r, file, err := svc.FileContent(pr.In.Context(), caller, pathParam(pr, "file_id"))
+	if err != nil {
+		return toHTTPError(pr, err)
+	}
+	header := http.Header{}
+	header.Set(httperrorutil.HeaderKeyContentType, "application/octet-stream")
+	header.Set(httperrorutil.HeaderKeyContentDisposition, fmt.Sprintf("attachment; filename=%q", file.Filename))
+	transports.TriggerRequestFilterGeneratedResponse(pr.Out, &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          r,
+		ContentLength: file.Bytes,
+		Request:       pr.Out,
+	})
+	return nil
+}
+
+func respondJSON(pr *httputil.ProxyRequest, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	header := http.Header{}
+	header.Set(httperrorutil.HeaderKeyContentType, string(httperrorutil.ApplicationJson))
+	transports.TriggerRequestFilterGeneratedResponse(pr.Out, &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       pr.Out,
+	})
+	return nil
+}
+
+func toHTTPError(pr *httputil.ProxyRequest, err error) error {
+	ctx := pr.In.Context()
+	var batchErr *batch.Error
+	if errors.As(err, &batchErr) {
+		return http_error.NewHTTPErrorWithCtx(ctx, batchErr.StatusCode, batchErr.Message, map[string]any{
+			"code":    batchErr.Code,
+			"message": batchErr.Message,
+			"type":    "invalid_request_error",
+		})
+	}
+	ctxhelper.MustGetLogger(ctx).Errorf("local batch request failed: %v", err)
+	return http_error.NewHTTPError(ctx, http.StatusInternalServerError, err.Error())
+}
+
+func pathParam(pr *httputil.ProxyRequest, key string) string {
+	v, _ := ctxhelper.GetPathParam(pr.In.Context(), key)
+	return v
+}
@@ -159,6 +159,20 @@ func (f *Filter) OnConnect(logger logs.Logger, ctx context.Context, name, versio
 	}
 	req.URL.RawQuery = query.Encode()
 
+	// streamable http transport sends messages to the connect endpoint
+	if req.Method == http.MethodPost {
+		rawBody, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(rawBody))
+		if err := guardToolCall(ctx, server, req.Header.Get(McpSessionIdHeader), rawBody, req, true); err != nil {
+			logger.Errorf("guard tool call failed: %v", err)
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -238,6 +252,11 @@ func (f *Filter) OnMessage(logger logs.Logger, ctx context.Context, name string,
 	req.URL.Host = endpoint
 	req.URL.Path = messagePath
 
+	if err := guardToolCall(ctx, server, sessionId, rawBody, req, false); err != nil {
+		logger.Errorf("guard tool call failed: %v", err)
+		return err
+	}
+
 	return nil
 }
 
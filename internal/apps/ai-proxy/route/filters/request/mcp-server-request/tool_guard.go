@@ -0,0 +1,225 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp_server_request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_server/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_tool_call"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/providers/dao"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/mcp-tool/mcptoolutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/mcp-server-request/request"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/transports"
+	httperrorutil "github.com/erda-project/erda/pkg/http/httputil"
+)
+
+// McpSessionIdHeader is the session header of streamable http transport.
+const McpSessionIdHeader = "Mcp-Session-Id"
+
+// guardToolCall checks `tools/call` against the tool policy of the client, and records it for audit.
+// Rejected calls get a JSON-RPC error generated here without reaching the mcp server.
+// Results of calls allowed are recorded by the mcp-server-response filter.
+//
+// For sse transport, inline is false: results are sent in the event stream, the call itself gets an http error if rejected.
+//
+// JSON-RPC batches carrying `tools/call` are rejected with 400, calls can't hide from the tool policy and audit in a batch.
+func guardToolCall(ctx context.Context, server *pb.MCPServer, mcpSessionID string, body []byte, req *http.Request, inline bool) error {
+	if isBatch(body) {
+		if batchHasToolCall(body) {
+			ctxhelper.MustGetLogger(ctx).Warnf("mcp tool call in batch rejected, mcp: %s", server.Name)
+			rejectToolCall(req, http.StatusBadRequest,
+				mcptoolutil.ErrorResponse(nil, mcptoolutil.CodeInvalidRequest, "tools/call is not allowed in a JSON-RPC batch"))
+		}
+		return nil
+	}
+	var rpcReq request.Request
+	if err := json.Unmarshal(body, &rpcReq); err != nil {
+		// a call the guard can't parse is not sent either
+		var msg rpcMessage
+		if json.Unmarshal(body, &msg) == nil && msg.Method == request.ToolCallMethod {
+			rejectToolCall(req, http.StatusBadRequest,
+				mcptoolutil.ErrorResponse(msg.ID, mcptoolutil.CodeInvalidRequest, fmt.Sprintf("invalid tools/call request: %v", err)))
+		}
+		return nil
+	}
+	if rpcReq.Method != request.ToolCallMethod {
+		return nil
+	}
+	client := ctxhelper.MustGetDBClient(ctx)
+	clientId, _ := ctxhelper.GetClientId(ctx)
+
+	call := &mcp_tool_call.McpToolCall{
+		ClientID:     clientId,
+		McpName:      server.Name,
+		McpVersion:   server.Version,
+		McpSessionID: mcpSessionID,
+		RpcID:        mcptoolutil.RPCID(rpcReq.ID),
+		ToolName:     rpcReq.Params.Name,
+		Arguments:    mcptoolutil.MarshalArguments(rpcReq.Params.Arguments),
+		Status:       mcptoolutil.StatusPending,
+	}
+	if token, ok := ctxhelper.GetClientToken(ctx); ok && token != nil {
+		call.ClientTokenID = token.Id
+	}
+
+	policy, err := loadToolPolicy(ctx, client, clientId, server.Name)
+	if err != nil {
+		return err
+	}
+	var code int
+	if !policy.Allows(call.ToolName) {
+		call.Status, code = mcptoolutil.StatusDenied, mcptoolutil.CodeToolDenied
+		call.Error = fmt.Sprintf("tool %s of mcp server %s is not allowed", call.ToolName, server.Name)
+	} else if policy != nil && policy.ValidateArguments {
+		if err := validateToolArguments(server.Tools, call.ToolName, rpcReq.Params.Arguments); err != nil {
+			call.Status, code = mcptoolutil.StatusInvalid, mcptoolutil.CodeInvalidParams
+			call.Error = err.Error()
+		}
+	}
+	if call.Status != mcptoolutil.StatusPending {
+		now := time.Now()
+		call.FinishedAt = &now
+	}
+	// calls are not sent unless they are recorded
+	if err := client.MCPToolCallClient().Create(ctx, call); err != nil {
+		return fmt.Errorf("failed to record tool call: %w", err)
+	}
+	if call.Status == mcptoolutil.StatusPending {
+		ctxhelper.PutMcpToolCall(ctx, call)
+		return nil
+	}
+
+	ctxhelper.MustGetLogger(ctx).Warnf("mcp tool call rejected, client: %s, mcp: %s, tool: %s, reason: %s", clientId, server.Name, call.ToolName, call.Error)
+	status := http.StatusOK
+	if !inline {
+		status = http.StatusForbidden
+		if code == mcptoolutil.CodeInvalidParams {
+			status = http.StatusBadRequest
+		}
+	}
+	rejectToolCall(req, status, mcptoolutil.ErrorResponse(rpcReq.ID, code, call.Error))
+	return nil
+}
+
+// rpcMessage is the part of a JSON-RPC message needed to tell tool calls, whatever its params are.
+type rpcMessage struct {
+	ID     any    `json:"id"`
+	Method string `json:"method"`
+}
+
+func isBatch(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// batchHasToolCall reports whether any message of the batch is `tools/call`, a batch that can't be parsed counts.
+func batchHasToolCall(body []byte) bool {
+	var msgs []rpcMessage
+	if err := json.Unmarshal(body, &msgs); err != nil {
+		return true
+	}
+	for _, msg := range msgs {
+		if msg.Method == request.ToolCallMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectToolCall answers the request with the JSON-RPC error b without reaching the mcp server.
+func rejectToolCall(req *http.Request, status int, b []byte) {
+	header := http.Header{}
+	header.Set(httperrorutil.HeaderKeyContentType, string(httperrorutil.ApplicationJson))
+	transports.TriggerRequestFilterGeneratedResponse(req, &http.Response{
+		StatusCode:    status,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       req,
+	})
+}
+
+// loadToolPolicy returns nil if the client has no policy of the mcp server, e.g., calls of admin.
+func loadToolPolicy(ctx context.Context, client dao.DAO, clientId, mcpName string) (*mcptoolutil.Policy, error) {
+	if clientId == "" {
+		return nil, nil
+	}
+	policy, err := client.MCPToolPolicyClient().Find(ctx, clientId, mcpName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mcp tool policy: %w", err)
+	}
+	if policy == nil {
+		return nil, nil
+	}
+	return &mcptoolutil.Policy{
+		AllowTools:        policy.AllowTools,
+		DenyTools:         policy.DenyTools,
+		ValidateArguments: policy.ValidateArguments,
+	}, nil
+}
+
+// validateToolArguments validates arguments against the input schema of the tool registered.
+func validateToolArguments(tools []*pb.MCPServerTool, name string, args map[string]any) error {
+	var tool *pb.MCPServerTool
+	for _, t := range tools {
+		if t.GetName() == name {
+			tool = t
+			break
+		}
+	}
+	if tool == nil {
+		return fmt.Errorf("unknown tool: %s", name)
+	}
+	schema := map[string]any{"type": "object"}
+	if s := tool.GetInputSchema(); s != nil {
+		if s.Type != "" {
+			schema["type"] = s.Type
+		}
+		properties := make(map[string]any, len(s.Properties))
+		for key, property := range s.Properties {
+			properties[key] = property.AsMap()
+		}
+		schema["properties"] = properties
+		if len(s.Required) > 0 {
+			schema["required"] = s.Required
+		}
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewGoLoader(args))
+	if err != nil {
+		return fmt.Errorf("failed to validate arguments of tool %s: %v", name, err)
+	}
+	if result.Valid() {
+		return nil
+	}
+	var errs []string
+	for _, item := range result.Errors() {
+		errs = append(errs, item.String())
+	}
+	return fmt.Errorf("invalid arguments of tool %s: %s", name, strings.Join(errs, "; "))
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcp_server_request
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/erda-project/erda-infra/base/logs/logrusx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda-proto-go/apps/aiproxy/mcp_server/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/mcp-tool/mcptoolutil"
+)
+
+func TestGuardToolCall_RejectBatch(t *testing.T) {
+	cases := []struct {
+		name     string
+		body     string
+		rejected bool
+	}{
+		{name: "denied tool call in batch", body: `[{"jsonrpc":"2.0","id":1,"method":"tools/list"},{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"rm_rf","arguments":{}}}]`, rejected: true},
+		{name: "malformed batch", body: `[{"jsonrpc":"2.0","id":1,"method":"tools/call"},`, rejected: true},
+		{name: "batch without tool call", body: `[{"jsonrpc":"2.0","id":1,"method":"tools/list"}]`},
+		{name: "unparsable tool call", body: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"rm_rf","arguments":[1]}}`, rejected: true},
+		{name: "not a tool call", body: `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+			ctxhelper.PutLogger(ctx, logrusx.New())
+			req := httptest.NewRequest(http.MethodPost, "http://mcp.test/mcp", strings.NewReader(c.body)).WithContext(ctx)
+
+			require.NoError(t, guardToolCall(ctx, &pb.MCPServer{Name: "demo"}, "", []byte(c.body), req, true))
+
+			resp, ok := ctxhelper.GetRequestFilterGeneratedResponse(ctx)
+			if !c.rejected {
+				require.False(t, ok && resp != nil)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			b, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			var rpcResp struct {
+				Error struct {
+					Code int `json:"code"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(b, &rpcResp))
+			require.Equal(t, mcptoolutil.CodeInvalidRequest, rpcResp.Error.Code)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompt_template
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"gorm.io/gorm"
+
+	metadatapb "github.com/erda-project/erda-proto-go/apps/aiproxy/metadata/pb"
+	promptpb "github.com/erda-project/erda-proto-go/apps/aiproxy/prompt/pb"
+	usagepb "github.com/erda-project/erda-proto-go/apps/aiproxy/usage/token/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/audithelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	prompttpl "github.com/erda-project/erda/internal/apps/ai-proxy/common/prompt_template"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/usage/token_usage"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/message"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/prompt"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/body_util"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	httperrorutil "github.com/erda-project/erda/pkg/http/httputil"
+)
+
+const (
+	Name = "prompt-template"
+
+	// bodyKey is the field of chat request referencing a prompt, e.g.:
+	//   "prompt": {"name": "summary@3", "variables": {"lang": "en"}}
+	bodyKey = "prompt"
+
+	stickyKeyUser    = "user"
+	stickyKeySession = "session"
+)
+
+var (
+	_ filter_define.ProxyRequestRewriter = (*Filter)(nil)
+)
+
+func init() {
+	filter_define.RegisterFilterCreator(Name, Creator)
+	token_usage.RegisterCollectedHook(noteTokenUsage)
+}
+
+// Filter renders the prompt referenced by the chat request, and prepends the rendered messages to request messages.
+//
+// The version is chosen in order: the version of reference, the experiment, the published version.
+// Prompts without any version are used as they are.
+type Filter struct{}
+
+var Creator filter_define.RequestRewriterCreator = func(_ string, _ json.RawMessage) filter_define.ProxyRequestRewriter {
+	return &Filter{}
+}
+
+type requestPrompt struct {
+	Name      string         `json:"name"`
+	Variables map[string]any `json:"variables"`
+}
+
+// Selection is the prompt version used by the request.
+type Selection struct {
+	Name    string
+	Version uint64
+	// Variant is set if the version is chosen by the experiment.
+	Variant bool
+}
+
+func (f *Filter) OnProxyRequest(pr *httputil.ProxyRequest) error {
+	ctx := pr.Out.Context()
+	if !strings.HasPrefix(pr.Out.Header.Get(httperrorutil.HeaderKeyContentType), string(httperrorutil.ApplicationJson)) {
+		return nil
+	}
+	bodyCopy, err := body_util.SmartCloneBody(&pr.Out.Body, body_util.MaxSample)
+	if err != nil {
+		return fmt.Errorf("failed to clone request body: %w", err)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(bodyCopy).Decode(&body); err != nil {
+		// not a JSON object, leave it to the provider
+		return nil
+	}
+	rawPrompt, ok := body[bodyKey]
+	if !ok {
+		return nil
+	}
+	var reqPrompt requestPrompt
+	if b, err := json.Marshal(rawPrompt); err != nil || json.Unmarshal(b, &reqPrompt) != nil {
+		return badRequest(ctx, `invalid "prompt", expected: {"name": "<name>[@<version>]", "variables": {...}}`)
+	}
+	ref, err := prompttpl.ParseRef(reqPrompt.Name)
+	if err != nil {
+		return badRequest(ctx, err.Error())
+	}
+
+	clientID, _ := ctxhelper.GetClientId(ctx)
+	promptClient := ctxhelper.MustGetDBClient(ctx).PromptClient()
+	p, err := promptClient.GetByName(ctx, clientID, ref.Name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return badRequest(ctx, fmt.Sprintf("prompt not found: %s", ref.Name))
+		}
+		return fmt.Errorf("failed to get prompt %s: %w", ref.Name, err)
+	}
+	selection := selectVersion(p, ref, stickyValue(ctx, body, p.Experiment.GetStickyKey()))
+
+	msgs := p.Messages
+	var variables prompt.Variables
+	if selection.Version > 0 || ref.Latest {
+		v, err := promptClient.FindVersion(ctx, p.Id, selection.Version)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return badRequest(ctx, fmt.Sprintf("prompt version not found: %s", ref))
+			}
+			return fmt.Errorf("failed to get version of prompt %s: %w", ref, err)
+		}
+		selection.Version = v.Version
+		msgs, variables = v.Messages, prompt.VariablesFromProtobuf(v.Variables)
+	}
+	values, err := prompttpl.ResolveVariables(variables, reqPrompt.Variables)
+	if err != nil {
+		return badRequest(ctx, fmt.Sprintf("invalid variables of prompt %s: %v", ref.Name, err))
+	}
+	rendered, err := prompttpl.RenderMessages(message.FromProtobuf(msgs), values)
+	if err != nil {
+		return badRequest(ctx, fmt.Sprintf("failed to render prompt %s: %v", ref.Name, err))
+	}
+
+	reqMsgs, _ := body["messages"].([]any)
+	allMsgs := make([]any, 0, len(rendered)+len(reqMsgs))
+	for _, msg := range rendered {
+		allMsgs = append(allMsgs, msg)
+	}
+	body["messages"] = append(allMsgs, reqMsgs...)
+	delete(body, bodyKey)
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	if err := body_util.SetBody(pr.Out, b); err != nil {
+		return fmt.Errorf("failed to set request body: %w", err)
+	}
+
+	ctxhelper.PutPromptSelection(ctx, &selection)
+	audithelper.Note(ctx, "prompt.name", selection.Name)
+	audithelper.Note(ctx, "prompt.version", selection.Version)
+	if selection.Variant {
+		audithelper.Note(ctx, "prompt.variant", variantName(selection.Version))
+	}
+	return nil
+}
+
+// selectVersion returns version 0 if the latest version or no version should be used.
+func selectVersion(p *promptpb.Prompt, ref prompttpl.Ref, stickyValue string) Selection {
+	selection := Selection{Name: ref.Name, Version: ref.Version}
+	if ref.Version > 0 || ref.Latest {
+		return selection
+	}
+	if variants := p.Experiment.GetVariants(); len(variants) > 0 {
+		candidates := make([]prompttpl.Variant, 0, len(variants))
+		for _, v := range variants {
+			candidates = append(candidates, prompttpl.Variant{Version: v.Version, Weight: v.Weight})
+		}
+		if picked, ok := prompttpl.PickVariant(candidates, stickyValue); ok {
+			selection.Version, selection.Variant = picked.Version, true
+			return selection
+		}
+	}
+	selection.Version = p.PublishedVersion
+	return selection
+}
+
+func stickyValue(ctx context.Context, body map[string]any, stickyKey string) string {
+	switch stickyKey {
+	case stickyKeyUser:
+		user, _ := body["user"].(string)
+		return user
+	case stickyKeySession:
+		if session, ok := ctxhelper.GetSession(ctx); ok && session != nil {
+			return session.Id
+		}
+	}
+	return ""
+}
+
+func variantName(version uint64) string {
+	return "v" + strconv.FormatUint(version, 10)
+}
+
+func badRequest(ctx context.Context, msg string) error {
+	return http_error.NewHTTPErrorWithCtx(ctx, http.StatusBadRequest, msg, map[string]any{
+		"code":    "invalid_prompt",
+		"message": msg,
+		"param":   bodyKey,
+		"type":    "invalid_request_error",
+	})
+}
+
+// GetSelection returns the prompt version used by the request.
+func GetSelection(ctx context.Context) (*Selection, bool) {
+	v, ok := ctxhelper.GetPromptSelection(ctx)
+	if !ok || v == nil {
+		return nil, false
+	}
+	selection, ok := v.(*Selection)
+	return selection, ok && selection != nil
+}
+
+// noteTokenUsage records the prompt version, so cost and latency can be compared per variant.
+func noteTokenUsage(ctx context.Context, usage *usagepb.TokenUsageCreateRequest) {
+	selection, ok := GetSelection(ctx)
+	if !ok {
+		return
+	}
+	if usage.Metadata == nil {
+		usage.Metadata = &metadatapb.Metadata{}
+	}
+	if usage.Metadata.Public == nil {
+		usage.Metadata.Public = make(map[string]*structpb.Value)
+	}
+	usage.Metadata.Public["prompt_name"] = structpb.NewStringValue(selection.Name)
+	usage.Metadata.Public["prompt_version"] = structpb.NewStringValue(strconv.FormatUint(selection.Version, 10))
+	if selection.Variant {
+		usage.Metadata.Public["prompt_variant"] = structpb.NewStringValue(variantName(selection.Version))
+	}
+}
@@ -15,6 +15,7 @@
 package rate_limit
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -25,10 +26,14 @@ import (
 
 	"golang.org/x/time/rate"
 
+	usagepb "github.com/erda-project/erda-proto-go/apps/aiproxy/usage/token/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/audithelper"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/usage/token_usage"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/client_token"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/lb/state_store"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
 	httperrorutil "github.com/erda-project/erda/pkg/http/httputil"
 )
@@ -39,6 +44,15 @@ var (
 	_ filter_define.ProxyRequestRewriter = (*RateLimiter)(nil)
 )
 
+var (
+	// getStore is a var for testing.
+	getStore = state_store.GetStore
+	// nowFunc is a var for testing.
+	nowFunc = time.Now
+)
+
+// TokenLimiter is the default limit per client token in each replica, used when no rule matches the request.
+// The limit is set by the `default_token_limit` setting, see TokenLimit.
 type TokenLimiter struct {
 	mu      sync.Mutex
 	limiter map[string]*rate.Limiter
@@ -48,6 +62,7 @@ var tokenLimiter *TokenLimiter
 
 func init() {
 	filter_define.RegisterFilterCreator(name, Creator)
+	token_usage.RegisterCollectedHook(recordTokenUsage)
 
 	// init pkg level vars
 	tokenLimiter = &TokenLimiter{
@@ -56,21 +71,32 @@ func init() {
 	}
 }
 
+// RateLimiter enforces RPM/TPM rules configured in setting namespace `rate_limit`.
+// Counters live in the lb state store, so limits are shared by all replicas when redis or etcd is used.
+// Requests matching no rule fall back to the default per client token limit.
+// It must be placed after `auth` and `context` to know the client, client token and model.
 type RateLimiter struct{}
 
 var Creator filter_define.RequestRewriterCreator = func(_ string, _ json.RawMessage) filter_define.ProxyRequestRewriter {
 	return &RateLimiter{}
 }
 
-func (ul *TokenLimiter) GetLimiter(token string) *rate.Limiter {
+// GetLimiter returns the limiter of the token, the limit of an existing one is updated if changed in settings.
+func (ul *TokenLimiter) GetLimiter(token string, limit TokenLimit) *rate.Limiter {
 	ul.mu.Lock()
 	defer ul.mu.Unlock()
 
 	limiter, ok := ul.limiter[token]
 	if !ok {
-		ul.limiter[token] = rate.NewLimiter(rate.Every(time.Second), 2) // burst = 2
+		ul.limiter[token] = rate.NewLimiter(rate.Limit(limit.RPS), limit.Burst)
 		return ul.limiter[token]
 	}
+	if limiter.Limit() != rate.Limit(limit.RPS) {
+		limiter.SetLimit(rate.Limit(limit.RPS))
+	}
+	if limiter.Burst() != limit.Burst {
+		limiter.SetBurst(limit.Burst)
+	}
 
 	return limiter
 }
@@ -83,20 +109,59 @@ func (f *RateLimiter) OnProxyRequest(pr *httputil.ProxyRequest) error {
 		return nil
 	}
 
-	// only limit token rate now
+	var matched []Rule
+	subject, ok := getSubject(ctx)
+	if ok {
+		for _, rule := range resolveRules(ctx) {
+			if rule.Match(subject) {
+				matched = append(matched, rule)
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return limitByToken(pr)
+	}
+
+	result, err := check(ctx, getStore(), matched, subject, nowFunc())
+	if err != nil {
+		state_store.FailOpen(l, err, "failed to check rate limit")
+		return nil
+	}
+	ctxhelper.PutRateLimitHeaders(ctx, result.headers())
+	ctxhelper.PutRateLimitTokenCounters(ctx, result.tokenCounters)
+
+	if result.exceeded != nil {
+		exceeded := result.exceeded
+		audithelper.Note(ctx, "rate_limit.exceeded", fmt.Sprintf("%s:%s", exceeded.counter.subject, exceeded.kind))
+		l.Warnf("rate limit exceeded, subject: %s, kind: %s, limit: %d", exceeded.counter.subject, exceeded.kind, exceeded.limit)
+		msg := fmt.Sprintf("Rate limit reached for %s on %s per min: Limit %d, Used %d. Please try again in %s.",
+			exceeded.counter.subject, exceeded.kind, exceeded.limit, exceeded.used, formatReset(result.reset))
+		return http_error.NewHTTPErrorWithCtx(ctx, http.StatusTooManyRequests, msg, map[string]any{
+			"code":    "rate_limit_exceeded",
+			"message": msg,
+			"type":    string(exceeded.kind),
+		})
+	}
+	l.Debugf("pass rate limit, subject: %+v", subject)
+	return nil
+}
+
+// limitByToken is the default limit when no rule matches the request
+func limitByToken(pr *httputil.ProxyRequest) error {
+	ctx := pr.In.Context()
+	l := ctxhelper.MustGetLogger(ctx)
 	token, isTokenInvoke := isClientTokenInvoke(pr)
 	if !isTokenInvoke {
 		return nil
 	}
 
-	limiter := tokenLimiter.GetLimiter(token)
+	limiter := tokenLimiter.GetLimiter(token, resolveTokenLimit(ctx))
 	if !limiter.Allow() {
 		err := fmt.Errorf("too many requests for token rate limit, token: %s", token)
 		l.Warn(err)
 		return http_error.NewHTTPError(ctx, http.StatusTooManyRequests, "too many requests for token rate limit")
 	}
 	l.Debugf("pass token rate limit, token: %s", token)
-
 	return nil
 }
 
@@ -107,3 +172,42 @@ func isClientTokenInvoke(pr *httputil.ProxyRequest) (string, bool) {
 	}
 	return "", false
 }
+
+func getSubject(ctx context.Context) (Subject, bool) {
+	var subject Subject
+	clientID, ok := ctxhelper.GetClientId(ctx)
+	if !ok || clientID == "" {
+		return subject, false
+	}
+	subject.ClientID = clientID
+	if clientToken, ok := ctxhelper.GetClientToken(ctx); ok && clientToken != nil {
+		subject.ClientTokenID = clientToken.Id
+	}
+	if model, ok := ctxhelper.GetModel(ctx); ok && model != nil {
+		subject.Model = model.Name
+	}
+	return subject, true
+}
+
+// recordTokenUsage adds the prompt+completion tokens of a finished call to the TPM counters
+// selected when the request passed the filter.
+func recordTokenUsage(ctx context.Context, usage *usagepb.TokenUsageCreateRequest) {
+	v, ok := ctxhelper.GetRateLimitTokenCounters(ctx)
+	if !ok || v == nil {
+		return
+	}
+	counters, ok := v.([]counter)
+	if !ok || len(counters) == 0 || usage.TotalTokens == 0 {
+		return
+	}
+	if hit, ok := ctxhelper.GetResponseCacheHit(ctx); ok && hit {
+		// served from response cache, the provider spent no tokens
+		return
+	}
+	store := getStore()
+	for _, c := range counters {
+		if _, err := store.IncrCounterBy(context.Background(), c.key, int64(usage.TotalTokens), counterTTL); err != nil {
+			ctxhelper.MustGetLogger(ctx).Warnf("failed to record token usage for rate limit, key: %s, err: %v", c.key, err)
+		}
+	}
+}
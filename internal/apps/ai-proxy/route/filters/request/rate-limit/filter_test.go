@@ -16,16 +16,27 @@ package rate_limit
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
 
 	"github.com/erda-project/erda-infra/base/logs/logrusx"
+	clienttokenpb "github.com/erda-project/erda-proto-go/apps/aiproxy/client_token/pb"
+	modelpb "github.com/erda-project/erda-proto-go/apps/aiproxy/model/pb"
+	settingpb "github.com/erda-project/erda-proto-go/apps/aiproxy/setting/pb"
+	usagepb "github.com/erda-project/erda-proto-go/apps/aiproxy/usage/token/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/cache/cachetypes"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/models/client_token"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/lb/state_store"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
 )
 
@@ -78,6 +89,124 @@ func TestRateLimiter_StillLimitNormalRequest(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_NoRulesDefaultTokenLimit(t *testing.T) {
+	resetTokenLimiterForTest()
+	useStoreForTest(t, state_store.NewMemoryStateStore())
+
+	limiter := &RateLimiter{}
+	proxyReq := newRuleProxyRequestForTest("")
+	proxyReq.In.Header.Set("Authorization", "Bearer "+client_token.TokenPrefix+"token")
+	require.NoError(t, limiter.OnProxyRequest(proxyReq))
+	require.NoError(t, limiter.OnProxyRequest(proxyReq))
+	require.Error(t, limiter.OnProxyRequest(proxyReq))
+}
+
+func TestRateLimiter_DefaultTokenLimitFromSettings(t *testing.T) {
+	resetTokenLimiterForTest()
+
+	limiter := &RateLimiter{}
+	proxyReq := newRuleProxyRequestForTest("")
+	proxyReq.In.Header.Set("Authorization", "Bearer "+client_token.TokenPrefix+"token")
+	ctxhelper.PutCacheManager(proxyReq.In.Context(), &mockSettingCacheManager{settings: []*settingpb.Setting{
+		{Namespace: rateLimitSettingNamespace, Key: settingKeyDefaultTokenLimit, Value: `{"rps":1,"burst":3}`},
+	}})
+	for i := 0; i < 3; i++ {
+		require.NoError(t, limiter.OnProxyRequest(proxyReq), "request %d", i)
+	}
+	require.Error(t, limiter.OnProxyRequest(proxyReq))
+
+	require.Equal(t, defaultTokenLimit, resolveTokenLimit(newRuleProxyRequestForTest("").In.Context()))
+}
+
+func TestRateLimiter_LimitRequestsPerClient(t *testing.T) {
+	useStoreForTest(t, state_store.NewMemoryStateStore())
+	useNowForTest(t, time.Unix(1700000010, 0))
+
+	limiter := &RateLimiter{}
+	proxyReq := newRuleProxyRequestForTest(`[{"scope":"client","rpm":2}]`)
+
+	require.NoError(t, limiter.OnProxyRequest(proxyReq))
+	require.NoError(t, limiter.OnProxyRequest(proxyReq))
+	err := limiter.OnProxyRequest(proxyReq)
+	var httpErr *http_error.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, http.StatusTooManyRequests, httpErr.StatusCode)
+	require.Equal(t, "requests", httpErr.ErrorCtx["type"])
+
+	headers, ok := ctxhelper.GetRateLimitHeaders(proxyReq.In.Context())
+	require.True(t, ok)
+	require.Equal(t, "2", headers.Get(headerLimitRequests))
+	require.Equal(t, "0", headers.Get(headerRemainingRequests))
+	require.Equal(t, "50", headers.Get(headerRetryAfter))
+
+	// next window
+	useNowForTest(t, time.Unix(1700000070, 0))
+	require.NoError(t, limiter.OnProxyRequest(proxyReq))
+}
+
+func TestRateLimiter_LimitTokensByRecordedUsage(t *testing.T) {
+	useStoreForTest(t, state_store.NewMemoryStateStore())
+	useNowForTest(t, time.Unix(1700000010, 0))
+
+	limiter := &RateLimiter{}
+	proxyReq := newRuleProxyRequestForTest(`[{"scope":"client_token","model":"gpt-4o","tpm":100}]`)
+
+	require.NoError(t, limiter.OnProxyRequest(proxyReq))
+	recordTokenUsage(proxyReq.In.Context(), &usagepb.TokenUsageCreateRequest{TotalTokens: 60})
+	require.NoError(t, limiter.OnProxyRequest(proxyReq))
+	recordTokenUsage(proxyReq.In.Context(), &usagepb.TokenUsageCreateRequest{TotalTokens: 60})
+
+	err := limiter.OnProxyRequest(proxyReq)
+	var httpErr *http_error.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	require.Equal(t, "tokens", httpErr.ErrorCtx["type"])
+}
+
+func TestRateLimiter_CacheHitNotCountedAsTokens(t *testing.T) {
+	useStoreForTest(t, state_store.NewMemoryStateStore())
+	useNowForTest(t, time.Unix(1700000010, 0))
+
+	limiter := &RateLimiter{}
+	proxyReq := newRuleProxyRequestForTest(`[{"scope":"client_token","model":"gpt-4o","tpm":100}]`)
+
+	require.NoError(t, limiter.OnProxyRequest(proxyReq))
+	ctxhelper.PutResponseCacheHit(proxyReq.In.Context(), true)
+	recordTokenUsage(proxyReq.In.Context(), &usagepb.TokenUsageCreateRequest{TotalTokens: 200})
+	require.NoError(t, limiter.OnProxyRequest(proxyReq))
+}
+
+func TestRule_CounterSubject(t *testing.T) {
+	subject := Subject{ClientID: "c1", ClientTokenID: "t1", Model: "gpt-4o"}
+	cases := []struct {
+		rule   Rule
+		expect string
+		ok     bool
+	}{
+		{rule: Rule{Scope: ScopeClient}, expect: "client:c1", ok: true},
+		{rule: Rule{Scope: ScopeClient, Model: "gpt-4o"}, expect: "client:c1|model:gpt-4o", ok: true},
+		{rule: Rule{Scope: ScopeClientToken}, expect: "client_token:t1", ok: true},
+		{rule: Rule{Scope: ScopeModel, ClientID: "c1"}, expect: "model:gpt-4o", ok: true},
+		{rule: Rule{Scope: ScopeClientToken}, ok: false},
+	}
+	for i, c := range cases {
+		s := subject
+		if i == len(cases)-1 {
+			s.ClientTokenID = ""
+		}
+		got, ok := c.rule.CounterSubject(s)
+		require.Equal(t, c.ok, ok, "case %d", i)
+		require.Equal(t, c.expect, got, "case %d", i)
+	}
+}
+
+func TestParseRules_IgnoreInvalid(t *testing.T) {
+	rules := parseRules(context.Background(), `[{"scope":"client","rpm":10},{"scope":"unknown","rpm":1},{"scope":"model"}]`)
+	require.Len(t, rules, 1)
+	require.Equal(t, ScopeClient, rules[0].Scope)
+
+	require.Nil(t, parseRules(context.Background(), `not-json`))
+}
+
 func newProxyRequestForTest() *httputil.ProxyRequest {
 	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
 	ctxhelper.PutLogger(ctx, logrusx.New())
@@ -92,8 +221,59 @@ func newProxyRequestForTest() *httputil.ProxyRequest {
 	}
 }
 
+func newRuleProxyRequestForTest(rules string) *httputil.ProxyRequest {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	ctxhelper.PutLogger(ctx, logrusx.New())
+	ctxhelper.PutClientId(ctx, "client-1")
+	ctxhelper.PutClientToken(ctx, &clienttokenpb.ClientToken{Id: "token-1"})
+	ctxhelper.PutModel(ctx, &modelpb.Model{Id: "model-1", Name: "gpt-4o"})
+	var settings []*settingpb.Setting
+	if rules != "" {
+		settings = append(settings, &settingpb.Setting{Namespace: rateLimitSettingNamespace, Key: settingKeyRules, Value: rules})
+	}
+	ctxhelper.PutCacheManager(ctx, &mockSettingCacheManager{settings: settings})
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/v1/chat/completions", nil).WithContext(ctx)
+	outReq := req.Clone(ctx)
+
+	return &httputil.ProxyRequest{
+		In:  req,
+		Out: outReq,
+	}
+}
+
 func resetTokenLimiterForTest() {
 	tokenLimiter = &TokenLimiter{
 		limiter: make(map[string]*rate.Limiter),
 	}
 }
+
+func useStoreForTest(t *testing.T, store state_store.LBStateStore) {
+	previous := getStore
+	getStore = func() state_store.LBStateStore { return store }
+	t.Cleanup(func() { getStore = previous })
+}
+
+func useNowForTest(t *testing.T, now time.Time) {
+	previous := nowFunc
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = previous })
+}
+
+type mockSettingCacheManager struct {
+	settings []*settingpb.Setting
+}
+
+func (m *mockSettingCacheManager) ListAll(ctx context.Context, itemType cachetypes.ItemType) (uint64, any, error) {
+	if itemType != cachetypes.ItemTypeSetting {
+		return 0, nil, fmt.Errorf("unsupported item type: %v", itemType)
+	}
+	return uint64(len(m.settings)), m.settings, nil
+}
+
+func (m *mockSettingCacheManager) GetByID(ctx context.Context, itemType cachetypes.ItemType, id string) (any, error) {
+	return nil, fmt.Errorf("unsupported")
+}
+
+func (m *mockSettingCacheManager) TriggerRefresh(ctx context.Context, itemTypes ...cachetypes.ItemType) {
+}
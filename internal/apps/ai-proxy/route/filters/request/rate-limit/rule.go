@@ -0,0 +1,131 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rate_limit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type Scope string
+
+const (
+	// ScopeClient counts all requests of a client together.
+	ScopeClient Scope = "client"
+	// ScopeClientToken counts requests of each client token separately.
+	ScopeClientToken Scope = "client_token"
+	// ScopeModel counts requests to a model name across all clients.
+	ScopeModel Scope = "model"
+)
+
+const matchAll = "*"
+
+// Rule limits requests (RPM) and prompt+completion tokens (TPM) per minute.
+//
+// ClientID, ClientTokenID and Model narrow down which requests the rule applies to,
+// empty or "*" matches everything. When Model is set on a client or client_token rule,
+// each model gets its own counter under that client/token.
+type Rule struct {
+	Scope         Scope  `json:"scope"`
+	ClientID      string `json:"client_id,omitempty"`
+	ClientTokenID string `json:"client_token_id,omitempty"`
+	Model         string `json:"model,omitempty"`
+
+	RPM int64 `json:"rpm,omitempty"`
+	TPM int64 `json:"tpm,omitempty"`
+}
+
+// Subject is the identity of a request that rules are matched against.
+type Subject struct {
+	ClientID      string
+	ClientTokenID string
+	Model         string
+}
+
+func (r *Rule) Validate() error {
+	switch r.Scope {
+	case ScopeClient, ScopeClientToken, ScopeModel:
+	default:
+		return fmt.Errorf("invalid scope: %q", r.Scope)
+	}
+	if r.RPM < 0 || r.TPM < 0 {
+		return fmt.Errorf("rpm and tpm must not be negative")
+	}
+	if r.RPM == 0 && r.TPM == 0 {
+		return fmt.Errorf("at least one of rpm and tpm is required")
+	}
+	return nil
+}
+
+func (r *Rule) Match(s Subject) bool {
+	return matchField(r.ClientID, s.ClientID) &&
+		matchField(r.ClientTokenID, s.ClientTokenID) &&
+		matchField(r.Model, s.Model)
+}
+
+// CounterSubject returns the counter identity of the subject under this rule.
+// Returns false if the subject doesn't carry the identity required by the scope,
+// e.g. a client_token rule for a request authorized by client ak.
+func (r *Rule) CounterSubject(s Subject) (string, bool) {
+	var parts []string
+	switch r.Scope {
+	case ScopeClient:
+		if s.ClientID == "" {
+			return "", false
+		}
+		parts = append(parts, "client:"+s.ClientID)
+	case ScopeClientToken:
+		if s.ClientTokenID == "" {
+			return "", false
+		}
+		parts = append(parts, "client_token:"+s.ClientTokenID)
+	case ScopeModel:
+		if s.Model == "" {
+			return "", false
+		}
+		return "model:" + s.Model, true
+	default:
+		return "", false
+	}
+	if !isMatchAll(r.Model) {
+		if s.Model == "" {
+			return "", false
+		}
+		parts = append(parts, "model:"+s.Model)
+	}
+	return strings.Join(parts, "|"), true
+}
+
+// ID identifies the rule in counter keys, so that rules with different matchers never share counters.
+func (r *Rule) ID() string {
+	b, _ := json.Marshal(r)
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])[:12]
+}
+
+func matchField(want, got string) bool {
+	if isMatchAll(want) {
+		return true
+	}
+	return want == got
+}
+
+func isMatchAll(v string) bool {
+	v = strings.TrimSpace(v)
+	return v == "" || v == matchAll
+}
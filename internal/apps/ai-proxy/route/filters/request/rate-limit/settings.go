@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rate_limit
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	settingpb "github.com/erda-project/erda-proto-go/apps/aiproxy/setting/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/cache/cachetypes"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+)
+
+const (
+	rateLimitSettingNamespace = "rate_limit"
+	// settingKeyRules holds a JSON array of Rule.
+	settingKeyRules = "rules"
+	// settingKeyDefaultTokenLimit holds a JSON TokenLimit, the limit per client token used when no rule matches.
+	settingKeyDefaultTokenLimit = "default_token_limit"
+)
+
+// TokenLimit is the default limit per client token in each replica, a token bucket refilled by RPS up to Burst.
+type TokenLimit struct {
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+}
+
+var defaultTokenLimit = TokenLimit{RPS: 1, Burst: 2}
+
+func resolveRules(ctx context.Context) []Rule {
+	return buildRulesFromList(ctx, listSettings(ctx, settingKeyRules))
+}
+
+// resolveTokenLimit returns the default token limit set in settings, or defaultTokenLimit if not set or invalid.
+func resolveTokenLimit(ctx context.Context) TokenLimit {
+	for _, item := range listSettings(ctx, settingKeyDefaultTokenLimit) {
+		if item == nil || item.Namespace != rateLimitSettingNamespace || item.Key != settingKeyDefaultTokenLimit {
+			continue
+		}
+		var limit TokenLimit
+		if err := json.Unmarshal([]byte(item.Value), &limit); err != nil || limit.RPS <= 0 || limit.Burst <= 0 {
+			if l, ok := ctxhelper.GetLogger(ctx); ok {
+				l.Warnf("invalid default token limit setting %q, ignored", item.Value)
+			}
+			continue
+		}
+		return limit
+	}
+	return defaultTokenLimit
+}
+
+// listSettings returns all cached settings, or the setting of key in rate limit namespace from db if no cache.
+func listSettings(ctx context.Context, key string) []*settingpb.Setting {
+	if cache, ok := ctxhelper.GetCacheManager(ctx); ok && cache != nil {
+		if manager, ok := cache.(cachetypes.Manager); ok && manager != nil {
+			if _, settingsV, err := manager.ListAll(ctx, cachetypes.ItemTypeSetting); err == nil {
+				if list, ok := settingsV.([]*settingpb.Setting); ok {
+					return list
+				}
+			}
+		}
+	}
+
+	dbClient, ok := ctxhelper.GetDBClient(ctx)
+	if !ok || dbClient == nil {
+		return nil
+	}
+	item, err := dbClient.SettingClient().GetByNamespaceKey(ctx, rateLimitSettingNamespace, key)
+	if err != nil {
+		return nil
+	}
+	return []*settingpb.Setting{item.ToProtobuf()}
+}
+
+func buildRulesFromList(ctx context.Context, items []*settingpb.Setting) []Rule {
+	var rules []Rule
+	for _, item := range items {
+		if item == nil || item.Namespace != rateLimitSettingNamespace || item.Key != settingKeyRules {
+			continue
+		}
+		rules = append(rules, parseRules(ctx, item.Value)...)
+	}
+	return rules
+}
+
+func parseRules(ctx context.Context, value string) []Rule {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	var rules []Rule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		if l, ok := ctxhelper.GetLogger(ctx); ok {
+			l.Warnf("invalid rate limit rules setting, ignored: %v", err)
+		}
+		return nil
+	}
+	valid := rules[:0]
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			if l, ok := ctxhelper.GetLogger(ctx); ok {
+				l.Warnf("invalid rate limit rule %+v, ignored: %v", rule, err)
+			}
+			continue
+		}
+		valid = append(valid, rule)
+	}
+	return valid
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rate_limit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/lb/state_store"
+)
+
+const (
+	window = time.Minute
+	// counterTTL outlives the window, so late token usage of long streaming calls still lands in its window.
+	counterTTL = 2 * window
+)
+
+type limitKind string
+
+const (
+	kindRequests limitKind = "requests"
+	kindTokens   limitKind = "tokens"
+)
+
+// response headers, compatible with OpenAI.
+const (
+	headerLimitRequests     = "X-Ratelimit-Limit-Requests"
+	headerRemainingRequests = "X-Ratelimit-Remaining-Requests"
+	headerResetRequests     = "X-Ratelimit-Reset-Requests"
+	headerLimitTokens       = "X-Ratelimit-Limit-Tokens"
+	headerRemainingTokens   = "X-Ratelimit-Remaining-Tokens"
+	headerResetTokens       = "X-Ratelimit-Reset-Tokens"
+	headerRetryAfter        = "Retry-After"
+)
+
+type counter struct {
+	key     string
+	subject string
+}
+
+type usage struct {
+	kind    limitKind
+	counter counter
+	limit   int64
+	used    int64
+}
+
+func (u *usage) remaining() int64 {
+	if u.used >= u.limit {
+		return 0
+	}
+	return u.limit - u.used
+}
+
+type checkResult struct {
+	reset time.Duration
+
+	// tightest limits, used for response headers
+	requests *usage
+	tokens   *usage
+
+	exceeded      *usage
+	tokenCounters []counter
+}
+
+// check evaluates all matched rules in a fixed one-minute window.
+// Every matched RPM counter is increased even if the request is finally rejected by another rule,
+// which is the usual behaviour of fixed-window limiters and keeps the check to one round-trip per counter.
+func check(ctx context.Context, store state_store.LBStateStore, rules []Rule, subject Subject, now time.Time) (*checkResult, error) {
+	windowStart := now.Truncate(window)
+	result := &checkResult{reset: windowStart.Add(window).Sub(now)}
+
+	for _, rule := range rules {
+		counterSubject, ok := rule.CounterSubject(subject)
+		if !ok {
+			continue
+		}
+		if rule.RPM > 0 {
+			c := newCounter(rule, counterSubject, kindRequests, windowStart)
+			used, err := store.IncrCounterBy(ctx, c.key, 1, counterTTL)
+			if err != nil {
+				return nil, err
+			}
+			u := &usage{kind: kindRequests, counter: c, limit: rule.RPM, used: used}
+			result.requests = tighter(result.requests, u)
+			if used > rule.RPM && result.exceeded == nil {
+				result.exceeded = u
+			}
+		}
+		if rule.TPM > 0 {
+			c := newCounter(rule, counterSubject, kindTokens, windowStart)
+			used, err := store.GetCounter(ctx, c.key)
+			if err != nil {
+				return nil, err
+			}
+			u := &usage{kind: kindTokens, counter: c, limit: rule.TPM, used: used}
+			result.tokens = tighter(result.tokens, u)
+			if used >= rule.TPM && result.exceeded == nil {
+				result.exceeded = u
+			}
+			result.tokenCounters = append(result.tokenCounters, c)
+		}
+	}
+	return result, nil
+}
+
+func newCounter(rule Rule, counterSubject string, kind limitKind, windowStart time.Time) counter {
+	return counter{
+		key:     fmt.Sprintf("rate-limit:%s:%s:%s:%d", rule.ID(), counterSubject, kind, windowStart.Unix()),
+		subject: counterSubject,
+	}
+}
+
+func tighter(a, b *usage) *usage {
+	if a == nil || b.remaining() < a.remaining() {
+		return b
+	}
+	return a
+}
+
+func (r *checkResult) headers() http.Header {
+	h := make(http.Header)
+	reset := formatReset(r.reset)
+	if r.requests != nil {
+		h.Set(headerLimitRequests, strconv.FormatInt(r.requests.limit, 10))
+		h.Set(headerRemainingRequests, strconv.FormatInt(r.requests.remaining(), 10))
+		h.Set(headerResetRequests, reset)
+	}
+	if r.tokens != nil {
+		h.Set(headerLimitTokens, strconv.FormatInt(r.tokens.limit, 10))
+		h.Set(headerRemainingTokens, strconv.FormatInt(r.tokens.remaining(), 10))
+		h.Set(headerResetTokens, reset)
+	}
+	if r.exceeded != nil {
+		h.Set(headerRetryAfter, strconv.Itoa(int(math.Ceil(r.reset.Seconds()))))
+	}
+	return h
+}
+
+func formatReset(d time.Duration) string {
+	return (time.Duration(math.Ceil(d.Seconds())) * time.Second).String()
+}
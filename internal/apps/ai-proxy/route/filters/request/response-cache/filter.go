@@ -0,0 +1,244 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package response_cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	metadatapb "github.com/erda-project/erda-proto-go/apps/aiproxy/metadata/pb"
+	usagepb "github.com/erda-project/erda-proto-go/apps/aiproxy/usage/token/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/audithelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/usage/token_usage"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/response-cache/cacheutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/session-memory/memoryutil"
+	prompt_template "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/prompt-template"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/lb/state_store"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/transports"
+)
+
+const Name = "response-cache"
+
+var (
+	_ filter_define.ProxyRequestRewriter = (*Filter)(nil)
+)
+
+var (
+	// nowFunc is a var for testing.
+	nowFunc = time.Now
+)
+
+func init() {
+	filter_define.RegisterFilterCreator(Name, Creator)
+	token_usage.RegisterCollectedHook(noteTokenUsage)
+}
+
+// Filter serves responses from cache for clients opted in via setting namespace `response_cache`.
+//
+// It must be the last request filter: a hit then has the same context (model, provider, splitter) as a real call,
+// and the cached raw upstream body goes through the response filters as usual, including token usage collection.
+// Responses are stored by the `response-cache` response filter, which must be the first response filter.
+type Filter struct{}
+
+var Creator filter_define.RequestRewriterCreator = func(_ string, _ json.RawMessage) filter_define.ProxyRequestRewriter {
+	return &Filter{}
+}
+
+func (f *Filter) OnProxyRequest(pr *httputil.ProxyRequest) error {
+	ctx := pr.In.Context()
+	l := ctxhelper.MustGetLogger(ctx)
+	if trusted, ok := ctxhelper.GetTrustedHealthProbe(ctx); ok && trusted {
+		return nil
+	}
+	clientID, ok := ctxhelper.GetClientId(ctx)
+	if !ok || clientID == "" {
+		return nil
+	}
+	model, ok := ctxhelper.GetModel(ctx)
+	if !ok || model == nil {
+		return nil
+	}
+	policy, ok := cacheutil.ResolvePolicy(ctx, clientID)
+	if !ok {
+		return nil
+	}
+	ttl, ok := policy.TTLFor(model.Name)
+	if !ok {
+		return nil
+	}
+	// the history added by session memory changes every turn, the same request never gets the same answer
+	if session, ok := ctxhelper.GetSession(ctx); ok && session != nil && !session.IsArchived && memoryutil.IsValidStrategy(session.ContextStrategy) {
+		return nil
+	}
+	scope := cacheutil.Scope{ClientID: clientID, ModelID: model.Id, Path: pr.In.URL.Path}
+	// the referenced prompt is rendered into the request by the prompt-template filter, key on its version
+	if selection, ok := prompt_template.GetSelection(ctx); ok {
+		if selection.Version == 0 {
+			// prompts without any version are changed in place
+			return nil
+		}
+		scope.Prompt = fmt.Sprintf("%s@%d", selection.Name, selection.Version)
+	}
+	bodyV, _ := ctxhelper.GetReverseProxyRequestBodyBytes(ctx)
+	bodyBytes, _ := bodyV.([]byte)
+	body, err := cacheutil.ParseRequestBody(bodyBytes)
+	if err != nil {
+		l.Debugf("response cache skipped: %v", err)
+		return nil
+	}
+
+	noCache, noStore := parseCacheControl(pr.In.Header.Get("Cache-Control"))
+	state := &cacheutil.State{
+		Key:     cacheutil.ExactKey(scope, body),
+		TTL:     ttl,
+		NoStore: noStore,
+	}
+	defer cacheutil.PutState(ctx, state)
+
+	store := cacheutil.GetStore()
+	if !noCache {
+		entry, ok, err := store.Get(ctx, state.Key)
+		if err != nil {
+			state_store.FailOpen(l, err, "failed to get cached response")
+		} else if ok {
+			state.HitType = cacheutil.HitTypeExact
+			replay(pr, entry)
+			audithelper.Note(ctx, "response_cache.hit", string(state.HitType))
+			return nil
+		}
+	}
+
+	if policy.Semantic == nil {
+		return nil
+	}
+	if entry, ok := lookupSemantic(pr, policy.Semantic, scope, body, state, noCache, store); ok {
+		replay(pr, entry)
+		audithelper.Note(ctx, "response_cache.hit", string(state.HitType))
+		audithelper.Note(ctx, "response_cache.similarity", state.Similarity)
+	}
+	return nil
+}
+
+// lookupSemantic embeds the prompt and looks for a cached answer of a similar prompt.
+// The vector is kept in state either way, so a missed response can be indexed once stored.
+func lookupSemantic(pr *httputil.ProxyRequest, cfg *cacheutil.SemanticConfig, scope cacheutil.Scope, body cacheutil.RequestBody,
+	state *cacheutil.State, noCache bool, store cacheutil.Store) (*cacheutil.Entry, bool) {
+	ctx := pr.In.Context()
+	l := ctxhelper.MustGetLogger(ctx)
+
+	text := cacheutil.PromptText(body)
+	if text == "" {
+		return nil, false
+	}
+	embedder, ok := cacheutil.GetEmbedder()
+	if !ok {
+		return nil, false
+	}
+	vector, err := embedder.Embed(ctx, pr.In.Header, cfg.EmbeddingModel, text)
+	if err != nil {
+		l.Warnf("failed to embed prompt for semantic response cache, skipped: %v", err)
+		return nil, false
+	}
+	state.SemanticScope = cacheutil.SemanticScope(scope, body)
+	state.Vector = vector
+	state.SemanticMaxEntries = cfg.MaxEntries
+	if noCache {
+		return nil, false
+	}
+
+	index := cacheutil.GetSemanticIndex()
+	key, similarity, ok, err := index.Lookup(ctx, state.SemanticScope, vector, cfg.Threshold, nowFunc())
+	if err != nil {
+		state_store.FailOpen(l, err, "failed to lookup semantic response cache index")
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	entry, ok, err := store.Get(ctx, key)
+	if err != nil {
+		state_store.FailOpen(l, err, "failed to get cached response")
+		return nil, false
+	}
+	if !ok {
+		if err := index.Remove(ctx, state.SemanticScope, key); err != nil {
+			l.Warnf("failed to remove stale semantic response cache index entry: %v", err)
+		}
+		return nil, false
+	}
+	state.HitType = cacheutil.HitTypeSemantic
+	state.Similarity = similarity
+	return entry, true
+}
+
+// replay short-circuits the upstream call with the cached raw upstream response.
+// Streaming bodies are stored as received, the chunk splitter splits them into the same SSE events again.
+func replay(pr *httputil.ProxyRequest, entry *cacheutil.Entry) {
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{entry.ContentType}, "Content-Length": []string{fmt.Sprintf("%d", len(entry.Body))}},
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       pr.Out,
+	}
+	transports.TriggerRequestFilterGeneratedResponse(pr.Out, resp)
+}
+
+// parseCacheControl supports the request directives:
+//   - no-cache: don't serve from cache, the fresh response is still stored
+//   - no-store: don't store the response
+func parseCacheControl(v string) (noCache, noStore bool) {
+	for _, directive := range strings.Split(v, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache":
+			noCache = true
+		case "no-store":
+			noStore = true
+		}
+	}
+	return
+}
+
+// noteTokenUsage marks token usage records of calls with response cache enabled.
+// Tokens of a hit are still recorded as they were in the cached response, but nothing is paid to the provider.
+func noteTokenUsage(ctx context.Context, usage *usagepb.TokenUsageCreateRequest) {
+	state, ok := cacheutil.GetState(ctx)
+	if !ok {
+		return
+	}
+	if usage.Metadata == nil {
+		usage.Metadata = &metadatapb.Metadata{}
+	}
+	if usage.Metadata.Public == nil {
+		usage.Metadata.Public = make(map[string]*structpb.Value)
+	}
+	if !state.Hit() {
+		usage.Metadata.Public["response_cache"] = structpb.NewStringValue("miss")
+		return
+	}
+	usage.Metadata.Public["response_cache"] = structpb.NewStringValue("hit")
+	usage.Metadata.Public["response_cache_hit_type"] = structpb.NewStringValue(string(state.HitType))
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package response_cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda-infra/base/logs/logrusx"
+	modelpb "github.com/erda-project/erda-proto-go/apps/aiproxy/model/pb"
+	sessionpb "github.com/erda-project/erda-proto-go/apps/aiproxy/session/pb"
+	settingpb "github.com/erda-project/erda-proto-go/apps/aiproxy/setting/pb"
+	usagepb "github.com/erda-project/erda-proto-go/apps/aiproxy/usage/token/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/cache/cachetypes"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/response-cache/cacheutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/session-memory/memoryutil"
+	prompt_template "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/prompt-template"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/transports"
+)
+
+const chatBody = `{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hi"}]}`
+
+func TestFilter_NoPolicyNoCache(t *testing.T) {
+	useStoreForTest(t)
+
+	pr := newProxyRequestForTest("", chatBody)
+	require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+	_, ok := cacheutil.GetState(pr.In.Context())
+	require.False(t, ok)
+	require.NotEqual(t, transports.SchemeForFilterGeneratedResponse, pr.Out.URL.Scheme)
+}
+
+func TestFilter_ExactHit(t *testing.T) {
+	store := useStoreForTest(t)
+	policies := `[{"client_id":"client-1","ttl":"10m"}]`
+
+	// miss
+	pr := newProxyRequestForTest(policies, chatBody)
+	require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+	state, ok := cacheutil.GetState(pr.In.Context())
+	require.True(t, ok)
+	require.False(t, state.Hit())
+	require.Equal(t, 10*time.Minute, state.TTL)
+	hit, ok := ctxhelper.GetResponseCacheHit(pr.In.Context())
+	require.True(t, ok)
+	require.False(t, hit)
+
+	// stored by the response filter
+	require.NoError(t, store.Set(context.Background(), state.Key, &cacheutil.Entry{ContentType: "application/json", Body: []byte(`{"id":"1"}`)}, state.TTL))
+
+	// hit, regardless of user field
+	pr = newProxyRequestForTest(policies, `{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hi"}],"user":"someone"}`)
+	require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+	state, ok = cacheutil.GetState(pr.In.Context())
+	require.True(t, ok)
+	require.Equal(t, cacheutil.HitTypeExact, state.HitType)
+	require.Equal(t, transports.SchemeForFilterGeneratedResponse, pr.Out.URL.Scheme)
+	resp, ok := ctxhelper.GetRequestFilterGeneratedResponse(pr.Out.Context())
+	require.True(t, ok)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	// no-cache skips lookup but still stores
+	pr = newProxyRequestForTest(policies, chatBody)
+	pr.In.Header.Set("Cache-Control", "no-cache")
+	require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+	state, ok = cacheutil.GetState(pr.In.Context())
+	require.True(t, ok)
+	require.False(t, state.Hit())
+	require.False(t, state.NoStore)
+}
+
+func TestFilter_ModelTTLDisabled(t *testing.T) {
+	useStoreForTest(t)
+
+	pr := newProxyRequestForTest(`[{"client_id":"*","model_ttls":{"gpt-4o":"0"}}]`, chatBody)
+	require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+	_, ok := cacheutil.GetState(pr.In.Context())
+	require.False(t, ok)
+}
+
+func TestFilter_SemanticHit(t *testing.T) {
+	store := useStoreForTest(t)
+	embedder := &fakeEmbedder{vectors: map[string][]float64{
+		"user: hi":    {1, 0},
+		"user: hello": {0.99, 0.05},
+		"user: bye":   {0, 1},
+	}}
+	cacheutil.SetEmbedder(embedder)
+	t.Cleanup(func() { cacheutil.SetEmbedder(nil) })
+	policies := `[{"client_id":"client-1","semantic":{"embedding_model":"text-embedding-3-small","threshold":0.9}}]`
+
+	pr := newProxyRequestForTest(policies, chatBody)
+	require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+	state, ok := cacheutil.GetState(pr.In.Context())
+	require.True(t, ok)
+	require.False(t, state.Hit())
+	require.Equal(t, []float64{1, 0}, state.Vector)
+	require.Equal(t, "Bearer sk-test", embedder.lastAuth)
+
+	// what the response filter does after storing
+	require.NoError(t, store.Set(context.Background(), state.Key, &cacheutil.Entry{ContentType: "application/json", Body: []byte(`{}`)}, state.TTL))
+	require.NoError(t, cacheutil.GetSemanticIndex().Add(context.Background(), state.SemanticScope, state.Vector, state.Key, time.Now().Add(state.TTL), state.SemanticMaxEntries, time.Now()))
+
+	pr = newProxyRequestForTest(policies, `{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"hello"}]}`)
+	require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+	state, ok = cacheutil.GetState(pr.In.Context())
+	require.True(t, ok)
+	require.Equal(t, cacheutil.HitTypeSemantic, state.HitType)
+	require.Greater(t, state.Similarity, 0.9)
+
+	pr = newProxyRequestForTest(policies, `{"model":"gpt-4o","temperature":0,"messages":[{"role":"user","content":"bye"}]}`)
+	require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+	state, ok = cacheutil.GetState(pr.In.Context())
+	require.True(t, ok)
+	require.False(t, state.Hit())
+}
+
+func TestFilter_SessionMemorySkipped(t *testing.T) {
+	useStoreForTest(t)
+
+	pr := newProxyRequestForTest(`[{"client_id":"client-1","ttl":"10m"}]`, chatBody)
+	ctxhelper.PutSession(pr.In.Context(), &sessionpb.Session{Id: "s1", ClientId: "client-1", ContextStrategy: memoryutil.StrategyTruncateOldest})
+	require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+	_, ok := cacheutil.GetState(pr.In.Context())
+	require.False(t, ok, "history of the session is not in the key")
+}
+
+func TestFilter_KeyByPromptVersion(t *testing.T) {
+	useStoreForTest(t)
+	policies := `[{"client_id":"client-1","ttl":"10m"}]`
+
+	keyOf := func(selection *prompt_template.Selection) (string, bool) {
+		pr := newProxyRequestForTest(policies, chatBody)
+		if selection != nil {
+			ctxhelper.PutPromptSelection(pr.In.Context(), selection)
+		}
+		require.NoError(t, (&Filter{}).OnProxyRequest(pr))
+		state, ok := cacheutil.GetState(pr.In.Context())
+		if !ok {
+			return "", false
+		}
+		return state.Key, true
+	}
+	plain, ok := keyOf(nil)
+	require.True(t, ok)
+	v1, ok := keyOf(&prompt_template.Selection{Name: "summary", Version: 1})
+	require.True(t, ok)
+	v2, ok := keyOf(&prompt_template.Selection{Name: "summary", Version: 2})
+	require.True(t, ok)
+	require.NotEqual(t, plain, v1)
+	require.NotEqual(t, v1, v2)
+
+	_, ok = keyOf(&prompt_template.Selection{Name: "summary"})
+	require.False(t, ok, "prompts without version are not cached")
+}
+
+func TestNoteTokenUsage(t *testing.T) {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	usage := &usagepb.TokenUsageCreateRequest{}
+	noteTokenUsage(ctx, usage)
+	require.Nil(t, usage.Metadata, "cache not enabled")
+
+	cacheutil.PutState(ctx, &cacheutil.State{HitType: cacheutil.HitTypeSemantic})
+	noteTokenUsage(ctx, usage)
+	require.Equal(t, "hit", usage.Metadata.Public["response_cache"].GetStringValue())
+	require.Equal(t, "semantic", usage.Metadata.Public["response_cache_hit_type"].GetStringValue())
+}
+
+func TestParseCacheControl(t *testing.T) {
+	noCache, noStore := parseCacheControl("No-Cache, max-age=0, no-store")
+	require.True(t, noCache)
+	require.True(t, noStore)
+	noCache, noStore = parseCacheControl("")
+	require.False(t, noCache)
+	require.False(t, noStore)
+}
+
+func newProxyRequestForTest(policies, body string) *httputil.ProxyRequest {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	ctxhelper.PutLogger(ctx, logrusx.New())
+	ctxhelper.PutClientId(ctx, "client-1")
+	ctxhelper.PutModel(ctx, &modelpb.Model{Id: "model-1", Name: "gpt-4o"})
+	ctxhelper.PutReverseProxyRequestBodyBytes(ctx, []byte(body))
+	var settings []*settingpb.Setting
+	if policies != "" {
+		settings = append(settings, &settingpb.Setting{Namespace: cacheutil.SettingNamespace, Key: cacheutil.SettingKeyPolicies, Value: policies})
+	}
+	ctxhelper.PutCacheManager(ctx, &mockSettingCacheManager{settings: settings})
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/v1/chat/completions", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer sk-test")
+	outReq := req.Clone(ctx)
+
+	return &httputil.ProxyRequest{
+		In:  req,
+		Out: outReq,
+	}
+}
+
+func useStoreForTest(t *testing.T) cacheutil.Store {
+	store := cacheutil.NewMemoryStore(cacheutil.DefaultMaxBytes)
+	cacheutil.SetStore(store)
+	cacheutil.SetSemanticIndex(cacheutil.NewMemorySemanticIndex())
+	t.Cleanup(func() {
+		cacheutil.SetStore(nil)
+		cacheutil.SetSemanticIndex(nil)
+	})
+	return store
+}
+
+type fakeEmbedder struct {
+	vectors  map[string][]float64
+	lastAuth string
+}
+
+func (e *fakeEmbedder) Embed(_ context.Context, header http.Header, _ string, input string) ([]float64, error) {
+	e.lastAuth = header.Get("Authorization")
+	v, ok := e.vectors[input]
+	if !ok {
+		return nil, fmt.Errorf("unexpected input: %s", input)
+	}
+	return v, nil
+}
+
+type mockSettingCacheManager struct {
+	settings []*settingpb.Setting
+}
+
+func (m *mockSettingCacheManager) ListAll(ctx context.Context, itemType cachetypes.ItemType) (uint64, any, error) {
+	if itemType != cachetypes.ItemTypeSetting {
+		return 0, nil, fmt.Errorf("unsupported item type: %v", itemType)
+	}
+	return uint64(len(m.settings)), m.settings, nil
+}
+
+func (m *mockSettingCacheManager) GetByID(ctx context.Context, itemType cachetypes.ItemType, id string) (any, error) {
+	return nil, fmt.Errorf("unsupported")
+}
+
+func (m *mockSettingCacheManager) TriggerRefresh(ctx context.Context, itemTypes ...cachetypes.ItemType) {
+}
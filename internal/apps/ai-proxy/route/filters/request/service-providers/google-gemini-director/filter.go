@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google_gemini_director
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata/api_segment/api_segment_getter"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata/api_segment/api_style"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/body_util"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	custom_http_director "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/custom-http-director"
+	gemini "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/google-gemini-director"
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+	set_resp_body_chunk_splitter "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/set-resp-body-chunk-splitter"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+)
+
+type GoogleGeminiDirector struct {
+	*custom_http_director.CustomHTTPDirector
+}
+
+var (
+	_ filter_define.ProxyRequestRewriter = (*GoogleGeminiDirector)(nil)
+)
+
+var Creator filter_define.RequestRewriterCreator = func(name string, _ json.RawMessage) filter_define.ProxyRequestRewriter {
+	return &GoogleGeminiDirector{CustomHTTPDirector: custom_http_director.New()}
+}
+
+func init() {
+	filter_define.RegisterFilterCreator("google-gemini-director", Creator)
+}
+
+func (f *GoogleGeminiDirector) Enable(pr *httputil.ProxyRequest) bool {
+	apiSegment := api_segment_getter.GetAPISegment(pr.In.Context())
+	return apiSegment != nil &&
+		strings.EqualFold(string(apiSegment.APIStyle), string(api_style.APIStyleGoogleGemini))
+}
+
+func (f *GoogleGeminiDirector) OnProxyRequest(pr *httputil.ProxyRequest) error {
+	if !f.Enable(pr) {
+		return nil
+	}
+	// host, path and api key are rendered by apiStyleConfig
+	if err := f.CustomHTTPDirector.OnProxyRequest(pr); err != nil {
+		return fmt.Errorf("gemini custom-http-director error: %w", err)
+	}
+
+	pathMatcher := ctxhelper.MustGetPathMatcher(pr.In.Context())
+	if !pathMatcher.Match(vars.RequestPathPrefixV1ChatCompletions) {
+		return fmt.Errorf("unsupported path for gemini: %s", pathMatcher.Pattern)
+	}
+	var openaiReq openai_chat.Request
+	if err := json.NewDecoder(pr.Out.Body).Decode(&openaiReq); err != nil {
+		return fmt.Errorf("failed to decode request body as openai format, err: %v", err)
+	}
+	geminiReq, err := gemini.ConvertRequest(pr.Out.Context(), openaiReq)
+	if err != nil {
+		return fmt.Errorf("failed to convert request to gemini format, err: %v", err)
+	}
+	if err := body_util.SetBody(pr.Out, geminiReq); err != nil {
+		return fmt.Errorf("failed to set gemini request body: %v", err)
+	}
+
+	if ctxhelper.MustGetIsStream(pr.Out.Context()) {
+		pr.Out.URL.Path = strings.Replace(pr.Out.URL.Path, ":generateContent", ":streamGenerateContent", 1)
+		query := pr.Out.URL.Query()
+		query.Set("alt", "sse")
+		pr.Out.URL.RawQuery = query.Encode()
+		// events are separated by "\r\n\r\n", which SSESplitter doesn't recognize
+		ctxhelper.PutRespBodyChunkSplitter(pr.Out.Context(), &set_resp_body_chunk_splitter.NewLineSplitter{})
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ollama_director
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata/api_segment/api_segment_getter"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata/api_segment/api_style"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/body_util"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	custom_http_director "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/custom-http-director"
+	ollama "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/ollama-director"
+	set_resp_body_chunk_splitter "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/set-resp-body-chunk-splitter"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+)
+
+type OllamaDirector struct {
+	*custom_http_director.CustomHTTPDirector
+}
+
+var (
+	_ filter_define.ProxyRequestRewriter = (*OllamaDirector)(nil)
+)
+
+var Creator filter_define.RequestRewriterCreator = func(name string, _ json.RawMessage) filter_define.ProxyRequestRewriter {
+	return &OllamaDirector{CustomHTTPDirector: custom_http_director.New()}
+}
+
+func init() {
+	filter_define.RegisterFilterCreator("ollama-director", Creator)
+}
+
+func (f *OllamaDirector) Enable(pr *httputil.ProxyRequest) bool {
+	apiSegment := api_segment_getter.GetAPISegment(pr.In.Context())
+	return apiSegment != nil &&
+		strings.EqualFold(string(apiSegment.APIStyle), string(api_style.APIStyleOllama))
+}
+
+func (f *OllamaDirector) OnProxyRequest(pr *httputil.ProxyRequest) error {
+	if !f.Enable(pr) {
+		return nil
+	}
+	// host and path are rendered by apiStyleConfig
+	if err := f.CustomHTTPDirector.OnProxyRequest(pr); err != nil {
+		return fmt.Errorf("ollama custom-http-director error: %w", err)
+	}
+
+	model := ctxhelper.MustGetModel(pr.Out.Context())
+	modelName := model.Metadata.Public["model_name"].GetStringValue()
+	if modelName == "" {
+		modelName = model.Name
+	}
+	pathMatcher := ctxhelper.MustGetPathMatcher(pr.In.Context())
+	switch {
+	case pathMatcher.Match(vars.RequestPathPrefixV1ChatCompletions):
+		var openaiReq ollama.OpenAIChatRequest
+		if err := json.NewDecoder(pr.Out.Body).Decode(&openaiReq); err != nil {
+			return fmt.Errorf("failed to decode request body as openai format, err: %v", err)
+		}
+		ollamaReq, err := ollama.ConvertChatRequest(pr.Out.Context(), openaiReq, modelName)
+		if err != nil {
+			return fmt.Errorf("failed to convert request to ollama format, err: %v", err)
+		}
+		if err := body_util.SetBody(pr.Out, ollamaReq); err != nil {
+			return fmt.Errorf("failed to set ollama request body: %v", err)
+		}
+		if ctxhelper.MustGetIsStream(pr.Out.Context()) {
+			// the stream is ndjson
+			ctxhelper.PutRespBodyChunkSplitter(pr.Out.Context(), &set_resp_body_chunk_splitter.NewLineSplitter{})
+		}
+	case pathMatcher.Match(vars.RequestPathPrefixV1Embeddings):
+		body, err := io.ReadAll(pr.Out.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %v", err)
+		}
+		ollamaReq, err := ollama.ConvertEmbeddingRequest(body, modelName)
+		if err != nil {
+			return fmt.Errorf("failed to convert request to ollama format, err: %v", err)
+		}
+		if err := body_util.SetBody(pr.Out, ollamaReq); err != nil {
+			return fmt.Errorf("failed to set ollama request body: %v", err)
+		}
+	default:
+		return fmt.Errorf("unsupported path for ollama: %s", pathMatcher.Pattern)
+	}
+	return nil
+}
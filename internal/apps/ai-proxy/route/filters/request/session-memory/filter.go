@@ -0,0 +1,273 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session_memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+
+	modelpb "github.com/erda-project/erda-proto-go/apps/aiproxy/model/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/audithelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/usage/token_usage/estimators/impl"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/body_util"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	openai_chat "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/openai-chat"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/session-memory/memoryutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	"github.com/erda-project/erda/pkg/common/pbutil"
+	httperrorutil "github.com/erda-project/erda/pkg/http/httputil"
+)
+
+const (
+	Name = "session-memory"
+
+	defaultContextTokens   = 8192
+	defaultMaxHistoryTurns = 100
+)
+
+var (
+	_ filter_define.ProxyRequestRewriter = (*Filter)(nil)
+)
+
+func init() {
+	filter_define.RegisterFilterCreator(Name, Creator)
+}
+
+// Filter adds the stored history of the session to the chat request, for sessions with a context strategy.
+// When the context window of the model would be exceeded, the strategy of the session decides which turns are sent.
+//
+// Messages are sent in order: leading system messages of request, summary, history, other requested messages.
+type Filter struct {
+	// DefaultContextTokens is used for models without `context` in public metadata.
+	DefaultContextTokens int `json:"defaultContextTokens" yaml:"defaultContextTokens"`
+	// MaxHistoryTurns limits the turns loaded from the session.
+	MaxHistoryTurns int `json:"maxHistoryTurns" yaml:"maxHistoryTurns"`
+}
+
+var Creator filter_define.RequestRewriterCreator = func(_ string, config json.RawMessage) filter_define.ProxyRequestRewriter {
+	f := Filter{DefaultContextTokens: defaultContextTokens, MaxHistoryTurns: defaultMaxHistoryTurns}
+	if len(config) > 0 {
+		if err := yaml.Unmarshal(config, &f); err != nil {
+			panic(fmt.Errorf("failed to unmarshal session memory config: %v", err))
+		}
+	}
+	return &f
+}
+
+func (f *Filter) OnProxyRequest(pr *httputil.ProxyRequest) error {
+	ctx := pr.Out.Context()
+	session, ok := ctxhelper.GetSession(ctx)
+	if !ok || session == nil || session.IsArchived || !memoryutil.IsValidStrategy(session.ContextStrategy) {
+		return nil
+	}
+	if clientID, _ := ctxhelper.GetClientId(ctx); clientID != session.ClientId {
+		return http_error.NewHTTPError(ctx, http.StatusForbidden, "Session does not belong to the client")
+	}
+	if !strings.HasPrefix(pr.Out.Header.Get(httperrorutil.HeaderKeyContentType), string(httperrorutil.ApplicationJson)) {
+		return nil
+	}
+	bodyCopy, err := body_util.SmartCloneBody(&pr.Out.Body, body_util.MaxSample)
+	if err != nil {
+		return fmt.Errorf("failed to clone request body: %w", err)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(bodyCopy).Decode(&body); err != nil {
+		return nil
+	}
+	reqMsgs, _ := body["messages"].([]any)
+
+	var (
+		l          = ctxhelper.MustGetLogger(ctx)
+		model      = ctxhelper.MustGetModel(ctx)
+		sessions   = ctxhelper.MustGetDBClient(ctx).SessionClient()
+		count      = tokenCounter(model.Name)
+		summarize  = session.ContextStrategy == memoryutil.StrategySummarize
+		summary    = ""
+		summarizer memoryutil.Summarizer
+	)
+	since := timeOf(session.ResetAt)
+	if summarize {
+		summary = session.Summary
+		if summarizedAt := timeOf(session.SummarizedAt); summarizedAt.After(since) {
+			since = summarizedAt
+		}
+		summarizer, summarize = memoryutil.GetSummarizer()
+	}
+	chatLogs, err := sessions.ListHistory(ctx, session.Id, since, f.MaxHistoryTurns)
+	if err != nil {
+		return fmt.Errorf("failed to list history of session %s: %w", session.Id, err)
+	}
+	history := make([]memoryutil.Turn, 0, len(chatLogs))
+	for _, chatLog := range chatLogs {
+		history = append(history, memoryutil.Turn{Prompt: chatLog.Prompt, Completion: chatLog.Completion, RequestAt: chatLog.RequestAt})
+	}
+
+	fixedTokens := countMessages(reqMsgs, count)
+	if summarize {
+		// room for the summary updated by this request
+		fixedTokens += summarizer.MaxTokens() + memoryutil.MessageOverheadTokens
+	} else if summary != "" {
+		fixedTokens += count(summary) + memoryutil.MessageOverheadTokens
+	}
+	window := memoryutil.Window{
+		Strategy:  session.ContextStrategy,
+		MaxTokens: contextTokens(model, body, f.DefaultContextTokens),
+		KeepLastN: int(session.NumOfCtxMsg),
+	}
+	fitted := window.Fit(history, fixedTokens, count)
+
+	summarized := false
+	if summarize && len(fitted.Evicted) > 0 {
+		newSummary, err := summarizer.Summarize(ctx, pr.In.Header, session.SummaryModel, summary, fitted.Evicted)
+		if err != nil {
+			// evicted turns are dropped like truncate_oldest, and summarized by later requests
+			l.Warnf("failed to summarize session %s, evicted turns are dropped: %v", session.Id, err)
+		} else {
+			summarizedAt := fitted.Evicted[len(fitted.Evicted)-1].RequestAt
+			if err := sessions.SaveSummary(ctx, session.Id, newSummary, summarizedAt); err != nil {
+				l.Warnf("failed to save summary of session %s: %v", session.Id, err)
+			}
+			summary, summarized = newSummary, true
+		}
+	}
+
+	body["messages"] = composeMessages(reqMsgs, summary, fitted.Kept)
+	if err := body_util.SetBody(pr.Out, body); err != nil {
+		return fmt.Errorf("failed to set request body: %w", err)
+	}
+
+	audithelper.Note(ctx, "session.memory.strategy", session.ContextStrategy)
+	audithelper.Note(ctx, "session.memory.history_turns", len(fitted.Kept))
+	audithelper.Note(ctx, "session.memory.evicted_turns", len(fitted.Evicted))
+	if summarized {
+		audithelper.Note(ctx, "session.memory.summarized", true)
+	}
+	return nil
+}
+
+// composeMessages inserts the summary and history after the leading system messages of request.
+func composeMessages(reqMsgs []any, summary string, history []memoryutil.Turn) []any {
+	var leading int
+	for leading < len(reqMsgs) && isSystemMessage(reqMsgs[leading]) {
+		leading++
+	}
+	msgs := make([]any, 0, len(reqMsgs)+2*len(history)+1)
+	msgs = append(msgs, reqMsgs[:leading]...)
+	if summary != "" {
+		msgs = append(msgs, map[string]any{
+			"role":    openai.ChatMessageRoleSystem,
+			"content": "Summary of the earlier conversation:\n" + summary,
+		})
+	}
+	for _, turn := range history {
+		msgs = append(msgs,
+			map[string]any{"role": openai.ChatMessageRoleUser, "content": turn.Prompt},
+			map[string]any{"role": openai.ChatMessageRoleAssistant, "content": turn.Completion},
+		)
+	}
+	return append(msgs, reqMsgs[leading:]...)
+}
+
+func isSystemMessage(msg any) bool {
+	m, _ := msg.(map[string]any)
+	role, _ := m["role"].(string)
+	return role == openai.ChatMessageRoleSystem || role == openai.ChatMessageRoleDeveloper
+}
+
+func countMessages(msgs []any, count memoryutil.TokenCounter) int {
+	var tokens int
+	for _, msg := range msgs {
+		tokens += memoryutil.MessageOverheadTokens
+		b, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		var m openai_chat.Message
+		if err := json.Unmarshal(b, &m); err != nil {
+			continue
+		}
+		text, _ := m.Text()
+		tokens += count(text)
+		for _, toolCall := range m.ToolCalls {
+			tokens += count(toolCall.Function.Name) + count(toolCall.Function.Arguments)
+		}
+	}
+	return tokens
+}
+
+func tokenCounter(model string) memoryutil.TokenCounter {
+	return func(text string) int {
+		if text == "" {
+			return 0
+		}
+		return int(impl.CountTextTokens(model, text))
+	}
+}
+
+// contextTokens returns the tokens of the context window available to the prompt,
+// by `context` of model public metadata, e.g.: {"context_length": 32768, "max_completion_tokens": 8192, "max_prompt_tokens": 30720}.
+func contextTokens(model *modelpb.Model, body map[string]any, fallback int) int {
+	var modelContext map[string]any
+	if model.GetMetadata() != nil {
+		modelContext, _ = model.Metadata.Public["context"].AsInterface().(map[string]any)
+	}
+	var (
+		contextLength       = intOf(modelContext["context_length"])
+		maxPromptTokens     = intOf(modelContext["max_prompt_tokens"])
+		maxCompletionTokens = intOf(modelContext["max_completion_tokens"])
+	)
+	// completion tokens requested are reserved
+	if requested := max(intOf(body["max_completion_tokens"]), intOf(body["max_tokens"])); requested > 0 {
+		maxCompletionTokens = requested
+	}
+	tokens := maxPromptTokens
+	if contextLength > 0 {
+		if available := contextLength - maxCompletionTokens; available > 0 && (tokens <= 0 || available < tokens) {
+			tokens = available
+		}
+	}
+	if tokens <= 0 {
+		return fallback
+	}
+	return tokens
+}
+
+func intOf(v any) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	default:
+		return 0
+	}
+}
+
+func timeOf(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *pbutil.GetTimeInLocal(t)
+}
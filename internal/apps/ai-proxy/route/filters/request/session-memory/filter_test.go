@@ -0,0 +1,93 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session_memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	metadatapb "github.com/erda-project/erda-proto-go/apps/aiproxy/metadata/pb"
+	modelpb "github.com/erda-project/erda-proto-go/apps/aiproxy/model/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/session-memory/memoryutil"
+)
+
+func TestComposeMessages(t *testing.T) {
+	reqMsgs := []any{
+		map[string]any{"role": "system", "content": "be brief"},
+		map[string]any{"role": "user", "content": "and now?"},
+	}
+	msgs := composeMessages(reqMsgs, "user asked about go", []memoryutil.Turn{{Prompt: "hi", Completion: "hello"}})
+	assert.Equal(t, []any{
+		map[string]any{"role": "system", "content": "be brief"},
+		map[string]any{"role": "system", "content": "Summary of the earlier conversation:\nuser asked about go"},
+		map[string]any{"role": "user", "content": "hi"},
+		map[string]any{"role": "assistant", "content": "hello"},
+		map[string]any{"role": "user", "content": "and now?"},
+	}, msgs)
+
+	// nothing to add
+	assert.Equal(t, reqMsgs, composeMessages(reqMsgs, "", nil))
+}
+
+func TestContextTokens(t *testing.T) {
+	modelWithContext := func(context map[string]any) *modelpb.Model {
+		v, _ := structpb.NewValue(context)
+		return &modelpb.Model{Metadata: &metadatapb.Metadata{Public: map[string]*structpb.Value{"context": v}}}
+	}
+	tests := []struct {
+		name  string
+		model *modelpb.Model
+		body  map[string]any
+		want  int
+	}{
+		{
+			name:  "no metadata",
+			model: &modelpb.Model{},
+			want:  100,
+		},
+		{
+			name:  "max prompt tokens",
+			model: modelWithContext(map[string]any{"context_length": 32768, "max_completion_tokens": 8192, "max_prompt_tokens": 20000}),
+			want:  20000,
+		},
+		{
+			name:  "context length minus completion",
+			model: modelWithContext(map[string]any{"context_length": 32768, "max_completion_tokens": 8192}),
+			want:  24576,
+		},
+		{
+			name:  "requested completion tokens are reserved",
+			model: modelWithContext(map[string]any{"context_length": 32768, "max_completion_tokens": 8192, "max_prompt_tokens": 30720}),
+			body:  map[string]any{"max_tokens": float64(16384)},
+			want:  16384,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, contextTokens(tt.model, tt.body, 100))
+		})
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	count := func(text string) int { return len(text) }
+	msgs := []any{
+		map[string]any{"role": "user", "content": "abc"},
+		map[string]any{"role": "user", "content": []any{map[string]any{"type": "text", "text": "de"}}},
+	}
+	assert.Equal(t, 3+2+2*memoryutil.MessageOverheadTokens, countMessages(msgs, count))
+}
@@ -64,6 +64,9 @@ func GetSplitterByResp(resp *http.Response) ChunkSplitter {
 		// SSE / text types
 		case strings.HasPrefix(ct, "text/event-stream"):
 			underlying = &SSESplitter{}
+		// Newline-delimited JSON, e.g., Ollama streaming
+		case strings.HasPrefix(ct, "application/x-ndjson"):
+			underlying = &NewLineSplitter{}
 		default:
 			// Fallback: fixed chunks or whole stream
 			underlying = &FixedSizeSplitter{Size: 32 << 10}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/common_types"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/common_types/common_types_util"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/thinking-handler/encoders"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/thinking-handler/types"
+)
+
+// OllamaThinkingEncoder handles Ollama thinking payloads.
+// Fields: think
+// see: https://github.com/ollama/ollama/blob/main/docs/api.md#generate-a-chat-completion
+//
+// gpt-oss models use effort levels: low, medium, high
+// other models use bool
+type OllamaThinkingEncoder struct{}
+
+func (e *OllamaThinkingEncoder) CanEncode(ctx context.Context) bool {
+	provider := ctxhelper.MustGetServiceProvider(ctx)
+	return strings.EqualFold(common_types_util.GetServiceProviderType(provider), common_types.ServiceProviderTypeOllama.String())
+}
+
+func (e *OllamaThinkingEncoder) Encode(ctx context.Context, ct types.CommonThinking) (map[string]any, error) {
+	switch ct.MustGetMode() {
+	case types.ModeAuto:
+		return nil, nil
+	case types.ModeOff:
+		return map[string]any{types.FieldThink: false}, nil
+	}
+	if ct.Effort != nil && *ct.Effort == types.EffortNone {
+		return map[string]any{types.FieldThink: false}, nil
+	}
+	model := ctxhelper.MustGetModel(ctx)
+	if !strings.Contains(strings.ToLower(model.Name), "gpt-oss") {
+		return map[string]any{types.FieldThink: true}, nil
+	}
+	suitableEffort := types.EffortMedium
+	if ct.Effort != nil {
+		suitableEffort = *ct.Effort
+	} else if ct.BudgetTokens != nil && *ct.BudgetTokens > 0 {
+		suitableEffort = types.MapBudgetTokensToEffort(*ct.BudgetTokens)
+	}
+	return map[string]any{types.FieldThink: normalizeEffortLevelForOllama(suitableEffort)}, nil
+}
+
+func (e *OllamaThinkingEncoder) GetPriority() int {
+	return 0 // ensure provider-specific logic wins over publisher-based defaults
+}
+
+func (e *OllamaThinkingEncoder) GetName() string {
+	return fmt.Sprintf("service_provider: %s", common_types.ServiceProviderTypeOllama.String())
+}
+
+// normalizeEffortLevelForOllama keeps levels supported by gpt-oss: low, medium, high.
+func normalizeEffortLevelForOllama(inputEffort types.CommonThinkingEffort) types.CommonThinkingEffort {
+	switch inputEffort {
+	case types.EffortMedium, types.EffortHigh:
+		return inputEffort
+	default:
+		return types.EffortLow
+	}
+}
+
+var _ encoders.CommonThinkingEncoder = (*OllamaThinkingEncoder)(nil)
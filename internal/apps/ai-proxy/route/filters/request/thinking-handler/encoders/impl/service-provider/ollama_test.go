@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service_provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	modelpb "github.com/erda-project/erda-proto-go/apps/aiproxy/model/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/common_types"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/request/thinking-handler/types"
+)
+
+func TestOllamaThinkingEncoder(t *testing.T) {
+	tests := []struct {
+		name      string
+		modelName string
+		input     types.CommonThinking
+		expected  map[string]any
+	}{
+		{
+			name:      "mode auto",
+			modelName: "qwen3:8b",
+			input:     types.CommonThinking{Mode: types.ModePtr(types.ModeAuto)},
+			expected:  nil,
+		},
+		{
+			name:      "mode off",
+			modelName: "qwen3:8b",
+			input:     types.CommonThinking{Mode: types.ModePtr(types.ModeOff)},
+			expected:  map[string]any{types.FieldThink: false},
+		},
+		{
+			name:      "mode on",
+			modelName: "qwen3:8b",
+			input:     types.CommonThinking{Mode: types.ModePtr(types.ModeOn), Effort: types.EffortPtr(types.EffortHigh)},
+			expected:  map[string]any{types.FieldThink: true},
+		},
+		{
+			name:      "effort none",
+			modelName: "qwen3:8b",
+			input:     types.CommonThinking{Mode: types.ModePtr(types.ModeOn), Effort: types.EffortPtr(types.EffortNone)},
+			expected:  map[string]any{types.FieldThink: false},
+		},
+		{
+			name:      "gpt-oss with effort",
+			modelName: "gpt-oss:20b",
+			input:     types.CommonThinking{Mode: types.ModePtr(types.ModeOn), Effort: types.EffortPtr(types.EffortHigh)},
+			expected:  map[string]any{types.FieldThink: types.EffortHigh},
+		},
+		{
+			name:      "gpt-oss with effort minimal",
+			modelName: "gpt-oss:20b",
+			input:     types.CommonThinking{Mode: types.ModePtr(types.ModeOn), Effort: types.EffortPtr(types.EffortMinimal)},
+			expected:  map[string]any{types.FieldThink: types.EffortLow},
+		},
+		{
+			name:      "gpt-oss with budget tokens",
+			modelName: "gpt-oss:20b",
+			input:     types.CommonThinking{BudgetTokens: func() *int { v := 3000; return &v }()},
+			expected:  map[string]any{types.FieldThink: types.EffortMedium},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+			ctxhelper.PutServiceProvider(ctx, newProviderWithServiceType(common_types.ServiceProviderTypeOllama))
+			ctxhelper.PutModel(ctx, &modelpb.Model{Name: tt.modelName})
+
+			encoder := &OllamaThinkingEncoder{}
+			assert.True(t, encoder.CanEncode(ctx))
+			result, err := encoder.Encode(ctx, tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
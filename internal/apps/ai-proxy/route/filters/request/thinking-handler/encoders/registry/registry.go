@@ -37,6 +37,8 @@ func NewRegistry() *Registry {
 		&sp.VolcengineThinkingEncoder{},
 		&sp.BailianThinkingEncoder{},
 		&sp.GoogleVertexAIThinkingEncoder{},
+		&sp.GoogleGeminiThinkingEncoder{},
+		&sp.OllamaThinkingEncoder{},
 		// model publisher
 		&mp.AnthropicThinkingEncoder{},
 		&mp.QwenThinkingEncoder{},
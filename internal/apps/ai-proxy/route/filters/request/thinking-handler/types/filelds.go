@@ -39,4 +39,7 @@ const (
 	FieldIncludeThoughts  = "include_thoughts"
 	FieldThoughtTagMarker = "thought_tag_marker"
 	FieldThinkingLevel    = "thinking_level"
+
+	// for ollama: https://github.com/ollama/ollama/blob/main/docs/api.md#generate-a-chat-completion
+	FieldThink = "think"
 )
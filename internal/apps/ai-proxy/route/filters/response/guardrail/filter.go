@@ -0,0 +1,315 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guardrail
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/guardrail/guardrailutil"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+	"github.com/erda-project/erda/pkg/desensitize"
+)
+
+const finishReasonContentFilter = "content_filter"
+
+var (
+	_ filter_define.ProxyResponseModifier = (*Filter)(nil)
+)
+
+func init() {
+	filter_define.RegisterFilterCreator("guardrail", ResponseModifierCreator)
+}
+
+// Filter detects PII and secrets in completions for clients opted in via setting namespace `guardrail`.
+//
+// It must run after the service provider director response filters, so the body is in the OpenAI-compatible format.
+// Streamed content is scanned incrementally: the tail of each choice's content is held back
+// until it can't be the beginning of sensitive data, then released masked.
+// Blocking stops the completion with finish_reason `content_filter`, as status and headers are already sent.
+type Filter struct {
+	resolved bool
+	policy   *guardrailutil.Policy
+
+	findings []desensitize.Finding
+
+	// streaming
+	buf       []byte
+	scanners  map[int]*streamScanner
+	blocked   bool
+	lastEvent map[string]json.RawMessage
+	// contents of choices in audit mode, scanned on complete
+	contents map[int]string
+}
+
+var ResponseModifierCreator filter_define.ResponseModifierCreator = func(name string, _ json.RawMessage) filter_define.ProxyResponseModifier {
+	return &Filter{
+		scanners: make(map[int]*streamScanner),
+		contents: make(map[int]string),
+	}
+}
+
+func (f *Filter) Enable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	if !f.resolved {
+		f.resolved = true
+		ctx := resp.Request.Context()
+		if clientID, ok := ctxhelper.GetClientId(ctx); ok && clientID != "" {
+			if policy, ok := guardrailutil.ResolvePolicy(ctx, clientID, guardrailutil.StageResponse); ok {
+				f.policy = policy
+			}
+		}
+	}
+	return f.policy != nil
+}
+
+func (f *Filter) OnHeaders(resp *http.Response) error {
+	return nil
+}
+
+func (f *Filter) OnBodyChunk(resp *http.Response, chunk []byte, index int64) ([]byte, error) {
+	if !ctxhelper.MustGetIsStream(resp.Request.Context()) {
+		// the whole json body comes in one chunk
+		return f.handleJSONBody(chunk), nil
+	}
+	f.buf = append(f.buf, chunk...)
+	var out []byte
+	for {
+		i := bytes.Index(f.buf, []byte("\n\n"))
+		if i < 0 {
+			break
+		}
+		event := f.buf[:i+2]
+		f.buf = f.buf[i+2:]
+		out = append(out, f.handleEvent(event)...)
+	}
+	return out, nil
+}
+
+func (f *Filter) OnComplete(resp *http.Response) ([]byte, error) {
+	ctx := resp.Request.Context()
+	var out []byte
+	if ctxhelper.MustGetIsStream(ctx) {
+		if len(f.buf) > 0 {
+			out = append(out, f.handleEvent(f.buf)...)
+			f.buf = nil
+		}
+		// the stream ended without [DONE]
+		out = append(out, f.flush()...)
+	}
+	if f.policy.Action == guardrailutil.ActionAudit {
+		for _, content := range f.contents {
+			f.findings = append(f.findings, desensitize.Scan(content, f.policy.Kinds...)...)
+		}
+	}
+	guardrailutil.NoteFindings(ctx, guardrailutil.StageResponse, f.policy.Action, f.findings)
+	return out, nil
+}
+
+// handleJSONBody guards `choices[].message.content` and `choices[].text` of a non-streaming completion.
+func (f *Filter) handleJSONBody(body []byte) []byte {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		return body
+	}
+	var choices []map[string]json.RawMessage
+	if err := json.Unmarshal(m["choices"], &choices); err != nil || len(choices) == 0 {
+		return body
+	}
+	modified := false
+	for _, choice := range choices {
+		var message map[string]json.RawMessage
+		if err := json.Unmarshal(choice["message"], &message); err == nil && message != nil {
+			if f.guardField(choice, message, "content") {
+				choice["message"] = mustMarshal(message)
+				modified = true
+			}
+		}
+		if f.guardField(choice, choice, "text") {
+			modified = true
+		}
+	}
+	if !modified {
+		return body
+	}
+	m["choices"] = mustMarshal(choices)
+	return mustMarshal(m)
+}
+
+// guardField guards the string field of obj, the finish_reason of choice is set if blocked.
+// It returns true if anything is changed.
+func (f *Filter) guardField(choice, obj map[string]json.RawMessage, field string) bool {
+	var text string
+	if err := json.Unmarshal(obj[field], &text); err != nil || text == "" {
+		return false
+	}
+	guarded, blocked, changed := f.guard(text)
+	if !changed {
+		return false
+	}
+	obj[field] = mustMarshal(guarded)
+	if blocked {
+		choice["finish_reason"] = mustMarshal(finishReasonContentFilter)
+	}
+	return true
+}
+
+// guard applies the policy action to text, which never ends in the middle of sensitive data.
+func (f *Filter) guard(text string) (guarded string, blocked, changed bool) {
+	switch f.policy.Action {
+	case guardrailutil.ActionMask:
+		masked, found := desensitize.MaskText(text, f.policy.Kinds...)
+		f.findings = append(f.findings, found...)
+		return masked, false, len(found) > 0
+	case guardrailutil.ActionBlock:
+		found := desensitize.Scan(text, f.policy.Kinds...)
+		if len(found) == 0 {
+			return text, false, false
+		}
+		f.findings = append(f.findings, found...)
+		return text[:found[0].Start], true, true
+	default:
+		f.findings = append(f.findings, desensitize.Scan(text, f.policy.Kinds...)...)
+		return text, false, false
+	}
+}
+
+// handleEvent guards `choices[].delta.content` of a chat completion chunk.
+func (f *Filter) handleEvent(event []byte) []byte {
+	data := bytes.TrimSpace(vars.TrimChunkDataPrefix(event))
+	if string(data) == "[DONE]" {
+		return append(f.flush(), event...)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return event
+	}
+	var choices []map[string]json.RawMessage
+	if err := json.Unmarshal(m["choices"], &choices); err != nil || len(choices) == 0 {
+		// e.g. the usage chunk
+		return event
+	}
+	if f.blocked {
+		return nil
+	}
+	f.lastEvent = m
+
+	if f.policy.Action == guardrailutil.ActionAudit {
+		for _, choice := range choices {
+			index, delta, content := parseStreamChoice(choice)
+			if delta != nil {
+				f.contents[index] += content
+			}
+		}
+		return event
+	}
+
+	for _, choice := range choices {
+		index, delta, content := parseStreamChoice(choice)
+		if delta == nil {
+			continue
+		}
+		released := f.scanner(index).Push(content, finished(choice))
+		guarded, blocked, _ := f.guard(released)
+		if _, ok := delta["content"]; ok || guarded != "" {
+			delta["content"] = mustMarshal(guarded)
+		}
+		choice["delta"] = mustMarshal(delta)
+		if blocked {
+			choice["finish_reason"] = mustMarshal(finishReasonContentFilter)
+			f.blocked = true
+			break
+		}
+	}
+	m["choices"] = mustMarshal(choices)
+	return vars.ConcatChunkDataPrefix(mustMarshal(m))
+}
+
+// flush releases content held back, in a chunk built on the last one.
+func (f *Filter) flush() []byte {
+	if f.blocked || f.lastEvent == nil {
+		return nil
+	}
+	indexes := make([]int, 0, len(f.scanners))
+	for index := range f.scanners {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	var choices []map[string]json.RawMessage
+	for _, index := range indexes {
+		released := f.scanners[index].Push("", true)
+		if released == "" {
+			continue
+		}
+		guarded, blocked, _ := f.guard(released)
+		choice := map[string]json.RawMessage{
+			"index":         mustMarshal(index),
+			"delta":         mustMarshal(map[string]string{"content": guarded}),
+			"finish_reason": json.RawMessage("null"),
+		}
+		if blocked {
+			choice["finish_reason"] = mustMarshal(finishReasonContentFilter)
+			f.blocked = true
+		}
+		choices = append(choices, choice)
+		if blocked {
+			break
+		}
+	}
+	if len(choices) == 0 {
+		return nil
+	}
+	m := make(map[string]json.RawMessage, len(f.lastEvent))
+	for k, v := range f.lastEvent {
+		m[k] = v
+	}
+	delete(m, "usage")
+	m["choices"] = mustMarshal(choices)
+	return vars.ConcatChunkDataPrefix(mustMarshal(m))
+}
+
+func (f *Filter) scanner(index int) *streamScanner {
+	s, ok := f.scanners[index]
+	if !ok {
+		s = &streamScanner{kinds: f.policy.Kinds}
+		f.scanners[index] = s
+	}
+	return s
+}
+
+func parseStreamChoice(choice map[string]json.RawMessage) (index int, delta map[string]json.RawMessage, content string) {
+	_ = json.Unmarshal(choice["index"], &index)
+	if err := json.Unmarshal(choice["delta"], &delta); err != nil || delta == nil {
+		return index, nil, ""
+	}
+	_ = json.Unmarshal(delta["content"], &content)
+	return index, delta, content
+}
+
+func finished(choice map[string]json.RawMessage) bool {
+	v := string(bytes.TrimSpace(choice["finish_reason"]))
+	return v != "" && v != "null"
+}
+
+func mustMarshal(v any) json.RawMessage {
+	b, _ := json.Marshal(v)
+	return b
+}
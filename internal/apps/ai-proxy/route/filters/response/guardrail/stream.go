@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guardrail
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/erda-project/erda/pkg/desensitize"
+)
+
+const (
+	// holdbackBytes of the tail of streamed content are held back, sensitive data there may be incomplete.
+	holdbackBytes = 64
+	// maxPendingBytes bounds held back content, e.g. of a never closed `-----BEGIN` block.
+	maxPendingBytes = 16 << 10
+)
+
+var pemEndRe = regexp.MustCompile(`-----END [A-Z ]+-----`)
+
+// streamScanner releases streamed content of one choice in pieces that never cut sensitive data in two,
+// so each released piece can be scanned on its own.
+type streamScanner struct {
+	kinds   []desensitize.Kind
+	pending string
+}
+
+// Push appends content and returns the part safe to scan and release, all pending content if final.
+func (s *streamScanner) Push(content string, final bool) string {
+	s.pending += content
+	cut := len(s.pending)
+	if !final {
+		cut = safeCut(s.pending, desensitize.Scan(s.pending, s.kinds...))
+	}
+	released := s.pending[:cut]
+	s.pending = s.pending[cut:]
+	return released
+}
+
+func safeCut(p string, findings []desensitize.Finding) int {
+	cut := runeStartBefore(p, len(p)-holdbackBytes)
+	// never cut a run of token characters, it may be the beginning of a card number, a key or an email
+	for cut > 0 && isTokenChar(p[cut-1]) && isTokenChar(p[cut]) {
+		cut--
+	}
+	// wait for the end of a pem block
+	if i := strings.LastIndex(p[:cut], "-----BEGIN"); i >= 0 && !pemEndRe.MatchString(p[i:]) {
+		cut = i
+	}
+	if cut == 0 && len(p) > maxPendingBytes {
+		cut = runeStartBefore(p, len(p)-holdbackBytes)
+	}
+	for _, f := range findings {
+		if f.Start < cut && cut < f.End {
+			cut = f.Start
+		}
+	}
+	return cut
+}
+
+func runeStartBefore(s string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+func isTokenChar(c byte) bool {
+	switch {
+	case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	}
+	return strings.IndexByte("._%+-@/=", c) >= 0
+}
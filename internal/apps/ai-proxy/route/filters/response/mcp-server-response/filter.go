@@ -15,6 +15,7 @@
 package mcp_server_response
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -25,7 +26,9 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/mcp_tool_call"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/mcp-tool/mcptoolutil"
 )
 
 const (
@@ -43,6 +46,11 @@ func init() {
 
 type Filter struct {
 	filter_define.PassThroughResponseModifier
+
+	// mcpSessionID is set once the endpoint event of sse transport is sent.
+	mcpSessionID string
+	// callFinished is set once the tool call of the request is finished.
+	callFinished bool
 }
 
 var Creator filter_define.ResponseModifierCreator = func(_ string, _ json.RawMessage) filter_define.ProxyResponseModifier {
@@ -52,6 +60,14 @@ var Creator filter_define.ResponseModifierCreator = func(_ string, _ json.RawMes
 
 func (f *Filter) OnComplete(resp *http.Response) (out []byte, err error) {
 	logrus.Infof("resp: %v", resp.StatusCode)
+	// the call is answered by neither the response nor the event stream
+	if call, ok := getToolCall(resp.Request.Context()); ok && !f.callFinished && resp.StatusCode >= http.StatusMultipleChoices {
+		f.callFinished = true
+		errMsg := fmt.Sprintf("mcp server responded with status %d", resp.StatusCode)
+		if err := ctxhelper.MustGetDBClient(resp.Request.Context()).MCPToolCallClient().Finish(resp.Request.Context(), call.ID.String, mcptoolutil.StatusError, errMsg, 0); err != nil {
+			logrus.Warnf("failed to finish mcp tool call %s: %v", call.ID.String, err)
+		}
+	}
 	return nil, nil
 }
 
@@ -60,10 +76,13 @@ func (f *Filter) OnBodyChunk(resp *http.Response, chunk []byte, index int64) (ou
 
 	logger.Debugf("method:%v response: %v", resp.Request.Method, string(chunk))
 
+	f.trackToolResult(resp, chunk)
+
 	router, sessionId, err := parseSessionId(string(chunk))
 	if err != nil {
 		return chunk, nil
 	}
+	f.mcpSessionID = sessionId
 	info, ok := ctxhelper.GetMcpInfo(resp.Request.Context())
 	if !ok {
 		return nil, fmt.Errorf("[Proxy Error] mcp info fail")
@@ -78,6 +97,39 @@ func (f *Filter) OnBodyChunk(resp *http.Response, chunk []byte, index int64) (ou
 	return buildMessage(&info, router, chunk), nil
 }
 
+// trackToolResult finishes the audit record of a tool call with its result.
+// The result is in the response of the call for streamable http transport, or in the event stream for sse transport.
+func (f *Filter) trackToolResult(resp *http.Response, chunk []byte) {
+	outcome, ok := mcptoolutil.ParseResponse(chunk)
+	if !ok {
+		return
+	}
+	ctx := resp.Request.Context()
+	calls := ctxhelper.MustGetDBClient(ctx).MCPToolCallClient()
+	if call, ok := getToolCall(ctx); ok && !f.callFinished && call.RpcID == outcome.RPCID {
+		f.callFinished = true
+		if err := calls.Finish(ctx, call.ID.String, outcome.Status, outcome.Error, outcome.ResultBytes); err != nil {
+			ctxhelper.MustGetLogger(ctx).Warnf("failed to finish mcp tool call %s: %v", call.ID.String, err)
+		}
+		return
+	}
+	if f.mcpSessionID == "" || (!outcome.IsToolResult && outcome.Status != mcptoolutil.StatusError) {
+		return
+	}
+	if _, err := calls.FinishBySession(ctx, f.mcpSessionID, outcome.RPCID, outcome.Status, outcome.Error, outcome.ResultBytes); err != nil {
+		ctxhelper.MustGetLogger(ctx).Warnf("failed to finish mcp tool call of session %s, rpc id: %s, err: %v", f.mcpSessionID, outcome.RPCID, err)
+	}
+}
+
+func getToolCall(ctx context.Context) (*mcp_tool_call.McpToolCall, bool) {
+	v, ok := ctxhelper.GetMcpToolCall(ctx)
+	if !ok || v == nil {
+		return nil, false
+	}
+	call, ok := v.(*mcp_tool_call.McpToolCall)
+	return call, ok && call != nil
+}
+
 func buildMessage(info *ctxhelper.McpInfo, router string, chunk []byte) []byte {
 	// prefix：/proxy/message/{name}/{tag}
 	prefix := fmt.Sprintf("/proxy/message/%s/%s", info.Name, info.Version)
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package response_cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/response-cache/cacheutil"
+)
+
+var (
+	_ filter_define.ProxyResponseModifier = (*Filter)(nil)
+)
+
+var (
+	// nowFunc is a var for testing.
+	nowFunc = time.Now
+)
+
+func init() {
+	filter_define.RegisterFilterCreator("response-cache", ResponseModifierCreator)
+}
+
+// Filter stores successful upstream responses of cache misses decided by the `response-cache` request filter.
+// It must be the first response filter to see the raw upstream body.
+type Filter struct {
+	contentType string
+	body        []byte
+	tooLarge    bool
+}
+
+var ResponseModifierCreator filter_define.ResponseModifierCreator = func(name string, _ json.RawMessage) filter_define.ProxyResponseModifier {
+	return &Filter{}
+}
+
+func (f *Filter) Enable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	state, ok := cacheutil.GetState(resp.Request.Context())
+	return ok && !state.Hit() && !state.NoStore
+}
+
+func (f *Filter) OnHeaders(resp *http.Response) error {
+	// the upstream Content-Type selects the same chunk splitter when the body is replayed
+	if ct, ok := ctxhelper.GetResponseUpstreamContentType(resp.Request.Context()); ok && ct != "" {
+		f.contentType = ct
+	} else {
+		f.contentType = resp.Header.Get("Content-Type")
+	}
+	return nil
+}
+
+func (f *Filter) OnBodyChunk(resp *http.Response, chunk []byte, index int64) ([]byte, error) {
+	if f.tooLarge {
+		return chunk, nil
+	}
+	if len(f.body)+len(chunk) > cacheutil.MaxEntryBodyBytes {
+		f.tooLarge = true
+		f.body = nil
+		return chunk, nil
+	}
+	f.body = append(f.body, chunk...)
+	return chunk, nil
+}
+
+func (f *Filter) OnComplete(resp *http.Response) ([]byte, error) {
+	ctx := resp.Request.Context()
+	if f.tooLarge || len(f.body) == 0 {
+		return nil, nil
+	}
+	// only cache a body received completely
+	if last, ok := ctxhelper.GetIsLastBodyChunk(ctx); !ok || !last {
+		return nil, nil
+	}
+	state, ok := cacheutil.GetState(ctx)
+	if !ok {
+		return nil, nil
+	}
+	now := nowFunc()
+	entry := &cacheutil.Entry{
+		ContentType: f.contentType,
+		Body:        f.body,
+		CreatedAt:   now,
+	}
+	if err := cacheutil.GetStore().Set(ctx, state.Key, entry, state.TTL); err != nil {
+		ctxhelper.MustGetLogger(ctx).Warnf("failed to store response cache: %v", err)
+		return nil, nil
+	}
+	if state.SemanticScope != "" && len(state.Vector) > 0 {
+		if err := cacheutil.GetSemanticIndex().Add(ctx, state.SemanticScope, state.Vector, state.Key, now.Add(state.TTL), state.SemanticMaxEntries, now); err != nil {
+			ctxhelper.MustGetLogger(ctx).Warnf("failed to index semantic response cache: %v", err)
+		}
+	}
+	return nil, nil
+}
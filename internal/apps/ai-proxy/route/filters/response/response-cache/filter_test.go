@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package response_cache
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda-infra/base/logs/logrusx"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/response-cache/cacheutil"
+)
+
+func TestFilter_StoreCompletedResponse(t *testing.T) {
+	store := useStoreForTest(t)
+	resp := newResponseForTest(&cacheutil.State{Key: "key-1", TTL: time.Minute})
+	ctxhelper.PutResponseUpstreamContentType(resp.Request.Context(), "text/event-stream")
+
+	f := &Filter{}
+	require.True(t, f.Enable(resp))
+	require.NoError(t, f.OnHeaders(resp))
+	for i, chunk := range []string{"data: {\"id\":1}\n\n", "data: [DONE]\n\n"} {
+		out, err := f.OnBodyChunk(resp, []byte(chunk), int64(i))
+		require.NoError(t, err)
+		require.Equal(t, chunk, string(out))
+	}
+	ctxhelper.PutIsLastBodyChunk(resp.Request.Context(), true)
+	_, err := f.OnComplete(resp)
+	require.NoError(t, err)
+
+	entry, ok, err := store.Get(context.Background(), "key-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "text/event-stream", entry.ContentType)
+	require.Equal(t, "data: {\"id\":1}\n\ndata: [DONE]\n\n", string(entry.Body))
+}
+
+func TestFilter_SkipIncompleteResponse(t *testing.T) {
+	store := useStoreForTest(t)
+	resp := newResponseForTest(&cacheutil.State{Key: "key-1", TTL: time.Minute})
+
+	f := &Filter{}
+	require.NoError(t, f.OnHeaders(resp))
+	_, err := f.OnBodyChunk(resp, []byte("partial"), 0)
+	require.NoError(t, err)
+	_, err = f.OnComplete(resp)
+	require.NoError(t, err)
+
+	_, ok, err := store.Get(context.Background(), "key-1")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestFilter_Enable(t *testing.T) {
+	require.False(t, (&Filter{}).Enable(newResponseForTest(nil)), "cache not enabled")
+	require.False(t, (&Filter{}).Enable(newResponseForTest(&cacheutil.State{HitType: cacheutil.HitTypeExact})), "replayed response")
+	require.False(t, (&Filter{}).Enable(newResponseForTest(&cacheutil.State{NoStore: true})), "no-store")
+
+	resp := newResponseForTest(&cacheutil.State{})
+	resp.StatusCode = http.StatusTooManyRequests
+	require.False(t, (&Filter{}).Enable(resp), "error response")
+}
+
+func newResponseForTest(state *cacheutil.State) *http.Response {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	ctxhelper.PutLogger(ctx, logrusx.New())
+	if state != nil {
+		cacheutil.PutState(ctx, state)
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://example.com/v1/chat/completions", nil)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Request:    req,
+	}
+}
+
+func useStoreForTest(t *testing.T) cacheutil.Store {
+	store := cacheutil.NewMemoryStore(cacheutil.DefaultMaxBytes)
+	cacheutil.SetStore(store)
+	cacheutil.SetSemanticIndex(cacheutil.NewMemorySemanticIndex())
+	t.Cleanup(func() {
+		cacheutil.SetStore(nil)
+		cacheutil.SetSemanticIndex(nil)
+	})
+	return store
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google_gemini_director
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata/api_segment/api_segment_getter"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/models/metadata/api_segment/api_style"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
+	gemini "github.com/erda-project/erda/internal/apps/ai-proxy/route/filters/common/google-gemini-director"
+)
+
+var (
+	_ filter_define.ProxyResponseModifier = (*GoogleGeminiDirectorResponse)(nil)
+)
+
+var ResponseModifierCreator filter_define.ResponseModifierCreator = func(name string, _ json.RawMessage) filter_define.ProxyResponseModifier {
+	return &GoogleGeminiDirectorResponse{}
+}
+
+func init() {
+	filter_define.RegisterFilterCreator("google-gemini-director", ResponseModifierCreator)
+}
+
+type GoogleGeminiDirectorResponse struct {
+	filter_define.PassThroughResponseModifier
+
+	// streamConverter is created at the first chunk of a stream response
+	streamConverter *gemini.StreamConverter
+}
+
+func (f *GoogleGeminiDirectorResponse) Enable(resp *http.Response) bool {
+	apiSegment := api_segment_getter.GetAPISegment(resp.Request.Context())
+	return apiSegment != nil && strings.EqualFold(string(apiSegment.APIStyle), string(api_style.APIStyleGoogleGemini))
+}
+
+func (f *GoogleGeminiDirectorResponse) OnBodyChunk(resp *http.Response, chunk []byte, index int64) ([]byte, error) {
+	if resp.StatusCode != http.StatusOK {
+		return chunk, nil // do not process non-200 responses
+	}
+	modelName := ctxhelper.MustGetModel(resp.Request.Context()).Name
+	// non-stream, convert all at once
+	if !ctxhelper.MustGetIsStream(resp.Request.Context()) {
+		return gemini.ConvertResponse(chunk, modelName)
+	}
+	if f.streamConverter == nil {
+		f.streamConverter = gemini.NewStreamConverter(modelName)
+	}
+	return f.streamConverter.Convert(chunk)
+}
+
+func (f *GoogleGeminiDirectorResponse) OnComplete(resp *http.Response) ([]byte, error) {
+	if resp.StatusCode != http.StatusOK || !ctxhelper.MustGetIsStream(resp.Request.Context()) {
+		return nil, nil
+	}
+	if f.streamConverter == nil {
+		f.streamConverter = gemini.NewStreamConverter(ctxhelper.MustGetModel(resp.Request.Context()).Name)
+	}
+	// flush the rest, then usage and [DONE] chunks
+	return f.streamConverter.Finish()
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state_store
+
+import (
+	"fmt"
+
+	"github.com/erda-project/erda-infra/base/logs"
+)
+
+// FailOpen logs an error of a shared store consulted on the request path.
+// A broken store must not break all LLM calls, so the caller skips its check and lets the request through.
+func FailOpen(l logs.Logger, err error, format string, args ...any) {
+	l.Warnf("%s, skipped: %v", fmt.Sprintf(format, args...), err)
+}
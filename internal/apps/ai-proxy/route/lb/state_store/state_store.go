@@ -32,4 +32,9 @@ type LBStateStore interface {
 	SetBinding(ctx context.Context, bindingKey BindingKey, stickyValue, instanceID string, ttl time.Duration) error
 	DeleteBinding(ctx context.Context, bindingKey BindingKey, stickyValue string) error
 	NextCounter(ctx context.Context, key CounterKey) (int64, error)
+	// IncrCounterBy adds delta to a counter that expires after ttl and returns the new value.
+	// The ttl is only applied when the counter is created, so fixed-window counters keep their deadline.
+	IncrCounterBy(ctx context.Context, key CounterKey, delta int64, ttl time.Duration) (int64, error)
+	// GetCounter returns the current value of a counter, 0 if it does not exist or has expired.
+	GetCounter(ctx context.Context, key CounterKey) (int64, error)
 }
@@ -106,6 +106,79 @@ func (s *EtcdStateStore) NextCounter(ctx context.Context, key CounterKey) (int64
 	}
 }
 
+func (s *EtcdStateStore) IncrCounterBy(ctx context.Context, key CounterKey, delta int64, ttl time.Duration) (int64, error) {
+	k := s.counterKey(key)
+	leaseTTL := int64(math.Ceil(ttl.Seconds()))
+	if leaseTTL <= 0 {
+		leaseTTL = int64((time.Hour).Seconds())
+	}
+	// granted is the lease of a new counter, granted at most once across the retries
+	// and revoked if the counter turns out to be created by others
+	var (
+		granted clientv3.LeaseID
+		used    bool
+	)
+	defer func() {
+		if granted != clientv3.NoLease && !used {
+			_, _ = s.client.Revoke(context.Background(), granted)
+		}
+	}()
+	for {
+		resp, err := s.client.Get(ctx, k)
+		if err != nil {
+			return 0, err
+		}
+		var (
+			current int64
+			version int64
+			leaseID clientv3.LeaseID
+		)
+		if len(resp.Kvs) > 0 {
+			current, _ = strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+			version = resp.Kvs[0].Version
+			leaseID = clientv3.LeaseID(resp.Kvs[0].Lease)
+		}
+		// the first increment of a window grants the lease, later ones reuse it to keep the deadline
+		if leaseID == clientv3.NoLease {
+			if granted == clientv3.NoLease {
+				lease, err := s.client.Grant(ctx, leaseTTL)
+				if err != nil {
+					return 0, err
+				}
+				granted = lease.ID
+			}
+			leaseID = granted
+		}
+		next := current + delta
+		txnResp, err := s.client.Txn(ctx).If(clientv3.Compare(clientv3.Version(k), "=", version)).
+			Then(clientv3.OpPut(k, strconv.FormatInt(next, 10), clientv3.WithLease(leaseID))).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if txnResp.Succeeded {
+			used = leaseID == granted
+			return next, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (s *EtcdStateStore) GetCounter(ctx context.Context, key CounterKey) (int64, error) {
+	resp, err := s.client.Get(ctx, s.counterKey(key))
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+}
+
 func (s *EtcdStateStore) bindingKey(bindingKey BindingKey, stickyValue string) string {
 	return fmt.Sprintf("%s:binding:%s:%s", s.prefix, bindingKey, stickyValue)
 }
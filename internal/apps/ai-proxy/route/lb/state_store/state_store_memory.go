@@ -24,8 +24,20 @@ import (
 type MemoryStateStore struct {
 	mu       sync.Mutex
 	counters map[string]int64
+	expiring map[string]counterEntry
 	bindings map[string]map[string]bindingEntry // bindingKey -> stickyValue -> entry
 	now      func() time.Time
+
+	nextSweep time.Time
+}
+
+// expiringSweepInterval is how often expired counters are pruned, counter keys usually embed the window
+// so they are never read again after expiry.
+const expiringSweepInterval = time.Minute
+
+type counterEntry struct {
+	value    int64
+	expireAt time.Time
 }
 
 type bindingEntry struct {
@@ -36,6 +48,7 @@ type bindingEntry struct {
 func NewMemoryStateStore() *MemoryStateStore {
 	return &MemoryStateStore{
 		counters: make(map[string]int64),
+		expiring: make(map[string]counterEntry),
 		bindings: make(map[string]map[string]bindingEntry),
 		now:      time.Now,
 	}
@@ -96,3 +109,43 @@ func (s *MemoryStateStore) NextCounter(_ context.Context, key CounterKey) (int64
 	s.counters[key] = next
 	return next, nil
 }
+
+func (s *MemoryStateStore) IncrCounterBy(_ context.Context, key CounterKey, delta int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweepExpiring(now)
+	entry, ok := s.expiring[key]
+	if !ok || now.After(entry.expireAt) {
+		entry = counterEntry{expireAt: now.Add(ttl)}
+	}
+	entry.value += delta
+	s.expiring[key] = entry
+	return entry.value, nil
+}
+
+func (s *MemoryStateStore) GetCounter(_ context.Context, key CounterKey) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.expiring[key]
+	if !ok {
+		return 0, nil
+	}
+	if s.now().After(entry.expireAt) {
+		delete(s.expiring, key)
+		return 0, nil
+	}
+	return entry.value, nil
+}
+
+func (s *MemoryStateStore) sweepExpiring(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(expiringSweepInterval)
+	for key, entry := range s.expiring {
+		if now.After(entry.expireAt) {
+			delete(s.expiring, key)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state_store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStateStoreWindowCounter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStateStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if v, _ := store.IncrCounterBy(ctx, "k", 2, time.Minute); v != 2 {
+		t.Fatalf("unexpected first incr: %d", v)
+	}
+	now = now.Add(30 * time.Second)
+	if v, _ := store.IncrCounterBy(ctx, "k", 5, time.Minute); v != 7 {
+		t.Fatalf("unexpected second incr: %d", v)
+	}
+	now = now.Add(31 * time.Second)
+	if v, _ := store.GetCounter(ctx, "k"); v != 0 {
+		t.Fatalf("expected counter expired, got %d", v)
+	}
+	if v, _ := store.IncrCounterBy(ctx, "k", 1, time.Minute); v != 1 {
+		t.Fatalf("expected counter restarted, got %d", v)
+	}
+}
+
+func TestMemoryStateStoreSweepExpiredCounters(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStateStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		_, _ = store.IncrCounterBy(ctx, "window:"+time.Duration(i).String(), 1, time.Minute)
+	}
+	now = now.Add(2 * time.Minute)
+	_, _ = store.IncrCounterBy(ctx, "window:new", 1, time.Minute)
+	if len(store.expiring) != 1 {
+		t.Fatalf("expected expired counters pruned, got %d", len(store.expiring))
+	}
+}
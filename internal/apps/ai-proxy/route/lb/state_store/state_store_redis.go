@@ -24,6 +24,16 @@ import (
 	redis "github.com/go-redis/redis"
 )
 
+// incrWithTTLScript increments a counter and only sets its expiration when the key has none,
+// so that all increments inside a window share the deadline set by the first one.
+var incrWithTTLScript = redis.NewScript(`
+local v = redis.call('INCRBY', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return v
+`)
+
 // RedisStateStore implements LBStateStore backed by Redis.
 // It is safe for multi-instance deployments when all instances share the same Redis.
 type RedisStateStore struct {
@@ -82,6 +92,25 @@ func (s *RedisStateStore) NextCounter(_ context.Context, key CounterKey) (int64,
 	return s.client.Incr(s.counterKey(key)).Result()
 }
 
+func (s *RedisStateStore) IncrCounterBy(_ context.Context, key CounterKey, delta int64, ttl time.Duration) (int64, error) {
+	k := s.counterKey(key)
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return incrWithTTLScript.Run(s.client, []string{k}, delta, ttl.Milliseconds()).Int64()
+}
+
+func (s *RedisStateStore) GetCounter(_ context.Context, key CounterKey) (int64, error) {
+	val, err := s.client.Get(s.counterKey(key)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return val, nil
+}
+
 func (s *RedisStateStore) bindingKey(bindingKey BindingKey, stickyValue string) string {
 	hash := hashSticky(stickyValue)
 	return fmt.Sprintf("%s:branch-bind:%s:sticky:%s", s.prefix, bindingKey, hash)
@@ -110,3 +110,34 @@ func TestRedisStateStoreUniversal(t *testing.T) {
 		t.Fatalf("expected branch-bind key in redis")
 	}
 }
+
+func TestRedisStateStoreWindowCounter(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+	defer mr.Close()
+
+	store := NewRedisStateStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test-lb")
+	ctx := context.Background()
+
+	if v, err := store.GetCounter(ctx, "window/1"); err != nil || v != 0 {
+		t.Fatalf("expected empty counter, got %d err=%v", v, err)
+	}
+	if v, err := store.IncrCounterBy(ctx, "window/1", 3, time.Second); err != nil || v != 3 {
+		t.Fatalf("unexpected first incr: %d err=%v", v, err)
+	}
+	mr.FastForward(500 * time.Millisecond)
+	if v, err := store.IncrCounterBy(ctx, "window/1", 2, time.Second); err != nil || v != 5 {
+		t.Fatalf("unexpected second incr: %d err=%v", v, err)
+	}
+	if v, err := store.GetCounter(ctx, "window/1"); err != nil || v != 5 {
+		t.Fatalf("unexpected counter value: %d err=%v", v, err)
+	}
+
+	// the deadline is set by the first increment only
+	mr.FastForward(600 * time.Millisecond)
+	if v, err := store.GetCounter(ctx, "window/1"); err != nil || v != 0 {
+		t.Fatalf("expected counter expired, got %d err=%v", v, err)
+	}
+}
@@ -22,11 +22,22 @@ import (
 	"time"
 )
 
+// MaxLLMBackendRequestCountLimit caps the attempts of a request, whatever config, header or policy group asks for.
+const MaxLLMBackendRequestCountLimit = 10
+
 type Config struct {
 	Enabled       bool          `file:"enabled" env:"AI_PROXY_MODEL_RETRY_ENABLED" default:"true"`
 	Conditions    Conditions    `file:"conditions"`
 	Actions       Actions       `file:"actions"`
 	Observability Observability `file:"observability"`
+
+	// requestOverrides records what is set by request headers, which a policy group fallback can't override.
+	requestOverrides requestOverrides
+}
+
+type requestOverrides struct {
+	enabled                   bool
+	maxLLMBackendRequestCount bool
 }
 
 type Conditions struct {
@@ -35,6 +46,8 @@ type Conditions struct {
 	RetryableHTTPStatuses             []int   `file:"retryable_http_statuses"`
 	RetryableHTTPStatusesRaw          string  `file:"-" env:"AI_PROXY_MODEL_RETRY_RETRYABLE_HTTP_STATUSES"`
 	MatchNetworkIssueFromResponseBody bool    `file:"match_network_issue_from_response_body" env:"AI_PROXY_MODEL_RETRY_MATCH_NETWORK_ISSUE_FROM_RESPONSE_BODY" default:"false"`
+	// Deadline bounds all attempts of a request, no more attempt is started after it. Zero means no deadline.
+	Deadline time.Duration `file:"deadline" env:"AI_PROXY_MODEL_RETRY_DEADLINE" default:"0s"`
 }
 
 type Backoff struct {
@@ -49,6 +62,9 @@ type Actions struct {
 	// It does not override model health filtering. When model health is enabled,
 	// a failed instance may already be filtered out before the next attempt.
 	ExcludeFailedInstance bool `file:"exclude_failed_instance" env:"AI_PROXY_MODEL_RETRY_EXCLUDE_FAILED_INSTANCE" default:"true"`
+	// ExcludeFailedBranch excludes the whole policy-group branch of the failed instance,
+	// so the next attempt goes to another branch, e.g. another provider of the same model.
+	ExcludeFailedBranch bool `file:"exclude_failed_branch" default:"false"`
 }
 
 type Observability struct {
@@ -59,18 +75,29 @@ func (cfg *Config) Normalize() {
 	if raw, ok := os.LookupEnv("AI_PROXY_MODEL_RETRY_RETRYABLE_HTTP_STATUSES"); ok {
 		cfg.Conditions.RetryableHTTPStatusesRaw = raw
 	}
-	if cfg.Conditions.MaxLLMBackendRequestCount <= 0 {
-		cfg.Conditions.MaxLLMBackendRequestCount = 1
-	}
+	cfg.Conditions.MaxLLMBackendRequestCount = clampMaxLLMBackendRequestCount(cfg.Conditions.MaxLLMBackendRequestCount)
 	if cfg.Conditions.Backoff.Base < 0 {
 		cfg.Conditions.Backoff.Base = 0
 	}
 	if cfg.Conditions.Backoff.Max <= 0 {
 		cfg.Conditions.Backoff.Max = 10 * time.Second
 	}
+	if cfg.Conditions.Deadline < 0 {
+		cfg.Conditions.Deadline = 0
+	}
 	cfg.Conditions.RetryableHTTPStatuses = normalizeHTTPStatusCodes(resolveRetryableHTTPStatuses(cfg.Conditions))
 }
 
+func clampMaxLLMBackendRequestCount(n int) int {
+	if n <= 0 {
+		return 1
+	}
+	if n > MaxLLMBackendRequestCountLimit {
+		return MaxLLMBackendRequestCountLimit
+	}
+	return n
+}
+
 func resolveRetryableHTTPStatuses(cfg Conditions) []int {
 	if strings.TrimSpace(cfg.RetryableHTTPStatusesRaw) == "" {
 		return cfg.RetryableHTTPStatuses
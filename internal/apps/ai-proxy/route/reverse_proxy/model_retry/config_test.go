@@ -49,6 +49,14 @@ func TestNormalize(t *testing.T) {
 	}
 }
 
+func TestNormalizeCapsMaxLLMBackendRequestCount(t *testing.T) {
+	cfg := &Config{Conditions: Conditions{MaxLLMBackendRequestCount: 1000}}
+	cfg.Normalize()
+	if cfg.Conditions.MaxLLMBackendRequestCount != MaxLLMBackendRequestCountLimit {
+		t.Fatalf("expected max request count capped to %d, got %d", MaxLLMBackendRequestCountLimit, cfg.Conditions.MaxLLMBackendRequestCount)
+	}
+}
+
 func TestNormalizeRetryableHTTPStatusesEnvOverride(t *testing.T) {
 	t.Setenv("AI_PROXY_MODEL_RETRY_RETRYABLE_HTTP_STATUSES", "503, 429,abc,429,,600,502")
 
@@ -38,6 +38,7 @@ func (cfg Config) WithRequestOverrides(r *http.Request) Config {
 	if raw := strings.TrimSpace(r.Header.Get(vars.XAIProxyModelRetry)); raw != "" {
 		if v, ok := parseHeaderBool(raw); ok {
 			cfg.Enabled = v
+			cfg.requestOverrides.enabled = true
 		} else {
 			logger.Warnf("invalid %s=%q", vars.XAIProxyModelRetry, raw)
 		}
@@ -46,6 +47,7 @@ func (cfg Config) WithRequestOverrides(r *http.Request) Config {
 		if v, ok := parseHeaderBool(raw); ok {
 			if v {
 				cfg.Enabled = false
+				cfg.requestOverrides.enabled = true
 			}
 		} else {
 			logger.Warnf("invalid %s=%q", vars.XAIProxyModelRetryDisabled, raw)
@@ -53,13 +55,15 @@ func (cfg Config) WithRequestOverrides(r *http.Request) Config {
 	}
 	if raw := strings.TrimSpace(r.Header.Get(vars.XAIProxyModelRetryMax)); raw != "" {
 		if maxRequestCount, err := strconv.Atoi(raw); err == nil && maxRequestCount > 0 {
-			cfg.Conditions.MaxLLMBackendRequestCount = maxRequestCount
+			cfg.Conditions.MaxLLMBackendRequestCount = clampMaxLLMBackendRequestCount(maxRequestCount)
+			cfg.requestOverrides.maxLLMBackendRequestCount = true
 		} else {
 			logger.Warnf("invalid %s=%q", vars.XAIProxyModelRetryMax, raw)
 		}
 	}
 	if health.IsHealthProbeRequest(r.Header) {
 		cfg.Enabled = false
+		cfg.requestOverrides.enabled = true
 	}
 
 	return cfg
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_retry
+
+import (
+	"context"
+	"time"
+
+	policypb "github.com/erda-project/erda-proto-go/apps/aiproxy/policy_group/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+)
+
+const (
+	FallbackScopeInstance = "instance"
+	FallbackScopeBranch   = "branch"
+)
+
+// WithPolicyGroupFallback overrides the config by the fallback policy of the policy group routing the request.
+// Overrides from request headers still win.
+func (cfg Config) WithPolicyGroupFallback(fallback *policypb.PolicyFallback) Config {
+	if fallback == nil {
+		return cfg
+	}
+	if !cfg.requestOverrides.enabled {
+		cfg.Enabled = fallback.Enabled
+	}
+	if fallback.MaxAttempts > 0 && !cfg.requestOverrides.maxLLMBackendRequestCount {
+		cfg.Conditions.MaxLLMBackendRequestCount = clampMaxLLMBackendRequestCount(int(fallback.MaxAttempts))
+	}
+	if len(fallback.RetryableHttpStatuses) > 0 {
+		statuses := make([]int, 0, len(fallback.RetryableHttpStatuses))
+		for _, code := range fallback.RetryableHttpStatuses {
+			statuses = append(statuses, int(code))
+		}
+		cfg.Conditions.RetryableHTTPStatuses = normalizeHTTPStatusCodes(statuses)
+	}
+	if deadline, err := time.ParseDuration(fallback.Deadline); err == nil && deadline > 0 {
+		cfg.Conditions.Deadline = deadline
+	}
+	cfg.Actions.ExcludeFailedInstance = true
+	cfg.Actions.ExcludeFailedBranch = fallback.Scope == FallbackScopeBranch
+	return cfg
+}
+
+// CanStartAttemptAfter reports whether another attempt can start after the delay without exceeding the deadline.
+func (cfg Config) CanStartAttemptAfter(startedAt, now time.Time, delay time.Duration) bool {
+	if cfg.Conditions.Deadline <= 0 {
+		return true
+	}
+	return now.Add(delay).Before(startedAt.Add(cfg.Conditions.Deadline))
+}
+
+// AttemptDeadline returns when an in-flight attempt must be given up, false if there is no deadline.
+func (cfg Config) AttemptDeadline(startedAt time.Time) (time.Time, bool) {
+	if !cfg.Enabled || cfg.Conditions.Deadline <= 0 {
+		return time.Time{}, false
+	}
+	return startedAt.Add(cfg.Conditions.Deadline), true
+}
+
+func PutPolicyGroupFallback(ctx context.Context, fallback *policypb.PolicyFallback) {
+	ctxhelper.PutModelRetryPolicyGroupFallback(ctx, fallback)
+}
+
+func GetPolicyGroupFallback(ctx context.Context) *policypb.PolicyFallback {
+	v, ok := ctxhelper.GetModelRetryPolicyGroupFallback(ctx)
+	if !ok || v == nil {
+		return nil
+	}
+	fallback, _ := v.(*policypb.PolicyFallback)
+	return fallback
+}
+
+func AddExcludedBranchName(ctx context.Context, branchName string) {
+	if branchName == "" {
+		return
+	}
+	excluded, _ := GetExcludedBranchNames(ctx)
+	cloned := make(map[string]struct{}, len(excluded)+1)
+	for name := range excluded {
+		cloned[name] = struct{}{}
+	}
+	cloned[branchName] = struct{}{}
+	ctxhelper.PutModelRetryExcludedBranchNames(ctx, cloned)
+}
+
+func GetExcludedBranchNames(ctx context.Context) (map[string]struct{}, bool) {
+	excluded, ok := ctxhelper.GetModelRetryExcludedBranchNames(ctx)
+	if !ok || excluded == nil {
+		return nil, false
+	}
+	ret, ok := excluded.(map[string]struct{})
+	if !ok || ret == nil {
+		return nil, false
+	}
+	return ret, true
+}
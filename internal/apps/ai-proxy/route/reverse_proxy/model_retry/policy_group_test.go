@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model_retry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs/logrusx"
+	policypb "github.com/erda-project/erda-proto-go/apps/aiproxy/policy_group/pb"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
+)
+
+func TestWithPolicyGroupFallback(t *testing.T) {
+	base := Config{
+		Conditions: Conditions{
+			MaxLLMBackendRequestCount: 1,
+			RetryableHTTPStatuses:     []int{429},
+		},
+	}
+
+	policy := base.WithPolicyGroupFallback(nil)
+	if policy.Enabled || policy.Conditions.MaxLLMBackendRequestCount != 1 {
+		t.Fatalf("expected config unchanged without fallback, got %+v", policy)
+	}
+
+	policy = base.WithPolicyGroupFallback(&policypb.PolicyFallback{
+		Enabled:               true,
+		Scope:                 FallbackScopeBranch,
+		MaxAttempts:           4,
+		RetryableHttpStatuses: []int32{503, 502},
+		Deadline:              "30s",
+	})
+	if !policy.Enabled {
+		t.Fatal("expected retry enabled by policy group")
+	}
+	if policy.Conditions.MaxLLMBackendRequestCount != 4 {
+		t.Fatalf("expected max request count=4, got %d", policy.Conditions.MaxLLMBackendRequestCount)
+	}
+	if policy.IsRetryableHTTPStatus(429) || !policy.IsRetryableHTTPStatus(503) || !policy.IsRetryableHTTPStatus(502) {
+		t.Fatalf("expected retryable statuses of policy group, got %v", policy.SortedRetryableHTTPStatuses())
+	}
+	if policy.Conditions.Deadline != 30*time.Second {
+		t.Fatalf("expected deadline=30s, got %s", policy.Conditions.Deadline)
+	}
+	if !policy.Actions.ExcludeFailedInstance || !policy.Actions.ExcludeFailedBranch {
+		t.Fatalf("expected failed instance and branch excluded, got %+v", policy.Actions)
+	}
+}
+
+func TestWithPolicyGroupFallbackRequestOverridesWin(t *testing.T) {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	ctxhelper.PutLoggerBase(ctx, logrusx.New())
+
+	req := httptest.NewRequest(http.MethodPost, "http://ai-proxy.test/v1/chat/completions", nil).WithContext(ctx)
+	req.Header.Set(vars.XAIProxyModelRetryDisabled, "true")
+	req.Header.Set(vars.XAIProxyModelRetryMax, "2")
+
+	policy := (Config{Enabled: true, Conditions: Conditions{MaxLLMBackendRequestCount: 3}}).
+		WithRequestOverrides(req).
+		WithPolicyGroupFallback(&policypb.PolicyFallback{Enabled: true, MaxAttempts: 5})
+	if policy.Enabled {
+		t.Fatal("expected retry kept disabled by request header")
+	}
+	if policy.Conditions.MaxLLMBackendRequestCount != 2 {
+		t.Fatalf("expected max request count=2 from request header, got %d", policy.Conditions.MaxLLMBackendRequestCount)
+	}
+}
+
+func TestCanStartAttemptAfter(t *testing.T) {
+	startedAt := time.Now()
+
+	if !(Config{}).CanStartAttemptAfter(startedAt, startedAt.Add(time.Hour), time.Hour) {
+		t.Fatal("expected no deadline by default")
+	}
+	policy := Config{Conditions: Conditions{Deadline: 10 * time.Second}}
+	if !policy.CanStartAttemptAfter(startedAt, startedAt.Add(5*time.Second), time.Second) {
+		t.Fatal("expected attempt allowed before deadline")
+	}
+	if policy.CanStartAttemptAfter(startedAt, startedAt.Add(5*time.Second), 5*time.Second) {
+		t.Fatal("expected attempt rejected at deadline")
+	}
+}
+
+func TestExcludedBranchNames(t *testing.T) {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+
+	if _, ok := GetExcludedBranchNames(ctx); ok {
+		t.Fatal("expected no excluded branch")
+	}
+	AddExcludedBranchName(ctx, "primary")
+	AddExcludedBranchName(ctx, "")
+	AddExcludedBranchName(ctx, "secondary")
+
+	excluded, ok := GetExcludedBranchNames(ctx)
+	if !ok || len(excluded) != 2 {
+		t.Fatalf("expected 2 excluded branches, got %v", excluded)
+	}
+	if _, hit := excluded["primary"]; !hit {
+		t.Fatal("expected primary excluded")
+	}
+}
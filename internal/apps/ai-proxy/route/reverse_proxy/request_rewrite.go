@@ -125,6 +125,10 @@ var MyRewrite = func(w http.ResponseWriter, requestFilters []filter_define.Filte
 					brokenInErr = err
 					return
 				}
+				// the request is answered by the filter itself, later filters (e.g. context, directors) don't apply
+				if pr.Out.URL.Scheme == transports.SchemeForFilterGeneratedResponse {
+					break
+				}
 			}
 		}
 		currentFilterName = ""
@@ -34,6 +34,8 @@ func handleAIProxyResponseHeader(resp *http.Response) {
 	_handleModelRetryMetaHeader(resp)
 	_handlePolicyTraceHeader(resp)
 	_handleModelHealthMetaHeader(resp)
+	_handleRateLimitHeaders(resp)
+	_handleResponseCacheHeader(resp)
 	_handleRequestIdHeaders(resp)
 	_handleRequestBodyTransformHeaders(resp)
 	_handleRequestThinkingTransformHeaders(resp)
@@ -104,6 +106,31 @@ func _handleModelHealthMetaHeader(resp *http.Response) {
 	resp.Header.Set(vars.XAIProxyModelHealthMeta, string(b))
 }
 
+// _handleRateLimitHeaders overrides upstream x-ratelimit-* headers with the ai-proxy's own limits,
+// upstream limits belong to the provider credential and are meaningless to clients.
+func _handleRateLimitHeaders(resp *http.Response) {
+	headers, ok := ctxhelper.GetRateLimitHeaders(resp.Request.Context())
+	if !ok || len(headers) == 0 {
+		return
+	}
+	for k, v := range headers {
+		resp.Header[k] = v
+	}
+}
+
+// _handleResponseCacheHeader tells clients whether the response was served from the response cache.
+func _handleResponseCacheHeader(resp *http.Response) {
+	hit, ok := ctxhelper.GetResponseCacheHit(resp.Request.Context())
+	if !ok {
+		return
+	}
+	if hit {
+		resp.Header.Set(vars.XAIProxyCache, "HIT")
+	} else {
+		resp.Header.Set(vars.XAIProxyCache, "MISS")
+	}
+}
+
 // _handleRequestIdHeaders handles request ID related header settings
 func _handleRequestIdHeaders(resp *http.Response) {
 	// handle X-Request-Id returned by LLM backend, rename to X-Request-Id-LLM-Backend
@@ -101,6 +101,9 @@ var MyResponseModify = func(w http.ResponseWriter, filters []filter_define.Filte
 		ctxhelper.MustGetLoggerBase(resp.Request.Context()).Debugf("splitter type: %s", reflect.TypeOf(splitter))
 		audithelper.NoteOnce(resp.Request.Context(), "response_body_splitter", reflect.TypeOf(splitter).String())
 
+		// keep the upstream Content-Type, it may be changed by handleAIProxyResponseHeader
+		ctxhelper.PutResponseUpstreamContentType(resp.Request.Context(), resp.Header.Get("Content-Type"))
+
 		// run modifiers before creating the pipe
 		dumplog.DumpResponseHeadersIn(resp)
 		handleAIProxyResponseHeader(resp)
@@ -30,6 +30,7 @@ import (
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/requestid"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/route/filter_define"
 	httperror "github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/policy_group"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/route/policy_group/health"
 	modelretry "github.com/erda-project/erda/internal/apps/ai-proxy/route/reverse_proxy/model_retry"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/route/transports"
@@ -82,21 +83,23 @@ func ServeWithRetry(
 
 	logger := ctxhelper.MustGetLoggerBase(ctx)
 	defaultErrorHandler := MyErrorHandler()
+	startedAt := time.Now()
 
-	totalAttempts := 1
-	if policy.Enabled && policy.Conditions.MaxLLMBackendRequestCount > 1 {
-		totalAttempts = policy.Conditions.MaxLLMBackendRequestCount
-	}
-
-	for attempt := 1; attempt <= totalAttempts; attempt++ {
+	// the loop ends once an attempt is not suppressed for retry, see ErrorHandler,
+	// which never suppresses the last attempt allowed by the policy routing it.
+	maxAttempts := policy.Conditions.MaxLLMBackendRequestCount
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		r = prepareRequestForAttempt(r.Context(), r, attempt)
 		ctx = r.Context()
 		ctxhelper.PutModelRetryRawLLMBackendRequestCount(ctx, attempt)
+		audithelper.Note(ctx, "retry.attempt", attempt)
 
 		retryCtx, attemptCancel := context.WithCancel(ctx)
 		reqForAttempt := r.WithContext(retryCtx)
 
 		result := proxyAttemptResult{}
+		// the policy group routing this attempt may override the retry policy
+		attemptPolicy := policy
 		proxy := httputil.ReverseProxy{
 			Rewrite: MyRewrite(tw, requestFilters),
 			Transport: transports.NewRequestFilterGeneratedResponseTransport(&transports.CurlPrinterTransport{
@@ -108,12 +111,15 @@ func ServeWithRetry(
 			ModifyResponse: MyResponseModify(tw, responseFilters),
 		}
 		proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+			attemptPolicy = policy.WithPolicyGroupFallback(modelretry.GetPolicyGroupFallback(req.Context()))
 			result.Err = err
 			result.StatusCode = extractHTTPErrorStatus(err)
-			result.Retryable = isRetryableProxyError(err, result.StatusCode, policy)
-			if policy.Enabled && attempt < totalAttempts && !tw.WroteHeader() && result.Retryable {
+			result.Retryable = isRetryableProxyError(err, result.StatusCode, attemptPolicy)
+			notePolicyGroupFallback(req.Context(), attemptPolicy)
+			if attemptPolicy.Enabled && attempt < attemptPolicy.Conditions.MaxLLMBackendRequestCount && !tw.WroteHeader() && result.Retryable &&
+				!deadlineExceeded(req.Context(), attemptPolicy, startedAt, attempt) {
 				result.Suppressed = true
-				if result.StatusCode != 0 && policy.IsRetryableHTTPStatus(result.StatusCode) {
+				if result.StatusCode != 0 && attemptPolicy.IsRetryableHTTPStatus(result.StatusCode) {
 					reportRetryableStatusFailure(ctx, req, result.StatusCode)
 				}
 				if result.StatusCode == 0 {
@@ -131,14 +137,18 @@ func ServeWithRetry(
 		for _, option := range options {
 			option(ctx, &proxy)
 		}
+		deadlineCancel := withAttemptDeadline(&proxy, policy, startedAt)
 
 		proxy.ServeHTTP(tw, reqForAttempt)
 
 		if result.Suppressed {
+			deadlineCancel()
 			attemptCancel()
 		} else {
 			defer attemptCancel()
+			defer deadlineCancel()
 		}
+		maxAttempts = attemptPolicy.Conditions.MaxLLMBackendRequestCount
 
 		result.WroteHeader = tw.WroteHeader()
 		result.InstanceID = getCurrentInstanceID(retryCtx)
@@ -153,16 +163,22 @@ func ServeWithRetry(
 			return
 		}
 
-		if policy.Actions.ExcludeFailedInstance && result.InstanceID != "" {
+		if attemptPolicy.Actions.ExcludeFailedInstance && result.InstanceID != "" {
 			modelretry.AddExcludedModelID(ctx, result.InstanceID)
 		}
+		branchName := getCurrentBranchName(retryCtx)
+		if attemptPolicy.Actions.ExcludeFailedBranch && branchName != "" {
+			modelretry.AddExcludedBranchName(ctx, branchName)
+		}
 
-		delay := policy.NextBackoff(attempt)
+		delay := attemptPolicy.NextBackoff(attempt)
 		audithelper.NoteAppend(ctx, "reverse_proxy.retry.events", map[string]any{
 			"attempt":      attempt,
 			"next_attempt": attempt + 1,
 			"sleep":        delay.String(),
 			"reason":       retryReason(result),
+			"instance_id":  result.InstanceID,
+			"branch_name":  branchName,
 		})
 		logger.Warnf("transparent retry trigger, attempt=%d, next_attempt=%d, sleep=%s, instance=%s, reason=%s", attempt, attempt+1, delay.String(), result.InstanceID, retryReason(result))
 		if delay > 0 {
@@ -171,6 +187,49 @@ func ServeWithRetry(
 	}
 }
 
+// withAttemptDeadline bounds the wait for the response headers by the deadline of the retry policy,
+// resolved after rewrite since the policy group routing the attempt may override it.
+// The deadline is not applied once the headers arrive, so a streaming response is not cut off.
+func withAttemptDeadline(proxy *httputil.ReverseProxy, policy modelretry.Config, startedAt time.Time) context.CancelFunc {
+	var (
+		timer  *time.Timer
+		cancel context.CancelFunc
+	)
+	rewrite := proxy.Rewrite
+	proxy.Rewrite = func(pr *httputil.ProxyRequest) {
+		if rewrite != nil {
+			rewrite(pr)
+		}
+		attemptPolicy := policy.WithPolicyGroupFallback(modelretry.GetPolicyGroupFallback(pr.In.Context()))
+		deadline, ok := attemptPolicy.AttemptDeadline(startedAt)
+		if !ok {
+			return
+		}
+		ctx, c := context.WithCancel(pr.Out.Context())
+		cancel = c
+		timer = time.AfterFunc(time.Until(deadline), c)
+		pr.Out = pr.Out.WithContext(ctx)
+	}
+	modifyResponse := proxy.ModifyResponse
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		if timer != nil {
+			timer.Stop()
+		}
+		if modifyResponse != nil {
+			return modifyResponse(resp)
+		}
+		return nil
+	}
+	return func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		if cancel != nil {
+			cancel()
+		}
+	}
+}
+
 func noteModelRetryPolicy(ctx context.Context, policy modelretry.Config) {
 	ctxhelper.PutModelRetryResponseHeaderMetaEnabled(ctx, policy.Observability.ResponseHeaderMeta)
 	audithelper.Note(ctx, "reverse_proxy.retry.enabled", true)
@@ -295,6 +354,38 @@ func hasRetryableNetworkIssueInHTTPErrorBody(err error) bool {
 	}
 }
 
+// notePolicyGroupFallback records the retry policy of the policy group routing the failed attempt.
+func notePolicyGroupFallback(ctx context.Context, policy modelretry.Config) {
+	fallback := modelretry.GetPolicyGroupFallback(ctx)
+	if fallback == nil {
+		return
+	}
+	audithelper.Note(ctx, "retry.policy_group_fallback.enabled", fallback.Enabled)
+	audithelper.Note(ctx, "retry.policy_group_fallback.scope", fallback.Scope)
+	audithelper.Note(ctx, "retry.max_llm_backend_request_count", policy.Conditions.MaxLLMBackendRequestCount)
+}
+
+// deadlineExceeded reports whether the next attempt would start after the deadline of the policy.
+func deadlineExceeded(ctx context.Context, policy modelretry.Config, startedAt time.Time, attempt int) bool {
+	if policy.CanStartAttemptAfter(startedAt, time.Now(), policy.NextBackoff(attempt)) {
+		return false
+	}
+	audithelper.Note(ctx, "retry.deadline_exceeded", policy.Conditions.Deadline.String())
+	return true
+}
+
+func getCurrentBranchName(ctx context.Context) string {
+	traceVal, ok := ctxhelper.GetPolicyTrace(ctx)
+	if !ok || traceVal == nil {
+		return ""
+	}
+	trace, ok := traceVal.(*policy_group.RouteTrace)
+	if !ok || trace == nil {
+		return ""
+	}
+	return trace.Branch.Name
+}
+
 func getCurrentInstanceID(ctx context.Context) string {
 	model, ok := ctxhelper.GetModel(ctx)
 	if !ok || model == nil {
@@ -28,9 +28,11 @@ import (
 
 	"github.com/erda-project/erda-infra/base/logs/logrusx"
 	modelpb "github.com/erda-project/erda-proto-go/apps/aiproxy/model/pb"
+	policypb "github.com/erda-project/erda-proto-go/apps/aiproxy/policy_group/pb"
 	audittypes "github.com/erda-project/erda/internal/apps/ai-proxy/common/audit/types"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/common/ctxhelper"
 	httperror "github.com/erda-project/erda/internal/apps/ai-proxy/route/http_error"
+	"github.com/erda-project/erda/internal/apps/ai-proxy/route/policy_group"
 	modelretry "github.com/erda-project/erda/internal/apps/ai-proxy/route/reverse_proxy/model_retry"
 	"github.com/erda-project/erda/internal/apps/ai-proxy/vars"
 )
@@ -607,3 +609,267 @@ func TestDisabledModelRetryDoesNotWriteRetryMetadata(t *testing.T) {
 		}
 	}
 }
+
+func TestServeWithTransparentRetry_PolicyGroupFallbackToNextBranch(t *testing.T) {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	ctxhelper.PutLogger(ctx, logrusx.New())
+	ctxhelper.PutLoggerBase(ctx, logrusx.New())
+	ctxhelper.PutReverseProxyRequestBodyBytes(ctx, []byte("{}"))
+	ctxhelper.PutRequestID(ctx, "req-branch")
+	ctxhelper.PutGeneratedCallID(ctx, "call-branch")
+
+	req := httptest.NewRequest(http.MethodPost, "http://ai-proxy.test/v1/chat/completions", strings.NewReader("{}"))
+	req.Header.Set(vars.XRequestId, "req-branch")
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	tw := NewTrackedResponseWriter(rec)
+
+	targetURL, err := url.Parse("http://upstream.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := 0
+	var selectedBranches []string
+	// retry is disabled globally, the policy group enables it
+	policy := modelretry.Config{
+		Conditions: modelretry.Conditions{
+			MaxLLMBackendRequestCount: 1,
+			Backoff:                   modelretry.Backoff{Base: 0},
+		},
+	}
+	options := []OptionFunc{
+		func(_ context.Context, proxy *httputil.ReverseProxy) {
+			proxy.Rewrite = func(pr *httputil.ProxyRequest) {
+				pr.SetURL(targetURL)
+				modelretry.PutPolicyGroupFallback(pr.In.Context(), &policypb.PolicyFallback{
+					Enabled:               true,
+					Scope:                 modelretry.FallbackScopeBranch,
+					MaxAttempts:           3,
+					RetryableHttpStatuses: []int32{http.StatusServiceUnavailable},
+				})
+				branch, modelID := "primary", "m-a"
+				if excluded, ok := modelretry.GetExcludedBranchNames(pr.In.Context()); ok {
+					if _, hit := excluded[branch]; hit {
+						branch, modelID = "secondary", "m-b"
+					}
+				}
+				selectedBranches = append(selectedBranches, branch)
+				ctxhelper.PutModel(pr.In.Context(), &modelpb.Model{Id: modelID})
+				ctxhelper.PutPolicyTrace(pr.In.Context(), &policy_group.RouteTrace{Branch: policy_group.RouteTraceBranch{Name: branch}})
+			}
+			proxy.ModifyResponse = nil
+			proxy.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts == 1 {
+					return nil, httperror.NewHTTPError(req.Context(), http.StatusServiceUnavailable, "upstream unavailable")
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     make(http.Header),
+					Body:       io.NopCloser(strings.NewReader("ok")),
+					Request:    req,
+				}, nil
+			})
+		},
+	}
+
+	ServeWithRetry(ctx, tw, req, nil, nil, options, policy)
+
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if len(selectedBranches) != 2 || selectedBranches[0] != "primary" || selectedBranches[1] != "secondary" {
+		t.Fatalf("expected retry to fall back to the next branch, got: %v", selectedBranches)
+	}
+}
+
+func TestServeWithTransparentRetry_PolicyGroupFallbackDeadline(t *testing.T) {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	ctxhelper.PutLogger(ctx, logrusx.New())
+	ctxhelper.PutLoggerBase(ctx, logrusx.New())
+	ctxhelper.PutReverseProxyRequestBodyBytes(ctx, []byte("{}"))
+	ctxhelper.PutRequestID(ctx, "req-deadline")
+	ctxhelper.PutGeneratedCallID(ctx, "call-deadline")
+
+	req := httptest.NewRequest(http.MethodPost, "http://ai-proxy.test/v1/chat/completions", strings.NewReader("{}"))
+	req.Header.Set(vars.XRequestId, "req-deadline")
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	tw := NewTrackedResponseWriter(rec)
+
+	targetURL, err := url.Parse("http://upstream.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := 0
+	policy := modelretry.Config{
+		Enabled: true,
+		Conditions: modelretry.Conditions{
+			MaxLLMBackendRequestCount: 3,
+			Backoff:                   modelretry.Backoff{Base: time.Second, Max: time.Second},
+		},
+	}
+	options := []OptionFunc{
+		func(_ context.Context, proxy *httputil.ReverseProxy) {
+			proxy.Rewrite = func(pr *httputil.ProxyRequest) {
+				pr.SetURL(targetURL)
+				// the backoff of the next attempt already exceeds the deadline
+				modelretry.PutPolicyGroupFallback(pr.In.Context(), &policypb.PolicyFallback{
+					Enabled:  true,
+					Scope:    modelretry.FallbackScopeInstance,
+					Deadline: "500ms",
+				})
+				ctxhelper.PutModel(pr.In.Context(), &modelpb.Model{Id: "m-a"})
+			}
+			proxy.ModifyResponse = nil
+			proxy.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return nil, errors.New("dial tcp 127.0.0.1:80: connect: connection refused")
+			})
+		},
+	}
+
+	ServeWithRetry(ctx, tw, req, nil, nil, options, policy)
+
+	if attempts != 1 {
+		t.Fatalf("expected no retry after the deadline, got %d attempts", attempts)
+	}
+	if rec.Code == http.StatusOK {
+		t.Fatal("expected the failure to be returned")
+	}
+}
+
+func TestServeWithTransparentRetry_DeadlineBoundsInFlightAttempt(t *testing.T) {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	ctxhelper.PutLogger(ctx, logrusx.New())
+	ctxhelper.PutLoggerBase(ctx, logrusx.New())
+	ctxhelper.PutReverseProxyRequestBodyBytes(ctx, []byte("{}"))
+	ctxhelper.PutRequestID(ctx, "req-inflight")
+	ctxhelper.PutGeneratedCallID(ctx, "call-inflight")
+
+	req := httptest.NewRequest(http.MethodPost, "http://ai-proxy.test/v1/chat/completions", strings.NewReader("{}"))
+	req.Header.Set(vars.XRequestId, "req-inflight")
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	tw := NewTrackedResponseWriter(rec)
+
+	targetURL, err := url.Parse("http://upstream.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := 0
+	policy := modelretry.Config{
+		Enabled: true,
+		Conditions: modelretry.Conditions{
+			MaxLLMBackendRequestCount: 3,
+			Deadline:                  100 * time.Millisecond,
+		},
+	}
+	options := []OptionFunc{
+		func(_ context.Context, proxy *httputil.ReverseProxy) {
+			proxy.Rewrite = func(pr *httputil.ProxyRequest) {
+				pr.SetURL(targetURL)
+				ctxhelper.PutModel(pr.In.Context(), &modelpb.Model{Id: "m-a"})
+			}
+			proxy.ModifyResponse = nil
+			proxy.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				// a hanging upstream
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			})
+		},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ServeWithRetry(ctx, tw, req, nil, nil, options, policy)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the in-flight attempt to be canceled at the deadline")
+	}
+	if attempts != 1 {
+		t.Fatalf("expected no retry after the deadline, got %d attempts", attempts)
+	}
+	if rec.Code == http.StatusOK {
+		t.Fatal("expected the failure to be returned")
+	}
+}
+
+func TestServeWithTransparentRetry_DeadlineDoesNotCutOffStreamingResponse(t *testing.T) {
+	ctx := ctxhelper.InitCtxMapIfNeed(context.Background())
+	ctxhelper.PutLogger(ctx, logrusx.New())
+	ctxhelper.PutLoggerBase(ctx, logrusx.New())
+	ctxhelper.PutReverseProxyRequestBodyBytes(ctx, []byte("{}"))
+	ctxhelper.PutRequestID(ctx, "req-stream")
+	ctxhelper.PutGeneratedCallID(ctx, "call-stream")
+
+	req := httptest.NewRequest(http.MethodPost, "http://ai-proxy.test/v1/chat/completions", strings.NewReader("{}"))
+	req.Header.Set(vars.XRequestId, "req-stream")
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	tw := NewTrackedResponseWriter(rec)
+
+	targetURL, err := url.Parse("http://upstream.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := modelretry.Config{
+		Enabled: true,
+		Conditions: modelretry.Conditions{
+			MaxLLMBackendRequestCount: 3,
+			Deadline:                  100 * time.Millisecond,
+		},
+	}
+	options := []OptionFunc{
+		func(_ context.Context, proxy *httputil.ReverseProxy) {
+			proxy.Rewrite = func(pr *httputil.ProxyRequest) {
+				pr.SetURL(targetURL)
+				ctxhelper.PutModel(pr.In.Context(), &modelpb.Model{Id: "m-a"})
+			}
+			proxy.ModifyResponse = nil
+			proxy.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				pr, pw := io.Pipe()
+				// the body is streamed past the deadline, and is broken if the request is canceled
+				go func() {
+					for i := 0; i < 3; i++ {
+						select {
+						case <-req.Context().Done():
+							_ = pw.CloseWithError(req.Context().Err())
+							return
+						case <-time.After(100 * time.Millisecond):
+						}
+						_, _ = pw.Write([]byte("data: chunk\n\n"))
+					}
+					_, _ = pw.Write([]byte("data: [DONE]\n\n"))
+					_ = pw.Close()
+				}()
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+					Body:       pr,
+					Request:    req,
+				}, nil
+			})
+		},
+	}
+
+	ServeWithRetry(ctx, tw, req, nil, nil, options, policy)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.HasSuffix(rec.Body.String(), "data: [DONE]\n\n") {
+		t.Fatalf("expected the streaming response to complete, got %q", rec.Body.String())
+	}
+}
@@ -46,6 +46,7 @@ const (
 	XAIProxyRequestBodyTransform     = "X-AI-Proxy-Request-Body-Transform"
 	XAIProxyRequestThinkingTransform = "X-AI-Proxy-Request-Thinking-Transform"
 	XAIProxyPolicyGroupTrace         = "X-AI-Proxy-Policy-Group-Trace"
+	XAIProxyCache                    = "X-AI-Proxy-Cache"
 
 	XAIProxyModelHealthMeta          = "X-AI-Proxy-Model-Health-Meta"
 	XAIProxyModelHealthProbe         = "X-AI-Proxy-Model-Health-Probe"
@@ -23,6 +23,8 @@ const (
 	RequestPathPrefixV1MultimodalEmbedding = "/v1/multimodal/embeddings"
 	RequestPathPrefixV1Responses           = "/v1/responses"
 	RequestPathPrefixV1Files               = "/v1/files"
+	RequestPathPrefixV1FilesByID           = "/v1/files/{file_id}"
+	RequestPathPrefixV1FilesContent        = "/v1/files/{file_id}/content"
 	RequestPathPrefixV1Batches             = "/v1/batches"
 	RequestPathPrefixV1BatchesByID         = "/v1/batches/{batch_id}"
 	RequestPathPrefixV1BatchesCancel       = "/v1/batches/{batch_id}/cancel"
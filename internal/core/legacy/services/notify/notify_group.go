@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/jinzhu/gorm"
 
@@ -37,6 +38,15 @@ type NotifyGroup struct {
 	db          *dao.DBClient
 	bdl         *bundle.Bundle
 	userService userpb.UserServiceServer
+	onCall      OnCallResolver
+}
+
+// OnCallResolver 查询值班表当前的值班用户
+type OnCallResolver interface {
+	// OnCallUsers 查询值班用户，不属于该组织和范围的值班表会被忽略
+	OnCallUsers(orgID int64, scopeType, scopeID string, scheduleIDs []string, at time.Time) ([]string, error)
+	// CheckOnCallSchedules 检查值班表存在且属于该组织和范围
+	CheckOnCallSchedules(orgID int64, scopeType, scopeID string, scheduleIDs []string) error
 }
 
 type Option func(*NotifyGroup)
@@ -62,6 +72,13 @@ func WithUserService(userService userpb.UserServiceServer) Option {
 	}
 }
 
+// WithOnCallResolver 配置值班用户查询
+func WithOnCallResolver(resolver OnCallResolver) Option {
+	return func(o *NotifyGroup) {
+		o.onCall = resolver
+	}
+}
+
 func (o *NotifyGroup) Create(locale *i18n.LocaleResource, createReq *apistructs.CreateNotifyGroupRequest) (int64, error) {
 	exist, err := o.db.CheckNotifyGroupNameExist(createReq.ScopeType, createReq.ScopeID, createReq.Name)
 	if err != nil {
@@ -74,6 +91,10 @@ func (o *NotifyGroup) Create(locale *i18n.LocaleResource, createReq *apistructs.
 	if err != nil {
 		return 0, err
 	}
+	err = o.checkOnCallTargets(createReq.OrgID, createReq.ScopeType, createReq.ScopeID, createReq.Targets)
+	if err != nil {
+		return 0, err
+	}
 	return o.db.CreateNotifyGroup(createReq)
 }
 
@@ -82,9 +103,37 @@ func (o *NotifyGroup) Update(updateReq *apistructs.UpdateNotifyGroupRequest) err
 	if err != nil {
 		return err
 	}
+	group, err := o.db.GetNotifyGroupByID(updateReq.ID, updateReq.OrgID)
+	if err != nil {
+		return err
+	}
+	err = o.checkOnCallTargets(updateReq.OrgID, group.ScopeType, group.ScopeID, updateReq.Targets)
+	if err != nil {
+		return err
+	}
 	return o.db.UpdateNotifyGroup(updateReq)
 }
 
+// checkOnCallTargets 检查值班表通知对象属于通知组的组织和范围
+func (o *NotifyGroup) checkOnCallTargets(orgID int64, scopeType, scopeID string, targets []apistructs.NotifyTarget) error {
+	var scheduleIDs []string
+	for _, target := range targets {
+		if target.Type != apistructs.OnCallNotifyTarget {
+			continue
+		}
+		for _, value := range target.Values {
+			scheduleIDs = append(scheduleIDs, value.Receiver)
+		}
+	}
+	if len(scheduleIDs) == 0 {
+		return nil
+	}
+	if o.onCall == nil {
+		return errors.New("oncall notify target is not supported")
+	}
+	return o.onCall.CheckOnCallSchedules(orgID, scopeType, scopeID, scheduleIDs)
+}
+
 func (o *NotifyGroup) Get(id int64, orgID int64) (*apistructs.NotifyGroup, error) {
 	return o.db.GetNotifyGroupByID(id, orgID)
 }
@@ -148,6 +197,26 @@ func (o *NotifyGroup) GetDetail(id int64, orgID int64) (*apistructs.NotifyGroupD
 			for _, webhookUrl := range target.Values {
 				result.WebHookList = append(result.WebHookList, webhookUrl.Receiver)
 			}
+		case apistructs.OnCallNotifyTarget:
+			if o.onCall == nil {
+				continue
+			}
+			var scheduleIDs []string
+			for _, value := range target.Values {
+				scheduleIDs = append(scheduleIDs, value.Receiver)
+			}
+			userIDs, err := o.onCall.OnCallUsers(orgID, group.ScopeType, group.ScopeID, scheduleIDs, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			if len(userIDs) == 0 {
+				continue
+			}
+			notifyUsers, err := o.getNotifyUsersByIDs(userIDs)
+			if err != nil {
+				return nil, err
+			}
+			result.Users = append(result.Users, notifyUsers...)
 		case apistructs.RoleNotifyTarget:
 			var roles []string
 			for _, r := range target.Values {
@@ -81,7 +81,7 @@ func New(dingtalk interfaces.DingTalkApiClientFactory, messenger pb.NotifyServic
 		return nil, err
 	}
 	httpS := httpsubscriber.New()
-	bundleS := bundle.New(bundle.WithErdaServer())
+	bundleS := bundle.New(bundle.WithErdaServer(), bundle.WithMonitor())
 	dingdingS := dingdingsubscriber.New(conf.Proxy(), messenger)
 	dingdingWorknoticeS := dingdingworknoticesubscriber.New(conf.Proxy(), messenger)
 	mboxS := mbox.New(bundle.New(bundle.WithErdaServer()), messenger)
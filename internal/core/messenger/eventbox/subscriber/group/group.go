@@ -63,6 +63,9 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 	if err != nil {
 		return []error{err}
 	}
+	if d.muted(groupID, &groupNotifyContent) {
+		return errs
+	}
 	groupDetail, err := d.bundle.GetNotifyGroupDetail(groupID, groupNotifyContent.OrgID, conf.BundleUserID())
 	if err != nil {
 		return []error{err}
@@ -84,6 +87,8 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 		ClusterName:   groupNotifyContent.ClusterName,
 	}
 
+	// whether the notification is sent to any receiver of the group
+	routed := false
 	for _, channel := range groupNotifyContent.Channels {
 		chr := *createHistoryRequest
 		chr.NotifySource.Params = channel.Params
@@ -131,6 +136,7 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 			}
 			if len(emails) > 0 {
 				d.routeMessage(msg)
+				routed = true
 			}
 		} else if channel.Name == "sms" {
 			mobiles := []string{}
@@ -151,6 +157,7 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 			}
 			if len(mobiles) > 0 {
 				d.routeMessage(msg)
+				routed = true
 			}
 		} else if channel.Name == "vms" {
 			mobiles := []string{}
@@ -171,6 +178,7 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 			}
 			if len(mobiles) > 0 {
 				d.routeMessage(msg)
+				routed = true
 			}
 		} else if channel.Name == "dingding" {
 			var atMobiles []string
@@ -213,6 +221,7 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 			}
 			if len(groupDetail.DingdingList) > 0 {
 				d.routeMessage(msg)
+				routed = true
 			}
 		} else if channel.Name == "mbox" {
 			userIDs := []string{}
@@ -229,6 +238,7 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 			}
 			if len(userIDs) > 0 {
 				d.routeMessage(msg)
+				routed = true
 			}
 		} else if channel.Name == "webhook" {
 			msg := &types.Message{
@@ -245,6 +255,7 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 			}
 			if len(groupDetail.WebHookList) > 0 {
 				d.routeMessage(msg)
+				routed = true
 			}
 		} else if channel.Name == "dingtalk_work_notice" {
 			mobiles := []string{}
@@ -264,12 +275,59 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 			}
 			if len(mobiles) > 0 {
 				d.routeMessage(msg)
+				routed = true
 			}
 		}
 	}
+	// an alert sent to the group keeps escalating by the escalation policies of the group until it is acknowledged,
+	// and stops escalating on recovery. Notifications of the escalation steps do not escalate again.
+	if labels, ok := alertLabels(&groupNotifyContent); ok && labels["escalation_id"] == "" &&
+		(routed || labels["trigger"] == "recover") {
+		var sender string
+		if msg != nil {
+			sender = msg.Sender
+		}
+		if err := d.bundle.TriggerNotifyEscalation(groupID, groupNotifyContent.OrgID, sender, content); err != nil {
+			logrus.Warnf("failed to trigger escalation of notify group %d: %v", groupID, err)
+		}
+	}
 	return errs
 }
 
+// alertLabels returns the labels of an alert notification, false if the notification is not of an alert
+func alertLabels(content *apistructs.GroupNotifyContent) (map[string]string, bool) {
+	tags := content.NotifyTags
+	if tags["alert_scope"] == nil || tags["alert_scope_id"] == nil {
+		return nil, false
+	}
+	labels := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if v != nil {
+			labels[k] = strutil.String(v)
+		}
+	}
+	return labels, true
+}
+
+// muted tells whether the notification of an alert is muted by the silences, maintenance windows or inhibit rules of monitor.
+// The notification is sent if it can not be decided, it is better to notify too much than to lose an alert.
+func (d *GroupSubscriber) muted(groupID int64, content *apistructs.GroupNotifyContent) bool {
+	labels, ok := alertLabels(content)
+	if !ok {
+		return false
+	}
+	reason, err := d.bundle.MuteAlert(labels)
+	if err != nil {
+		logrus.Warnf("failed to check whether the alert notification of notify group %d is muted: %v", groupID, err)
+		return false
+	}
+	if reason == "" {
+		return false
+	}
+	logrus.Infof("alert notification of notify group %d is muted by %s, alert group id: %s", groupID, reason, labels["group_id"])
+	return true
+}
+
 func (d *GroupSubscriber) Status() interface{} {
 	return nil
 }
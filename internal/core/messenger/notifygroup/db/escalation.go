@@ -0,0 +1,205 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// escalation status
+const (
+	EscalationStatusOpen         = "open"
+	EscalationStatusAcknowledged = "acknowledged"
+	EscalationStatusResolved     = "resolved"
+	EscalationStatusStopped      = "stopped"
+)
+
+// EscalationPolicy escalates the notifications sent to NotifyGroupID through Steps until acknowledged
+type EscalationPolicy struct {
+	ID            string    `gorm:"column:id;primary_key"`
+	OrgID         int64     `gorm:"column:org_id"`
+	ScopeType     string    `gorm:"column:scope_type"`
+	ScopeID       string    `gorm:"column:scope_id"`
+	Name          string    `gorm:"column:name"`
+	NotifyGroupID int64     `gorm:"column:notify_group_id"`
+	Steps         string    `gorm:"column:steps"`
+	Enabled       bool      `gorm:"column:enabled"`
+	Creator       string    `gorm:"column:creator"`
+	CreatedAt     time.Time `gorm:"column:created_at"`
+	UpdatedAt     time.Time `gorm:"column:updated_at"`
+}
+
+func (EscalationPolicy) TableName() string {
+	return "erda_notify_escalation_policy"
+}
+
+// Escalation is a notification being escalated by a policy
+type Escalation struct {
+	ID             string     `gorm:"column:id;primary_key"`
+	OrgID          int64      `gorm:"column:org_id"`
+	PolicyID       string     `gorm:"column:policy_id"`
+	DedupKey       string     `gorm:"column:dedup_key"`
+	Title          string     `gorm:"column:title"`
+	Status         string     `gorm:"column:status"`
+	Step           int        `gorm:"column:step"`
+	NextEscalateAt *time.Time `gorm:"column:next_escalate_at"`
+	Sender         string     `gorm:"column:sender"`
+	Content        string     `gorm:"column:content"`
+	AcknowledgedBy string     `gorm:"column:acknowledged_by"`
+	AcknowledgedAt *time.Time `gorm:"column:acknowledged_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at"`
+}
+
+func (Escalation) TableName() string {
+	return "erda_notify_escalation"
+}
+
+type EscalationPolicyDB struct {
+	*gorm.DB
+}
+
+func (db *EscalationPolicyDB) Create(policy *EscalationPolicy) error {
+	return db.DB.Create(policy).Error
+}
+
+func (db *EscalationPolicyDB) Update(policy *EscalationPolicy) error {
+	return db.Save(policy).Error
+}
+
+func (db *EscalationPolicyDB) GetByID(id string) (*EscalationPolicy, error) {
+	var policy EscalationPolicy
+	err := db.Where("id = ?", id).First(&policy).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (db *EscalationPolicyDB) ListEnabledByNotifyGroup(groupID int64) ([]*EscalationPolicy, error) {
+	var list []*EscalationPolicy
+	err := db.Where("notify_group_id = ? and enabled = ?", groupID, true).Find(&list).Error
+	return list, err
+}
+
+func (db *EscalationPolicyDB) Query(scopeType, scopeID string, pageNo, pageSize int64) ([]*EscalationPolicy, int64, error) {
+	var (
+		list  []*EscalationPolicy
+		total int64
+	)
+	query := db.Model(&EscalationPolicy{}).Where("scope_type = ? and scope_id = ?", scopeType, scopeID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// Delete removes the policy and stops its open escalations
+func (db *EscalationPolicyDB) Delete(id string) error {
+	err := db.Model(&Escalation{}).Where("policy_id = ? and status = ?", id, EscalationStatusOpen).
+		Updates(map[string]interface{}{"status": EscalationStatusStopped, "next_escalate_at": nil}).Error
+	if err != nil {
+		return err
+	}
+	return db.Where("id = ?", id).Delete(&EscalationPolicy{}).Error
+}
+
+type EscalationDB struct {
+	*gorm.DB
+}
+
+func (db *EscalationDB) Create(escalation *Escalation) error {
+	return db.DB.Create(escalation).Error
+}
+
+func (db *EscalationDB) GetByID(id string) (*Escalation, error) {
+	var escalation Escalation
+	err := db.Where("id = ?", id).First(&escalation).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &escalation, nil
+}
+
+// GetOpen returns the open escalation of the policy with the dedup key
+func (db *EscalationDB) GetOpen(policyID, dedupKey string) (*Escalation, error) {
+	var escalation Escalation
+	err := db.Where("policy_id = ? and dedup_key = ? and status = ?", policyID, dedupKey, EscalationStatusOpen).
+		First(&escalation).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &escalation, nil
+}
+
+// ListDue returns the open escalations whose next step is due at now
+func (db *EscalationDB) ListDue(now time.Time, limit int) ([]*Escalation, error) {
+	var list []*Escalation
+	err := db.Where("status = ? and next_escalate_at <= ?", EscalationStatusOpen, now).
+		Order("next_escalate_at asc").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+func (db *EscalationDB) Query(policyID, status string, pageNo, pageSize int64) ([]*Escalation, int64, error) {
+	var (
+		list  []*Escalation
+		total int64
+	)
+	query := db.Model(&Escalation{}).Where("policy_id = ?", policyID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// Advance moves an open escalation from step to step+1, it returns false if the escalation
+// has been advanced, acknowledged or resolved concurrently
+func (db *EscalationDB) Advance(id string, step int, next *time.Time) (bool, error) {
+	result := db.Model(&Escalation{}).Where("id = ? and status = ? and step = ?", id, EscalationStatusOpen, step).
+		Updates(map[string]interface{}{"step": step + 1, "next_escalate_at": next})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ClearNext stops scheduling the next step of an escalation which has no more step, the escalation stays open
+func (db *EscalationDB) ClearNext(id string) error {
+	return db.Model(&Escalation{}).Where("id = ?", id).Update("next_escalate_at", nil).Error
+}
+
+// Close changes an open escalation to status, it returns false if the escalation is not open
+func (db *EscalationDB) Close(id, status, userID string, now time.Time) (bool, error) {
+	fields := map[string]interface{}{"status": status, "next_escalate_at": nil}
+	if status == EscalationStatusAcknowledged {
+		fields["acknowledged_by"] = userID
+		fields["acknowledged_at"] = now
+	}
+	result := db.Model(&Escalation{}).Where("id = ? and status = ?", id, EscalationStatusOpen).Updates(fields)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// OnCallSchedule is a rotation of users taking turns to be on call
+type OnCallSchedule struct {
+	ID        string    `gorm:"column:id;primary_key"`
+	OrgID     int64     `gorm:"column:org_id"`
+	ScopeType string    `gorm:"column:scope_type"`
+	ScopeID   string    `gorm:"column:scope_id"`
+	Name      string    `gorm:"column:name"`
+	Timezone  string    `gorm:"column:timezone"`
+	Users     string    `gorm:"column:users"`
+	StartTime time.Time `gorm:"column:start_time"`
+	ShiftDays int       `gorm:"column:shift_days"`
+	Creator   string    `gorm:"column:creator"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (OnCallSchedule) TableName() string {
+	return "erda_notify_oncall_schedule"
+}
+
+// OnCallOverride puts another user on call for a period
+type OnCallOverride struct {
+	ID         string    `gorm:"column:id;primary_key"`
+	ScheduleID string    `gorm:"column:schedule_id"`
+	UserID     string    `gorm:"column:user_id"`
+	StartTime  time.Time `gorm:"column:start_time"`
+	EndTime    time.Time `gorm:"column:end_time"`
+	Creator    string    `gorm:"column:creator"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+	UpdatedAt  time.Time `gorm:"column:updated_at"`
+}
+
+func (OnCallOverride) TableName() string {
+	return "erda_notify_oncall_override"
+}
+
+type OnCallScheduleDB struct {
+	*gorm.DB
+}
+
+func (db *OnCallScheduleDB) Create(schedule *OnCallSchedule) error {
+	return db.DB.Create(schedule).Error
+}
+
+func (db *OnCallScheduleDB) Update(schedule *OnCallSchedule) error {
+	return db.Save(schedule).Error
+}
+
+func (db *OnCallScheduleDB) GetByID(id string) (*OnCallSchedule, error) {
+	var schedule OnCallSchedule
+	err := db.Where("id = ?", id).First(&schedule).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (db *OnCallScheduleDB) ListByIDs(ids []string) ([]*OnCallSchedule, error) {
+	var list []*OnCallSchedule
+	err := db.Where("id in (?)", ids).Find(&list).Error
+	return list, err
+}
+
+func (db *OnCallScheduleDB) Query(scopeType, scopeID string, pageNo, pageSize int64) ([]*OnCallSchedule, int64, error) {
+	var (
+		list  []*OnCallSchedule
+		total int64
+	)
+	query := db.Model(&OnCallSchedule{}).Where("scope_type = ? and scope_id = ?", scopeType, scopeID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// Delete removes the schedule with its overrides
+func (db *OnCallScheduleDB) Delete(id string) error {
+	if err := db.Where("schedule_id = ?", id).Delete(&OnCallOverride{}).Error; err != nil {
+		return err
+	}
+	return db.Where("id = ?", id).Delete(&OnCallSchedule{}).Error
+}
+
+type OnCallOverrideDB struct {
+	*gorm.DB
+}
+
+func (db *OnCallOverrideDB) Create(override *OnCallOverride) error {
+	return db.DB.Create(override).Error
+}
+
+func (db *OnCallOverrideDB) GetByID(id string) (*OnCallOverride, error) {
+	var override OnCallOverride
+	err := db.Where("id = ?", id).First(&override).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// ListBySchedules returns the overrides of the schedules ending after since, in creation order
+func (db *OnCallOverrideDB) ListBySchedules(scheduleIDs []string, since time.Time) ([]*OnCallOverride, error) {
+	var list []*OnCallOverride
+	err := db.Where("schedule_id in (?) and end_time > ?", scheduleIDs, since).
+		Order("created_at asc").Find(&list).Error
+	return list, err
+}
+
+func (db *OnCallOverrideDB) Delete(id string) error {
+	return db.Where("id = ?", id).Delete(&OnCallOverride{}).Error
+}
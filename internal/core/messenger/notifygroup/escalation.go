@@ -0,0 +1,475 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifygroup
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-proto-go/core/messenger/notifygroup/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/core/messenger/notifygroup/db"
+	"github.com/erda-project/erda/internal/core/messenger/notifygroup/oncall"
+	"github.com/erda-project/erda/pkg/common/apis"
+	"github.com/erda-project/erda/pkg/common/errors"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+const (
+	escalationIDTag   = "escalation_id"
+	escalationStepTag = "escalation_step"
+
+	escalationBatchSize = 100
+)
+
+func (s *onCallService) CreateEscalationPolicy(ctx context.Context, request *pb.CreateEscalationPolicyRequest) (*pb.CreateEscalationPolicyResponse, error) {
+	orgID, err := s.checkScope(ctx, request.ScopeType, request.ScopeId, apistructs.CreateAction)
+	if err != nil {
+		return nil, err
+	}
+	policy := &db.EscalationPolicy{
+		ID:        uuid.New(),
+		OrgID:     orgID,
+		ScopeType: request.ScopeType,
+		ScopeID:   request.ScopeId,
+		Creator:   apis.GetUserID(ctx),
+	}
+	if err := setEscalationPolicy(policy, request.Name, request.NotifyGroupId, request.Steps, request.Enabled); err != nil {
+		return nil, err
+	}
+	if err := s.checkNotifyGroups(policy); err != nil {
+		return nil, err
+	}
+	if err := s.db.EscalationPolicyDB.Create(policy); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	data, err := toPbPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateEscalationPolicyResponse{Data: data}, nil
+}
+
+func (s *onCallService) UpdateEscalationPolicy(ctx context.Context, request *pb.UpdateEscalationPolicyRequest) (*pb.UpdateEscalationPolicyResponse, error) {
+	policy, err := s.getPolicy(ctx, request.Id, apistructs.UpdateAction)
+	if err != nil {
+		return nil, err
+	}
+	if err := setEscalationPolicy(policy, request.Name, request.NotifyGroupId, request.Steps, request.Enabled); err != nil {
+		return nil, err
+	}
+	if err := s.checkNotifyGroups(policy); err != nil {
+		return nil, err
+	}
+	if err := s.db.EscalationPolicyDB.Update(policy); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	data, err := toPbPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateEscalationPolicyResponse{Data: data}, nil
+}
+
+func (s *onCallService) GetEscalationPolicy(ctx context.Context, request *pb.GetEscalationPolicyRequest) (*pb.GetEscalationPolicyResponse, error) {
+	policy, err := s.getPolicy(ctx, request.Id, apistructs.GetAction)
+	if err != nil {
+		return nil, err
+	}
+	data, err := toPbPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetEscalationPolicyResponse{Data: data}, nil
+}
+
+func (s *onCallService) QueryEscalationPolicies(ctx context.Context, request *pb.QueryEscalationPoliciesRequest) (*pb.QueryEscalationPoliciesResponse, error) {
+	if _, err := s.checkScope(ctx, request.ScopeType, request.ScopeId, apistructs.ListAction); err != nil {
+		return nil, err
+	}
+	pageNo, pageSize := normalizePage(request.PageNo, request.PageSize)
+	list, total, err := s.db.EscalationPolicyDB.Query(request.ScopeType, request.ScopeId, pageNo, pageSize)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	result := &pb.QueryEscalationPoliciesResponse{Total: total}
+	for _, policy := range list {
+		data, err := toPbPolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		result.List = append(result.List, data)
+	}
+	return result, nil
+}
+
+func (s *onCallService) DeleteEscalationPolicy(ctx context.Context, request *pb.DeleteEscalationPolicyRequest) (*pb.DeleteEscalationPolicyResponse, error) {
+	if _, err := s.getPolicy(ctx, request.Id, apistructs.DeleteAction); err != nil {
+		return nil, err
+	}
+	if err := s.db.EscalationPolicyDB.Delete(request.Id); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.DeleteEscalationPolicyResponse{Data: true}, nil
+}
+
+func (s *onCallService) QueryEscalations(ctx context.Context, request *pb.QueryEscalationsRequest) (*pb.QueryEscalationsResponse, error) {
+	if _, err := s.getPolicy(ctx, request.PolicyId, apistructs.ListAction); err != nil {
+		return nil, err
+	}
+	pageNo, pageSize := normalizePage(request.PageNo, request.PageSize)
+	list, total, err := s.db.EscalationDB.Query(request.PolicyId, request.Status, pageNo, pageSize)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	result := &pb.QueryEscalationsResponse{Total: total}
+	for _, e := range list {
+		result.List = append(result.List, toPbEscalation(e))
+	}
+	return result, nil
+}
+
+func (s *onCallService) AcknowledgeEscalation(ctx context.Context, request *pb.AcknowledgeEscalationRequest) (*pb.AcknowledgeEscalationResponse, error) {
+	if request.Id == "" {
+		return nil, errors.NewMissingParameterError("id")
+	}
+	e, err := s.db.EscalationDB.GetByID(request.Id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if e == nil {
+		return nil, errors.NewNotFoundError("escalation")
+	}
+	if _, err := s.getPolicy(ctx, e.PolicyID, apistructs.GetAction); err != nil {
+		return nil, err
+	}
+	if e.Status != db.EscalationStatusOpen {
+		return nil, errors.NewInvalidParameterError("id", fmt.Sprintf("escalation is already %s", e.Status))
+	}
+	if _, err := s.db.EscalationDB.Close(e.ID, db.EscalationStatusAcknowledged, apis.GetUserID(ctx), time.Now()); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	e, err = s.db.EscalationDB.GetByID(request.Id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.AcknowledgeEscalationResponse{Data: toPbEscalation(e)}, nil
+}
+
+// TriggerEscalation is called by eventbox after a notification has been sent to the notify group,
+// it opens an escalation for each enabled policy of the group, or resolves them on recovery
+func (s *onCallService) TriggerEscalation(ctx context.Context, request *pb.TriggerEscalationRequest) (*pb.TriggerEscalationResponse, error) {
+	if !apis.IsInternalClient(ctx) {
+		return nil, errors.NewPermissionError(apistructs.NotifyResource, apistructs.CreateAction, "internal only")
+	}
+	var content apistructs.GroupNotifyContent
+	if err := json.Unmarshal([]byte(request.Content), &content); err != nil {
+		return nil, errors.NewInvalidParameterError("content", err.Error())
+	}
+	if _, ok := content.NotifyTags[escalationIDTag]; ok {
+		// notifications sent by escalation steps do not escalate again
+		return &pb.TriggerEscalationResponse{}, nil
+	}
+	policies, err := s.db.EscalationPolicyDB.ListEnabledByNotifyGroup(request.NotifyGroupId)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	key := dedupKey(&content)
+	recovered := fmt.Sprint(content.NotifyTags["trigger"]) == "recover"
+	now := time.Now()
+	result := &pb.TriggerEscalationResponse{}
+	for _, policy := range policies {
+		open, err := s.db.EscalationDB.GetOpen(policy.ID, key)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		if recovered {
+			if open != nil {
+				if _, err := s.db.EscalationDB.Close(open.ID, db.EscalationStatusResolved, "", now); err != nil {
+					return nil, errors.NewDatabaseError(err)
+				}
+				result.Data = append(result.Data, open.ID)
+			}
+			continue
+		}
+		if open != nil {
+			// the alert fires again before being acknowledged, it keeps escalating as before
+			result.Data = append(result.Data, open.ID)
+			continue
+		}
+		steps, err := parseSteps(policy.Steps)
+		if err != nil {
+			s.log.Warnf("invalid steps of escalation policy %s: %v", policy.ID, err)
+			continue
+		}
+		e := &db.Escalation{
+			ID:       uuid.New(),
+			OrgID:    policy.OrgID,
+			PolicyID: policy.ID,
+			DedupKey: key,
+			Title:    escalationTitle(&content),
+			Status:   db.EscalationStatusOpen,
+			Sender:   request.Sender,
+			Content:  request.Content,
+		}
+		if next, ok := oncall.NextEscalation(steps, 0, now); ok {
+			e.NextEscalateAt = &next
+		}
+		if err := s.db.EscalationDB.Create(e); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		result.Data = append(result.Data, e.ID)
+	}
+	return result, nil
+}
+
+// escalateDue notifies the next step of the escalations which are not acknowledged in time
+func (s *onCallService) escalateDue(now time.Time) {
+	list, err := s.db.EscalationDB.ListDue(now, escalationBatchSize)
+	if err != nil {
+		s.log.Errorf("failed to list due escalations: %v", err)
+		return
+	}
+	for _, e := range list {
+		if err := s.escalate(e, now); err != nil {
+			s.log.Errorf("failed to escalate %s: %v", e.ID, err)
+		}
+	}
+}
+
+func (s *onCallService) escalate(e *db.Escalation, now time.Time) error {
+	policy, err := s.db.EscalationPolicyDB.GetByID(e.PolicyID)
+	if err != nil {
+		return err
+	}
+	if policy == nil || !policy.Enabled {
+		_, err := s.db.EscalationDB.Close(e.ID, db.EscalationStatusStopped, "", now)
+		return err
+	}
+	steps, err := parseSteps(policy.Steps)
+	if err != nil {
+		_, cerr := s.db.EscalationDB.Close(e.ID, db.EscalationStatusStopped, "", now)
+		if cerr != nil {
+			return cerr
+		}
+		return err
+	}
+	if e.Step >= len(steps) {
+		// the steps have been shortened since the last escalation
+		return s.db.EscalationDB.ClearNext(e.ID)
+	}
+	var next *time.Time
+	if t, ok := oncall.NextEscalation(steps, e.Step+1, now); ok {
+		next = &t
+	}
+	// the step is claimed before sending, so that other instances will not send it again
+	ok, err := s.db.EscalationDB.Advance(e.ID, e.Step, next)
+	if err != nil || !ok {
+		return err
+	}
+	var content apistructs.GroupNotifyContent
+	if err := json.Unmarshal([]byte(e.Content), &content); err != nil {
+		return err
+	}
+	if content.NotifyTags == nil {
+		content.NotifyTags = make(map[string]interface{})
+	}
+	content.NotifyTags[escalationIDTag] = e.ID
+	content.NotifyTags[escalationStepTag] = e.Step + 2
+	var errs []string
+	for _, groupID := range steps[e.Step].NotifyGroupIDs {
+		err := s.bdl.CreateEventNotify(&apistructs.EventBoxRequest{
+			Sender:  e.Sender,
+			Content: content,
+			Labels: map[string]interface{}{
+				"GROUP": groupID,
+			},
+		})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("notify group %d: %v", groupID, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *onCallService) getPolicy(ctx context.Context, id, action string) (*db.EscalationPolicy, error) {
+	if id == "" {
+		return nil, errors.NewMissingParameterError("id")
+	}
+	policy, err := s.db.EscalationPolicyDB.GetByID(id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if policy == nil {
+		return nil, errors.NewNotFoundError("escalation policy")
+	}
+	if _, err := s.checkScope(ctx, policy.ScopeType, policy.ScopeID, action); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// checkNotifyGroups checks the notify groups of the policy and its steps belong to the scope of the policy,
+// so that an escalation never notifies the groups of another scope or org.
+func (s *onCallService) checkNotifyGroups(policy *db.EscalationPolicy) error {
+	steps, err := parseSteps(policy.Steps)
+	if err != nil {
+		return errors.NewInternalServerError(err)
+	}
+	groupIDs := []int64{policy.NotifyGroupID}
+	for _, step := range steps {
+		groupIDs = append(groupIDs, step.NotifyGroupIDs...)
+	}
+	checked := make(map[int64]bool, len(groupIDs))
+	for _, id := range groupIDs {
+		if checked[id] {
+			continue
+		}
+		checked[id] = true
+		group, err := s.groups.NotifyGroup.Get(id, policy.OrgID)
+		if err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return errors.NewInvalidParameterError("notifyGroupId", fmt.Sprintf("notify group %d not found", id))
+			}
+			return errors.NewDatabaseError(err)
+		}
+		if group.ScopeType != policy.ScopeType || group.ScopeID != policy.ScopeID {
+			return errors.NewInvalidParameterError("notifyGroupId", fmt.Sprintf("notify group %d is not in the scope of the policy", id))
+		}
+	}
+	return nil
+}
+
+func setEscalationPolicy(policy *db.EscalationPolicy, name string, groupID int64, pbSteps []*pb.EscalationStep, enabled bool) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.NewInvalidParameterError("name", "name is empty")
+	}
+	if utf8.RuneCountInString(name) > 50 {
+		return errors.NewInvalidParameterError("name", "name is too long")
+	}
+	if groupID <= 0 {
+		return errors.NewMissingParameterError("notifyGroupId")
+	}
+	steps := make([]*oncall.Step, 0, len(pbSteps))
+	for _, step := range pbSteps {
+		steps = append(steps, &oncall.Step{DelayMinutes: step.DelayMinutes, NotifyGroupIDs: step.NotifyGroupIds})
+	}
+	if err := oncall.ValidateSteps(steps); err != nil {
+		return errors.NewInvalidParameterError("steps", err.Error())
+	}
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return errors.NewInternalServerError(err)
+	}
+	policy.Name = name
+	policy.NotifyGroupID = groupID
+	policy.Steps = string(data)
+	policy.Enabled = enabled
+	return nil
+}
+
+func parseSteps(data string) ([]*oncall.Step, error) {
+	var steps []*oncall.Step
+	if err := json.Unmarshal([]byte(data), &steps); err != nil {
+		return nil, err
+	}
+	return steps, oncall.ValidateSteps(steps)
+}
+
+// dedupKey identifies the alert of a notification, so that repeated notifications share an escalation
+func dedupKey(content *apistructs.GroupNotifyContent) string {
+	parts := []string{content.SourceType, content.SourceID, content.NotifyName}
+	for _, tag := range []string{"group_id", "alert_group_id", "alertId"} {
+		if v, ok := content.NotifyTags[tag]; ok {
+			parts = append(parts, fmt.Sprint(v))
+			break
+		}
+	}
+	sum := md5.Sum([]byte(strings.Join(parts, "/")))
+	return hex.EncodeToString(sum[:])
+}
+
+func escalationTitle(content *apistructs.GroupNotifyContent) string {
+	for _, channel := range content.Channels {
+		if title := channel.Params["title"]; title != "" {
+			return truncate(title, 200)
+		}
+	}
+	if content.NotifyItemDisplayName != "" {
+		return truncate(content.NotifyItemDisplayName, 200)
+	}
+	return truncate(content.NotifyName, 200)
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func toPbPolicy(policy *db.EscalationPolicy) (*pb.EscalationPolicy, error) {
+	var steps []*oncall.Step
+	if err := json.Unmarshal([]byte(policy.Steps), &steps); err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	data := &pb.EscalationPolicy{
+		Id:            policy.ID,
+		Name:          policy.Name,
+		ScopeType:     policy.ScopeType,
+		ScopeId:       policy.ScopeID,
+		NotifyGroupId: policy.NotifyGroupID,
+		Enabled:       policy.Enabled,
+		Creator:       policy.Creator,
+		CreatedAt:     timestamppb.New(policy.CreatedAt),
+		UpdatedAt:     timestamppb.New(policy.UpdatedAt),
+	}
+	for _, step := range steps {
+		data.Steps = append(data.Steps, &pb.EscalationStep{DelayMinutes: step.DelayMinutes, NotifyGroupIds: step.NotifyGroupIDs})
+	}
+	return data, nil
+}
+
+func toPbEscalation(e *db.Escalation) *pb.Escalation {
+	data := &pb.Escalation{
+		Id:             e.ID,
+		PolicyId:       e.PolicyID,
+		Title:          e.Title,
+		Status:         e.Status,
+		Step:           int64(e.Step),
+		AcknowledgedBy: e.AcknowledgedBy,
+		CreatedAt:      timestamppb.New(e.CreatedAt),
+		UpdatedAt:      timestamppb.New(e.UpdatedAt),
+	}
+	if e.NextEscalateAt != nil {
+		data.NextEscalateAt = timestamppb.New(*e.NextEscalateAt)
+	}
+	if e.AcknowledgedAt != nil {
+		data.AcknowledgedAt = timestamppb.New(*e.AcknowledgedAt)
+	}
+	return data
+}
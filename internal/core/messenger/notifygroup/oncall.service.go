@@ -0,0 +1,410 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifygroup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-proto-go/core/messenger/notifygroup/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/core/messenger/notifygroup/db"
+	"github.com/erda-project/erda/internal/core/messenger/notifygroup/oncall"
+	"github.com/erda-project/erda/pkg/common/apis"
+	"github.com/erda-project/erda/pkg/common/errors"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+type onCallService struct {
+	log    logs.Logger
+	db     *db.DB
+	bdl    *bundle.Bundle
+	groups *notifyGroupService
+}
+
+func (s *onCallService) CreateOnCallSchedule(ctx context.Context, request *pb.CreateOnCallScheduleRequest) (*pb.CreateOnCallScheduleResponse, error) {
+	orgID, err := s.checkScope(ctx, request.ScopeType, request.ScopeId, apistructs.CreateAction)
+	if err != nil {
+		return nil, err
+	}
+	schedule := &db.OnCallSchedule{
+		ID:        uuid.New(),
+		OrgID:     orgID,
+		ScopeType: request.ScopeType,
+		ScopeID:   request.ScopeId,
+		Creator:   apis.GetUserID(ctx),
+	}
+	if err := setOnCallSchedule(schedule, request.Name, request.Timezone, request.Users, request.StartTime, request.ShiftDays); err != nil {
+		return nil, err
+	}
+	if err := s.db.OnCallScheduleDB.Create(schedule); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	data, err := s.toPbSchedule(schedule, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CreateOnCallScheduleResponse{Data: data}, nil
+}
+
+func (s *onCallService) UpdateOnCallSchedule(ctx context.Context, request *pb.UpdateOnCallScheduleRequest) (*pb.UpdateOnCallScheduleResponse, error) {
+	schedule, err := s.getSchedule(ctx, request.Id, apistructs.UpdateAction)
+	if err != nil {
+		return nil, err
+	}
+	if err := setOnCallSchedule(schedule, request.Name, request.Timezone, request.Users, request.StartTime, request.ShiftDays); err != nil {
+		return nil, err
+	}
+	if err := s.db.OnCallScheduleDB.Update(schedule); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	overrides, err := s.db.OnCallOverrideDB.ListBySchedules([]string{schedule.ID}, time.Now())
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	data, err := s.toPbSchedule(schedule, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.UpdateOnCallScheduleResponse{Data: data}, nil
+}
+
+func (s *onCallService) GetOnCallSchedule(ctx context.Context, request *pb.GetOnCallScheduleRequest) (*pb.GetOnCallScheduleResponse, error) {
+	schedule, err := s.getSchedule(ctx, request.Id, apistructs.GetAction)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := s.db.OnCallOverrideDB.ListBySchedules([]string{schedule.ID}, time.Now())
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	data, err := s.toPbSchedule(schedule, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetOnCallScheduleResponse{Data: data}, nil
+}
+
+func (s *onCallService) QueryOnCallSchedules(ctx context.Context, request *pb.QueryOnCallSchedulesRequest) (*pb.QueryOnCallSchedulesResponse, error) {
+	if _, err := s.checkScope(ctx, request.ScopeType, request.ScopeId, apistructs.ListAction); err != nil {
+		return nil, err
+	}
+	pageNo, pageSize := normalizePage(request.PageNo, request.PageSize)
+	list, total, err := s.db.OnCallScheduleDB.Query(request.ScopeType, request.ScopeId, pageNo, pageSize)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	result := &pb.QueryOnCallSchedulesResponse{Total: total}
+	for _, schedule := range list {
+		data, err := s.toPbSchedule(schedule, nil)
+		if err != nil {
+			return nil, err
+		}
+		result.List = append(result.List, data)
+	}
+	return result, nil
+}
+
+func (s *onCallService) DeleteOnCallSchedule(ctx context.Context, request *pb.DeleteOnCallScheduleRequest) (*pb.DeleteOnCallScheduleResponse, error) {
+	if _, err := s.getSchedule(ctx, request.Id, apistructs.DeleteAction); err != nil {
+		return nil, err
+	}
+	if err := s.db.OnCallScheduleDB.Delete(request.Id); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.DeleteOnCallScheduleResponse{Data: true}, nil
+}
+
+func (s *onCallService) GetOnCallShifts(ctx context.Context, request *pb.GetOnCallShiftsRequest) (*pb.GetOnCallShiftsResponse, error) {
+	schedule, err := s.getSchedule(ctx, request.Id, apistructs.GetAction)
+	if err != nil {
+		return nil, err
+	}
+	start, end := time.Now(), time.Now().Add(7*24*time.Hour)
+	if request.Start > 0 {
+		start = time.Unix(0, request.Start*int64(time.Millisecond))
+	}
+	if request.End > 0 {
+		end = time.Unix(0, request.End*int64(time.Millisecond))
+	}
+	overrides, err := s.db.OnCallOverrideDB.ListBySchedules([]string{schedule.ID}, start)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	sc, err := toOnCallSchedule(schedule, overrides)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	shifts, err := sc.Shifts(start, end)
+	if err != nil {
+		return nil, errors.NewInvalidParameterError("end", err.Error())
+	}
+	result := &pb.GetOnCallShiftsResponse{}
+	for _, shift := range shifts {
+		result.Data = append(result.Data, &pb.OnCallShift{
+			UserId:     shift.UserID,
+			StartTime:  timestamppb.New(shift.Start),
+			EndTime:    timestamppb.New(shift.End),
+			OverrideId: shift.OverrideID,
+		})
+	}
+	return result, nil
+}
+
+func (s *onCallService) CreateOnCallOverride(ctx context.Context, request *pb.CreateOnCallOverrideRequest) (*pb.CreateOnCallOverrideResponse, error) {
+	schedule, err := s.getSchedule(ctx, request.ScheduleId, apistructs.UpdateAction)
+	if err != nil {
+		return nil, err
+	}
+	if request.UserId == "" {
+		return nil, errors.NewMissingParameterError("userId")
+	}
+	if request.StartTime == nil || request.EndTime == nil {
+		return nil, errors.NewMissingParameterError("startTime/endTime")
+	}
+	override := &db.OnCallOverride{
+		ID:         uuid.New(),
+		ScheduleID: schedule.ID,
+		UserID:     request.UserId,
+		StartTime:  request.StartTime.AsTime(),
+		EndTime:    request.EndTime.AsTime(),
+		Creator:    apis.GetUserID(ctx),
+	}
+	if !override.EndTime.After(override.StartTime) {
+		return nil, errors.NewInvalidParameterError("endTime", "end time must be after start time")
+	}
+	if err := s.db.OnCallOverrideDB.Create(override); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.CreateOnCallOverrideResponse{Data: toPbOverride(override)}, nil
+}
+
+func (s *onCallService) DeleteOnCallOverride(ctx context.Context, request *pb.DeleteOnCallOverrideRequest) (*pb.DeleteOnCallOverrideResponse, error) {
+	if _, err := s.getSchedule(ctx, request.ScheduleId, apistructs.UpdateAction); err != nil {
+		return nil, err
+	}
+	override, err := s.db.OnCallOverrideDB.GetByID(request.Id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if override == nil || override.ScheduleID != request.ScheduleId {
+		return nil, errors.NewNotFoundError("oncall override")
+	}
+	if err := s.db.OnCallOverrideDB.Delete(request.Id); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.DeleteOnCallOverrideResponse{Data: true}, nil
+}
+
+// OnCallUsers returns the users on call at the time of the schedules, it resolves the oncall notify targets.
+// Schedules out of the scope of the notify group are ignored.
+func (s *onCallService) OnCallUsers(orgID int64, scopeType, scopeID string, scheduleIDs []string, at time.Time) ([]string, error) {
+	if len(scheduleIDs) == 0 {
+		return nil, nil
+	}
+	schedules, err := s.db.OnCallScheduleDB.ListByIDs(scheduleIDs)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := s.db.OnCallOverrideDB.ListBySchedules(scheduleIDs, at)
+	if err != nil {
+		return nil, err
+	}
+	var users []string
+	seen := make(map[string]bool)
+	for _, schedule := range schedules {
+		if !inScope(schedule, orgID, scopeType, scopeID) {
+			s.log.Warnf("oncall schedule %s is not in the scope %s/%s of org %d", schedule.ID, scopeType, scopeID, orgID)
+			continue
+		}
+		sc, err := toOnCallSchedule(schedule, overrides)
+		if err != nil {
+			s.log.Warnf("invalid oncall schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		if user := sc.OnCall(at); user != "" && !seen[user] {
+			seen[user] = true
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// CheckOnCallSchedules checks the schedules exist in the scope of the org, it validates the oncall notify targets
+func (s *onCallService) CheckOnCallSchedules(orgID int64, scopeType, scopeID string, scheduleIDs []string) error {
+	schedules, err := s.db.OnCallScheduleDB.ListByIDs(scheduleIDs)
+	if err != nil {
+		return err
+	}
+	found := make(map[string]bool, len(schedules))
+	for _, schedule := range schedules {
+		if inScope(schedule, orgID, scopeType, scopeID) {
+			found[schedule.ID] = true
+		}
+	}
+	for _, id := range scheduleIDs {
+		if !found[id] {
+			return fmt.Errorf("oncall schedule %s not found in the scope of the notify group", id)
+		}
+	}
+	return nil
+}
+
+func inScope(schedule *db.OnCallSchedule, orgID int64, scopeType, scopeID string) bool {
+	return schedule.OrgID == orgID && schedule.ScopeType == scopeType && schedule.ScopeID == scopeID
+}
+
+func (s *onCallService) getSchedule(ctx context.Context, id, action string) (*db.OnCallSchedule, error) {
+	if id == "" {
+		return nil, errors.NewMissingParameterError("id")
+	}
+	schedule, err := s.db.OnCallScheduleDB.GetByID(id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if schedule == nil {
+		return nil, errors.NewNotFoundError("oncall schedule")
+	}
+	if _, err := s.checkScope(ctx, schedule.ScopeType, schedule.ScopeID, action); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// checkScope checks the notify permission of the user in the scope and returns the org id
+func (s *onCallService) checkScope(ctx context.Context, scopeType, scopeID, action string) (int64, error) {
+	if scopeType == "" || scopeID == "" {
+		return 0, errors.NewMissingParameterError("scopeType/scopeId")
+	}
+	if err := s.groups.checkNotifyPermission(ctx, apis.GetUserID(ctx), scopeType, scopeID, action); err != nil {
+		return 0, errors.NewPermissionError(apistructs.NotifyResource, action, err.Error())
+	}
+	orgID, err := apis.GetIntOrgID(ctx)
+	if err != nil {
+		return 0, errors.NewInvalidParameterError("orgId", "orgId is invalidate")
+	}
+	return orgID, nil
+}
+
+func setOnCallSchedule(schedule *db.OnCallSchedule, name, timezone string, users []string, start *timestamppb.Timestamp, shiftDays int64) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.NewInvalidParameterError("name", "name is empty")
+	}
+	if utf8.RuneCountInString(name) > 50 {
+		return errors.NewInvalidParameterError("name", "name is too long")
+	}
+	if start == nil {
+		return errors.NewMissingParameterError("startTime")
+	}
+	usersJSON, err := json.Marshal(users)
+	if err != nil {
+		return errors.NewInvalidParameterError("users", err.Error())
+	}
+	schedule.Name = name
+	schedule.Timezone = timezone
+	schedule.Users = string(usersJSON)
+	schedule.StartTime = start.AsTime()
+	schedule.ShiftDays = int(shiftDays)
+	if _, err := toOnCallSchedule(schedule, nil); err != nil {
+		return errors.NewInvalidParameterError("schedule", err.Error())
+	}
+	return nil
+}
+
+func toOnCallSchedule(schedule *db.OnCallSchedule, overrides []*db.OnCallOverride) (*oncall.Schedule, error) {
+	loc := time.Local
+	if schedule.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var users []string
+	if err := json.Unmarshal([]byte(schedule.Users), &users); err != nil {
+		return nil, err
+	}
+	sc := &oncall.Schedule{Rotation: oncall.Rotation{
+		Users:     users,
+		Start:     schedule.StartTime,
+		ShiftDays: schedule.ShiftDays,
+		Location:  loc,
+	}}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		if o.ScheduleID == schedule.ID {
+			sc.Overrides = append(sc.Overrides, &oncall.Override{ID: o.ID, UserID: o.UserID, Start: o.StartTime, End: o.EndTime})
+		}
+	}
+	return sc, nil
+}
+
+func (s *onCallService) toPbSchedule(schedule *db.OnCallSchedule, overrides []*db.OnCallOverride) (*pb.OnCallSchedule, error) {
+	var users []string
+	if err := json.Unmarshal([]byte(schedule.Users), &users); err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	data := &pb.OnCallSchedule{
+		Id:        schedule.ID,
+		Name:      schedule.Name,
+		ScopeType: schedule.ScopeType,
+		ScopeId:   schedule.ScopeID,
+		Timezone:  schedule.Timezone,
+		Users:     users,
+		StartTime: timestamppb.New(schedule.StartTime),
+		ShiftDays: int64(schedule.ShiftDays),
+		Creator:   schedule.Creator,
+		CreatedAt: timestamppb.New(schedule.CreatedAt),
+		UpdatedAt: timestamppb.New(schedule.UpdatedAt),
+	}
+	for _, o := range overrides {
+		data.Overrides = append(data.Overrides, toPbOverride(o))
+	}
+	if sc, err := toOnCallSchedule(schedule, overrides); err == nil {
+		data.OnCall = sc.OnCall(time.Now())
+	}
+	return data, nil
+}
+
+func toPbOverride(o *db.OnCallOverride) *pb.OnCallOverride {
+	return &pb.OnCallOverride{
+		Id:         o.ID,
+		ScheduleId: o.ScheduleID,
+		UserId:     o.UserID,
+		StartTime:  timestamppb.New(o.StartTime),
+		EndTime:    timestamppb.New(o.EndTime),
+		Creator:    o.Creator,
+	}
+}
+
+func normalizePage(pageNo, pageSize int64) (int64, int64) {
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	return pageNo, pageSize
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oncall
+
+import (
+	"fmt"
+	"time"
+)
+
+// Step is an escalation step, it notifies the groups when the alert is still not acknowledged
+// DelayMinutes after the previous step
+type Step struct {
+	DelayMinutes   int64   `json:"delayMinutes"`
+	NotifyGroupIDs []int64 `json:"notifyGroupIds"`
+}
+
+// ValidateSteps checks the escalation steps following the first notification
+func ValidateSteps(steps []*Step) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("escalation steps are required")
+	}
+	for i, s := range steps {
+		if s == nil || len(s.NotifyGroupIDs) == 0 {
+			return fmt.Errorf("notify groups of step %d are required", i+2)
+		}
+		if s.DelayMinutes <= 0 {
+			return fmt.Errorf("delay of step %d must be positive", i+2)
+		}
+	}
+	return nil
+}
+
+// NextEscalation returns when the next step escalates, given that the first notification and
+// the first notified steps have been sent with the last one at last. It returns false when all steps are done.
+func NextEscalation(steps []*Step, notified int, last time.Time) (time.Time, bool) {
+	if notified < 0 || notified >= len(steps) {
+		return time.Time{}, false
+	}
+	return last.Add(time.Duration(steps[notified].DelayMinutes) * time.Minute), true
+}
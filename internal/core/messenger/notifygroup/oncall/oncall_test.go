@@ -0,0 +1,125 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oncall
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_OnCall(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	s := &Schedule{Rotation: Rotation{
+		Users:     []string{"1", "2", "3"},
+		Start:     time.Date(2026, 10, 19, 9, 0, 0, 0, loc), // Monday 09:00
+		ShiftDays: 7,
+		Location:  loc,
+	}}
+	for _, tt := range []struct {
+		at   time.Time
+		want string
+	}{
+		{time.Date(2026, 10, 19, 8, 59, 0, 0, loc), ""},
+		{time.Date(2026, 10, 19, 9, 0, 0, 0, loc), "1"},
+		{time.Date(2026, 10, 26, 8, 59, 0, 0, loc), "1"},
+		// daylight saving time ends on 2026-10-25, the handoff stays at 09:00 local time
+		{time.Date(2026, 10, 26, 9, 0, 0, 0, loc), "2"},
+		{time.Date(2026, 11, 2, 9, 0, 0, 0, loc), "3"},
+		{time.Date(2026, 11, 9, 9, 30, 0, 0, loc), "1"},
+	} {
+		if got := s.OnCall(tt.at); got != tt.want {
+			t.Errorf("OnCall(%s) = %q, want %q", tt.at, got, tt.want)
+		}
+	}
+
+	s.Overrides = []*Override{
+		{ID: "o1", UserID: "9", Start: time.Date(2026, 10, 20, 0, 0, 0, 0, loc), End: time.Date(2026, 10, 21, 0, 0, 0, 0, loc)},
+		{ID: "o2", UserID: "8", Start: time.Date(2026, 10, 20, 12, 0, 0, 0, loc), End: time.Date(2026, 10, 20, 13, 0, 0, 0, loc)},
+	}
+	if got := s.OnCall(time.Date(2026, 10, 20, 10, 0, 0, 0, loc)); got != "9" {
+		t.Errorf("override should win, got %q", got)
+	}
+	if got := s.OnCall(time.Date(2026, 10, 20, 12, 30, 0, 0, loc)); got != "8" {
+		t.Errorf("later override should win, got %q", got)
+	}
+	if got := s.OnCall(time.Date(2026, 10, 21, 0, 0, 0, 0, loc)); got != "1" {
+		t.Errorf("override should end, got %q", got)
+	}
+}
+
+func TestSchedule_Shifts(t *testing.T) {
+	start := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	s := &Schedule{
+		Rotation: Rotation{Users: []string{"a", "b"}, Start: start, ShiftDays: 1, Location: time.UTC},
+		Overrides: []*Override{
+			{ID: "o1", UserID: "c", Start: start.Add(30 * time.Hour), End: start.Add(34 * time.Hour)},
+		},
+	}
+	shifts, err := s.Shifts(start.Add(-2*time.Hour), start.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		user       string
+		from, to   time.Duration
+		overrideID string
+	}{
+		{"a", 0, 24 * time.Hour, ""},
+		{"b", 24 * time.Hour, 30 * time.Hour, ""},
+		{"c", 30 * time.Hour, 34 * time.Hour, "o1"},
+		{"b", 34 * time.Hour, 48 * time.Hour, ""},
+	}
+	if len(shifts) != len(want) {
+		t.Fatalf("want %d shifts, got %d: %+v", len(want), len(shifts), shifts)
+	}
+	for i, w := range want {
+		got := shifts[i]
+		if got.UserID != w.user || !got.Start.Equal(start.Add(w.from)) || !got.End.Equal(start.Add(w.to)) || got.OverrideID != w.overrideID {
+			t.Errorf("shift %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if _, err := s.Shifts(start, start.Add(MaxShiftsRange+time.Hour)); err == nil {
+		t.Errorf("too long range should fail")
+	}
+}
+
+func TestNextEscalation(t *testing.T) {
+	steps := []*Step{
+		{DelayMinutes: 10, NotifyGroupIDs: []int64{2}},
+		{DelayMinutes: 30, NotifyGroupIDs: []int64{3}},
+	}
+	if err := ValidateSteps(steps); err != nil {
+		t.Fatal(err)
+	}
+	last := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	if at, ok := NextEscalation(steps, 0, last); !ok || !at.Equal(last.Add(10*time.Minute)) {
+		t.Errorf("first escalation at %s %v", at, ok)
+	}
+	if at, ok := NextEscalation(steps, 1, last); !ok || !at.Equal(last.Add(30*time.Minute)) {
+		t.Errorf("second escalation at %s %v", at, ok)
+	}
+	if _, ok := NextEscalation(steps, 2, last); ok {
+		t.Errorf("no more escalation after the last step")
+	}
+	for _, invalid := range [][]*Step{nil, {{DelayMinutes: 0, NotifyGroupIDs: []int64{1}}}, {{DelayMinutes: 5}}} {
+		if err := ValidateSteps(invalid); err == nil {
+			t.Errorf("ValidateSteps(%v) should fail", invalid)
+		}
+	}
+}
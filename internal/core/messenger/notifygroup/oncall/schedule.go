@@ -0,0 +1,191 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oncall computes who is on call for a rotation schedule with overrides,
+// and when an unacknowledged notification escalates to the next step.
+package oncall
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxShiftsRange bounds the time range of Shifts
+const MaxShiftsRange = 92 * 24 * time.Hour
+
+// Rotation hands the duty over to the next user every ShiftDays days at the wall clock time of Start
+// in Location, so that the handoff time is kept across daylight saving changes
+type Rotation struct {
+	Users     []string
+	Start     time.Time
+	ShiftDays int
+	Location  *time.Location
+}
+
+// Validate checks the rotation
+func (r *Rotation) Validate() error {
+	if len(r.Users) == 0 {
+		return fmt.Errorf("rotation users are required")
+	}
+	for _, u := range r.Users {
+		if u == "" {
+			return fmt.Errorf("empty rotation user")
+		}
+	}
+	if r.ShiftDays <= 0 {
+		return fmt.Errorf("shift days must be positive")
+	}
+	if r.Start.IsZero() {
+		return fmt.Errorf("rotation start time is required")
+	}
+	return nil
+}
+
+func (r *Rotation) location() *time.Location {
+	if r.Location == nil {
+		return time.Local
+	}
+	return r.Location
+}
+
+// handoff returns the start of the k-th shift
+func (r *Rotation) handoff(k int) time.Time {
+	s := r.Start.In(r.location())
+	return time.Date(s.Year(), s.Month(), s.Day()+k*r.ShiftDays, s.Hour(), s.Minute(), s.Second(), 0, r.location())
+}
+
+// shift returns the index of the shift covering t, false if the rotation has not started
+func (r *Rotation) shift(t time.Time) (int, bool) {
+	if len(r.Users) == 0 || r.ShiftDays <= 0 || t.Before(r.Start) {
+		return 0, false
+	}
+	k := int(t.Sub(r.Start) / (time.Duration(r.ShiftDays) * 24 * time.Hour))
+	for k > 0 && r.handoff(k).After(t) {
+		k--
+	}
+	for !r.handoff(k + 1).After(t) {
+		k++
+	}
+	return k, true
+}
+
+// OnCall returns the user on duty at t by the rotation only
+func (r *Rotation) OnCall(t time.Time) string {
+	k, ok := r.shift(t)
+	if !ok {
+		return ""
+	}
+	return r.Users[k%len(r.Users)]
+}
+
+// Override replaces the user on duty in [Start, End)
+type Override struct {
+	ID     string
+	UserID string
+	Start  time.Time
+	End    time.Time
+}
+
+// Schedule is a rotation with overrides, a later override in the list takes precedence
+type Schedule struct {
+	Rotation
+	Overrides []*Override
+}
+
+func (s *Schedule) override(t time.Time) *Override {
+	for i := len(s.Overrides) - 1; i >= 0; i-- {
+		o := s.Overrides[i]
+		if !t.Before(o.Start) && t.Before(o.End) {
+			return o
+		}
+	}
+	return nil
+}
+
+// OnCall returns the user on duty at t, empty if there is none
+func (s *Schedule) OnCall(t time.Time) string {
+	if o := s.override(t); o != nil {
+		return o.UserID
+	}
+	return s.Rotation.OnCall(t)
+}
+
+// Shift is a period during which one user is on duty
+type Shift struct {
+	UserID     string
+	Start      time.Time
+	End        time.Time
+	OverrideID string
+}
+
+// Shifts returns the shifts overlapping [start, end), clipped to the range
+func (s *Schedule) Shifts(start, end time.Time) ([]*Shift, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+	if end.Sub(start) > MaxShiftsRange {
+		return nil, fmt.Errorf("time range must not exceed %s", MaxShiftsRange)
+	}
+	bounds := []time.Time{start, end}
+	if len(s.Users) > 0 && s.ShiftDays > 0 {
+		first := 0
+		if k, ok := s.shift(start); ok {
+			first = k + 1
+		}
+		for k := first; ; k++ {
+			h := s.handoff(k)
+			if !h.Before(end) {
+				break
+			}
+			if h.After(start) {
+				bounds = append(bounds, h)
+			}
+		}
+	}
+	for _, o := range s.Overrides {
+		for _, t := range []time.Time{o.Start, o.End} {
+			if t.After(start) && t.Before(end) {
+				bounds = append(bounds, t)
+			}
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i].Before(bounds[j]) })
+
+	var shifts []*Shift
+	for i := 0; i+1 < len(bounds); i++ {
+		from, to := bounds[i], bounds[i+1]
+		if !to.After(from) {
+			continue
+		}
+		shift := &Shift{Start: from, End: to}
+		if o := s.override(from); o != nil {
+			shift.UserID, shift.OverrideID = o.UserID, o.ID
+		} else {
+			shift.UserID = s.Rotation.OnCall(from)
+		}
+		if shift.UserID == "" {
+			continue
+		}
+		if n := len(shifts); n > 0 {
+			last := shifts[n-1]
+			if last.End.Equal(from) && last.UserID == shift.UserID && last.OverrideID == shift.OverrideID {
+				last.End = to
+				continue
+			}
+		}
+		shifts = append(shifts, shift)
+	}
+	return shifts, nil
+}
@@ -16,6 +16,7 @@ package notifygroup
 
 import (
 	"context"
+	"time"
 
 	"github.com/jinzhu/gorm"
 
@@ -29,13 +30,16 @@ import (
 	"github.com/erda-project/erda/internal/core/legacy/dao"
 	"github.com/erda-project/erda/internal/core/legacy/services/notify"
 	"github.com/erda-project/erda/internal/core/legacy/services/permission"
+	"github.com/erda-project/erda/internal/core/messenger/notifygroup/db"
 	"github.com/erda-project/erda/internal/core/org"
 	"github.com/erda-project/erda/internal/pkg/audit"
 	"github.com/erda-project/erda/pkg/common/apis"
 	"github.com/erda-project/erda/pkg/i18n"
 )
 
-type config struct{}
+type config struct {
+	EscalationInterval time.Duration `file:"escalation_interval" default:"30s"`
+}
 
 type provider struct {
 	Cfg                *config
@@ -43,6 +47,7 @@ type provider struct {
 	Register           transport.Register `autowired:"service-register" optional:"true"`
 	DB                 *gorm.DB           `autowired:"mysql-client"`
 	notifyGroupService *notifyGroupService
+	onCallService      *onCallService
 	audit              audit.Auditor
 	Org                org.Interface
 	UserSvc            userpb.UserServiceServer `autowired:"erda.core.user.UserService"`
@@ -55,14 +60,21 @@ func (p *provider) Init(ctx servicehub.Context) error {
 		DB: p.DB,
 	}))
 	p.notifyGroupService.Permission = pm
+	p.notifyGroupService.org = p.Org
+	p.notifyGroupService.bdl = bundle.New(bundle.WithI18nLoader(&i18n.LocaleResourceLoader{}))
+	p.onCallService = &onCallService{
+		log:    p.Log,
+		db:     db.New(p.DB),
+		bdl:    p.notifyGroupService.bdl,
+		groups: p.notifyGroupService,
+	}
 	p.notifyGroupService.NotifyGroup = notify.New(
 		notify.WithDBClient(&dao.DBClient{
 			p.DB,
 		}),
 		notify.WithUserService(p.UserSvc),
+		notify.WithOnCallResolver(p.onCallService),
 	)
-	p.notifyGroupService.org = p.Org
-	p.notifyGroupService.bdl = bundle.New(bundle.WithI18nLoader(&i18n.LocaleResourceLoader{}))
 	if p.Register != nil {
 		type NotifyGroupService = pb.NotifyGroupServiceServer
 		pb.RegisterNotifyGroupServiceImp(p.Register, p.notifyGroupService, apis.Options(),
@@ -84,14 +96,30 @@ func (p *provider) Init(ctx servicehub.Context) error {
 				),
 			),
 		)
+		pb.RegisterOnCallServiceImp(p.Register, p.onCallService, apis.Options())
 	}
 	return nil
 }
 
+func (p *provider) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.Cfg.EscalationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			p.onCallService.escalateDue(now)
+		}
+	}
+}
+
 func (p *provider) Provide(ctx servicehub.DependencyContext, args ...interface{}) interface{} {
 	switch {
 	case ctx.Service() == "erda.core.messenger.notifygroup.NotifyGroupService" || ctx.Type() == pb.NotifyGroupServiceServerType() || ctx.Type() == pb.NotifyGroupServiceHandlerType():
 		return p.notifyGroupService
+	case ctx.Service() == "erda.core.messenger.notifygroup.OnCallService" || ctx.Type() == pb.OnCallServiceServerType() || ctx.Type() == pb.OnCallServiceHandlerType():
+		return p.onCallService
 	}
 	return p
 }
@@ -88,6 +88,14 @@ func (db *AlertEventDB) GetByAlertGroupID(groupID string) (*AlertEvent, error) {
 	return &record, nil
 }
 
+// UpdateFiringLabels saves the labels of the alert group if it is firing
+func (db *AlertEventDB) UpdateFiringLabels(groupID, labels string) error {
+	return db.Table(TableAlertEvent).
+		Where("alert_group_id=?", groupID).
+		Where("alert_state=?", "alert").
+		Update("labels", labels).Error
+}
+
 // QueryByCondition .
 func (db *AlertEventDB) QueryByCondition(scope, scopeId string, condition *AlertEventQueryCondition, sorts []*AlertEventSort, pageNo, pageSize int64) ([]*AlertEvent, error) {
 	var result []*AlertEvent
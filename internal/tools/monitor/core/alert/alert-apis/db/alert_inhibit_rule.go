@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// AlertInhibitRuleDB .
+type AlertInhibitRuleDB struct {
+	*gorm.DB
+}
+
+func (db *AlertInhibitRuleDB) Create(data *AlertInhibitRule) error {
+	return db.DB.Create(data).Error
+}
+
+func (db *AlertInhibitRuleDB) Update(data *AlertInhibitRule) error {
+	return db.Save(data).Error
+}
+
+func (db *AlertInhibitRuleDB) GetByID(id string) (*AlertInhibitRule, error) {
+	var record AlertInhibitRule
+	if err := db.Where("id=?", id).Find(&record).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (db *AlertInhibitRuleDB) DeleteByID(id string) error {
+	return db.Where("id=?", id).Delete(&AlertInhibitRule{}).Error
+}
+
+// QueryByScope returns a page of the inhibit rules of a scope
+func (db *AlertInhibitRuleDB) QueryByScope(scope, scopeID string, pageNo, pageSize int64) ([]*AlertInhibitRule, int64, error) {
+	query := db.Table(TableAlertInhibitRule).Where("scope=?", scope).Where("scope_id=?", scopeID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []*AlertInhibitRule
+	err := query.Order("created_at desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ListEnabled returns the enabled inhibit rules of all scopes
+func (db *AlertInhibitRuleDB) ListEnabled() ([]*AlertInhibitRule, error) {
+	var list []*AlertInhibitRule
+	err := db.Table(TableAlertInhibitRule).Where("enabled=?", true).Find(&list).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// AlertMaintenanceWindowDB .
+type AlertMaintenanceWindowDB struct {
+	*gorm.DB
+}
+
+func (db *AlertMaintenanceWindowDB) Create(data *AlertMaintenanceWindow) error {
+	return db.DB.Create(data).Error
+}
+
+func (db *AlertMaintenanceWindowDB) Update(data *AlertMaintenanceWindow) error {
+	return db.Save(data).Error
+}
+
+func (db *AlertMaintenanceWindowDB) GetByID(id string) (*AlertMaintenanceWindow, error) {
+	var record AlertMaintenanceWindow
+	if err := db.Where("id=?", id).Find(&record).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (db *AlertMaintenanceWindowDB) DeleteByID(id string) error {
+	return db.Where("id=?", id).Delete(&AlertMaintenanceWindow{}).Error
+}
+
+// QueryByScope returns a page of the maintenance windows of a scope
+func (db *AlertMaintenanceWindowDB) QueryByScope(scope, scopeID string, pageNo, pageSize int64) ([]*AlertMaintenanceWindow, int64, error) {
+	query := db.Table(TableAlertMaintenanceWindow).Where("scope=?", scope).Where("scope_id=?", scopeID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []*AlertMaintenanceWindow
+	err := query.Order("created_at desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ListEnabled returns the enabled maintenance windows of all scopes
+func (db *AlertMaintenanceWindowDB) ListEnabled() ([]*AlertMaintenanceWindow, error) {
+	var list []*AlertMaintenanceWindow
+	err := db.Table(TableAlertMaintenanceWindow).Where("enabled=?", true).Find(&list).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// silence states
+const (
+	SilenceStatePending = "pending"
+	SilenceStateActive  = "active"
+	SilenceStateExpired = "expired"
+)
+
+// AlertSilenceDB .
+type AlertSilenceDB struct {
+	*gorm.DB
+}
+
+func (db *AlertSilenceDB) Create(data *AlertSilence) error {
+	return db.DB.Create(data).Error
+}
+
+func (db *AlertSilenceDB) Update(data *AlertSilence) error {
+	return db.Save(data).Error
+}
+
+func (db *AlertSilenceDB) GetByID(id string) (*AlertSilence, error) {
+	var record AlertSilence
+	if err := db.Where("id=?", id).Find(&record).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Expire ends the silence at now, expired silences are kept for the alert history
+func (db *AlertSilenceDB) Expire(id string, now time.Time) error {
+	return db.Table(TableAlertSilence).Where("id=?", id).Where("end_time>?", now).
+		Updates(map[string]interface{}{"end_time": now}).Error
+}
+
+// QueryByScope returns a page of the silences of a scope, state filters by pending, active or expired at now
+func (db *AlertSilenceDB) QueryByScope(scope, scopeID, state string, now time.Time, pageNo, pageSize int64) ([]*AlertSilence, int64, error) {
+	query := db.Table(TableAlertSilence).Where("scope=?", scope).Where("scope_id=?", scopeID)
+	switch state {
+	case SilenceStatePending:
+		query = query.Where("start_time>?", now)
+	case SilenceStateActive:
+		query = query.Where("start_time<=?", now).Where("end_time>?", now)
+	case SilenceStateExpired:
+		query = query.Where("end_time<=?", now)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []*AlertSilence
+	err := query.Order("end_time desc").Offset((pageNo - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ListUnexpired returns the silences of all scopes which are not yet expired at now
+func (db *AlertSilenceDB) ListUnexpired(now time.Time) ([]*AlertSilence, error) {
+	var list []*AlertSilence
+	err := db.Table(TableAlertSilence).Where("end_time>?", now).Find(&list).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	return list, nil
+}
@@ -32,6 +32,9 @@ type DB struct {
 	AlertRecord                  AlertRecordDB
 	AlertEventDB                 AlertEventDB
 	AlertEventSuppressDB         AlertEventSuppressDB
+	AlertSilence                 AlertSilenceDB
+	AlertMaintenanceWindow       AlertMaintenanceWindowDB
+	AlertInhibitRule             AlertInhibitRuleDB
 }
 
 // New .
@@ -49,6 +52,9 @@ func New(db *gorm.DB) *DB {
 		AlertRecord:                  AlertRecordDB{db},
 		AlertEventDB:                 AlertEventDB{db},
 		AlertEventSuppressDB:         AlertEventSuppressDB{db},
+		AlertSilence:                 AlertSilenceDB{db},
+		AlertMaintenanceWindow:       AlertMaintenanceWindowDB{db},
+		AlertInhibitRule:             AlertInhibitRuleDB{db},
 	}
 }
 
@@ -34,6 +34,9 @@ const (
 	TableAlert                        = "sp_alert"
 	TableAlertEvent                   = "sp_alert_event"
 	TableAlertEventSuppress           = "sp_alert_event_suppress"
+	TableAlertSilence                 = "sp_alert_silence"
+	TableAlertMaintenanceWindow       = "sp_alert_maintenance_window"
+	TableAlertInhibitRule             = "sp_alert_inhibit_rule"
 )
 
 type AlertEvent struct {
@@ -57,6 +60,8 @@ type AlertEvent struct {
 	ExpressionID     uint64    `gorm:"column:expression_id"`
 	LastTriggerTime  time.Time `gorm:"column:last_trigger_time"`
 	FirstTriggerTime time.Time `gorm:"column:first_trigger_time"`
+	// Labels is the json of the labels of the alert when it fired last time
+	Labels string `gorm:"column:labels"`
 }
 
 // TableName .
@@ -75,6 +80,65 @@ type AlertEventSuppress struct {
 
 func (AlertEventSuppress) TableName() string { return TableAlertEventSuppress }
 
+// AlertSilence mutes the alerts matching all of its label matchers between StartTime and EndTime
+type AlertSilence struct {
+	Id        string    `gorm:"column:id;primary_key"`
+	OrgID     int64     `gorm:"column:org_id"`
+	Scope     string    `gorm:"column:scope"`
+	ScopeID   string    `gorm:"column:scope_id"`
+	Matchers  string    `gorm:"column:matchers"`
+	StartTime time.Time `gorm:"column:start_time"`
+	EndTime   time.Time `gorm:"column:end_time"`
+	Creator   string    `gorm:"column:creator"`
+	Comment   string    `gorm:"column:comment"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (AlertSilence) TableName() string { return TableAlertSilence }
+
+// AlertMaintenanceWindow mutes the matching alerts during a daily or weekly recurring period
+type AlertMaintenanceWindow struct {
+	Id             string     `gorm:"column:id;primary_key"`
+	OrgID          int64      `gorm:"column:org_id"`
+	Scope          string     `gorm:"column:scope"`
+	ScopeID        string     `gorm:"column:scope_id"`
+	Name           string     `gorm:"column:name"`
+	Matchers       string     `gorm:"column:matchers"`
+	Timezone       string     `gorm:"column:timezone"`
+	Weekdays       string     `gorm:"column:weekdays"`
+	StartTime      string     `gorm:"column:start_time"`
+	Duration       int64      `gorm:"column:duration"`
+	EffectiveFrom  *time.Time `gorm:"column:effective_from"`
+	EffectiveUntil *time.Time `gorm:"column:effective_until"`
+	Enabled        bool       `gorm:"column:enabled"`
+	Creator        string     `gorm:"column:creator"`
+	Comment        string     `gorm:"column:comment"`
+	CreatedAt      time.Time  `gorm:"column:created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at"`
+}
+
+func (AlertMaintenanceWindow) TableName() string { return TableAlertMaintenanceWindow }
+
+// AlertInhibitRule mutes the target alerts while a source alert with the same equal labels is firing
+type AlertInhibitRule struct {
+	Id             string    `gorm:"column:id;primary_key"`
+	OrgID          int64     `gorm:"column:org_id"`
+	Scope          string    `gorm:"column:scope"`
+	ScopeID        string    `gorm:"column:scope_id"`
+	Name           string    `gorm:"column:name"`
+	SourceMatchers string    `gorm:"column:source_matchers"`
+	TargetMatchers string    `gorm:"column:target_matchers"`
+	EqualLabels    string    `gorm:"column:equal_labels"`
+	Enabled        bool      `gorm:"column:enabled"`
+	Creator        string    `gorm:"column:creator"`
+	Comment        string    `gorm:"column:comment"`
+	CreatedAt      time.Time `gorm:"column:created_at"`
+	UpdatedAt      time.Time `gorm:"column:updated_at"`
+}
+
+func (AlertInhibitRule) TableName() string { return TableAlertInhibitRule }
+
 type AlertRecord struct {
 	GroupID       string    `gorm:"column:group_id;primary_key"`
 	Scope         string    `gorm:"column:scope"`
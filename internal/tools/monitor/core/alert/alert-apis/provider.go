@@ -84,6 +84,7 @@ type provider struct {
 	Metric        metricpb.MetricServiceServer `autowired:"erda.core.monitor.metric.MetricService"`
 	Perm          perm.Interface               `autowired:"permission"`
 	alertService  *alertService
+	silenceSvc    *alertSilenceService
 	NotifyChannel channelpb.NotifyChannelServiceServer `autowired:"erda.core.messenger.notifychannel.NotifyChannelService"`
 	Org           org.ClientInterface
 }
@@ -156,6 +157,7 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	p.alertService = &alertService{
 		p: p,
 	}
+	p.silenceSvc = &alertSilenceService{p: p}
 
 	if p.Register != nil {
 		type MonitorService = pb.AlertServiceServer
@@ -250,6 +252,25 @@ func (p *provider) Init(ctx servicehub.Context) error {
 				audit.Method(MonitorService.UpdateOrgCustomizeAlertEnable, audit.OrgScope, string(apistructs.SwitchOrgCustomAlert), p.alertService.auditOperateOrgCustomAlert(apistructs.SwitchOrgCustomAlert, "")),
 			),
 		)
+		type SilenceService = pb.AlertSilenceServiceServer
+		pb.RegisterAlertSilenceServiceImp(p.Register, p.silenceSvc, apis.Options(), p.Perm.Check(
+			perm.NoPermMethod(SilenceService.CreateAlertSilence),
+			perm.NoPermMethod(SilenceService.UpdateAlertSilence),
+			perm.NoPermMethod(SilenceService.GetAlertSilence),
+			perm.NoPermMethod(SilenceService.QueryAlertSilences),
+			perm.NoPermMethod(SilenceService.ExpireAlertSilence),
+			perm.NoPermMethod(SilenceService.CreateAlertMaintenanceWindow),
+			perm.NoPermMethod(SilenceService.UpdateAlertMaintenanceWindow),
+			perm.NoPermMethod(SilenceService.GetAlertMaintenanceWindow),
+			perm.NoPermMethod(SilenceService.QueryAlertMaintenanceWindows),
+			perm.NoPermMethod(SilenceService.DeleteAlertMaintenanceWindow),
+			perm.NoPermMethod(SilenceService.CreateAlertInhibitRule),
+			perm.NoPermMethod(SilenceService.UpdateAlertInhibitRule),
+			perm.NoPermMethod(SilenceService.GetAlertInhibitRule),
+			perm.NoPermMethod(SilenceService.QueryAlertInhibitRules),
+			perm.NoPermMethod(SilenceService.DeleteAlertInhibitRule),
+			perm.NoPermMethod(SilenceService.MuteAlert),
+		))
 	}
 	return nil
 }
@@ -258,6 +279,8 @@ func (p *provider) Provide(ctx servicehub.DependencyContext, args ...interface{}
 	switch {
 	case ctx.Service() == "erda.core.monitor.alert" || ctx.Type() == pb.AlertServiceServerType() || ctx.Type() == pb.AlertServiceHandlerType():
 		return p.alertService
+	case ctx.Type() == pb.AlertSilenceServiceServerType() || ctx.Type() == pb.AlertSilenceServiceHandlerType():
+		return p.silenceSvc
 	}
 	return p
 }
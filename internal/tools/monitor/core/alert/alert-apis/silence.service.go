@@ -0,0 +1,579 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erda-project/erda-proto-go/core/monitor/alert/pb"
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/silence"
+	"github.com/erda-project/erda/pkg/common/apis"
+	"github.com/erda-project/erda/pkg/common/errors"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+// muteSetTTL is how long MuteAlert reuses the loaded silences, maintenance windows and inhibit rules,
+// changes of them apply to the notifications after it.
+const muteSetTTL = 15 * time.Second
+
+type alertSilenceService struct {
+	p *provider
+
+	muteLock     sync.Mutex
+	muteSet      *silence.Set
+	muteLoadedAt time.Time
+}
+
+func (s *alertSilenceService) CreateAlertSilence(ctx context.Context, req *pb.CreateAlertSilenceRequest) (*pb.CreateAlertSilenceResponse, error) {
+	orgID, err := parseOrgID(ctx, req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScope(req.Scope, req.ScopeID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	startTime := now
+	if req.StartTime > 0 {
+		startTime = fromMilliseconds(req.StartTime)
+	}
+	data := &db.AlertSilence{
+		Id:        uuid.UUID(),
+		OrgID:     orgID,
+		Scope:     req.Scope,
+		ScopeID:   req.ScopeID,
+		Matchers:  toMatchers(req.Matchers).JSON(),
+		StartTime: startTime,
+		EndTime:   fromMilliseconds(req.EndTime),
+		Creator:   apis.GetUserID(ctx),
+		Comment:   req.Comment,
+	}
+	if !data.EndTime.After(now) {
+		return nil, errors.NewInvalidParameterError("endTime", "end time must be in the future")
+	}
+	if _, err := silence.NewSilence(data); err != nil {
+		return nil, errors.NewInvalidParameterError("silence", err.Error())
+	}
+	if err := s.p.db.AlertSilence.Create(data); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.CreateAlertSilenceResponse{Data: toPbSilence(data, now)}, nil
+}
+
+func (s *alertSilenceService) UpdateAlertSilence(ctx context.Context, req *pb.UpdateAlertSilenceRequest) (*pb.UpdateAlertSilenceResponse, error) {
+	data, err := s.getSilence(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if silence.SilenceState(data.StartTime, data.EndTime, now) == db.SilenceStateExpired {
+		return nil, errors.NewInvalidParameterError("id", "expired silence can not be updated")
+	}
+	if req.StartTime > 0 {
+		data.StartTime = fromMilliseconds(req.StartTime)
+	}
+	if req.EndTime > 0 {
+		data.EndTime = fromMilliseconds(req.EndTime)
+	}
+	if len(req.Matchers) > 0 {
+		data.Matchers = toMatchers(req.Matchers).JSON()
+	}
+	data.Comment = req.Comment
+	if _, err := silence.NewSilence(data); err != nil {
+		return nil, errors.NewInvalidParameterError("silence", err.Error())
+	}
+	if err := s.p.db.AlertSilence.Update(data); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.UpdateAlertSilenceResponse{Data: toPbSilence(data, now)}, nil
+}
+
+func (s *alertSilenceService) GetAlertSilence(ctx context.Context, req *pb.GetAlertSilenceRequest) (*pb.GetAlertSilenceResponse, error) {
+	data, err := s.getSilence(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetAlertSilenceResponse{Data: toPbSilence(data, time.Now())}, nil
+}
+
+func (s *alertSilenceService) QueryAlertSilences(ctx context.Context, req *pb.QueryAlertSilencesRequest) (*pb.QueryAlertSilencesResponse, error) {
+	if err := checkScope(req.Scope, req.ScopeID); err != nil {
+		return nil, err
+	}
+	switch req.State {
+	case "", db.SilenceStatePending, db.SilenceStateActive, db.SilenceStateExpired:
+	default:
+		return nil, errors.NewInvalidParameterError("state", "state must be pending, active or expired")
+	}
+	pageNo, pageSize := normalizePage(req.PageNo, req.PageSize)
+	now := time.Now()
+	list, total, err := s.p.db.AlertSilence.QueryByScope(req.Scope, req.ScopeID, req.State, now, pageNo, pageSize)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	result := &pb.QueryAlertSilencesData{Total: total}
+	for _, item := range list {
+		result.List = append(result.List, toPbSilence(item, now))
+	}
+	return &pb.QueryAlertSilencesResponse{Data: result}, nil
+}
+
+func (s *alertSilenceService) ExpireAlertSilence(ctx context.Context, req *pb.ExpireAlertSilenceRequest) (*pb.ExpireAlertSilenceResponse, error) {
+	if _, err := s.getSilence(req.Id); err != nil {
+		return nil, err
+	}
+	if err := s.p.db.AlertSilence.Expire(req.Id, time.Now()); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.ExpireAlertSilenceResponse{Data: true}, nil
+}
+
+func (s *alertSilenceService) getSilence(id string) (*db.AlertSilence, error) {
+	if id == "" {
+		return nil, errors.NewMissingParameterError("id")
+	}
+	data, err := s.p.db.AlertSilence.GetByID(id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if data == nil {
+		return nil, errors.NewNotFoundError("alert silence")
+	}
+	return data, nil
+}
+
+func (s *alertSilenceService) CreateAlertMaintenanceWindow(ctx context.Context, req *pb.CreateAlertMaintenanceWindowRequest) (*pb.CreateAlertMaintenanceWindowResponse, error) {
+	if req.Data == nil {
+		return nil, errors.NewMissingParameterError("data")
+	}
+	orgID, err := parseOrgID(ctx, req.Data.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScope(req.Data.Scope, req.Data.ScopeID); err != nil {
+		return nil, err
+	}
+	data := &db.AlertMaintenanceWindow{
+		Id:      uuid.UUID(),
+		OrgID:   orgID,
+		Scope:   req.Data.Scope,
+		ScopeID: req.Data.ScopeID,
+		Creator: apis.GetUserID(ctx),
+	}
+	setMaintenanceWindow(data, req.Data)
+	if _, err := silence.NewMaintenanceWindow(data); err != nil {
+		return nil, errors.NewInvalidParameterError("maintenanceWindow", err.Error())
+	}
+	if err := s.p.db.AlertMaintenanceWindow.Create(data); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.CreateAlertMaintenanceWindowResponse{Data: toPbMaintenanceWindow(data, time.Now())}, nil
+}
+
+func (s *alertSilenceService) UpdateAlertMaintenanceWindow(ctx context.Context, req *pb.UpdateAlertMaintenanceWindowRequest) (*pb.UpdateAlertMaintenanceWindowResponse, error) {
+	if req.Data == nil {
+		return nil, errors.NewMissingParameterError("data")
+	}
+	data, err := s.getMaintenanceWindow(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	setMaintenanceWindow(data, req.Data)
+	if _, err := silence.NewMaintenanceWindow(data); err != nil {
+		return nil, errors.NewInvalidParameterError("maintenanceWindow", err.Error())
+	}
+	if err := s.p.db.AlertMaintenanceWindow.Update(data); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.UpdateAlertMaintenanceWindowResponse{Data: toPbMaintenanceWindow(data, time.Now())}, nil
+}
+
+func (s *alertSilenceService) GetAlertMaintenanceWindow(ctx context.Context, req *pb.GetAlertMaintenanceWindowRequest) (*pb.GetAlertMaintenanceWindowResponse, error) {
+	data, err := s.getMaintenanceWindow(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetAlertMaintenanceWindowResponse{Data: toPbMaintenanceWindow(data, time.Now())}, nil
+}
+
+func (s *alertSilenceService) QueryAlertMaintenanceWindows(ctx context.Context, req *pb.QueryAlertMaintenanceWindowsRequest) (*pb.QueryAlertMaintenanceWindowsResponse, error) {
+	if err := checkScope(req.Scope, req.ScopeID); err != nil {
+		return nil, err
+	}
+	pageNo, pageSize := normalizePage(req.PageNo, req.PageSize)
+	list, total, err := s.p.db.AlertMaintenanceWindow.QueryByScope(req.Scope, req.ScopeID, pageNo, pageSize)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	now := time.Now()
+	result := &pb.QueryAlertMaintenanceWindowsData{Total: total}
+	for _, item := range list {
+		result.List = append(result.List, toPbMaintenanceWindow(item, now))
+	}
+	return &pb.QueryAlertMaintenanceWindowsResponse{Data: result}, nil
+}
+
+func (s *alertSilenceService) DeleteAlertMaintenanceWindow(ctx context.Context, req *pb.DeleteAlertMaintenanceWindowRequest) (*pb.DeleteAlertMaintenanceWindowResponse, error) {
+	if _, err := s.getMaintenanceWindow(req.Id); err != nil {
+		return nil, err
+	}
+	if err := s.p.db.AlertMaintenanceWindow.DeleteByID(req.Id); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.DeleteAlertMaintenanceWindowResponse{Data: true}, nil
+}
+
+func (s *alertSilenceService) getMaintenanceWindow(id string) (*db.AlertMaintenanceWindow, error) {
+	if id == "" {
+		return nil, errors.NewMissingParameterError("id")
+	}
+	data, err := s.p.db.AlertMaintenanceWindow.GetByID(id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if data == nil {
+		return nil, errors.NewNotFoundError("alert maintenance window")
+	}
+	return data, nil
+}
+
+func (s *alertSilenceService) CreateAlertInhibitRule(ctx context.Context, req *pb.CreateAlertInhibitRuleRequest) (*pb.CreateAlertInhibitRuleResponse, error) {
+	if req.Data == nil {
+		return nil, errors.NewMissingParameterError("data")
+	}
+	orgID, err := parseOrgID(ctx, req.Data.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScope(req.Data.Scope, req.Data.ScopeID); err != nil {
+		return nil, err
+	}
+	data := &db.AlertInhibitRule{
+		Id:      uuid.UUID(),
+		OrgID:   orgID,
+		Scope:   req.Data.Scope,
+		ScopeID: req.Data.ScopeID,
+		Creator: apis.GetUserID(ctx),
+	}
+	setInhibitRule(data, req.Data)
+	if _, err := silence.NewInhibitRule(data); err != nil {
+		return nil, errors.NewInvalidParameterError("inhibitRule", err.Error())
+	}
+	if err := s.p.db.AlertInhibitRule.Create(data); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.CreateAlertInhibitRuleResponse{Data: toPbInhibitRule(data)}, nil
+}
+
+func (s *alertSilenceService) UpdateAlertInhibitRule(ctx context.Context, req *pb.UpdateAlertInhibitRuleRequest) (*pb.UpdateAlertInhibitRuleResponse, error) {
+	if req.Data == nil {
+		return nil, errors.NewMissingParameterError("data")
+	}
+	data, err := s.getInhibitRule(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	setInhibitRule(data, req.Data)
+	if _, err := silence.NewInhibitRule(data); err != nil {
+		return nil, errors.NewInvalidParameterError("inhibitRule", err.Error())
+	}
+	if err := s.p.db.AlertInhibitRule.Update(data); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.UpdateAlertInhibitRuleResponse{Data: toPbInhibitRule(data)}, nil
+}
+
+func (s *alertSilenceService) GetAlertInhibitRule(ctx context.Context, req *pb.GetAlertInhibitRuleRequest) (*pb.GetAlertInhibitRuleResponse, error) {
+	data, err := s.getInhibitRule(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetAlertInhibitRuleResponse{Data: toPbInhibitRule(data)}, nil
+}
+
+func (s *alertSilenceService) QueryAlertInhibitRules(ctx context.Context, req *pb.QueryAlertInhibitRulesRequest) (*pb.QueryAlertInhibitRulesResponse, error) {
+	if err := checkScope(req.Scope, req.ScopeID); err != nil {
+		return nil, err
+	}
+	pageNo, pageSize := normalizePage(req.PageNo, req.PageSize)
+	list, total, err := s.p.db.AlertInhibitRule.QueryByScope(req.Scope, req.ScopeID, pageNo, pageSize)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	result := &pb.QueryAlertInhibitRulesData{Total: total}
+	for _, item := range list {
+		result.List = append(result.List, toPbInhibitRule(item))
+	}
+	return &pb.QueryAlertInhibitRulesResponse{Data: result}, nil
+}
+
+func (s *alertSilenceService) DeleteAlertInhibitRule(ctx context.Context, req *pb.DeleteAlertInhibitRuleRequest) (*pb.DeleteAlertInhibitRuleResponse, error) {
+	if _, err := s.getInhibitRule(req.Id); err != nil {
+		return nil, err
+	}
+	if err := s.p.db.AlertInhibitRule.DeleteByID(req.Id); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &pb.DeleteAlertInhibitRuleResponse{Data: true}, nil
+}
+
+func (s *alertSilenceService) getInhibitRule(id string) (*db.AlertInhibitRule, error) {
+	if id == "" {
+		return nil, errors.NewMissingParameterError("id")
+	}
+	data, err := s.p.db.AlertInhibitRule.GetByID(id)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if data == nil {
+		return nil, errors.NewNotFoundError("alert inhibit rule")
+	}
+	return data, nil
+}
+
+// maxFiringAlerts limits the firing alerts of a scope loaded for the inhibit rules
+const maxFiringAlerts = 1000
+
+// MuteAlert is called by the notification senders before an alert is notified.
+// Firing alerts are read from the alert events, the labels of a firing alert are kept by its event here,
+// since alerts of the stream jobs are only written to the events with the fields of the event.
+func (s *alertSilenceService) MuteAlert(ctx context.Context, req *pb.MuteAlertRequest) (*pb.MuteAlertResponse, error) {
+	if !apis.IsInternalClient(ctx) {
+		return nil, errors.NewPermissionError("alert silence", "mute", "internal client only")
+	}
+	scope, scopeID := req.Labels[silence.ScopeLabel], req.Labels[silence.ScopeIDLabel]
+	if err := checkScope(scope, scopeID); err != nil {
+		return nil, err
+	}
+	if groupID := req.Labels["group_id"]; groupID != "" && req.Labels["trigger"] == "alert" {
+		if err := s.p.db.AlertEventDB.UpdateFiringLabels(groupID, silence.EventLabels(req.Labels)); err != nil {
+			s.p.L.Warnf("failed to save the labels of alert group %s: %s", groupID, err)
+		}
+	}
+	now := time.Now()
+	set, err := s.loadMuteSet(now)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	var firing []*silence.Alert
+	if set.HasInhibitRules(scope, scopeID) {
+		events, err := s.p.db.AlertEventDB.QueryByCondition(scope, scopeID, &db.AlertEventQueryCondition{
+			AlertStates: []string{"alert"},
+		}, nil, 1, maxFiringAlerts)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		for _, e := range events {
+			firing = append(firing, silence.EventAlert(e))
+		}
+	}
+	reason := set.Muter(now, firing).Mutes(&silence.Alert{GroupID: req.Labels["group_id"], Labels: req.Labels})
+	if reason == nil {
+		return &pb.MuteAlertResponse{}, nil
+	}
+	return &pb.MuteAlertResponse{Data: &pb.AlertMuteReason{Type: reason.Type, Id: reason.ID}}, nil
+}
+
+// loadMuteSet returns the silences, maintenance windows and inhibit rules loaded within muteSetTTL,
+// since every alert notification is checked by MuteAlert.
+func (s *alertSilenceService) loadMuteSet(now time.Time) (*silence.Set, error) {
+	s.muteLock.Lock()
+	defer s.muteLock.Unlock()
+	if s.muteSet != nil && now.Sub(s.muteLoadedAt) < muteSetTTL {
+		return s.muteSet, nil
+	}
+	set, err := silence.Load(&s.p.db.AlertSilence, &s.p.db.AlertMaintenanceWindow, &s.p.db.AlertInhibitRule, now, s.p.L.Warnf)
+	if err != nil {
+		return nil, err
+	}
+	s.muteSet, s.muteLoadedAt = set, now
+	return set, nil
+}
+
+func parseOrgID(ctx context.Context, orgID string) (int64, error) {
+	if len(orgID) == 0 {
+		orgID = apis.GetOrgID(ctx)
+	}
+	id, err := strconv.ParseInt(orgID, 10, 64)
+	if err != nil {
+		return 0, errors.NewInvalidParameterError("orgId", "invalid orgId")
+	}
+	return id, nil
+}
+
+func checkScope(scope, scopeID string) error {
+	if scope == "" {
+		return errors.NewMissingParameterError("scope")
+	}
+	if scopeID == "" {
+		return errors.NewMissingParameterError("scopeID")
+	}
+	return nil
+}
+
+func normalizePage(pageNo, pageSize int64) (int64, int64) {
+	if pageNo <= 0 {
+		pageNo = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	return pageNo, pageSize
+}
+
+func fromMilliseconds(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+func toMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func toMatchers(list []*pb.LabelMatcher) silence.Matchers {
+	matchers := make(silence.Matchers, 0, len(list))
+	for _, item := range list {
+		if item == nil {
+			continue
+		}
+		matchers = append(matchers, &silence.Matcher{Name: item.Name, Operator: item.Operator, Value: item.Value})
+	}
+	return matchers
+}
+
+func toPbMatchers(text string) []*pb.LabelMatcher {
+	var matchers silence.Matchers
+	if err := json.Unmarshal([]byte(text), &matchers); err != nil {
+		return nil
+	}
+	list := make([]*pb.LabelMatcher, 0, len(matchers))
+	for _, m := range matchers {
+		list = append(list, &pb.LabelMatcher{Name: m.Name, Operator: m.Operator, Value: m.Value})
+	}
+	return list
+}
+
+func toPbSilence(data *db.AlertSilence, now time.Time) *pb.AlertSilence {
+	return &pb.AlertSilence{
+		Id:         data.Id,
+		OrgID:      strconv.FormatInt(data.OrgID, 10),
+		Scope:      data.Scope,
+		ScopeID:    data.ScopeID,
+		Matchers:   toPbMatchers(data.Matchers),
+		StartTime:  toMilliseconds(data.StartTime),
+		EndTime:    toMilliseconds(data.EndTime),
+		Creator:    data.Creator,
+		Comment:    data.Comment,
+		State:      silence.SilenceState(data.StartTime, data.EndTime, now),
+		CreateTime: toMilliseconds(data.CreatedAt),
+		UpdateTime: toMilliseconds(data.UpdatedAt),
+	}
+}
+
+func setMaintenanceWindow(data *db.AlertMaintenanceWindow, req *pb.AlertMaintenanceWindow) {
+	data.Name = req.Name
+	data.Matchers = toMatchers(req.Matchers).JSON()
+	data.Timezone = req.Timezone
+	weekdays := make([]string, 0, len(req.Weekdays))
+	for _, d := range req.Weekdays {
+		weekdays = append(weekdays, strconv.Itoa(int(d)))
+	}
+	data.Weekdays = strings.Join(weekdays, ",")
+	data.StartTime = req.StartTime
+	data.Duration = req.Duration
+	data.EffectiveFrom, data.EffectiveUntil = nil, nil
+	if req.EffectiveFrom > 0 {
+		t := fromMilliseconds(req.EffectiveFrom)
+		data.EffectiveFrom = &t
+	}
+	if req.EffectiveUntil > 0 {
+		t := fromMilliseconds(req.EffectiveUntil)
+		data.EffectiveUntil = &t
+	}
+	data.Enabled = req.Enable
+	data.Comment = req.Comment
+}
+
+func toPbMaintenanceWindow(data *db.AlertMaintenanceWindow, now time.Time) *pb.AlertMaintenanceWindow {
+	result := &pb.AlertMaintenanceWindow{
+		Id:         data.Id,
+		OrgID:      strconv.FormatInt(data.OrgID, 10),
+		Scope:      data.Scope,
+		ScopeID:    data.ScopeID,
+		Name:       data.Name,
+		Matchers:   toPbMatchers(data.Matchers),
+		Timezone:   data.Timezone,
+		StartTime:  data.StartTime,
+		Duration:   data.Duration,
+		Enable:     data.Enabled,
+		Creator:    data.Creator,
+		Comment:    data.Comment,
+		CreateTime: toMilliseconds(data.CreatedAt),
+		UpdateTime: toMilliseconds(data.UpdatedAt),
+	}
+	if weekdays, err := silence.ParseWeekdays(data.Weekdays); err == nil {
+		for _, d := range weekdays {
+			result.Weekdays = append(result.Weekdays, int32(d))
+		}
+	}
+	if data.EffectiveFrom != nil {
+		result.EffectiveFrom = toMilliseconds(*data.EffectiveFrom)
+	}
+	if data.EffectiveUntil != nil {
+		result.EffectiveUntil = toMilliseconds(*data.EffectiveUntil)
+	}
+	if w, err := silence.NewMaintenanceWindow(data); err == nil {
+		result.Active = data.Enabled && w.Active(now)
+	}
+	return result
+}
+
+func setInhibitRule(data *db.AlertInhibitRule, req *pb.AlertInhibitRule) {
+	data.Name = req.Name
+	data.SourceMatchers = toMatchers(req.SourceMatchers).JSON()
+	data.TargetMatchers = toMatchers(req.TargetMatchers).JSON()
+	var equal []string
+	for _, name := range req.Equal {
+		if name = strings.TrimSpace(name); name != "" {
+			equal = append(equal, name)
+		}
+	}
+	data.EqualLabels = strings.Join(equal, ",")
+	data.Enabled = req.Enable
+	data.Comment = req.Comment
+}
+
+func toPbInhibitRule(data *db.AlertInhibitRule) *pb.AlertInhibitRule {
+	return &pb.AlertInhibitRule{
+		Id:             data.Id,
+		OrgID:          strconv.FormatInt(data.OrgID, 10),
+		Scope:          data.Scope,
+		ScopeID:        data.ScopeID,
+		Name:           data.Name,
+		SourceMatchers: toPbMatchers(data.SourceMatchers),
+		TargetMatchers: toPbMatchers(data.TargetMatchers),
+		Equal:          silence.ParseLabelNames(data.EqualLabels),
+		Enable:         data.Enabled,
+		Creator:        data.Creator,
+		Comment:        data.Comment,
+		CreateTime:     toMilliseconds(data.CreatedAt),
+		UpdateTime:     toMilliseconds(data.UpdatedAt),
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/internal/pkg/metrics/report"
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/silence"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+const (
+	alertMetricName     = "analyzer_alert"
+	defaultAlertSource  = "System"
+	expressionKeyPrefix = "alert_"
+)
+
+// alertContext is the rendered alert of a transition
+type alertContext struct {
+	groupID    string
+	alertGroup string
+	tags       map[string]string
+	fields     map[string]interface{}
+}
+
+var placeholderRegexp = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+// render replaces the {{key}} placeholders of the template
+func render(tmpl string, vars map[string]string) string {
+	return placeholderRegexp.ReplaceAllStringFunc(tmpl, func(s string) string {
+		key := placeholderRegexp.FindStringSubmatch(s)[1]
+		return vars[key]
+	})
+}
+
+func (r *rule) alertContext(tr *transition) *alertContext {
+	tags := make(map[string]string)
+	for k, v := range r.attributes {
+		if v == nil {
+			continue
+		}
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		}
+		tags[k] = fmt.Sprint(v)
+	}
+	s := tr.sample
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	for key, value := range r.selects {
+		if tag := strings.TrimPrefix(value, tagPrefix); tag != value {
+			if v, ok := s.tags[tag]; ok {
+				tags[key] = v
+			}
+			continue
+		}
+		tags[key] = value
+	}
+	tags["trigger"] = tr.trigger
+
+	fields := make(map[string]interface{})
+	for k, v := range s.values {
+		if v != nil {
+			fields[k] = v
+		}
+	}
+	if tr.trigger == triggerRecover {
+		fields["trigger_duration"] = tr.duration.Milliseconds()
+	}
+
+	alertGroup := s.key
+	if tmpl := attributeString(r.attributes, "alert_group"); tmpl != "" {
+		alertGroup = render(tmpl, tags)
+	}
+	tags["alert_group"] = alertGroup
+	sum := md5.Sum([]byte(strconv.FormatUint(r.id, 10) + "-" + alertGroup))
+	groupID := hex.EncodeToString(sum[:])
+	tags["group_id"] = groupID
+	return &alertContext{groupID: groupID, alertGroup: alertGroup, tags: tags, fields: fields}
+}
+
+// emit writes the alert event and record consumed by the alert apis, and reports the analyzer_alert metric.
+// Muted alerts are still written and reported to keep the alert history, but flagged as suppressed
+// and not notified.
+func (p *provider) emit(r *rule, tr *transition, muter *silence.Muter, n *notifier) error {
+	if tr.trigger == triggerRecover && !r.recover {
+		return nil
+	}
+	ac := r.alertContext(tr)
+	reason := muter.Mutes(&silence.Alert{GroupID: ac.groupID, Labels: ac.tags})
+	eventID, suppressed, err := p.saveAlertEvent(r, tr, ac)
+	if err != nil {
+		return fmt.Errorf("save alert event: %w", err)
+	}
+	if err := p.saveAlertRecord(r, tr, ac); err != nil {
+		return fmt.Errorf("save alert record: %w", err)
+	}
+	if !suppressed && reason == nil {
+		p.notify(n, r, tr, ac)
+	}
+	if p.Report == nil {
+		return nil
+	}
+	ac.tags["alert_event_id"] = eventID
+	ac.tags["alert_suppressed"] = strconv.FormatBool(suppressed || reason != nil)
+	if reason != nil {
+		ac.tags["alert_suppressed_by"] = reason.Type
+		ac.tags["alert_suppressed_id"] = reason.ID
+	}
+	return p.Report.Send([]*report.Metric{{
+		Name:      alertMetricName,
+		Timestamp: tr.at.UnixNano(),
+		Tags:      ac.tags,
+		Fields:    ac.fields,
+	}})
+}
+
+func (p *provider) saveAlertEvent(r *rule, tr *transition, ac *alertContext) (string, bool, error) {
+	scope, scopeID := ac.tags["alert_scope"], ac.tags["alert_scope_id"]
+	exist, err := p.alertEventDB.GetByAlertGroupID(ac.groupID)
+	if err != nil {
+		return "", false, err
+	}
+	if exist == nil {
+		if tr.trigger == triggerRecover {
+			return "", false, nil
+		}
+		source := ac.tags["alert_source"]
+		if source == "" {
+			source = defaultAlertSource
+		}
+		orgID, _ := strconv.ParseInt(ac.tags["org_id"], 10, 64)
+		ruleID, _ := strconv.ParseUint(ac.tags["rule_id"], 10, 64)
+		event := &db.AlertEvent{
+			Id:               uuid.UUID(),
+			Name:             ac.tags["alert_title"],
+			OrgID:            orgID,
+			AlertGroupID:     ac.groupID,
+			AlertGroup:       ac.alertGroup,
+			Scope:            scope,
+			ScopeID:          scopeID,
+			AlertID:          r.alertID,
+			AlertName:        ac.tags["alert_name"],
+			AlertType:        ac.tags["alert_type"],
+			AlertIndex:       ac.tags["alert_index"],
+			AlertLevel:       ac.tags["level"],
+			AlertSource:      source,
+			AlertSubject:     ac.alertGroup,
+			AlertState:       tr.trigger,
+			RuleID:           ruleID,
+			RuleName:         ac.tags["alert_name"],
+			ExpressionID:     r.id,
+			FirstTriggerTime: tr.at,
+			LastTriggerTime:  tr.at,
+			Labels:           silence.EventLabels(ac.tags),
+		}
+		return event.Id, false, p.alertEventDB.CreateAlertEvent(event)
+	}
+
+	enabled := true
+	list, err := p.alertEventSuppressDB.QueryByCondition(scope, scopeID, &db.AlertEventSuppressQueryCondition{
+		SuppressTypes: []string{db.SuppressTypePause, db.SuppressTypeStop},
+		EventIds:      []string{exist.Id},
+		Enabled:       &enabled,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	suppressed := len(list) > 0
+
+	fields := map[string]interface{}{
+		"alert_state":       tr.trigger,
+		"alert_level":       ac.tags["level"],
+		"last_trigger_time": tr.at,
+	}
+	if tr.trigger == triggerAlert {
+		fields["labels"] = silence.EventLabels(ac.tags)
+	}
+	return exist.Id, suppressed, p.alertEventDB.UpdateAlertEvent(exist.Id, fields)
+}
+
+func (p *provider) saveAlertRecord(r *rule, tr *transition, ac *alertContext) error {
+	now := time.Now()
+	exist := &db.AlertRecord{}
+	err := p.DB.Model(&db.AlertRecord{}).Where("group_id = ?", ac.groupID).First(exist).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if exist.GroupID != "" {
+		return p.DB.Model(&db.AlertRecord{}).Where("group_id = ?", ac.groupID).Updates(map[string]interface{}{
+			"alert_state": tr.trigger,
+			"alert_time":  tr.at,
+			"update_time": now,
+		}).Error
+	}
+	if tr.trigger == triggerRecover {
+		return nil
+	}
+	ruleID, _ := strconv.ParseUint(ac.tags["rule_id"], 10, 64)
+	return p.DB.Create(&db.AlertRecord{
+		GroupID:       ac.groupID,
+		Scope:         ac.tags["alert_scope"],
+		ScopeKey:      ac.tags["alert_scope_id"],
+		AlertGroup:    ac.alertGroup,
+		Title:         ac.tags["alert_title"],
+		AlertState:    tr.trigger,
+		AlertType:     ac.tags["alert_type"],
+		AlertIndex:    ac.tags["alert_index"],
+		ExpressionKey: expressionKeyPrefix + strconv.FormatUint(r.id, 10),
+		AlertID:       r.alertID,
+		AlertName:     ac.tags["alert_name"],
+		RuleID:        ruleID,
+		AlertTime:     tr.at,
+		CreateTime:    now,
+		UpdateTime:    now,
+	}).Error
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/erda-project/erda-infra/pkg/transport"
+	metricpb "github.com/erda-project/erda-proto-go/core/monitor/metric/pb"
+	"github.com/erda-project/erda/pkg/common/apis"
+)
+
+// evaluate runs the rule query of every metric and returns one sample per alert group
+func (p *provider) evaluate(ctx context.Context, r *rule, now time.Time) ([]*sample, error) {
+	cols, err := r.columns()
+	if err != nil {
+		return nil, err
+	}
+	filters, err := r.queryFilters()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Cfg.QueryTimeout)
+	defer cancel()
+	ctx = apis.GetContext(ctx, func(header *transport.Header) {
+		if org := attributeString(r.attributes, "org_name"); org != "" {
+			header.Set("org", org)
+		}
+		if tk := attributeString(r.attributes, "terminus_key"); tk != "" {
+			header.Set("terminus_key", tk)
+		}
+	})
+
+	var samples []*sample
+	for _, metric := range r.metrics {
+		resp, err := p.Metric.QueryWithInfluxFormat(ctx, &metricpb.QueryWithInfluxFormatRequest{
+			Start:     strconv.FormatInt(now.Add(-r.window).UnixNano()/int64(time.Millisecond), 10),
+			End:       strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10),
+			Statement: r.statement(metric, cols, p.Cfg.MaxGroups),
+			Filters:   filters,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("query metric %s: %w", metric, err)
+		}
+		samples = append(samples, r.samples(cols, resp.Results)...)
+	}
+	return samples, nil
+}
+
+func (r *rule) queryFilters() ([]*metricpb.Filter, error) {
+	var filters []*metricpb.Filter
+	for _, f := range r.filters {
+		if f.Tag == "" || !pushdownFilter(f) || skipFilter(f) {
+			continue
+		}
+		value := f.Value
+		if f.Operator == "in" {
+			value = toSlice(value)
+		}
+		val, err := structpb.NewValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of filter %s: %w", f.Tag, err)
+		}
+		filters = append(filters, &metricpb.Filter{Key: "tags." + f.Tag, Op: f.queryOperator(), Value: val})
+	}
+	return filters, nil
+}
+
+// samples converts the query results to samples and evaluates the trigger condition
+func (r *rule) samples(cols []*column, results []*metricpb.Result) []*sample {
+	var samples []*sample
+	for _, result := range results {
+		for _, serie := range result.Series {
+			for _, row := range serie.Rows {
+				if s := r.newSample(cols, row.Values); s != nil {
+					samples = append(samples, s)
+				}
+			}
+		}
+	}
+	return samples
+}
+
+func (r *rule) newSample(cols []*column, values []*structpb.Value) *sample {
+	s := &sample{
+		tags:   make(map[string]string),
+		values: make(map[string]interface{}),
+	}
+	for i, col := range cols {
+		if i >= len(values) || values[i] == nil {
+			continue
+		}
+		val := values[i].AsInterface()
+		if col.tag {
+			if val != nil {
+				s.tags[col.key] = fmt.Sprint(val)
+			}
+			continue
+		}
+		s.values[col.key] = val
+	}
+	for _, f := range r.filters {
+		if f.Tag != "" && !pushdownFilter(f) && !skipFilter(f) && !f.matchTag(s.tags) {
+			return nil
+		}
+	}
+	keys := make([]string, 0, len(r.group))
+	for _, tag := range r.group {
+		keys = append(keys, tag+"="+s.tags[tag])
+	}
+	s.key = strings.Join(keys, ",")
+
+	conditions := 0
+	s.triggered = true
+	for _, fn := range r.functions {
+		if fn.Operator == "" || fn.Value == nil {
+			continue
+		}
+		conditions++
+		if !compare(fn.Operator, s.values[fn.outputKey()], fn.Value) {
+			s.triggered = false
+		}
+	}
+	if conditions == 0 {
+		s.triggered = false
+	}
+	return s
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erda-project/erda-proto-go/core/monitor/expression/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+	"github.com/erda-project/erda/internal/tools/monitor/core/expression/model"
+	"github.com/erda-project/erda/internal/tools/monitor/utils"
+)
+
+const (
+	notifySender         = "analyzer-alert"
+	notifyTypeGroup      = "notify_group"
+	notifyTypeDingding   = "dingding"
+	notifyTemplateFormat = "markdown"
+)
+
+// messageSender sends messages by eventbox, it is implemented by bundle
+type messageSender interface {
+	CreateMessage(message *apistructs.MessageCreateRequest) error
+}
+
+// notifier renders the notify templates of an alert and sends them to its notify targets
+// like the stream job does. It is loaded on every round, except the sent times of notifies.
+type notifier struct {
+	notifies  map[uint64][]*db.AlertNotify
+	templates map[string][]*model.NotifyTemplate
+	locales   map[string]string
+	sent      *sentNotifies
+}
+
+// sentNotifies keeps when an alert group is notified to a target last time,
+// so that a firing alert is notified again only after the silence of the target.
+type sentNotifies struct {
+	lock sync.Mutex
+	at   map[string]time.Time
+}
+
+func newSentNotifies() *sentNotifies {
+	return &sentNotifies{at: make(map[string]time.Time)}
+}
+
+// shouldSend reports whether to notify the transition of the alert group, recovers are always notified.
+func (s *sentNotifies) shouldSend(key string, tr *transition, silence time.Duration) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if tr.trigger == triggerRecover {
+		delete(s.at, key)
+		return true
+	}
+	if last, ok := s.at[key]; ok && tr.at.Sub(last) < silence {
+		return false
+	}
+	s.at[key] = tr.at
+	return true
+}
+
+func templateKey(alertIndex, trigger, target string) string {
+	return alertIndex + "/" + trigger + "/" + target
+}
+
+// loadNotifier loads the notify targets of rules, notify templates and locales of orgs,
+// nothing is notified in this round if they can not be loaded.
+func (p *provider) loadNotifier(ctx context.Context, rules []*rule, sent *sentNotifies) *notifier {
+	alertIDs := make([]uint64, 0, len(rules))
+	seen := make(map[uint64]bool, len(rules))
+	for _, r := range rules {
+		if !seen[r.alertID] {
+			seen[r.alertID] = true
+			alertIDs = append(alertIDs, r.alertID)
+		}
+	}
+	n := &notifier{
+		notifies:  make(map[uint64][]*db.AlertNotify),
+		templates: make(map[string][]*model.NotifyTemplate),
+		sent:      sent,
+	}
+	if len(alertIDs) == 0 {
+		return n
+	}
+	notifies, err := p.alertNotifyDB.QueryByAlertIDs(alertIDs)
+	if err != nil {
+		p.Log.Warnf("failed to load alert notifies: %s", err)
+		return nil
+	}
+	for _, item := range notifies {
+		if item.Enable {
+			n.notifies[item.AlertID] = append(n.notifies[item.AlertID], item)
+		}
+	}
+
+	for pageNo := int64(1); ; pageNo++ {
+		resp, err := p.Expression.GetTemplates(ctx, &pb.GetTemplatesRequest{PageNo: pageNo, PageSize: p.Cfg.PageSize})
+		if err != nil {
+			p.Log.Warnf("failed to load alert notify templates: %s", err)
+			return nil
+		}
+		if resp.Data == nil || len(resp.Data.List) == 0 {
+			break
+		}
+		// same as the expression service, templates are converted by json
+		data, err := json.Marshal(resp.Data.List)
+		if err != nil {
+			p.Log.Warnf("failed to marshal alert notify templates: %s", err)
+			return nil
+		}
+		var templates []*model.NotifyTemplate
+		if err := json.Unmarshal(data, &templates); err != nil {
+			p.Log.Warnf("failed to unmarshal alert notify templates: %s", err)
+			return nil
+		}
+		for _, t := range templates {
+			key := templateKey(t.AlertIndex, t.Trigger, t.Target)
+			n.templates[key] = append(n.templates[key], t)
+		}
+		if pageNo*p.Cfg.PageSize >= resp.Data.Total {
+			break
+		}
+	}
+
+	locales, err := p.Expression.GetOrgsLocale(ctx, &pb.GetOrgsLocaleRequest{})
+	if err != nil {
+		p.Log.Warnf("failed to load locales of orgs: %s", err)
+	} else {
+		n.locales = locales.Data
+	}
+	return n
+}
+
+// notify sends the transition to every notify target of the alert.
+func (p *provider) notify(n *notifier, r *rule, tr *transition, ac *alertContext) {
+	if n == nil || p.bdl == nil {
+		return
+	}
+	for _, target := range n.notifies[r.alertID] {
+		key := ac.groupID + "-" + strconv.FormatUint(target.ID, 10)
+		if !n.sent.shouldSend(key, tr, time.Duration(target.Silence)*time.Millisecond) {
+			continue
+		}
+		msg, err := n.message(target, tr.trigger, ac)
+		if err != nil {
+			p.Log.Warnf("invalid alert notify %d of alert %d: %s", target.ID, r.alertID, err)
+			continue
+		}
+		if msg == nil {
+			continue
+		}
+		if err := p.bdl.CreateMessage(msg); err != nil {
+			p.Log.Warnf("failed to send %s of alert group %s to notify %d: %s", tr.trigger, ac.groupID, target.ID, err)
+		}
+	}
+}
+
+// template returns the template in the org's locale, or any one if there is no such language.
+func (n *notifier) template(ac *alertContext, trigger, target string) *model.NotifyTemplate {
+	list := n.templates[templateKey(ac.tags["alert_index"], trigger, target)]
+	if len(list) == 0 {
+		return nil
+	}
+	locale := n.locales[ac.tags["org_name"]]
+	if locale == "" {
+		locale = model.ZHLange
+	}
+	for _, t := range list {
+		if t.Language == locale {
+			return t
+		}
+	}
+	return list[0]
+}
+
+// message makes the eventbox message of the notify target, nil if there is no template for it.
+func (n *notifier) message(target *db.AlertNotify, trigger string, ac *alertContext) (*apistructs.MessageCreateRequest, error) {
+	vars := make(map[string]string, len(ac.tags)+len(ac.fields))
+	for k, v := range ac.fields {
+		vars[k] = fmt.Sprint(v)
+	}
+	for k, v := range ac.tags {
+		vars[k] = v
+	}
+
+	notifyType, _ := utils.GetMapValueString(target.NotifyTarget, "type")
+	switch notifyType {
+	case notifyTypeGroup:
+		groupID, ok := utils.GetMapValueInt64(target.NotifyTarget, "group_id")
+		if !ok {
+			return nil, fmt.Errorf("group_id not found")
+		}
+		groupType, _ := utils.GetMapValueString(target.NotifyTarget, "group_type")
+		var title string
+		var channels []apistructs.GroupNotifyChannel
+		for _, channel := range strings.Split(groupType, ",") {
+			channel = strings.TrimSpace(channel)
+			t := n.template(ac, trigger, channel)
+			if t == nil {
+				continue
+			}
+			channelTitle := render(t.Title, vars)
+			if title == "" {
+				title = channelTitle
+			}
+			// params are filled by the group subscriber of eventbox, it must not be nil
+			channels = append(channels, apistructs.GroupNotifyChannel{
+				Name:     channel,
+				Template: render(t.Template, vars),
+				Type:     notifyTemplateFormat,
+				Params:   map[string]string{"title": channelTitle},
+			})
+		}
+		if len(channels) == 0 {
+			return nil, nil
+		}
+		orgID, _ := strconv.ParseInt(ac.tags["org_id"], 10, 64)
+		notifyTags := make(map[string]interface{}, len(ac.tags))
+		for k, v := range ac.tags {
+			notifyTags[k] = v
+		}
+		return &apistructs.MessageCreateRequest{
+			Sender: notifySender,
+			Content: apistructs.GroupNotifyContent{
+				SourceName:            ac.tags["alert_name"],
+				SourceType:            ac.tags["alert_scope"],
+				SourceID:              ac.tags["alert_scope_id"],
+				NotifyName:            title,
+				NotifyItemDisplayName: title,
+				Channels:              channels,
+				OrgID:                 orgID,
+				NotifyTags:            notifyTags,
+				Label:                 ac.tags["level"],
+			},
+			Labels: map[apistructs.MessageLabel]interface{}{"GROUP": groupID},
+		}, nil
+	case notifyTypeDingding:
+		url, ok := utils.GetMapValueString(target.NotifyTarget, "dingding_url")
+		if !ok || url == "" {
+			return nil, fmt.Errorf("dingding_url not found")
+		}
+		t := n.template(ac, trigger, notifyTypeDingding)
+		if t == nil {
+			return nil, nil
+		}
+		return &apistructs.MessageCreateRequest{
+			Sender:  notifySender,
+			Content: render(t.Template, vars),
+			Labels: map[apistructs.MessageLabel]interface{}{
+				apistructs.DingdingLabel:         []string{url},
+				apistructs.DingdingMarkdownLabel: map[string]interface{}{"title": render(t.Title, vars)},
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported notify type: %s", notifyType)
+}
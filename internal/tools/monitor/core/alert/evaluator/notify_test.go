@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+	"github.com/erda-project/erda/internal/tools/monitor/core/expression/model"
+	"github.com/erda-project/erda/pkg/encoding/jsonmap"
+)
+
+func Test_sentNotifies_shouldSend(t *testing.T) {
+	base := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) *transition {
+		return &transition{trigger: triggerAlert, at: base.Add(time.Duration(minutes) * time.Minute)}
+	}
+	sent := newSentNotifies()
+	if !sent.shouldSend("g-1", at(0), 5*time.Minute) {
+		t.Fatalf("first alert should be sent")
+	}
+	if sent.shouldSend("g-1", at(3), 5*time.Minute) {
+		t.Fatalf("alert in silence should not be sent")
+	}
+	if !sent.shouldSend("g-2", at(3), 5*time.Minute) {
+		t.Fatalf("alert to another target should be sent")
+	}
+	if !sent.shouldSend("g-1", at(5), 5*time.Minute) {
+		t.Fatalf("alert after silence should be sent")
+	}
+	if !sent.shouldSend("g-1", &transition{trigger: triggerRecover, at: base.Add(6 * time.Minute)}, 5*time.Minute) {
+		t.Fatalf("recover should be sent")
+	}
+	if !sent.shouldSend("g-1", at(7), 5*time.Minute) {
+		t.Fatalf("alert after recover should be sent")
+	}
+}
+
+func Test_notifier_message(t *testing.T) {
+	n := &notifier{
+		templates: map[string][]*model.NotifyTemplate{
+			templateKey("machine_cpu", triggerAlert, "dingding"): {
+				{Title: "cpu of {{host_ip}}", Template: "cpu {{cpu_usage}}", Language: model.ENLange},
+				{Title: "{{host_ip}} cpu", Template: "cpu 使用率 {{cpu_usage}}", Language: model.ZHLange},
+			},
+			templateKey("machine_cpu", triggerAlert, "email"): {
+				{Title: "cpu of {{host_ip}}", Template: "email {{host_ip}}"},
+			},
+		},
+		locales: map[string]string{"erda": model.ENLange},
+	}
+	ac := &alertContext{
+		groupID: "g",
+		tags:    map[string]string{"alert_index": "machine_cpu", "org_name": "erda", "org_id": "1", "host_ip": "10.0.0.1"},
+		fields:  map[string]interface{}{"cpu_usage": 95.5},
+	}
+
+	msg, err := n.message(&db.AlertNotify{NotifyTarget: jsonmap.JSONMap{
+		"type": "notify_group", "group_id": 3, "group_type": "dingding,email,sms",
+	}}, triggerAlert, ac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, ok := msg.Content.(apistructs.GroupNotifyContent)
+	if !ok || msg.Labels["GROUP"] != int64(3) || content.OrgID != 1 || content.NotifyName != "cpu of 10.0.0.1" {
+		t.Fatalf("unexpected group message: %+v", msg)
+	}
+	if len(content.Channels) != 2 || content.Channels[0].Template != "cpu 95.5" || content.Channels[1].Template != "email 10.0.0.1" {
+		t.Fatalf("unexpected channels: %+v", content.Channels)
+	}
+	if content.Channels[0].Params["title"] != "cpu of 10.0.0.1" {
+		t.Fatalf("channel params must carry the title, got %+v", content.Channels[0].Params)
+	}
+
+	msg, err = n.message(&db.AlertNotify{NotifyTarget: jsonmap.JSONMap{
+		"type": "dingding", "dingding_url": "https://oapi.dingtalk.com/robot/send",
+	}}, triggerAlert, ac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "cpu 95.5" {
+		t.Fatalf("unexpected dingding message: %+v", msg)
+	}
+
+	msg, err = n.message(&db.AlertNotify{NotifyTarget: jsonmap.JSONMap{
+		"type": "notify_group", "group_id": 3, "group_type": "dingding",
+	}}, triggerRecover, ac)
+	if err != nil || msg != nil {
+		t.Fatalf("message without template should be skipped, got %+v, %v", msg, err)
+	}
+
+	if _, err := n.message(&db.AlertNotify{NotifyTarget: jsonmap.JSONMap{"type": "unknown"}}, triggerAlert, ac); err == nil {
+		t.Fatalf("unknown notify type should fail")
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-infra/base/servicehub"
+	election "github.com/erda-project/erda-infra/providers/etcd-election"
+	expressionpb "github.com/erda-project/erda-proto-go/core/monitor/expression/pb"
+	metricpb "github.com/erda-project/erda-proto-go/core/monitor/metric/pb"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/pkg/metrics/report"
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+)
+
+type config struct {
+	Interval     time.Duration `file:"interval" default:"1m"`
+	QueryTimeout time.Duration `file:"query_timeout" default:"30s"`
+	Parallelism  int           `file:"parallelism" default:"4"`
+	PageSize     int64         `file:"page_size" default:"100"`
+	MaxGroups    int           `file:"max_groups" default:"1000"`
+}
+
+// +provider
+type provider struct {
+	Cfg        *config
+	Log        logs.Logger
+	DB         *gorm.DB                             `autowired:"mysql-client"`
+	Election   election.Interface                   `autowired:"etcd-election@alert-evaluator"`
+	Metric     metricpb.MetricServiceServer         `autowired:"erda.core.monitor.metric.MetricService"`
+	Expression expressionpb.ExpressionServiceServer `autowired:"erda.core.monitor.expression.ExpressionService"`
+	Report     report.MetricReport                  `autowired:"metric-report-client" optional:"true"`
+
+	bdl                  messageSender
+	alertExpressionDB    *db.AlertExpressionDB
+	alertNotifyDB        *db.AlertNotifyDB
+	alertEventDB         *db.AlertEventDB
+	alertEventSuppressDB *db.AlertEventSuppressDB
+	silenceDB            *db.AlertSilenceDB
+	maintenanceWindowDB  *db.AlertMaintenanceWindowDB
+	inhibitRuleDB        *db.AlertInhibitRuleDB
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	if p.Cfg.Parallelism <= 0 {
+		p.Cfg.Parallelism = 1
+	}
+	p.alertExpressionDB = &db.AlertExpressionDB{DB: p.DB}
+	p.bdl = bundle.New(bundle.WithErdaServer())
+	p.alertNotifyDB = &db.AlertNotifyDB{DB: p.DB}
+	p.alertEventDB = &db.AlertEventDB{DB: p.DB}
+	p.alertEventSuppressDB = &db.AlertEventSuppressDB{DB: p.DB}
+	p.silenceDB = &db.AlertSilenceDB{DB: p.DB}
+	p.maintenanceWindowDB = &db.AlertMaintenanceWindowDB{DB: p.DB}
+	p.inhibitRuleDB = &db.AlertInhibitRuleDB{DB: p.DB}
+	p.Election.OnLeader(func(ctx context.Context) {
+		// states are kept by the leader only, a new leader starts from inactive
+		trackers := make(map[uint64]*tracker)
+		sent := newSentNotifies()
+		timer := time.NewTicker(p.Cfg.Interval)
+		defer timer.Stop()
+		for {
+			p.runOnce(ctx, trackers, sent, time.Now())
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// runOnce evaluates all enabled alert expressions
+func (p *provider) runOnce(ctx context.Context, trackers map[uint64]*tracker, sent *sentNotifies, now time.Time) {
+	rules, err := p.loadRules()
+	if err != nil {
+		p.Log.Warnf("failed to load alert expressions: %s", err)
+		return
+	}
+	active := make(map[uint64]bool, len(rules))
+	for _, r := range rules {
+		active[r.id] = true
+		if _, ok := trackers[r.id]; !ok {
+			trackers[r.id] = newTracker()
+		}
+	}
+	for id := range trackers {
+		if !active[id] {
+			delete(trackers, id)
+		}
+	}
+
+	// evaluate all rules first, so that inhibit rules see every alert firing in this round
+	transitions := make([][]*transition, len(rules))
+	p.parallel(ctx, len(rules), func(i int) {
+		transitions[i] = p.runRule(ctx, rules[i], trackers[rules[i].id], now)
+	})
+	muter := p.loadMuter(trackers, rules, now)
+	n := p.loadNotifier(ctx, rules, sent)
+	p.parallel(ctx, len(rules), func(i int) {
+		r := rules[i]
+		for _, tr := range transitions[i] {
+			if err := p.emit(r, tr, muter, n); err != nil {
+				p.Log.Warnf("failed to emit %s of alert expression %d: %s", tr.trigger, r.id, err)
+			}
+		}
+	})
+}
+
+// parallel calls fn with 0 to n-1 on Cfg.Parallelism workers
+func (p *provider) parallel(ctx context.Context, n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	ch := make(chan int)
+	for i := 0; i < p.Cfg.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				fn(i)
+			}
+		}()
+	}
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case ch <- i:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(ch)
+	wg.Wait()
+}
+
+func (p *provider) runRule(ctx context.Context, r *rule, t *tracker, now time.Time) []*transition {
+	samples, err := p.evaluate(ctx, r, now)
+	if err != nil {
+		p.Log.Warnf("failed to evaluate alert expression %d: %s", r.id, err)
+		return nil
+	}
+	return t.update(now, r.forDuration, samples)
+}
+
+func (p *provider) loadRules() ([]*rule, error) {
+	var rules []*rule
+	for pageNo := int64(1); ; pageNo++ {
+		list, total, err := p.alertExpressionDB.GetAllAlertExpression(pageNo, p.Cfg.PageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, expr := range list {
+			r, err := newRule(expr)
+			if err != nil {
+				p.Log.Warnf("invalid alert expression %d: %s", expr.ID, err)
+				continue
+			}
+			if r != nil {
+				rules = append(rules, r)
+			}
+		}
+		if len(list) == 0 || pageNo*p.Cfg.PageSize >= total {
+			break
+		}
+	}
+	return rules, nil
+}
+
+func init() {
+	servicehub.Register("erda.core.monitor.alert.evaluator", &servicehub.Spec{
+		Services:     []string{"erda.core.monitor.alert.evaluator"},
+		Dependencies: []string{"etcd-election"},
+		Description:  "evaluate alert expressions in process without the stream job",
+		ConfigFunc:   func() interface{} { return &config{} },
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
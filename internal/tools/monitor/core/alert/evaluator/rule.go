@@ -0,0 +1,398 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+)
+
+const (
+	outputAlert = "alert"
+	tagPrefix   = "#"
+)
+
+type (
+	filter struct {
+		Tag      string      `json:"tag"`
+		Operator string      `json:"operator"`
+		Value    interface{} `json:"value"`
+	}
+
+	function struct {
+		Aggregator string      `json:"aggregator"`
+		Field      string      `json:"field"`
+		Alias      string      `json:"alias"`
+		Operator   string      `json:"operator"`
+		Value      interface{} `json:"value"`
+	}
+
+	// expressionSpec is the expression json generated by adapt.ToDBAlertExpressionModel
+	expressionSpec struct {
+		Metric    string            `json:"metric"`
+		Metrics   []string          `json:"metrics"`
+		Window    float64           `json:"window"`
+		For       interface{}       `json:"for"`
+		Filters   []*filter         `json:"filters"`
+		Functions []*function       `json:"functions"`
+		Group     []string          `json:"group"`
+		Select    map[string]string `json:"select"`
+		Outputs   []string          `json:"outputs"`
+	}
+)
+
+// rule is an alert expression prepared for evaluation
+type rule struct {
+	id          uint64
+	alertID     uint64
+	metrics     []string
+	window      time.Duration
+	forDuration time.Duration
+	filters     []*filter
+	functions   []*function
+	group       []string
+	selects     map[string]string
+	attributes  map[string]interface{}
+	recover     bool
+}
+
+func newRule(expr *db.AlertExpression) (*rule, error) {
+	data, err := json.Marshal(expr.Expression)
+	if err != nil {
+		return nil, err
+	}
+	spec := &expressionSpec{}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	if !containsString(spec.Outputs, outputAlert) {
+		return nil, nil
+	}
+	r := &rule{
+		id:         expr.ID,
+		alertID:    expr.AlertID,
+		metrics:    spec.Metrics,
+		window:     time.Duration(spec.Window * float64(time.Minute)),
+		filters:    spec.Filters,
+		functions:  spec.Functions,
+		group:      spec.Group,
+		selects:    spec.Select,
+		attributes: map[string]interface{}(expr.Attributes),
+	}
+	if spec.Metric != "" {
+		r.metrics = append([]string{spec.Metric}, r.metrics...)
+	}
+	if len(r.metrics) == 0 {
+		return nil, fmt.Errorf("metric is empty")
+	}
+	if r.window <= 0 {
+		r.window = time.Minute
+	}
+	if r.forDuration, err = parseForDuration(spec.For); err != nil {
+		return nil, err
+	}
+	for _, fn := range r.functions {
+		if _, err := aggregateExpr(fn); err != nil {
+			return nil, err
+		}
+	}
+	if r.attributes == nil {
+		r.attributes = make(map[string]interface{})
+	}
+	r.recover = attributeString(r.attributes, "recover") == "true"
+	return r, nil
+}
+
+// parseForDuration accepts a number of minutes, the same unit as window, or a duration string
+func parseForDuration(v interface{}) (time.Duration, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return time.Duration(val * float64(time.Minute)), nil
+	case string:
+		if val == "" {
+			return 0, nil
+		}
+		if minutes, err := strconv.ParseFloat(val, 64); err == nil {
+			return time.Duration(minutes * float64(time.Minute)), nil
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return 0, fmt.Errorf("invalid for duration %q: %w", val, err)
+		}
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid for duration %v", v)
+}
+
+// column is one selected column of the rule query
+type column struct {
+	key  string
+	expr string
+	tag  bool
+}
+
+// columns returns the selected columns, group tags first
+func (r *rule) columns() ([]*column, error) {
+	var cols []*column
+	seen := make(map[string]bool)
+	addTag := func(tag string, grouped bool) {
+		if seen[tag] {
+			return
+		}
+		seen[tag] = true
+		expr := tag + "::tag"
+		if !grouped {
+			expr = "last(" + expr + ")"
+		}
+		cols = append(cols, &column{key: tag, expr: expr, tag: true})
+	}
+	for _, tag := range r.group {
+		addTag(tag, true)
+	}
+	for _, key := range sortedKeys(r.selects) {
+		if tag := r.selects[key]; strings.HasPrefix(tag, tagPrefix) {
+			addTag(strings.TrimPrefix(tag, tagPrefix), false)
+		}
+	}
+	for _, f := range r.filters {
+		if f.Tag != "" && !pushdownFilter(f) && !skipFilter(f) {
+			addTag(f.Tag, false)
+		}
+	}
+	for _, fn := range r.functions {
+		expr, err := aggregateExpr(fn)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, &column{key: fn.outputKey(), expr: expr})
+	}
+	return cols, nil
+}
+
+// statement builds the influxql statement of the metric, tag filters are passed separately
+func (r *rule) statement(metric string, cols []*column, limit int) string {
+	exprs := make([]string, 0, len(cols))
+	for _, col := range cols {
+		exprs = append(exprs, col.expr)
+	}
+	sb := &strings.Builder{}
+	sb.WriteString("SELECT ")
+	sb.WriteString(strings.Join(exprs, ","))
+	sb.WriteString(" FROM ")
+	sb.WriteString(metric)
+	if len(r.group) > 0 {
+		groups := make([]string, 0, len(r.group))
+		for _, tag := range r.group {
+			groups = append(groups, tag+"::tag")
+		}
+		sb.WriteString(" GROUP BY ")
+		sb.WriteString(strings.Join(groups, ","))
+	}
+	if limit > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(strconv.Itoa(limit))
+	}
+	return sb.String()
+}
+
+func (fn *function) outputKey() string {
+	if fn.Alias != "" {
+		return fn.Alias
+	}
+	return fn.Field + "_" + fn.Aggregator
+}
+
+func aggregateExpr(fn *function) (string, error) {
+	field := fn.Field + "::field"
+	switch fn.Aggregator {
+	case "sum", "avg", "max", "min", "count", "diffps", "distinct":
+		return fn.Aggregator + "(" + field + ")", nil
+	case "value":
+		return "last(" + field + ")", nil
+	case "distinct_count":
+		return "distinct(" + field + ")", nil
+	case "p99", "p95", "p90", "p75", "p50":
+		return "percentiles(" + field + "," + strings.TrimPrefix(fn.Aggregator, "p") + ")", nil
+	}
+	return "", fmt.Errorf("aggregator %q of field %q is not supported", fn.Aggregator, fn.Field)
+}
+
+// pushdownFilter reports whether the filter can be executed by the metric query layer
+func pushdownFilter(f *filter) bool {
+	switch f.Operator {
+	case "eq", "neq", "in", "like", "match", "notMatch":
+		return true
+	}
+	return false
+}
+
+// skipFilter reports whether the filter is always satisfied,
+// such as an unresolved placeholder left by the alert template.
+func skipFilter(f *filter) bool {
+	if f.Operator == "false" {
+		return true
+	}
+	if s, ok := f.Value.(string); ok && strings.HasPrefix(s, "$") {
+		return true
+	}
+	return false
+}
+
+func (f *filter) queryOperator() string {
+	switch f.Operator {
+	case "like":
+		return "match"
+	case "notMatch":
+		return "nmatch"
+	}
+	return f.Operator
+}
+
+// matchTag evaluates the filters that are not pushed down to the query layer
+func (f *filter) matchTag(tags map[string]string) bool {
+	actual, exist := tags[f.Tag]
+	switch f.Operator {
+	case "any":
+		return exist && actual != ""
+	case "null":
+		return actual == ""
+	case "ieq":
+		return strings.EqualFold(actual, fmt.Sprint(f.Value))
+	case "all":
+		return actual == fmt.Sprint(f.Value)
+	case "notIn":
+		for _, v := range toSlice(f.Value) {
+			if actual == fmt.Sprint(v) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+// compare checks the aggregated value against the function threshold
+func compare(operator string, actual, threshold interface{}) bool {
+	if operator == "any" {
+		return true
+	}
+	if actual == nil {
+		return false
+	}
+	a, aok := toFloat(actual)
+	b, bok := toFloat(threshold)
+	if aok && bok {
+		switch operator {
+		case "gt":
+			return a > b
+		case "gte":
+			return a >= b
+		case "lt":
+			return a < b
+		case "lte":
+			return a <= b
+		case "eq", "all":
+			return a == b
+		case "neq":
+			return a != b
+		}
+	}
+	as, bs := fmt.Sprint(actual), fmt.Sprint(threshold)
+	switch operator {
+	case "eq", "all":
+		return as == bs
+	case "neq":
+		return as != bs
+	case "like", "contains":
+		return strings.Contains(as, bs)
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	case json.Number:
+		f, err := val.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toSlice(v interface{}) []interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		return val
+	case []string:
+		list := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			list = append(list, item)
+		}
+		return list
+	case string:
+		var list []interface{}
+		for _, item := range strings.Split(val, ",") {
+			list = append(list, item)
+		}
+		return list
+	}
+	return []interface{}{v}
+}
+
+func attributeString(attrs map[string]interface{}, key string) string {
+	v, ok := attrs[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
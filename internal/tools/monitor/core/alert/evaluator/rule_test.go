@@ -0,0 +1,182 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	metricpb "github.com/erda-project/erda-proto-go/core/monitor/metric/pb"
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+	"github.com/erda-project/erda/pkg/encoding/jsonmap"
+)
+
+func testExpression() *db.AlertExpression {
+	return &db.AlertExpression{
+		ID:      10,
+		AlertID: 1,
+		Attributes: jsonmap.JSONMap{
+			"alert_group": "{{cluster_name}}-{{container_id}}",
+			"recover":     "true",
+			"org_name":    "erda",
+		},
+		Expression: jsonmap.JSONMap{
+			"metric": "docker_container_summary",
+			"window": 5,
+			"for":    "2m",
+			"filters": []interface{}{
+				map[string]interface{}{"tag": "org_name", "operator": "eq", "value": "erda"},
+				map[string]interface{}{"tag": "workspace", "operator": "ieq", "value": "PROD"},
+				map[string]interface{}{"tag": "cluster_name", "operator": "eq", "value": "$cluster_name"},
+			},
+			"functions": []interface{}{
+				map[string]interface{}{"aggregator": "avg", "field": "cpu_usage_percent", "operator": "gte", "value": 90},
+				map[string]interface{}{"aggregator": "max", "field": "cpu_limit", "alias": "cpu_limit_value"},
+			},
+			"group":   []interface{}{"container_id"},
+			"select":  map[string]interface{}{"cluster_name": "#cluster_name"},
+			"outputs": []interface{}{"alert"},
+		},
+	}
+}
+
+func Test_newRule(t *testing.T) {
+	r, err := newRule(testExpression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.window != 5*time.Minute || r.forDuration != 2*time.Minute || !r.recover {
+		t.Fatalf("unexpected rule: %+v", r)
+	}
+	cols, err := r.columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT container_id::tag,last(cluster_name::tag),last(workspace::tag),avg(cpu_usage_percent::field),max(cpu_limit::field) FROM docker_container_summary GROUP BY container_id::tag LIMIT 100"
+	if got := r.statement(r.metrics[0], cols, 100); got != want {
+		t.Fatalf("statement:\n got %s\nwant %s", got, want)
+	}
+	filters, err := r.queryFilters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filters) != 1 || filters[0].Key != "tags.org_name" || filters[0].Op != "eq" {
+		t.Fatalf("unexpected filters: %+v", filters)
+	}
+
+	expr := testExpression()
+	expr.Expression["outputs"] = []interface{}{"metric"}
+	if r, err := newRule(expr); err != nil || r != nil {
+		t.Fatalf("non alert expression should be ignored, got %v, %v", r, err)
+	}
+	expr = testExpression()
+	expr.Expression["functions"] = []interface{}{map[string]interface{}{"aggregator": "values", "field": "x"}}
+	if _, err := newRule(expr); err == nil {
+		t.Fatalf("unsupported aggregator should fail")
+	}
+}
+
+func Test_rule_samples(t *testing.T) {
+	r, err := newRule(testExpression())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, _ := r.columns()
+	row := func(values ...interface{}) []*structpb.Value {
+		var list []*structpb.Value
+		for _, v := range values {
+			val, _ := structpb.NewValue(v)
+			list = append(list, val)
+		}
+		return list
+	}
+	samples := r.samples(cols, []*metricpb.Result{{
+		Series: []*metricpb.Serie{{
+			Rows: []*metricpb.Row{
+				{Values: row("c1", "dev", "prod", 95.0, 2.0)},
+				{Values: row("c2", "dev", "prod", 10.0, 2.0)},
+				{Values: row("c3", "dev", "test", 99.0, 2.0)},
+			},
+		}},
+	}})
+	if len(samples) != 2 {
+		t.Fatalf("want 2 samples after ieq filter, got %d", len(samples))
+	}
+	if samples[0].key != "container_id=c1" || !samples[0].triggered || samples[0].values["cpu_limit_value"] != 2.0 {
+		t.Fatalf("unexpected sample: %+v", samples[0])
+	}
+	if samples[1].triggered {
+		t.Fatalf("c2 should not trigger")
+	}
+
+	ac := r.alertContext(&transition{trigger: triggerAlert, sample: samples[0]})
+	if ac.alertGroup != "dev-c1" || ac.tags["trigger"] != triggerAlert || ac.tags["cluster_name"] != "dev" {
+		t.Fatalf("unexpected alert context: %+v", ac)
+	}
+	if other := r.alertContext(&transition{trigger: triggerRecover, sample: samples[0]}); other.groupID != ac.groupID {
+		t.Fatalf("group id should be stable")
+	}
+}
+
+func Test_compare(t *testing.T) {
+	tests := []struct {
+		operator          string
+		actual, threshold interface{}
+		want              bool
+	}{
+		{"gte", 90.0, 90, true},
+		{"gt", 90.0, 90, false},
+		{"lt", "1.5", 2, true},
+		{"lte", nil, 2, false},
+		{"eq", "ok", "ok", true},
+		{"neq", "ok", "ok", false},
+		{"like", "connection refused", "refused", true},
+		{"any", nil, nil, true},
+	}
+	for _, tt := range tests {
+		if got := compare(tt.operator, tt.actual, tt.threshold); got != tt.want {
+			t.Errorf("compare(%s, %v, %v) = %v, want %v", tt.operator, tt.actual, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func Test_filter_matchTag(t *testing.T) {
+	tags := map[string]string{"workspace": "PROD", "cluster": "a"}
+	tests := []struct {
+		f    *filter
+		want bool
+	}{
+		{&filter{Tag: "workspace", Operator: "ieq", Value: "prod"}, true},
+		{&filter{Tag: "cluster", Operator: "notIn", Value: []interface{}{"a", "b"}}, false},
+		{&filter{Tag: "cluster", Operator: "notIn", Value: "b,c"}, true},
+		{&filter{Tag: "cluster", Operator: "any"}, true},
+		{&filter{Tag: "host", Operator: "any"}, false},
+		{&filter{Tag: "host", Operator: "null"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.f.matchTag(tags); got != tt.want {
+			t.Errorf("%+v matchTag = %v, want %v", tt.f, got, tt.want)
+		}
+	}
+}
+
+func Test_render(t *testing.T) {
+	got := render("{{cluster_name}}-{{ container_id }}-{{missing}}", map[string]string{"cluster_name": "dev", "container_id": "c1"})
+	if got != "dev-c1-" {
+		t.Fatalf("got %s", got)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"time"
+
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/silence"
+)
+
+// loadMuter loads the silences, maintenance windows and inhibit rules of all scopes.
+// Invalid entries are skipped, and nothing is muted if they can not be loaded,
+// it is better to notify too much than to lose an alert.
+func (p *provider) loadMuter(trackers map[uint64]*tracker, rules []*rule, now time.Time) *silence.Muter {
+	set, err := silence.Load(p.silenceDB, p.maintenanceWindowDB, p.inhibitRuleDB, now, p.Log.Warnf)
+	if err != nil {
+		p.Log.Warnf("failed to load alert silences: %s", err)
+		return nil
+	}
+
+	var firing []*silence.Alert
+	for _, r := range rules {
+		t := trackers[r.id]
+		if t == nil {
+			continue
+		}
+		for _, inst := range t.instances {
+			if inst.state != stateFiring {
+				continue
+			}
+			ac := r.alertContext(&transition{trigger: triggerAlert, at: now, sample: inst.sample})
+			if !set.HasInhibitRules(ac.tags[silence.ScopeLabel], ac.tags[silence.ScopeIDLabel]) {
+				continue
+			}
+			firing = append(firing, &silence.Alert{GroupID: ac.groupID, Labels: ac.tags})
+		}
+	}
+	return set.Muter(now, firing)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"time"
+)
+
+type alertState int
+
+const (
+	stateInactive alertState = iota
+	statePending
+	stateFiring
+)
+
+func (s alertState) String() string {
+	switch s {
+	case statePending:
+		return "pending"
+	case stateFiring:
+		return "firing"
+	}
+	return "inactive"
+}
+
+const (
+	triggerAlert   = "alert"
+	triggerRecover = "recover"
+)
+
+// sample is one group of the rule query result
+type sample struct {
+	key       string
+	tags      map[string]string
+	values    map[string]interface{}
+	triggered bool
+}
+
+// instance tracks the state of one alert group
+type instance struct {
+	key      string
+	state    alertState
+	activeAt time.Time
+	firedAt  time.Time
+	sample   *sample
+}
+
+// transition is an alert or recover event produced by an evaluation
+type transition struct {
+	trigger  string
+	at       time.Time
+	duration time.Duration
+	sample   *sample
+	first    bool
+}
+
+// tracker keeps the pending/firing states of a rule between evaluations
+type tracker struct {
+	instances map[string]*instance
+}
+
+func newTracker() *tracker {
+	return &tracker{instances: make(map[string]*instance)}
+}
+
+// update applies the samples of an evaluation at now.
+// A group fires after its condition has held for forDuration and emits an alert on every
+// evaluation while firing; it recovers once the condition no longer holds or the group disappears.
+func (t *tracker) update(now time.Time, forDuration time.Duration, samples []*sample) []*transition {
+	var transitions []*transition
+	seen := make(map[string]*sample, len(samples))
+	for _, s := range samples {
+		seen[s.key] = s
+		if !s.triggered {
+			continue
+		}
+		inst, ok := t.instances[s.key]
+		if !ok {
+			inst = &instance{key: s.key, state: statePending, activeAt: now}
+			t.instances[s.key] = inst
+		}
+		inst.sample = s
+		if inst.state == statePending && now.Sub(inst.activeAt) >= forDuration {
+			inst.state = stateFiring
+			inst.firedAt = now
+			transitions = append(transitions, &transition{trigger: triggerAlert, at: now, sample: s, first: true})
+			continue
+		}
+		if inst.state == stateFiring {
+			transitions = append(transitions, &transition{trigger: triggerAlert, at: now, sample: s})
+		}
+	}
+	for key, inst := range t.instances {
+		if s, ok := seen[key]; ok && s.triggered {
+			continue
+		}
+		if inst.state == stateFiring {
+			last := inst.sample
+			if s, ok := seen[key]; ok {
+				last = s
+			}
+			transitions = append(transitions, &transition{
+				trigger:  triggerRecover,
+				at:       now,
+				duration: now.Sub(inst.firedAt),
+				sample:   last,
+			})
+		}
+		delete(t.instances, key)
+	}
+	return transitions
+}
+
+// states returns the current state of every tracked group
+func (t *tracker) states() map[string]alertState {
+	result := make(map[string]alertState, len(t.instances))
+	for key, inst := range t.instances {
+		result[key] = inst.state
+	}
+	return result
+}
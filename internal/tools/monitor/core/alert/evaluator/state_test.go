@@ -0,0 +1,78 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evaluator
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_tracker_update(t *testing.T) {
+	base := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	firing := &sample{key: "a", triggered: true}
+	normal := &sample{key: "a"}
+
+	tr := newTracker()
+	if list := tr.update(at(0), 2*time.Minute, []*sample{firing}); len(list) != 0 {
+		t.Fatalf("pending group should not emit, got %d transitions", len(list))
+	}
+	if got := tr.states()["a"]; got != statePending {
+		t.Fatalf("want pending, got %s", got)
+	}
+	if list := tr.update(at(1), 2*time.Minute, []*sample{firing}); len(list) != 0 {
+		t.Fatalf("pending group should not emit before for duration, got %d", len(list))
+	}
+	list := tr.update(at(2), 2*time.Minute, []*sample{firing})
+	if len(list) != 1 || list[0].trigger != triggerAlert || !list[0].first {
+		t.Fatalf("want first alert, got %+v", list)
+	}
+	list = tr.update(at(3), 2*time.Minute, []*sample{firing})
+	if len(list) != 1 || list[0].trigger != triggerAlert || list[0].first {
+		t.Fatalf("want repeated alert, got %+v", list)
+	}
+	list = tr.update(at(5), 2*time.Minute, []*sample{normal})
+	if len(list) != 1 || list[0].trigger != triggerRecover || list[0].duration != 3*time.Minute || list[0].sample != normal {
+		t.Fatalf("want recover, got %+v", list)
+	}
+	if len(tr.states()) != 0 {
+		t.Fatalf("recovered group should be removed")
+	}
+}
+
+func Test_tracker_update_PendingReset(t *testing.T) {
+	base := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	tr := newTracker()
+	tr.update(base, time.Minute, []*sample{{key: "a", triggered: true}})
+	if list := tr.update(base.Add(30*time.Second), time.Minute, nil); len(list) != 0 {
+		t.Fatalf("pending group should be dropped silently, got %+v", list)
+	}
+	if list := tr.update(base.Add(time.Minute), time.Minute, []*sample{{key: "a", triggered: true}}); len(list) != 0 {
+		t.Fatalf("pending should restart after reset, got %+v", list)
+	}
+}
+
+func Test_tracker_update_MissingGroupRecovers(t *testing.T) {
+	base := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	tr := newTracker()
+	s := &sample{key: "a", triggered: true}
+	if list := tr.update(base, 0, []*sample{s}); len(list) != 1 || !list[0].first {
+		t.Fatalf("want immediate alert without for duration, got %+v", list)
+	}
+	list := tr.update(base.Add(time.Minute), 0, nil)
+	if len(list) != 1 || list[0].trigger != triggerRecover || list[0].sample != s {
+		t.Fatalf("want recover with last sample, got %+v", list)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+)
+
+// Load loads the silences, maintenance windows and inhibit rules of all scopes which may mute alerts at now.
+// Invalid entries are skipped and reported by warnf.
+func Load(silenceDB *db.AlertSilenceDB, windowDB *db.AlertMaintenanceWindowDB, ruleDB *db.AlertInhibitRuleDB,
+	now time.Time, warnf func(template string, args ...interface{})) (*Set, error) {
+	silenceList, err := silenceDB.ListUnexpired(now)
+	if err != nil {
+		return nil, fmt.Errorf("load alert silences: %w", err)
+	}
+	windowList, err := windowDB.ListEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("load alert maintenance windows: %w", err)
+	}
+	ruleList, err := ruleDB.ListEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("load alert inhibit rules: %w", err)
+	}
+
+	var silences []*Silence
+	for _, item := range silenceList {
+		s, err := NewSilence(item)
+		if err != nil {
+			warnf("invalid alert silence %s: %s", item.Id, err)
+			continue
+		}
+		silences = append(silences, s)
+	}
+	var windows []*MaintenanceWindow
+	for _, item := range windowList {
+		w, err := NewMaintenanceWindow(item)
+		if err != nil {
+			warnf("invalid alert maintenance window %s: %s", item.Id, err)
+			continue
+		}
+		windows = append(windows, w)
+	}
+	var rules []*InhibitRule
+	for _, item := range ruleList {
+		r, err := NewInhibitRule(item)
+		if err != nil {
+			warnf("invalid alert inhibit rule %s: %s", item.Id, err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return NewSet(silences, windows, rules), nil
+}
+
+// EventLabels encodes the labels of a firing alert kept by its alert event
+func EventLabels(labels map[string]string) string {
+	data, _ := json.Marshal(labels)
+	return string(data)
+}
+
+// EventAlert converts a stored alert event, the labels of the alert kept by the event are merged
+// with the fields of the event, so that rules can match the tags such as cluster_name of firing alerts.
+func EventAlert(e *db.AlertEvent) *Alert {
+	labels := make(map[string]string)
+	if e.Labels != "" {
+		// labels are best effort, the fields of the event are still available if they are broken
+		_ = json.Unmarshal([]byte(e.Labels), &labels)
+	}
+	for k, v := range map[string]string{
+		"group_id":     e.AlertGroupID,
+		"alert_group":  e.AlertGroup,
+		"alert_id":     strconv.FormatUint(e.AlertID, 10),
+		"alert_name":   e.AlertName,
+		"alert_type":   e.AlertType,
+		"alert_index":  e.AlertIndex,
+		"alert_source": e.AlertSource,
+		"level":        e.AlertLevel,
+		"org_id":       strconv.FormatInt(e.OrgID, 10),
+		ScopeLabel:     e.Scope,
+		ScopeIDLabel:   e.ScopeID,
+	} {
+		labels[k] = v
+	}
+	return &Alert{GroupID: e.AlertGroupID, Labels: labels}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package silence mutes alerts by label matchers: one-off silences, recurring maintenance windows
+// and inhibition rules which mute alerts while a related alert is firing.
+package silence
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// matcher operators
+const (
+	OpEqual     = "="
+	OpNotEqual  = "!="
+	OpRegexp    = "=~"
+	OpNotRegexp = "!~"
+)
+
+// Matcher matches the value of one alert label, a missing label has the empty value
+type Matcher struct {
+	Name     string `json:"name"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+
+	re *regexp.Regexp
+}
+
+// NewMatcher returns a validated matcher, regular expressions are anchored at both ends
+func NewMatcher(name, operator, value string) (*Matcher, error) {
+	m := &Matcher{Name: name, Operator: operator, Value: value}
+	if err := m.compile(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Matcher) compile() error {
+	if m.Name == "" {
+		return fmt.Errorf("empty matcher label name")
+	}
+	switch m.Operator {
+	case OpEqual, OpNotEqual:
+	case OpRegexp, OpNotRegexp:
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid regular expression of label %q: %w", m.Name, err)
+		}
+		m.re = re
+	default:
+		return fmt.Errorf("invalid operator %q of label %q", m.Operator, m.Name)
+	}
+	return nil
+}
+
+func (m *Matcher) matchValue(v string) bool {
+	switch m.Operator {
+	case OpEqual:
+		return v == m.Value
+	case OpNotEqual:
+		return v != m.Value
+	case OpRegexp:
+		return m.re.MatchString(v)
+	case OpNotRegexp:
+		return !m.re.MatchString(v)
+	}
+	return false
+}
+
+// Matches reports whether the labels satisfy the matcher
+func (m *Matcher) Matches(labels map[string]string) bool {
+	return m.matchValue(labels[m.Name])
+}
+
+func (m *Matcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Operator, m.Value)
+}
+
+// Matchers matches the labels satisfying all of its matchers
+type Matchers []*Matcher
+
+// Matches reports whether the labels satisfy every matcher
+func (ms Matchers) Matches(labels map[string]string) bool {
+	for _, m := range ms {
+		if !m.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate compiles the matchers and requires at least one of them not to match the empty value,
+// so that a silence or rule never mutes every alert of its scope by accident
+func (ms Matchers) Validate() error {
+	if len(ms) == 0 {
+		return fmt.Errorf("matchers are required")
+	}
+	selective := false
+	for _, m := range ms {
+		if m == nil {
+			return fmt.Errorf("nil matcher")
+		}
+		if err := m.compile(); err != nil {
+			return err
+		}
+		if !m.matchValue("") {
+			selective = true
+		}
+	}
+	if !selective {
+		return fmt.Errorf("at least one matcher must not match an empty label value")
+	}
+	return nil
+}
+
+// ParseMatchers decodes and validates the matchers stored as json
+func ParseMatchers(text string) (Matchers, error) {
+	var ms Matchers
+	if err := json.Unmarshal([]byte(text), &ms); err != nil {
+		return nil, fmt.Errorf("invalid matchers: %w", err)
+	}
+	if err := ms.Validate(); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// JSON encodes the matchers to be stored
+func (ms Matchers) JSON() string {
+	if ms == nil {
+		ms = Matchers{}
+	}
+	byts, _ := json.Marshal(ms)
+	return string(byts)
+}
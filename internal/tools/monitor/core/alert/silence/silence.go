@@ -0,0 +1,362 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+)
+
+// labels of an alert identifying its scope, silences and rules only apply to the alerts of their own scope
+const (
+	ScopeLabel   = "alert_scope"
+	ScopeIDLabel = "alert_scope_id"
+)
+
+// the kinds of mute reason
+const (
+	ReasonSilence     = "silence"
+	ReasonMaintenance = "maintenance"
+	ReasonInhibit     = "inhibit"
+)
+
+// maxWindowDuration bounds a single occurrence of a maintenance window
+const maxWindowDuration = 7 * 24 * time.Hour
+
+// Alert is a firing or recovering alert group
+type Alert struct {
+	GroupID string
+	Labels  map[string]string
+}
+
+func (a *Alert) scopeKey() string {
+	return scopeKey(a.Labels[ScopeLabel], a.Labels[ScopeIDLabel])
+}
+
+func scopeKey(scope, scopeID string) string {
+	return scope + "/" + scopeID
+}
+
+// Reason tells which silence, maintenance window or inhibit rule muted an alert
+type Reason struct {
+	Type string
+	ID   string
+}
+
+// Silence mutes the matching alerts in [StartTime, EndTime)
+type Silence struct {
+	ID        string
+	Scope     string
+	ScopeID   string
+	Matchers  Matchers
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// NewSilence converts a stored silence
+func NewSilence(m *db.AlertSilence) (*Silence, error) {
+	matchers, err := ParseMatchers(m.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	if !m.EndTime.After(m.StartTime) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+	return &Silence{
+		ID:        m.Id,
+		Scope:     m.Scope,
+		ScopeID:   m.ScopeID,
+		Matchers:  matchers,
+		StartTime: m.StartTime,
+		EndTime:   m.EndTime,
+	}, nil
+}
+
+// State returns pending, active or expired at now
+func (s *Silence) State(now time.Time) string {
+	return SilenceState(s.StartTime, s.EndTime, now)
+}
+
+// SilenceState returns the state at now of a silence in [start, end)
+func SilenceState(start, end, now time.Time) string {
+	if now.Before(start) {
+		return db.SilenceStatePending
+	}
+	if now.Before(end) {
+		return db.SilenceStateActive
+	}
+	return db.SilenceStateExpired
+}
+
+// MaintenanceWindow mutes the matching alerts for Duration from Start every day, or only on Weekdays if set
+type MaintenanceWindow struct {
+	ID       string
+	Scope    string
+	ScopeID  string
+	Matchers Matchers
+	Location *time.Location
+	Weekdays []time.Weekday
+	Start    time.Duration
+	Duration time.Duration
+	From     *time.Time
+	Until    *time.Time
+}
+
+// NewMaintenanceWindow converts a stored maintenance window
+func NewMaintenanceWindow(m *db.AlertMaintenanceWindow) (*MaintenanceWindow, error) {
+	matchers, err := ParseMatchers(m.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.Local
+	if m.Timezone != "" {
+		loc, err = time.LoadLocation(m.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", m.Timezone, err)
+		}
+	}
+	weekdays, err := ParseWeekdays(m.Weekdays)
+	if err != nil {
+		return nil, err
+	}
+	start, err := ParseClock(m.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Duration(m.Duration) * time.Minute
+	if duration <= 0 || duration > maxWindowDuration {
+		return nil, fmt.Errorf("duration must be between 1 minute and %s", maxWindowDuration)
+	}
+	if m.EffectiveFrom != nil && m.EffectiveUntil != nil && !m.EffectiveUntil.After(*m.EffectiveFrom) {
+		return nil, fmt.Errorf("effective until must be after effective from")
+	}
+	return &MaintenanceWindow{
+		ID:       m.Id,
+		Scope:    m.Scope,
+		ScopeID:  m.ScopeID,
+		Matchers: matchers,
+		Location: loc,
+		Weekdays: weekdays,
+		Start:    start,
+		Duration: duration,
+		From:     m.EffectiveFrom,
+		Until:    m.EffectiveUntil,
+	}, nil
+}
+
+// ParseWeekdays parses comma separated weekdays, 0 is Sunday
+func ParseWeekdays(text string) ([]time.Weekday, error) {
+	var weekdays []time.Weekday
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		n, err := strconv.Atoi(item)
+		if err != nil || n < 0 || n > 6 {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+		weekdays = append(weekdays, time.Weekday(n))
+	}
+	return weekdays, nil
+}
+
+// ParseClock parses a HH:MM time of day into the offset from midnight
+func ParseClock(text string) (time.Duration, error) {
+	t, err := time.Parse("15:04", text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expect HH:MM", text)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Active reports whether an occurrence of the window covers now
+func (w *MaintenanceWindow) Active(now time.Time) bool {
+	if (w.From != nil && now.Before(*w.From)) || (w.Until != nil && !now.Before(*w.Until)) {
+		return false
+	}
+	local := now.In(w.Location)
+	// an occurrence longer than a day may have started several days ago
+	days := int((w.Start + w.Duration) / (24 * time.Hour))
+	for d := 0; d <= days; d++ {
+		start := time.Date(local.Year(), local.Month(), local.Day()-d,
+			int(w.Start/time.Hour), int(w.Start%time.Hour/time.Minute), 0, 0, w.Location)
+		if !w.onWeekday(start.Weekday()) {
+			continue
+		}
+		if !now.Before(start) && now.Before(start.Add(w.Duration)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *MaintenanceWindow) onWeekday(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range w.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// InhibitRule mutes the alerts matching Target while an alert matching Source,
+// with the same values of the Equal labels, is firing in the same scope
+type InhibitRule struct {
+	ID      string
+	Scope   string
+	ScopeID string
+	Source  Matchers
+	Target  Matchers
+	Equal   []string
+}
+
+// NewInhibitRule converts a stored inhibit rule
+func NewInhibitRule(m *db.AlertInhibitRule) (*InhibitRule, error) {
+	source, err := ParseMatchers(m.SourceMatchers)
+	if err != nil {
+		return nil, fmt.Errorf("source %w", err)
+	}
+	target, err := ParseMatchers(m.TargetMatchers)
+	if err != nil {
+		return nil, fmt.Errorf("target %w", err)
+	}
+	return &InhibitRule{
+		ID:      m.Id,
+		Scope:   m.Scope,
+		ScopeID: m.ScopeID,
+		Source:  source,
+		Target:  target,
+		Equal:   ParseLabelNames(m.EqualLabels),
+	}, nil
+}
+
+// ParseLabelNames parses comma separated label names
+func ParseLabelNames(text string) []string {
+	var names []string
+	for _, name := range strings.Split(text, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (r *InhibitRule) inhibits(a *Alert, firing []*Alert) bool {
+	if !r.Target.Matches(a.Labels) {
+		return false
+	}
+	for _, f := range firing {
+		// an alert never inhibits itself
+		if f.GroupID == a.GroupID || !r.Source.Matches(f.Labels) {
+			continue
+		}
+		equal := true
+		for _, name := range r.Equal {
+			if f.Labels[name] != a.Labels[name] {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Set is the silences, maintenance windows and inhibit rules of all scopes
+type Set struct {
+	silences map[string][]*Silence
+	windows  map[string][]*MaintenanceWindow
+	rules    map[string][]*InhibitRule
+}
+
+// NewSet indexes the silences, maintenance windows and inhibit rules by scope
+func NewSet(silences []*Silence, windows []*MaintenanceWindow, rules []*InhibitRule) *Set {
+	s := &Set{
+		silences: make(map[string][]*Silence),
+		windows:  make(map[string][]*MaintenanceWindow),
+		rules:    make(map[string][]*InhibitRule),
+	}
+	for _, item := range silences {
+		key := scopeKey(item.Scope, item.ScopeID)
+		s.silences[key] = append(s.silences[key], item)
+	}
+	for _, item := range windows {
+		key := scopeKey(item.Scope, item.ScopeID)
+		s.windows[key] = append(s.windows[key], item)
+	}
+	for _, item := range rules {
+		key := scopeKey(item.Scope, item.ScopeID)
+		s.rules[key] = append(s.rules[key], item)
+	}
+	return s
+}
+
+// HasInhibitRules reports whether any inhibit rule is defined in the scope,
+// the firing alerts are only needed by the muter if so
+func (s *Set) HasInhibitRules(scope, scopeID string) bool {
+	return len(s.rules[scopeKey(scope, scopeID)]) > 0
+}
+
+// Muter returns the muter at now, firing is every alert currently firing and is used by the inhibit rules
+func (s *Set) Muter(now time.Time, firing []*Alert) *Muter {
+	m := &Muter{set: s, now: now, firing: make(map[string][]*Alert)}
+	for _, a := range firing {
+		key := a.scopeKey()
+		m.firing[key] = append(m.firing[key], a)
+	}
+	return m
+}
+
+// Muter decides whether an alert is muted
+type Muter struct {
+	set    *Set
+	now    time.Time
+	firing map[string][]*Alert
+}
+
+// Mutes returns why the alert is muted, or nil if it is not.
+// Silences are checked first, then maintenance windows and inhibit rules.
+func (m *Muter) Mutes(a *Alert) *Reason {
+	if m == nil || m.set == nil {
+		return nil
+	}
+	key := a.scopeKey()
+	for _, s := range m.set.silences[key] {
+		if s.State(m.now) == db.SilenceStateActive && s.Matchers.Matches(a.Labels) {
+			return &Reason{Type: ReasonSilence, ID: s.ID}
+		}
+	}
+	for _, w := range m.set.windows[key] {
+		if w.Matchers.Matches(a.Labels) && w.Active(m.now) {
+			return &Reason{Type: ReasonMaintenance, ID: w.ID}
+		}
+	}
+	for _, r := range m.set.rules[key] {
+		if r.inhibits(a, m.firing[key]) {
+			return &Reason{Type: ReasonInhibit, ID: r.ID}
+		}
+	}
+	return nil
+}
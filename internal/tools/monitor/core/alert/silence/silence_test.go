@@ -0,0 +1,260 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erda-project/erda/internal/tools/monitor/core/alert/alert-apis/db"
+)
+
+func mustMatchers(t *testing.T, text string) Matchers {
+	t.Helper()
+	ms, err := ParseMatchers(text)
+	if err != nil {
+		t.Fatalf("ParseMatchers(%s): %s", text, err)
+	}
+	return ms
+}
+
+func TestMatchers(t *testing.T) {
+	ms := mustMatchers(t, `[{"name":"cluster_name","operator":"=","value":"prod"},{"name":"service_name","operator":"=~","value":"api|web"},{"name":"level","operator":"!=","value":"Breakdown"}]`)
+	tests := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{map[string]string{"cluster_name": "prod", "service_name": "api", "level": "Warning"}, true},
+		{map[string]string{"cluster_name": "prod", "service_name": "web"}, true},
+		{map[string]string{"cluster_name": "prod", "service_name": "api-gateway"}, false},
+		{map[string]string{"cluster_name": "test", "service_name": "api"}, false},
+		{map[string]string{"cluster_name": "prod", "service_name": "api", "level": "Breakdown"}, false},
+	}
+	for _, tt := range tests {
+		if got := ms.Matches(tt.labels); got != tt.want {
+			t.Errorf("Matches(%v) = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+
+	for _, text := range []string{
+		`[]`,
+		`[{"name":"cluster_name","operator":"==","value":"prod"}]`,
+		`[{"name":"","operator":"=","value":"prod"}]`,
+		`[{"name":"service_name","operator":"=~","value":"("}]`,
+		`[{"name":"service_name","operator":"=~","value":".*"}]`,
+		`[{"name":"service_name","operator":"!=","value":"api"}]`,
+		`not json`,
+	} {
+		if _, err := ParseMatchers(text); err == nil {
+			t.Errorf("ParseMatchers(%s) should fail", text)
+		}
+	}
+}
+
+func TestSilenceState(t *testing.T) {
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	s := &Silence{StartTime: start, EndTime: start.Add(time.Hour)}
+	for _, tt := range []struct {
+		at   time.Time
+		want string
+	}{
+		{start.Add(-time.Second), db.SilenceStatePending},
+		{start, db.SilenceStateActive},
+		{start.Add(59 * time.Minute), db.SilenceStateActive},
+		{start.Add(time.Hour), db.SilenceStateExpired},
+	} {
+		if got := s.State(tt.at); got != tt.want {
+			t.Errorf("State(%s) = %s, want %s", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestMaintenanceWindow_Active(t *testing.T) {
+	// every Saturday 23:00 - Sunday 01:00 in Shanghai
+	w, err := NewMaintenanceWindow(&db.AlertMaintenanceWindow{
+		Matchers:  `[{"name":"cluster_name","operator":"=","value":"prod"}]`,
+		Timezone:  "Asia/Shanghai",
+		Weekdays:  "6",
+		StartTime: "23:00",
+		Duration:  120,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := w.Location
+	for _, tt := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2026, 10, 17, 22, 59, 0, 0, loc), false}, // Saturday
+		{time.Date(2026, 10, 17, 23, 0, 0, 0, loc), true},
+		{time.Date(2026, 10, 18, 0, 30, 0, 0, loc), true}, // Sunday, started on Saturday
+		{time.Date(2026, 10, 18, 1, 0, 0, 0, loc), false},
+		{time.Date(2026, 10, 18, 23, 30, 0, 0, loc), false},     // Sunday is not a start day
+		{time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC), true}, // 23:30 in Shanghai
+	} {
+		if got := w.Active(tt.at); got != tt.want {
+			t.Errorf("Active(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	until := time.Date(2026, 10, 17, 0, 0, 0, 0, loc)
+	w.Until = &until
+	if w.Active(time.Date(2026, 10, 17, 23, 30, 0, 0, loc)) {
+		t.Errorf("window should not be active after effective until")
+	}
+
+	for _, m := range []*db.AlertMaintenanceWindow{
+		{Matchers: `[{"name":"a","operator":"=","value":"b"}]`, StartTime: "25:00", Duration: 10},
+		{Matchers: `[{"name":"a","operator":"=","value":"b"}]`, StartTime: "01:00", Duration: 0},
+		{Matchers: `[{"name":"a","operator":"=","value":"b"}]`, StartTime: "01:00", Duration: 10, Weekdays: "7"},
+		{Matchers: `[{"name":"a","operator":"=","value":"b"}]`, StartTime: "01:00", Duration: 10, Timezone: "Nowhere/City"},
+	} {
+		if _, err := NewMaintenanceWindow(m); err == nil {
+			t.Errorf("NewMaintenanceWindow(%+v) should fail", m)
+		}
+	}
+}
+
+func TestMuter_Mutes(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	scope := func(labels map[string]string) map[string]string {
+		labels[ScopeLabel] = "org"
+		labels[ScopeIDLabel] = "1"
+		return labels
+	}
+	rule, err := NewInhibitRule(&db.AlertInhibitRule{
+		Id:             "r1",
+		Scope:          "org",
+		ScopeID:        "1",
+		SourceMatchers: `[{"name":"alert_index","operator":"=","value":"cluster_down"}]`,
+		TargetMatchers: `[{"name":"alert_type","operator":"=","value":"kubernetes_pod"}]`,
+		EqualLabels:    "cluster_name",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := NewSet(
+		[]*Silence{
+			{ID: "s1", Scope: "org", ScopeID: "1", Matchers: mustMatchers(t, `[{"name":"service_name","operator":"=","value":"api"}]`), StartTime: now.Add(-time.Minute), EndTime: now.Add(time.Hour)},
+			{ID: "s2", Scope: "org", ScopeID: "1", Matchers: mustMatchers(t, `[{"name":"service_name","operator":"=","value":"web"}]`), StartTime: now.Add(time.Minute), EndTime: now.Add(time.Hour)},
+		},
+		nil,
+		[]*InhibitRule{rule},
+	)
+	clusterDown := &Alert{GroupID: "g0", Labels: scope(map[string]string{"alert_index": "cluster_down", "cluster_name": "c1"})}
+	muter := set.Muter(now, []*Alert{clusterDown})
+
+	for _, tt := range []struct {
+		name  string
+		alert *Alert
+		want  *Reason
+	}{
+		{"silenced", &Alert{GroupID: "g1", Labels: scope(map[string]string{"service_name": "api"})}, &Reason{ReasonSilence, "s1"}},
+		{"pending silence", &Alert{GroupID: "g2", Labels: scope(map[string]string{"service_name": "web"})}, nil},
+		{"other scope", &Alert{GroupID: "g3", Labels: map[string]string{ScopeLabel: "org", ScopeIDLabel: "2", "service_name": "api"}}, nil},
+		{"inhibited", &Alert{GroupID: "g4", Labels: scope(map[string]string{"alert_type": "kubernetes_pod", "cluster_name": "c1"})}, &Reason{ReasonInhibit, "r1"}},
+		{"other cluster", &Alert{GroupID: "g5", Labels: scope(map[string]string{"alert_type": "kubernetes_pod", "cluster_name": "c2"})}, nil},
+		{"source itself", clusterDown, nil},
+	} {
+		got := muter.Mutes(tt.alert)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("%s: Mutes() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	var nilMuter *Muter
+	if nilMuter.Mutes(clusterDown) != nil {
+		t.Errorf("nil muter should not mute")
+	}
+}
+
+func TestEventAlert(t *testing.T) {
+	rule, err := NewInhibitRule(&db.AlertInhibitRule{
+		Id:             "r1",
+		Scope:          "org",
+		ScopeID:        "1",
+		SourceMatchers: `[{"name":"alert_index","operator":"=","value":"cluster_down"}]`,
+		TargetMatchers: `[{"name":"alert_type","operator":"=","value":"kubernetes_pod"}]`,
+		EqualLabels:    "org_id",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := NewSet(nil, nil, []*InhibitRule{rule})
+	if !set.HasInhibitRules("org", "1") || set.HasInhibitRules("org", "2") {
+		t.Fatalf("HasInhibitRules() should only be true in scope org/1")
+	}
+
+	firing := EventAlert(&db.AlertEvent{
+		AlertGroupID: "g0",
+		OrgID:        1,
+		Scope:        "org",
+		ScopeID:      "1",
+		AlertIndex:   "cluster_down",
+	})
+	if firing.GroupID != "g0" || firing.Labels["group_id"] != "g0" || firing.Labels["org_id"] != "1" {
+		t.Fatalf("EventAlert() labels = %v", firing.Labels)
+	}
+	alert := &Alert{GroupID: "g1", Labels: map[string]string{ScopeLabel: "org", ScopeIDLabel: "1", "org_id": "1", "alert_type": "kubernetes_pod"}}
+	got := set.Muter(time.Now(), []*Alert{firing}).Mutes(alert)
+	if got == nil || *got != (Reason{ReasonInhibit, "r1"}) {
+		t.Fatalf("Mutes() = %+v, want inhibited by r1", got)
+	}
+}
+
+func TestEventAlertLabels(t *testing.T) {
+	// cluster-down suppresses the pod alerts in that cluster
+	rule, err := NewInhibitRule(&db.AlertInhibitRule{
+		Id:             "r1",
+		Scope:          "org",
+		ScopeID:        "1",
+		SourceMatchers: `[{"name":"alert_index","operator":"=","value":"cluster_down"}]`,
+		TargetMatchers: `[{"name":"alert_type","operator":"=","value":"kubernetes_pod"}]`,
+		EqualLabels:    "cluster_name",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	firing := EventAlert(&db.AlertEvent{
+		AlertGroupID: "g0",
+		OrgID:        1,
+		Scope:        "org",
+		ScopeID:      "1",
+		AlertIndex:   "cluster_down",
+		Labels:       EventLabels(map[string]string{"cluster_name": "prod", "alert_index": "stale", "trigger": "alert"}),
+	})
+	if firing.Labels["cluster_name"] != "prod" || firing.Labels["alert_index"] != "cluster_down" {
+		t.Fatalf("EventAlert() labels = %v", firing.Labels)
+	}
+	muter := NewSet(nil, nil, []*InhibitRule{rule}).Muter(time.Now(), []*Alert{firing})
+
+	pod := func(groupID, cluster string) *Alert {
+		return &Alert{GroupID: groupID, Labels: map[string]string{
+			ScopeLabel: "org", ScopeIDLabel: "1", "alert_type": "kubernetes_pod", "cluster_name": cluster,
+		}}
+	}
+	if got := muter.Mutes(pod("g1", "prod")); got == nil || *got != (Reason{ReasonInhibit, "r1"}) {
+		t.Fatalf("Mutes() = %+v, want inhibited by r1", got)
+	}
+	if got := muter.Mutes(pod("g2", "test")); got != nil {
+		t.Fatalf("Mutes() = %+v, want the pod alert of another cluster not muted", got)
+	}
+
+	broken := EventAlert(&db.AlertEvent{AlertGroupID: "g0", Scope: "org", ScopeID: "1", Labels: "not json"})
+	if broken.Labels["group_id"] != "g0" || broken.Labels[ScopeLabel] != "org" {
+		t.Fatalf("EventAlert() labels = %v", broken.Labels)
+	}
+}
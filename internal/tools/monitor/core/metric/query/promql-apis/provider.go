@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql_apis
+
+import (
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-infra/providers/clickhouse"
+	"github.com/erda-project/erda-infra/providers/httpserver"
+	"github.com/erda-project/erda-infra/providers/httpserver/interceptors"
+	"github.com/erda-project/erda/internal/core/org"
+	"github.com/erda-project/erda/internal/tools/monitor/core/metric/query/promql"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/loader"
+)
+
+type config struct {
+	LookbackDelta time.Duration `file:"lookback_delta" default:"5m"`
+	QueryTimeout  time.Duration `file:"query_timeout" default:"2m"`
+	MaxPoints     int64         `file:"max_points" default:"11000"`
+	MaxSamples    int           `file:"max_samples" default:"5000000"`
+	MaxSeries     int           `file:"max_series" default:"10000"`
+	DebugSQL      bool          `file:"debug_sql"`
+}
+
+type provider struct {
+	C          *config
+	L          logs.Logger
+	Clickhouse clickhouse.Interface `autowired:"clickhouse"`
+	Loader     loader.Interface     `autowired:"clickhouse.table.loader@metric"`
+	Org        org.ClientInterface
+
+	engine *promql.Engine
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.engine = promql.NewEngine(promql.EngineOptions{
+		LookbackDelta: p.C.LookbackDelta,
+		MaxPoints:     p.C.MaxPoints,
+	})
+	routes := ctx.Service("http-server", interceptors.Recover(p.L), interceptors.CORS(true)).(httpserver.Router)
+	p.initRoutes(routes)
+	return nil
+}
+
+func init() {
+	servicehub.Register("erda.core.monitor.metric.promql", &servicehub.Spec{
+		Dependencies: []string{"http-server"},
+		Description:  "prometheus compatible query apis over the clickhouse metric storage",
+		ConfigFunc:   func() interface{} { return &config{} },
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql_apis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/internal/tools/monitor/core/metric/query/promql"
+)
+
+// Metrics are mapped to prometheus series in the same way as the prometheus remote-write receiver stores them:
+// the metric group is the "job" label, each number field is a series named after the field, and tags are labels.
+const (
+	jobLabel    = "job"
+	fieldColumn = "field"
+	valueColumn = "value"
+)
+
+type sampleRow struct {
+	MetricGroup string   `ch:"metric_group"`
+	TagKeys     []string `ch:"tag_keys"`
+	TagValues   []string `ch:"tag_values"`
+	Field       string   `ch:"field"`
+	Value       float64  `ch:"value"`
+	Timestamp   int64    `ch:"ts"`
+}
+
+type seriesRow struct {
+	MetricGroup string   `ch:"metric_group"`
+	TagKeys     []string `ch:"tag_keys"`
+	TagValues   []string `ch:"tag_values"`
+	Field       string   `ch:"field"`
+}
+
+type valueRow struct {
+	Value string `ch:"value"`
+}
+
+// querier loads samples of the org and tenant from the clickhouse metric table
+type querier struct {
+	p           *provider
+	org         string
+	terminusKey string
+}
+
+func (q *querier) Select(ctx context.Context, start, end int64, matchers []*promql.Matcher) ([]*promql.Series, error) {
+	sql := q.from(start, end, true).Select(
+		goqu.C("metric_group"),
+		goqu.C("tag_keys"),
+		goqu.C("tag_values"),
+		goqu.C(fieldColumn),
+		goqu.C(valueColumn),
+		goqu.L("toUnixTimestamp64Milli(timestamp)").As("ts"),
+	).Where(matcherExpressions(matchers)...).Order(goqu.C("timestamp").Asc()).Limit(uint(q.p.C.MaxSamples + 1))
+
+	rows, err := q.query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := make(map[string]*promql.Series)
+	var samples int
+	for rows.Next() {
+		var row sampleRow
+		if err := rows.ScanStruct(&row); err != nil {
+			return nil, errors.Wrap(err, "failed to scan sample row")
+		}
+		if samples++; samples > q.p.C.MaxSamples {
+			return nil, fmt.Errorf("query selects more than %d samples, narrow down the selectors or the time range", q.p.C.MaxSamples)
+		}
+		labels := seriesLabels(row.MetricGroup, row.Field, row.TagKeys, row.TagValues)
+		key := labels.String()
+		s, ok := series[key]
+		if !ok {
+			s = &promql.Series{Metric: labels}
+			series[key] = s
+		}
+		if n := len(s.Points); n > 0 && s.Points[n-1].T == row.Timestamp {
+			s.Points[n-1].V = row.Value
+			continue
+		}
+		s.Points = append(s.Points, promql.Point{T: row.Timestamp, V: row.Value})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read sample rows")
+	}
+	result := make([]*promql.Series, 0, len(series))
+	for _, s := range series {
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// Series returns the label sets of the series matched by any of the selectors
+func (q *querier) Series(ctx context.Context, start, end int64, selectors [][]*promql.Matcher) ([]promql.Labels, error) {
+	var where []exp.Expression
+	for _, matchers := range selectors {
+		where = append(where, goqu.And(matcherExpressions(matchers)...))
+	}
+	sql := q.from(start, end, true).Select(
+		goqu.C("metric_group"),
+		goqu.C("tag_keys"),
+		goqu.C("tag_values"),
+		goqu.C(fieldColumn),
+	).Distinct().Where(goqu.Or(where...)).Limit(uint(q.p.C.MaxSeries))
+
+	rows, err := q.query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	var result []promql.Labels
+	for rows.Next() {
+		var row seriesRow
+		if err := rows.ScanStruct(&row); err != nil {
+			return nil, errors.Wrap(err, "failed to scan series row")
+		}
+		labels := seriesLabels(row.MetricGroup, row.Field, row.TagKeys, row.TagValues)
+		if key := labels.String(); !seen[key] {
+			seen[key] = true
+			result = append(result, labels)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read series rows")
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].String() < result[j].String() })
+	return result, nil
+}
+
+// LabelNames returns the sorted label names of all the series of the tenant
+func (q *querier) LabelNames(ctx context.Context, start, end int64) ([]string, error) {
+	values, err := q.distinct(ctx, q.from(start, end, false).Select(goqu.L("arrayJoin(tag_keys)").As(valueColumn)))
+	if err != nil {
+		return nil, err
+	}
+	return mergeSorted(values, promql.MetricNameLabel, jobLabel), nil
+}
+
+// LabelValues returns the sorted values of the label of all the series of the tenant
+func (q *querier) LabelValues(ctx context.Context, start, end int64, name string) ([]string, error) {
+	var sql *goqu.SelectDataset
+	switch name {
+	case promql.MetricNameLabel:
+		sql = q.from(start, end, false).Select(goqu.L("arrayJoin(number_field_keys)").As(valueColumn))
+	case jobLabel:
+		sql = q.from(start, end, false).Select(goqu.C("metric_group").As(valueColumn))
+	default:
+		sql = q.from(start, end, false).Select(labelColumn(name).As(valueColumn)).
+			Where(goqu.L("has(tag_keys, ?)", name))
+	}
+	values, err := q.distinct(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	return mergeSorted(values), nil
+}
+
+func (q *querier) distinct(ctx context.Context, sql *goqu.SelectDataset) ([]string, error) {
+	rows, err := q.query(ctx, sql.Distinct().Limit(uint(q.p.C.MaxSeries)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var row valueRow
+		if err := rows.ScanStruct(&row); err != nil {
+			return nil, errors.Wrap(err, "failed to scan value row")
+		}
+		values = append(values, row.Value)
+	}
+	return values, errors.Wrap(rows.Err(), "failed to read value rows")
+}
+
+// from returns the query of the tenant's metrics in the time range,
+// every number field is joined as a row if the series are selected.
+func (q *querier) from(start, end int64, joinFields bool) *goqu.SelectDataset {
+	table, _ := q.p.Loader.GetSearchTable(q.org)
+	var sql *goqu.SelectDataset
+	if joinFields {
+		sql = goqu.From(goqu.L("? ARRAY JOIN number_field_keys AS ?, number_field_values AS ?",
+			goqu.I(table), goqu.I(fieldColumn), goqu.I(valueColumn)))
+	} else {
+		sql = goqu.From(table)
+	}
+	sql = sql.Where(
+		// compatible erda and empty, erda components sometimes use empty and erda org
+		goqu.C("org_name").In(q.org, "erda", ""),
+		goqu.C("timestamp").Gte(goqu.L("fromUnixTimestamp64Nano(cast(?,'Int64'))", start*int64(time.Millisecond))),
+		goqu.C("timestamp").Lte(goqu.L("fromUnixTimestamp64Nano(cast(?,'Int64'))", end*int64(time.Millisecond))),
+	)
+	if len(q.terminusKey) > 0 {
+		sql = sql.Where(goqu.C("tenant_id").Eq(q.terminusKey))
+	}
+	return sql
+}
+
+func (q *querier) query(ctx context.Context, sql *goqu.SelectDataset) (driver.Rows, error) {
+	str, _, err := sql.ToSQL()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate SQL")
+	}
+	if q.p.C.DebugSQL {
+		q.p.L.Infof("PromQL clickhouse SQL: \n%s", str)
+	}
+	rows, err := q.p.Clickhouse.Client().Query(ctx, str)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query: %s", str)
+	}
+	return rows, nil
+}
+
+// labelColumn returns the column expression of the label
+func labelColumn(name string) exp.LiteralExpression {
+	switch name {
+	case promql.MetricNameLabel:
+		return goqu.L("?", goqu.I(fieldColumn))
+	case jobLabel:
+		return goqu.L("?", goqu.I("metric_group"))
+	}
+	return goqu.L("tag_values[indexOf(tag_keys,?)]", name)
+}
+
+// matcherExpressions converts the matchers to where conditions, absent tags are empty values as prometheus labels
+func matcherExpressions(matchers []*promql.Matcher) []exp.Expression {
+	list := make([]exp.Expression, 0, len(matchers))
+	for _, m := range matchers {
+		col := labelColumn(m.Name)
+		switch m.Type {
+		case promql.MatchEqual:
+			list = append(list, col.Eq(m.Value))
+		case promql.MatchNotEqual:
+			list = append(list, col.Neq(m.Value))
+		case promql.MatchRegexp:
+			list = append(list, goqu.L("match(?, ?)", col, "^(?:"+m.Value+")$"))
+		case promql.MatchNotRegexp:
+			list = append(list, goqu.L("NOT match(?, ?)", col, "^(?:"+m.Value+")$"))
+		}
+	}
+	return list
+}
+
+func seriesLabels(metricGroup, field string, keys, values []string) promql.Labels {
+	m := make(map[string]string, len(keys)+2)
+	for i := 0; i < len(keys) && i < len(values); i++ {
+		m[keys[i]] = values[i]
+	}
+	m[jobLabel] = metricGroup
+	m[promql.MetricNameLabel] = field
+	return promql.NewLabels(m)
+}
+
+func mergeSorted(values []string, extra ...string) []string {
+	set := make(map[string]bool, len(values)+len(extra))
+	result := make([]string, 0, len(values)+len(extra))
+	for _, list := range [][]string{values, extra} {
+		for _, v := range list {
+			if v != "" && !set[v] {
+				set[v] = true
+				result = append(result, v)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
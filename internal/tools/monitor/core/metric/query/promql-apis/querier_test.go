@@ -0,0 +1,190 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql_apis
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ckdriver "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/erda-project/erda-infra/providers/clickhouse"
+	"github.com/erda-project/erda/internal/tools/monitor/core/metric/query/promql"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/loader"
+)
+
+type mockLoader struct {
+	loader.Interface
+}
+
+func (l *mockLoader) GetSearchTable(tenant string) (string, *loader.TableMeta) {
+	return "monitor.metrics_all", nil
+}
+
+type mockClickhouse struct {
+	clickhouse.Interface
+	conn *mockConn
+}
+
+func (c *mockClickhouse) Client() ckdriver.Conn {
+	return c.conn
+}
+
+type mockConn struct {
+	ckdriver.Conn
+	sql  string
+	rows []sampleRow
+}
+
+func (c *mockConn) Query(ctx context.Context, query string, args ...interface{}) (ckdriver.Rows, error) {
+	c.sql = query
+	return &mockRows{rows: c.rows, index: -1}, nil
+}
+
+type mockRows struct {
+	ckdriver.Rows
+	rows  []sampleRow
+	index int
+}
+
+func (r *mockRows) Next() bool {
+	r.index++
+	return r.index < len(r.rows)
+}
+
+func (r *mockRows) ScanStruct(dest interface{}) error {
+	*dest.(*sampleRow) = r.rows[r.index]
+	return nil
+}
+
+func (r *mockRows) Err() error   { return nil }
+func (r *mockRows) Close() error { return nil }
+
+func newTestQuerier(rows []sampleRow) (*querier, *mockConn) {
+	conn := &mockConn{rows: rows}
+	p := &provider{
+		C:          &config{MaxSamples: 100, MaxSeries: 100},
+		Clickhouse: &mockClickhouse{conn: conn},
+		Loader:     &mockLoader{},
+	}
+	return &querier{p: p, org: "erda", terminusKey: "tk"}, conn
+}
+
+func Test_querier_Select(t *testing.T) {
+	q, conn := newTestQuerier([]sampleRow{
+		{MetricGroup: "node", TagKeys: []string{"instance"}, TagValues: []string{"a"}, Field: "cpu_seconds_total", Value: 1, Timestamp: 1000},
+		{MetricGroup: "node", TagKeys: []string{"instance"}, TagValues: []string{"b"}, Field: "cpu_seconds_total", Value: 5, Timestamp: 1000},
+		{MetricGroup: "node", TagKeys: []string{"instance"}, TagValues: []string{"a"}, Field: "cpu_seconds_total", Value: 2, Timestamp: 2000},
+		{MetricGroup: "node", TagKeys: []string{"instance"}, TagValues: []string{"a"}, Field: "cpu_seconds_total", Value: 3, Timestamp: 2000},
+	})
+	expr, err := promql.ParseExpr(`cpu_seconds_total{job="node", instance=~"a|b", mode!="idle"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	series, err := q.Select(context.Background(), 1000, 2000, expr.(*promql.VectorSelector).Matchers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`FROM "monitor"."metrics_all" ARRAY JOIN number_field_keys AS "field", number_field_values AS "value"`,
+		`("org_name" IN ('erda', 'erda', ''))`,
+		`("timestamp" >= fromUnixTimestamp64Nano(cast(1000000000,'Int64')))`,
+		`("timestamp" <= fromUnixTimestamp64Nano(cast(2000000000,'Int64')))`,
+		`("tenant_id" = 'tk')`,
+		`("metric_group" = 'node')`,
+		`match(tag_values[indexOf(tag_keys,'instance')], '^(?:a|b)$')`,
+		`(tag_values[indexOf(tag_keys,'mode')] != 'idle')`,
+		`("field" = 'cpu_seconds_total')`,
+		`ORDER BY "timestamp" ASC LIMIT 101`,
+	} {
+		if !strings.Contains(conn.sql, want) {
+			t.Errorf("sql %s\nwant contains %s", conn.sql, want)
+		}
+	}
+
+	got := make(map[string][]promql.Point)
+	for _, s := range series {
+		got[s.Metric.String()] = s.Points
+	}
+	want := map[string][]promql.Point{
+		`{__name__="cpu_seconds_total", instance="a", job="node"}`: {{T: 1000, V: 1}, {T: 2000, V: 3}},
+		`{__name__="cpu_seconds_total", instance="b", job="node"}`: {{T: 1000, V: 5}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Select() got %v, want %v", got, want)
+	}
+}
+
+func Test_querier_SelectLimit(t *testing.T) {
+	rows := make([]sampleRow, 101)
+	q, _ := newTestQuerier(rows)
+	m, _ := promql.NewMatcher(promql.MatchEqual, promql.MetricNameLabel, "up")
+	if _, err := q.Select(context.Background(), 0, 1000, []*promql.Matcher{m}); err == nil {
+		t.Fatal("Select() want error if the samples exceed the limit")
+	}
+}
+
+func Test_parseTime(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{input: "", want: time.Unix(1, 0)},
+		{input: "1600000000.5", want: time.Unix(1600000000, int64(500*time.Millisecond))},
+		{input: "2020-09-13T12:26:40Z", want: time.Unix(1600000000, 0)},
+		{input: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.input, time.Unix(1, 0))
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseTime(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if err == nil && !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func Test_parseDuration(t *testing.T) {
+	for input, want := range map[string]time.Duration{"15": 15 * time.Second, "0.5": 500 * time.Millisecond, "1m": time.Minute} {
+		if got, err := parseDuration(input); err != nil || got != want {
+			t.Errorf("parseDuration(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "0", "-1", "abc", "0.0001", "100us"} {
+		if _, err := parseDuration(input); err == nil {
+			t.Errorf("parseDuration(%q) want error", input)
+		}
+	}
+}
+
+func Test_encodeValue(t *testing.T) {
+	vec := promql.Vector{{Metric: promql.NewLabels(map[string]string{"job": "node"}), Point: promql.Point{T: 1500, V: 0.5}}}
+	want := []map[string]interface{}{{
+		"metric": map[string]string{"job": "node"},
+		"value":  []interface{}{1.5, "0.5"},
+	}}
+	if got := encodeValue(vec); !reflect.DeepEqual(got, want) {
+		t.Errorf("encodeValue() = %v, want %v", got, want)
+	}
+	if got := encodeValue(promql.Scalar{T: 2000, V: 1}); !reflect.DeepEqual(got, []interface{}{2.0, "1"}) {
+		t.Errorf("encodeValue() = %v", got)
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql_apis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda-infra/providers/httpserver"
+	orgpb "github.com/erda-project/erda-proto-go/core/org/pb"
+	"github.com/erda-project/erda/internal/tools/monitor/core/metric/query/promql"
+	"github.com/erda-project/erda/pkg/common/apis"
+	api "github.com/erda-project/erda/pkg/common/httpapi"
+	"github.com/erda-project/erda/pkg/discover"
+)
+
+// defaultSeriesRange is the time range of series and labels apis if start is not specified
+const defaultSeriesRange = time.Hour
+
+func (p *provider) initRoutes(routes httpserver.Router) {
+	routes.GET("/api/v1/query", p.query)
+	routes.POST("/api/v1/query", p.query)
+	routes.GET("/api/v1/query_range", p.queryRange)
+	routes.POST("/api/v1/query_range", p.queryRange)
+	routes.GET("/api/v1/series", p.series)
+	routes.POST("/api/v1/series", p.series)
+	routes.GET("/api/v1/labels", p.labels)
+	routes.POST("/api/v1/labels", p.labels)
+	routes.GET("/api/v1/label/:name/values", p.labelValues)
+}
+
+// error types of the prometheus http api
+const (
+	errorBadData  = "bad_data"
+	errorExec     = "execution"
+	errorTimeout  = "timeout"
+	errorCanceled = "canceled"
+)
+
+type response struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+func writeData(rw http.ResponseWriter, data interface{}) {
+	writeJSON(rw, http.StatusOK, &response{Status: "success", Data: data})
+}
+
+func writeError(rw http.ResponseWriter, typ string, err error) {
+	code := http.StatusUnprocessableEntity
+	switch typ {
+	case errorBadData:
+		code = http.StatusBadRequest
+	case errorTimeout, errorCanceled:
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(rw, code, &response{Status: "error", ErrorType: typ, Error: err.Error()})
+}
+
+func writeJSON(rw http.ResponseWriter, code int, resp *response) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	json.NewEncoder(rw).Encode(resp)
+}
+
+// writeQueryError writes the error of the query engine with its error type
+func writeQueryError(rw http.ResponseWriter, err error) {
+	var parseErr *promql.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		writeError(rw, errorBadData, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(rw, errorTimeout, err)
+	case errors.Is(err, context.Canceled):
+		writeError(rw, errorCanceled, err)
+	default:
+		writeError(rw, errorExec, err)
+	}
+}
+
+// newQuerier returns the querier scoped to the org and tenant of the request
+func (p *provider) newQuerier(r *http.Request) (*querier, error) {
+	org := r.Header.Get("org")
+	if len(org) == 0 {
+		orgID := api.OrgID(r)
+		if len(orgID) == 0 {
+			return nil, fmt.Errorf("org is required")
+		}
+		resp, err := p.Org.GetOrg(apis.WithInternalClientContext(context.Background(), discover.SvcMonitor), &orgpb.GetOrgRequest{
+			IdOrName: orgID,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get org %s", orgID)
+		}
+		org = resp.Data.Name
+	}
+	return &querier{p: p, org: org, terminusKey: r.Header.Get("terminus_key")}, nil
+}
+
+func (p *provider) prepare(rw http.ResponseWriter, r *http.Request) (*querier, context.Context, context.CancelFunc, bool) {
+	if err := r.ParseForm(); err != nil {
+		writeError(rw, errorBadData, err)
+		return nil, nil, nil, false
+	}
+	q, err := p.newQuerier(r)
+	if err != nil {
+		writeError(rw, errorBadData, err)
+		return nil, nil, nil, false
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), p.C.QueryTimeout)
+	return q, ctx, cancel, true
+}
+
+func (p *provider) query(rw http.ResponseWriter, r *http.Request) {
+	q, ctx, cancel, ok := p.prepare(rw, r)
+	if !ok {
+		return
+	}
+	defer cancel()
+	ts, err := parseTime(r.Form.Get("time"), time.Now())
+	if err != nil {
+		writeError(rw, errorBadData, errors.Wrap(err, "invalid parameter 'time'"))
+		return
+	}
+	val, err := p.engine.Instant(ctx, q, r.Form.Get("query"), ts)
+	if err != nil {
+		writeQueryError(rw, err)
+		return
+	}
+	writeData(rw, map[string]interface{}{
+		"resultType": val.Type(),
+		"result":     encodeValue(val),
+	})
+}
+
+func (p *provider) queryRange(rw http.ResponseWriter, r *http.Request) {
+	q, ctx, cancel, ok := p.prepare(rw, r)
+	if !ok {
+		return
+	}
+	defer cancel()
+	start, err := parseTime(r.Form.Get("start"), time.Time{})
+	if err == nil && start.IsZero() {
+		err = fmt.Errorf("start is required")
+	}
+	if err != nil {
+		writeError(rw, errorBadData, errors.Wrap(err, "invalid parameter 'start'"))
+		return
+	}
+	end, err := parseTime(r.Form.Get("end"), time.Time{})
+	if err == nil && end.IsZero() {
+		err = fmt.Errorf("end is required")
+	}
+	if err != nil {
+		writeError(rw, errorBadData, errors.Wrap(err, "invalid parameter 'end'"))
+		return
+	}
+	step, err := parseDuration(r.Form.Get("step"))
+	if err != nil {
+		writeError(rw, errorBadData, errors.Wrap(err, "invalid parameter 'step'"))
+		return
+	}
+	val, err := p.engine.Range(ctx, q, r.Form.Get("query"), start, end, step)
+	if err != nil {
+		writeQueryError(rw, err)
+		return
+	}
+	writeData(rw, map[string]interface{}{
+		"resultType": val.Type(),
+		"result":     encodeValue(val),
+	})
+}
+
+func (p *provider) series(rw http.ResponseWriter, r *http.Request) {
+	q, ctx, cancel, ok := p.prepare(rw, r)
+	if !ok {
+		return
+	}
+	defer cancel()
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(rw, errorBadData, err)
+		return
+	}
+	selectors, err := parseMatchers(r.Form["match[]"])
+	if err != nil {
+		writeError(rw, errorBadData, err)
+		return
+	}
+	if len(selectors) == 0 {
+		writeError(rw, errorBadData, fmt.Errorf("no match[] parameter provided"))
+		return
+	}
+	list, err := q.Series(ctx, start, end, selectors)
+	if err != nil {
+		writeQueryError(rw, err)
+		return
+	}
+	data := make([]map[string]string, 0, len(list))
+	for _, labels := range list {
+		data = append(data, labels.Map())
+	}
+	writeData(rw, data)
+}
+
+func (p *provider) labels(rw http.ResponseWriter, r *http.Request) {
+	q, ctx, cancel, ok := p.prepare(rw, r)
+	if !ok {
+		return
+	}
+	defer cancel()
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(rw, errorBadData, err)
+		return
+	}
+	selectors, err := parseMatchers(r.Form["match[]"])
+	if err != nil {
+		writeError(rw, errorBadData, err)
+		return
+	}
+	var names []string
+	if len(selectors) > 0 {
+		var list []promql.Labels
+		list, err = q.Series(ctx, start, end, selectors)
+		for _, labels := range list {
+			for _, l := range labels {
+				names = append(names, l.Name)
+			}
+		}
+		names = mergeSorted(names)
+	} else {
+		names, err = q.LabelNames(ctx, start, end)
+	}
+	if err != nil {
+		writeQueryError(rw, err)
+		return
+	}
+	writeData(rw, names)
+}
+
+func (p *provider) labelValues(rw http.ResponseWriter, r *http.Request) {
+	q, ctx, cancel, ok := p.prepare(rw, r)
+	if !ok {
+		return
+	}
+	defer cancel()
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/label/"), "/values")
+	if len(name) == 0 {
+		writeError(rw, errorBadData, fmt.Errorf("invalid label name"))
+		return
+	}
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(rw, errorBadData, err)
+		return
+	}
+	values, err := q.LabelValues(ctx, start, end, name)
+	if err != nil {
+		writeQueryError(rw, err)
+		return
+	}
+	writeData(rw, values)
+}
+
+// parseTime parses the unix timestamp in seconds or the RFC3339 time
+func parseTime(s string, defaultTime time.Time) (time.Time, error) {
+	if len(s) == 0 {
+		return defaultTime, nil
+	}
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(math.Round(frac*1000))*int64(time.Millisecond)), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// parseDuration parses the duration in seconds or the prometheus duration
+func parseDuration(s string) (d time.Duration, err error) {
+	if v, perr := strconv.ParseFloat(s, 64); perr == nil {
+		d = time.Duration(v * float64(time.Second))
+	} else if d, err = promql.ParseDuration(s); err != nil {
+		return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+	}
+	// the engine evaluates in milliseconds
+	if d < time.Millisecond {
+		return 0, fmt.Errorf("cannot parse %q to a valid duration, it must be at least 1ms", s)
+	}
+	return d, nil
+}
+
+func parseTimeRange(r *http.Request) (int64, int64, error) {
+	end, err := parseTime(r.Form.Get("end"), time.Now())
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "invalid parameter 'end'")
+	}
+	start, err := parseTime(r.Form.Get("start"), end.Add(-defaultSeriesRange))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "invalid parameter 'start'")
+	}
+	if end.Before(start) {
+		return 0, 0, fmt.Errorf("end timestamp must not be before start time")
+	}
+	return start.UnixNano() / int64(time.Millisecond), end.UnixNano() / int64(time.Millisecond), nil
+}
+
+// parseMatchers parses the series selectors of match[] parameters
+func parseMatchers(list []string) ([][]*promql.Matcher, error) {
+	var selectors [][]*promql.Matcher
+	for _, s := range list {
+		expr, err := promql.ParseExpr(s)
+		if err != nil {
+			return nil, err
+		}
+		vs, ok := expr.(*promql.VectorSelector)
+		if !ok {
+			return nil, fmt.Errorf("invalid parameter 'match[]': %q is not a series selector", s)
+		}
+		selectors = append(selectors, vs.Matchers)
+	}
+	return selectors, nil
+}
+
+// encodeValue converts the value to the result format of the prometheus http api
+func encodeValue(val promql.Value) interface{} {
+	switch val := val.(type) {
+	case promql.Scalar:
+		return encodePoint(val.T, promql.FormatFloat(val.V))
+	case promql.String:
+		return encodePoint(val.T, val.V)
+	case promql.Vector:
+		result := make([]map[string]interface{}, 0, len(val))
+		for _, s := range val {
+			result = append(result, map[string]interface{}{
+				"metric": s.Metric.Map(),
+				"value":  encodePoint(s.T, promql.FormatFloat(s.V)),
+			})
+		}
+		return result
+	case promql.Matrix:
+		result := make([]map[string]interface{}, 0, len(val))
+		for _, s := range val {
+			values := make([]interface{}, 0, len(s.Points))
+			for _, p := range s.Points {
+				values = append(values, encodePoint(p.T, promql.FormatFloat(p.V)))
+			}
+			result = append(result, map[string]interface{}{
+				"metric": s.Metric.Map(),
+				"values": values,
+			})
+		}
+		return result
+	}
+	return nil
+}
+
+func encodePoint(t int64, v string) []interface{} {
+	return []interface{}{float64(t) / 1000, v}
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+type aggregateGroup struct {
+	labels  Labels
+	value   float64
+	mean    float64
+	count   int
+	samples Vector
+}
+
+func (ev *evaluator) aggregate(e *AggregateExpr) (Value, error) {
+	val, err := ev.eval(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	vec := val.(Vector)
+	var param float64
+	var valueLabel string
+	if e.Param != nil {
+		pv, err := ev.eval(e.Param)
+		if err != nil {
+			return nil, err
+		}
+		switch pv := pv.(type) {
+		case Scalar:
+			param = pv.V
+		case String:
+			valueLabel = pv.V
+		}
+	}
+	if e.Op == "count_values" {
+		return ev.countValues(e, vec, valueLabel), nil
+	}
+
+	groups := make(map[string]*aggregateGroup)
+	var order []string
+	for _, s := range vec {
+		labels := groupingLabels(s.Metric, e.Grouping, e.Without)
+		key := labels.String()
+		g, ok := groups[key]
+		if !ok {
+			g = &aggregateGroup{labels: labels, value: s.V, mean: s.V, count: 1}
+			switch e.Op {
+			case "count", "group":
+				g.value = 1
+			case "stddev", "stdvar":
+				g.value = 0
+			case "topk", "bottomk", "quantile":
+				g.samples = Vector{s}
+			}
+			groups[key] = g
+			order = append(order, key)
+			continue
+		}
+		g.count++
+		switch e.Op {
+		case "sum":
+			g.value += s.V
+		case "avg":
+			g.mean += (s.V - g.mean) / float64(g.count)
+		case "max":
+			if g.value < s.V || math.IsNaN(g.value) {
+				g.value = s.V
+			}
+		case "min":
+			if g.value > s.V || math.IsNaN(g.value) {
+				g.value = s.V
+			}
+		case "count":
+			g.value++
+		case "stddev", "stdvar":
+			delta := s.V - g.mean
+			g.mean += delta / float64(g.count)
+			g.value += delta * (s.V - g.mean)
+		case "topk", "bottomk", "quantile":
+			g.samples = append(g.samples, s)
+		}
+	}
+
+	result := make(Vector, 0, len(groups))
+	for _, key := range order {
+		g := groups[key]
+		var v float64
+		switch e.Op {
+		case "avg":
+			v = g.mean
+		case "stdvar":
+			v = g.value / float64(g.count)
+		case "stddev":
+			v = math.Sqrt(g.value / float64(g.count))
+		case "quantile":
+			values := make([]float64, len(g.samples))
+			for i, s := range g.samples {
+				values[i] = s.V
+			}
+			v = quantile(param, values)
+		case "topk", "bottomk":
+			result = append(result, selectK(e.Op, param, g.samples)...)
+			continue
+		default:
+			v = g.value
+		}
+		result = append(result, Sample{Metric: g.labels, Point: Point{T: ev.ts, V: v}})
+	}
+	return result, nil
+}
+
+func groupingLabels(metric Labels, grouping []string, without bool) Labels {
+	if without {
+		return metric.without(append([]string{MetricNameLabel}, grouping...)...)
+	}
+	return metric.only(grouping...)
+}
+
+// selectK returns the k largest (topk) or smallest (bottomk) samples with their labels kept
+func selectK(op string, k float64, samples Vector) Vector {
+	if k < 1 || math.IsNaN(k) {
+		return nil
+	}
+	sorted := make(Vector, len(samples))
+	copy(sorted, samples)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].V, sorted[j].V
+		if math.IsNaN(a) {
+			return false
+		}
+		if math.IsNaN(b) {
+			return true
+		}
+		if op == "topk" {
+			return a > b
+		}
+		return a < b
+	})
+	if k < float64(len(sorted)) {
+		sorted = sorted[:int(k)]
+	}
+	return sorted
+}
+
+func (ev *evaluator) countValues(e *AggregateExpr, vec Vector, valueLabel string) Vector {
+	counts := make(map[string]*Sample)
+	var order []string
+	for _, s := range vec {
+		labels := groupingLabels(s.Metric, e.Grouping, e.Without).with(valueLabel, strconv.FormatFloat(s.V, 'f', -1, 64))
+		key := labels.String()
+		if c, ok := counts[key]; ok {
+			c.V++
+			continue
+		}
+		counts[key] = &Sample{Metric: labels, Point: Point{T: ev.ts, V: 1}}
+		order = append(order, key)
+	}
+	result := make(Vector, 0, len(order))
+	for _, key := range order {
+		result = append(result, *counts[key])
+	}
+	return result
+}
+
+// quantile calculates the q-quantile of the values with linear interpolation between the closest ranks
+func quantile(q float64, values []float64) float64 {
+	if len(values) == 0 || math.IsNaN(q) {
+		return math.NaN()
+	}
+	if q < 0 {
+		return math.Inf(-1)
+	}
+	if q > 1 {
+		return math.Inf(1)
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	n := float64(len(sorted))
+	rank := q * (n - 1)
+	lower := math.Max(0, math.Floor(rank))
+	upper := math.Min(n-1, lower+1)
+	weight := rank - math.Floor(rank)
+	return sorted[int(lower)]*(1-weight) + sorted[int(upper)]*weight
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// MatchType is the type of a label matcher
+type MatchType int
+
+// match types
+const (
+	MatchEqual MatchType = iota
+	MatchNotEqual
+	MatchRegexp
+	MatchNotRegexp
+)
+
+func (t MatchType) String() string {
+	switch t {
+	case MatchEqual:
+		return "="
+	case MatchNotEqual:
+		return "!="
+	case MatchRegexp:
+		return "=~"
+	case MatchNotRegexp:
+		return "!~"
+	}
+	return fmt.Sprintf("MatchType(%d)", int(t))
+}
+
+// Matcher matches the value of a label
+type Matcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+	re    *regexp.Regexp
+}
+
+// NewMatcher returns a matcher, the regexp is anchored at both ends as prometheus does
+func NewMatcher(typ MatchType, name, value string) (*Matcher, error) {
+	m := &Matcher{Type: typ, Name: name, Value: value}
+	if typ == MatchRegexp || typ == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %s", value, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches returns whether the value matches
+func (m *Matcher) Matches(s string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return s == m.Value
+	case MatchNotEqual:
+		return s != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(s)
+	case MatchNotRegexp:
+		return !m.re.MatchString(s)
+	}
+	return false
+}
+
+// Expr is a node of the parsed expression
+type Expr interface {
+	Type() ValueType
+}
+
+// NumberLiteral is a float literal
+type NumberLiteral struct {
+	Val float64
+}
+
+// StringLiteral is a string literal
+type StringLiteral struct {
+	Val string
+}
+
+// VectorSelector selects the latest sample of the matched series
+type VectorSelector struct {
+	Name     string
+	Matchers []*Matcher
+	Offset   time.Duration
+}
+
+// MatrixSelector selects the samples of the matched series in a range
+type MatrixSelector struct {
+	*VectorSelector
+	Range time.Duration
+}
+
+// Call is a function call
+type Call struct {
+	Func *Function
+	Args []Expr
+}
+
+// AggregateExpr is an aggregation over the samples of a vector
+type AggregateExpr struct {
+	Op       string
+	Expr     Expr
+	Param    Expr
+	Grouping []string
+	Without  bool
+}
+
+// VectorMatching describes how the samples of two vectors are matched
+type VectorMatching struct {
+	Card    string
+	On      bool
+	Labels  []string
+	Include []string
+}
+
+// vector matching cardinalities
+const (
+	CardOneToOne   = "one-to-one"
+	CardManyToOne  = "many-to-one"
+	CardOneToMany  = "one-to-many"
+	CardManyToMany = "many-to-many"
+)
+
+// BinaryExpr is a binary operation
+type BinaryExpr struct {
+	Op         string
+	LHS, RHS   Expr
+	ReturnBool bool
+	Matching   *VectorMatching
+}
+
+// UnaryExpr is a unary operation
+type UnaryExpr struct {
+	Op   string
+	Expr Expr
+}
+
+// ParenExpr is a parenthesized expression
+type ParenExpr struct {
+	Expr Expr
+}
+
+// Type .
+func (*NumberLiteral) Type() ValueType { return ValueTypeScalar }
+
+// Type .
+func (*StringLiteral) Type() ValueType { return ValueTypeString }
+
+// Type .
+func (*VectorSelector) Type() ValueType { return ValueTypeVector }
+
+// Type .
+func (*MatrixSelector) Type() ValueType { return ValueTypeMatrix }
+
+// Type .
+func (e *Call) Type() ValueType { return e.Func.ReturnType }
+
+// Type .
+func (*AggregateExpr) Type() ValueType { return ValueTypeVector }
+
+// Type .
+func (e *BinaryExpr) Type() ValueType {
+	if e.LHS.Type() == ValueTypeScalar && e.RHS.Type() == ValueTypeScalar {
+		return ValueTypeScalar
+	}
+	return ValueTypeVector
+}
+
+// Type .
+func (e *UnaryExpr) Type() ValueType { return e.Expr.Type() }
+
+// Type .
+func (e *ParenExpr) Type() ValueType { return e.Expr.Type() }
+
+// Selectors returns all the vector selectors of the expression
+func Selectors(expr Expr) []*VectorSelector {
+	var list []*VectorSelector
+	walk(expr, func(e Expr) {
+		switch e := e.(type) {
+		case *VectorSelector:
+			list = append(list, e)
+		case *MatrixSelector:
+			list = append(list, e.VectorSelector)
+		}
+	})
+	return list
+}
+
+func walk(expr Expr, fn func(Expr)) {
+	if expr == nil {
+		return
+	}
+	fn(expr)
+	switch e := expr.(type) {
+	case *Call:
+		for _, arg := range e.Args {
+			walk(arg, fn)
+		}
+	case *AggregateExpr:
+		walk(e.Param, fn)
+		walk(e.Expr, fn)
+	case *BinaryExpr:
+		walk(e.LHS, fn)
+		walk(e.RHS, fn)
+	case *UnaryExpr:
+		walk(e.Expr, fn)
+	case *ParenExpr:
+		walk(e.Expr, fn)
+	}
+}
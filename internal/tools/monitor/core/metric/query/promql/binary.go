@@ -0,0 +1,259 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"math"
+)
+
+func (ev *evaluator) binary(e *BinaryExpr) (Value, error) {
+	lhs, err := ev.eval(e.LHS)
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := ev.eval(e.RHS)
+	if err != nil {
+		return nil, err
+	}
+	switch l := lhs.(type) {
+	case Scalar:
+		switch r := rhs.(type) {
+		case Scalar:
+			v, keep := scalarBinop(e.Op, l.V, r.V)
+			if e.ReturnBool {
+				v = boolValue(keep)
+			}
+			return Scalar{T: ev.ts, V: v}, nil
+		case Vector:
+			return ev.vectorScalarBinop(e, r, l.V, true), nil
+		}
+	case Vector:
+		switch r := rhs.(type) {
+		case Scalar:
+			return ev.vectorScalarBinop(e, l, r.V, false), nil
+		case Vector:
+			switch e.Op {
+			case "and":
+				return ev.vectorAnd(l, r, e.Matching), nil
+			case "or":
+				return ev.vectorOr(l, r, e.Matching), nil
+			case "unless":
+				return ev.vectorUnless(l, r, e.Matching), nil
+			}
+			return ev.vectorBinop(e, l, r)
+		}
+	}
+	return nil, fmt.Errorf("invalid operand types %s and %s of binary expression", lhs.Type(), rhs.Type())
+}
+
+// scalarBinop returns the result of arithmetic operators, or the lhs and whether the comparison is true
+func scalarBinop(op string, lhs, rhs float64) (float64, bool) {
+	switch op {
+	case "+":
+		return lhs + rhs, true
+	case "-":
+		return lhs - rhs, true
+	case "*":
+		return lhs * rhs, true
+	case "/":
+		return lhs / rhs, true
+	case "%":
+		return math.Mod(lhs, rhs), true
+	case "^":
+		return math.Pow(lhs, rhs), true
+	case "==":
+		return lhs, lhs == rhs
+	case "!=":
+		return lhs, lhs != rhs
+	case ">":
+		return lhs, lhs > rhs
+	case "<":
+		return lhs, lhs < rhs
+	case ">=":
+		return lhs, lhs >= rhs
+	case "<=":
+		return lhs, lhs <= rhs
+	}
+	return math.NaN(), false
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (ev *evaluator) vectorScalarBinop(e *BinaryExpr, vec Vector, scalar float64, swap bool) Vector {
+	comparison := isComparisonOperator(e.Op)
+	result := make(Vector, 0, len(vec))
+	for _, s := range vec {
+		lv, rv := s.V, scalar
+		if swap {
+			lv, rv = rv, lv
+		}
+		v, keep := scalarBinop(e.Op, lv, rv)
+		// comparisons filter the samples of the vector side
+		if comparison && swap {
+			v = s.V
+		}
+		metric := s.Metric
+		if e.ReturnBool {
+			v, keep = boolValue(keep), true
+		}
+		if !keep {
+			continue
+		}
+		if !comparison || e.ReturnBool {
+			metric = metric.without(MetricNameLabel)
+		}
+		result = append(result, Sample{Metric: metric, Point: Point{T: ev.ts, V: v}})
+	}
+	return result
+}
+
+// matchingKey returns the key of the labels used to match samples of the two sides
+func matchingKey(metric Labels, matching *VectorMatching) string {
+	if matching.On {
+		return metric.only(matching.Labels...).String()
+	}
+	return metric.without(append([]string{MetricNameLabel}, matching.Labels...)...).String()
+}
+
+func (ev *evaluator) vectorAnd(lhs, rhs Vector, matching *VectorMatching) Vector {
+	keys := make(map[string]bool, len(rhs))
+	for _, s := range rhs {
+		keys[matchingKey(s.Metric, matching)] = true
+	}
+	var result Vector
+	for _, s := range lhs {
+		if keys[matchingKey(s.Metric, matching)] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (ev *evaluator) vectorOr(lhs, rhs Vector, matching *VectorMatching) Vector {
+	keys := make(map[string]bool, len(lhs))
+	result := make(Vector, 0, len(lhs)+len(rhs))
+	for _, s := range lhs {
+		keys[matchingKey(s.Metric, matching)] = true
+		result = append(result, s)
+	}
+	for _, s := range rhs {
+		if !keys[matchingKey(s.Metric, matching)] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (ev *evaluator) vectorUnless(lhs, rhs Vector, matching *VectorMatching) Vector {
+	keys := make(map[string]bool, len(rhs))
+	for _, s := range rhs {
+		keys[matchingKey(s.Metric, matching)] = true
+	}
+	var result Vector
+	for _, s := range lhs {
+		if !keys[matchingKey(s.Metric, matching)] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (ev *evaluator) vectorBinop(e *BinaryExpr, lhs, rhs Vector) (Vector, error) {
+	matching := e.Matching
+	// the "one" side is always on the right
+	if matching.Card == CardOneToMany {
+		lhs, rhs = rhs, lhs
+	}
+	ones := make(map[string]Sample, len(rhs))
+	for _, s := range rhs {
+		key := matchingKey(s.Metric, matching)
+		if dup, ok := ones[key]; ok {
+			return nil, fmt.Errorf("found duplicate series for the match group %s on the %s hand-side of the operation: [%s, %s];"+
+				"many-to-many matching not allowed: matching labels must be unique on one side",
+				key, oneSide(matching), dup.Metric, s.Metric)
+		}
+		ones[key] = s
+	}
+
+	comparison := isComparisonOperator(e.Op)
+	matched := make(map[string]bool)
+	outputs := make(map[string]bool)
+	var result Vector
+	for _, ls := range lhs {
+		key := matchingKey(ls.Metric, matching)
+		rs, ok := ones[key]
+		if !ok {
+			continue
+		}
+		lv, rv := ls.V, rs.V
+		if matching.Card == CardOneToMany {
+			lv, rv = rv, lv
+		}
+		v, keep := scalarBinop(e.Op, lv, rv)
+		if e.ReturnBool {
+			v, keep = boolValue(keep), true
+		}
+		if !keep {
+			continue
+		}
+		metric := resultMetric(ls.Metric, rs.Metric, matching, !comparison || e.ReturnBool)
+		if matching.Card == CardOneToOne {
+			if matched[key] {
+				return nil, fmt.Errorf("multiple matches for labels: many-to-one matching must be explicit (group_left/group_right)")
+			}
+			matched[key] = true
+		} else {
+			out := metric.String()
+			if outputs[out] {
+				return nil, fmt.Errorf("multiple matches for labels: grouping labels must ensure unique matches")
+			}
+			outputs[out] = true
+		}
+		result = append(result, Sample{Metric: metric, Point: Point{T: ev.ts, V: v}})
+	}
+	return result, nil
+}
+
+func oneSide(matching *VectorMatching) string {
+	if matching.Card == CardOneToMany {
+		return "left"
+	}
+	return "right"
+}
+
+// resultMetric returns the labels of the result sample of the "many" side sample lhs and the "one" side sample rhs
+func resultMetric(lhs, rhs Labels, matching *VectorMatching, dropName bool) Labels {
+	metric := lhs
+	if dropName {
+		metric = metric.without(MetricNameLabel)
+	}
+	if matching.Card == CardOneToOne {
+		if matching.On {
+			metric = metric.only(matching.Labels...)
+		} else {
+			metric = metric.without(matching.Labels...)
+		}
+	}
+	for _, name := range matching.Include {
+		metric = metric.with(name, rhs.Get(name))
+	}
+	return metric
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Querier loads the raw samples of the series matched by all the matchers,
+// the points of each series must be ordered by timestamp.
+type Querier interface {
+	Select(ctx context.Context, start, end int64, matchers []*Matcher) ([]*Series, error)
+}
+
+// EngineOptions .
+type EngineOptions struct {
+	// LookbackDelta is how far back an instant vector selector looks for the latest sample
+	LookbackDelta time.Duration
+	// MaxPoints is the max number of steps of a range query
+	MaxPoints int64
+}
+
+// Engine evaluates promql expressions against the samples of a Querier
+type Engine struct {
+	lookbackDelta time.Duration
+	maxPoints     int64
+}
+
+// NewEngine .
+func NewEngine(opts EngineOptions) *Engine {
+	if opts.LookbackDelta <= 0 {
+		opts.LookbackDelta = 5 * time.Minute
+	}
+	if opts.MaxPoints <= 0 {
+		opts.MaxPoints = 11000
+	}
+	return &Engine{lookbackDelta: opts.LookbackDelta, maxPoints: opts.MaxPoints}
+}
+
+// Instant evaluates the query at the timestamp
+func (e *Engine) Instant(ctx context.Context, q Querier, query string, ts time.Time) (Value, error) {
+	expr, err := ParseExpr(query)
+	if err != nil {
+		return nil, err
+	}
+	t := toMillis(ts)
+	ev, err := e.newEvaluator(ctx, q, expr, t, t)
+	if err != nil {
+		return nil, err
+	}
+	ev.ts = t
+	val, err := ev.eval(expr)
+	if err != nil {
+		return nil, err
+	}
+	if vec, ok := val.(Vector); ok {
+		if err := checkDuplicates(vec); err != nil {
+			return nil, err
+		}
+	}
+	return val, nil
+}
+
+// Range evaluates the query at each step from start to end
+func (e *Engine) Range(ctx context.Context, q Querier, query string, start, end time.Time, step time.Duration) (Matrix, error) {
+	if step <= 0 {
+		return nil, &ParseError{Err: fmt.Errorf("zero or negative query resolution step widths are not accepted")}
+	}
+	// steps are applied in milliseconds, a shorter one would never advance
+	if step < time.Millisecond {
+		return nil, &ParseError{Err: fmt.Errorf("query resolution step width must be at least 1ms")}
+	}
+	if end.Before(start) {
+		return nil, &ParseError{Err: fmt.Errorf("end timestamp must not be before start time")}
+	}
+	if end.Sub(start)/step+1 > time.Duration(e.maxPoints) {
+		return nil, &ParseError{Err: fmt.Errorf("exceeded maximum resolution of %d points per timeseries, try decreasing the query resolution", e.maxPoints)}
+	}
+	expr, err := ParseExpr(query)
+	if err != nil {
+		return nil, err
+	}
+	if t := expr.Type(); t != ValueTypeScalar && t != ValueTypeVector {
+		return nil, &ParseError{Err: fmt.Errorf("invalid expression type %q for range query, must be scalar or instant vector", t)}
+	}
+	startMs, endMs, stepMs := toMillis(start), toMillis(end), step.Milliseconds()
+	ev, err := e.newEvaluator(ctx, q, expr, startMs, endMs)
+	if err != nil {
+		return nil, err
+	}
+	series := make(map[string]*Series)
+	for ts := startMs; ts <= endMs; ts += stepMs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ev.ts = ts
+		val, err := ev.eval(expr)
+		if err != nil {
+			return nil, err
+		}
+		switch val := val.(type) {
+		case Scalar:
+			appendPoint(series, nil, Point{T: ts, V: val.V})
+		case Vector:
+			if err := checkDuplicates(val); err != nil {
+				return nil, err
+			}
+			for _, s := range val {
+				appendPoint(series, s.Metric, Point{T: ts, V: s.V})
+			}
+		}
+	}
+	result := make(Matrix, 0, len(series))
+	for _, s := range series {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Metric.String() < result[j].Metric.String() })
+	return result, nil
+}
+
+func appendPoint(series map[string]*Series, metric Labels, p Point) {
+	key := metric.String()
+	s, ok := series[key]
+	if !ok {
+		s = &Series{Metric: metric}
+		series[key] = s
+	}
+	s.Points = append(s.Points, p)
+}
+
+func checkDuplicates(vec Vector) error {
+	seen := make(map[string]bool, len(vec))
+	for _, s := range vec {
+		key := s.Metric.String()
+		if seen[key] {
+			return fmt.Errorf("vector cannot contain metrics with the same labelset")
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+type evaluator struct {
+	ctx      context.Context
+	ts       int64
+	lookback int64
+	data     map[*VectorSelector][]*Series
+}
+
+// newEvaluator loads the samples of all the selectors needed to evaluate the expression from start to end
+func (e *Engine) newEvaluator(ctx context.Context, q Querier, expr Expr, start, end int64) (*evaluator, error) {
+	ev := &evaluator{
+		ctx:      ctx,
+		lookback: e.lookbackDelta.Milliseconds(),
+		data:     make(map[*VectorSelector][]*Series),
+	}
+	var err error
+	walk(expr, func(node Expr) {
+		if err != nil {
+			return
+		}
+		var vs *VectorSelector
+		rng := ev.lookback
+		switch node := node.(type) {
+		case *VectorSelector:
+			vs = node
+		case *MatrixSelector:
+			vs, rng = node.VectorSelector, node.Range.Milliseconds()
+		default:
+			return
+		}
+		offset := vs.Offset.Milliseconds()
+		var series []*Series
+		series, err = q.Select(ctx, start-offset-rng, end-offset, vs.Matchers)
+		if err == nil {
+			ev.data[vs] = series
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+func (ev *evaluator) eval(expr Expr) (Value, error) {
+	switch e := expr.(type) {
+	case *NumberLiteral:
+		return Scalar{T: ev.ts, V: e.Val}, nil
+	case *StringLiteral:
+		return String{T: ev.ts, V: e.Val}, nil
+	case *ParenExpr:
+		return ev.eval(e.Expr)
+	case *UnaryExpr:
+		val, err := ev.eval(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		switch val := val.(type) {
+		case Scalar:
+			return Scalar{T: val.T, V: -val.V}, nil
+		case Vector:
+			result := make(Vector, 0, len(val))
+			for _, s := range val {
+				result = append(result, Sample{Metric: s.Metric.without(MetricNameLabel), Point: Point{T: s.T, V: -s.V}})
+			}
+			return result, nil
+		}
+		return nil, fmt.Errorf("unexpected type %s of unary expression", val.Type())
+	case *VectorSelector:
+		return ev.vectorSelector(e), nil
+	case *MatrixSelector:
+		return ev.matrixSelector(e), nil
+	case *Call:
+		args := make([]Value, len(e.Args))
+		for i, arg := range e.Args {
+			val, err := ev.eval(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = val
+		}
+		return e.Func.call(ev, e, args)
+	case *AggregateExpr:
+		return ev.aggregate(e)
+	case *BinaryExpr:
+		return ev.binary(e)
+	}
+	return nil, fmt.Errorf("unhandled expression of type %T", expr)
+}
+
+func (ev *evaluator) vectorSelector(vs *VectorSelector) Vector {
+	refTime := ev.ts - vs.Offset.Milliseconds()
+	result := make(Vector, 0, len(ev.data[vs]))
+	for _, s := range ev.data[vs] {
+		// the index of the first point after refTime
+		i := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].T > refTime })
+		if i == 0 {
+			continue
+		}
+		p := s.Points[i-1]
+		if p.T <= refTime-ev.lookback {
+			continue
+		}
+		result = append(result, Sample{Metric: s.Metric, Point: Point{T: ev.ts, V: p.V}})
+	}
+	return result
+}
+
+func (ev *evaluator) matrixSelector(ms *MatrixSelector) Matrix {
+	maxt := ev.ts - ms.Offset.Milliseconds()
+	mint := maxt - ms.Range.Milliseconds()
+	result := make(Matrix, 0, len(ev.data[ms.VectorSelector]))
+	for _, s := range ev.data[ms.VectorSelector] {
+		from := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].T > mint })
+		to := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].T > maxt })
+		if from >= to {
+			continue
+		}
+		result = append(result, &Series{Metric: s.Metric, Points: s.Points[from:to]})
+	}
+	return result
+}
@@ -0,0 +1,271 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+type memoryQuerier []*Series
+
+func (q memoryQuerier) Select(ctx context.Context, start, end int64, matchers []*Matcher) ([]*Series, error) {
+	var result []*Series
+	for _, s := range q {
+		matched := true
+		for _, m := range matchers {
+			if !m.Matches(s.Metric.Get(m.Name)) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		var points []Point
+		for _, p := range s.Points {
+			if p.T >= start && p.T <= end {
+				points = append(points, p)
+			}
+		}
+		result = append(result, &Series{Metric: s.Metric, Points: points})
+	}
+	return result, nil
+}
+
+// counter returns a series with a sample every 15s from 0 to 10m, increasing by inc each sample
+func counter(inc float64, labels ...string) *Series {
+	m := map[string]string{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		m[labels[i]] = labels[i+1]
+	}
+	s := &Series{Metric: NewLabels(m)}
+	for i := 0; i <= 40; i++ {
+		s.Points = append(s.Points, Point{T: int64(i) * 15000, V: float64(i) * inc})
+	}
+	return s
+}
+
+func testQuerier() memoryQuerier {
+	return memoryQuerier{
+		counter(15, MetricNameLabel, "http_requests_total", "job", "api", "instance", "a", "code", "200"),
+		counter(30, MetricNameLabel, "http_requests_total", "job", "api", "instance", "b", "code", "200"),
+		counter(1.5, MetricNameLabel, "http_requests_total", "job", "api", "instance", "a", "code", "500"),
+		counter(3, MetricNameLabel, "http_requests_total", "job", "web", "instance", "c", "code", "200"),
+		counter(1, MetricNameLabel, "request_duration_bucket", "le", "0.1"),
+		counter(2, MetricNameLabel, "request_duration_bucket", "le", "0.5"),
+		counter(4, MetricNameLabel, "request_duration_bucket", "le", "+Inf"),
+		counter(0, MetricNameLabel, "instance_info", "instance", "a", "zone", "z1"),
+		counter(0, MetricNameLabel, "instance_info", "instance", "b", "zone", "z2"),
+	}
+}
+
+func instant(t *testing.T, query string, ts time.Time) Value {
+	val, err := NewEngine(EngineOptions{}).Instant(context.Background(), testQuerier(), query, ts)
+	if err != nil {
+		t.Fatalf("Instant(%q) error: %s", query, err)
+	}
+	return val
+}
+
+func vectorValues(vec Vector) map[string]float64 {
+	result := make(map[string]float64, len(vec))
+	for _, s := range vec {
+		result[s.Metric.String()] = s.V
+	}
+	return result
+}
+
+func assertVector(t *testing.T, query string, got Value, want map[string]float64) {
+	t.Helper()
+	vec, ok := got.(Vector)
+	if !ok {
+		t.Fatalf("%s: want vector, got %s", query, got.Type())
+	}
+	values := vectorValues(vec)
+	if len(values) != len(want) {
+		t.Fatalf("%s: got %v, want %v", query, values, want)
+	}
+	for k, v := range want {
+		if gv, ok := values[k]; !ok || math.Abs(gv-v) > 1e-9 {
+			t.Fatalf("%s: got %v, want %v", query, values, want)
+		}
+	}
+}
+
+func TestEngine_Instant(t *testing.T) {
+	ts := time.Unix(600, 0)
+	tests := []struct {
+		query string
+		want  map[string]float64
+	}{
+		{
+			query: `http_requests_total{job="api", code="200"}`,
+			want: map[string]float64{
+				`{__name__="http_requests_total", code="200", instance="a", job="api"}`: 600,
+				`{__name__="http_requests_total", code="200", instance="b", job="api"}`: 1200,
+			},
+		},
+		{
+			query: `http_requests_total{code="200", instance="a"} offset 5m`,
+			want:  map[string]float64{`{__name__="http_requests_total", code="200", instance="a", job="api"}`: 300},
+		},
+		{
+			query: `rate(http_requests_total{instance="a", code="200"}[5m])`,
+			want:  map[string]float64{`{code="200", instance="a", job="api"}`: 1},
+		},
+		{
+			query: `sum by (job) (rate(http_requests_total[5m]))`,
+			want:  map[string]float64{`{job="api"}`: 3.1, `{job="web"}`: 0.2},
+		},
+		{
+			query: `sum without (instance, code) (increase(http_requests_total[5m]))`,
+			want:  map[string]float64{`{job="api"}`: 930, `{job="web"}`: 60},
+		},
+		{
+			query: `topk(1, http_requests_total)`,
+			want:  map[string]float64{`{__name__="http_requests_total", code="200", instance="b", job="api"}`: 1200},
+		},
+		{
+			query: `count(http_requests_total) by (code)`,
+			want:  map[string]float64{`{code="200"}`: 3, `{code="500"}`: 1},
+		},
+		{
+			query: `histogram_quantile(0.5, rate(request_duration_bucket[5m]))`,
+			want:  map[string]float64{`{}`: 0.5},
+		},
+		{
+			query: `histogram_quantile(0.25, rate(request_duration_bucket[5m]))`,
+			want:  map[string]float64{`{}`: 0.1},
+		},
+		{
+			query: `http_requests_total{code="500"} / ignoring(code) http_requests_total{code="200"}`,
+			want:  map[string]float64{`{instance="a", job="api"}`: 0.1},
+		},
+		{
+			query: `http_requests_total{code="200"} * on(instance) group_left(zone) instance_info`,
+			want: map[string]float64{
+				`{code="200", instance="a", job="api", zone="z1"}`: 0,
+				`{code="200", instance="b", job="api", zone="z2"}`: 0,
+			},
+		},
+		{
+			query: `http_requests_total > 1000`,
+			want:  map[string]float64{`{__name__="http_requests_total", code="200", instance="b", job="api"}`: 1200},
+		},
+		{
+			query: `http_requests_total{job="web"} > bool 1000`,
+			want:  map[string]float64{`{code="200", instance="c", job="web"}`: 0},
+		},
+		{
+			query: `http_requests_total{job="api"} unless http_requests_total{code="200"}`,
+			want:  map[string]float64{`{__name__="http_requests_total", code="500", instance="a", job="api"}`: 60},
+		},
+		{
+			query: `label_replace(instance_info, "host", "host-$1", "instance", "(.*)")`,
+			want: map[string]float64{
+				`{__name__="instance_info", host="host-a", instance="a", zone="z1"}`: 0,
+				`{__name__="instance_info", host="host-b", instance="b", zone="z2"}`: 0,
+			},
+		},
+		{
+			query: `absent(nonexistent{job="x"})`,
+			want:  map[string]float64{`{job="x"}`: 1},
+		},
+		{
+			query: `max_over_time(http_requests_total{job="web"}[1m]) - 2 * 3`,
+			want:  map[string]float64{`{code="200", instance="c", job="web"}`: 114},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assertVector(t, tt.query, instant(t, tt.query, ts), tt.want)
+		})
+	}
+}
+
+func TestEngine_InstantScalar(t *testing.T) {
+	val := instant(t, `scalar(sum(http_requests_total{job="web"})) / time()`, time.Unix(600, 0))
+	s, ok := val.(Scalar)
+	if !ok || s.V != 0.2 {
+		t.Fatalf("got %#v, want scalar 0.2", val)
+	}
+}
+
+func TestEngine_Lookback(t *testing.T) {
+	// the last sample is at 600s, so it is stale after the 5m lookback delta
+	assertVector(t, "stale", instant(t, `instance_info`, time.Unix(901, 0)), map[string]float64{})
+	val := instant(t, `count(instance_info)`, time.Unix(899, 0))
+	assertVector(t, "not stale", val, map[string]float64{`{}`: 2})
+}
+
+func TestEngine_Range(t *testing.T) {
+	e := NewEngine(EngineOptions{})
+	m, err := e.Range(context.Background(), testQuerier(), `sum(rate(http_requests_total{job="api"}[1m]))`,
+		time.Unix(300, 0), time.Unix(600, 0), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || len(m[0].Points) != 6 {
+		t.Fatalf("unexpected result %+v", m)
+	}
+	for _, p := range m[0].Points {
+		if math.Abs(p.V-3.1) > 1e-9 {
+			t.Fatalf("unexpected point %+v", p)
+		}
+	}
+
+	if _, err := e.Range(context.Background(), testQuerier(), `up[5m]`, time.Unix(0, 0), time.Unix(60, 0), time.Second); err == nil {
+		t.Fatal("range query of matrix must fail")
+	}
+	if _, err := e.Range(context.Background(), testQuerier(), `up`, time.Unix(0, 0), time.Unix(86400, 0), time.Second); err == nil {
+		t.Fatal("range query exceeding max points must fail")
+	}
+	for _, step := range []time.Duration{0, -time.Second, time.Microsecond} {
+		if _, err := e.Range(context.Background(), testQuerier(), `up`, time.Unix(0, 0), time.Unix(0, int64(time.Millisecond)), step); err == nil {
+			t.Fatalf("range query with step %s must fail", step)
+		}
+	}
+}
+
+func TestEngine_Errors(t *testing.T) {
+	e := NewEngine(EngineOptions{})
+	for _, query := range []string{
+		`http_requests_total / http_requests_total{code="200"} * on(job) http_requests_total`,
+		`label_replace(http_requests_total{job="api"}, "code", "", "code", ".*")`,
+	} {
+		if _, err := e.Instant(context.Background(), testQuerier(), query, time.Unix(600, 0)); err == nil {
+			t.Errorf("Instant(%q) want error", query)
+		}
+	}
+}
+
+func Test_bucketQuantile(t *testing.T) {
+	buckets := []bucket{{upperBound: math.Inf(1), count: 100}, {upperBound: 1, count: 50}, {upperBound: 2, count: 90}}
+	if v := bucketQuantile(0.5, buckets); v != 1 {
+		t.Errorf("bucketQuantile(0.5) = %v, want 1", v)
+	}
+	if v := bucketQuantile(0.7, buckets); math.Abs(v-1.5) > 1e-9 {
+		t.Errorf("bucketQuantile(0.7) = %v, want 1.5", v)
+	}
+	if v := bucketQuantile(0.99, buckets); v != 2 {
+		t.Errorf("bucketQuantile(0.99) = %v, want 2", v)
+	}
+	if v := bucketQuantile(0.5, []bucket{{upperBound: 1, count: 1}}); !math.IsNaN(v) {
+		t.Errorf("bucketQuantile without +Inf bucket = %v, want NaN", v)
+	}
+}
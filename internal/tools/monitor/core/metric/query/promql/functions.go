@@ -0,0 +1,620 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Function is a promql function
+type Function struct {
+	Name       string
+	ArgTypes   []ValueType
+	Optional   int
+	Variadic   bool
+	ReturnType ValueType
+	call       func(ev *evaluator, e *Call, args []Value) (Value, error)
+}
+
+var functions = map[string]*Function{}
+
+func init() {
+	vector := []ValueType{ValueTypeVector}
+	matrix := []ValueType{ValueTypeMatrix}
+	for name, fn := range map[string]func(points []Point, rangeStart, rangeEnd int64) (float64, bool){
+		"rate": func(points []Point, start, end int64) (float64, bool) {
+			return extrapolatedRate(points, start, end, true, true)
+		},
+		"increase": func(points []Point, start, end int64) (float64, bool) {
+			return extrapolatedRate(points, start, end, true, false)
+		},
+		"delta": func(points []Point, start, end int64) (float64, bool) {
+			return extrapolatedRate(points, start, end, false, false)
+		},
+		"irate":   func(points []Point, _, _ int64) (float64, bool) { return instantValue(points, true) },
+		"idelta":  func(points []Point, _, _ int64) (float64, bool) { return instantValue(points, false) },
+		"changes": func(points []Point, _, _ int64) (float64, bool) { return changes(points), true },
+		"resets":  func(points []Point, _, _ int64) (float64, bool) { return resets(points), true },
+		"deriv":   func(points []Point, _, _ int64) (float64, bool) { return deriv(points) },
+		"avg_over_time": func(points []Point, _, _ int64) (float64, bool) {
+			var mean float64
+			for i, p := range points {
+				mean += (p.V - mean) / float64(i+1)
+			}
+			return mean, true
+		},
+		"sum_over_time": func(points []Point, _, _ int64) (float64, bool) {
+			var sum float64
+			for _, p := range points {
+				sum += p.V
+			}
+			return sum, true
+		},
+		"min_over_time": func(points []Point, _, _ int64) (float64, bool) {
+			result := points[0].V
+			for _, p := range points {
+				if p.V < result || math.IsNaN(result) {
+					result = p.V
+				}
+			}
+			return result, true
+		},
+		"max_over_time": func(points []Point, _, _ int64) (float64, bool) {
+			result := points[0].V
+			for _, p := range points {
+				if p.V > result || math.IsNaN(result) {
+					result = p.V
+				}
+			}
+			return result, true
+		},
+		"count_over_time":   func(points []Point, _, _ int64) (float64, bool) { return float64(len(points)), true },
+		"last_over_time":    func(points []Point, _, _ int64) (float64, bool) { return points[len(points)-1].V, true },
+		"present_over_time": func(points []Point, _, _ int64) (float64, bool) { return 1, true },
+		"stddev_over_time":  func(points []Point, _, _ int64) (float64, bool) { return math.Sqrt(variance(points)), true },
+		"stdvar_over_time":  func(points []Point, _, _ int64) (float64, bool) { return variance(points), true },
+	} {
+		register(&Function{Name: name, ArgTypes: matrix, ReturnType: ValueTypeVector, call: rangeFunction(fn, name == "last_over_time")})
+	}
+	for name, fn := range map[string]func(float64) float64{
+		"abs":   math.Abs,
+		"ceil":  math.Ceil,
+		"floor": math.Floor,
+		"exp":   math.Exp,
+		"ln":    math.Log,
+		"log2":  math.Log2,
+		"log10": math.Log10,
+		"sqrt":  math.Sqrt,
+		"sgn": func(v float64) float64 {
+			switch {
+			case v < 0:
+				return -1
+			case v > 0:
+				return 1
+			}
+			return v
+		},
+	} {
+		register(&Function{Name: name, ArgTypes: vector, ReturnType: ValueTypeVector, call: simpleFunction(fn)})
+	}
+	for name, fn := range map[string]func(time.Time) float64{
+		"minute":       func(t time.Time) float64 { return float64(t.Minute()) },
+		"hour":         func(t time.Time) float64 { return float64(t.Hour()) },
+		"day_of_week":  func(t time.Time) float64 { return float64(t.Weekday()) },
+		"day_of_month": func(t time.Time) float64 { return float64(t.Day()) },
+		"days_in_month": func(t time.Time) float64 {
+			return float64(32 - time.Date(t.Year(), t.Month(), 32, 0, 0, 0, 0, time.UTC).Day())
+		},
+		"month": func(t time.Time) float64 { return float64(t.Month()) },
+		"year":  func(t time.Time) float64 { return float64(t.Year()) },
+	} {
+		register(&Function{Name: name, ArgTypes: vector, Optional: 1, ReturnType: ValueTypeVector, call: dateFunction(fn)})
+	}
+	register(&Function{Name: "quantile_over_time", ArgTypes: []ValueType{ValueTypeScalar, ValueTypeMatrix}, ReturnType: ValueTypeVector, call: funcQuantileOverTime})
+	register(&Function{Name: "predict_linear", ArgTypes: []ValueType{ValueTypeMatrix, ValueTypeScalar}, ReturnType: ValueTypeVector, call: funcPredictLinear})
+	register(&Function{Name: "round", ArgTypes: []ValueType{ValueTypeVector, ValueTypeScalar}, Optional: 1, ReturnType: ValueTypeVector, call: funcRound})
+	register(&Function{Name: "clamp", ArgTypes: []ValueType{ValueTypeVector, ValueTypeScalar, ValueTypeScalar}, ReturnType: ValueTypeVector, call: funcClamp})
+	register(&Function{Name: "clamp_min", ArgTypes: []ValueType{ValueTypeVector, ValueTypeScalar}, ReturnType: ValueTypeVector, call: funcClamp})
+	register(&Function{Name: "clamp_max", ArgTypes: []ValueType{ValueTypeVector, ValueTypeScalar}, ReturnType: ValueTypeVector, call: funcClamp})
+	register(&Function{Name: "histogram_quantile", ArgTypes: []ValueType{ValueTypeScalar, ValueTypeVector}, ReturnType: ValueTypeVector, call: funcHistogramQuantile})
+	register(&Function{Name: "time", ReturnType: ValueTypeScalar, call: funcTime})
+	register(&Function{Name: "timestamp", ArgTypes: vector, ReturnType: ValueTypeVector, call: funcTimestamp})
+	register(&Function{Name: "vector", ArgTypes: []ValueType{ValueTypeScalar}, ReturnType: ValueTypeVector, call: funcVector})
+	register(&Function{Name: "scalar", ArgTypes: vector, ReturnType: ValueTypeScalar, call: funcScalar})
+	register(&Function{Name: "sort", ArgTypes: vector, ReturnType: ValueTypeVector, call: funcSort})
+	register(&Function{Name: "sort_desc", ArgTypes: vector, ReturnType: ValueTypeVector, call: funcSort})
+	register(&Function{Name: "absent", ArgTypes: vector, ReturnType: ValueTypeVector, call: funcAbsent})
+	register(&Function{Name: "absent_over_time", ArgTypes: matrix, ReturnType: ValueTypeVector, call: funcAbsent})
+	register(&Function{
+		Name:       "label_replace",
+		ArgTypes:   []ValueType{ValueTypeVector, ValueTypeString, ValueTypeString, ValueTypeString, ValueTypeString},
+		ReturnType: ValueTypeVector,
+		call:       funcLabelReplace,
+	})
+	register(&Function{
+		Name:       "label_join",
+		ArgTypes:   []ValueType{ValueTypeVector, ValueTypeString, ValueTypeString, ValueTypeString},
+		Optional:   1,
+		Variadic:   true,
+		ReturnType: ValueTypeVector,
+		call:       funcLabelJoin,
+	})
+}
+
+func register(fn *Function) {
+	functions[fn.Name] = fn
+}
+
+// rangeFunction applies fn to the points of each series of the range, the metric name is dropped unless keepName
+func rangeFunction(fn func(points []Point, rangeStart, rangeEnd int64) (float64, bool), keepName bool) func(ev *evaluator, e *Call, args []Value) (Value, error) {
+	return func(ev *evaluator, e *Call, args []Value) (Value, error) {
+		ms := unwrapParens(e.Args[0]).(*MatrixSelector)
+		rangeEnd := ev.ts - ms.Offset.Milliseconds()
+		rangeStart := rangeEnd - ms.Range.Milliseconds()
+		var result Vector
+		for _, s := range args[0].(Matrix) {
+			if len(s.Points) == 0 {
+				continue
+			}
+			v, ok := fn(s.Points, rangeStart, rangeEnd)
+			if !ok {
+				continue
+			}
+			metric := s.Metric
+			if !keepName {
+				metric = metric.without(MetricNameLabel)
+			}
+			result = append(result, Sample{Metric: metric, Point: Point{T: ev.ts, V: v}})
+		}
+		return result, nil
+	}
+}
+
+// extrapolatedRate calculates the rate or increase of counters, or the delta of gauges,
+// extrapolated to the boundaries of the range in the same way as prometheus.
+func extrapolatedRate(points []Point, rangeStart, rangeEnd int64, isCounter, isRate bool) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	first, last := points[0], points[len(points)-1]
+	result := last.V - first.V
+	if isCounter {
+		prev := first.V
+		for _, p := range points[1:] {
+			if p.V < prev {
+				result += prev
+			}
+			prev = p.V
+		}
+	}
+	durationToStart := float64(first.T-rangeStart) / 1000
+	durationToEnd := float64(rangeEnd-last.T) / 1000
+	sampledInterval := float64(last.T-first.T) / 1000
+	if sampledInterval <= 0 {
+		return 0, false
+	}
+	averageDurationBetweenSamples := sampledInterval / float64(len(points)-1)
+	if isCounter && result > 0 && first.V >= 0 {
+		// counters can't be negative, so don't extrapolate further than the zero point
+		durationToZero := sampledInterval * (first.V / result)
+		if durationToZero < durationToStart {
+			durationToStart = durationToZero
+		}
+	}
+	threshold := averageDurationBetweenSamples * 1.1
+	extrapolateToInterval := sampledInterval
+	if durationToStart < threshold {
+		extrapolateToInterval += durationToStart
+	} else {
+		extrapolateToInterval += averageDurationBetweenSamples / 2
+	}
+	if durationToEnd < threshold {
+		extrapolateToInterval += durationToEnd
+	} else {
+		extrapolateToInterval += averageDurationBetweenSamples / 2
+	}
+	result = result * (extrapolateToInterval / sampledInterval)
+	if isRate {
+		result = result / (float64(rangeEnd-rangeStart) / 1000)
+	}
+	return result, true
+}
+
+// instantValue calculates irate or idelta from the last two points
+func instantValue(points []Point, isRate bool) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	last, prev := points[len(points)-1], points[len(points)-2]
+	result := last.V - prev.V
+	if isRate {
+		if last.V < prev.V {
+			// counter reset
+			result = last.V
+		}
+		interval := float64(last.T-prev.T) / 1000
+		if interval <= 0 {
+			return 0, false
+		}
+		result = result / interval
+	}
+	return result, true
+}
+
+func changes(points []Point) float64 {
+	var n float64
+	for i := 1; i < len(points); i++ {
+		cur, prev := points[i].V, points[i-1].V
+		if cur != prev && !(math.IsNaN(cur) && math.IsNaN(prev)) {
+			n++
+		}
+	}
+	return n
+}
+
+func resets(points []Point) float64 {
+	var n float64
+	for i := 1; i < len(points); i++ {
+		if points[i].V < points[i-1].V {
+			n++
+		}
+	}
+	return n
+}
+
+func variance(points []Point) float64 {
+	var mean, sum float64
+	for i, p := range points {
+		delta := p.V - mean
+		mean += delta / float64(i+1)
+		sum += delta * (p.V - mean)
+	}
+	return sum / float64(len(points))
+}
+
+// linearRegression returns the slope and the intercept at interceptTime of the points by least squares
+func linearRegression(points []Point, interceptTime int64) (slope, intercept float64) {
+	var n, sumX, sumY, sumXY, sumX2 float64
+	for _, p := range points {
+		x := float64(p.T-interceptTime) / 1000
+		n++
+		sumX += x
+		sumY += p.V
+		sumXY += x * p.V
+		sumX2 += x * x
+	}
+	covXY := sumXY - sumX*sumY/n
+	varX := sumX2 - sumX*sumX/n
+	slope = covXY / varX
+	intercept = sumY/n - slope*sumX/n
+	return slope, intercept
+}
+
+func deriv(points []Point) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	slope, _ := linearRegression(points, points[0].T)
+	return slope, true
+}
+
+func funcPredictLinear(ev *evaluator, e *Call, args []Value) (Value, error) {
+	duration := args[1].(Scalar).V
+	var result Vector
+	for _, s := range args[0].(Matrix) {
+		if len(s.Points) < 2 {
+			continue
+		}
+		slope, intercept := linearRegression(s.Points, ev.ts)
+		result = append(result, Sample{Metric: s.Metric.without(MetricNameLabel), Point: Point{T: ev.ts, V: slope*duration + intercept}})
+	}
+	return result, nil
+}
+
+func funcQuantileOverTime(ev *evaluator, e *Call, args []Value) (Value, error) {
+	q := args[0].(Scalar).V
+	var result Vector
+	for _, s := range args[1].(Matrix) {
+		values := make([]float64, len(s.Points))
+		for i, p := range s.Points {
+			values[i] = p.V
+		}
+		result = append(result, Sample{Metric: s.Metric.without(MetricNameLabel), Point: Point{T: ev.ts, V: quantile(q, values)}})
+	}
+	return result, nil
+}
+
+func simpleFunction(fn func(float64) float64) func(ev *evaluator, e *Call, args []Value) (Value, error) {
+	return func(ev *evaluator, e *Call, args []Value) (Value, error) {
+		vec := args[0].(Vector)
+		result := make(Vector, 0, len(vec))
+		for _, s := range vec {
+			result = append(result, Sample{Metric: s.Metric.without(MetricNameLabel), Point: Point{T: ev.ts, V: fn(s.V)}})
+		}
+		return result, nil
+	}
+}
+
+func dateFunction(fn func(time.Time) float64) func(ev *evaluator, e *Call, args []Value) (Value, error) {
+	return func(ev *evaluator, e *Call, args []Value) (Value, error) {
+		if len(args) == 0 {
+			t := time.Unix(ev.ts/1000, 0).UTC()
+			return Vector{{Point: Point{T: ev.ts, V: fn(t)}}}, nil
+		}
+		return simpleFunction(func(v float64) float64 {
+			return fn(time.Unix(int64(v), 0).UTC())
+		})(ev, e, args)
+	}
+}
+
+func funcRound(ev *evaluator, e *Call, args []Value) (Value, error) {
+	toNearest := 1.0
+	if len(args) > 1 {
+		toNearest = args[1].(Scalar).V
+	}
+	// invert as it seems to cause fewer floating point accuracy issues
+	toNearestInverse := 1.0 / toNearest
+	return simpleFunction(func(v float64) float64 {
+		return math.Floor(v*toNearestInverse+0.5) / toNearestInverse
+	})(ev, e, args[:1])
+}
+
+func funcClamp(ev *evaluator, e *Call, args []Value) (Value, error) {
+	lower, upper := math.Inf(-1), math.Inf(1)
+	switch e.Func.Name {
+	case "clamp":
+		lower, upper = args[1].(Scalar).V, args[2].(Scalar).V
+		if upper < lower {
+			return Vector{}, nil
+		}
+	case "clamp_min":
+		lower = args[1].(Scalar).V
+	case "clamp_max":
+		upper = args[1].(Scalar).V
+	}
+	return simpleFunction(func(v float64) float64 {
+		return math.Max(lower, math.Min(upper, v))
+	})(ev, e, args[:1])
+}
+
+type bucket struct {
+	upperBound float64
+	count      float64
+}
+
+func funcHistogramQuantile(ev *evaluator, e *Call, args []Value) (Value, error) {
+	q := args[0].(Scalar).V
+	type histogram struct {
+		labels  Labels
+		buckets []bucket
+	}
+	histograms := make(map[string]*histogram)
+	var order []string
+	for _, s := range args[1].(Vector) {
+		upperBound, err := strconv.ParseFloat(s.Metric.Get("le"), 64)
+		if err != nil {
+			// the sample is not a bucket of a histogram
+			continue
+		}
+		labels := s.Metric.without(MetricNameLabel, "le")
+		key := labels.String()
+		h, ok := histograms[key]
+		if !ok {
+			h = &histogram{labels: labels}
+			histograms[key] = h
+			order = append(order, key)
+		}
+		h.buckets = append(h.buckets, bucket{upperBound: upperBound, count: s.V})
+	}
+	result := make(Vector, 0, len(order))
+	for _, key := range order {
+		h := histograms[key]
+		result = append(result, Sample{Metric: h.labels, Point: Point{T: ev.ts, V: bucketQuantile(q, h.buckets)}})
+	}
+	return result, nil
+}
+
+// bucketQuantile calculates the quantile q of the cumulative histogram buckets,
+// assuming a linear distribution within a bucket as prometheus does.
+func bucketQuantile(q float64, buckets []bucket) float64 {
+	if q < 0 {
+		return math.Inf(-1)
+	}
+	if q > 1 {
+		return math.Inf(1)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].upperBound < buckets[j].upperBound })
+	if len(buckets) == 0 || !math.IsInf(buckets[len(buckets)-1].upperBound, 1) {
+		return math.NaN()
+	}
+	// merge the buckets with the same upper bound
+	merged := buckets[:1]
+	for _, b := range buckets[1:] {
+		if b.upperBound == merged[len(merged)-1].upperBound {
+			merged[len(merged)-1].count += b.count
+			continue
+		}
+		merged = append(merged, b)
+	}
+	buckets = merged
+	// the counts may be not monotonic because of precision issues
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i].count < buckets[i-1].count {
+			buckets[i].count = buckets[i-1].count
+		}
+	}
+	if len(buckets) < 2 {
+		return math.NaN()
+	}
+	rank := q * buckets[len(buckets)-1].count
+	b := sort.Search(len(buckets)-1, func(i int) bool { return buckets[i].count >= rank })
+	if b == len(buckets)-1 {
+		return buckets[len(buckets)-2].upperBound
+	}
+	if b == 0 && buckets[0].upperBound <= 0 {
+		return buckets[0].upperBound
+	}
+	bucketStart, bucketEnd, count := 0.0, buckets[b].upperBound, buckets[b].count
+	if b > 0 {
+		bucketStart = buckets[b-1].upperBound
+		count -= buckets[b-1].count
+		rank -= buckets[b-1].count
+	}
+	return bucketStart + (bucketEnd-bucketStart)*(rank/count)
+}
+
+func funcTime(ev *evaluator, e *Call, args []Value) (Value, error) {
+	return Scalar{T: ev.ts, V: float64(ev.ts) / 1000}, nil
+}
+
+func funcTimestamp(ev *evaluator, e *Call, args []Value) (Value, error) {
+	vec := args[0].(Vector)
+	result := make(Vector, 0, len(vec))
+	// the samples of the vector are stamped with the evaluation time, so look up the raw timestamps of selectors
+	raw := make(map[string]int64)
+	if vs, ok := unwrapParens(e.Args[0]).(*VectorSelector); ok {
+		refTime := ev.ts - vs.Offset.Milliseconds()
+		for _, s := range ev.data[vs] {
+			i := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].T > refTime })
+			if i > 0 {
+				raw[s.Metric.String()] = s.Points[i-1].T
+			}
+		}
+	}
+	for _, s := range vec {
+		t, ok := raw[s.Metric.String()]
+		if !ok {
+			t = s.T
+		}
+		result = append(result, Sample{Metric: s.Metric.without(MetricNameLabel), Point: Point{T: ev.ts, V: float64(t) / 1000}})
+	}
+	return result, nil
+}
+
+func unwrapParens(expr Expr) Expr {
+	for {
+		p, ok := expr.(*ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.Expr
+	}
+}
+
+func funcVector(ev *evaluator, e *Call, args []Value) (Value, error) {
+	return Vector{{Point: Point{T: ev.ts, V: args[0].(Scalar).V}}}, nil
+}
+
+func funcScalar(ev *evaluator, e *Call, args []Value) (Value, error) {
+	vec := args[0].(Vector)
+	if len(vec) != 1 {
+		return Scalar{T: ev.ts, V: math.NaN()}, nil
+	}
+	return Scalar{T: ev.ts, V: vec[0].V}, nil
+}
+
+func funcSort(ev *evaluator, e *Call, args []Value) (Value, error) {
+	vec := append(Vector{}, args[0].(Vector)...)
+	desc := e.Func.Name == "sort_desc"
+	sort.SliceStable(vec, func(i, j int) bool {
+		if desc {
+			return vec[i].V > vec[j].V
+		}
+		return vec[i].V < vec[j].V
+	})
+	return vec, nil
+}
+
+// funcAbsent returns a 1-element vector with the labels of the equality matchers if the argument has no elements
+func funcAbsent(ev *evaluator, e *Call, args []Value) (Value, error) {
+	switch arg := args[0].(type) {
+	case Vector:
+		if len(arg) > 0 {
+			return Vector{}, nil
+		}
+	case Matrix:
+		if len(arg) > 0 {
+			return Vector{}, nil
+		}
+	}
+	labels := map[string]string{}
+	var vs *VectorSelector
+	switch arg := unwrapParens(e.Args[0]).(type) {
+	case *VectorSelector:
+		vs = arg
+	case *MatrixSelector:
+		vs = arg.VectorSelector
+	}
+	if vs != nil {
+		for _, m := range vs.Matchers {
+			if m.Type == MatchEqual && m.Name != MetricNameLabel {
+				labels[m.Name] = m.Value
+			}
+		}
+	}
+	return Vector{{Metric: NewLabels(labels), Point: Point{T: ev.ts, V: 1}}}, nil
+}
+
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func funcLabelReplace(ev *evaluator, e *Call, args []Value) (Value, error) {
+	dst, replacement, src, expr := args[1].(String).V, args[2].(String).V, args[3].(String).V, args[4].(String).V
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression in label_replace(): %s", expr)
+	}
+	if !labelNameRegexp.MatchString(dst) {
+		return nil, fmt.Errorf("invalid destination label name in label_replace(): %s", dst)
+	}
+	vec := args[0].(Vector)
+	result := make(Vector, 0, len(vec))
+	for _, s := range vec {
+		value := s.Metric.Get(src)
+		metric := s.Metric
+		if indexes := re.FindStringSubmatchIndex(value); indexes != nil {
+			res := re.ExpandString([]byte{}, replacement, value, indexes)
+			metric = metric.with(dst, string(res))
+		}
+		result = append(result, Sample{Metric: metric, Point: s.Point})
+	}
+	return result, nil
+}
+
+func funcLabelJoin(ev *evaluator, e *Call, args []Value) (Value, error) {
+	dst, sep := args[1].(String).V, args[2].(String).V
+	if !labelNameRegexp.MatchString(dst) {
+		return nil, fmt.Errorf("invalid destination label name in label_join(): %s", dst)
+	}
+	var srcs []string
+	for _, arg := range args[3:] {
+		srcs = append(srcs, arg.(String).V)
+	}
+	vec := args[0].(Vector)
+	result := make(Vector, 0, len(vec))
+	for _, s := range vec {
+		values := make([]string, len(srcs))
+		for i, src := range srcs {
+			values[i] = s.Metric.Get(src)
+		}
+		result = append(result, Sample{Metric: s.Metric.with(dst, strings.Join(values, sep)), Point: s.Point})
+	}
+	return result, nil
+}
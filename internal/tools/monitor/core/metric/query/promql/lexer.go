@@ -0,0 +1,263 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdentifier
+	tokenNumber
+	tokenString
+	tokenDuration
+	tokenLeftBrace
+	tokenRightBrace
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBracket
+	tokenRightBracket
+	tokenComma
+	tokenAssign
+	tokenOperator
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokenEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+// operators ordered so that the longer ones are matched first
+var operators = []string{"==", "!=", ">=", "<=", "=~", "!~", "+", "-", "*", "/", "%", "^", ">", "<"}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(input); {
+		c := input[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '#':
+			for pos < len(input) && input[pos] != '\n' {
+				pos++
+			}
+		case c == '{':
+			tokens = append(tokens, token{typ: tokenLeftBrace, val: "{", pos: pos})
+			pos++
+		case c == '}':
+			tokens = append(tokens, token{typ: tokenRightBrace, val: "}", pos: pos})
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{typ: tokenLeftParen, val: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{typ: tokenRightParen, val: ")", pos: pos})
+			pos++
+		case c == '[':
+			tokens = append(tokens, token{typ: tokenLeftBracket, val: "[", pos: pos})
+			pos++
+		case c == ']':
+			tokens = append(tokens, token{typ: tokenRightBracket, val: "]", pos: pos})
+			pos++
+		case c == ',':
+			tokens = append(tokens, token{typ: tokenComma, val: ",", pos: pos})
+			pos++
+		case c == '"' || c == '\'' || c == '`':
+			end, val, err := lexString(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{typ: tokenString, val: val, pos: pos})
+			pos = end
+		case isDigit(c) || (c == '.' && pos+1 < len(input) && isDigit(input[pos+1])):
+			end, typ := lexNumberOrDuration(input, pos)
+			tokens = append(tokens, token{typ: typ, val: input[pos:end], pos: pos})
+			pos = end
+		case isIdentifierStart(c):
+			end := pos + 1
+			for end < len(input) && (isIdentifierStart(input[end]) || isDigit(input[end])) {
+				end++
+			}
+			tokens = append(tokens, token{typ: tokenIdentifier, val: input[pos:end], pos: pos})
+			pos = end
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(input[pos:], op) {
+					tokens = append(tokens, token{typ: tokenOperator, val: op, pos: pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				if c == '=' {
+					tokens = append(tokens, token{typ: tokenAssign, val: "=", pos: pos})
+					pos++
+					continue
+				}
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, pos)
+			}
+		}
+	}
+	return append(tokens, token{typ: tokenEOF, pos: len(input)}), nil
+}
+
+func lexString(input string, pos int) (int, string, error) {
+	quote := input[pos]
+	end := pos + 1
+	for end < len(input) && input[end] != quote {
+		if input[end] == '\\' && quote != '`' {
+			end++
+		}
+		end++
+	}
+	if end >= len(input) {
+		return 0, "", fmt.Errorf("unterminated string at position %d", pos)
+	}
+	raw := input[pos+1 : end]
+	if quote == '`' {
+		return end + 1, raw, nil
+	}
+	if quote == '\'' {
+		raw = strings.ReplaceAll(strings.ReplaceAll(raw, `\'`, `'`), `"`, `\"`)
+	}
+	val, err := strconv.Unquote(`"` + raw + `"`)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid string at position %d: %s", pos, err)
+	}
+	return end + 1, val, nil
+}
+
+func lexNumberOrDuration(input string, pos int) (int, tokenType) {
+	end := pos
+	for end < len(input) && (isDigit(input[end]) || input[end] == '.') {
+		end++
+	}
+	if end < len(input) && (input[end] == 'e' || input[end] == 'E') {
+		exp := end + 1
+		if exp < len(input) && (input[exp] == '+' || input[exp] == '-') {
+			exp++
+		}
+		if exp < len(input) && isDigit(input[exp]) {
+			end = exp
+			for end < len(input) && isDigit(input[end]) {
+				end++
+			}
+			return end, tokenNumber
+		}
+	}
+	if unit := durationUnitAt(input, end); unit != "" {
+		for {
+			end += len(unit)
+			next := end
+			for next < len(input) && isDigit(input[next]) {
+				next++
+			}
+			if next == end {
+				break
+			}
+			unit = durationUnitAt(input, next)
+			if unit == "" {
+				return end, tokenDuration
+			}
+			end = next
+		}
+		return end, tokenDuration
+	}
+	return end, tokenNumber
+}
+
+var durationUnits = []string{"ms", "s", "m", "h", "d", "w", "y"}
+
+func durationUnitAt(input string, pos int) string {
+	for _, unit := range durationUnits {
+		if strings.HasPrefix(input[pos:], unit) {
+			next := pos + len(unit)
+			if next < len(input) && isIdentifierStart(input[next]) {
+				continue
+			}
+			return unit
+		}
+	}
+	return ""
+}
+
+// ParseDuration parses a prometheus duration such as 5m or 1h30m
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	var total time.Duration
+	for pos := 0; pos < len(s); {
+		start := pos
+		for pos < len(s) && isDigit(s[pos]) {
+			pos++
+		}
+		if start == pos {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.ParseInt(s[start:pos], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		unit := durationUnitAt(s, pos)
+		if unit == "" {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		pos += len(unit)
+		var d time.Duration
+		switch unit {
+		case "ms":
+			d = time.Millisecond
+		case "s":
+			d = time.Second
+		case "m":
+			d = time.Minute
+		case "h":
+			d = time.Hour
+		case "d":
+			d = 24 * time.Hour
+		case "w":
+			d = 7 * 24 * time.Hour
+		case "y":
+			d = 365 * 24 * time.Hour
+		}
+		total += time.Duration(n) * d
+	}
+	return total, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,559 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var aggregators = map[string]bool{
+	"sum": true, "avg": true, "min": true, "max": true, "count": true, "group": true,
+	"stddev": true, "stdvar": true, "topk": true, "bottomk": true, "quantile": true, "count_values": true,
+}
+
+var binaryPrecedence = map[string]int{
+	"or":     1,
+	"and":    2,
+	"unless": 2,
+	"==":     3, "!=": 3, "<=": 3, "<": 3, ">=": 3, ">": 3,
+	"+": 4, "-": 4,
+	"*": 5, "/": 5, "%": 5,
+	"^": 6,
+}
+
+func isComparisonOperator(op string) bool {
+	return binaryPrecedence[op] == 3
+}
+
+func isSetOperator(op string) bool {
+	return op == "and" || op == "or" || op == "unless"
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseError is the error of an invalid query
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+// ParseExpr parses the promql expression
+func ParseExpr(input string) (Expr, error) {
+	expr, err := parseExpr(input)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+	return expr, nil
+}
+
+func parseExpr(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, p.unexpected(t, "end of input")
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(n int) token {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(typ tokenType, want string) (token, error) {
+	t := p.next()
+	if t.typ != typ {
+		return t, p.unexpected(t, want)
+	}
+	return t, nil
+}
+
+func (p *parser) unexpected(t token, want string) error {
+	return fmt.Errorf("parse error at position %d: unexpected %s, expected %s", t.pos, t, want)
+}
+
+// binaryOperator returns the binary operator of the next token, or empty
+func (p *parser) binaryOperator() string {
+	t := p.peek()
+	switch t.typ {
+	case tokenOperator:
+		return t.val
+	case tokenIdentifier:
+		if op := strings.ToLower(t.val); isSetOperator(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *parser) parseBinary(minPrec int) (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.binaryOperator()
+		prec, ok := binaryPrecedence[op]
+		if !ok || prec < minPrec {
+			return lhs, nil
+		}
+		p.next()
+		expr := &BinaryExpr{Op: op, LHS: lhs}
+		if err := p.parseBinaryModifiers(expr); err != nil {
+			return nil, err
+		}
+		// ^ is right associative, all the other operators are left associative
+		nextPrec := prec + 1
+		if op == "^" {
+			nextPrec = prec
+		}
+		if expr.RHS, err = p.parseBinary(nextPrec); err != nil {
+			return nil, err
+		}
+		if err := checkBinary(expr); err != nil {
+			return nil, err
+		}
+		lhs = expr
+	}
+}
+
+func (p *parser) parseBinaryModifiers(expr *BinaryExpr) error {
+	if t := p.peek(); t.typ == tokenIdentifier && strings.ToLower(t.val) == "bool" {
+		if !isComparisonOperator(expr.Op) {
+			return fmt.Errorf("bool modifier can only be used on comparison operators")
+		}
+		p.next()
+		expr.ReturnBool = true
+	}
+	t := p.peek()
+	if t.typ != tokenIdentifier {
+		return nil
+	}
+	switch strings.ToLower(t.val) {
+	case "on", "ignoring":
+		p.next()
+		labels, err := p.parseLabelList()
+		if err != nil {
+			return err
+		}
+		expr.Matching = &VectorMatching{Card: CardOneToOne, On: strings.ToLower(t.val) == "on", Labels: labels}
+		if isSetOperator(expr.Op) {
+			expr.Matching.Card = CardManyToMany
+		}
+	default:
+		return nil
+	}
+	t = p.peek()
+	if t.typ != tokenIdentifier {
+		return nil
+	}
+	switch strings.ToLower(t.val) {
+	case "group_left", "group_right":
+		if isSetOperator(expr.Op) {
+			return fmt.Errorf("no grouping allowed for %q operation", expr.Op)
+		}
+		p.next()
+		expr.Matching.Card = CardManyToOne
+		if strings.ToLower(t.val) == "group_right" {
+			expr.Matching.Card = CardOneToMany
+		}
+		if p.peek().typ == tokenLeftParen {
+			labels, err := p.parseLabelList()
+			if err != nil {
+				return err
+			}
+			expr.Matching.Include = labels
+		}
+		for _, l := range expr.Matching.Include {
+			if expr.Matching.On && containsString(expr.Matching.Labels, l) {
+				return fmt.Errorf("label %q must not occur in ON and GROUP clause at once", l)
+			}
+		}
+	}
+	return nil
+}
+
+func checkBinary(expr *BinaryExpr) error {
+	lt, rt := expr.LHS.Type(), expr.RHS.Type()
+	for _, t := range []ValueType{lt, rt} {
+		if t != ValueTypeScalar && t != ValueTypeVector {
+			return fmt.Errorf("binary expression must contain only scalar and instant vector types")
+		}
+	}
+	if isSetOperator(expr.Op) && (lt != ValueTypeVector || rt != ValueTypeVector) {
+		return fmt.Errorf("set operator %q not allowed in binary scalar expression", expr.Op)
+	}
+	if isComparisonOperator(expr.Op) && !expr.ReturnBool && lt == ValueTypeScalar && rt == ValueTypeScalar {
+		return fmt.Errorf("comparisons between scalars must use BOOL modifier")
+	}
+	if expr.Matching != nil && (lt != ValueTypeVector || rt != ValueTypeVector) {
+		return fmt.Errorf("vector matching only allowed between instant vectors")
+	}
+	if isSetOperator(expr.Op) && expr.Matching == nil {
+		expr.Matching = &VectorMatching{Card: CardManyToMany}
+	}
+	if expr.Matching == nil && lt == ValueTypeVector && rt == ValueTypeVector {
+		expr.Matching = &VectorMatching{Card: CardOneToOne}
+	}
+	return nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t := p.peek()
+	if t.typ == tokenOperator && (t.val == "-" || t.val == "+") {
+		p.next()
+		// unary operators bind weaker than ^, so -a^b is -(a^b)
+		expr, err := p.parseBinary(binaryPrecedence["^"])
+		if err != nil {
+			return nil, err
+		}
+		if expr.Type() != ValueTypeScalar && expr.Type() != ValueTypeVector {
+			return nil, fmt.Errorf("unary expression only allowed on expressions of type scalar or instant vector")
+		}
+		if t.val == "+" {
+			return expr, nil
+		}
+		if n, ok := expr.(*NumberLiteral); ok {
+			n.Val = -n.Val
+			return n, nil
+		}
+		return &UnaryExpr{Op: t.val, Expr: expr}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (Expr, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().typ == tokenLeftBracket {
+		vs, ok := expr.(*VectorSelector)
+		if !ok {
+			return nil, fmt.Errorf("ranges only allowed for vector selectors")
+		}
+		p.next()
+		t, err := p.expect(tokenDuration, "duration")
+		if err != nil {
+			return nil, err
+		}
+		d, err := ParseDuration(t.val)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().typ == tokenRightBracket {
+			p.next()
+		} else if p.peek().typ == tokenIdentifier || p.peek().val == ":" {
+			return nil, fmt.Errorf("subqueries are not supported")
+		} else {
+			return nil, p.unexpected(p.peek(), `"]"`)
+		}
+		expr = &MatrixSelector{VectorSelector: vs, Range: d}
+	}
+	if t := p.peek(); t.typ == tokenIdentifier && strings.ToLower(t.val) == "offset" {
+		p.next()
+		var vs *VectorSelector
+		switch e := expr.(type) {
+		case *VectorSelector:
+			vs = e
+		case *MatrixSelector:
+			vs = e.VectorSelector
+		default:
+			return nil, fmt.Errorf("offset modifier must be preceded by an instant or range selector")
+		}
+		t, err := p.expect(tokenDuration, "duration")
+		if err != nil {
+			return nil, err
+		}
+		if vs.Offset, err = ParseDuration(t.val); err != nil {
+			return nil, err
+		}
+	}
+	return expr, nil
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch t.typ {
+	case tokenNumber:
+		p.next()
+		v, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.val)
+		}
+		return &NumberLiteral{Val: v}, nil
+	case tokenString:
+		p.next()
+		return &StringLiteral{Val: t.val}, nil
+	case tokenLeftParen:
+		p.next()
+		expr, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRightParen, `")"`); err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Expr: expr}, nil
+	case tokenLeftBrace:
+		return p.parseVectorSelector("")
+	case tokenIdentifier:
+		name := t.val
+		lower := strings.ToLower(name)
+		next := p.peekAt(1)
+		if aggregators[lower] && (next.typ == tokenLeftParen || (next.typ == tokenIdentifier && isGroupingKeyword(next.val))) {
+			return p.parseAggregation()
+		}
+		if next.typ == tokenLeftParen {
+			return p.parseCall()
+		}
+		switch lower {
+		case "inf":
+			p.next()
+			return &NumberLiteral{Val: math.Inf(1)}, nil
+		case "nan":
+			p.next()
+			return &NumberLiteral{Val: math.NaN()}, nil
+		}
+		p.next()
+		return p.parseVectorSelector(name)
+	}
+	return nil, p.unexpected(t, "expression")
+}
+
+func isGroupingKeyword(s string) bool {
+	s = strings.ToLower(s)
+	return s == "by" || s == "without"
+}
+
+func (p *parser) parseVectorSelector(name string) (Expr, error) {
+	vs := &VectorSelector{Name: name}
+	if p.peek().typ == tokenLeftBrace {
+		p.next()
+		for p.peek().typ != tokenRightBrace {
+			label, err := p.expect(tokenIdentifier, "label name")
+			if err != nil {
+				return nil, err
+			}
+			op := p.next()
+			var typ MatchType
+			switch {
+			case op.typ == tokenAssign:
+				typ = MatchEqual
+			case op.typ == tokenOperator && op.val == "!=":
+				typ = MatchNotEqual
+			case op.typ == tokenOperator && op.val == "=~":
+				typ = MatchRegexp
+			case op.typ == tokenOperator && op.val == "!~":
+				typ = MatchNotRegexp
+			default:
+				return nil, p.unexpected(op, "label matching operator")
+			}
+			value, err := p.expect(tokenString, "label value")
+			if err != nil {
+				return nil, err
+			}
+			m, err := NewMatcher(typ, label.val, value.val)
+			if err != nil {
+				return nil, err
+			}
+			if m.Name == MetricNameLabel && name != "" {
+				return nil, fmt.Errorf("metric name must not be set twice: %q", name)
+			}
+			vs.Matchers = append(vs.Matchers, m)
+			if p.peek().typ == tokenComma {
+				p.next()
+			} else if p.peek().typ != tokenRightBrace {
+				return nil, p.unexpected(p.peek(), `"," or "}"`)
+			}
+		}
+		p.next()
+	}
+	if name != "" {
+		m, _ := NewMatcher(MatchEqual, MetricNameLabel, name)
+		vs.Matchers = append(vs.Matchers, m)
+	}
+	notEmpty := false
+	for _, m := range vs.Matchers {
+		if !m.Matches("") {
+			notEmpty = true
+			break
+		}
+	}
+	if !notEmpty {
+		return nil, fmt.Errorf("vector selector must contain at least one non-empty matcher")
+	}
+	for _, m := range vs.Matchers {
+		if m.Name == MetricNameLabel && m.Type == MatchEqual {
+			vs.Name = m.Value
+		}
+	}
+	return vs, nil
+}
+
+func (p *parser) parseLabelList() ([]string, error) {
+	if _, err := p.expect(tokenLeftParen, `"("`); err != nil {
+		return nil, err
+	}
+	labels := []string{}
+	for p.peek().typ != tokenRightParen {
+		t, err := p.expect(tokenIdentifier, "label name")
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, t.val)
+		if p.peek().typ == tokenComma {
+			p.next()
+		} else if p.peek().typ != tokenRightParen {
+			return nil, p.unexpected(p.peek(), `"," or ")"`)
+		}
+	}
+	p.next()
+	return labels, nil
+}
+
+func (p *parser) parseGrouping(agg *AggregateExpr) error {
+	t := p.next()
+	agg.Without = strings.ToLower(t.val) == "without"
+	labels, err := p.parseLabelList()
+	if err != nil {
+		return err
+	}
+	agg.Grouping = labels
+	return nil
+}
+
+func (p *parser) parseAggregation() (Expr, error) {
+	agg := &AggregateExpr{Op: strings.ToLower(p.next().val)}
+	grouped := false
+	if t := p.peek(); t.typ == tokenIdentifier && isGroupingKeyword(t.val) {
+		if err := p.parseGrouping(agg); err != nil {
+			return nil, err
+		}
+		grouped = true
+	}
+	args, err := p.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); !grouped && t.typ == tokenIdentifier && isGroupingKeyword(t.val) {
+		if err := p.parseGrouping(agg); err != nil {
+			return nil, err
+		}
+	}
+	switch agg.Op {
+	case "topk", "bottomk", "quantile", "count_values":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("wrong number of arguments for aggregate expression provided, expected 2, got %d", len(args))
+		}
+		agg.Param, agg.Expr = args[0], args[1]
+		want := ValueTypeScalar
+		if agg.Op == "count_values" {
+			want = ValueTypeString
+		}
+		if agg.Param.Type() != want {
+			return nil, fmt.Errorf("expected type %s in aggregation parameter, got %s", want, agg.Param.Type())
+		}
+	default:
+		if len(args) != 1 {
+			return nil, fmt.Errorf("wrong number of arguments for aggregate expression provided, expected 1, got %d", len(args))
+		}
+		agg.Expr = args[0]
+	}
+	if agg.Expr.Type() != ValueTypeVector {
+		return nil, fmt.Errorf("expected type %s in aggregation expression, got %s", ValueTypeVector, agg.Expr.Type())
+	}
+	return agg, nil
+}
+
+func (p *parser) parseCall() (Expr, error) {
+	t := p.next()
+	fn, ok := functions[t.val]
+	if !ok {
+		return nil, fmt.Errorf("unknown function with name %q", t.val)
+	}
+	args, err := p.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+	minArgs, maxArgs := len(fn.ArgTypes)-fn.Optional, len(fn.ArgTypes)
+	if fn.Variadic {
+		maxArgs = math.MaxInt32
+	}
+	if len(args) < minArgs || len(args) > maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments for function %s(), got %d", fn.Name, len(args))
+	}
+	for i, arg := range args {
+		want := fn.ArgTypes[len(fn.ArgTypes)-1]
+		if i < len(fn.ArgTypes) {
+			want = fn.ArgTypes[i]
+		}
+		if arg.Type() != want {
+			return nil, fmt.Errorf("expected type %s in call to function %s(), got %s", want, fn.Name, arg.Type())
+		}
+	}
+	return &Call{Func: fn, Args: args}, nil
+}
+
+func (p *parser) parseArgs() ([]Expr, error) {
+	if _, err := p.expect(tokenLeftParen, `"("`); err != nil {
+		return nil, err
+	}
+	var args []Expr
+	for p.peek().typ != tokenRightParen {
+		arg, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek().typ == tokenComma {
+			p.next()
+		} else if p.peek().typ != tokenRightParen {
+			return nil, p.unexpected(p.peek(), `"," or ")"`)
+		}
+	}
+	p.next()
+	return args, nil
+}
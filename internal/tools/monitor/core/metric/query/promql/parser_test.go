@@ -0,0 +1,143 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		input   string
+		typ     ValueType
+		wantErr bool
+	}{
+		{input: `1 + 2 * 3`, typ: ValueTypeScalar},
+		{input: `http_requests_total`, typ: ValueTypeVector},
+		{input: `http_requests_total{job="api", code=~"5..", method!="GET",}`, typ: ValueTypeVector},
+		{input: `{__name__="up"}`, typ: ValueTypeVector},
+		{input: `up[5m]`, typ: ValueTypeMatrix},
+		{input: `up[1h30m] offset 1d`, typ: ValueTypeMatrix},
+		{input: `sum by (job) (rate(http_requests_total[5m]))`, typ: ValueTypeVector},
+		{input: `sum(rate(http_requests_total[5m])) without (instance)`, typ: ValueTypeVector},
+		{input: `topk(3, up)`, typ: ValueTypeVector},
+		{input: `count_values("version", build_info)`, typ: ValueTypeVector},
+		{input: `histogram_quantile(0.9, sum by (le) (rate(request_duration_bucket[5m])))`, typ: ValueTypeVector},
+		{input: `a / on(instance) group_left(job) b`, typ: ValueTypeVector},
+		{input: `a > bool 1`, typ: ValueTypeVector},
+		{input: `a and ignoring(code) b or c unless d`, typ: ValueTypeVector},
+		{input: `scalar(up) > bool 1`, typ: ValueTypeScalar},
+		{input: `time()`, typ: ValueTypeScalar},
+		{input: `label_join(up, "dst", "-", "a", "b", "c")`, typ: ValueTypeVector},
+		{input: `-up ^ 2`, typ: ValueTypeVector},
+		{input: `{job=""}`, wantErr: true},
+		{input: `1 > 2`, wantErr: true},
+		{input: `rate(up)`, wantErr: true},
+		{input: `unknown_func(up)`, wantErr: true},
+		{input: `sum(up[5m])`, wantErr: true},
+		{input: `up[5m:1m]`, wantErr: true},
+		{input: `1 and 2`, wantErr: true},
+		{input: `up{job="a"`, wantErr: true},
+		{input: `up{job=~"("}`, wantErr: true},
+		{input: `(up)[5m]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expr, err := ParseExpr(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExpr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && expr.Type() != tt.typ {
+				t.Errorf("ParseExpr() type = %s, want %s", expr.Type(), tt.typ)
+			}
+		})
+	}
+}
+
+func TestParseExprPrecedence(t *testing.T) {
+	expr, err := ParseExpr(`1 + 2 * 3 ^ 2 ^ 0.5 - 4`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ((1 + (2 * (3 ^ (2 ^ 0.5)))) - 4)
+	sub, ok := expr.(*BinaryExpr)
+	if !ok || sub.Op != "-" {
+		t.Fatalf("unexpected root %#v", expr)
+	}
+	add := sub.LHS.(*BinaryExpr)
+	if add.Op != "+" {
+		t.Fatalf("unexpected op %q", add.Op)
+	}
+	pow := add.RHS.(*BinaryExpr).RHS.(*BinaryExpr)
+	if pow.Op != "^" || pow.RHS.(*BinaryExpr).Op != "^" {
+		t.Fatalf("^ must be right associative")
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	expr, err := ParseExpr(`http_requests_total{job="api"}[5m] offset 1h`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := expr.(*MatrixSelector)
+	if ms.Name != "http_requests_total" || ms.Range != 5*time.Minute || ms.Offset != time.Hour {
+		t.Fatalf("unexpected selector %+v", ms.VectorSelector)
+	}
+	if len(ms.Matchers) != 2 || !ms.Matchers[0].Matches("api") || ms.Matchers[1].Name != MetricNameLabel {
+		t.Fatalf("unexpected matchers %+v", ms.Matchers)
+	}
+
+	agg := mustParse(t, `sum without (instance) (up)`).(*AggregateExpr)
+	if !agg.Without || len(agg.Grouping) != 1 || agg.Grouping[0] != "instance" {
+		t.Fatalf("unexpected aggregation %+v", agg)
+	}
+
+	bin := mustParse(t, `a * on(instance) group_right(job) b`).(*BinaryExpr)
+	if bin.Matching.Card != CardOneToMany || !bin.Matching.On || bin.Matching.Include[0] != "job" {
+		t.Fatalf("unexpected matching %+v", bin.Matching)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30s":   30 * time.Second,
+		"5m":    5 * time.Minute,
+		"1h30m": 90 * time.Minute,
+		"500ms": 500 * time.Millisecond,
+		"2d":    48 * time.Hour,
+		"1w":    7 * 24 * time.Hour,
+		"1y":    365 * 24 * time.Hour,
+	}
+	for input, want := range tests {
+		got, err := ParseDuration(input)
+		if err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "5", "m", "5x"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q) want error", input)
+		}
+	}
+}
+
+func mustParse(t *testing.T, input string) Expr {
+	expr, err := ParseExpr(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expr
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetricNameLabel is the label name of the metric name
+const MetricNameLabel = "__name__"
+
+// Label is a name/value pair
+type Label struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Labels is a label set sorted by name
+type Labels []Label
+
+// NewLabels returns the sorted label set of the map, empty values are dropped
+func NewLabels(m map[string]string) Labels {
+	ls := make(Labels, 0, len(m))
+	for k, v := range m {
+		if v == "" {
+			continue
+		}
+		ls = append(ls, Label{Name: k, Value: v})
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i].Name < ls[j].Name })
+	return ls
+}
+
+// Get returns the value of the label name, or empty if not exist
+func (ls Labels) Get(name string) string {
+	for _, l := range ls {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// Map returns the label set as a map
+func (ls Labels) Map() map[string]string {
+	m := make(map[string]string, len(ls))
+	for _, l := range ls {
+		m[l.Name] = l.Value
+	}
+	return m
+}
+
+// String returns the label set in the form of {a="b", c="d"}
+func (ls Labels) String() string {
+	sb := &strings.Builder{}
+	sb.WriteByte('{')
+	for i, l := range ls {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(l.Name)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(l.Value))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// with returns a copy of the label set with the label set to value, empty value removes the label
+func (ls Labels) with(name, value string) Labels {
+	m := ls.Map()
+	m[name] = value
+	return NewLabels(m)
+}
+
+// without returns a copy of the label set without the names
+func (ls Labels) without(names ...string) Labels {
+	result := make(Labels, 0, len(ls))
+	for _, l := range ls {
+		if !containsString(names, l.Name) {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// only returns a copy of the label set with the names only
+func (ls Labels) only(names ...string) Labels {
+	result := make(Labels, 0, len(names))
+	for _, l := range ls {
+		if containsString(names, l.Name) {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ValueType is the type of the evaluation result
+type ValueType string
+
+// value types
+const (
+	ValueTypeScalar ValueType = "scalar"
+	ValueTypeVector ValueType = "vector"
+	ValueTypeMatrix ValueType = "matrix"
+	ValueTypeString ValueType = "string"
+)
+
+// Value is the result of an expression
+type Value interface {
+	Type() ValueType
+}
+
+// Point is a sample value at timestamp in milliseconds
+type Point struct {
+	T int64
+	V float64
+}
+
+// Series is a label set with points ordered by timestamp
+type Series struct {
+	Metric Labels
+	Points []Point
+}
+
+// Sample is a single point of a series
+type Sample struct {
+	Metric Labels
+	Point
+}
+
+// Scalar is a float value
+type Scalar struct {
+	T int64
+	V float64
+}
+
+// String is a string value
+type String struct {
+	T int64
+	V string
+}
+
+// Vector is a set of samples at the same timestamp
+type Vector []Sample
+
+// Matrix is a set of series
+type Matrix []*Series
+
+// Type .
+func (Scalar) Type() ValueType { return ValueTypeScalar }
+
+// Type .
+func (String) Type() ValueType { return ValueTypeString }
+
+// Type .
+func (Vector) Type() ValueType { return ValueTypeVector }
+
+// Type .
+func (Matrix) Type() ValueType { return ValueTypeMatrix }
+
+// FormatFloat formats the value as the prometheus http api does
+func FormatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
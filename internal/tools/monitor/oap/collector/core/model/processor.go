@@ -15,6 +15,8 @@
 package model
 
 import (
+	"time"
+
 	"github.com/erda-project/erda/internal/apps/msp/apm/trace"
 	"github.com/erda-project/erda/internal/tools/monitor/core/log"
 	"github.com/erda-project/erda/internal/tools/monitor/core/metric"
@@ -41,6 +43,14 @@ type Processor interface {
 	ProcessProfile(item *profile.ProfileIngest) (*profile.Output, error)
 }
 
+// BufferedProcessor holds data for a while before passing it on, e.g. the tail-based sampling of spans.
+// The held data is returned as nil by the Process methods, and the pipeline calls Release periodically
+// to get the data ready to go on with the processors after it. Release returns all the held data when flush is true.
+type BufferedProcessor interface {
+	Processor
+	Release(now time.Time, flush bool) []odata2.ObservableData
+}
+
 type NoopProcessor struct {
 }
 
@@ -47,6 +47,9 @@ type Pipeline struct {
 	waitExporters, waitProcessors sync.WaitGroup
 }
 
+// releaseInterval is the interval the pipeline collects the data released by buffered processors
+const releaseInterval = time.Second
+
 var (
 	dataReceived                *prometheus.CounterVec
 	dataProcessed, dataExported *prometheus.CounterVec
@@ -209,81 +212,126 @@ func (p *Pipeline) StartExporters(out <-chan odata2.ObservableData) {
 func (p *Pipeline) startProcessors(in <-chan odata2.ObservableData, out chan<- odata2.ObservableData) {
 	p.waitProcessors.Add(1)
 	defer p.waitProcessors.Done()
-loop:
-	for data := range in {
-		// TODO. Parallelism
-		for _, pr := range p.processors {
-			if !pr.Filter.Selected(data) {
+
+	var release <-chan time.Time
+	if p.hasBufferedProcessor() {
+		ticker := time.NewTicker(releaseInterval)
+		defer ticker.Stop()
+		release = ticker.C
+	}
+	for {
+		select {
+		case data, ok := <-in:
+			if !ok {
+				p.releaseProcessors(time.Now(), true, out)
+				return
+			}
+			if data = p.process(data, 0); data != nil {
+				out <- data
+			}
+		case now := <-release:
+			p.releaseProcessors(now, false, out)
+		}
+	}
+}
+
+// process runs the data through the processors starting from the index from,
+// it returns nil if the data is dropped or held by a processor.
+func (p *Pipeline) process(data odata2.ObservableData, from int) odata2.ObservableData {
+	for _, pr := range p.processors[from:] {
+		if !pr.Filter.Selected(data) {
+			continue
+		}
+		dataProcessed.WithLabelValues(p.name, string(p.dtype), pr.Name, data.GetTags()["org_name"]).Inc()
+		switch p.dtype {
+		case odata2.MetricType:
+			tmp, err := pr.Processor.ProcessMetric(data.(*metric.Metric))
+			if err != nil {
+				p.Log.Errorf("Processor<%s> process data error: %s", pr.Name, err)
+				continue
+			}
+			if tmp == nil {
+				return nil
+			}
+			data = tmp
+		case odata2.LogType:
+			tmp, err := pr.Processor.ProcessLog(data.(*log.Log))
+			if err != nil {
+				p.Log.Errorf("Processor<%s> process data error: %s", pr.Name, err)
 				continue
 			}
-			dataProcessed.WithLabelValues(p.name, string(p.dtype), pr.Name, data.GetTags()["org_name"]).Inc()
-			switch p.dtype {
-			case odata2.MetricType:
-				tmp, err := pr.Processor.ProcessMetric(data.(*metric.Metric))
-				if err != nil {
-					p.Log.Errorf("Processor<%s> process data error: %s", pr.Name, err)
-					continue
-				}
-				if tmp == nil {
-					goto loop
-				}
-				data = tmp
-			case odata2.LogType:
-				tmp, err := pr.Processor.ProcessLog(data.(*log.Log))
-				if err != nil {
-					p.Log.Errorf("Processor<%s> process data error: %s", pr.Name, err)
-					continue
-				}
-				if tmp == nil {
-					goto loop
-				}
-				data = tmp
-			case odata2.SpanType:
-				tmp, err := pr.Processor.ProcessSpan(data.(*trace.Span))
-				if err != nil {
-					p.Log.Errorf("Processor<%s> process data error: %s", pr.Name, err)
-					continue
-				}
-				if tmp == nil {
-					goto loop
-				}
-				data = tmp
-			case odata2.RawType:
-				tmp, err := pr.Processor.ProcessRaw(data.(*odata2.Raw))
-				if err != nil {
-					p.Log.Errorf("Processor<%s> process data error: %s", pr.Name, err)
-					continue
-				}
-				if tmp == nil {
-					goto loop
-				}
-				data = tmp
-			case odata2.ProfileType:
-				tmp, err := pr.Processor.ProcessProfile(data.(*profile.ProfileIngest))
-				if err != nil {
-					p.Log.Errorf("Processor<%s> process profile data error: %s", pr.Name, err)
-					continue
-				}
-				if tmp == nil {
-					goto loop
-				}
-				data = tmp
-			case odata2.ExternalMetricType:
-				tmp, err := pr.Processor.ProcessMetric(data.(*metric.Metric))
-				if err != nil {
-					p.Log.Errorf("Processor<%s> process external metric error: %s", pr.Name, err)
-					continue
-				}
-				if tmp == nil {
-					goto loop
-				}
-				data = tmp
-			default:
+			if tmp == nil {
+				return nil
+			}
+			data = tmp
+		case odata2.SpanType:
+			tmp, err := pr.Processor.ProcessSpan(data.(*trace.Span))
+			if err != nil {
+				p.Log.Errorf("Processor<%s> process data error: %s", pr.Name, err)
+				continue
+			}
+			if tmp == nil {
+				return nil
+			}
+			data = tmp
+		case odata2.RawType:
+			tmp, err := pr.Processor.ProcessRaw(data.(*odata2.Raw))
+			if err != nil {
+				p.Log.Errorf("Processor<%s> process data error: %s", pr.Name, err)
 				continue
 			}
+			if tmp == nil {
+				return nil
+			}
+			data = tmp
+		case odata2.ProfileType:
+			tmp, err := pr.Processor.ProcessProfile(data.(*profile.ProfileIngest))
+			if err != nil {
+				p.Log.Errorf("Processor<%s> process profile data error: %s", pr.Name, err)
+				continue
+			}
+			if tmp == nil {
+				return nil
+			}
+			data = tmp
+		case odata2.ExternalMetricType:
+			tmp, err := pr.Processor.ProcessMetric(data.(*metric.Metric))
+			if err != nil {
+				p.Log.Errorf("Processor<%s> process external metric error: %s", pr.Name, err)
+				continue
+			}
+			if tmp == nil {
+				return nil
+			}
+			data = tmp
+		default:
+			continue
 		}
+	}
+	return data
+}
 
-		out <- data
+func (p *Pipeline) hasBufferedProcessor() bool {
+	for _, pr := range p.processors {
+		if _, ok := pr.Processor.(model.BufferedProcessor); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseProcessors passes the data released by buffered processors on to the processors after them.
+func (p *Pipeline) releaseProcessors(now time.Time, flush bool, out chan<- odata2.ObservableData) {
+	for i, pr := range p.processors {
+		bp, ok := pr.Processor.(model.BufferedProcessor)
+		if !ok {
+			continue
+		}
+		for _, data := range bp.Release(now, flush) {
+			if data = p.process(data, i+1); data != nil {
+				out <- data
+			}
+		}
 	}
 }
 
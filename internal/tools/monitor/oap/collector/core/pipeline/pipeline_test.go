@@ -79,6 +79,60 @@ func Test_startProcessors(t *testing.T) {
 	}
 }
 
+func Test_startProcessors_buffered(t *testing.T) {
+	buffered := &mockBufferedProcessor{}
+	next := &mockCountProcessor{}
+	p := &Pipeline{
+		processors: []*model.RuntimeProcessor{
+			{Name: "buffered", Processor: buffered, Filter: &model.DataFilter{}},
+			{Name: "next", Processor: next, Filter: &model.DataFilter{}},
+		},
+		dtype: odata.MetricType,
+	}
+	dataIn := make(chan odata.ObservableData, 10)
+	dataOut := make(chan odata.ObservableData, 10)
+	dataIn <- &metric.Metric{Name: "metric1"}
+	dataIn <- &metric.Metric{Name: "metric2"}
+	close(dataIn)
+
+	p.startProcessors(dataIn, dataOut)
+	if len(dataOut) != 2 {
+		t.Fatalf("released data count: want 2, got %d", len(dataOut))
+	}
+	if next.count != 2 {
+		t.Errorf("the released data should go on with the next processor, got %d", next.count)
+	}
+}
+
+type mockBufferedProcessor struct {
+	mockProfileProcessor
+	held []odata.ObservableData
+}
+
+func (m *mockBufferedProcessor) ProcessMetric(item *metric.Metric) (*metric.Metric, error) {
+	m.held = append(m.held, item)
+	return nil, nil
+}
+
+func (m *mockBufferedProcessor) Release(now time.Time, flush bool) []odata.ObservableData {
+	if !flush {
+		return nil
+	}
+	held := m.held
+	m.held = nil
+	return held
+}
+
+type mockCountProcessor struct {
+	mockProfileProcessor
+	count int
+}
+
+func (m *mockCountProcessor) ProcessMetric(item *metric.Metric) (*metric.Metric, error) {
+	m.count++
+	return item, nil
+}
+
 type mockProfileProcessor struct{}
 
 func (m *mockProfileProcessor) ComponentConfig() interface{} { return nil }
@@ -30,6 +30,7 @@ import (
 	_ "github.com/erda-project/erda/internal/tools/monitor/oap/collector/plugins/processors/modifier"
 	_ "github.com/erda-project/erda/internal/tools/monitor/oap/collector/plugins/processors/profile"
 	_ "github.com/erda-project/erda/internal/tools/monitor/oap/collector/plugins/processors/stdout"
+	_ "github.com/erda-project/erda/internal/tools/monitor/oap/collector/plugins/processors/tail-sampler"
 
 	// exporters
 	_ "github.com/erda-project/erda/internal/tools/monitor/oap/collector/plugins/exporters/clickhouse"
@@ -0,0 +1,62 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	tracesSampled, spansSampled, earlyDecisions *prometheus.CounterVec
+	bufferedTraces, bufferedSpans               *prometheus.GaugeVec
+)
+
+func init() {
+	tracesSampled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "data_pipeline",
+		Subsystem: "tail_sampler",
+		Name:      "traces",
+		Help:      "trace count decided by the tail sampler",
+	}, []string{"processor", "decision", "policy"})
+
+	spansSampled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "data_pipeline",
+		Subsystem: "tail_sampler",
+		Name:      "spans",
+		Help:      "span count kept or dropped by the tail sampler",
+	}, []string{"processor", "decision"})
+
+	earlyDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "data_pipeline",
+		Subsystem: "tail_sampler",
+		Name:      "early_decisions",
+		Help:      "trace count decided before the decision wait is over due to the memory bounds",
+	}, []string{"processor", "cause"})
+
+	bufferedTraces = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "data_pipeline",
+		Subsystem: "tail_sampler",
+		Name:      "buffered_traces",
+		Help:      "the current buffered traces of the tail sampler",
+	}, []string{"processor"})
+
+	bufferedSpans = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "data_pipeline",
+		Subsystem: "tail_sampler",
+		Name:      "buffered_spans",
+		Help:      "the current buffered spans of the tail sampler",
+	}, []string{"processor"})
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda/internal/apps/msp/apm/trace"
+	"github.com/erda-project/erda/internal/tools/monitor/core/log"
+	"github.com/erda-project/erda/internal/tools/monitor/core/metric"
+	"github.com/erda-project/erda/internal/tools/monitor/core/profile"
+	"github.com/erda-project/erda/internal/tools/monitor/oap/collector/core/model"
+	"github.com/erda-project/erda/internal/tools/monitor/oap/collector/core/model/odata"
+	"github.com/erda-project/erda/internal/tools/monitor/oap/collector/lib/filter"
+	"github.com/erda-project/erda/internal/tools/monitor/oap/collector/plugins"
+)
+
+var providerName = plugins.WithPrefixProcessor("tail-sampler")
+
+type config struct {
+	Keypass map[string][]string `file:"keypass"`
+	Keydrop map[string][]string `file:"keydrop"`
+
+	DecisionWait     time.Duration `file:"decision_wait" default:"10s" desc:"how long the spans of a trace are buffered since its first span before the decision"`
+	DecisionTTL      time.Duration `file:"decision_ttl" default:"1m" desc:"how long the late spans of a decided trace follow the decision"`
+	MaxTraces        int           `file:"max_traces" default:"50000" desc:"the max buffered traces, the oldest one is decided early when exceeded"`
+	MaxSpans         int           `file:"max_spans" default:"1000000" desc:"the max buffered spans, the oldest traces are decided early when exceeded"`
+	MaxSpansPerTrace int           `file:"max_spans_per_trace" default:"2000" desc:"the trace is decided early when it has so many spans"`
+	MaxDecisions     int           `file:"max_decisions" default:"200000" desc:"the max remembered decisions for late spans"`
+
+	// policies, a trace is kept if any of them matches
+	KeepErrors         bool                `file:"keep_errors" default:"true" desc:"keep the traces with any error span"`
+	LatencyThreshold   time.Duration       `file:"latency_threshold" desc:"keep the traces lasting longer than it, zero to disable"`
+	Attributes         map[string][]string `file:"attributes" desc:"keep the traces with any span whose tag matches the patterns"`
+	SampleRate         float64             `file:"sample_rate" default:"1" desc:"the rate of the other traces to keep"`
+	ServiceSampleRates map[string]float64  `file:"service_sample_rates" desc:"the sample rate by the service of the root span"`
+}
+
+var (
+	_ model.Processor         = (*provider)(nil)
+	_ model.BufferedProcessor = (*provider)(nil)
+)
+
+// +provider
+type provider struct {
+	Cfg *config
+	Log logs.Logger
+
+	sampler *sampler
+}
+
+func (p *provider) ComponentClose() error {
+	return nil
+}
+
+func (p *provider) ComponentConfig() interface{} {
+	return p.Cfg
+}
+
+func (p *provider) ProcessMetric(item *metric.Metric) (*metric.Metric, error) { return item, nil }
+func (p *provider) ProcessLog(item *log.Log) (*log.Log, error)                { return item, nil }
+func (p *provider) ProcessRaw(item *odata.Raw) (*odata.Raw, error)            { return item, nil }
+func (p *provider) ProcessProfile(item *profile.ProfileIngest) (*profile.Output, error) {
+	return &profile.Output{}, nil
+}
+
+func (p *provider) ProcessSpan(item *trace.Span) (*trace.Span, error) {
+	return p.sampler.add(item, time.Now()), nil
+}
+
+func (p *provider) Release(now time.Time, flush bool) []odata.ObservableData {
+	spans := p.sampler.release(now, flush)
+	if len(spans) == 0 {
+		return nil
+	}
+	res := make([]odata.ObservableData, len(spans))
+	for i, span := range spans {
+		res[i] = span
+	}
+	return res
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	pl, err := newPolicy(p.Cfg)
+	if err != nil {
+		return err
+	}
+	if p.Cfg.DecisionWait <= 0 {
+		return fmt.Errorf("decision_wait must be positive")
+	}
+	if p.Cfg.MaxTraces <= 0 || p.Cfg.MaxSpans <= 0 || p.Cfg.MaxSpansPerTrace <= 0 || p.Cfg.MaxDecisions <= 0 {
+		return fmt.Errorf("max_traces, max_spans, max_spans_per_trace and max_decisions must be positive")
+	}
+	p.sampler = newSampler(ctx.Key(), p.Cfg, pl)
+	return nil
+}
+
+func newPolicy(cfg *config) (*policy, error) {
+	pl := &policy{
+		keepErrors:   cfg.KeepErrors,
+		latency:      cfg.LatencyThreshold,
+		attributes:   make(map[string]filter.Filter),
+		rate:         cfg.SampleRate,
+		serviceRates: cfg.ServiceSampleRates,
+	}
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return nil, fmt.Errorf("sample_rate must be in [0, 1]")
+	}
+	for service, rate := range cfg.ServiceSampleRates {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("sample rate of service<%s> must be in [0, 1]", service)
+		}
+	}
+	for key, patterns := range cfg.Attributes {
+		f, err := filter.Compile(patterns)
+		if err != nil {
+			return nil, fmt.Errorf("attributes<%s>: %w", key, err)
+		}
+		if f != nil {
+			pl.attributes[key] = f
+		}
+	}
+	return pl, nil
+}
+
+func init() {
+	servicehub.Register(providerName, &servicehub.Spec{
+		Services: []string{
+			providerName,
+		},
+		Description: "tail-based sampling of traces, keeps the traces with errors, high latency, matched attributes or by sample rate",
+		ConfigFunc: func() interface{} {
+			return &config{}
+		},
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
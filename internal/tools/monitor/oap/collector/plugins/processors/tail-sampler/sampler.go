@@ -0,0 +1,268 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampler
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/erda-project/erda/internal/apps/msp/apm/trace"
+	"github.com/erda-project/erda/internal/tools/monitor/oap/collector/lib/filter"
+)
+
+const (
+	policyError         = "error"
+	policyLatency       = "latency"
+	policyAttribute     = "attribute"
+	policyProbabilistic = "probabilistic"
+
+	causeMaxTraces        = "max_traces"
+	causeMaxSpans         = "max_spans"
+	causeMaxSpansPerTrace = "max_spans_per_trace"
+
+	tagError       = "error"
+	tagServiceName = "service_name"
+)
+
+// policy decides whether to keep a trace by all its spans
+type policy struct {
+	keepErrors   bool
+	latency      time.Duration
+	attributes   map[string]filter.Filter
+	rate         float64
+	serviceRates map[string]float64
+}
+
+// decide returns the name of the policy which keeps the trace, or empty if the trace is dropped
+func (p *policy) decide(traceID string, spans []*trace.Span) string {
+	var (
+		start, end int64
+		service    string
+	)
+	for i, span := range spans {
+		if p.keepErrors && span.Tags[tagError] == "true" {
+			return policyError
+		}
+		for key, f := range p.attributes {
+			if val, ok := span.Tags[key]; ok && f.Match(val) {
+				return policyAttribute
+			}
+		}
+		if i == 0 || span.StartTime < start {
+			start = span.StartTime
+		}
+		if i == 0 || span.EndTime > end {
+			end = span.EndTime
+		}
+		if service == "" || span.ParentSpanId == "" {
+			if name := span.Tags[tagServiceName]; name != "" {
+				service = name
+			}
+		}
+	}
+	if p.latency > 0 && time.Duration(end-start) > p.latency {
+		return policyLatency
+	}
+	rate, ok := p.serviceRates[service]
+	if !ok {
+		rate = p.rate
+	}
+	if sampledByRate(traceID, rate) {
+		return policyProbabilistic
+	}
+	return ""
+}
+
+// sampledByRate hashes the trace id, so that the collectors receiving different spans of a trace make the same decision
+func sampledByRate(traceID string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(traceID))
+	return float64(h.Sum64()%10000) < rate*10000
+}
+
+type traceBuffer struct {
+	id       string
+	spans    []*trace.Span
+	deadline time.Time
+	decided  bool
+}
+
+type decision struct {
+	keep   bool
+	expire time.Time
+}
+
+type decisionEntry struct {
+	id     string
+	expire time.Time
+}
+
+// sampler buffers the spans of a trace for the decision wait since its first span, then keeps or drops them together.
+// The spans arriving after the decision follow it within the decision ttl.
+type sampler struct {
+	name             string
+	policy           *policy
+	wait             time.Duration
+	decisionTTL      time.Duration
+	maxTraces        int
+	maxSpans         int
+	maxSpansPerTrace int
+	maxDecisions     int
+
+	mu        sync.Mutex
+	traces    map[string]*traceBuffer
+	queue     []*traceBuffer
+	spans     int
+	ready     []*trace.Span
+	decisions map[string]decision
+	expiring  []decisionEntry
+}
+
+func newSampler(name string, cfg *config, p *policy) *sampler {
+	return &sampler{
+		name:             name,
+		policy:           p,
+		wait:             cfg.DecisionWait,
+		decisionTTL:      cfg.DecisionTTL,
+		maxTraces:        cfg.MaxTraces,
+		maxSpans:         cfg.MaxSpans,
+		maxSpansPerTrace: cfg.MaxSpansPerTrace,
+		maxDecisions:     cfg.MaxDecisions,
+		traces:           make(map[string]*traceBuffer),
+		decisions:        make(map[string]decision),
+	}
+}
+
+// add returns the span if it goes on at once, or nil if it is held or dropped
+func (s *sampler) add(span *trace.Span, now time.Time) *trace.Span {
+	if span.TraceId == "" {
+		return span
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, ok := s.decisions[span.TraceId]; ok && now.Before(d.expire) {
+		spansSampled.WithLabelValues(s.name, decisionLabel(d.keep)).Inc()
+		if d.keep {
+			return span
+		}
+		return nil
+	}
+	t, ok := s.traces[span.TraceId]
+	if !ok {
+		for len(s.traces) >= s.maxTraces && s.evictOldest(now, causeMaxTraces) {
+		}
+		t = &traceBuffer{id: span.TraceId, deadline: now.Add(s.wait)}
+		s.traces[t.id] = t
+		s.queue = append(s.queue, t)
+	}
+	t.spans = append(t.spans, span)
+	s.spans++
+	if len(t.spans) >= s.maxSpansPerTrace {
+		s.decide(t, now, causeMaxSpansPerTrace)
+	}
+	for s.spans > s.maxSpans && s.evictOldest(now, causeMaxSpans) {
+	}
+	return nil
+}
+
+// release returns the spans of the kept traces, deciding the traces whose decision wait is over, or all the traces if flush
+func (s *sampler) release(now time.Time, flush bool) []*trace.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.queue) > 0 {
+		t := s.queue[0]
+		if !t.decided {
+			if !flush && now.Before(t.deadline) {
+				break
+			}
+			s.decide(t, now, "")
+		}
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+	}
+	for len(s.expiring) > 0 && !now.Before(s.expiring[0].expire) {
+		s.forget(s.expiring[0])
+		s.expiring = s.expiring[1:]
+	}
+	bufferedTraces.WithLabelValues(s.name).Set(float64(len(s.traces)))
+	bufferedSpans.WithLabelValues(s.name).Set(float64(s.spans))
+
+	ready := s.ready
+	s.ready = nil
+	return ready
+}
+
+// evictOldest decides the oldest trace before its decision wait is over, to bound the memory
+func (s *sampler) evictOldest(now time.Time, cause string) bool {
+	for len(s.queue) > 0 {
+		t := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		if !t.decided {
+			s.decide(t, now, cause)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *sampler) decide(t *traceBuffer, now time.Time, cause string) {
+	t.decided = true
+	delete(s.traces, t.id)
+	s.spans -= len(t.spans)
+
+	by := s.policy.decide(t.id, t.spans)
+	keep := by != ""
+	if keep {
+		s.ready = append(s.ready, t.spans...)
+	}
+	tracesSampled.WithLabelValues(s.name, decisionLabel(keep), by).Inc()
+	spansSampled.WithLabelValues(s.name, decisionLabel(keep)).Add(float64(len(t.spans)))
+	if cause != "" {
+		earlyDecisions.WithLabelValues(s.name, cause).Inc()
+	}
+	t.spans = nil
+
+	for len(s.expiring) >= s.maxDecisions && len(s.expiring) > 0 {
+		s.forget(s.expiring[0])
+		s.expiring = s.expiring[1:]
+	}
+	expire := now.Add(s.decisionTTL)
+	s.decisions[t.id] = decision{keep: keep, expire: expire}
+	s.expiring = append(s.expiring, decisionEntry{id: t.id, expire: expire})
+}
+
+func (s *sampler) forget(e decisionEntry) {
+	// the trace may be decided again after its decision expired
+	if d, ok := s.decisions[e.id]; ok && d.expire.Equal(e.expire) {
+		delete(s.decisions, e.id)
+	}
+}
+
+func decisionLabel(keep bool) string {
+	if keep {
+		return "kept"
+	}
+	return "dropped"
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/internal/apps/msp/apm/trace"
+)
+
+func testConfig() *config {
+	return &config{
+		DecisionWait:     10 * time.Second,
+		DecisionTTL:      time.Minute,
+		MaxTraces:        100,
+		MaxSpans:         1000,
+		MaxSpansPerTrace: 100,
+		MaxDecisions:     100,
+		KeepErrors:       true,
+		LatencyThreshold: time.Second,
+		Attributes:       map[string][]string{"http_path": {"/api/orders*"}},
+		SampleRate:       0,
+	}
+}
+
+func newTestSampler(t *testing.T, cfg *config) *sampler {
+	pl, err := newPolicy(cfg)
+	assert.NoError(t, err)
+	return newSampler("test", cfg, pl)
+}
+
+func span(traceID, spanID, parentID string, start, end int64, tags map[string]string) *trace.Span {
+	return &trace.Span{
+		TraceId:      traceID,
+		SpanId:       spanID,
+		ParentSpanId: parentID,
+		StartTime:    start,
+		EndTime:      end,
+		Tags:         tags,
+	}
+}
+
+func Test_policy_decide(t *testing.T) {
+	pl, err := newPolicy(&config{
+		KeepErrors:         true,
+		LatencyThreshold:   time.Second,
+		Attributes:         map[string][]string{"http_path": {"/api/orders*"}},
+		SampleRate:         0,
+		ServiceSampleRates: map[string]float64{"gateway": 1},
+	})
+	assert.NoError(t, err)
+	ms := int64(time.Millisecond)
+	tests := []struct {
+		name  string
+		spans []*trace.Span
+		want  string
+	}{
+		{
+			name: "error",
+			spans: []*trace.Span{
+				span("t", "1", "", 0, 10*ms, nil),
+				span("t", "2", "1", 0, 5*ms, map[string]string{"error": "true"}),
+			},
+			want: policyError,
+		},
+		{
+			name: "latency across spans",
+			spans: []*trace.Span{
+				span("t", "1", "", 0, 600*ms, nil),
+				span("t", "2", "1", 500*ms, 1200*ms, nil),
+			},
+			want: policyLatency,
+		},
+		{
+			name: "attribute",
+			spans: []*trace.Span{
+				span("t", "1", "", 0, 10*ms, map[string]string{"http_path": "/api/orders/1"}),
+			},
+			want: policyAttribute,
+		},
+		{
+			name: "service rate of the root span",
+			spans: []*trace.Span{
+				span("t", "2", "1", 0, 10*ms, map[string]string{"service_name": "order"}),
+				span("t", "1", "", 0, 10*ms, map[string]string{"service_name": "gateway"}),
+			},
+			want: policyProbabilistic,
+		},
+		{
+			name: "dropped",
+			spans: []*trace.Span{
+				span("t", "1", "", 0, 10*ms, map[string]string{"service_name": "order", "error": "false"}),
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pl.decide("t", tt.spans))
+		})
+	}
+}
+
+func Test_sampledByRate(t *testing.T) {
+	assert.True(t, sampledByRate("a", 1))
+	assert.False(t, sampledByRate("a", 0))
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+		if sampledByRate(id, 0.1) {
+			kept++
+		}
+		assert.Equal(t, sampledByRate(id, 0.1), sampledByRate(id, 0.1))
+	}
+	assert.InDelta(t, 1000, kept, 200)
+}
+
+func Test_sampler_release(t *testing.T) {
+	s := newTestSampler(t, testConfig())
+	now := time.Now()
+
+	assert.Nil(t, s.add(span("kept", "1", "", 0, 1, map[string]string{"error": "true"}), now))
+	assert.Nil(t, s.add(span("kept", "2", "1", 0, 1, nil), now))
+	assert.Nil(t, s.add(span("dropped", "1", "", 0, 1, nil), now.Add(time.Second)))
+
+	assert.Empty(t, s.release(now.Add(5*time.Second), false))
+
+	released := s.release(now.Add(10*time.Second), false)
+	assert.Len(t, released, 2)
+	assert.Len(t, s.traces, 1)
+
+	// the late span follows the decision
+	late := span("kept", "3", "1", 0, 1, nil)
+	assert.Equal(t, late, s.add(late, now.Add(12*time.Second)))
+
+	assert.Empty(t, s.release(now.Add(11*time.Second), false))
+	assert.Len(t, s.traces, 0)
+	assert.Nil(t, s.add(span("dropped", "2", "1", 0, 1, map[string]string{"error": "true"}), now.Add(12*time.Second)))
+	assert.Len(t, s.traces, 0)
+
+	// the decisions expire
+	s.release(now.Add(2*time.Minute), false)
+	assert.Empty(t, s.decisions)
+	assert.Empty(t, s.expiring)
+
+	// spans without trace id go on
+	noTrace := span("", "1", "", 0, 1, nil)
+	assert.Equal(t, noTrace, s.add(noTrace, now))
+}
+
+func Test_sampler_bounds(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxTraces = 2
+	cfg.MaxSpans = 5
+	cfg.MaxSpansPerTrace = 3
+	cfg.MaxDecisions = 2
+	s := newTestSampler(t, cfg)
+	now := time.Now()
+	errTag := map[string]string{"error": "true"}
+
+	// decided early by max spans per trace
+	s.add(span("a", "1", "", 0, 1, errTag), now)
+	s.add(span("a", "2", "1", 0, 1, nil), now)
+	s.add(span("a", "3", "1", 0, 1, nil), now)
+	assert.Len(t, s.traces, 0)
+	assert.Equal(t, 0, s.spans)
+
+	// the oldest trace is decided early by max traces
+	s.add(span("b", "1", "", 0, 1, errTag), now)
+	s.add(span("c", "1", "", 0, 1, nil), now)
+	s.add(span("d", "1", "", 0, 1, nil), now)
+	assert.Len(t, s.traces, 2)
+	assert.Contains(t, s.decisions, "b")
+
+	// the oldest traces are decided early by max spans
+	s.add(span("c", "2", "1", 0, 1, nil), now)
+	s.add(span("d", "2", "1", 0, 1, nil), now)
+	s.add(span("e", "1", "", 0, 1, nil), now)
+	assert.LessOrEqual(t, s.spans, cfg.MaxSpans)
+	assert.LessOrEqual(t, len(s.decisions), cfg.MaxDecisions)
+
+	released := s.release(now, true)
+	assert.Len(t, released, 4)
+	assert.Len(t, s.traces, 0)
+	assert.Equal(t, 0, s.spans)
+	assert.LessOrEqual(t, len(s.decisions), cfg.MaxDecisions)
+}
+
+func Test_newPolicy(t *testing.T) {
+	_, err := newPolicy(&config{SampleRate: 2})
+	assert.Error(t, err)
+	_, err = newPolicy(&config{SampleRate: 0.5, ServiceSampleRates: map[string]float64{"a": -1}})
+	assert.Error(t, err)
+	pl, err := newPolicy(&config{SampleRate: 0.5, Attributes: map[string][]string{"a": nil, "b": {"x"}}})
+	assert.NoError(t, err)
+	assert.Len(t, pl.attributes, 1)
+}
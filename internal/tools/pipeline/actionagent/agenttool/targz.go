@@ -0,0 +1,201 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agenttool
+
+import (
+	archivetar "archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TarGzFiles packs files into a gzip compressed tar, directories are packed recursively.
+// Entries are named by their paths relative to baseDir, so they can be extracted into another directory.
+func TarGzFiles(tarFile, baseDir string, files []string) (err error) {
+	f, err := os.Create(tarFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(tarFile)
+		}
+	}()
+	gw := gzip.NewWriter(f)
+	tw := archivetar.NewWriter(gw)
+	added := make(map[string]struct{})
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(baseDir, file)
+		}
+		if err := filepath.WalkDir(file, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name := tarEntryName(baseDir, path)
+			if _, ok := added[name]; ok {
+				return nil
+			}
+			added[name] = struct{}{}
+			return addTarEntry(tw, path, name)
+		}); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func tarEntryName(baseDir, path string) string {
+	if rel, err := filepath.Rel(baseDir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(rel)
+	}
+	// outside baseDir, keep the absolute path without the leading slash
+	return strings.TrimPrefix(filepath.ToSlash(path), "/")
+}
+
+func addTarEntry(tw *archivetar.Writer, path, name string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	var link string
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+	header, err := archivetar.FileInfoHeader(fi, link)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if fi.IsDir() {
+		header.Name += "/"
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// UnTarGz extracts a gzip compressed tar into destDir, entries escaping destDir are rejected.
+// Symlinks must point inside destDir, and nothing is written through a symlink already extracted.
+func UnTarGz(r io.Reader, destDir string) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+	destDir = filepath.Clean(destDir)
+	tr := archivetar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destDir, header.Name)
+		if !isWithinDir(destDir, target) {
+			return fmt.Errorf("illegal file path in tar: %s", header.Name)
+		}
+		if err := checkNoSymlinkInPath(destDir, target); err != nil {
+			return fmt.Errorf("illegal file path in tar: %s: %v", header.Name, err)
+		}
+		switch header.Typeflag {
+		case archivetar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case archivetar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				return fmt.Errorf("illegal file path in tar: %s: write through symlink", header.Name)
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			_ = f.Close()
+			if err != nil {
+				return err
+			}
+		case archivetar.TypeSymlink:
+			if filepath.IsAbs(header.Linkname) || !isWithinDir(destDir, filepath.Join(filepath.Dir(target), header.Linkname)) {
+				return fmt.Errorf("illegal symlink in tar: %s -> %s", header.Name, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func isWithinDir(dir, target string) bool {
+	return target == dir || strings.HasPrefix(target, dir+string(filepath.Separator))
+}
+
+// checkNoSymlinkInPath makes sure no parent of target under dir is a symlink.
+func checkNoSymlinkInPath(dir, target string) error {
+	rel, err := filepath.Rel(dir, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+	current := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		fi, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("parent %s is a symlink", current)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agenttool
+
+import (
+	archivetar "archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarGzFiles(t *testing.T) {
+	base := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(base, "target", "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(base, "target", "app.jar"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(base, "target", "lib", "a.jar"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(base, "report.xml"), []byte("report"), 0644))
+
+	tarFile := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	err := TarGzFiles(tarFile, base, []string{"target", "report.xml", filepath.Join(base, "target", "app.jar")})
+	require.NoError(t, err)
+
+	f, err := os.Open(tarFile)
+	require.NoError(t, err)
+	defer f.Close()
+	dest := t.TempDir()
+	require.NoError(t, UnTarGz(f, dest))
+
+	for file, content := range map[string]string{
+		"target/app.jar":   "app",
+		"target/lib/a.jar": "a",
+		"report.xml":       "report",
+	} {
+		b, err := os.ReadFile(filepath.Join(dest, file))
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+}
+
+func TestUnTarGzIllegalPath(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := archivetar.NewWriter(gw)
+	require.NoError(t, tw.WriteHeader(&archivetar.Header{Name: "../evil", Typeflag: archivetar.TypeReg, Mode: 0644, Size: 1}))
+	_, err := tw.Write([]byte("x"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	assert.Error(t, UnTarGz(&buf, t.TempDir()))
+}
+
+func TestUnTarGzSymlink(t *testing.T) {
+	type entry struct {
+		name, link, content string
+	}
+	build := func(entries ...entry) *bytes.Buffer {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		tw := archivetar.NewWriter(gw)
+		for _, e := range entries {
+			if e.link != "" {
+				require.NoError(t, tw.WriteHeader(&archivetar.Header{Name: e.name, Typeflag: archivetar.TypeSymlink, Linkname: e.link}))
+				continue
+			}
+			require.NoError(t, tw.WriteHeader(&archivetar.Header{Name: e.name, Typeflag: archivetar.TypeReg, Mode: 0644, Size: int64(len(e.content))}))
+			_, err := tw.Write([]byte(e.content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		require.NoError(t, gw.Close())
+		return &buf
+	}
+
+	outside := t.TempDir()
+	// symlink escaping destDir, then a file written through it
+	dest := t.TempDir()
+	assert.Error(t, UnTarGz(build(entry{name: "link", link: outside}, entry{name: "link/evil", content: "x"}), dest))
+	assert.Error(t, UnTarGz(build(entry{name: "link", link: "../../"}), dest))
+	_, err := os.Stat(filepath.Join(outside, "evil"))
+	assert.True(t, os.IsNotExist(err))
+
+	// symlink inside destDir is kept, but nothing is written through it
+	dest = t.TempDir()
+	assert.Error(t, UnTarGz(build(entry{name: "a/b", content: "b"}, entry{name: "lib", link: "a"}, entry{name: "lib/c", content: "c"}), dest))
+	assert.Error(t, UnTarGz(build(entry{name: "a/b", content: "b"}, entry{name: "b", link: "a/b"}, entry{name: "b", content: "x"}), dest))
+
+	dest = t.TempDir()
+	require.NoError(t, UnTarGz(build(entry{name: "a/b", content: "b"}, entry{name: "lib", link: "a"}), dest))
+	b, err := os.ReadFile(filepath.Join(dest, "lib", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(b))
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actionagent
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/internal/tools/pipeline/actionagent/agenttool"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+	"github.com/erda-project/erda/pkg/retry"
+)
+
+const (
+	logArtifactPrefix = "[artifacts] "
+)
+
+// uploadArtifacts packs files of declared artifacts and uploads them to the pipeline platform.
+func (agent *Agent) uploadArtifacts() {
+	if agent.Arg == nil || len(agent.Arg.Artifacts) == 0 {
+		return
+	}
+	success := agent.ExitCode == 0 && len(agent.Errs) == 0
+	for _, artifact := range agent.Arg.Artifacts {
+		if !artifact.ShouldUpload(success) {
+			logrus.Printf(logArtifactPrefix+"skip artifact %s, when: %s\n", artifact.Name, artifact.When)
+			continue
+		}
+		files, err := agent.matchArtifactFiles(artifact)
+		if err != nil {
+			agent.AppendError(errors.Wrapf(err, "failed to match files of artifact %s", artifact.Name))
+			continue
+		}
+		if len(files) == 0 {
+			logrus.Printf(logArtifactPrefix+"no files found for artifact %s, paths: %v\n", artifact.Name, artifact.Paths)
+			continue
+		}
+		if err := agent.uploadArtifact(artifact, files); err != nil {
+			agent.AppendError(errors.Wrapf(err, "failed to upload artifact %s", artifact.Name))
+			continue
+		}
+		logrus.Printf(logArtifactPrefix+"upload artifact %s success, files: %d\n", artifact.Name, len(files))
+	}
+}
+
+// matchArtifactFiles returns files matched by glob patterns of artifact paths.
+func (agent *Agent) matchArtifactFiles(artifact pipelineyml.ActionArtifact) ([]string, error) {
+	var files []string
+	for _, pattern := range artifact.Paths {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(agent.EasyUse.ContainerWd, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func (agent *Agent) uploadArtifact(artifact pipelineyml.ActionArtifact, files []string) error {
+	tarFile := filepath.Join(agent.EasyUse.ContainerTempTarUploadDir, artifact.Name+".tar.gz")
+	if err := agenttool.TarGzFiles(tarFile, agent.EasyUse.ContainerWd, files); err != nil {
+		return err
+	}
+	defer os.Remove(tarFile)
+	return retry.DoWithInterval(func() error {
+		f, err := os.Open(tarFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return agent.CallbackReporter.UploadArtifact(agent.Arg.PipelineID, agent.Arg.PipelineTaskID, artifact, f)
+	}, 5, time.Second*5)
+}
+
+// downloadArtifacts downloads artifacts needed by the action and extracts them before logic.
+func (agent *Agent) downloadArtifacts() {
+	for _, download := range agent.Arg.DownloadArtifacts {
+		pipelineID := download.PipelineID
+		if pipelineID == 0 {
+			pipelineID = agent.Arg.PipelineID
+		}
+		destDir := download.Path
+		if destDir == "" {
+			destDir = agent.EasyUse.ContainerWd
+		} else if !filepath.IsAbs(destDir) {
+			destDir = filepath.Join(agent.EasyUse.ContainerWd, destDir)
+		}
+		err := retry.DoWithInterval(func() error {
+			r, err := agent.CallbackReporter.DownloadArtifact(pipelineID, download.Name)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			return agenttool.UnTarGz(r, destDir)
+		}, 3, time.Second*5)
+		if err != nil {
+			agent.AppendError(errors.Wrapf(err, "failed to download artifact %s of pipeline %d", download.Name, pipelineID))
+			continue
+		}
+		logrus.Printf(logArtifactPrefix+"download artifact %s of pipeline %d into %s success\n", download.Name, pipelineID, destDir)
+	}
+}
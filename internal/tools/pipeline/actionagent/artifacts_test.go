@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actionagent
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+)
+
+type fakeArtifactReporter struct {
+	CenterCallbackReporter
+	uploaded map[string][]byte
+}
+
+func (r *fakeArtifactReporter) UploadArtifact(pipelineID, taskID uint64, artifact pipelineyml.ActionArtifact, file *os.File) error {
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	r.uploaded[artifact.Name] = b
+	return nil
+}
+
+func (r *fakeArtifactReporter) DownloadArtifact(pipelineID uint64, name string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(r.uploaded[name])), nil
+}
+
+func TestAgent_UploadAndDownloadArtifacts(t *testing.T) {
+	wd := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(wd, "target"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(wd, "target", "app.jar"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(wd, "target", "app.txt"), []byte("txt"), 0644))
+
+	reporter := &fakeArtifactReporter{uploaded: map[string][]byte{}}
+	agent := &Agent{
+		Arg: &AgentArg{
+			PipelineID:     1,
+			PipelineTaskID: 2,
+			Artifacts: []pipelineyml.ActionArtifact{
+				{Name: "jar", Paths: []string{"target/*.jar"}},
+				{Name: "report", Paths: []string{"report.xml"}, When: pipelineyml.ArtifactWhenOnFailure},
+			},
+		},
+		EasyUse: EasyUse{
+			ContainerWd:               wd,
+			ContainerTempTarUploadDir: t.TempDir(),
+		},
+		CallbackReporter: reporter,
+	}
+	agent.uploadArtifacts()
+	assert.Empty(t, agent.Errs)
+	assert.Contains(t, reporter.uploaded, "jar")
+	assert.NotContains(t, reporter.uploaded, "report")
+
+	dest := t.TempDir()
+	agent.Arg.DownloadArtifacts = []pipelineyml.ActionArtifactDownload{{Name: "jar", Path: dest}}
+	agent.downloadArtifacts()
+	assert.Empty(t, agent.Errs)
+	b, err := os.ReadFile(filepath.Join(dest, "target", "app.jar"))
+	assert.NoError(t, err)
+	assert.Equal(t, "app", string(b))
+	_, err = os.Stat(filepath.Join(dest, "target", "app.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
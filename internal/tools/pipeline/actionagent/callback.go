@@ -43,6 +43,8 @@ const (
 func (agent *Agent) Callback() {
 	cb := &Callback{}
 	defer func() {
+		exitCode := agent.ExitCode
+		cb.ExitCode = &exitCode
 		cb.Errors = append(cb.Errors, agent.MergeErrors()...)
 		if err := agent.callbackToPipelinePlatform(cb); err != nil {
 			for _, err := range cb.Errors {
@@ -158,7 +160,7 @@ func (agent *Agent) callbackToPipelinePlatform(cb *Callback) (err error) {
 	}
 
 	// 如果全部为空，则不需要回调
-	if len(cb.Metadata) == 0 && len(cb.Errors) == 0 && cb.MachineStat == nil {
+	if len(cb.Metadata) == 0 && len(cb.Errors) == 0 && cb.MachineStat == nil && cb.ExitCode == nil {
 		return nil
 	}
 
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -30,6 +31,7 @@ import (
 	"github.com/erda-project/erda/pkg/filehelper"
 	"github.com/erda-project/erda/pkg/http/httpclient"
 	"github.com/erda-project/erda/pkg/http/httputil"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
 )
 
 var (
@@ -45,6 +47,8 @@ type CallbackReporter interface {
 	SetOpenApiToken(token string)
 	SetCollectorAddress(address string)
 	PushCollectorLog(logLines *[]apistructs.LogPushLine) error
+	UploadArtifact(pipelineID, taskID uint64, artifact pipelineyml.ActionArtifact, file *os.File) error
+	DownloadArtifact(pipelineID uint64, name string) (io.ReadCloser, error)
 }
 
 type CenterCallbackReporter struct {
@@ -167,6 +171,54 @@ func (cr *CenterCallbackReporter) GetCmsFile(uuid string, absPath string) error
 	return nil
 }
 
+func (cr *CenterCallbackReporter) UploadArtifact(pipelineID, taskID uint64, artifact pipelineyml.ActionArtifact, file *os.File) error {
+	return uploadArtifact(httpclient.New(httpclient.WithCompleteRedirect(), httpclient.WithTimeout(cr.FileStreamTimeoutSec, cr.FileStreamTimeoutSec)).
+		Post(cr.OpenAPIAddr).
+		Header("Authorization", cr.TokenForBootstrap), pipelineID, taskID, artifact, file)
+}
+
+func (cr *CenterCallbackReporter) DownloadArtifact(pipelineID uint64, name string) (io.ReadCloser, error) {
+	return downloadArtifact(httpclient.New(httpclient.WithCompleteRedirect(), httpclient.WithTimeout(cr.FileStreamTimeoutSec, cr.FileStreamTimeoutSec)).
+		Get(cr.OpenAPIAddr).
+		Header("Authorization", cr.TokenForBootstrap), pipelineID, name)
+}
+
+func uploadArtifact(req *httpclient.Request, pipelineID, taskID uint64, artifact pipelineyml.ActionArtifact, file *os.File) error {
+	var uploadResp apistructs.Header
+	resp, err := req.
+		Path(fmt.Sprintf("/api/pipelines/%d/tasks/%d/artifacts", pipelineID, taskID)).
+		Param("name", artifact.Name).
+		MultipartFormDataBody(map[string]httpclient.MultipartItem{
+			"file": {Reader: file},
+		}).
+		Do().
+		JSON(&uploadResp)
+	if err != nil {
+		return err
+	}
+	if !resp.IsOK() || !uploadResp.Success {
+		return fmt.Errorf("statusCode: %d, respError: %s", resp.StatusCode(), uploadResp.Error.Msg)
+	}
+	return nil
+}
+
+func downloadArtifact(req *httpclient.Request, pipelineID uint64, name string) (io.ReadCloser, error) {
+	respBody, resp, err := req.
+		Path("/api/pipeline-artifacts/actions/download").
+		Param("pipelineID", strconv.FormatUint(pipelineID, 10)).
+		Param("name", name).
+		Do().StreamBody()
+	if err != nil {
+		return nil, errors.Errorf("failed to download artifact, pipelineID: %d, name: %s, err: %v", pipelineID, name, err)
+	}
+	if !resp.IsOK() {
+		bodyBytes, _ := io.ReadAll(respBody)
+		_ = respBody.Close()
+		return nil, errors.Errorf("failed to download artifact, pipelineID: %d, name: %s, err: %s", pipelineID, name, string(bodyBytes))
+	}
+	return respBody, nil
+}
+
 type EdgeCallbackReporter struct {
 	PipelineAddr         string
 	OpenAPIToken         string
@@ -229,6 +281,20 @@ func (er *EdgeCallbackReporter) GetCmsFile(uuid string, absPath string) error {
 	return fmt.Errorf("edge pipeline doesn't support get cms file")
 }
 
+func (er *EdgeCallbackReporter) UploadArtifact(pipelineID, taskID uint64, artifact pipelineyml.ActionArtifact, file *os.File) error {
+	return uploadArtifact(httpclient.New(httpclient.WithCompleteRedirect(), httpclient.WithTimeout(er.FileStreamTimeoutSec, er.FileStreamTimeoutSec)).
+		Post(er.PipelineAddr).
+		Header(httputil.InternalHeader, "edge-pipeline").
+		Header("Authorization", er.TokenForBootstrap), pipelineID, taskID, artifact, file)
+}
+
+func (er *EdgeCallbackReporter) DownloadArtifact(pipelineID uint64, name string) (io.ReadCloser, error) {
+	return downloadArtifact(httpclient.New(httpclient.WithCompleteRedirect(), httpclient.WithTimeout(er.FileStreamTimeoutSec, er.FileStreamTimeoutSec)).
+		Get(er.PipelineAddr).
+		Header(httputil.InternalHeader, "edge-pipeline").
+		Header("Authorization", er.TokenForBootstrap), pipelineID, name)
+}
+
 func (er *EdgeCallbackReporter) SetOpenApiToken(token string) {
 	er.OpenAPIToken = token
 }
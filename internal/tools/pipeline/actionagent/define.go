@@ -27,6 +27,7 @@ import (
 	"github.com/erda-project/erda/internal/tools/pipeline/pkg/taskerror"
 	"github.com/erda-project/erda/internal/tools/pipeline/pkg/taskinspect"
 	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
 )
 
 const (
@@ -86,6 +87,10 @@ type AgentArg struct {
 	// breakpoint config
 	DebugOnFailure bool           `json:"debugOnFailure"`
 	DebugTimeout   *time.Duration `json:"debugTimeout"`
+
+	// artifacts uploaded after logic, and downloaded before logic
+	Artifacts         []pipelineyml.ActionArtifact         `json:"artifacts,omitempty"`
+	DownloadArtifacts []pipelineyml.ActionArtifactDownload `json:"downloadArtifacts,omitempty"`
 }
 
 type EasyUse struct {
@@ -77,6 +77,10 @@ func (agent *Agent) Execute(r io.Reader) {
 	defer func() {
 		agent.store()
 	}()
+	agent.downloadArtifacts()
+	if len(agent.Errs) > 0 {
+		return
+	}
 
 	// 4. logic
 	agent.logic()
@@ -19,4 +19,7 @@ func (agent *Agent) PreStop() {
 
 	// 打包目录并上传
 	agent.uploadDir()
+
+	// 上传声明的 artifacts
+	agent.uploadArtifacts()
 }
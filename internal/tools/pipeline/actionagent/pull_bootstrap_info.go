@@ -68,6 +68,8 @@ func (agent *Agent) pullBootstrapInfo() {
 	agent.Arg.EncryptSecretKeys = bootstrapArg.EncryptSecretKeys
 	agent.Arg.DebugOnFailure = bootstrapArg.DebugOnFailure
 	agent.Arg.DebugTimeout = bootstrapArg.DebugTimeout
+	agent.Arg.Artifacts = bootstrapArg.Artifacts
+	agent.Arg.DownloadArtifacts = bootstrapArg.DownloadArtifacts
 
 	valueLen, err := strconv.Atoi(os.Getenv(EncryptedValueMinLen))
 	if err != nil || valueLen < 6 {
@@ -38,6 +38,7 @@ type Conf struct {
 	TaskDefaultTimeout         time.Duration `env:"TASK_DEFAULT_TIMEOUT" default:"1h"`
 	TaskRunWaitInterval        time.Duration `env:"TASK_RUN_WAIT_INTERVAL" default:"5s"`
 	TaskQueueAlertTime         time.Duration `env:"TASK_QUEUE_ALERT_TIME" default:"10m"`
+	TaskDefaultExecutorKind    string        `env:"TASK_DEFAULT_EXECUTOR_KIND" default:"K8SJOB"`
 
 	// agent
 	AgentAccessibleCacheTTL int64  `env:"AGENT_ACCESSIBLE_CACHE_TTL" default:"43200"` // 默认 12 小时
@@ -173,6 +174,12 @@ func TaskDefaultTimeout() time.Duration {
 	return cfg.TaskDefaultTimeout
 }
 
+// TaskDefaultExecutorKind return the executor kind used by tasks which don't specify one,
+// set it to MEMORY to run tasks as local processes where there is no kubernetes.
+func TaskDefaultExecutorKind() string {
+	return cfg.TaskDefaultExecutorKind
+}
+
 // TaskRunWaitInterval 返回 task run 在 wait 过程中的时间间隔.
 // 时间越短越精确，但对 scheduler 压力会变大，建议使用默认值 5s.
 func TaskRunWaitInterval() time.Duration {
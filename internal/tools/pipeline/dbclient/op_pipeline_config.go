@@ -17,6 +17,7 @@ package dbclient
 import (
 	"github.com/mitchellh/mapstructure"
 
+	"github.com/erda-project/erda/internal/tools/pipeline/conf"
 	"github.com/erda-project/erda/internal/tools/pipeline/spec"
 )
 
@@ -75,13 +76,33 @@ var defaultDockerActionExecutor = spec.PipelineConfig{
 	},
 }
 
+// defaultMemoryActionExecutor runs actions as local processes of the pipeline server,
+// so it is only registered when TASK_DEFAULT_EXECUTOR_KIND is MEMORY.
+var defaultMemoryActionExecutor = spec.PipelineConfig{
+	Type: spec.PipelineConfigTypeActionExecutor,
+	Value: spec.ActionExecutorConfig{
+		Kind:    string(spec.PipelineTaskExecutorKindMemory),
+		Name:    spec.PipelineTaskExecutorNameMemoryDefault.String(),
+		Options: nil,
+	},
+}
+
+// defaultActionExecutors return default api-test wait k8sjob k8sflink k8sspark action executor,
+// and memory action executor if it is explicitly enabled.
+func defaultActionExecutors() []spec.PipelineConfig {
+	executors := []spec.PipelineConfig{defaultAPITestActionExecutor, defaultWaitActionExecutor,
+		defaultK8sJobActionExecutor, defaultK8sFlinkActionExecutor, defaultK8sSparkActionExecutor}
+	if conf.TaskDefaultExecutorKind() == spec.PipelineTaskExecutorKindMemory.String() {
+		executors = append(executors, defaultMemoryActionExecutor)
+	}
+	return executors
+}
+
 func (client *Client) ListPipelineConfigsOfActionExecutor() (configs []spec.PipelineConfig, cfgChan chan spec.ActionExecutorConfig, err error) {
 	if err := client.Find(&configs, spec.PipelineConfig{Type: spec.PipelineConfigTypeActionExecutor}); err != nil {
 		return nil, nil, err
 	}
-	// add default api-test wait k8sjob k8sflink k8sspark action executor
-	configs = append(configs, defaultAPITestActionExecutor, defaultWaitActionExecutor,
-		defaultK8sJobActionExecutor, defaultK8sFlinkActionExecutor, defaultK8sSparkActionExecutor)
+	configs = append(configs, defaultActionExecutors()...)
 	cfgChan = make(chan spec.ActionExecutorConfig, 100)
 	for _, c := range configs {
 		var r spec.ActionExecutorConfig
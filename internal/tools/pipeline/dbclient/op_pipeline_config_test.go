@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbclient
+
+import (
+	"testing"
+
+	"bou.ke/monkey"
+
+	"github.com/erda-project/erda/internal/tools/pipeline/conf"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+func Test_defaultActionExecutors(t *testing.T) {
+	hasMemory := func(executors []spec.PipelineConfig) bool {
+		for _, e := range executors {
+			if e.Value.(spec.ActionExecutorConfig).Kind == spec.PipelineTaskExecutorKindMemory.String() {
+				return true
+			}
+		}
+		return false
+	}
+
+	kind := spec.PipelineTaskExecutorKindK8sJob.String()
+	monkey.Patch(conf.TaskDefaultExecutorKind, func() string { return kind })
+	defer monkey.UnpatchAll()
+
+	if hasMemory(defaultActionExecutors()) {
+		t.Fatalf("memory executor should not be registered when default executor kind is %s", kind)
+	}
+	kind = spec.PipelineTaskExecutorKindMemory.String()
+	if !hasMemory(defaultActionExecutors()) {
+		t.Fatalf("memory executor should be registered when default executor kind is %s", kind)
+	}
+}
@@ -247,6 +247,29 @@ func (client *Client) DeletePipelineLabelsByPipelineID(pipelineID uint64, ops ..
 	}, 3, time.Second)
 }
 
+// ListPipelinesInConcurrencyGroup returns pipelines in the concurrency group with given statuses, order by id asc.
+// Pipelines are filtered by the group label in database, so only pipelines of the group are loaded.
+func (client *Client) ListPipelinesInConcurrencyGroup(source apistructs.PipelineSource, group string, statuses []apistructs.PipelineStatus, ops ...SessionOption) (_ []spec.PipelineBase, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "failed to list pipelines in concurrency group: %s", group)
+		}
+	}()
+
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var bases []spec.PipelineBase
+	if err := session.In("status", statuses).Where("pipeline_source = ? AND is_snippet = ?", source, false).
+		And(fmt.Sprintf("id IN (SELECT `target_id` FROM `%s` WHERE `type` = ? AND `pipeline_source` = ? AND `key` = ? AND `value` = ?)",
+			spec.PipelineLabel{}.TableName()),
+			apistructs.PipelineLabelTypeInstance, source, apistructs.LabelPipelineConcurrencyGroup, group).
+		Asc("id").Find(&bases); err != nil {
+		return nil, err
+	}
+	return bases, nil
+}
+
 // merge 合并 inputs 里的 id
 func merge(inputs ...[]uint64) []uint64 {
 	m := make(map[uint64]struct{})
@@ -41,6 +41,13 @@ const (
 	queueLabelKeyConcurrency      string = "__queue_concurrency"
 	queueLabelKeyMaxCPU           string = "__queue_max_cpu"
 	queueLabelKeyMaxMemoryMB      string = "__queue_max_memory_MB"
+
+	// fair share fields, weights are stored one label per tenant because label value is limited to 191 characters
+	queueLabelKeyFairShareTenantBy         string = "__queue_fair_share_tenant_by"
+	queueLabelKeyFairShareDefaultWeight    string = "__queue_fair_share_default_weight"
+	queueLabelKeyFairShareDisableBorrowing string = "__queue_fair_share_disable_borrowing"
+	queueLabelKeyFairShareStarvationSec    string = "__queue_fair_share_starvation_sec"
+	queueLabelKeyPrefixFairShareWeight     string = "__queue_fair_share_weight_"
 )
 
 // CreatePipelineQueue
@@ -102,6 +109,18 @@ func (client *Client) createPipelineQueueFields(req *pb.QueueCreateRequest, queu
 		maxCPULabel,
 		maxMemoryMBLabel,
 	}
+	if fs := req.FairShare; fs != nil {
+		source := apistructs.PipelineSource(req.PipelineSource)
+		queueMetaLabels = append(queueMetaLabels,
+			genMetaLabelFunc(queueID, source, queueLabelKeyFairShareTenantBy, fs.TenantBy),
+			genMetaLabelFunc(queueID, source, queueLabelKeyFairShareDefaultWeight, strutil.String(fs.DefaultWeight)),
+			genMetaLabelFunc(queueID, source, queueLabelKeyFairShareDisableBorrowing, strconv.FormatBool(fs.DisableBorrowing)),
+			genMetaLabelFunc(queueID, source, queueLabelKeyFairShareStarvationSec, strutil.String(fs.StarvationSec)),
+		)
+		for tenant, weight := range fs.Weights {
+			queueMetaLabels = append(queueMetaLabels, genMetaLabelFunc(queueID, source, queueLabelKeyPrefixFairShareWeight+tenant, strutil.String(weight)))
+		}
+	}
 	for k, v := range req.Labels {
 		queueMetaLabels = append(queueMetaLabels, genMetaLabelFunc(queueID, apistructs.PipelineSource(req.PipelineSource), k, v))
 	}
@@ -177,8 +196,41 @@ func constructQueueByLabels(labels []spec.PipelineLabel) (*pb.Queue, error) {
 				return nil, fmt.Errorf("failed to construct queue for maxMemoryMB, queueID: %d, value: %s, err: %v", q.ID, label.Value, err)
 			}
 			q.MaxMemoryMB = maxMemoryMB
+		case queueLabelKeyFairShareTenantBy:
+			ensureQueueFairShare(&q).TenantBy = label.Value
+		case queueLabelKeyFairShareDefaultWeight:
+			weight, err := strconv.ParseFloat(label.Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to construct queue for fair share default weight, queueID: %d, value: %s, err: %v", q.ID, label.Value, err)
+			}
+			ensureQueueFairShare(&q).DefaultWeight = weight
+		case queueLabelKeyFairShareDisableBorrowing:
+			disableBorrowing, err := strconv.ParseBool(label.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to construct queue for fair share disable borrowing, queueID: %d, value: %s, err: %v", q.ID, label.Value, err)
+			}
+			ensureQueueFairShare(&q).DisableBorrowing = disableBorrowing
+		case queueLabelKeyFairShareStarvationSec:
+			starvationSec, err := strconv.ParseInt(label.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to construct queue for fair share starvation sec, queueID: %d, value: %s, err: %v", q.ID, label.Value, err)
+			}
+			ensureQueueFairShare(&q).StarvationSec = starvationSec
 
 		default:
+			// fair share weights
+			if tenant := strings.TrimPrefix(label.Key, queueLabelKeyPrefixFairShareWeight); tenant != label.Key {
+				weight, err := strconv.ParseFloat(label.Value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("failed to construct queue for fair share weight, queueID: %d, tenant: %s, value: %s, err: %v", q.ID, tenant, label.Value, err)
+				}
+				fs := ensureQueueFairShare(&q)
+				if fs.Weights == nil {
+					fs.Weights = make(map[string]float64)
+				}
+				fs.Weights[tenant] = weight
+				continue
+			}
 			// other labels
 			if q.Labels == nil {
 				q.Labels = make(map[string]string)
@@ -203,6 +255,13 @@ func constructQueueByLabels(labels []spec.PipelineLabel) (*pb.Queue, error) {
 	return &q, nil
 }
 
+func ensureQueueFairShare(q *pb.Queue) *pb.QueueFairShare {
+	if q.FairShare == nil {
+		q.FairShare = &pb.QueueFairShare{}
+	}
+	return q.FairShare
+}
+
 // GetPipelineQueue
 func (client *Client) GetPipelineQueue(queueID uint64, ops ...SessionOption) (*pb.Queue, bool, error) {
 	session := client.NewSession(ops...)
@@ -427,6 +486,7 @@ func (client *Client) UpdatePipelineQueue(req *pb.QueueUpdateRequest, ops ...Ses
 		MaxMemoryMB:      req.MaxMemoryMB,
 		Labels:           req.Labels,
 		IdentityInfo:     req.IdentityInfo,
+		FairShare:        req.FairShare,
 	}, queueIDLabel, ops...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update queue fields, queueID: %d, err: %v", req.QueueID, err)
@@ -17,6 +17,12 @@ package dbclient
 import (
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/queue/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
 )
 
 func Test_transferMustMatchLabelsToMap(t *testing.T) {
@@ -54,3 +60,31 @@ func Test_transferMustMatchLabelsToMap(t *testing.T) {
 		})
 	}
 }
+
+func Test_constructQueueByLabels_fairShare(t *testing.T) {
+	source := apistructs.PipelineSourceDice
+	labels := []spec.PipelineLabel{
+		{ID: 1, Key: queueLabelKeyID, Value: "1", PipelineSource: source},
+		genMetaLabelFunc(1, source, queueLabelKeyScheduleStrategy, apistructs.ScheduleStrategyInsidePipelineQueueOfFairShare.String()),
+		genMetaLabelFunc(1, source, queueLabelKeyFairShareTenantBy, apistructs.PipelineQueueTenantByOwner.String()),
+		genMetaLabelFunc(1, source, queueLabelKeyFairShareDefaultWeight, "1.5"),
+		genMetaLabelFunc(1, source, queueLabelKeyFairShareDisableBorrowing, "true"),
+		genMetaLabelFunc(1, source, queueLabelKeyFairShareStarvationSec, "600"),
+		genMetaLabelFunc(1, source, queueLabelKeyPrefixFairShareWeight+"10001", "3"),
+		genMetaLabelFunc(1, source, "team", "ci"),
+	}
+	q, err := constructQueueByLabels(labels)
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.QueueFairShare{
+		TenantBy:         apistructs.PipelineQueueTenantByOwner.String(),
+		Weights:          map[string]float64{"10001": 3},
+		DefaultWeight:    1.5,
+		DisableBorrowing: true,
+		StarvationSec:    600,
+	}, q.FairShare)
+	assert.Equal(t, map[string]string{"team": "ci"}, q.Labels)
+
+	labels = append(labels, genMetaLabelFunc(1, source, queueLabelKeyPrefixFairShareWeight+"10002", "x"))
+	_, err = constructQueueByLabels(labels)
+	assert.Error(t, err)
+}
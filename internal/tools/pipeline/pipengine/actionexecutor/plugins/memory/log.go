@@ -0,0 +1,147 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/http/httpclient"
+)
+
+const (
+	asyncPushInterval = time.Second * 3
+	// maxBufferedLines drops the oldest lines if the collector is unreachable for long.
+	maxBufferedLines = 10000
+)
+
+// lineWriter writes process output into a local file and emits it line by line.
+type lineWriter struct {
+	path   string
+	stream string
+	file   *os.File
+	emit   func(stream, line string)
+	buf    bytes.Buffer
+}
+
+func newLineWriter(path, stream string, emit func(stream, line string)) (*lineWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &lineWriter{path: path, stream: stream, file: f, emit: emit}, nil
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	if err != nil {
+		return n, err
+	}
+	w.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if idx < 0 {
+			break
+		}
+		line := w.buf.Next(idx + 1)
+		w.emit(w.stream, string(bytes.TrimRight(line, "\r\n")))
+	}
+	return n, nil
+}
+
+// Close emits the last unterminated line and closes the file.
+func (w *lineWriter) Close() error {
+	if w.buf.Len() > 0 {
+		w.emit(w.stream, w.buf.String())
+		w.buf.Reset()
+	}
+	return w.file.Close()
+}
+
+// logPusher batches log lines and pushes them to collector, the same way action agent does,
+// so the logs are queried exactly like those of other executors.
+// Lines are only kept in local files when collector is not configured.
+type logPusher struct {
+	collectorAddr string
+
+	mu    sync.Mutex
+	lines []apistructs.LogPushLine
+	once  sync.Once
+}
+
+func newLogPusher(collectorAddr string) *logPusher {
+	return &logPusher{collectorAddr: collectorAddr}
+}
+
+func (l *logPusher) push(line apistructs.LogPushLine) {
+	if l.collectorAddr == "" {
+		return
+	}
+	l.once.Do(func() { go l.loop() })
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, line)
+	if len(l.lines) > maxBufferedLines {
+		l.lines = l.lines[len(l.lines)-maxBufferedLines:]
+	}
+}
+
+func (l *logPusher) loop() {
+	ticker := time.NewTicker(asyncPushInterval)
+	for range ticker.C {
+		l.flush()
+	}
+}
+
+func (l *logPusher) flush() {
+	if l.collectorAddr == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.lines) == 0 {
+		return
+	}
+	if err := l.do(l.lines); err != nil {
+		logrus.Error(err)
+		// not refresh logs, try together at next time
+		return
+	}
+	l.lines = nil
+}
+
+func (l *logPusher) do(lines []apistructs.LogPushLine) error {
+	var respBody bytes.Buffer
+	resp, err := httpclient.New(httpclient.WithCompleteRedirect()).
+		Post(l.collectorAddr).
+		Path("/collect/logs/job").
+		JSONBody(lines).
+		Header("Content-Type", "application/json").
+		Do().
+		Body(&respBody)
+	if err != nil {
+		return fmt.Errorf("failed to push log to collector, err: %v", err)
+	}
+	if !resp.IsOK() {
+		return fmt.Errorf("failed to push log to collector, resp body: %s", respBody.String())
+	}
+	return nil
+}
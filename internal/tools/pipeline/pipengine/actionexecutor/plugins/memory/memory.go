@@ -12,32 +12,93 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package memory implements the MEMORY action executor, which runs an action's
+// commands as child processes of the pipeline server itself. It needs neither
+// Kubernetes nor a Docker daemon, so the whole engine can run end to end in
+// integration tests and on small edge nodes.
 package memory
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 
 	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/actionagent"
+	"github.com/erda-project/erda/internal/tools/pipeline/pipengine/actionexecutor/logic"
 	"github.com/erda-project/erda/internal/tools/pipeline/pipengine/actionexecutor/types"
+	"github.com/erda-project/erda/internal/tools/pipeline/pipengine/pvolumes"
+	"github.com/erda-project/erda/internal/tools/pipeline/pkg/task_uuid"
 	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/discover"
+)
+
+var Kind = types.Kind(spec.PipelineTaskExecutorKindMemory)
+
+// executor options, configured by pipeline_configs
+const (
+	OptionWorkDir         = "work_dir"
+	OptionShell           = "shell"
+	OptionKillGracePeriod = "kill_grace_period"
 )
 
 const (
-	Kind = "MEMORY"
+	defaultShell           = "/bin/sh"
+	defaultKillGracePeriod = 10 * time.Second
 )
 
+// hostEnvKeys are the only envs inherited from the pipeline server, so that its own
+// credentials are never leaked to user commands.
+var hostEnvKeys = []string{"PATH", "HOME", "USER", "LANG", "TMPDIR", "SHELL"}
+
 func init() {
-	types.Register(Kind, func(name types.Name, options map[string]string) (types.ActionExecutor, error) {
-		return &Memory{
-			name:    name,
-			options: options,
-		}, nil
+	types.MustRegister(Kind, func(name types.Name, options map[string]string) (types.ActionExecutor, error) {
+		return New(name, options)
 	})
 }
 
 type Memory struct {
-	name    types.Name
-	options map[string]string
+	name            types.Name
+	workDir         string
+	shell           string
+	killGracePeriod time.Duration
+	logPusher       *logPusher
+	errWrapper      *logic.ErrorWrapper
+}
+
+func New(name types.Name, options map[string]string) (*Memory, error) {
+	m := &Memory{
+		name:            name,
+		workDir:         options[OptionWorkDir],
+		shell:           options[OptionShell],
+		killGracePeriod: defaultKillGracePeriod,
+		logPusher:       newLogPusher(discover.Collector()),
+		errWrapper:      logic.NewErrorWrapper(name.String()),
+	}
+	if m.workDir == "" {
+		m.workDir = filepath.Join(os.TempDir(), "erda-pipeline-local")
+	}
+	if m.shell == "" {
+		m.shell = defaultShell
+	}
+	if s := options[OptionKillGracePeriod]; s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, errors.Errorf("invalid %s: %s, err: %v", OptionKillGracePeriod, s, err)
+		}
+		m.killGracePeriod = d
+	}
+	if err := os.MkdirAll(m.workDir, 0755); err != nil {
+		return nil, errors.Errorf("failed to create work dir %s, err: %v", m.workDir, err)
+	}
+	return m, nil
 }
 
 func (m *Memory) Kind() types.Kind {
@@ -48,38 +109,260 @@ func (m *Memory) Name() types.Name {
 	return m.name
 }
 
-func (m *Memory) Exist(ctx context.Context, action *spec.PipelineTask) (bool, bool, error) {
-	panic("implement me")
+func (m *Memory) Exist(ctx context.Context, task *spec.PipelineTask) (created, started bool, err error) {
+	if err := logic.ValidateAction(task); err != nil {
+		return false, false, err
+	}
+	jobDir := m.jobDir(task)
+	if _, err := os.Stat(jobDir); err != nil {
+		if os.IsNotExist(err) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	if getProcess(jobDir) != nil {
+		return true, true, nil
+	}
+	if _, err := readState(jobDir); err == nil {
+		return true, true, nil
+	}
+	return true, false, nil
+}
+
+func (m *Memory) Create(ctx context.Context, task *spec.PipelineTask) (data interface{}, err error) {
+	defer m.errWrapper.WrapTaskError(&err, "create job", task)
+	if err := logic.ValidateAction(task); err != nil {
+		return nil, err
+	}
+	created, _, err := m.Exist(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		logrus.Warnf("%s: task already created, taskInfo: %s", m.Kind().String(), logic.PrintTaskInfo(task))
+		return nil, nil
+	}
+	if err := os.MkdirAll(m.jobDir(task), 0755); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (m *Memory) Start(ctx context.Context, task *spec.PipelineTask) (data interface{}, err error) {
+	defer m.errWrapper.WrapTaskError(&err, "start job", task)
+	if err := logic.ValidateAction(task); err != nil {
+		return nil, err
+	}
+	created, started, err := m.Exist(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		logrus.Warnf("%s: task not created(auto try to create), taskInfo: %s", m.Kind().String(), logic.PrintTaskInfo(task))
+		if _, err := m.Create(ctx, task); err != nil {
+			return nil, err
+		}
+	}
+	if started {
+		logrus.Warnf("%s: task already started, taskInfo: %s", m.Kind().String(), logic.PrintTaskInfo(task))
+		return nil, nil
+	}
+
+	commands, err := task.Extra.Action.GetSliceCommands()
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) == 0 {
+		return nil, errors.Errorf("%s executor only supports actions with commands", m.Kind().String())
+	}
+	envs := m.makeEnvs(task)
+	workDir := envs[actionagent.EnvWorkDir]
+	if workDir == "" {
+		workDir = filepath.Join(m.pipelineDir(task), task.Name)
+	}
+	for _, dir := range []string{workDir, filepath.Dir(envs[actionagent.EnvMetaFile]), envs[actionagent.EnvUploadDir]} {
+		if dir == "" || dir == "." {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &process{
+		jobDir:      m.jobDir(task),
+		workDir:     workDir,
+		shell:       m.shell,
+		script:      makeScript(commands),
+		envs:        envs,
+		timeout:     task.Extra.Timeout,
+		gracePeriod: m.killGracePeriod,
+		logID:       task.Extra.UUID,
+		logTags: map[string]interface{}{
+			"dice_org_name": task.Extra.Labels[apistructs.EnvDiceOrgName],
+			"dice_org_id":   task.Extra.Labels[apistructs.EnvDiceOrgID],
+		},
+		pusher: m.logPusher,
+	}
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	putProcess(p)
+	return p.snapshot(), nil
+}
+
+func (m *Memory) Update(ctx context.Context, task *spec.PipelineTask) (interface{}, error) {
+	return nil, errors.Errorf("%s not support update operation", m.Kind().String())
+}
+
+func (m *Memory) Status(ctx context.Context, task *spec.PipelineTask) (desc apistructs.PipelineStatusDesc, err error) {
+	if err := logic.ValidateAction(task); err != nil {
+		return desc, err
+	}
+	state, err := m.state(task)
+	if err != nil {
+		return desc, err
+	}
+	desc.Status = state.Status
+	desc.Desc = state.Message
+	return desc, nil
+}
+
+func (m *Memory) Inspect(ctx context.Context, task *spec.PipelineTask) (apistructs.TaskInspect, error) {
+	if err := logic.ValidateAction(task); err != nil {
+		return apistructs.TaskInspect{}, err
+	}
+	state, err := m.state(task)
+	if err != nil {
+		return apistructs.TaskInspect{}, err
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return apistructs.TaskInspect{}, err
+	}
+	return apistructs.TaskInspect{Desc: string(b)}, nil
+}
+
+func (m *Memory) Cancel(ctx context.Context, task *spec.PipelineTask) (data interface{}, err error) {
+	defer m.errWrapper.WrapTaskError(&err, "cancel job", task)
+	if err := logic.ValidateAction(task); err != nil {
+		return nil, err
+	}
+	p := getProcess(m.jobDir(task))
+	if p == nil {
+		logrus.Warnf("%s: task not running, skip cancel, taskInfo: %s", m.Kind().String(), logic.PrintTaskInfo(task))
+		return nil, nil
+	}
+	p.stop(apistructs.PipelineStatusStopByUser)
+	return task_uuid.MakeJobID(task), nil
+}
+
+func (m *Memory) Remove(ctx context.Context, task *spec.PipelineTask) (data interface{}, err error) {
+	defer m.errWrapper.WrapTaskError(&err, "remove job", task)
+	if err := logic.ValidateAction(task); err != nil {
+		return nil, err
+	}
+	return m.delete(task)
 }
 
-func (m *Memory) Create(ctx context.Context, action *spec.PipelineTask) (interface{}, error) {
-	panic("implement me")
+// BatchDelete removes all given jobs, and then the working directories of their pipelines.
+func (m *Memory) BatchDelete(ctx context.Context, tasks []*spec.PipelineTask) (data interface{}, err error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	defer m.errWrapper.WrapTaskError(&err, "batch delete job", tasks[0])
+	pipelineDirs := make(map[string]struct{})
+	for _, task := range tasks {
+		if len(task.Extra.UUID) <= 0 {
+			continue
+		}
+		if _, err := m.delete(task); err != nil {
+			return nil, err
+		}
+		pipelineDirs[m.pipelineDir(task)] = struct{}{}
+	}
+	for dir := range pipelineDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
 }
 
-func (m *Memory) Start(ctx context.Context, action *spec.PipelineTask) (interface{}, error) {
-	panic("implement me")
+func (m *Memory) delete(task *spec.PipelineTask) (interface{}, error) {
+	jobDir := m.jobDir(task)
+	if p := getProcess(jobDir); p != nil {
+		p.stop(apistructs.PipelineStatusStopByUser)
+		p.wait()
+		deleteProcess(jobDir)
+	}
+	if err := os.RemoveAll(jobDir); err != nil {
+		return nil, err
+	}
+	return task_uuid.MakeJobID(task), nil
 }
 
-func (m *Memory) Update(ctx context.Context, action *spec.PipelineTask) (interface{}, error) {
-	panic("implement me")
+// state returns the state of a running process, or the persisted state of a finished one.
+func (m *Memory) state(task *spec.PipelineTask) (processState, error) {
+	jobDir := m.jobDir(task)
+	if p := getProcess(jobDir); p != nil {
+		return p.snapshot(), nil
+	}
+	state, err := readState(jobDir)
+	if err == nil {
+		return state, nil
+	}
+	if !os.IsNotExist(err) {
+		return processState{}, err
+	}
+	if _, err := os.Stat(jobDir); err != nil {
+		if os.IsNotExist(err) {
+			return processState{}, errors.Errorf("job %s not found", task_uuid.MakeJobID(task))
+		}
+		return processState{}, err
+	}
+	// job dir exists but neither a live process nor a persisted state,
+	// the pipeline server was restarted while the process was running
+	return processState{
+		Status:  apistructs.PipelineStatusFailed,
+		Message: "process lost, pipeline server may have been restarted while it was running",
+	}, nil
 }
 
-func (m *Memory) Status(ctx context.Context, action *spec.PipelineTask) (apistructs.PipelineStatusDesc, error) {
-	panic("implement me")
+func (m *Memory) pipelineDir(task *spec.PipelineTask) string {
+	return filepath.Join(m.workDir, fmt.Sprintf("%d", task.PipelineID))
 }
 
-func (m *Memory) Inspect(ctx context.Context, action *spec.PipelineTask) (apistructs.TaskInspect, error) {
-	panic("implement me")
+func (m *Memory) jobDir(task *spec.PipelineTask) string {
+	return filepath.Join(m.pipelineDir(task), "jobs", task_uuid.MakeJobID(task))
 }
 
-func (m *Memory) Cancel(ctx context.Context, action *spec.PipelineTask) (interface{}, error) {
-	panic("implement me")
+// makeEnvs merges the allowed host envs with the task's envs. Paths pointing into the
+// action container are mapped into the pipeline's local directory, so tasks of the same
+// pipeline share context just like they share volumes on other executors.
+func (m *Memory) makeEnvs(task *spec.PipelineTask) map[string]string {
+	envs := make(map[string]string)
+	for _, k := range hostEnvKeys {
+		if v, ok := os.LookupEnv(k); ok {
+			envs[k] = v
+		}
+	}
+	pipelineDir := m.pipelineDir(task)
+	for _, kvs := range []map[string]string{task.Extra.PublicEnvs, task.Extra.PrivateEnvs} {
+		for k, v := range kvs {
+			envs[k] = localizePath(v, pipelineDir)
+		}
+	}
+	return envs
 }
 
-func (m *Memory) Remove(ctx context.Context, action *spec.PipelineTask) (interface{}, error) {
-	panic("implement me")
+func localizePath(v, pipelineDir string) string {
+	if v == pvolumes.ContainerRootDir || strings.HasPrefix(v, pvolumes.ContainerRootDir+"/") {
+		return filepath.Join(pipelineDir, strings.TrimPrefix(v, pvolumes.ContainerRootDir))
+	}
+	return v
 }
 
-func (m *Memory) BatchDelete(ctx context.Context, actions []*spec.PipelineTask) (interface{}, error) {
-	panic("implement me")
+func makeScript(commands []string) string {
+	return "set -e\n" + strings.Join(commands, "\n") + "\n"
 }
@@ -0,0 +1,248 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const (
+	stateFileName  = "state.json"
+	stdoutFileName = "stdout.log"
+	stderrFileName = "stderr.log"
+
+	// waitDelay bounds how long Wait blocks on output pipes held open by orphaned grandchildren.
+	waitDelay = 5 * time.Second
+)
+
+// processes holds all processes started by this server, keyed by job dir.
+var (
+	processes   = make(map[string]*process)
+	processesMu sync.Mutex
+)
+
+func getProcess(jobDir string) *process {
+	processesMu.Lock()
+	defer processesMu.Unlock()
+	return processes[jobDir]
+}
+
+func putProcess(p *process) {
+	processesMu.Lock()
+	defer processesMu.Unlock()
+	processes[p.jobDir] = p
+}
+
+func deleteProcess(jobDir string) {
+	processesMu.Lock()
+	defer processesMu.Unlock()
+	delete(processes, jobDir)
+}
+
+// processState is what Status and Inspect report, and is persisted into the job dir
+// once the process exits.
+type processState struct {
+	PID        int                       `json:"pid,omitempty"`
+	Command    string                    `json:"command,omitempty"`
+	WorkDir    string                    `json:"workDir,omitempty"`
+	StdoutFile string                    `json:"stdoutFile,omitempty"`
+	StderrFile string                    `json:"stderrFile,omitempty"`
+	Status     apistructs.PipelineStatus `json:"status"`
+	ExitCode   *int                      `json:"exitCode,omitempty"`
+	Message    string                    `json:"message,omitempty"`
+	TimeBegin  time.Time                 `json:"timeBegin,omitempty"`
+	TimeEnd    time.Time                 `json:"timeEnd,omitempty"`
+}
+
+type process struct {
+	jobDir      string
+	workDir     string
+	shell       string
+	script      string
+	envs        map[string]string
+	timeout     time.Duration
+	gracePeriod time.Duration
+	logID       string
+	logTags     map[string]interface{}
+	pusher      *logPusher
+
+	mu         sync.Mutex
+	cmd        *exec.Cmd
+	state      processState
+	stopReason apistructs.PipelineStatus
+	done       chan struct{}
+}
+
+func (p *process) start() error {
+	stdout, err := newLineWriter(filepath.Join(p.jobDir, stdoutFileName), apistructs.CollectorLogPushStreamStdout, p.emit)
+	if err != nil {
+		return err
+	}
+	stderr, err := newLineWriter(filepath.Join(p.jobDir, stderrFileName), apistructs.CollectorLogPushStreamStderr, p.emit)
+	if err != nil {
+		stdout.Close()
+		return err
+	}
+
+	cmd := exec.Command(p.shell, "-c", p.script)
+	cmd.Dir = p.workDir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = waitDelay
+	for k, v := range p.envs {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		stdout.Close()
+		stderr.Close()
+		return err
+	}
+
+	p.cmd = cmd
+	p.done = make(chan struct{})
+	p.state = processState{
+		PID:        cmd.Process.Pid,
+		Command:    p.script,
+		WorkDir:    p.workDir,
+		StdoutFile: stdout.path,
+		StderrFile: stderr.path,
+		Status:     apistructs.PipelineStatusRunning,
+		TimeBegin:  time.Now(),
+	}
+
+	var timer *time.Timer
+	if p.timeout > 0 {
+		timer = time.AfterFunc(p.timeout, func() { p.stop(apistructs.PipelineStatusTimeout) })
+	}
+	go func() {
+		waitErr := cmd.Wait()
+		if timer != nil {
+			timer.Stop()
+		}
+		stdout.Close()
+		stderr.Close()
+		p.finish(waitErr)
+		p.pusher.flush()
+		close(p.done)
+	}()
+	return nil
+}
+
+// finish records the final state of the process and persists it.
+func (p *process) finish(waitErr error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state.TimeEnd = time.Now()
+	exitCode := p.cmd.ProcessState.ExitCode()
+	if exitCode >= 0 {
+		p.state.ExitCode = &exitCode
+	}
+	switch {
+	case p.stopReason != "":
+		p.state.Status = p.stopReason
+		if p.stopReason == apistructs.PipelineStatusTimeout {
+			p.state.Message = fmt.Sprintf("process killed after timeout %s", p.timeout)
+		}
+	case waitErr == nil:
+		p.state.Status = apistructs.PipelineStatusSuccess
+	default:
+		p.state.Status = apistructs.PipelineStatusFailed
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) {
+			p.state.Message = fmt.Sprintf("process exited with code %d", exitCode)
+		} else {
+			p.state.Message = waitErr.Error()
+		}
+	}
+	if err := writeState(p.jobDir, p.state); err != nil {
+		logrus.Errorf("failed to persist state of local process, jobDir: %s, err: %v", p.jobDir, err)
+	}
+}
+
+// stop terminates the whole process group, escalating to SIGKILL after the grace period.
+func (p *process) stop(reason apistructs.PipelineStatus) {
+	p.mu.Lock()
+	if p.state.Status != apistructs.PipelineStatusRunning || p.stopReason != "" {
+		p.mu.Unlock()
+		return
+	}
+	p.stopReason = reason
+	p.mu.Unlock()
+
+	if err := terminateProcessGroup(p.cmd); err != nil {
+		logrus.Warnf("failed to terminate local process, pid: %d, err: %v", p.cmd.Process.Pid, err)
+	}
+	go func() {
+		select {
+		case <-p.done:
+		case <-time.After(p.gracePeriod):
+			if err := killProcessGroup(p.cmd); err != nil {
+				logrus.Warnf("failed to kill local process, pid: %d, err: %v", p.cmd.Process.Pid, err)
+			}
+		}
+	}()
+}
+
+func (p *process) wait() {
+	<-p.done
+}
+
+func (p *process) snapshot() processState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+func (p *process) emit(stream, line string) {
+	p.pusher.push(apistructs.LogPushLine{
+		ID:        p.logID,
+		Source:    string(apistructs.DashboardSpotLogSourceJob),
+		Stream:    &stream,
+		Timestamp: time.Now().UnixNano(),
+		Content:   line,
+		Tags:      p.logTags,
+	})
+}
+
+func writeState(jobDir string, state processState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(jobDir, stateFileName), b, 0644)
+}
+
+func readState(jobDir string) (processState, error) {
+	var state processState
+	b, err := os.ReadFile(filepath.Join(jobDir, stateFileName))
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(b, &state)
+	return state, err
+}
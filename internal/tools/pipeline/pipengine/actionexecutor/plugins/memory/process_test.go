@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func newTestProcess(t *testing.T, script string, timeout time.Duration) *process {
+	dir := t.TempDir()
+	return &process{
+		jobDir:      dir,
+		workDir:     dir,
+		shell:       defaultShell,
+		script:      makeScript(strings.Split(script, "\n")),
+		envs:        map[string]string{"GREETING": "hello"},
+		timeout:     timeout,
+		gracePeriod: time.Second,
+		logID:       "pipeline-task-1",
+		pusher:      newLogPusher(""),
+	}
+}
+
+func TestProcessSuccess(t *testing.T) {
+	p := newTestProcess(t, "echo $GREETING\npwd", 0)
+	assert.NoError(t, p.start())
+	p.wait()
+
+	state := p.snapshot()
+	assert.Equal(t, apistructs.PipelineStatusSuccess, state.Status)
+	assert.Equal(t, 0, *state.ExitCode)
+	stdout, err := os.ReadFile(state.StdoutFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n"+p.workDir+"\n", string(stdout))
+
+	persisted, err := readState(p.jobDir)
+	assert.NoError(t, err)
+	assert.Equal(t, apistructs.PipelineStatusSuccess, persisted.Status)
+}
+
+func TestProcessFailed(t *testing.T) {
+	p := newTestProcess(t, "echo oops >&2\nexit 3\necho unreachable", 0)
+	assert.NoError(t, p.start())
+	p.wait()
+
+	state := p.snapshot()
+	assert.Equal(t, apistructs.PipelineStatusFailed, state.Status)
+	assert.Equal(t, 3, *state.ExitCode)
+	assert.Equal(t, "process exited with code 3", state.Message)
+	stderr, err := os.ReadFile(filepath.Join(p.jobDir, stderrFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, "oops\n", string(stderr))
+	stdout, err := os.ReadFile(filepath.Join(p.jobDir, stdoutFileName))
+	assert.NoError(t, err)
+	assert.Empty(t, stdout)
+}
+
+func TestProcessTimeout(t *testing.T) {
+	p := newTestProcess(t, "sleep 30", 200*time.Millisecond)
+	assert.NoError(t, p.start())
+	p.wait()
+
+	state := p.snapshot()
+	assert.Equal(t, apistructs.PipelineStatusTimeout, state.Status)
+	assert.True(t, state.TimeEnd.Sub(state.TimeBegin) < 10*time.Second)
+}
+
+func TestProcessStopKillsGroup(t *testing.T) {
+	// the trap ignores SIGTERM, so the group must be killed after the grace period;
+	// the background sleep must die along with the shell
+	p := newTestProcess(t, "trap '' TERM\nsleep 30 &\necho $! > child.pid\nwait", 0)
+	assert.NoError(t, p.start())
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(p.workDir, "child.pid"))
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	p.stop(apistructs.PipelineStatusStopByUser)
+	p.wait()
+	assert.Equal(t, apistructs.PipelineStatusStopByUser, p.snapshot().Status)
+
+	// stop after exit is a no-op
+	p.stop(apistructs.PipelineStatusTimeout)
+	assert.Equal(t, apistructs.PipelineStatusStopByUser, p.snapshot().Status)
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w, err := newLineWriter(filepath.Join(t.TempDir(), "out.log"), apistructs.CollectorLogPushStreamStdout, func(stream, line string) {
+		lines = append(lines, stream+":"+line)
+	})
+	assert.NoError(t, err)
+	_, _ = w.Write([]byte("a\nb"))
+	_, _ = w.Write([]byte("c\r\nd"))
+	assert.Equal(t, []string{"stdout:a", "stdout:bc"}, lines)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, []string{"stdout:a", "stdout:bc", "stdout:d"}, lines)
+}
+
+func TestLocalizePath(t *testing.T) {
+	assert.Equal(t, "/data/1/context/build", localizePath("/.pipeline/container/context/build", "/data/1"))
+	assert.Equal(t, "/data/1", localizePath("/.pipeline/container", "/data/1"))
+	assert.Equal(t, "/.pipeline/containerx", localizePath("/.pipeline/containerx", "/data/1"))
+	assert.Equal(t, "value", localizePath("value", "/data/1"))
+}
@@ -28,6 +28,8 @@ type Inspect struct {
 	Inspect     string                   `json:"inspect,omitempty"`
 	Events      string                   `json:"events,omitempty"`
 	MachineStat *PipelineTaskMachineStat `json:"machineStat,omitempty"`
+	// ExitCode is the exit code of the action reported by action-agent, nil if not reported
+	ExitCode *int `json:"exitCode,omitempty"`
 
 	// Errors stores from pipeline internal, not callback(like action-agent).
 	// For external errors, use taskresult.Result.Errors.
@@ -0,0 +1,160 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/analytics/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/services/apierrors"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/common/apis"
+)
+
+type analyticsService struct {
+	p *provider
+}
+
+func (s *analyticsService) GetPipelineDefinitionAnalytics(ctx context.Context, req *pb.GetPipelineDefinitionAnalyticsRequest) (*pb.GetPipelineDefinitionAnalyticsResponse, error) {
+	start, end, err := s.p.parseWindow(req.StartTime, req.EndTime)
+	if err != nil {
+		return nil, apierrors.ErrGetPipelineAnalytics.InvalidParameter(err)
+	}
+	d, truncated, err := s.loadDataset(ctx, req.DefinitionID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	data := d.analyzeDefinition()
+	data.DefinitionID = req.DefinitionID
+	data.StartTime = timestamppb.New(start)
+	data.EndTime = timestamppb.New(end)
+	data.Truncated = truncated
+	return &pb.GetPipelineDefinitionAnalyticsResponse{Data: data}, nil
+}
+
+func (s *analyticsService) ListPipelineTaskAnalytics(ctx context.Context, req *pb.ListPipelineTaskAnalyticsRequest) (*pb.ListPipelineTaskAnalyticsResponse, error) {
+	start, end, err := s.p.parseWindow(req.StartTime, req.EndTime)
+	if err != nil {
+		return nil, apierrors.ErrGetPipelineAnalytics.InvalidParameter(err)
+	}
+	switch req.OrderBy {
+	case "", orderByP50, orderByP95, orderByFailureRate, orderByFlakyRate, orderByQueue:
+	default:
+		return nil, apierrors.ErrGetPipelineAnalytics.InvalidParameter(fmt.Errorf("invalid orderBy: %s", req.OrderBy))
+	}
+	d, truncated, err := s.loadDataset(ctx, req.DefinitionID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	tasks := d.analyzeTasks()
+	sortTaskAnalytics(tasks, req.OrderBy)
+	if req.Limit > 0 && int(req.Limit) < len(tasks) {
+		tasks = tasks[:req.Limit]
+	}
+	return &pb.ListPipelineTaskAnalyticsResponse{Data: tasks, Truncated: truncated}, nil
+}
+
+func (s *analyticsService) GetPipelineAnalyticsTrend(ctx context.Context, req *pb.GetPipelineAnalyticsTrendRequest) (*pb.GetPipelineAnalyticsTrendResponse, error) {
+	start, end, err := s.p.parseWindow(req.StartTime, req.EndTime)
+	if err != nil {
+		return nil, apierrors.ErrGetPipelineAnalytics.InvalidParameter(err)
+	}
+	interval := req.Interval
+	switch interval {
+	case "":
+		interval = intervalDay
+	case intervalHour:
+		if end.Sub(start) > s.p.Cfg.MaxHourlyWindow {
+			return nil, apierrors.ErrGetPipelineAnalytics.InvalidParameter(
+				fmt.Errorf("window of hourly trend must not exceed %s", s.p.Cfg.MaxHourlyWindow))
+		}
+	case intervalDay, intervalWeek:
+	default:
+		return nil, apierrors.ErrGetPipelineAnalytics.InvalidParameter(fmt.Errorf("invalid interval: %s", req.Interval))
+	}
+	d, truncated, err := s.loadDataset(ctx, req.DefinitionID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetPipelineAnalyticsTrendResponse{Data: d.trend(start, end, interval, req.Name), Truncated: truncated}, nil
+}
+
+// loadDataset checks permission and loads pipelines of the definition in window,
+// truncated is true if there are more pipelines than the limit.
+func (s *analyticsService) loadDataset(ctx context.Context, definitionID string, start, end time.Time) (*dataset, bool, error) {
+	if err := s.checkPermission(ctx, definitionID); err != nil {
+		return nil, false, err
+	}
+	pipelines, err := s.p.dbClient.ListDefinitionPipelines(definitionID, start, end, s.p.Cfg.MaxPipelines)
+	if err != nil {
+		return nil, false, apierrors.ErrGetPipelineAnalytics.InternalError(err)
+	}
+	pipelineIDs := make([]uint64, 0, len(pipelines))
+	var rerunIDs []uint64
+	for _, p := range pipelines {
+		pipelineIDs = append(pipelineIDs, p.ID)
+		if p.Type == apistructs.PipelineTypeRerun || p.Type == apistructs.PipelineTypeRerunFailed {
+			rerunIDs = append(rerunIDs, p.ID)
+		}
+	}
+	tasks, err := s.p.dbClient.ListPipelineTasks(pipelineIDs)
+	if err != nil {
+		return nil, false, apierrors.ErrGetPipelineAnalytics.InternalError(err)
+	}
+	extras, err := s.p.dbClient.ListPipelineExtraInfos(rerunIDs)
+	if err != nil {
+		return nil, false, apierrors.ErrGetPipelineAnalytics.InternalError(err)
+	}
+	d := &dataset{pipelines: pipelines, tasks: make(map[uint64][]spec.PipelineTask, len(pipelines)), extras: extras}
+	for _, t := range tasks {
+		d.tasks[t.PipelineID] = append(d.tasks[t.PipelineID], t)
+	}
+	return d, len(pipelines) >= s.p.Cfg.MaxPipelines, nil
+}
+
+// checkPermission checks the read permission on the app and branch of the definition.
+func (s *analyticsService) checkPermission(ctx context.Context, definitionID string) error {
+	if definitionID == "" {
+		return apierrors.ErrGetPipelineAnalytics.InvalidParameter("missing definitionID")
+	}
+	extra, err := s.p.definitionDBClient.GetPipelineDefinitionExtraByDefinitionID(definitionID)
+	if err != nil {
+		return apierrors.ErrGetPipelineAnalytics.InternalError(err)
+	}
+	if extra == nil || extra.Extra.CreateRequest == nil {
+		return apierrors.ErrGetPipelineAnalytics.NotFound()
+	}
+	appID, branch := getDefinitionAppAndBranch(extra.Extra.CreateRequest.Labels, extra.Extra.CreateRequest.NormalLabels)
+	if err := s.p.Permission.CheckBranch(apis.GetIdentityInfo(ctx), appID, branch, apistructs.GetAction); err != nil {
+		return apierrors.ErrGetPipelineAnalytics.AccessDenied()
+	}
+	return nil
+}
+
+// getDefinitionAppAndBranch returns app and branch from labels, labels too long are moved to normal labels.
+func getDefinitionAppAndBranch(labels, normalLabels map[string]string) (appID, branch string) {
+	get := func(key string) string {
+		if v := labels[key]; v != "" {
+			return v
+		}
+		return normalLabels[key]
+	}
+	return get(apistructs.LabelAppID), get(apistructs.LabelBranch)
+}
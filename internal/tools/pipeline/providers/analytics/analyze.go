@@ -0,0 +1,355 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/analytics/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+// dataset is pipelines of a definition in a time window with their tasks.
+type dataset struct {
+	pipelines []spec.PipelineBase
+	// tasks by pipeline id
+	tasks map[uint64][]spec.PipelineTask
+	// extras of rerun pipelines by pipeline id
+	extras map[uint64]spec.PipelineExtraInfo
+}
+
+// rerunEvent is a task failed in a pipeline and ended in its rerun.
+type rerunEvent struct {
+	name  string
+	time  time.Time
+	flaky bool
+}
+
+// isCountedPipeline returns true if the pipeline ended and was not stopped by user.
+func isCountedPipeline(p spec.PipelineBase) bool {
+	return p.Status.IsEndStatus() && !p.Status.IsStopByUser()
+}
+
+// isCountedTask returns true if the task was executed to end, skipped and disabled tasks are not counted.
+func isCountedTask(t spec.PipelineTask) bool {
+	return t.Status.IsEndStatus() && !t.Status.IsStopByUser() && !t.Status.IsNoNeedBySystem() && !t.Status.IsDisabledStatus()
+}
+
+func getCreatedTime(p spec.PipelineBase) time.Time {
+	if p.TimeCreated == nil {
+		return time.Time{}
+	}
+	return *p.TimeCreated
+}
+
+// executedTasks returns tasks executed by the pipeline,
+// successful tasks copied from the origin of a rerun-failed pipeline are excluded.
+func (d *dataset) executedTasks(p spec.PipelineBase) []spec.PipelineTask {
+	tasks := d.tasks[p.ID]
+	extra, ok := d.extras[p.ID]
+	if p.Type != apistructs.PipelineTypeRerunFailed || !ok || extra.RerunFailedDetail == nil {
+		return tasks
+	}
+	executed := make([]spec.PipelineTask, 0, len(tasks))
+	for _, t := range tasks {
+		if _, copied := extra.RerunFailedDetail.SuccessTasks[t.Name]; copied {
+			continue
+		}
+		executed = append(executed, t)
+	}
+	return executed
+}
+
+// rerunEvents finds tasks failed and then rerun. Reruns copy the commit of origin,
+// so a task failed in origin and passed in rerun is flaky.
+// The origin of a rerun pipeline is only known if it's in the dataset,
+// while rerun-failed pipelines carry failed tasks in RerunFailedDetail.
+func (d *dataset) rerunEvents() []rerunEvent {
+	var events []rerunEvent
+	for _, p := range d.pipelines {
+		extra, ok := d.extras[p.ID]
+		if !ok {
+			continue
+		}
+		var failed []string
+		switch {
+		case p.Type == apistructs.PipelineTypeRerunFailed && extra.RerunFailedDetail != nil:
+			for name := range extra.RerunFailedDetail.FailedTasks {
+				failed = append(failed, name)
+			}
+		case p.Type == apistructs.PipelineTypeRerun && extra.CopyFromPipelineID != nil:
+			for _, t := range d.tasks[*extra.CopyFromPipelineID] {
+				if t.Status.IsFailedStatus() && !t.Status.IsStopByUser() {
+					failed = append(failed, t.Name)
+				}
+			}
+		default:
+			continue
+		}
+		statuses := make(map[string]apistructs.PipelineStatus, len(d.tasks[p.ID]))
+		for _, t := range d.tasks[p.ID] {
+			statuses[t.Name] = t.Status
+		}
+		sort.Strings(failed)
+		for _, name := range failed {
+			status, ok := statuses[name]
+			if !ok || !status.IsEndStatus() || status.IsStopByUser() {
+				continue
+			}
+			events = append(events, rerunEvent{name: name, time: getCreatedTime(p), flaky: status.IsSuccessStatus()})
+		}
+	}
+	return events
+}
+
+func (d *dataset) analyzeDefinition() *pb.DefinitionAnalytics {
+	result := &pb.DefinitionAnalytics{}
+	var durations, queues []float64
+	for _, p := range d.pipelines {
+		if !isCountedPipeline(p) {
+			continue
+		}
+		result.TotalRuns++
+		if p.Status.IsFailedStatus() {
+			result.FailedRuns++
+		} else if p.CostTimeSec >= 0 {
+			durations = append(durations, float64(p.CostTimeSec))
+		}
+		for _, t := range d.executedTasks(p) {
+			if isCountedTask(t) && t.QueueTimeSec >= 0 {
+				queues = append(queues, float64(t.QueueTimeSec))
+			}
+		}
+	}
+	result.FailureRate = rate(result.FailedRuns, result.TotalRuns)
+	result.Duration = newDurationStats(durations)
+	result.Queue = newDurationStats(queues)
+	return result
+}
+
+func (d *dataset) analyzeTasks() []*pb.TaskAnalytics {
+	type taskStats struct {
+		result    *pb.TaskAnalytics
+		durations []float64
+		queues    []float64
+	}
+	statsByName := make(map[string]*taskStats)
+	get := func(name string) *taskStats {
+		s, ok := statsByName[name]
+		if !ok {
+			s = &taskStats{result: &pb.TaskAnalytics{Name: name}}
+			statsByName[name] = s
+		}
+		return s
+	}
+	for _, p := range d.pipelines {
+		for _, t := range d.executedTasks(p) {
+			if !isCountedTask(t) {
+				continue
+			}
+			s := get(t.Name)
+			if t.Type != "" {
+				s.result.Type = t.Type
+			}
+			s.result.TotalRuns++
+			if t.Status.IsFailedStatus() {
+				s.result.FailedRuns++
+			} else if t.CostTimeSec >= 0 {
+				s.durations = append(s.durations, float64(t.CostTimeSec))
+			}
+			if t.QueueTimeSec >= 0 {
+				s.queues = append(s.queues, float64(t.QueueTimeSec))
+			}
+		}
+	}
+	for _, e := range d.rerunEvents() {
+		s := get(e.name)
+		s.result.RerunCount++
+		if e.flaky {
+			s.result.FlakyCount++
+		}
+	}
+
+	results := make([]*pb.TaskAnalytics, 0, len(statsByName))
+	for _, s := range statsByName {
+		s.result.FailureRate = rate(s.result.FailedRuns, s.result.TotalRuns)
+		s.result.FlakyRate = rate(s.result.FlakyCount, s.result.TotalRuns)
+		s.result.Duration = newDurationStats(s.durations)
+		s.result.Queue = newDurationStats(s.queues)
+		results = append(results, s.result)
+	}
+	return results
+}
+
+// trend buckets pipelines by created time, tasks of name if specified.
+func (d *dataset) trend(start, end time.Time, interval string, name string) []*pb.TrendPoint {
+	type bucket struct {
+		point     *pb.TrendPoint
+		durations []float64
+	}
+	var buckets []*bucket
+	index := make(map[time.Time]*bucket)
+	for t := truncateTime(start, interval); t.Before(end); t = nextTime(t, interval) {
+		b := &bucket{point: &pb.TrendPoint{Time: timestamppb.New(t)}}
+		buckets = append(buckets, b)
+		index[t] = b
+	}
+	record := func(created time.Time, status apistructs.PipelineStatus, costTimeSec int64) {
+		b, ok := index[truncateTime(created.In(start.Location()), interval)]
+		if !ok {
+			return
+		}
+		b.point.TotalRuns++
+		if status.IsFailedStatus() {
+			b.point.FailedRuns++
+		} else if costTimeSec >= 0 {
+			b.durations = append(b.durations, float64(costTimeSec))
+		}
+	}
+	for _, p := range d.pipelines {
+		if name == "" {
+			if isCountedPipeline(p) {
+				record(getCreatedTime(p), p.Status, p.CostTimeSec)
+			}
+			continue
+		}
+		for _, t := range d.executedTasks(p) {
+			if t.Name == name && isCountedTask(t) {
+				record(getCreatedTime(p), t.Status, t.CostTimeSec)
+			}
+		}
+	}
+	for _, e := range d.rerunEvents() {
+		if !e.flaky || (name != "" && e.name != name) {
+			continue
+		}
+		if b, ok := index[truncateTime(e.time.In(start.Location()), interval)]; ok {
+			b.point.FlakyCount++
+		}
+	}
+
+	points := make([]*pb.TrendPoint, 0, len(buckets))
+	for _, b := range buckets {
+		b.point.FailureRate = rate(b.point.FailedRuns, b.point.TotalRuns)
+		b.point.Duration = newDurationStats(b.durations)
+		points = append(points, b.point)
+	}
+	return points
+}
+
+const (
+	intervalHour = "hour"
+	intervalDay  = "day"
+	intervalWeek = "week"
+)
+
+// truncateTime truncates t to the start of interval in its location, weeks start on monday.
+func truncateTime(t time.Time, interval string) time.Time {
+	switch interval {
+	case intervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case intervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+func nextTime(t time.Time, interval string) time.Time {
+	switch interval {
+	case intervalHour:
+		return t.Add(time.Hour)
+	case intervalWeek:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// sortTaskAnalytics sorts descending by orderBy, then by name.
+func sortTaskAnalytics(tasks []*pb.TaskAnalytics, orderBy string) {
+	key := func(t *pb.TaskAnalytics) float64 {
+		switch orderBy {
+		case orderByP50:
+			return t.Duration.P50Sec
+		case orderByFailureRate:
+			return t.FailureRate
+		case orderByFlakyRate:
+			return t.FlakyRate
+		case orderByQueue:
+			return t.Queue.P95Sec
+		default:
+			return t.Duration.P95Sec
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		ki, kj := key(tasks[i]), key(tasks[j])
+		if ki != kj {
+			return ki > kj
+		}
+		return tasks[i].Name < tasks[j].Name
+	})
+}
+
+const (
+	orderByP50         = "p50"
+	orderByP95         = "p95"
+	orderByFailureRate = "failureRate"
+	orderByFlakyRate   = "flakyRate"
+	orderByQueue       = "queue"
+)
+
+func newDurationStats(samples []float64) *pb.DurationStats {
+	stats := &pb.DurationStats{Count: int64(len(samples))}
+	if len(samples) == 0 {
+		return stats
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	stats.AvgSec = sum / float64(len(sorted))
+	stats.P50Sec = percentile(sorted, 50)
+	stats.P95Sec = percentile(sorted, 95)
+	stats.MaxSec = sorted[len(sorted)-1]
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted samples.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func rate(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
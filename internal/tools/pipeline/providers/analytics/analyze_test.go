@@ -0,0 +1,142 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+func newPipeline(id uint64, status apistructs.PipelineStatus, typ apistructs.PipelineType, costTimeSec int64, created time.Time) spec.PipelineBase {
+	return spec.PipelineBase{ID: id, Status: status, Type: typ, CostTimeSec: costTimeSec, TimeCreated: &created}
+}
+
+func newTask(pipelineID uint64, name string, status apistructs.PipelineStatus, costTimeSec, queueTimeSec int64) spec.PipelineTask {
+	return spec.PipelineTask{PipelineID: pipelineID, Name: name, Type: "custom-script", Status: status, CostTimeSec: costTimeSec, QueueTimeSec: queueTimeSec}
+}
+
+func newTestDataset() *dataset {
+	day1 := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	origin := uint64(3)
+	return &dataset{
+		pipelines: []spec.PipelineBase{
+			newPipeline(1, apistructs.PipelineStatusSuccess, apistructs.PipelineTypeNormal, 100, day1),
+			newPipeline(2, apistructs.PipelineStatusStopByUser, apistructs.PipelineTypeNormal, 10, day1),
+			newPipeline(3, apistructs.PipelineStatusFailed, apistructs.PipelineTypeNormal, 50, day1),
+			newPipeline(4, apistructs.PipelineStatusSuccess, apistructs.PipelineTypeRerunFailed, 40, day2),
+			newPipeline(5, apistructs.PipelineStatusRunning, apistructs.PipelineTypeNormal, -1, day2),
+			newPipeline(6, apistructs.PipelineStatusFailed, apistructs.PipelineTypeRerun, 60, day2),
+		},
+		tasks: map[uint64][]spec.PipelineTask{
+			1: {newTask(1, "build", apistructs.PipelineStatusSuccess, 60, 2), newTask(1, "test", apistructs.PipelineStatusSuccess, 30, 4)},
+			2: {newTask(2, "build", apistructs.PipelineStatusStopByUser, 10, 1)},
+			3: {newTask(3, "build", apistructs.PipelineStatusSuccess, 40, 6), newTask(3, "test", apistructs.PipelineStatusFailed, 10, 8)},
+			// build is copied from pipeline 3 by rerun-failed
+			4: {newTask(4, "build", apistructs.PipelineStatusSuccess, 40, 6), newTask(4, "test", apistructs.PipelineStatusSuccess, 20, 2)},
+			5: {newTask(5, "build", apistructs.PipelineStatusRunning, -1, -1)},
+			6: {newTask(6, "build", apistructs.PipelineStatusSuccess, 50, 2), newTask(6, "test", apistructs.PipelineStatusFailed, 10, 2)},
+		},
+		extras: map[uint64]spec.PipelineExtraInfo{
+			4: {RerunFailedDetail: &spec.RerunFailedDetail{
+				RerunPipelineID: 3,
+				SuccessTasks:    map[string]uint64{"build": 31},
+				FailedTasks:     map[string]uint64{"test": 32},
+			}},
+			6: {CopyFromPipelineID: &origin},
+		},
+	}
+}
+
+func TestDataset_AnalyzeDefinition(t *testing.T) {
+	result := newTestDataset().analyzeDefinition()
+	assert.Equal(t, int64(4), result.TotalRuns)
+	assert.Equal(t, int64(2), result.FailedRuns)
+	assert.Equal(t, 0.5, result.FailureRate)
+	assert.Equal(t, int64(2), result.Duration.Count)
+	assert.Equal(t, 40.0, result.Duration.P50Sec)
+	assert.Equal(t, 100.0, result.Duration.P95Sec)
+	// the copied build of pipeline 4 is not counted
+	assert.Equal(t, int64(7), result.Queue.Count)
+}
+
+func TestDataset_AnalyzeTasks(t *testing.T) {
+	tasks := newTestDataset().analyzeTasks()
+	sortTaskAnalytics(tasks, orderByFlakyRate)
+	assert.Len(t, tasks, 2)
+
+	test := tasks[0]
+	assert.Equal(t, "test", test.Name)
+	assert.Equal(t, "custom-script", test.Type)
+	assert.Equal(t, int64(4), test.TotalRuns)
+	assert.Equal(t, int64(2), test.FailedRuns)
+	// failed in 3, passed in rerun-failed 4 and failed again in rerun 6
+	assert.Equal(t, int64(2), test.RerunCount)
+	assert.Equal(t, int64(1), test.FlakyCount)
+	assert.Equal(t, 0.25, test.FlakyRate)
+	assert.Equal(t, []float64{20, 30}, []float64{test.Duration.P50Sec, test.Duration.P95Sec})
+
+	build := tasks[1]
+	assert.Equal(t, "build", build.Name)
+	assert.Equal(t, int64(3), build.TotalRuns)
+	assert.Equal(t, int64(0), build.FailedRuns)
+	assert.Equal(t, int64(0), build.RerunCount)
+	assert.Equal(t, 60.0, build.Duration.MaxSec)
+}
+
+func TestDataset_Trend(t *testing.T) {
+	d := newTestDataset()
+	start := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	points := d.trend(start, end, intervalDay, "")
+	assert.Len(t, points, 2)
+	assert.Equal(t, int64(2), points[0].TotalRuns)
+	assert.Equal(t, int64(1), points[0].FailedRuns)
+	assert.Equal(t, int64(2), points[1].TotalRuns)
+	assert.Equal(t, int64(1), points[1].FlakyCount)
+
+	points = d.trend(start, end, intervalDay, "test")
+	assert.Equal(t, int64(2), points[0].TotalRuns)
+	assert.Equal(t, 0.5, points[0].FailureRate)
+	assert.Equal(t, int64(2), points[1].TotalRuns)
+	assert.Equal(t, int64(1), points[1].FlakyCount)
+
+	points = d.trend(start, end, intervalHour, "build")
+	assert.Len(t, points, 48)
+	assert.Equal(t, int64(2), points[10].TotalRuns)
+}
+
+func TestTruncateTime(t *testing.T) {
+	friday := time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC), truncateTime(friday, intervalHour))
+	assert.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), truncateTime(friday, intervalDay))
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), truncateTime(friday, intervalWeek))
+	sunday := time.Date(2026, 10, 18, 1, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), truncateTime(sunday, intervalWeek))
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Equal(t, 0.0, percentile(nil, 50))
+	sorted := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	assert.Equal(t, 5.0, percentile(sorted, 50))
+	assert.Equal(t, 10.0, percentile(sorted, 95))
+	assert.Equal(t, 1.0, percentile(sorted, 0))
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+// batchSize limits the size of IN clause.
+const batchSize = 500
+
+type Client struct {
+	mysqlxorm.Interface
+}
+
+// ListDefinitionPipelines lists pipelines of the definition created in [start, end), the latest first.
+// Snippet pipelines are excluded, they are counted as tasks of their parents.
+func (client *Client) ListDefinitionPipelines(definitionID string, start, end time.Time, limit int, ops ...mysqlxorm.SessionOption) ([]spec.PipelineBase, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var pipelines []spec.PipelineBase
+	err := session.Cols("id", "status", "type", "cost_time_sec", "time_begin", "time_end", "time_created").
+		Where("pipeline_definition_id = ?", definitionID).
+		Where("is_snippet = ?", false).
+		Where("time_created >= ? AND time_created < ?", start, end).
+		Desc("id").Limit(limit).Find(&pipelines)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list pipelines, definitionID: %s", definitionID)
+	}
+	return pipelines, nil
+}
+
+// ListPipelineTasks lists tasks of pipelines without json columns.
+func (client *Client) ListPipelineTasks(pipelineIDs []uint64, ops ...mysqlxorm.SessionOption) ([]spec.PipelineTask, error) {
+	var tasks []spec.PipelineTask
+	for _, batch := range splitIDs(pipelineIDs) {
+		var batchTasks []spec.PipelineTask
+		if err := func() error {
+			session := client.NewSession(ops...)
+			defer session.Close()
+			return session.Cols("id", "pipeline_id", "name", "type", "status", "cost_time_sec", "queue_time_sec", "time_begin", "time_end").
+				In("pipeline_id", batch).Find(&batchTasks)
+		}(); err != nil {
+			return nil, errors.Wrap(err, "failed to list pipeline tasks")
+		}
+		tasks = append(tasks, batchTasks...)
+	}
+	return tasks, nil
+}
+
+// ListPipelineExtraInfos returns extra info of pipelines, keyed by pipeline id.
+func (client *Client) ListPipelineExtraInfos(pipelineIDs []uint64, ops ...mysqlxorm.SessionOption) (map[uint64]spec.PipelineExtraInfo, error) {
+	result := make(map[uint64]spec.PipelineExtraInfo, len(pipelineIDs))
+	for _, batch := range splitIDs(pipelineIDs) {
+		var extras []spec.PipelineExtra
+		if err := func() error {
+			session := client.NewSession(ops...)
+			defer session.Close()
+			return session.Cols("pipeline_id", "extra").In("pipeline_id", batch).Find(&extras)
+		}(); err != nil {
+			return nil, errors.Wrap(err, "failed to list pipeline extras")
+		}
+		for _, extra := range extras {
+			result[extra.PipelineID] = extra.Extra
+		}
+	}
+	return result, nil
+}
+
+func splitIDs(ids []uint64) [][]uint64 {
+	var batches [][]uint64
+	for len(ids) > batchSize {
+		batches = append(batches, ids[:batchSize])
+		ids = ids[batchSize:]
+	}
+	if len(ids) > 0 {
+		batches = append(batches, ids)
+	}
+	return batches
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-infra/pkg/transport"
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda-proto-go/core/pipeline/analytics/pb"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/analytics/db"
+	definitiondb "github.com/erda-project/erda/internal/tools/pipeline/providers/definition/db"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/permission"
+	"github.com/erda-project/erda/pkg/common/apis"
+)
+
+type config struct {
+	// MaxPipelines limits pipelines analyzed per request, the latest are kept
+	MaxPipelines    int           `file:"max_pipelines" env:"PIPELINE_ANALYTICS_MAX_PIPELINES" default:"2000"`
+	DefaultWindow   time.Duration `file:"default_window" env:"PIPELINE_ANALYTICS_DEFAULT_WINDOW" default:"720h"`
+	MaxWindow       time.Duration `file:"max_window" env:"PIPELINE_ANALYTICS_MAX_WINDOW" default:"2160h"`
+	MaxHourlyWindow time.Duration `file:"max_hourly_window" env:"PIPELINE_ANALYTICS_MAX_HOURLY_WINDOW" default:"168h"`
+}
+
+// +provider
+type provider struct {
+	Cfg        *config
+	Log        logs.Logger
+	Register   transport.Register
+	MySQL      mysqlxorm.Interface
+	Permission permission.Interface
+
+	dbClient           *db.Client
+	definitionDBClient *definitiondb.Client
+	analyticsService   *analyticsService
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.dbClient = &db.Client{Interface: p.MySQL}
+	p.definitionDBClient = &definitiondb.Client{Interface: p.MySQL}
+	p.analyticsService = &analyticsService{p: p}
+	if p.Register != nil {
+		pb.RegisterAnalyticsServiceImp(p.Register, p.analyticsService, apis.Options())
+	}
+	return nil
+}
+
+// parseWindow parses the RFC3339 window, end defaults to now and start defaults to DefaultWindow before end.
+func (p *provider) parseWindow(startTime, endTime string) (start, end time.Time, err error) {
+	end = time.Now()
+	if endTime != "" {
+		if end, err = time.Parse(time.RFC3339, endTime); err != nil {
+			return start, end, fmt.Errorf("invalid endTime: %v", err)
+		}
+	}
+	start = end.Add(-p.Cfg.DefaultWindow)
+	if startTime != "" {
+		if start, err = time.Parse(time.RFC3339, startTime); err != nil {
+			return start, end, fmt.Errorf("invalid startTime: %v", err)
+		}
+	}
+	if !start.Before(end) {
+		return start, end, fmt.Errorf("startTime must be before endTime")
+	}
+	if end.Sub(start) > p.Cfg.MaxWindow {
+		return start, end, fmt.Errorf("window must not exceed %s", p.Cfg.MaxWindow)
+	}
+	return start, end, nil
+}
+
+func (p *provider) Provide(ctx servicehub.DependencyContext, args ...interface{}) interface{} {
+	switch {
+	case ctx.Service() == "erda.core.pipeline.analytics.AnalyticsService" || ctx.Type() == pb.AnalyticsServiceServerType() || ctx.Type() == pb.AnalyticsServiceHandlerType():
+		return p.analyticsService
+	}
+	return p
+}
+
+func init() {
+	servicehub.Register("erda.core.pipeline.analytics", &servicehub.Spec{
+		Services:             pb.ServiceNames(),
+		Types:                pb.Types(),
+		OptionalDependencies: []string{"service-register"},
+		Description:          "duration, failure and flakiness analytics of pipeline definitions",
+		ConfigFunc: func() interface{} {
+			return &config{}
+		},
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifact
+
+import (
+	"context"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/artifact/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/artifact/db"
+	"github.com/erda-project/erda/internal/tools/pipeline/services/apierrors"
+	"github.com/erda-project/erda/pkg/common/apis"
+)
+
+type artifactService struct {
+	p *provider
+}
+
+func (s *artifactService) ListPipelineArtifacts(ctx context.Context, req *pb.ListPipelineArtifactsRequest) (*pb.ListPipelineArtifactsResponse, error) {
+	p, err := s.p.pipelineDBClient.GetPipeline(req.PipelineID)
+	if err != nil {
+		return nil, apierrors.ErrListPipelineArtifact.InternalError(err)
+	}
+	identityInfo := apis.GetIdentityInfo(ctx)
+	if err := s.p.Permission.CheckBranch(identityInfo, p.Labels[apistructs.LabelAppID], p.Labels[apistructs.LabelBranch], apistructs.GetAction); err != nil {
+		return nil, apierrors.ErrListPipelineArtifact.AccessDenied()
+	}
+	artifacts, err := s.p.dbClient.ListArtifacts(db.ListArtifactsRequest{
+		PipelineID: req.PipelineID,
+		TaskID:     req.TaskID,
+		Name:       req.Name,
+	})
+	if err != nil {
+		return nil, apierrors.ErrListPipelineArtifact.InternalError(err)
+	}
+	data := make([]*pb.Artifact, 0, len(artifacts))
+	for i := range artifacts {
+		data = append(data, artifacts[i].Convert2PB())
+	}
+	return &pb.ListPipelineArtifactsResponse{Data: data}, nil
+}
+
+// UploadArtifact is implemented by provider.UploadArtifact as a pure http handler.
+func (s *artifactService) UploadArtifact(ctx context.Context, req *pb.UploadArtifactRequest) (*pb.UploadArtifactResponse, error) {
+	return nil, apierrors.ErrUploadPipelineArtifact.InvalidParameter("only multipart request is supported")
+}
+
+// DownloadArtifact is implemented by provider.DownloadArtifact as a pure http handler.
+func (s *artifactService) DownloadArtifact(ctx context.Context, req *pb.DownloadArtifactRequest) (*pb.ArtifactPart, error) {
+	return nil, apierrors.ErrDownloadPipelineArtifact.InvalidParameter("only http download is supported")
+}
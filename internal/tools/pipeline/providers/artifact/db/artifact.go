@@ -0,0 +1,173 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda-proto-go/core/pipeline/artifact/pb"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+// PipelineArtifact is an artifact declared by an action, the content is stored in object storage.
+type PipelineArtifact struct {
+	ID            string    `json:"id" xorm:"pk"`
+	CreatedAt     time.Time `json:"timeCreated" xorm:"created_at created"`
+	UpdatedAt     time.Time `json:"timeUpdated" xorm:"updated_at updated"`
+	SoftDeletedAt int64     `json:"softDeletedAt"`
+
+	OrgID      uint64 `json:"orgID"`
+	OrgName    string `json:"orgName"`
+	AppID      uint64 `json:"appID"`
+	Branch     string `json:"branch"`
+	PipelineID uint64 `json:"pipelineID"`
+	TaskID     uint64 `json:"taskID"`
+	TaskName   string `json:"taskName"`
+
+	Name       string    `json:"name"`
+	ObjectName string    `json:"objectName"`
+	ByteSize   int64     `json:"byteSize"`
+	ExpiredAt  time.Time `json:"expiredAt"`
+}
+
+func (PipelineArtifact) TableName() string {
+	return "pipeline_artifacts"
+}
+
+func (a *PipelineArtifact) Convert2PB() *pb.Artifact {
+	if a == nil {
+		return nil
+	}
+	return &pb.Artifact{
+		ID:          a.ID,
+		PipelineID:  a.PipelineID,
+		TaskID:      a.TaskID,
+		TaskName:    a.TaskName,
+		Name:        a.Name,
+		ByteSize:    a.ByteSize,
+		ExpiredAt:   timestamppb.New(a.ExpiredAt),
+		TimeCreated: timestamppb.New(a.CreatedAt),
+	}
+}
+
+// SaveArtifact creates the artifact, or updates it if an artifact with the same name exists in the pipeline,
+// so a retried task overwrites the artifact uploaded before.
+func (client *Client) SaveArtifact(artifact *PipelineArtifact, ops ...mysqlxorm.SessionOption) error {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var exist PipelineArtifact
+	found, err := session.Where("pipeline_id = ? AND name = ? AND soft_deleted_at = 0", artifact.PipelineID, artifact.Name).Get(&exist)
+	if err != nil {
+		return errors.Wrapf(err, "failed to query artifact, pipelineID: %d, name: %s", artifact.PipelineID, artifact.Name)
+	}
+	if found {
+		artifact.ID = exist.ID
+		artifact.CreatedAt = exist.CreatedAt
+		if _, err := session.ID(artifact.ID).AllCols().Update(artifact); err != nil {
+			return errors.Wrapf(err, "failed to update artifact, id: %s", artifact.ID)
+		}
+		return nil
+	}
+	artifact.ID = uuid.New()
+	if _, err := session.InsertOne(artifact); err != nil {
+		return errors.Wrapf(err, "failed to insert artifact, pipelineID: %d, name: %s", artifact.PipelineID, artifact.Name)
+	}
+	return nil
+}
+
+// GetArtifact returns nil if the artifact not found.
+func (client *Client) GetArtifact(id string, ops ...mysqlxorm.SessionOption) (*PipelineArtifact, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var artifact PipelineArtifact
+	found, err := session.Where("id = ? AND soft_deleted_at = 0", id).Get(&artifact)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get artifact, id: %s", id)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &artifact, nil
+}
+
+// GetArtifactByName returns nil if the artifact not found.
+func (client *Client) GetArtifactByName(pipelineID uint64, name string, ops ...mysqlxorm.SessionOption) (*PipelineArtifact, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var artifact PipelineArtifact
+	found, err := session.Where("pipeline_id = ? AND name = ? AND soft_deleted_at = 0", pipelineID, name).Get(&artifact)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get artifact, pipelineID: %d, name: %s", pipelineID, name)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &artifact, nil
+}
+
+type ListArtifactsRequest struct {
+	PipelineID uint64
+	TaskID     uint64
+	Name       string
+}
+
+func (client *Client) ListArtifacts(req ListArtifactsRequest, ops ...mysqlxorm.SessionOption) ([]PipelineArtifact, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	session.Where("pipeline_id = ? AND soft_deleted_at = 0", req.PipelineID)
+	if req.TaskID > 0 {
+		session.Where("task_id = ?", req.TaskID)
+	}
+	if req.Name != "" {
+		session.Where("name = ?", req.Name)
+	}
+	var artifacts []PipelineArtifact
+	if err := session.Asc("created_at").Find(&artifacts); err != nil {
+		return nil, errors.Wrapf(err, "failed to list artifacts, pipelineID: %d", req.PipelineID)
+	}
+	return artifacts, nil
+}
+
+// ListExpiredArtifacts lists artifacts expired before the time, at most limit ones.
+func (client *Client) ListExpiredArtifacts(before time.Time, limit int, ops ...mysqlxorm.SessionOption) ([]PipelineArtifact, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var artifacts []PipelineArtifact
+	if err := session.Where("expired_at < ? AND soft_deleted_at = 0", before).
+		Asc("expired_at").Limit(limit).Find(&artifacts); err != nil {
+		return nil, errors.Wrapf(err, "failed to list expired artifacts")
+	}
+	return artifacts, nil
+}
+
+func (client *Client) DeleteArtifact(id string, ops ...mysqlxorm.SessionOption) error {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	if _, err := session.Table(PipelineArtifact{}.TableName()).Where("id = ?", id).
+		Update(map[string]interface{}{"soft_deleted_at": time.Now().UnixNano() / 1e6}); err != nil {
+		return errors.Wrapf(err, "failed to delete artifact, id: %s", id)
+	}
+	return nil
+}
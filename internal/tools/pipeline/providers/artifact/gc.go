@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifact
+
+import (
+	"context"
+	"time"
+
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/artifact/db"
+)
+
+// DeleteExpiredArtifacts deletes artifacts expired before now from storage and database, returns the deleted count.
+func (p *provider) DeleteExpiredArtifacts(ctx context.Context, now time.Time) (int, error) {
+	var deleted int
+	for {
+		select {
+		case <-ctx.Done():
+			return deleted, ctx.Err()
+		default:
+		}
+		artifacts, err := p.dbClient.ListExpiredArtifacts(now, p.Cfg.GCBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+		if len(artifacts) == 0 {
+			return deleted, nil
+		}
+		for i := range artifacts {
+			if err := p.deleteArtifact(&artifacts[i]); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if len(artifacts) < p.Cfg.GCBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// DeletePipelineArtifacts deletes all artifacts of the pipeline, used when the pipeline is deleted.
+func (p *provider) DeletePipelineArtifacts(ctx context.Context, pipelineID uint64) error {
+	artifacts, err := p.dbClient.ListArtifacts(db.ListArtifactsRequest{PipelineID: pipelineID})
+	if err != nil {
+		return err
+	}
+	for i := range artifacts {
+		if err := p.deleteArtifact(&artifacts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteArtifact deletes the object first, so no object is left without a record.
+func (p *provider) deleteArtifact(artifact *db.PipelineArtifact) error {
+	storage, err := p.getStorage()
+	if err != nil {
+		return err
+	}
+	if err := storage.DeleteFile(p.Cfg.StorageBucket, artifact.ObjectName); err != nil {
+		return err
+	}
+	if err := p.dbClient.DeleteArtifact(artifact.ID); err != nil {
+		return err
+	}
+	p.Log.Infof("artifact deleted, pipelineID: %d, name: %s", artifact.PipelineID, artifact.Name)
+	return nil
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifact
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	infrahttpserver "github.com/erda-project/erda-infra/providers/httpserver"
+	commonpb "github.com/erda-project/erda-proto-go/common/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/artifact/db"
+	"github.com/erda-project/erda/internal/tools/pipeline/services/apierrors"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+	"github.com/erda-project/erda/pkg/http/httputil"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+)
+
+const (
+	// multipartOverheadBytes is the size allowed besides the file in a multipart body
+	multipartOverheadBytes = 1 << 20
+	multipartMaxMemory     = 32 << 20
+)
+
+// UploadArtifact is invoked by action-agent to upload an artifact declared by the task.
+func (p *provider) UploadArtifact(rw http.ResponseWriter, r *http.Request) {
+	identityInfo := identityFromRequest(r)
+	if identityInfo == nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.NotLogin())
+		return
+	}
+	pipelineID, err := pathUint64(r, "pipelineID")
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter(err))
+		return
+	}
+	taskID, err := pathUint64(r, "taskID")
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter(err))
+		return
+	}
+	name := r.URL.Query().Get("name")
+	task, err := p.pipelineDBClient.GetPipelineTask(taskID)
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InternalError(err))
+		return
+	}
+	if task.PipelineID != pipelineID {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter("task not belong to pipeline"))
+		return
+	}
+	if !task.Status.IsRunningStatus() {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter(fmt.Sprintf("task %s is not running", task.Name)))
+		return
+	}
+	declared, ok := findDeclaredArtifact(task.Extra.Action.Artifacts, name)
+	if !ok {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter(fmt.Sprintf("artifact %q is not declared by task %s", name, task.Name)))
+		return
+	}
+	expiredAt, err := p.calcExpiredAt(declared.Retention, time.Now())
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter(err))
+		return
+	}
+	pipeline, err := p.pipelineDBClient.GetPipeline(pipelineID)
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InternalError(err))
+		return
+	}
+	if err := p.checkUploadPermission(identityInfo, pipeline.Labels); err != nil {
+		errorresp.Error(rw, err)
+		return
+	}
+	storage, err := p.getStorage()
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InternalError(err))
+		return
+	}
+
+	// check the size
+	maxSize := p.Cfg.MaxSizeMB << 20
+	if r.ContentLength > maxSize+multipartOverheadBytes {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter(errors.Errorf("max artifact size: %dMB", p.Cfg.MaxSizeMB)))
+		return
+	}
+	r.Body = http.MaxBytesReader(rw, r.Body, maxSize+multipartOverheadBytes)
+	if err := r.ParseMultipartForm(multipartMaxMemory); err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter(err))
+		return
+	}
+	formFile, _, err := r.FormFile("file")
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter(err))
+		return
+	}
+	defer formFile.Close()
+
+	// cloud storage uploads from a local file
+	tmpFile, err := os.CreateTemp("", "pipeline-artifact-")
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InternalError(err))
+		return
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+	byteSize, err := io.Copy(tmpFile, io.LimitReader(formFile, maxSize+1))
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InternalError(err))
+		return
+	}
+	if byteSize > maxSize {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InvalidParameter(errors.Errorf("max artifact size: %dMB", p.Cfg.MaxSizeMB)))
+		return
+	}
+	objectName := makeObjectName(pipelineID, name)
+	if _, err := storage.UploadFile(p.Cfg.StorageBucket, objectName, tmpFile.Name()); err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InternalError(err))
+		return
+	}
+
+	orgID, _ := strconv.ParseUint(pipeline.Labels[apistructs.LabelOrgID], 10, 64)
+	appID, _ := strconv.ParseUint(pipeline.Labels[apistructs.LabelAppID], 10, 64)
+	artifact := &db.PipelineArtifact{
+		OrgID:      orgID,
+		OrgName:    pipeline.Labels[apistructs.LabelOrgName],
+		AppID:      appID,
+		Branch:     pipeline.Labels[apistructs.LabelBranch],
+		PipelineID: pipelineID,
+		TaskID:     taskID,
+		TaskName:   task.Name,
+		Name:       name,
+		ObjectName: objectName,
+		ByteSize:   byteSize,
+		ExpiredAt:  expiredAt,
+	}
+	if err := p.dbClient.SaveArtifact(artifact); err != nil {
+		errorresp.Error(rw, apierrors.ErrUploadPipelineArtifact.InternalError(err))
+		return
+	}
+	p.Log.Infof("artifact uploaded, pipelineID: %d, taskID: %d, name: %s, size: %d", pipelineID, taskID, name, byteSize)
+	httpserver.WriteData(rw, artifact.Convert2PB())
+}
+
+// DownloadArtifact downloads an artifact by id, or by pipelineID and name.
+func (p *provider) DownloadArtifact(rw http.ResponseWriter, r *http.Request) {
+	identityInfo := identityFromRequest(r)
+	if identityInfo == nil {
+		errorresp.Error(rw, apierrors.ErrDownloadPipelineArtifact.NotLogin())
+		return
+	}
+	artifact, err := p.getArtifactToDownload(r)
+	if err != nil {
+		errorresp.Error(rw, err)
+		return
+	}
+	if err := p.checkDownloadPermission(identityInfo, artifact); err != nil {
+		errorresp.Error(rw, err)
+		return
+	}
+	storage, err := p.getStorage()
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrDownloadPipelineArtifact.InternalError(err))
+		return
+	}
+	reader, size, err := storage.DownloadFileStream(p.Cfg.StorageBucket, artifact.ObjectName)
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrDownloadPipelineArtifact.InternalError(err))
+		return
+	}
+	defer reader.Close()
+	rw.Header().Set("Content-Type", "application/gzip")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar.gz", artifact.Name))
+	rw.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	rw.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(rw, reader); err != nil {
+		p.Log.Errorf("failed to write artifact, id: %s, err: %v", artifact.ID, err)
+	}
+}
+
+func (p *provider) getArtifactToDownload(r *http.Request) (*db.PipelineArtifact, error) {
+	var (
+		artifact *db.PipelineArtifact
+		err      error
+	)
+	if id := r.URL.Query().Get("id"); id != "" {
+		artifact, err = p.dbClient.GetArtifact(id)
+	} else {
+		pipelineID, parseErr := strconv.ParseUint(r.URL.Query().Get("pipelineID"), 10, 64)
+		name := r.URL.Query().Get("name")
+		if parseErr != nil || name == "" {
+			return nil, apierrors.ErrDownloadPipelineArtifact.InvalidParameter("id, or pipelineID and name must be specified")
+		}
+		artifact, err = p.dbClient.GetArtifactByName(pipelineID, name)
+	}
+	if err != nil {
+		return nil, apierrors.ErrDownloadPipelineArtifact.InternalError(err)
+	}
+	if artifact == nil || !artifact.ExpiredAt.After(time.Now()) {
+		return nil, apierrors.ErrDownloadPipelineArtifact.NotFound()
+	}
+	return artifact, nil
+}
+
+// checkUploadPermission allows internal clients of the pipeline's org, such as the action-agent with its pipeline signed token,
+// and users who can operate the branch of the pipeline.
+func (p *provider) checkUploadPermission(identityInfo *commonpb.IdentityInfo, labels map[string]string) error {
+	if identityInfo.InternalClient != "" {
+		if identityInfo.OrgID == "" || identityInfo.OrgID != labels[apistructs.LabelOrgID] {
+			return apierrors.ErrUploadPipelineArtifact.AccessDenied()
+		}
+		return nil
+	}
+	if err := p.Permission.CheckBranch(identityInfo, labels[apistructs.LabelAppID], labels[apistructs.LabelBranch], apistructs.OperateAction); err != nil {
+		return apierrors.ErrUploadPipelineArtifact.AccessDenied()
+	}
+	return nil
+}
+
+// checkDownloadPermission allows internal clients of the same org, and users who can read the branch of the pipeline.
+func (p *provider) checkDownloadPermission(identityInfo *commonpb.IdentityInfo, artifact *db.PipelineArtifact) error {
+	if identityInfo.InternalClient != "" {
+		if identityInfo.OrgID == "" || identityInfo.OrgID != strconv.FormatUint(artifact.OrgID, 10) {
+			return apierrors.ErrDownloadPipelineArtifact.AccessDenied()
+		}
+		return nil
+	}
+	if err := p.Permission.CheckBranch(identityInfo, strconv.FormatUint(artifact.AppID, 10), artifact.Branch, apistructs.GetAction); err != nil {
+		return apierrors.ErrDownloadPipelineArtifact.AccessDenied()
+	}
+	return nil
+}
+
+// calcExpiredAt returns the expired time by the declared retention, limited by the max retention.
+func (p *provider) calcExpiredAt(retention string, now time.Time) (time.Time, error) {
+	d, err := pipelineyml.ParseArtifactRetention(retention)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if d == 0 {
+		d = p.Cfg.DefaultRetention
+	}
+	if p.Cfg.MaxRetention > 0 && d > p.Cfg.MaxRetention {
+		d = p.Cfg.MaxRetention
+	}
+	return now.Add(d), nil
+}
+
+func findDeclaredArtifact(artifacts []pipelineyml.ActionArtifact, name string) (pipelineyml.ActionArtifact, bool) {
+	for _, artifact := range artifacts {
+		if artifact.Name == name {
+			return artifact, true
+		}
+	}
+	return pipelineyml.ActionArtifact{}, false
+}
+
+// makeObjectName returns the object name of an artifact, artifact name is unique in a pipeline.
+func makeObjectName(pipelineID uint64, name string) string {
+	return fmt.Sprintf("pipelines/%d/%s.tar.gz", pipelineID, name)
+}
+
+func pathUint64(r *http.Request, key string) (uint64, error) {
+	v, _ := infrahttpserver.Var(r, key)
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid %s: %s", key, v)
+	}
+	return id, nil
+}
+
+// identityFromRequest returns nil if neither user nor internal client found.
+func identityFromRequest(r *http.Request) *commonpb.IdentityInfo {
+	userID := r.Header.Get(httputil.UserHeader)
+	internalClient := r.Header.Get(httputil.InternalHeader)
+	if userID == "" && internalClient == "" {
+		return nil
+	}
+	return &commonpb.IdentityInfo{
+		UserID:         userID,
+		InternalClient: internalClient,
+		OrgID:          r.Header.Get(httputil.OrgHeader),
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifact
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	commonpb "github.com/erda-project/erda-proto-go/common/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/artifact/db"
+	"github.com/erda-project/erda/pkg/http/httputil"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+)
+
+func TestCalcExpiredAt(t *testing.T) {
+	p := &provider{Cfg: &config{DefaultRetention: 7 * 24 * time.Hour, MaxRetention: 30 * 24 * time.Hour}}
+	now := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		retention string
+		want      time.Time
+		wantErr   bool
+	}{
+		{name: "default", retention: "", want: now.Add(7 * 24 * time.Hour)},
+		{name: "days", retention: "3d", want: now.Add(3 * 24 * time.Hour)},
+		{name: "duration", retention: "12h", want: now.Add(12 * time.Hour)},
+		{name: "limited by max retention", retention: "365d", want: now.Add(30 * 24 * time.Hour)},
+		{name: "invalid", retention: "forever", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.calcExpiredAt(tt.retention, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFindDeclaredArtifact(t *testing.T) {
+	artifacts := []pipelineyml.ActionArtifact{{Name: "dist", Paths: []string{"dist/"}}, {Name: "report"}}
+	artifact, ok := findDeclaredArtifact(artifacts, "dist")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"dist/"}, artifact.Paths)
+	_, ok = findDeclaredArtifact(artifacts, "coverage")
+	assert.False(t, ok)
+}
+
+func TestMakeObjectName(t *testing.T) {
+	assert.Equal(t, "pipelines/10000001/dist.tar.gz", makeObjectName(10000001, "dist"))
+}
+
+func TestIdentityFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/pipeline-artifacts/actions/download", nil)
+	assert.Nil(t, identityFromRequest(r))
+
+	r.Header.Set(httputil.UserHeader, "1")
+	r.Header.Set(httputil.OrgHeader, "2")
+	identityInfo := identityFromRequest(r)
+	assert.Equal(t, "1", identityInfo.UserID)
+	assert.Equal(t, "2", identityInfo.OrgID)
+	assert.Empty(t, identityInfo.InternalClient)
+
+	r = httptest.NewRequest("GET", "/api/pipeline-artifacts/actions/download", nil)
+	r.Header.Set(httputil.InternalHeader, "edge-pipeline")
+	assert.Equal(t, "edge-pipeline", identityFromRequest(r).InternalClient)
+}
+
+type branchPermission struct {
+	actions map[string]bool
+}
+
+func (b *branchPermission) CheckInternalClient(identityInfo *commonpb.IdentityInfo) error {
+	return nil
+}
+
+func (b *branchPermission) CheckApp(identityInfo *commonpb.IdentityInfo, appID uint64, action string) error {
+	return nil
+}
+
+func (b *branchPermission) CheckBranch(identityInfo *commonpb.IdentityInfo, appIDStr, branch, action string) error {
+	if !b.actions[action] {
+		return fmt.Errorf("no %s permission", action)
+	}
+	return nil
+}
+
+func TestCheckUploadPermission(t *testing.T) {
+	labels := map[string]string{apistructs.LabelOrgID: "1", apistructs.LabelAppID: "2", apistructs.LabelBranch: "master"}
+
+	p := &provider{Permission: &branchPermission{actions: map[string]bool{apistructs.GetAction: true}}}
+	assert.NoError(t, p.checkUploadPermission(&commonpb.IdentityInfo{InternalClient: "pipeline-signed-openapi-token", OrgID: "1"}, labels))
+	assert.Error(t, p.checkUploadPermission(&commonpb.IdentityInfo{InternalClient: "pipeline-signed-openapi-token", OrgID: "3"}, labels))
+	assert.Error(t, p.checkUploadPermission(&commonpb.IdentityInfo{InternalClient: "pipeline-signed-openapi-token"}, labels))
+	assert.Error(t, p.checkUploadPermission(&commonpb.IdentityInfo{UserID: "4", OrgID: "1"}, labels), "read permission is not enough")
+
+	p = &provider{Permission: &branchPermission{actions: map[string]bool{apistructs.OperateAction: true}}}
+	assert.NoError(t, p.checkUploadPermission(&commonpb.IdentityInfo{UserID: "4", OrgID: "1"}, labels))
+}
+
+func TestCheckDownloadPermission(t *testing.T) {
+	artifact := &db.PipelineArtifact{OrgID: 1, AppID: 2, Branch: "master"}
+	p := &provider{Permission: &branchPermission{actions: map[string]bool{apistructs.GetAction: true}}}
+	assert.NoError(t, p.checkDownloadPermission(&commonpb.IdentityInfo{InternalClient: "edge-pipeline", OrgID: "1"}, artifact))
+	assert.Error(t, p.checkDownloadPermission(&commonpb.IdentityInfo{InternalClient: "edge-pipeline", OrgID: "3"}, artifact))
+	assert.Error(t, p.checkDownloadPermission(&commonpb.IdentityInfo{InternalClient: "edge-pipeline"}, artifact))
+	assert.NoError(t, p.checkDownloadPermission(&commonpb.IdentityInfo{UserID: "4"}, artifact))
+
+	p = &provider{Permission: &branchPermission{}}
+	assert.Error(t, p.checkDownloadPermission(&commonpb.IdentityInfo{UserID: "4"}, artifact))
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifact
+
+import (
+	"context"
+	"time"
+)
+
+type Interface interface {
+	// DeleteExpiredArtifacts deletes artifacts expired before now from object storage and database.
+	DeleteExpiredArtifacts(ctx context.Context, now time.Time) (int, error)
+	// DeletePipelineArtifacts deletes all artifacts of the pipeline, used when the pipeline is deleted.
+	DeletePipelineArtifacts(ctx context.Context, pipelineID uint64) error
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifact
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-infra/pkg/transport"
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda-proto-go/core/pipeline/artifact/pb"
+	"github.com/erda-project/erda/internal/tools/pipeline/dbclient"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/artifact/db"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/permission"
+	"github.com/erda-project/erda/pkg/cloudstorage"
+	"github.com/erda-project/erda/pkg/common/apis"
+)
+
+type config struct {
+	StorageEndpoint  string        `file:"storage_endpoint" env:"PIPELINE_ARTIFACT_STORAGE_ENDPOINT"`
+	StorageAccessKey string        `file:"storage_access_key" env:"PIPELINE_ARTIFACT_STORAGE_ACCESS_KEY"`
+	StorageSecretKey string        `file:"storage_secret_key" env:"PIPELINE_ARTIFACT_STORAGE_SECRET_KEY"`
+	StorageBucket    string        `file:"storage_bucket" env:"PIPELINE_ARTIFACT_STORAGE_BUCKET" default:"pipeline-artifacts"`
+	DefaultRetention time.Duration `file:"default_retention" env:"PIPELINE_ARTIFACT_DEFAULT_RETENTION" default:"168h"`
+	MaxRetention     time.Duration `file:"max_retention" env:"PIPELINE_ARTIFACT_MAX_RETENTION" default:"2160h"`
+	MaxSizeMB        int64         `file:"max_size_mb" env:"PIPELINE_ARTIFACT_MAX_SIZE_MB" default:"1024"`
+	GCBatchSize      int           `file:"gc_batch_size" default:"100"`
+}
+
+// +provider
+type provider struct {
+	Cfg        *config
+	Log        logs.Logger
+	Register   transport.Register
+	MySQL      mysqlxorm.Interface
+	Permission permission.Interface
+
+	dbClient         *db.Client
+	pipelineDBClient *dbclient.Client
+	artifactService  *artifactService
+
+	storageLock sync.Mutex
+	storage     cloudstorage.Client
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.dbClient = &db.Client{Interface: p.MySQL}
+	p.pipelineDBClient = &dbclient.Client{Engine: p.MySQL.DB()}
+	p.artifactService = &artifactService{p: p}
+	if p.Cfg.StorageEndpoint == "" {
+		p.Log.Warnf("pipeline artifact storage endpoint is not configured, artifacts are disabled")
+	}
+	if p.Register != nil {
+		pb.RegisterArtifactServiceImp(p.Register, p.artifactService, apis.Options())
+		p.Register.Add(http.MethodPost, "/api/pipelines/{pipelineID}/tasks/{taskID}/artifacts", p.UploadArtifact)
+		p.Register.Add(http.MethodGet, "/api/pipeline-artifacts/actions/download", p.DownloadArtifact)
+	}
+	return nil
+}
+
+// getStorage connects to object storage lazily, so pipeline can still start when the storage is unavailable.
+func (p *provider) getStorage() (cloudstorage.Client, error) {
+	p.storageLock.Lock()
+	defer p.storageLock.Unlock()
+	if p.storage != nil {
+		return p.storage, nil
+	}
+	if p.Cfg.StorageEndpoint == "" {
+		return nil, errors.New("artifact storage is not configured")
+	}
+	storage, err := cloudstorage.New(p.Cfg.StorageEndpoint, p.Cfg.StorageAccessKey, p.Cfg.StorageSecretKey)
+	if err != nil {
+		return nil, err
+	}
+	p.storage = storage
+	return storage, nil
+}
+
+func (p *provider) Provide(ctx servicehub.DependencyContext, args ...interface{}) interface{} {
+	switch {
+	case ctx.Service() == "erda.core.pipeline.artifact.ArtifactService" || ctx.Type() == pb.ArtifactServiceServerType() || ctx.Type() == pb.ArtifactServiceHandlerType():
+		return p.artifactService
+	}
+	return p
+}
+
+func init() {
+	interfaceType := reflect.TypeOf((*Interface)(nil)).Elem()
+	servicehub.Register("erda.core.pipeline.artifact", &servicehub.Spec{
+		Services:             pb.ServiceNames(),
+		Types:                append([]reflect.Type{interfaceType}, pb.Types()...),
+		OptionalDependencies: []string{"service-register"},
+		Description:          "pipeline artifacts persisted to object storage",
+		ConfigFunc: func() interface{} {
+			return &config{}
+		},
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
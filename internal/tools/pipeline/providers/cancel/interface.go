@@ -28,6 +28,7 @@ import (
 type Interface interface {
 	CancelOnePipeline(ctx context.Context, req *pb.PipelineCancelRequest) error
 	StopRelatedRunningPipelinesOfOnePipeline(ctx context.Context, p *spec.Pipeline, identityInfo *commonpb.IdentityInfo) error
+	SupersedeConcurrencyGroup(ctx context.Context, p *spec.Pipeline, identityInfo *commonpb.IdentityInfo) error
 }
 
 func (s *provider) CancelOnePipeline(ctx context.Context, req *pb.PipelineCancelRequest) error {
@@ -80,3 +81,56 @@ func (s *provider) StopRelatedRunningPipelinesOfOnePipeline(ctx context.Context,
 	}
 	return nil
 }
+
+// SupersedeConcurrencyGroup cancels older pipelines in the same concurrency group of p.
+// Pending pipelines are always superseded, running pipelines are canceled only if cancel_in_progress is set.
+func (s *provider) SupersedeConcurrencyGroup(ctx context.Context, p *spec.Pipeline, identityInfo *commonpb.IdentityInfo) error {
+	if p.Extra.Concurrency == nil {
+		return nil
+	}
+	olderPipelines, err := s.dbClient.ListPipelinesInConcurrencyGroup(p.PipelineSource, p.Extra.Concurrency.Group, apistructs.ReconcilerRunningStatuses())
+	if err != nil {
+		return apierrors.ErrCancelPipeline.InternalError(err)
+	}
+	for _, older := range olderPipelines {
+		if older.ID >= p.ID {
+			continue
+		}
+		if older.Status != apistructs.PipelineStatusQueue && !p.Extra.Concurrency.CancelInProgress {
+			continue
+		}
+		if err := s.supersedeOnePipeline(ctx, older.ID, p.ID, identityInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *provider) supersedeOnePipeline(ctx context.Context, pipelineID, supersededBy uint64, identityInfo *commonpb.IdentityInfo) error {
+	p, err := s.dbClient.GetPipeline(pipelineID)
+	if err != nil {
+		return apierrors.ErrGetPipeline.InternalError(err)
+	}
+	if p.Status.IsEndStatus() {
+		return nil
+	}
+	// pipelines at other statuses like Paused can't be canceled, they still block the group until end
+	if !p.Status.CanCancel() {
+		s.Log.Warnf("skip supersede pipeline in concurrency group, pipelineID: %d, status: %s, supersededBy: %d", p.ID, p.Status, supersededBy)
+		return nil
+	}
+
+	if p.Extra.Concurrency == nil {
+		p.Extra.Concurrency = &spec.ConcurrencyInfo{}
+	}
+	p.Extra.Concurrency.SupersededBy = supersededBy
+	if identityInfo != nil && identityInfo.UserID != "" {
+		p.Extra.CancelUser = s.User.TryGetUser(ctx, identityInfo.UserID)
+	}
+	if err := s.dbClient.UpdatePipelineExtraExtraInfoByPipelineID(p.ID, p.Extra); err != nil {
+		return apierrors.ErrCancelPipeline.InternalError(err)
+	}
+	s.Log.Infof("supersede pipeline in concurrency group: %s, pipelineID: %d, status: %s, supersededBy: %d",
+		p.Extra.Concurrency.Group, p.ID, p.Status, supersededBy)
+	return s.Engine.DistributedStopPipeline(ctx, p.ID)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cron
+
+import (
+	"context"
+
+	"github.com/go-errors/errors"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/cron/pb"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/cron/db"
+	"github.com/erda-project/erda/internal/tools/pipeline/services/apierrors"
+)
+
+func (s *provider) CronCalendarCreate(ctx context.Context, req *pb.CronCalendarCreateRequest) (*pb.CronCalendarCreateResponse, error) {
+	if req.OrgID == 0 {
+		return nil, apierrors.ErrCreatePipelineCronCalendar.InvalidParameter(errors.Errorf("missing orgID"))
+	}
+	if req.Name == "" {
+		return nil, apierrors.ErrCreatePipelineCronCalendar.InvalidParameter(errors.Errorf("missing name"))
+	}
+	calendar := &db.PipelineCronCalendar{
+		OrgID:       req.OrgID,
+		Name:        req.Name,
+		TimeZone:    req.TimeZone,
+		Description: req.Description,
+		Exclusions:  db.ConvertExclusionsFromPB(req.Exclusions),
+	}
+	if req.IdentityInfo != nil {
+		calendar.Creator = req.IdentityInfo.UserID
+	}
+	if _, err := calendar.ToCalendar(); err != nil {
+		return nil, apierrors.ErrCreatePipelineCronCalendar.InvalidParameter(err)
+	}
+
+	_, exist, err := s.dbClient.GetCronCalendarByName(req.OrgID, req.Name)
+	if err != nil {
+		return nil, apierrors.ErrCreatePipelineCronCalendar.InternalError(err)
+	}
+	if exist {
+		return nil, apierrors.ErrCreatePipelineCronCalendar.AlreadyExists()
+	}
+	if err := s.dbClient.CreateCronCalendar(calendar); err != nil {
+		return nil, apierrors.ErrCreatePipelineCronCalendar.InternalError(err)
+	}
+	return &pb.CronCalendarCreateResponse{Data: calendar.Convert2PB()}, nil
+}
+
+func (s *provider) CronCalendarUpdate(ctx context.Context, req *pb.CronCalendarUpdateRequest) (*pb.CronCalendarUpdateResponse, error) {
+	calendar, found, err := s.dbClient.GetCronCalendar(req.CalendarID)
+	if err != nil {
+		return nil, apierrors.ErrUpdatePipelineCronCalendar.InternalError(err)
+	}
+	if !found {
+		return nil, apierrors.ErrUpdatePipelineCronCalendar.NotFound()
+	}
+	calendar.TimeZone = req.TimeZone
+	calendar.Description = req.Description
+	calendar.Exclusions = db.ConvertExclusionsFromPB(req.Exclusions)
+	if _, err := calendar.ToCalendar(); err != nil {
+		return nil, apierrors.ErrUpdatePipelineCronCalendar.InvalidParameter(err)
+	}
+	if err := s.dbClient.UpdateCronCalendar(calendar); err != nil {
+		return nil, apierrors.ErrUpdatePipelineCronCalendar.InternalError(err)
+	}
+	return &pb.CronCalendarUpdateResponse{Data: calendar.Convert2PB()}, nil
+}
+
+func (s *provider) CronCalendarGet(ctx context.Context, req *pb.CronCalendarGetRequest) (*pb.CronCalendarGetResponse, error) {
+	calendar, found, err := s.dbClient.GetCronCalendar(req.CalendarID)
+	if err != nil {
+		return nil, apierrors.ErrGetPipelineCronCalendar.InternalError(err)
+	}
+	if !found {
+		return nil, apierrors.ErrGetPipelineCronCalendar.NotFound()
+	}
+	return &pb.CronCalendarGetResponse{Data: calendar.Convert2PB()}, nil
+}
+
+// CronCalendarDelete deletes the calendar, crons still referencing it are no longer skipped by it.
+func (s *provider) CronCalendarDelete(ctx context.Context, req *pb.CronCalendarDeleteRequest) (*pb.CronCalendarDeleteResponse, error) {
+	if err := s.dbClient.DeleteCronCalendar(req.CalendarID); err != nil {
+		return nil, apierrors.ErrDeletePipelineCronCalendar.InternalError(err)
+	}
+	return &pb.CronCalendarDeleteResponse{}, nil
+}
+
+func (s *provider) CronCalendarPaging(ctx context.Context, req *pb.CronCalendarPagingRequest) (*pb.CronCalendarPagingResponse, error) {
+	if req.OrgID == 0 {
+		return nil, apierrors.ErrPagingPipelineCronCalendar.InvalidParameter(errors.Errorf("missing orgID"))
+	}
+	calendars, total, err := s.dbClient.PagingCronCalendars(req)
+	if err != nil {
+		return nil, apierrors.ErrPagingPipelineCronCalendar.InternalError(err)
+	}
+	data := make([]*pb.CronCalendar, 0, len(calendars))
+	for i := range calendars {
+		data = append(data, calendars[i].Convert2PB())
+	}
+	return &pb.CronCalendarPagingResponse{Total: total, Data: data}, nil
+}
+
+func (s *provider) CronSkippedTriggerPaging(ctx context.Context, req *pb.CronSkippedTriggerPagingRequest) (*pb.CronSkippedTriggerPagingResponse, error) {
+	triggers, total, err := s.dbClient.PagingCronSkippedTriggers(req)
+	if err != nil {
+		return nil, apierrors.ErrPagingPipelineCronSkippedTrigger.InternalError(err)
+	}
+	data := make([]*pb.CronSkippedTrigger, 0, len(triggers))
+	for i := range triggers {
+		data = append(data, triggers[i].Convert2PB())
+	}
+	return &pb.CronSkippedTriggerPagingResponse{Total: total, Data: data}, nil
+}
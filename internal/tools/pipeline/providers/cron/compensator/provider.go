@@ -38,6 +38,7 @@ import (
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/edgepipeline_register"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/leaderworker"
 	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/cron"
 	"github.com/erda-project/erda/pkg/jsonstore"
 	"github.com/erda-project/erda/pkg/limit_sync_group"
 	"github.com/erda-project/erda/pkg/parser/pipelineyml"
@@ -234,7 +235,7 @@ func (p *provider) cronInterruptCompensate(ctx context.Context, pc db.PipelineCr
 	var thisCompensateFromTime = now.Add(time.Second * -time.Duration(conf.CronFailureCreateIntervalCompensateTimeSecond()))
 
 	// Use cron expr to calculate all required trigger times from the starting compensation point
-	needTriggerTimes, err := pipelineyml.ListNextCronTime(pc.CronExpr,
+	needTriggerTimes, err := pipelineyml.ListNextCronTime(pc.GetCronSpec(),
 		pipelineyml.WithCronStartEndTime(&beforeCompensateFromTime, &thisCompensateFromTime),
 		pipelineyml.WithListNextScheduleCount(100),
 	)
@@ -243,6 +244,11 @@ func (p *provider) cronInterruptCompensate(ctx context.Context, pc db.PipelineCr
 	}
 
 	if len(needTriggerTimes) > 0 {
+		calendars, err := p.cronDBClient.GetCronCalendars(&pc)
+		if err != nil {
+			return errors.Errorf("[alert] failed to get cron calendars, cronID: %d, err: %v", pc.ID, err)
+		}
+
 		// Pipeline search record created based on createlyname + source
 		result, err := p.dbClient.PageListPipelines(&pipelinepb.PipelinePagingRequest{
 			Source:           []string{pc.PipelineSource.String()},
@@ -272,6 +278,12 @@ func (p *provider) cronInterruptCompensate(ctx context.Context, pc db.PipelineCr
 				p.Log.Infof("no need do interrupt-compensate, cronID: %d, triggerTime: %v, exist pipelineID: %d", pc.ID, ntt, pipeline.ID)
 				continue
 			}
+			if calendar, exclusion, ok := cron.MatchCalendars(calendars, ntt); ok {
+				p.Log.Infof("no need do interrupt-compensate, cronID: %d, triggerTime: %v, skipped by calendar: %s, exclusion: %s",
+					pc.ID, ntt, calendar.Name, exclusion.Name)
+				p.recordSkippedTrigger(pc, ntt, calendar, exclusion)
+				continue
+			}
 			p.Log.Infof("need do interrupt-compensate, cronID: %d, triggerTime: %v", pc.ID, ntt)
 			// create
 			created, err := p.createCronCompensatePipeline(ctx, pc, ntt)
@@ -300,6 +312,28 @@ func (p *provider) cronInterruptCompensate(ctx context.Context, pc db.PipelineCr
 	return nil
 }
 
+// recordSkippedTrigger records the trigger time skipped while crond was interrupted,
+// those already recorded by crond are ignored.
+func (p *provider) recordSkippedTrigger(pc db.PipelineCron, triggerTime time.Time, calendar *cron.Calendar, exclusion *cron.Exclusion) {
+	skipped, err := p.cronDBClient.IsCronTriggerSkipped(pc.ID, triggerTime)
+	if err != nil {
+		p.Log.Errorf("failed to check skipped trigger, cronID: %d, triggerTime: %v, err: %v", pc.ID, triggerTime, err)
+		return
+	}
+	if skipped {
+		return
+	}
+	if err := p.cronDBClient.CreateCronSkippedTrigger(&db.PipelineCronSkippedTrigger{
+		CronID:      pc.ID,
+		TriggerTime: triggerTime,
+		Calendar:    calendar.Name,
+		Exclusion:   exclusion.Name,
+		Source:      db.SkippedTriggerSourceCompensator,
+	}); err != nil {
+		p.Log.Errorf("failed to record skipped trigger, cronID: %d, triggerTime: %v, err: %v", pc.ID, triggerTime, err)
+	}
+}
+
 func (p *provider) doNonExecuteCompensateByCronID(ctx context.Context, id uint64) error {
 	cron, found, err := p.cronDBClient.GetPipelineCron(id)
 	if err != nil {
@@ -30,6 +30,7 @@ import (
 	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/cron/db"
 	"github.com/erda-project/erda/internal/tools/pipeline/services/apierrors"
+	pkgcron "github.com/erda-project/erda/pkg/cron"
 	"github.com/erda-project/erda/pkg/parser/pipelineyml"
 	"github.com/erda-project/erda/pkg/strutil"
 )
@@ -76,6 +77,7 @@ func (s *provider) CronCreate(ctx context.Context, req *pb.CronCreateRequest) (*
 	if req.CronExpr == "" {
 		req.CronExpr = pipelineYml.Spec().Cron
 	}
+	timeZone, calendars := getCronTimeZoneAndCalendars(pipelineYml.Spec(), req.TimeZone, req.Calendars)
 
 	createCron := &db.PipelineCron{
 		ID:              req.ID,
@@ -100,10 +102,17 @@ func (s *provider) CronCreate(ctx context.Context, req *pb.CronCreateRequest) (*
 			Version:         "v2",
 			Compensator:     compensator,
 			IncomingSecrets: req.IncomingSecrets,
+			TimeZone:        timeZone,
+			Calendars:       calendars,
 		},
 		PipelineDefinitionID: req.PipelineDefinitionID,
 		ClusterName:          req.ClusterName,
 	}
+	if req.CronExpr != "" {
+		if err := s.validateCronCalendars(createCron); err != nil {
+			return nil, apierrors.ErrCreatePipelineCron.InvalidParameter(err)
+		}
+	}
 
 	err = Transaction(s.dbClient, func(op mysqlxorm.SessionOption) error {
 
@@ -361,6 +370,10 @@ func (s *provider) CronUpdate(ctx context.Context, req *pb.CronUpdateRequest) (*
 		}
 	}
 	cron.CronExpr = req.CronExpr
+	cron.Extra.TimeZone, cron.Extra.Calendars = getCronTimeZoneAndCalendars(pipeline.Spec(), req.TimeZone, req.Calendars)
+	if err := s.validateCronCalendars(&cron); err != nil {
+		return nil, apierrors.ErrUpdatePipelineCron.InvalidParameter(err)
+	}
 	cron.Extra.PipelineYml = req.PipelineYml
 	cron.Extra.ConfigManageNamespaces = strutil.DedupSlice(append(cron.Extra.ConfigManageNamespaces, req.ConfigManageNamespaces...), true)
 	cron.Extra.IncomingSecrets = req.Secrets
@@ -578,3 +591,40 @@ func pbCronToDBCron(pbCron *common.Cron) (*db.PipelineCron, error) {
 	}
 	return &result, nil
 }
+
+// getCronTimeZoneAndCalendars returns the time zone and calendars of cron,
+// those of request take precedence over pipeline yml.
+func getCronTimeZoneAndCalendars(spec *pipelineyml.Spec, timeZone string, calendars []string) (string, []string) {
+	if timeZone == "" {
+		timeZone = spec.CronTimeZone
+	}
+	if len(calendars) == 0 {
+		calendars = spec.CronCalendars
+	}
+	return timeZone, strutil.DedupSlice(calendars, true)
+}
+
+// validateCronCalendars checks the time zone and that referenced calendars exist in the org of cron.
+// Calendars are not checked on edge, they are managed by center.
+func (s *provider) validateCronCalendars(pc *db.PipelineCron) error {
+	if _, err := pkgcron.LoadLocation(pc.Extra.TimeZone); err != nil {
+		return err
+	}
+	if len(pc.Extra.Calendars) == 0 || s.EdgePipelineRegister.IsEdge() {
+		return nil
+	}
+	calendars, err := s.dbClient.ListCronCalendarsByNames(pc.GetOrgID(), pc.Extra.Calendars)
+	if err != nil {
+		return err
+	}
+	found := make(map[string]struct{}, len(calendars))
+	for _, calendar := range calendars {
+		found[calendar.Name] = struct{}{}
+	}
+	for _, name := range pc.Extra.Calendars {
+		if _, ok := found[name]; !ok {
+			return errors.Errorf("cron calendar %s not found", name)
+		}
+	}
+	return nil
+}
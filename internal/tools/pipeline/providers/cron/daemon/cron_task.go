@@ -75,6 +75,11 @@ func (d *provider) runCronPipelineFunc(ctx context.Context, id uint64) {
 		return
 	}
 
+	// if trigger time is inside an exclusion of calendars, record it as skipped
+	if d.isCronTriggerExcluded(pc, cronTriggerTime) {
+		return
+	}
+
 	if pc.Extra.NormalLabels == nil {
 		pc.Extra.NormalLabels = make(map[string]string)
 	}
@@ -160,6 +165,33 @@ func (s *provider) isCronShouldBeIgnored(pc db.PipelineCron) bool {
 	return triggerTime.Before(*pc.Extra.CronStartFrom)
 }
 
+func (d *provider) isCronTriggerExcluded(pc db.PipelineCron, triggerTime time.Time) bool {
+	if len(pc.Extra.Calendars) == 0 {
+		return false
+	}
+	calendars, err := d.dbClient.GetCronCalendars(&pc)
+	if err != nil {
+		logrus.Errorf("crond: pipelineCronID: %d, failed to get calendars, err: %v", pc.ID, err)
+		return false
+	}
+	calendar, exclusion, ok := cron.MatchCalendars(calendars, triggerTime)
+	if !ok {
+		return false
+	}
+	logrus.Infof("crond: pipelineCronID: %d, triggered but skipped by calendar: %s, exclusion: %s, triggerTime: %s",
+		pc.ID, calendar.Name, exclusion.Name, triggerTime)
+	if err := d.dbClient.CreateCronSkippedTrigger(&db.PipelineCronSkippedTrigger{
+		CronID:      pc.ID,
+		TriggerTime: triggerTime.Truncate(time.Second),
+		Calendar:    calendar.Name,
+		Exclusion:   exclusion.Name,
+		Source:      db.SkippedTriggerSourceDaemon,
+	}); err != nil {
+		logrus.Errorf("crond: pipelineCronID: %d, failed to record skipped trigger, err: %v", pc.ID, err)
+	}
+	return true
+}
+
 func (d *provider) DoCrondAbout(ctx context.Context) {
 
 	// load cron info
@@ -306,13 +338,13 @@ func (s *provider) reloadCrond(ctx context.Context) ([]string, error) {
 				continue
 			}
 
-			if err = s.crond.AddFunc(pc.CronExpr, func() { s.runCronPipelineFunc(ctx, pc.ID) }, makePipelineCronName(pc.ID)); err != nil {
-				l := fmt.Sprintf("failed to load pipeline cron item: %s, cronExpr: %v, err: %v", makePipelineCronName(pc.ID), pc.CronExpr, err)
+			if err = s.crond.AddFunc(pc.GetCronSpec(), func() { s.runCronPipelineFunc(ctx, pc.ID) }, makePipelineCronName(pc.ID)); err != nil {
+				l := fmt.Sprintf("failed to load pipeline cron item: %s, cronExpr: %v, err: %v", makePipelineCronName(pc.ID), pc.GetCronSpec(), err)
 				logs = append(logs, l)
 				logrus.Errorln("[alert]", l)
 				continue
 			}
-			logs = append(logs, fmt.Sprintf("loaded pipeline cron item: %s, cronExpr: %v", makePipelineCronName(pc.ID), pc.CronExpr))
+			logs = append(logs, fmt.Sprintf("loaded pipeline cron item: %s, cronExpr: %v", makePipelineCronName(pc.ID), pc.GetCronSpec()))
 		}
 	}
 
@@ -365,6 +397,8 @@ func (s *provider) syncCronToEdge(dbCron db.PipelineCron) error {
 			}(),
 			IncomingSecrets:      pc.Extra.IncomingSecrets,
 			PipelineDefinitionID: pc.PipelineDefinitionID,
+			TimeZone:             pc.Extra.TimeZone,
+			Calendars:            pc.Extra.Calendars,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to CreateCron error %v", err)
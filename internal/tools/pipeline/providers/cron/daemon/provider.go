@@ -81,7 +81,7 @@ func (p *provider) AddIntoPipelineCrond(cron *db.PipelineCron) error {
 		return nil
 	}
 
-	_, err := p.EtcdClient.Put(context.Background(), etcdCronPrefixAddKey+strconv.FormatUint(cron.ID, 10), cron.CronExpr)
+	_, err := p.EtcdClient.Put(context.Background(), etcdCronPrefixAddKey+strconv.FormatUint(cron.ID, 10), cron.GetCronSpec())
 	return err
 }
 
@@ -0,0 +1,243 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda-proto-go/core/pipeline/cron/pb"
+	"github.com/erda-project/erda/pkg/cron"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+// PipelineCronCalendar is a named set of exclusions, crons of the same org reference it by name.
+type PipelineCronCalendar struct {
+	ID            string    `json:"id" xorm:"pk"`
+	CreatedAt     time.Time `json:"timeCreated" xorm:"created_at created"`
+	UpdatedAt     time.Time `json:"timeUpdated" xorm:"updated_at updated"`
+	SoftDeletedAt int64     `json:"softDeletedAt"`
+
+	OrgID       uint64                  `json:"orgID"`
+	Name        string                  `json:"name"`
+	TimeZone    string                  `json:"timeZone"`
+	Description string                  `json:"description"`
+	Exclusions  []PipelineCronExclusion `json:"exclusions" xorm:"json"`
+	Creator     string                  `json:"creator"`
+}
+
+// PipelineCronExclusion is either fixed by Start and End, or recurring by CronExpr and Duration.
+type PipelineCronExclusion struct {
+	Name     string `json:"name"`
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+	CronExpr string `json:"cronExpr,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+func (PipelineCronCalendar) TableName() string {
+	return "pipeline_cron_calendars"
+}
+
+// ToCalendar parses exclusions in the time zone of calendar.
+func (c *PipelineCronCalendar) ToCalendar() (cron.Calendar, error) {
+	loc, err := cron.LoadLocation(c.TimeZone)
+	if err != nil {
+		return cron.Calendar{}, err
+	}
+	calendar := cron.Calendar{Name: c.Name, Location: loc}
+	for i, e := range c.Exclusions {
+		name := e.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		var exclusion cron.Exclusion
+		if e.CronExpr != "" {
+			exclusion, err = cron.ParseRecurringExclusion(name, e.CronExpr, e.Duration, loc)
+		} else {
+			exclusion, err = cron.ParseFixedExclusion(name, e.Start, e.End, loc)
+		}
+		if err != nil {
+			return cron.Calendar{}, err
+		}
+		calendar.Exclusions = append(calendar.Exclusions, exclusion)
+	}
+	return calendar, nil
+}
+
+func (c *PipelineCronCalendar) Convert2PB() *pb.CronCalendar {
+	if c == nil {
+		return nil
+	}
+	result := &pb.CronCalendar{
+		ID:          c.ID,
+		OrgID:       c.OrgID,
+		Name:        c.Name,
+		TimeZone:    c.TimeZone,
+		Description: c.Description,
+		Creator:     c.Creator,
+		TimeCreated: timestamppb.New(c.CreatedAt),
+		TimeUpdated: timestamppb.New(c.UpdatedAt),
+	}
+	for _, e := range c.Exclusions {
+		result.Exclusions = append(result.Exclusions, &pb.CronCalendarExclusion{
+			Name:     e.Name,
+			Start:    e.Start,
+			End:      e.End,
+			CronExpr: e.CronExpr,
+			Duration: e.Duration,
+		})
+	}
+	return result
+}
+
+// ConvertExclusionsFromPB converts exclusions of api request.
+func ConvertExclusionsFromPB(exclusions []*pb.CronCalendarExclusion) []PipelineCronExclusion {
+	result := make([]PipelineCronExclusion, 0, len(exclusions))
+	for _, e := range exclusions {
+		if e == nil {
+			continue
+		}
+		result = append(result, PipelineCronExclusion{
+			Name:     e.Name,
+			Start:    e.Start,
+			End:      e.End,
+			CronExpr: e.CronExpr,
+			Duration: e.Duration,
+		})
+	}
+	return result
+}
+
+func (client *Client) CreateCronCalendar(calendar *PipelineCronCalendar, ops ...mysqlxorm.SessionOption) error {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	calendar.ID = uuid.New()
+	if _, err := session.InsertOne(calendar); err != nil {
+		return errors.Wrapf(err, "failed to create cron calendar, orgID: %d, name: %s", calendar.OrgID, calendar.Name)
+	}
+	return nil
+}
+
+func (client *Client) UpdateCronCalendar(calendar *PipelineCronCalendar, ops ...mysqlxorm.SessionOption) error {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	if _, err := session.ID(calendar.ID).Cols("time_zone", "description", "exclusions").Update(calendar); err != nil {
+		return errors.Wrapf(err, "failed to update cron calendar, id: %s", calendar.ID)
+	}
+	return nil
+}
+
+func (client *Client) GetCronCalendar(id string, ops ...mysqlxorm.SessionOption) (*PipelineCronCalendar, bool, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var calendar PipelineCronCalendar
+	found, err := session.Where("id = ? AND soft_deleted_at = 0", id).Get(&calendar)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to get cron calendar, id: %s", id)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return &calendar, true, nil
+}
+
+func (client *Client) GetCronCalendarByName(orgID uint64, name string, ops ...mysqlxorm.SessionOption) (*PipelineCronCalendar, bool, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var calendar PipelineCronCalendar
+	found, err := session.Where("org_id = ? AND name = ? AND soft_deleted_at = 0", orgID, name).Get(&calendar)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to get cron calendar, orgID: %d, name: %s", orgID, name)
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return &calendar, true, nil
+}
+
+func (client *Client) DeleteCronCalendar(id string, ops ...mysqlxorm.SessionOption) error {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	if _, err := session.Table(PipelineCronCalendar{}.TableName()).Where("id = ? AND soft_deleted_at = 0", id).
+		Update(map[string]interface{}{"soft_deleted_at": time.Now().UnixNano() / 1e6}); err != nil {
+		return errors.Wrapf(err, "failed to delete cron calendar, id: %s", id)
+	}
+	return nil
+}
+
+// PagingCronCalendars lists calendars of the org ordered by name.
+func (client *Client) PagingCronCalendars(req *pb.CronCalendarPagingRequest, ops ...mysqlxorm.SessionOption) ([]PipelineCronCalendar, int64, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	session.Where("org_id = ? AND soft_deleted_at = 0", req.OrgID)
+	if req.Name != "" {
+		session.Where("name LIKE ?", "%"+req.Name+"%")
+	}
+	if req.PageNo <= 0 {
+		req.PageNo = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	var calendars []PipelineCronCalendar
+	total, err := session.Asc("name").Limit(int(req.PageSize), int((req.PageNo-1)*req.PageSize)).FindAndCount(&calendars)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to paging cron calendars, orgID: %d", req.OrgID)
+	}
+	return calendars, total, nil
+}
+
+// ListCronCalendarsByNames returns calendars found in the org, missing names are ignored.
+func (client *Client) ListCronCalendarsByNames(orgID uint64, names []string, ops ...mysqlxorm.SessionOption) ([]PipelineCronCalendar, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var calendars []PipelineCronCalendar
+	if err := session.Where("org_id = ? AND soft_deleted_at = 0", orgID).In("name", names).Find(&calendars); err != nil {
+		return nil, errors.Wrapf(err, "failed to list cron calendars, orgID: %d, names: %v", orgID, names)
+	}
+	return calendars, nil
+}
+
+// GetCronCalendars returns parsed calendars referenced by the cron.
+func (client *Client) GetCronCalendars(pc *PipelineCron, ops ...mysqlxorm.SessionOption) ([]cron.Calendar, error) {
+	dbCalendars, err := client.ListCronCalendarsByNames(pc.GetOrgID(), pc.Extra.Calendars, ops...)
+	if err != nil {
+		return nil, err
+	}
+	calendars := make([]cron.Calendar, 0, len(dbCalendars))
+	for i := range dbCalendars {
+		calendar, err := dbCalendars[i].ToCalendar()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cron calendar %s", dbCalendars[i].Name)
+		}
+		calendars = append(calendars, calendar)
+	}
+	return calendars, nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineCron_GetCronSpec(t *testing.T) {
+	pc := PipelineCron{CronExpr: "0 0 8 * * ?"}
+	assert.Equal(t, "0 0 8 * * ?", pc.GetCronSpec())
+
+	pc.Extra.TimeZone = "Asia/Shanghai"
+	assert.Equal(t, "CRON_TZ=Asia/Shanghai 0 0 8 * * ?", pc.GetCronSpec())
+
+	pc.CronExpr = "CRON_TZ=UTC 0 0 8 * * ?"
+	assert.Equal(t, "CRON_TZ=UTC 0 0 8 * * ?", pc.GetCronSpec())
+}
+
+func TestPipelineCronCalendar_ToCalendar(t *testing.T) {
+	c := PipelineCronCalendar{
+		Name:     "release",
+		TimeZone: "UTC",
+		Exclusions: []PipelineCronExclusion{
+			{Name: "national day", Start: "2026-10-01", End: "2026-10-07"},
+			{CronExpr: "0 0 18 * * FRI", Duration: "62h"},
+		},
+	}
+	calendar, err := c.ToCalendar()
+	assert.NoError(t, err)
+	assert.Len(t, calendar.Exclusions, 2)
+	assert.Equal(t, "#1", calendar.Exclusions[1].Name)
+
+	exclusion, ok := calendar.Match(time.Date(2026, 10, 3, 8, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, "national day", exclusion.Name)
+	exclusion, ok = calendar.Match(time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, "#1", exclusion.Name)
+	_, ok = calendar.Match(time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+
+	c.TimeZone = "Nowhere/Land"
+	_, err = c.ToCalendar()
+	assert.Error(t, err)
+
+	c.TimeZone = ""
+	c.Exclusions = []PipelineCronExclusion{{Name: "bad", Start: "2026/10/01"}}
+	_, err = c.ToCalendar()
+	assert.Error(t, err)
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/erda-project/erda-proto-go/core/pipeline/pb"
 	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/cron"
 )
 
 const (
@@ -86,6 +87,16 @@ type PipelineCronExtra struct {
 	Compensator *pb.CronCompensator `json:"compensator,omitempty"`
 	//每次中断补偿执行的时间，下次中断补偿从这个时间开始查询
 	LastCompensateAt *time.Time `json:"lastCompensateAt,omitempty"`
+
+	// TimeZone IANA 时区，CronExpr 在该时区下计算；为空时使用服务端本地时区
+	TimeZone string `json:"timeZone,omitempty"`
+	// Calendars 引用的排除日历名称，触发时间落在排除时段内时跳过并记录
+	Calendars []string `json:"calendars,omitempty"`
+}
+
+// GetCronSpec returns the cron expr prefixed with the time zone of cron.
+func (pc *PipelineCron) GetCronSpec() string {
+	return cron.WithTimeZone(pc.CronExpr, pc.Extra.TimeZone)
 }
 
 func (pc *PipelineCron) GenCompensateCreatePipelineReqNormalLabels(triggerTime time.Time) map[string]string {
@@ -169,6 +180,8 @@ func (pc *PipelineCron) Convert2DTO() *pb.Cron {
 			}
 			return timestamppb.New(*pc.Extra.LastCompensateAt)
 		}(),
+		TimeZone:  pc.Extra.TimeZone,
+		Calendars: pc.Extra.Calendars,
 	}
 	result.Extra = extra
 
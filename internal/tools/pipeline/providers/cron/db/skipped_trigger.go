@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda-proto-go/core/pipeline/cron/pb"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+const (
+	SkippedTriggerSourceDaemon      = "daemon"
+	SkippedTriggerSourceCompensator = "compensator"
+)
+
+// PipelineCronSkippedTrigger records a scheduled run skipped by an exclusion calendar.
+type PipelineCronSkippedTrigger struct {
+	ID        string    `json:"id" xorm:"pk"`
+	CreatedAt time.Time `json:"timeCreated" xorm:"created_at created"`
+	UpdatedAt time.Time `json:"timeUpdated" xorm:"updated_at updated"`
+
+	CronID      uint64    `json:"cronID"`
+	TriggerTime time.Time `json:"triggerTime"`
+	Calendar    string    `json:"calendar"`
+	Exclusion   string    `json:"exclusion"`
+	Source      string    `json:"source"`
+}
+
+func (PipelineCronSkippedTrigger) TableName() string {
+	return "pipeline_cron_skipped_triggers"
+}
+
+func (t *PipelineCronSkippedTrigger) Convert2PB() *pb.CronSkippedTrigger {
+	if t == nil {
+		return nil
+	}
+	return &pb.CronSkippedTrigger{
+		ID:          t.ID,
+		CronID:      t.CronID,
+		TriggerTime: timestamppb.New(t.TriggerTime),
+		Calendar:    t.Calendar,
+		Exclusion:   t.Exclusion,
+		Source:      t.Source,
+		TimeCreated: timestamppb.New(t.CreatedAt),
+	}
+}
+
+func (client *Client) CreateCronSkippedTrigger(trigger *PipelineCronSkippedTrigger, ops ...mysqlxorm.SessionOption) error {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	trigger.ID = uuid.New()
+	if _, err := session.InsertOne(trigger); err != nil {
+		return errors.Wrapf(err, "failed to create cron skipped trigger, cronID: %d", trigger.CronID)
+	}
+	return nil
+}
+
+// PagingCronSkippedTriggers lists skipped triggers of the cron, the latest first.
+func (client *Client) PagingCronSkippedTriggers(req *pb.CronSkippedTriggerPagingRequest, ops ...mysqlxorm.SessionOption) ([]PipelineCronSkippedTrigger, int64, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	if req.PageNo <= 0 {
+		req.PageNo = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	var triggers []PipelineCronSkippedTrigger
+	total, err := session.Where("cron_id = ?", req.CronID).Desc("trigger_time").
+		Limit(int(req.PageSize), int((req.PageNo-1)*req.PageSize)).FindAndCount(&triggers)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to paging cron skipped triggers, cronID: %d", req.CronID)
+	}
+	return triggers, total, nil
+}
+
+// IsCronTriggerSkipped returns true if the trigger time of the cron has been recorded as skipped.
+func (client *Client) IsCronTriggerSkipped(cronID uint64, triggerTime time.Time, ops ...mysqlxorm.SessionOption) (bool, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	exist, err := session.Where("cron_id = ? AND trigger_time = ?", cronID, triggerTime).Exist(&PipelineCronSkippedTrigger{})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check cron skipped trigger, cronID: %d", cronID)
+	}
+	return exist, nil
+}
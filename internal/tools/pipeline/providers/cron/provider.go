@@ -46,6 +46,7 @@ type provider struct {
 func (s *provider) Init(ctx servicehub.Context) error {
 	s.dbClient = &db.Client{Interface: s.MySQL}
 	pb.RegisterCronServiceImp(s.Register, s)
+	pb.RegisterCronCalendarServiceImp(s.Register, s)
 	return nil
 }
 
@@ -56,6 +56,9 @@ func (p *provider) PipelineDatabaseGC(ctx context.Context) {
 		p.doAnalyzedPipelineArchiveGC()
 		p.doNotAnalyzedPipelineArchiveGC()
 
+		// expired artifacts clean up
+		p.doArtifactGC(ctx)
+
 		return rutil.ContinueWorkingWithDefaultInterval
 	}, rutil.WithContinueWorkingDefaultRetryInterval(p.Cfg.PipelineDBGCDuration))
 }
@@ -170,6 +173,19 @@ func (p *provider) doNotAnalyzedPipelineArchiveGC() {
 	}
 }
 
+func (p *provider) doArtifactGC(ctx context.Context) {
+	if p.Artifact == nil {
+		return
+	}
+	deleted, err := p.Artifact.DeleteExpiredArtifacts(ctx, time.Now())
+	if err != nil {
+		p.Log.Errorf("failed to delete expired artifacts, err: %v", err)
+	}
+	if deleted > 0 {
+		p.Log.Infof("expired artifacts deleted, count: %d", deleted)
+	}
+}
+
 func (p *provider) WaitDBGC(pipelineID uint64, ttl uint64, needArchive bool) {
 	var err error
 	defer func() {
@@ -225,6 +241,12 @@ func (p *provider) DoDBGC(pipeline *spec.Pipeline, gcOption apistructs.PipelineG
 			p.Log.Errorf("failed to delete pipeline, id: %d, err: %v", pipeline.ID, err)
 		}
 		p.Log.Debugf("delete pipeline success, id: %d", pipeline.ID)
+		// artifacts of a deleted pipeline can not be downloaded anymore
+		if p.Artifact != nil {
+			if err := p.Artifact.DeletePipelineArtifacts(context.Background(), pipeline.ID); err != nil {
+				p.Log.Errorf("failed to delete pipeline artifacts, id: %d, err: %v", pipeline.ID, err)
+			}
+		}
 	}
 	return nil
 }
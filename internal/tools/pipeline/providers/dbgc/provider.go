@@ -22,6 +22,7 @@ import (
 	"github.com/erda-project/erda-infra/base/servicehub"
 	"github.com/erda-project/erda-infra/providers/mysqlxorm"
 	"github.com/erda-project/erda/internal/tools/pipeline/dbclient"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/artifact"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/dbgc/db"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/dbgc/dbgcconfig"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/leaderworker"
@@ -36,8 +37,9 @@ type provider struct {
 	etcd     *etcd.Store
 	dbClient *db.Client
 
-	MySQL mysqlxorm.Interface
-	LW    leaderworker.Interface
+	MySQL    mysqlxorm.Interface
+	LW       leaderworker.Interface
+	Artifact artifact.Interface `autowired:"erda.core.pipeline.artifact" optional:"true"`
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
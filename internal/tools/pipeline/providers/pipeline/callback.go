@@ -123,13 +123,17 @@ func (s *pipelineService) DealPipelineCallbackOfAction(data []byte) (err error)
 }
 
 func (s *pipelineService) appendPipelineTaskInspect(p *spec.Pipeline, task *spec.PipelineTask, cb apistructs.ActionCallback) error {
-	if cb.MachineStat == nil {
+	if cb.MachineStat == nil && cb.ExitCode == nil {
 		return nil
 	}
 	// machine stat
 	if cb.MachineStat != nil {
 		task.Inspect.MachineStat = cb.MachineStat
 	}
+	// exit code
+	if cb.ExitCode != nil {
+		task.Inspect.ExitCode = cb.ExitCode
+	}
 
 	if err := s.dbClient.UpdatePipelineTaskInspect(task.ID, task.Inspect); err != nil {
 		return err
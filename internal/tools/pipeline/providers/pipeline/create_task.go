@@ -110,6 +110,16 @@ func (s *pipelineService) makeNormalPipelineTask(p *spec.Pipeline, ps *spec.Pipe
 		}
 	}
 
+	// 给 matrix 展开的 task 注入 matrix 变量，如 MATRIX_GO_VERSION
+	if action.MatrixInstance != nil {
+		for name, v := range action.MatrixInstance.Values {
+			if task.Extra.PrivateEnvs == nil {
+				task.Extra.PrivateEnvs = map[string]string{}
+			}
+			task.Extra.PrivateEnvs[pipelineyml.MatrixEnvKey(name)] = v
+		}
+	}
+
 	// applied resources
 	task.Extra.AppliedResources = s.resource.CalculateNormalTaskResources(action, passedDataWhenCreate.GetActionJobDefine(s.actionMgr.MakeActionTypeVersion(action)))
 
@@ -210,6 +220,10 @@ func (s *pipelineService) judgeTaskExecutor(task *spec.PipelineTask, actionSpec
 				kind = spec.PipelineTaskExecutorKindK8sSpark
 			}
 		}
+		// local process executor runs without kubernetes, used by integration tests and small edge nodes
+		if kind == spec.PipelineTaskExecutorKindK8sJob && conf.TaskDefaultExecutorKind() == spec.PipelineTaskExecutorKindMemory.String() {
+			return spec.PipelineTaskExecutorKindMemory, spec.PipelineTaskExecutorNameMemoryDefault
+		}
 		return kind, kind.GenExecutorNameByClusterName(task.Extra.ClusterName)
 	}
 	// if specify executor k8s kind, add the cluster name to executor name
@@ -0,0 +1,242 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	basepb "github.com/erda-project/erda-proto-go/core/pipeline/base/pb"
+	"github.com/erda-project/erda-proto-go/core/pipeline/pipeline/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/conf"
+	"github.com/erda-project/erda/internal/tools/pipeline/services/apierrors"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/common/apis"
+	"github.com/erda-project/erda/pkg/parser/diceyml"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+)
+
+// PipelineDryRun renders the pipeline which would be created by the request, nothing is created or executed.
+func (s *pipelineService) PipelineDryRun(ctx context.Context, req *pb.PipelineCreateRequestV2) (*pb.PipelineDryRunResponse, error) {
+	identityInfo := apis.GetIdentityInfo(ctx)
+	if req.UserID == "" && identityInfo != nil {
+		req.UserID = identityInfo.UserID
+	}
+	if req.InternalClient == "" && identityInfo != nil {
+		req.InternalClient = identityInfo.InternalClient
+	}
+
+	if err := s.ValidateCreateRequest(req); err != nil {
+		return nil, err
+	}
+	setDefault(req)
+
+	p, err := s.MakePipelineFromRequestV2(req)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := s.fetchDryRunSecrets(ctx, p)
+	if err != nil {
+		return nil, apierrors.ErrDryRunPipeline.InternalError(err)
+	}
+
+	result, err := pipelineyml.Render([]byte(p.PipelineYml),
+		pipelineyml.WithEnvs(p.Snapshot.Envs),
+		pipelineyml.WithSecrets(secrets),
+		pipelineyml.WithRunParams(p.Snapshot.RunPipelineParams),
+		pipelineyml.WithActionTypeMapping(conf.ActionTypeMapping()),
+		pipelineyml.WithTriggerLabels(p.Labels),
+		pipelineyml.WithSnippetYmlLoader(func(snippetConfig pipelineyml.SnippetConfig) (string, error) {
+			cfg := pipelineyml.HandleSnippetConfigLabel(&snippetConfig, p.Labels)
+			return s.queryPipelineYAMLBySnippetConfig(&pb.SnippetDetailQuery{
+				Source: cfg.Source,
+				Name:   cfg.Name,
+				Labels: cfg.Labels,
+			})
+		}),
+	)
+	if err != nil {
+		return nil, apierrors.ErrParsePipelineYml.InvalidParameter(err)
+	}
+
+	data, err := s.convertDryRunResult(result)
+	if err != nil {
+		return nil, apierrors.ErrDryRunPipeline.InternalError(err)
+	}
+	return &pb.PipelineDryRunResponse{Data: data}, nil
+}
+
+// fetchDryRunSecrets returns secrets in the same precedence as pipeline run: platform < config manage < request.
+func (s *pipelineService) fetchDryRunSecrets(ctx context.Context, p *spec.Pipeline) (map[string]string, error) {
+	secrets, _, holdOnKeys, _, err := s.secret.FetchSecrets(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	platformSecrets, err := s.secret.FetchPlatformSecrets(ctx, p, holdOnKeys)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(platformSecrets)+len(secrets)+len(p.Extra.IncomingSecrets))
+	for k, v := range platformSecrets {
+		result[k] = v
+	}
+	for k, v := range secrets {
+		result[k] = v
+	}
+	for k, v := range p.Extra.IncomingSecrets {
+		result[k] = v
+	}
+	return result, nil
+}
+
+func (s *pipelineService) convertDryRunResult(result *pipelineyml.RenderResult) (*pb.PipelineDryRunResult, error) {
+	if result == nil {
+		return nil, nil
+	}
+
+	// action defines are used to normalize loop and resources
+	var extItems []string
+	for _, stage := range result.Stages {
+		for _, ra := range stage {
+			if ra.Type == apistructs.ActionTypeSnippet {
+				continue
+			}
+			extItems = append(extItems, s.actionMgr.MakeActionTypeVersion(&pipelineyml.Action{
+				Type:    pipelineyml.ActionType(ra.Type),
+				Version: ra.Version,
+			}))
+		}
+	}
+	actionJobDefines, actionSpecs, err := s.actionMgr.SearchActions(extItems)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &pb.PipelineDryRunResult{
+		Yml:    result.Yml,
+		Graph:  result.Graph,
+		Errors: result.Errors,
+		Warns:  result.Warns,
+	}
+	for _, param := range result.Params {
+		value, err := toStructValue(param.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "param %s", param.Name)
+		}
+		data.Params = append(data.Params, &basepb.PipelineRunParam{Name: param.Name, Value: value})
+	}
+	for _, secret := range result.Secrets {
+		data.Secrets = append(data.Secrets, &pb.PipelineDryRunSecret{Name: secret.Name, Found: secret.Found})
+	}
+	for _, stage := range result.Stages {
+		pbStage := &pb.PipelineDryRunStage{}
+		for _, ra := range stage {
+			action := &pipelineyml.Action{
+				Type:      pipelineyml.ActionType(ra.Type),
+				Version:   ra.Version,
+				Resources: ra.Resources,
+			}
+			typeVersion := s.actionMgr.MakeActionTypeVersion(action)
+			pbAction, err := s.convertDryRunAction(ra, action, actionJobDefines[typeVersion], actionSpecs[typeVersion])
+			if err != nil {
+				return nil, errors.Wrapf(err, "action %s", ra.Alias)
+			}
+			pbStage.Actions = append(pbStage.Actions, pbAction)
+		}
+		data.Stages = append(data.Stages, pbStage)
+	}
+	return data, nil
+}
+
+func (s *pipelineService) convertDryRunAction(ra *pipelineyml.RenderedAction, action *pipelineyml.Action,
+	jobDefine *diceyml.Job, actionSpec *apistructs.ActionSpec) (*pb.PipelineDryRunAction, error) {
+	pbAction := &pb.PipelineDryRunAction{
+		Alias:      ra.Alias,
+		Type:       ra.Type,
+		Version:    ra.Version,
+		Needs:      ra.Needs,
+		Disable:    ra.Disable,
+		If:         ra.If,
+		IfResult:   ra.IfResult,
+		IfMessage:  ra.IfMessage,
+		Image:      ra.Image,
+		Commands:   ra.Commands,
+		Unresolved: ra.Unresolved,
+	}
+	if ra.Params != nil {
+		params, err := toStructValue(ra.Params)
+		if err != nil {
+			return nil, err
+		}
+		pbAction.Params = params.GetStructValue()
+	}
+
+	if ra.Snippet != nil {
+		snippet, err := s.convertDryRunResult(ra.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		pbAction.Snippet = snippet
+		return pbAction, nil
+	}
+	if ra.Type == apistructs.ActionTypeSnippet {
+		return pbAction, nil
+	}
+
+	// timeout, same as task prepare
+	pbAction.TimeoutSec = ra.Timeout
+	if ra.Timeout == 0 || ra.Timeout < -1 {
+		pbAction.TimeoutSec = int64(conf.TaskDefaultTimeout() / time.Second)
+	}
+
+	// loop
+	loop := ra.Loop
+	if loop == nil && actionSpec != nil {
+		loop = pipelineyml.NormalizeLoop(actionSpec.Loop, nil)
+	}
+	if loop != nil {
+		value, err := toStructValue(loop)
+		if err != nil {
+			return nil, err
+		}
+		pbAction.Loop = value
+	}
+
+	// resources
+	resources := s.resource.CalculateNormalTaskResources(action, jobDefine)
+	pbAction.Limits = &pb.PipelineDryRunResource{Cpu: resources.Limits.CPU, MemoryMB: resources.Limits.MemoryMB}
+	pbAction.Requests = &pb.PipelineDryRunResource{Cpu: resources.Requests.CPU, MemoryMB: resources.Requests.MemoryMB}
+
+	return pbAction, nil
+}
+
+// toStructValue converts through json, values parsed from yaml are not always accepted by structpb.NewValue.
+func toStructValue(v interface{}) (*structpb.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	value := &structpb.Value{}
+	if err := value.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -17,6 +17,8 @@ package queuemanager
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
 	commonpb "github.com/erda-project/erda-proto-go/common/pb"
 	"github.com/erda-project/erda-proto-go/core/pipeline/queue/pb"
 	"github.com/erda-project/erda/apistructs"
@@ -341,3 +343,37 @@ func TestPipelineQueueUpdateRequest_Validate(t *testing.T) {
 		})
 	}
 }
+
+func Test_polishQueueFairShare(t *testing.T) {
+	fairShare := apistructs.ScheduleStrategyInsidePipelineQueueOfFairShare.String()
+
+	// only kept for fair share queue
+	fs, err := polishQueueFairShare(apistructs.ScheduleStrategyInsidePipelineQueueOfFIFO.String(), &pb.QueueFairShare{})
+	assert.NoError(t, err)
+	assert.Nil(t, fs)
+
+	// default values
+	fs, err = polishQueueFairShare(fairShare, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.QueueFairShare{
+		TenantBy:      apistructs.PipelineQueueTenantByProject.String(),
+		DefaultWeight: apistructs.PipelineQueueDefaultTenantWeight,
+		StarvationSec: apistructs.PipelineQueueDefaultFairShareStarvationSec,
+	}, fs)
+
+	fs, err = polishQueueFairShare(fairShare, &pb.QueueFairShare{TenantBy: "OWNER", Weights: map[string]float64{"1": 2}, StarvationSec: -1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), fs.StarvationSec)
+
+	// invalid
+	for _, invalid := range []*pb.QueueFairShare{
+		{TenantBy: "ORG"},
+		{DefaultWeight: -1},
+		{Weights: map[string]float64{"1": 0}},
+		{Weights: map[string]float64{"": 1}},
+		{StarvationSec: -2},
+	} {
+		_, err := polishQueueFairShare(fairShare, invalid)
+		assert.Error(t, err)
+	}
+}
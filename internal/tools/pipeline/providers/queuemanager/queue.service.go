@@ -129,6 +129,12 @@ func (p *provider) ValidateQueueCreateRequest(req *pb.QueueCreateRequest) error
 	if req.MaxMemoryMB < 0 {
 		return fmt.Errorf("max memory(MB) must >= 0")
 	}
+	// fair share
+	fairShare, err := polishQueueFairShare(req.ScheduleStrategy, req.FairShare)
+	if err != nil {
+		return err
+	}
+	req.FairShare = fairShare
 	return nil
 }
 
@@ -142,6 +148,55 @@ func (p *provider) ValidateQueueUpdateRequest(req *pb.QueueUpdateRequest) error
 	if req.PipelineSource != "" {
 		return fmt.Errorf("cannot change queue's source")
 	}
+	// fair share
+	if req.ScheduleStrategy != "" && !apistructs.ScheduleStrategyInsidePipelineQueue(req.ScheduleStrategy).IsValid() {
+		return fmt.Errorf("invalid schedule strategy: %s", req.ScheduleStrategy)
+	}
+	fairShare, err := polishQueueFairShare(req.ScheduleStrategy, req.FairShare)
+	if err != nil {
+		return err
+	}
+	req.FairShare = fairShare
 
 	return nil
 }
+
+// polishQueueFairShare validates fair share and fills default values, it's only kept for FAIR_SHARE queue.
+func polishQueueFairShare(strategy string, fs *pb.QueueFairShare) (*pb.QueueFairShare, error) {
+	if !apistructs.ScheduleStrategyInsidePipelineQueue(strategy).IsFairShare() {
+		return nil, nil
+	}
+	if fs == nil {
+		fs = &pb.QueueFairShare{}
+	}
+	// tenantBy
+	if fs.TenantBy == "" {
+		fs.TenantBy = apistructs.PipelineQueueDefaultTenantBy.String()
+	}
+	if !apistructs.PipelineQueueTenantBy(fs.TenantBy).IsValid() {
+		return nil, fmt.Errorf("invalid fair share tenantBy: %s", fs.TenantBy)
+	}
+	// weights
+	if fs.DefaultWeight == 0 {
+		fs.DefaultWeight = apistructs.PipelineQueueDefaultTenantWeight
+	}
+	if fs.DefaultWeight < 0 {
+		return nil, fmt.Errorf("fair share default weight must > 0")
+	}
+	for tenant, weight := range fs.Weights {
+		if tenant == "" {
+			return nil, fmt.Errorf("fair share weight with empty tenant")
+		}
+		if weight <= 0 {
+			return nil, fmt.Errorf("fair share weight of tenant %s must > 0", tenant)
+		}
+	}
+	// starvation
+	if fs.StarvationSec == 0 {
+		fs.StarvationSec = apistructs.PipelineQueueDefaultFairShareStarvationSec
+	}
+	if fs.StarvationSec < -1 {
+		return nil, fmt.Errorf("fair share starvationSec must > 0 or -1")
+	}
+	return fs, nil
+}
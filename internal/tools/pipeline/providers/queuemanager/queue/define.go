@@ -54,6 +54,14 @@ type defaultQueue struct {
 
 	// is updating pending queue
 	updatingPendingQueue bool
+
+	// concurrencyBlockedBy records which pipeline blocks the pending pipeline in the same concurrency group,
+	// use a standalone lock because it's updated when validating under the read lock.
+	concurrencyBlockedBy map[uint64]uint64
+	concurrencyLock      sync.Mutex
+	// concurrencyRunning caches running pipelines of concurrency groups queried from database during a range of pending queue,
+	// it's nil out of ranging.
+	concurrencyRunning map[string][]spec.PipelineBase
 }
 
 func New(pq *pb.Queue, ops ...Option) *defaultQueue {
@@ -63,6 +71,7 @@ func New(pq *pb.Queue, ops ...Option) *defaultQueue {
 		doneChanByPipelineID: make(map[uint64]chan struct{}),
 		pipelineCaches:       make(map[uint64]*spec.Pipeline),
 		rangeAtOnceCh:        make(chan bool),
+		concurrencyBlockedBy: make(map[uint64]uint64),
 	}
 
 	// apply options
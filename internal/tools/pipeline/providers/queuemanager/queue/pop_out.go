@@ -26,6 +26,7 @@ func (q *defaultQueue) PopOutPipeline(p *spec.Pipeline) {
 	q.eq.PopProcessing(makeItemKey(p))
 	// delete from caches
 	delete(q.pipelineCaches, p.ID)
+	q.setConcurrencyBlockedBy(p.ID, 0)
 	// send popped signal to channel
 	ch, ok := q.doneChanByPipelineID[p.ID]
 	if ok {
@@ -51,6 +51,8 @@ func (q *defaultQueue) RangePendingQueue() {
 	}
 	q.setIsRangingPendingQueueFlag()
 	defer q.unsetIsRangingPendingQueueFlag()
+	q.startConcurrencyRunningCache()
+	defer q.stopConcurrencyRunningCache()
 	usage := q.Usage()
 	usageByte, _ := json.Marshal(&usage)
 	logrus.Debugf("queueManager: queueID: %s, queueName: %s, usage: %s", q.ID(), q.pq.Name, string(usageByte))
@@ -62,6 +64,9 @@ func (q *defaultQueue) RangePendingQueue() {
 			q.unsetNeedReRangePendingQueueFlag()
 		}
 	}()
+	// tenants which have pipelines not popped because of fair share in this round,
+	// in strict mode later pipelines of these tenants are skipped to keep order inside tenant.
+	fairShareBlockedTenants := make(map[string]struct{})
 	// TODO: query items every cycle instead of using original passed range, support items priority swap
 	q.eq.PendingQueue().Range(func(item priorityqueue.Item) (stopRange bool) {
 		// fast reRange
@@ -102,6 +107,27 @@ func (q *defaultQueue) RangePendingQueue() {
 			return q.IsStrictMode()
 		}
 
+		// fair share, pipelines of other tenants are still checked even in strict mode
+		if q.IsFairShare() {
+			strictMode := q.IsStrictMode()
+			q.lock.RLock()
+			tenant := getTenant(q.fairShare(), p)
+			if _, blocked := fairShareBlockedTenants[tenant]; blocked && strictMode {
+				validateResult = apistructs.PipelineQueueValidateResult{
+					Success: false,
+					Reason:  fmt.Sprintf("waiting for earlier pipelines of tenant %q", tenant),
+				}
+			} else {
+				validateResult = q.ValidateFairShare(p)
+			}
+			q.lock.RUnlock()
+			if !validateResult.Success {
+				fairShareBlockedTenants[tenant] = struct{}{}
+				q.emitEvent(p, PendingQueueValidate, validateResult.Reason, events.EventLevelWarning)
+				return false
+			}
+		}
+
 		// precheck before run
 		customKVsOfAOP := map[interface{}]interface{}{}
 		ctx := aop.NewContextForPipeline(*p, aoptypes.TuneTriggerPipelineInQueuePrecheckBeforePop, customKVsOfAOP)
@@ -21,6 +21,7 @@ import (
 
 	"github.com/erda-project/erda-proto-go/core/pipeline/queue/pb"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/queuemanager/pkg/queue/priorityqueue"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
 	"github.com/erda-project/erda/pkg/numeral"
 )
 
@@ -28,6 +29,15 @@ func (q *defaultQueue) Usage() pb.QueueUsage {
 	q.lock.RLock()
 	defer q.lock.RUnlock()
 
+	fs := q.fairShare()
+	isFairShare := q.IsFairShare()
+	getTenantIfFairShare := func(p *spec.Pipeline) string {
+		if !isFairShare {
+			return ""
+		}
+		return getTenant(fs, p)
+	}
+
 	// processing
 	var (
 		inUseCPU          float64
@@ -50,6 +60,8 @@ func (q *defaultQueue) Usage() pb.QueueUsage {
 			Index:            int64(item.Index()),
 			Priority:         item.Priority(),
 			AddedTime:        timestamppb.New(item.CreationTime()),
+			ConcurrencyGroup: getConcurrencyGroup(existP),
+			Tenant:           getTenantIfFairShare(existP),
 		})
 		return false
 	})
@@ -64,12 +76,15 @@ func (q *defaultQueue) Usage() pb.QueueUsage {
 		}
 		resources := existP.GetPipelineAppliedResources()
 		pendingDetails = append(pendingDetails, &pb.QueueUsageItem{
-			PipelineID:       pipelineID,
-			RequestsCPU:      resources.Requests.CPU,
-			RequestsMemoryMB: resources.Requests.MemoryMB,
-			Index:            int64(item.Index()),
-			Priority:         item.Priority(),
-			AddedTime:        timestamppb.New(time.Now()),
+			PipelineID:           pipelineID,
+			RequestsCPU:          resources.Requests.CPU,
+			RequestsMemoryMB:     resources.Requests.MemoryMB,
+			Index:                int64(item.Index()),
+			Priority:             item.Priority(),
+			AddedTime:            timestamppb.New(time.Now()),
+			ConcurrencyGroup:     getConcurrencyGroup(existP),
+			ConcurrencyBlockedBy: q.getConcurrencyBlockedBy(pipelineID),
+			Tenant:               getTenantIfFairShare(existP),
 		})
 		return false
 	})
@@ -83,5 +98,13 @@ func (q *defaultQueue) Usage() pb.QueueUsage {
 		PendingCount:      int64(len(pendingDetails)),
 		ProcessingDetails: processingDetails,
 		PendingDetails:    pendingDetails,
+		TenantUsages:      q.tenantUsagesPB(),
+	}
+}
+
+func getConcurrencyGroup(p *spec.Pipeline) string {
+	if p.Extra.Concurrency == nil {
+		return ""
 	}
+	return p.Extra.Concurrency.Group
 }
@@ -0,0 +1,139 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"fmt"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/queuemanager/pkg/queue/priorityqueue"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/queuemanager/types"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+// concurrencyBlockingStatuses are statuses of pipelines which block other pipelines in the same concurrency group.
+// Pipelines at Queue status are still waiting, so they don't block others.
+func concurrencyBlockingStatuses() []apistructs.PipelineStatus {
+	var statuses []apistructs.PipelineStatus
+	for _, status := range apistructs.ReconcilerRunningStatuses() {
+		if status != apistructs.PipelineStatusQueue {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// ValidateConcurrencyGroup makes pipelines in the same concurrency group run one at a time.
+// A group may cross queues, so besides processing pipelines of this queue, running pipelines are queried from database.
+func (q *defaultQueue) ValidateConcurrencyGroup(tryPopP *spec.Pipeline) apistructs.PipelineQueueValidateResult {
+	if tryPopP.Extra.Concurrency == nil {
+		return types.SuccessValidateResult
+	}
+	group := tryPopP.Extra.Concurrency.Group
+
+	// processing pipelines of this queue, their status may not be updated to Running yet
+	var blockedBy uint64
+	q.eq.ProcessingQueue().Range(func(item priorityqueue.Item) (stopRange bool) {
+		pipelineID := parsePipelineIDFromQueueItem(item)
+		existP := q.pipelineCaches[pipelineID]
+		if existP == nil || existP.ID == tryPopP.ID || existP.Extra.Concurrency == nil {
+			return false
+		}
+		if existP.PipelineSource == tryPopP.PipelineSource && existP.Extra.Concurrency.Group == group {
+			blockedBy = existP.ID
+			return true
+		}
+		return false
+	})
+
+	// running pipelines of other queues or without queue
+	if blockedBy == 0 && q.dbClient != nil {
+		runningPipelines, err := q.listRunningInConcurrencyGroup(tryPopP.PipelineSource, group)
+		if err != nil {
+			return apistructs.PipelineQueueValidateResult{
+				Success: false,
+				Reason:  fmt.Sprintf("failed to query running pipelines in concurrency group %q, err: %v", group, err),
+			}
+		}
+		for _, running := range runningPipelines {
+			if running.ID != tryPopP.ID {
+				blockedBy = running.ID
+				break
+			}
+		}
+	}
+
+	q.setConcurrencyBlockedBy(tryPopP.ID, blockedBy)
+	if blockedBy > 0 {
+		return apistructs.PipelineQueueValidateResult{
+			Success: false,
+			Reason:  fmt.Sprintf("waiting for pipeline %d in the same concurrency group %q", blockedBy, group),
+		}
+	}
+	return types.SuccessValidateResult
+}
+
+// listRunningInConcurrencyGroup queries running pipelines of the group from database,
+// the result is reused in the same range of pending queue.
+func (q *defaultQueue) listRunningInConcurrencyGroup(source apistructs.PipelineSource, group string) ([]spec.PipelineBase, error) {
+	key := string(source) + "/" + group
+	q.concurrencyLock.Lock()
+	running, ok := q.concurrencyRunning[key]
+	q.concurrencyLock.Unlock()
+	if ok {
+		return running, nil
+	}
+	running, err := q.dbClient.ListPipelinesInConcurrencyGroup(source, group, concurrencyBlockingStatuses())
+	if err != nil {
+		return nil, err
+	}
+	q.concurrencyLock.Lock()
+	defer q.concurrencyLock.Unlock()
+	if q.concurrencyRunning != nil {
+		q.concurrencyRunning[key] = running
+	}
+	return running, nil
+}
+
+func (q *defaultQueue) startConcurrencyRunningCache() {
+	q.concurrencyLock.Lock()
+	defer q.concurrencyLock.Unlock()
+	q.concurrencyRunning = make(map[string][]spec.PipelineBase)
+}
+
+func (q *defaultQueue) stopConcurrencyRunningCache() {
+	q.concurrencyLock.Lock()
+	defer q.concurrencyLock.Unlock()
+	q.concurrencyRunning = nil
+}
+
+func (q *defaultQueue) setConcurrencyBlockedBy(pipelineID, blockedBy uint64) {
+	q.concurrencyLock.Lock()
+	defer q.concurrencyLock.Unlock()
+	if blockedBy == 0 {
+		delete(q.concurrencyBlockedBy, pipelineID)
+		return
+	}
+	if q.concurrencyBlockedBy == nil {
+		q.concurrencyBlockedBy = make(map[uint64]uint64)
+	}
+	q.concurrencyBlockedBy[pipelineID] = blockedBy
+}
+
+func (q *defaultQueue) getConcurrencyBlockedBy(pipelineID uint64) uint64 {
+	q.concurrencyLock.Lock()
+	defer q.concurrencyLock.Unlock()
+	return q.concurrencyBlockedBy[pipelineID]
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"bou.ke/monkey"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/queue/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/dbclient"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+func TestValidateConcurrencyGroup(t *testing.T) {
+	q := New(&pb.Queue{ID: 1, Concurrency: 10})
+	newPipeline := func(id uint64, group string) *spec.Pipeline {
+		p := &spec.Pipeline{PipelineBase: spec.PipelineBase{ID: id, PipelineSource: apistructs.PipelineSourceDice}}
+		if group != "" {
+			p.Extra.Concurrency = &spec.ConcurrencyInfo{Group: group}
+		}
+		return p
+	}
+	running := newPipeline(1, "1-master")
+	q.pipelineCaches[running.ID] = running
+	q.eq.Add(makeItemKey(running), 0, time.Now())
+	assert.Equal(t, makeItemKey(running), q.eq.PopPending())
+
+	// same group, blocked
+	result := q.ValidateConcurrencyGroup(newPipeline(2, "1-master"))
+	assert.False(t, result.Success)
+	assert.Equal(t, uint64(1), q.getConcurrencyBlockedBy(2))
+
+	// another group
+	assert.True(t, q.ValidateConcurrencyGroup(newPipeline(3, "1-develop")).Success)
+	assert.Equal(t, uint64(0), q.getConcurrencyBlockedBy(3))
+
+	// no group
+	assert.True(t, q.ValidateConcurrencyGroup(newPipeline(4, "")).Success)
+
+	// running one popped out
+	q.PopOutPipeline(running)
+	assert.True(t, q.ValidateConcurrencyGroup(newPipeline(2, "1-master")).Success)
+	assert.Equal(t, uint64(0), q.getConcurrencyBlockedBy(2))
+}
+
+func TestValidateConcurrencyGroup_RunningCachedInRange(t *testing.T) {
+	db := &dbclient.Client{}
+	queried := 0
+	pm := monkey.PatchInstanceMethod(reflect.TypeOf(db), "ListPipelinesInConcurrencyGroup",
+		func(_ *dbclient.Client, source apistructs.PipelineSource, group string, statuses []apistructs.PipelineStatus, ops ...dbclient.SessionOption) ([]spec.PipelineBase, error) {
+			queried++
+			return []spec.PipelineBase{{ID: 1}}, nil
+		})
+	defer pm.Unpatch()
+	q := New(&pb.Queue{ID: 1, Concurrency: 10}, WithDBClient(db))
+	newPipeline := func(id uint64) *spec.Pipeline {
+		p := &spec.Pipeline{PipelineBase: spec.PipelineBase{ID: id, PipelineSource: apistructs.PipelineSourceDice}}
+		p.Extra.Concurrency = &spec.ConcurrencyInfo{Group: "1-master"}
+		return p
+	}
+
+	// out of ranging, always queried
+	assert.False(t, q.ValidateConcurrencyGroup(newPipeline(2)).Success)
+	assert.False(t, q.ValidateConcurrencyGroup(newPipeline(3)).Success)
+	assert.Equal(t, 2, queried)
+
+	// queried once in a range
+	q.startConcurrencyRunningCache()
+	assert.False(t, q.ValidateConcurrencyGroup(newPipeline(2)).Success)
+	assert.False(t, q.ValidateConcurrencyGroup(newPipeline(3)).Success)
+	assert.Equal(t, uint64(1), q.getConcurrencyBlockedBy(3))
+	assert.Equal(t, 3, queried)
+	q.stopConcurrencyRunningCache()
+
+	assert.False(t, q.ValidateConcurrencyGroup(newPipeline(2)).Success)
+	assert.Equal(t, 4, queried)
+}
+
+func Test_concurrencyBlockingStatuses(t *testing.T) {
+	statuses := concurrencyBlockingStatuses()
+	assert.NotContains(t, statuses, apistructs.PipelineStatusQueue)
+	assert.Contains(t, statuses, apistructs.PipelineStatusRunning)
+	assert.Contains(t, statuses, apistructs.PipelineStatusCanceling)
+}
@@ -0,0 +1,255 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/queue/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/queuemanager/pkg/queue/priorityqueue"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/queuemanager/types"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// tenantUsage is the usage and fair share of one tenant inside a FAIR_SHARE queue.
+type tenantUsage struct {
+	tenant string
+	weight float64
+
+	processingCount int64
+	pendingCount    int64
+	inUseCPU        float64
+	inUseMemoryMB   float64
+
+	shareConcurrency float64
+	shareCPU         float64
+	shareMemoryMB    float64
+
+	// starvingPipelineIDs are pending pipelines which wait longer than starvation threshold
+	starvingPipelineIDs []uint64
+}
+
+// canRunMore means tenant has not used up its share yet, a tenant without processing pipelines is
+// always able to run one, otherwise pipelines larger than the share would never run.
+func (u *tenantUsage) canRunMore(cpu, memoryMB float64) bool {
+	if u.processingCount == 0 {
+		return true
+	}
+	concurrency := math.Max(1, math.Floor(u.shareConcurrency))
+	return float64(u.processingCount+1) <= concurrency &&
+		u.inUseCPU+cpu <= u.shareCPU &&
+		u.inUseMemoryMB+memoryMB <= u.shareMemoryMB
+}
+
+// isBorrowing means tenant uses more than its share.
+func (u *tenantUsage) isBorrowing() bool {
+	concurrency := math.Max(1, math.Floor(u.shareConcurrency))
+	return u.processingCount > 1 &&
+		(float64(u.processingCount) > concurrency || u.inUseCPU > u.shareCPU || u.inUseMemoryMB > u.shareMemoryMB)
+}
+
+func (q *defaultQueue) IsFairShare() bool {
+	return apistructs.ScheduleStrategyInsidePipelineQueue(q.pq.ScheduleStrategy).IsFairShare()
+}
+
+// fairShare returns the fair share config of queue, default values are used if not set.
+func (q *defaultQueue) fairShare() *pb.QueueFairShare {
+	fs := &pb.QueueFairShare{
+		TenantBy:      apistructs.PipelineQueueDefaultTenantBy.String(),
+		DefaultWeight: apistructs.PipelineQueueDefaultTenantWeight,
+		StarvationSec: apistructs.PipelineQueueDefaultFairShareStarvationSec,
+	}
+	if q.pq.FairShare == nil {
+		return fs
+	}
+	fs.Weights = q.pq.FairShare.Weights
+	fs.DisableBorrowing = q.pq.FairShare.DisableBorrowing
+	if q.pq.FairShare.TenantBy != "" {
+		fs.TenantBy = q.pq.FairShare.TenantBy
+	}
+	if q.pq.FairShare.DefaultWeight > 0 {
+		fs.DefaultWeight = q.pq.FairShare.DefaultWeight
+	}
+	if q.pq.FairShare.StarvationSec != 0 {
+		fs.StarvationSec = q.pq.FairShare.StarvationSec
+	}
+	return fs
+}
+
+// getTenant returns projectID or owner of pipeline according to queue fair share config.
+func getTenant(fs *pb.QueueFairShare, p *spec.Pipeline) string {
+	switch apistructs.PipelineQueueTenantBy(fs.TenantBy) {
+	case apistructs.PipelineQueueTenantByOwner:
+		return p.GetUserID()
+	default:
+		return p.GetLabel(apistructs.LabelProjectID)
+	}
+}
+
+func isStarving(fs *pb.QueueFairShare, pendingSince time.Time) bool {
+	if fs.StarvationSec < 0 {
+		return false
+	}
+	return time.Since(pendingSince) >= time.Duration(fs.StarvationSec)*time.Second
+}
+
+// calculateTenantUsages calculates usage and share of tenants which have processing or pending pipelines,
+// must be called with queue lock held.
+func (q *defaultQueue) calculateTenantUsages() map[string]*tenantUsage {
+	fs := q.fairShare()
+	usages := make(map[string]*tenantUsage)
+	getUsage := func(p *spec.Pipeline) *tenantUsage {
+		tenant := getTenant(fs, p)
+		u, ok := usages[tenant]
+		if !ok {
+			u = &tenantUsage{tenant: tenant, weight: fs.DefaultWeight}
+			if weight, ok := fs.Weights[tenant]; ok && weight > 0 {
+				u.weight = weight
+			}
+			usages[tenant] = u
+		}
+		return u
+	}
+	q.eq.ProcessingQueue().Range(func(item priorityqueue.Item) (stopRange bool) {
+		p := q.pipelineCaches[parsePipelineIDFromQueueItem(item)]
+		if p == nil {
+			return false
+		}
+		u := getUsage(p)
+		resources := p.GetPipelineAppliedResources()
+		u.processingCount++
+		u.inUseCPU += resources.Requests.CPU
+		u.inUseMemoryMB += resources.Requests.MemoryMB
+		return false
+	})
+	q.eq.PendingQueue().Range(func(item priorityqueue.Item) (stopRange bool) {
+		p := q.pipelineCaches[parsePipelineIDFromQueueItem(item)]
+		if p == nil {
+			return false
+		}
+		u := getUsage(p)
+		u.pendingCount++
+		if isStarving(fs, item.CreationTime()) && q.getConcurrencyBlockedBy(p.ID) == 0 {
+			u.starvingPipelineIDs = append(u.starvingPipelineIDs, p.ID)
+		}
+		return false
+	})
+
+	// share among active tenants
+	var totalWeight float64
+	for _, u := range usages {
+		totalWeight += u.weight
+	}
+	for _, u := range usages {
+		ratio := u.weight / totalWeight
+		u.shareConcurrency = ratio * float64(q.pq.Concurrency)
+		u.shareCPU = ratio * q.pq.MaxCPU
+		u.shareMemoryMB = ratio * q.pq.MaxMemoryMB
+	}
+	return usages
+}
+
+// ValidateFairShare divides queue capacity between tenants by weights:
+//   - a pipeline pending longer than starvation threshold ignores shares, and holds capacity from other tenants;
+//   - a tenant can always run within its share;
+//   - a tenant can borrow idle share only when no other waiting tenant is under its share.
+func (q *defaultQueue) ValidateFairShare(tryPopP *spec.Pipeline) apistructs.PipelineQueueValidateResult {
+	if !q.IsFairShare() {
+		return types.SuccessValidateResult
+	}
+	fs := q.fairShare()
+	tenant := getTenant(fs, tryPopP)
+	usages := q.calculateTenantUsages()
+	u, ok := usages[tenant]
+	if !ok {
+		return types.SuccessValidateResult
+	}
+
+	// starvation protection
+	for _, starvingID := range u.starvingPipelineIDs {
+		if starvingID == tryPopP.ID {
+			return types.SuccessValidateResult
+		}
+	}
+	for _, other := range sortedTenantUsages(usages) {
+		if other.tenant != tenant && len(other.starvingPipelineIDs) > 0 {
+			return apistructs.PipelineQueueValidateResult{
+				Success: false,
+				Reason: fmt.Sprintf("waiting for starving pipeline %d of tenant %q, which pends more than %ds",
+					other.starvingPipelineIDs[0], other.tenant, fs.StarvationSec),
+			}
+		}
+	}
+
+	// within share
+	resources := tryPopP.GetPipelineAppliedResources()
+	if u.canRunMore(resources.Requests.CPU, resources.Requests.MemoryMB) {
+		return types.SuccessValidateResult
+	}
+	reason := fmt.Sprintf("tenant %q used up its fair share(weight: %s, concurrency: %d/%.2f, cpu: %.2f/%.2f, memory: %.2fMB/%.2fMB)",
+		tenant, strutil.String(u.weight), u.processingCount, u.shareConcurrency,
+		u.inUseCPU, u.shareCPU, u.inUseMemoryMB, u.shareMemoryMB)
+
+	// borrow idle share
+	if fs.DisableBorrowing {
+		return apistructs.PipelineQueueValidateResult{Success: false, Reason: reason + ", borrowing is disabled"}
+	}
+	for _, other := range sortedTenantUsages(usages) {
+		if other.tenant != tenant && other.pendingCount > 0 && other.canRunMore(0, 0) {
+			return apistructs.PipelineQueueValidateResult{
+				Success: false,
+				Reason:  fmt.Sprintf("%s, cannot borrow because tenant %q is waiting under its share", reason, other.tenant),
+			}
+		}
+	}
+	return types.SuccessValidateResult
+}
+
+func sortedTenantUsages(usages map[string]*tenantUsage) []*tenantUsage {
+	result := make([]*tenantUsage, 0, len(usages))
+	for _, u := range usages {
+		result = append(result, u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].tenant < result[j].tenant })
+	return result
+}
+
+// tenantUsagesPB converts tenant usages for queue usage api, must be called with queue lock held.
+func (q *defaultQueue) tenantUsagesPB() []*pb.QueueTenantUsage {
+	if !q.IsFairShare() {
+		return nil
+	}
+	var result []*pb.QueueTenantUsage
+	for _, u := range sortedTenantUsages(q.calculateTenantUsages()) {
+		result = append(result, &pb.QueueTenantUsage{
+			Tenant:           u.tenant,
+			Weight:           u.weight,
+			ShareConcurrency: u.shareConcurrency,
+			ShareCPU:         u.shareCPU,
+			ShareMemoryMB:    u.shareMemoryMB,
+			ProcessingCount:  u.processingCount,
+			PendingCount:     u.pendingCount,
+			InUseCPU:         u.inUseCPU,
+			InUseMemoryMB:    u.inUseMemoryMB,
+			Borrowing:        u.isBorrowing(),
+		})
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/queue/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+func newFairShareQueue(concurrency int64, fs *pb.QueueFairShare) *defaultQueue {
+	return New(&pb.Queue{
+		ID:               1,
+		ScheduleStrategy: apistructs.ScheduleStrategyInsidePipelineQueueOfFairShare.String(),
+		Concurrency:      concurrency,
+		MaxCPU:           100,
+		MaxMemoryMB:      102400,
+		FairShare:        fs,
+	})
+}
+
+func addFairSharePipeline(q *defaultQueue, id uint64, projectID string, processing bool, addedTime time.Time) *spec.Pipeline {
+	p := &spec.Pipeline{
+		PipelineBase: spec.PipelineBase{ID: id, PipelineSource: apistructs.PipelineSourceDice},
+		Labels:       map[string]string{apistructs.LabelProjectID: projectID},
+	}
+	p.Snapshot.AppliedResources.Requests = apistructs.PipelineAppliedResource{CPU: 1, MemoryMB: 1024}
+	q.pipelineCaches[p.ID] = p
+	q.eq.Add(makeItemKey(p), 0, addedTime)
+	if processing {
+		q.eq.PopPendingKey(makeItemKey(p))
+	}
+	return p
+}
+
+func TestValidateFairShare(t *testing.T) {
+	now := time.Now()
+
+	t.Run("not fair share queue", func(t *testing.T) {
+		q := New(&pb.Queue{ID: 1, Concurrency: 2})
+		addFairSharePipeline(q, 1, "1", true, now)
+		addFairSharePipeline(q, 2, "1", true, now)
+		assert.True(t, q.ValidateFairShare(addFairSharePipeline(q, 3, "1", false, now)).Success)
+	})
+
+	t.Run("within share and borrowing", func(t *testing.T) {
+		q := newFairShareQueue(6, nil)
+		addFairSharePipeline(q, 1, "1", true, now)
+		addFairSharePipeline(q, 2, "2", true, now)
+		addFairSharePipeline(q, 3, "2", true, now)
+		addFairSharePipeline(q, 4, "2", true, now)
+		p5 := addFairSharePipeline(q, 5, "2", false, now)
+		// project 2 used up its share(3), but project 1 has no pending pipeline, so it can borrow
+		assert.True(t, q.ValidateFairShare(p5).Success)
+
+		// project 1 is waiting under its share, no borrowing
+		p6 := addFairSharePipeline(q, 6, "1", false, now)
+		result := q.ValidateFairShare(p5)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Reason, `tenant "1" is waiting under its share`)
+		assert.True(t, q.ValidateFairShare(p6).Success)
+	})
+
+	t.Run("borrowing disabled", func(t *testing.T) {
+		q := newFairShareQueue(2, &pb.QueueFairShare{DisableBorrowing: true})
+		addFairSharePipeline(q, 1, "1", true, now)
+		addFairSharePipeline(q, 2, "2", false, now)
+		addFairSharePipeline(q, 3, "2", true, now)
+		p4 := addFairSharePipeline(q, 4, "1", false, now)
+		result := q.ValidateFairShare(p4)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Reason, "borrowing is disabled")
+	})
+
+	t.Run("weights", func(t *testing.T) {
+		q := newFairShareQueue(4, &pb.QueueFairShare{Weights: map[string]float64{"1": 3}})
+		addFairSharePipeline(q, 1, "1", true, now)
+		addFairSharePipeline(q, 2, "1", true, now)
+		addFairSharePipeline(q, 3, "2", false, now)
+		// share of project 1 is 3
+		assert.True(t, q.ValidateFairShare(addFairSharePipeline(q, 4, "1", false, now)).Success)
+		q.eq.PopPendingKey("4")
+		assert.False(t, q.ValidateFairShare(addFairSharePipeline(q, 5, "1", false, now)).Success)
+	})
+
+	t.Run("starvation", func(t *testing.T) {
+		q := newFairShareQueue(4, &pb.QueueFairShare{StarvationSec: 60})
+		addFairSharePipeline(q, 1, "1", true, now)
+		addFairSharePipeline(q, 2, "1", true, now)
+		starving := addFairSharePipeline(q, 3, "1", false, now.Add(-time.Hour))
+		addFairSharePipeline(q, 4, "2", true, now)
+		p5 := addFairSharePipeline(q, 5, "2", false, now)
+		// starving pipeline ignores shares
+		assert.True(t, q.ValidateFairShare(starving).Success)
+		// other tenants wait for the starving pipeline
+		result := q.ValidateFairShare(p5)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Reason, "waiting for starving pipeline 3")
+
+		// starvation protection disabled
+		q.pq.FairShare.StarvationSec = -1
+		assert.True(t, q.ValidateFairShare(p5).Success)
+	})
+}
+
+func TestFairShareUsage(t *testing.T) {
+	q := newFairShareQueue(4, &pb.QueueFairShare{Weights: map[string]float64{"1": 3}})
+	addFairSharePipeline(q, 1, "1", true, time.Now())
+	addFairSharePipeline(q, 2, "2", true, time.Now())
+	addFairSharePipeline(q, 3, "2", true, time.Now())
+	addFairSharePipeline(q, 4, "2", false, time.Now())
+
+	usage := q.Usage()
+	for _, item := range usage.ProcessingDetails {
+		if item.PipelineID == 1 {
+			assert.Equal(t, "1", item.Tenant)
+		} else {
+			assert.Equal(t, "2", item.Tenant)
+		}
+	}
+	assert.Equal(t, []*pb.QueueTenantUsage{
+		{Tenant: "1", Weight: 3, ShareConcurrency: 3, ShareCPU: 75, ShareMemoryMB: 76800, ProcessingCount: 1, InUseCPU: 1, InUseMemoryMB: 1024},
+		{Tenant: "2", Weight: 1, ShareConcurrency: 1, ShareCPU: 25, ShareMemoryMB: 25600, ProcessingCount: 2, PendingCount: 1, InUseCPU: 2, InUseMemoryMB: 2048, Borrowing: true},
+	}, usage.TenantUsages)
+
+	// tenant usages are only calculated for fair share queue
+	q.pq.ScheduleStrategy = apistructs.ScheduleStrategyInsidePipelineQueueOfFIFO.String()
+	usage = q.Usage()
+	assert.Empty(t, usage.TenantUsages)
+	assert.Empty(t, usage.ProcessingDetails[0].Tenant)
+}
+
+func TestGetTenant(t *testing.T) {
+	p := &spec.Pipeline{Labels: map[string]string{apistructs.LabelProjectID: "1"}}
+	assert.Equal(t, "1", getTenant(&pb.QueueFairShare{}, p))
+	assert.Equal(t, "1", getTenant(&pb.QueueFairShare{TenantBy: apistructs.PipelineQueueTenantByProject.String()}, p))
+	assert.Equal(t, "", getTenant(&pb.QueueFairShare{TenantBy: apistructs.PipelineQueueTenantByOwner.String()}, p))
+}
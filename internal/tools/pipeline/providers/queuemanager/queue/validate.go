@@ -31,6 +31,11 @@ func (q *defaultQueue) validatePipeline(p *spec.Pipeline) apistructs.PipelineQue
 	if result.IsFailed() {
 		return result
 	}
+	// concurrency group
+	result = q.ValidateConcurrencyGroup(p)
+	if result.IsFailed() {
+		return result
+	}
 
 	// default result
 	return types.SuccessValidateResult
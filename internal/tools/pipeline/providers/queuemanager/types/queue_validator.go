@@ -24,4 +24,6 @@ var SuccessValidateResult = apistructs.PipelineQueueValidateResult{Success: true
 type QueueValidator interface {
 	ValidateCapacity(tryPop *spec.Pipeline) apistructs.PipelineQueueValidateResult
 	ValidateFreeResources(tryPop *spec.Pipeline) apistructs.PipelineQueueValidateResult
+	ValidateConcurrencyGroup(tryPop *spec.Pipeline) apistructs.PipelineQueueValidateResult
+	ValidateFairShare(tryPop *spec.Pipeline) apistructs.PipelineQueueValidateResult
 }
@@ -37,6 +37,7 @@ import (
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/reconciler/schedulabletask"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/resourcegc"
 	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
 	"github.com/erda-project/erda/pkg/strutil"
 )
 
@@ -175,6 +176,10 @@ func (pr *defaultPipelineReconciler) GetTasksCanBeConcurrentlyScheduled(ctx cont
 	if err != nil {
 		return nil, err
 	}
+	schedulableTasks = limitMatrixParallel(allTasks, schedulableTasks, func(task *spec.PipelineTask) bool {
+		_, onProcessing := pr.processingTasks.Load(task.Name)
+		return onProcessing || task.Status.IsReconcilerRunningStatus()
+	})
 	var filteredTasks []*spec.PipelineTask
 	for _, task := range schedulableTasks {
 		_, onProcessing := pr.processingTasks.LoadOrStore(task.Name, struct{}{})
@@ -194,6 +199,46 @@ func (pr *defaultPipelineReconciler) GetTasksCanBeConcurrentlyScheduled(ctx cont
 	return filteredTasks, nil
 }
 
+// limitMatrixParallel keeps at most max-parallel instances of each matrix group in progress.
+// Instances wait by their index, a failed instance doesn't hold back the others.
+func limitMatrixParallel(allTasks, schedulableTasks []*spec.PipelineTask, inProgress func(*spec.PipelineTask) bool) []*spec.PipelineTask {
+	running := make(map[pipelineyml.ActionAlias]int)
+	for _, task := range allTasks {
+		if mi := task.Extra.Action.MatrixInstance; mi != nil && mi.MaxParallel > 0 && inProgress(task) {
+			running[mi.Group]++
+		}
+	}
+
+	var limited []*spec.PipelineTask
+	var groups []pipelineyml.ActionAlias
+	waiting := make(map[pipelineyml.ActionAlias][]*spec.PipelineTask)
+	for _, task := range schedulableTasks {
+		mi := task.Extra.Action.MatrixInstance
+		if mi == nil || mi.MaxParallel <= 0 || inProgress(task) {
+			limited = append(limited, task)
+			continue
+		}
+		if _, ok := waiting[mi.Group]; !ok {
+			groups = append(groups, mi.Group)
+		}
+		waiting[mi.Group] = append(waiting[mi.Group], task)
+	}
+	for _, group := range groups {
+		tasks := waiting[group]
+		sort.Slice(tasks, func(i, j int) bool {
+			return tasks[i].Extra.Action.MatrixInstance.Index < tasks[j].Extra.Action.MatrixInstance.Index
+		})
+		for _, task := range tasks {
+			if running[group] >= task.Extra.Action.MatrixInstance.MaxParallel {
+				break
+			}
+			running[group]++
+			limited = append(limited, task)
+		}
+	}
+	return limited
+}
+
 func (pr *defaultPipelineReconciler) ReconcileOneSchedulableTask(ctx context.Context, p *spec.Pipeline, task *spec.PipelineTask) {
 	tr := &defaultTaskReconciler{
 		log:                  pr.r.Log.Sub("task"),
@@ -266,6 +311,14 @@ func (pr *defaultPipelineReconciler) TeardownAfterReconcileDone(ctx context.Cont
 	go metrics.PipelineCounterTotalAdd(*p, 1)
 	go metrics.PipelineGaugeProcessingAdd(*p, -1)
 	go metrics.PipelineEndEvent(*p)
+	// tracing
+	if pr.r.Tracing != nil {
+		go func(p spec.Pipeline) {
+			if err := pr.r.Tracing.ExportPipeline(context.Background(), &p); err != nil {
+				pr.log.Warnf("failed to export pipeline trace, pipelineID: %d, err: %v", p.ID, err)
+			}
+		}(*p)
+	}
 	// aop
 	rutil.ContinueWorking(ctx, pr.log, func(ctx context.Context) rutil.WaitDuration {
 		if err := aop.Handle(aop.NewContextForPipeline(*p, aoptypes.TuneTriggerPipelineAfterExec)); err != nil {
@@ -24,6 +24,7 @@ import (
 	"github.com/erda-project/erda-infra/base/logs/logrusx"
 	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
 )
 
 func Test_defaultPipelineReconciler_IsReconcileDone(t *testing.T) {
@@ -147,3 +148,36 @@ func Test_defaultPipelineReconciler_PrepareBeforeReconcile(t *testing.T) {
 		t.Fatalf("should be running")
 	}
 }
+
+func Test_limitMatrixParallel(t *testing.T) {
+	newInstance := func(index int, status apistructs.PipelineStatus) *spec.PipelineTask {
+		task := &spec.PipelineTask{Name: "build-" + string(rune('a'+index)), Status: status}
+		task.Extra.Action.MatrixInstance = &pipelineyml.MatrixInstance{Group: "build", Index: index, MaxParallel: 2}
+		return task
+	}
+	other := &spec.PipelineTask{Name: "lint", Status: apistructs.PipelineStatusAnalyzed}
+	names := func(tasks []*spec.PipelineTask) []string {
+		var result []string
+		for _, task := range tasks {
+			result = append(result, task.Name)
+		}
+		return result
+	}
+	inProgress := func(task *spec.PipelineTask) bool { return task.Status.IsReconcilerRunningStatus() }
+
+	// nothing started, the first two instances by index
+	tasks := []*spec.PipelineTask{newInstance(3, apistructs.PipelineStatusAnalyzed), newInstance(2, apistructs.PipelineStatusAnalyzed),
+		newInstance(1, apistructs.PipelineStatusAnalyzed), newInstance(0, apistructs.PipelineStatusAnalyzed), other}
+	got := names(limitMatrixParallel(tasks, tasks, inProgress))
+	if want := []string{"lint", "build-a", "build-b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+
+	// one failed and one running, a single slot is left
+	tasks = []*spec.PipelineTask{newInstance(0, apistructs.PipelineStatusFailed), newInstance(1, apistructs.PipelineStatusRunning),
+		newInstance(2, apistructs.PipelineStatusAnalyzed), newInstance(3, apistructs.PipelineStatusAnalyzed)}
+	got = names(limitMatrixParallel(tasks, tasks[1:], inProgress))
+	if want := []string{"build-b", "build-c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
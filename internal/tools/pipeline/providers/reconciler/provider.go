@@ -34,6 +34,7 @@ import (
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/leaderworker"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/reconciler/taskpolicy"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/resourcegc"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/tracing"
 )
 
 type provider struct {
@@ -51,6 +52,7 @@ type provider struct {
 	EdgeReporter    edgereporter.Interface
 	ActionMgr       actionmgr.Interface
 	ActionAgentSvc  actionagent.Interface
+	Tracing         tracing.Interface `autowired:"pipeline-tracing" optional:"true"`
 
 	dbClient *dbclient.Client
 	bdl      *bundle.Bundle
@@ -28,6 +28,7 @@ import (
 	"github.com/erda-project/erda/internal/tools/pipeline/pkg/taskerror"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/leaderworker/lwctx"
 	"github.com/erda-project/erda/internal/tools/pipeline/providers/reconciler/rlog"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
 	"github.com/erda-project/erda/pkg/strutil"
 )
 
@@ -86,6 +87,8 @@ func (tr *TaskRun) waitOp(itr TaskOp, o *Elem) (result error) {
 		resultErrMsg []string
 		oldStatus    = tr.Task.Status
 		startTime    = time.Now()
+		// processedTime is the time op processing returned
+		processedTime time.Time
 	)
 	defer func() {
 		if r := recover(); r != nil {
@@ -121,6 +124,8 @@ func (tr *TaskRun) waitOp(itr TaskOp, o *Elem) (result error) {
 			}
 		}
 
+		tr.recordOpTime(itr.Op(), startTime, processedTime)
+
 		// if we invoke `tr.fetchLatestTask` method here before `update`,
 		// we will lost changes made by `WhenXXX` methods.
 		tr.Update()
@@ -145,6 +150,7 @@ func (tr *TaskRun) waitOp(itr TaskOp, o *Elem) (result error) {
 		}
 
 	case data := <-o.DoneCh:
+		processedTime = time.Now()
 		tr.LogStep(itr.Op(), "begin do WhenDone")
 		defer tr.LogStep(itr.Op(), "end do WhenDone")
 		if err := itr.WhenDone(data); err != nil {
@@ -154,6 +160,7 @@ func (tr *TaskRun) waitOp(itr TaskOp, o *Elem) (result error) {
 		_ = aop.Handle(aop.NewContextForTask(*tr.Task, *tr.P, itr.TuneTriggers().AfterProcessing))
 
 	case err := <-o.ErrCh:
+		processedTime = time.Now()
 		logrus.Errorf("reconciler: pipelineID: %d, task %q %s received error (%v)", tr.P.ID, tr.Task.Name, itr.Op(), err)
 		if errorsx.IsNetworkError(err) {
 			// convert network error
@@ -168,6 +175,7 @@ func (tr *TaskRun) waitOp(itr TaskOp, o *Elem) (result error) {
 		}
 
 	case <-o.TimeoutCh:
+		processedTime = time.Now()
 		tr.LogStep(itr.Op(), "begin do WhenTimeout")
 		defer tr.LogStep(itr.Op(), "end do WhenTimeout")
 		if err := itr.WhenTimeout(); err != nil {
@@ -191,6 +199,15 @@ func (tr *TaskRun) waitOp(itr TaskOp, o *Elem) (result error) {
 	return
 }
 
+// recordOpTime records the time of op into task, which is used to export the task as trace spans.
+// For looped task, the time of the last loop is kept.
+func (tr *TaskRun) recordOpTime(op Op, begin, processed time.Time) {
+	if tr.Task.Extra.OpTimes == nil {
+		tr.Task.Extra.OpTimes = make(map[string]spec.TaskOpTime)
+	}
+	tr.Task.Extra.OpTimes[string(op)] = spec.TaskOpTime{Begin: begin, Processed: processed, End: time.Now()}
+}
+
 // reconciler: pipelineID: 1, taskID: 1, taskName: repo, taskOp: start, step: begin do WhenDone
 func (tr *TaskRun) LogStep(taskOp Op, step string) {
 	logrus.Debugf("reconciler: pipelineID: %d, taskID: %d, taskName: %s, taskOp: %s, step: %s",
@@ -192,3 +192,20 @@ func Test_waitOpForLoopNetWorkError(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordOpTime(t *testing.T) {
+	tr := &TaskRun{Task: &spec.PipelineTask{}}
+	begin := time.Now().Add(-time.Minute)
+	processed := time.Now().Add(-time.Second)
+	tr.recordOpTime(Wait, begin, processed)
+	opTime := tr.Task.Extra.OpTimes[string(Wait)]
+	if !opTime.Begin.Equal(begin) || !opTime.Processed.Equal(processed) || opTime.End.Before(processed) {
+		t.Errorf("unexpected op time: %+v", opTime)
+	}
+
+	// the last loop is kept
+	tr.recordOpTime(Wait, processed, time.Time{})
+	if opTime := tr.Task.Extra.OpTimes[string(Wait)]; !opTime.Begin.Equal(processed) || !opTime.Processed.IsZero() {
+		t.Errorf("unexpected op time of the last loop: %+v", opTime)
+	}
+}
@@ -662,6 +662,18 @@ func (pre *prepare) generateOpenapiTokenForPullBootstrapInfo(task *spec.Pipeline
 					Method: http.MethodPost,
 					Schema: "http",
 				},
+				// PIPELINE_ARTIFACT_UPLOAD
+				{
+					Path:   "/api/pipelines/<pipelineID>/tasks/<taskID>/artifacts",
+					Method: http.MethodPost,
+					Schema: "http",
+				},
+				// PIPELINE_ARTIFACT_DOWNLOAD, org is checked by the artifact service
+				{
+					Path:   "/api/pipeline-artifacts/actions/download",
+					Method: http.MethodGet,
+					Schema: "http",
+				},
 			},
 			Metadata: map[string]string{
 				"pipelineID":            strconv.FormatUint(task.PipelineID, 10),
@@ -690,50 +702,12 @@ func getLoopOptions(actionSpec apistructs.ActionSpec, taskLoop *apistructs.Pipel
 	if actionSpec.Loop == nil && taskLoop == nil {
 		return nil
 	}
-	opt := apistructs.PipelineTaskLoopOptions{
+	return &apistructs.PipelineTaskLoopOptions{
 		TaskLoop:       taskLoop,
 		SpecYmlLoop:    actionSpec.Loop,
-		CalculatedLoop: nil,
+		CalculatedLoop: pipelineyml.NormalizeLoop(actionSpec.Loop, taskLoop),
 		LoopedTimes:    apistructs.TaskLoopTimeBegin, // 当前这次运行即为 1
 	}
-	// calculate
-	//
-	opt.CalculatedLoop = opt.SpecYmlLoop.Duplicate()
-	if opt.TaskLoop != nil {
-		opt.CalculatedLoop = opt.TaskLoop.Duplicate()
-	}
-
-	// 默认值
-	if opt.CalculatedLoop.Break == "" {
-		// 默认退出条件为任务成功
-		opt.CalculatedLoop.Break = `task_status == 'Success'`
-	}
-	if opt.CalculatedLoop.Strategy == nil {
-		// 默认策略
-		opt.CalculatedLoop.Strategy = &apistructs.PipelineTaskDefaultLoopStrategy
-	}
-
-	if opt.CalculatedLoop.Strategy.IntervalSec == 0 {
-		opt.CalculatedLoop.Strategy.IntervalSec = apistructs.PipelineTaskDefaultLoopStrategy.IntervalSec
-	}
-
-	if opt.CalculatedLoop.Strategy.DeclineRatio <= 0 {
-		if opt.CalculatedLoop.Strategy.IntervalSec == 0 {
-			opt.CalculatedLoop.Strategy.DeclineRatio = apistructs.PipelineTaskDefaultLoopStrategy.DeclineRatio
-		} else {
-			opt.CalculatedLoop.Strategy.DeclineRatio = 1
-		}
-	}
-
-	if opt.CalculatedLoop.Strategy.DeclineLimitSec == 0 {
-		if opt.CalculatedLoop.Strategy.IntervalSec == 0 {
-			opt.CalculatedLoop.Strategy.DeclineLimitSec = apistructs.PipelineTaskDefaultLoopStrategy.DeclineLimitSec
-		} else {
-			opt.CalculatedLoop.Strategy.DeclineLimitSec = int64(opt.CalculatedLoop.Strategy.IntervalSec)
-		}
-	}
-
-	return &opt
 }
 
 func condition(task *spec.PipelineTask) bool {
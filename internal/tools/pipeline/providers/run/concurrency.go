@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package run
+
+import (
+	"fmt"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/services/apierrors"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+)
+
+// resolveConcurrency renders the concurrency group defined in pipeline.yml and stores it into pipeline extra.
+// Snippet pipelines follow the root pipeline, so they are skipped.
+func (s *provider) resolveConcurrency(p *spec.Pipeline) error {
+	p.Extra.Concurrency = nil
+	if p.IsSnippet {
+		return nil
+	}
+	y, err := pipelineyml.New([]byte(p.PipelineYml))
+	if err != nil {
+		return err
+	}
+	concurrency := y.Spec().Concurrency
+	if concurrency == nil {
+		return nil
+	}
+	group, err := pipelineyml.RenderConcurrencyGroup(concurrency.Group, makeConcurrencyVars(p))
+	if err != nil {
+		return fmt.Errorf("invalid concurrency group: %v", err)
+	}
+	p.Extra.Concurrency = &spec.ConcurrencyInfo{
+		Group:            group,
+		CancelInProgress: concurrency.CancelInProgress,
+	}
+	return nil
+}
+
+// limitConcurrencyGroup rejects the pipeline if another pipeline of its concurrency group is running.
+// Pipelines bound to a queue wait in the queue for the group instead, see queue.ValidateConcurrencyGroup.
+func (s *provider) limitConcurrencyGroup(p *spec.Pipeline) error {
+	if p.Extra.Concurrency == nil || p.Extra.QueueInfo != nil {
+		return nil
+	}
+	runningPipelines, err := s.dbClient.ListPipelinesInConcurrencyGroup(p.PipelineSource, p.Extra.Concurrency.Group, apistructs.ReconcilerRunningStatuses())
+	if err != nil {
+		return apierrors.ErrParallelRunPipeline.InternalError(err)
+	}
+	for _, running := range runningPipelines {
+		if running.ID == p.ID {
+			continue
+		}
+		ctxMap := map[string]interface{}{
+			apierrors.ErrParallelRunPipeline.Error(): fmt.Sprintf("%d", running.ID),
+		}
+		return apierrors.ErrParallelRunPipeline.InvalidState("ErrParallelRunPipeline").SetCtx(ctxMap)
+	}
+	return nil
+}
+
+func makeConcurrencyVars(p *spec.Pipeline) map[string]string {
+	labels := p.MergeLabels()
+	return map[string]string{
+		pipelineyml.ConcurrencyVarOrg:             labels[apistructs.LabelOrgID],
+		pipelineyml.ConcurrencyVarProject:         labels[apistructs.LabelProjectID],
+		pipelineyml.ConcurrencyVarProjectName:     labels[apistructs.LabelProjectName],
+		pipelineyml.ConcurrencyVarApp:             labels[apistructs.LabelAppID],
+		pipelineyml.ConcurrencyVarAppName:         labels[apistructs.LabelAppName],
+		pipelineyml.ConcurrencyVarBranch:          labels[apistructs.LabelBranch],
+		pipelineyml.ConcurrencyVarWorkspace:       p.Extra.DiceWorkspace.String(),
+		pipelineyml.ConcurrencyVarPipelineYmlName: p.PipelineYmlName,
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package run
+
+import (
+	"reflect"
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/dbclient"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+func Test_resolveConcurrency(t *testing.T) {
+	p := &spec.Pipeline{
+		PipelineBase: spec.PipelineBase{
+			PipelineSource:  "dice",
+			PipelineYmlName: "pipeline.yml",
+		},
+		PipelineExtra: spec.PipelineExtra{
+			PipelineYml: `version: "1.1"
+concurrency:
+  group: ${{ project }}-${{ branch }}
+  cancel_in_progress: true
+stages:
+  - stage:
+      - git-checkout:
+`,
+			NormalLabels: map[string]string{apistructs.LabelProjectID: "1"},
+		},
+		Labels: map[string]string{apistructs.LabelBranch: "master"},
+	}
+	s := &provider{}
+	assert.NoError(t, s.resolveConcurrency(p))
+	assert.Equal(t, &spec.ConcurrencyInfo{Group: "1-master", CancelInProgress: true}, p.Extra.Concurrency)
+
+	// no concurrency
+	p.PipelineYml = `version: "1.1"
+stages:
+  - stage:
+      - git-checkout:
+`
+	assert.NoError(t, s.resolveConcurrency(p))
+	assert.Nil(t, p.Extra.Concurrency)
+
+	// snippet follows the root pipeline
+	p.IsSnippet = true
+	assert.NoError(t, s.resolveConcurrency(p))
+	assert.Nil(t, p.Extra.Concurrency)
+}
+
+func Test_limitConcurrencyGroup(t *testing.T) {
+	db := &dbclient.Client{}
+	queried := 0
+	pm := monkey.PatchInstanceMethod(reflect.TypeOf(db), "ListPipelinesInConcurrencyGroup",
+		func(_ *dbclient.Client, source apistructs.PipelineSource, group string, statuses []apistructs.PipelineStatus, ops ...dbclient.SessionOption) ([]spec.PipelineBase, error) {
+			queried++
+			return []spec.PipelineBase{{ID: 1}, {ID: 2}}, nil
+		})
+	defer pm.Unpatch()
+	s := &provider{dbClient: db}
+
+	p := &spec.Pipeline{PipelineBase: spec.PipelineBase{ID: 2}}
+	assert.NoError(t, s.limitConcurrencyGroup(p), "no concurrency")
+
+	// without queue, the group is checked when run
+	p.Extra.Concurrency = &spec.ConcurrencyInfo{Group: "g"}
+	assert.Error(t, s.limitConcurrencyGroup(p))
+	assert.Equal(t, 1, queried)
+
+	// the queue waits for the group
+	p.Extra.QueueInfo = &spec.QueueInfo{}
+	assert.NoError(t, s.limitConcurrencyGroup(p))
+	assert.Equal(t, 1, queried)
+}
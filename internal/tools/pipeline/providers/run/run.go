@@ -56,6 +56,9 @@ func (s *provider) RunOnePipeline(ctx context.Context, req *pipelinepb.PipelineR
 	if !canManualRun {
 		return nil, apierrors.ErrRunPipeline.InvalidState(reason)
 	}
+	if err := s.resolveConcurrency(&p); err != nil {
+		return nil, apierrors.ErrRunPipeline.InvalidParameter(err)
+	}
 	if req.ForceRun {
 		err := s.Cancel.StopRelatedRunningPipelinesOfOnePipeline(ctx, &p, &commonpb.IdentityInfo{
 			UserID:         req.UserID,
@@ -64,11 +67,15 @@ func (s *provider) RunOnePipeline(ctx context.Context, req *pipelinepb.PipelineR
 		if err != nil {
 			return nil, err
 		}
-	} else {
+	} else if p.Extra.Concurrency == nil || !p.Extra.Concurrency.CancelInProgress {
 		// 校验已运行的 pipeline
+		// cancel_in_progress 的流水线会取消同 concurrency group 中运行中的流水线，无需校验
 		if err := s.limitParallelRunningPipelines(&p); err != nil {
 			return nil, err
 		}
+		if err := s.limitConcurrencyGroup(&p); err != nil {
+			return nil, err
+		}
 	}
 
 	p.Extra.ConfigManageNamespaces = append(p.Extra.ConfigManageNamespaces, req.ConfigManageNamespaces...)
@@ -159,6 +166,14 @@ func (s *provider) RunOnePipeline(ctx context.Context, req *pipelinepb.PipelineR
 		return nil, apierrors.ErrRunPipeline.InternalError(err)
 	}
 
+	// supersede older pipelines in the same concurrency group
+	if err := s.Cancel.SupersedeConcurrencyGroup(ctx, &p, &commonpb.IdentityInfo{
+		UserID:         req.UserID,
+		InternalClient: req.InternalClient,
+	}); err != nil {
+		s.Log.Errorf("failed to supersede pipelines in concurrency group, pipelineID: %d, err: %v", p.ID, err)
+	}
+
 	// aop
 	_ = aop.Handle(aop.NewContextForPipeline(p, aoptypes.TuneTriggerPipelineBeforeExec))
 
@@ -186,6 +201,17 @@ func (s *provider) createPipelineRunLabels(p spec.Pipeline, req *pipelinepb.Pipe
 			Value:           req.UserID,
 		})
 	}
+	if p.Extra.Concurrency != nil {
+		labels = append(labels, spec.PipelineLabel{
+			ID:              uuid.SnowFlakeIDUint64(),
+			Type:            apistructs.PipelineLabelTypeInstance,
+			TargetID:        p.ID,
+			PipelineSource:  p.PipelineSource,
+			PipelineYmlName: p.PipelineYmlName,
+			Key:             apistructs.LabelPipelineConcurrencyGroup,
+			Value:           p.Extra.Concurrency.Group,
+		})
+	}
 	if len(labels) > 0 {
 		err = s.dbClient.BatchInsertLabels(labels)
 	}
@@ -100,6 +100,8 @@ func (s *taskService) PipelineTaskGetBootstrapInfo(ctx context.Context, req *pb.
 		PrivateEnvs:       task.Extra.PrivateEnvs,
 		EncryptSecretKeys: task.Extra.EncryptSecretKeys,
 		DebugTimeout:      debugTimeout,
+		Artifacts:         task.Extra.Action.Artifacts,
+		DownloadArtifacts: task.Extra.Action.DownloadArtifacts,
 	}
 	if breakpointConfig.On != nil {
 		bootstrapInfo.DebugOnFailure = breakpointConfig.On.Failure
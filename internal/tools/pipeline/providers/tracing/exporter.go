@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	tracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	headerErdaEnvID    = "x-erda-env-id"
+	headerErdaEnvToken = "x-erda-env-token"
+	headerErdaOrg      = "x-erda-org"
+)
+
+// export posts traces in otlp protobuf to the collector.
+func (p *provider) export(ctx context.Context, data *tracev1.TracesData) error {
+	body, err := proto.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal traces: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for key, value := range map[string]string{
+		headerErdaEnvID:    p.Cfg.EnvID,
+		headerErdaEnvToken: p.Cfg.EnvToken,
+		headerErdaOrg:      p.Cfg.OrgName,
+	} {
+		if value != "" {
+			req.Header.Set(key, value)
+		}
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export traces: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to export traces, status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
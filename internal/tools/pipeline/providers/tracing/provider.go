@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda/internal/tools/pipeline/dbclient"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+type config struct {
+	Enabled bool `file:"enabled" env:"PIPELINE_TRACING_ENABLED" default:"false"`
+	// Endpoint is the otlp http traces endpoint of collector, default is http://${COLLECTOR_ADDR}/api/otlp/v1/traces
+	Endpoint    string        `file:"endpoint" env:"PIPELINE_TRACING_ENDPOINT"`
+	EnvID       string        `file:"env_id" env:"PIPELINE_TRACING_ENV_ID"`
+	EnvToken    string        `file:"env_token" env:"PIPELINE_TRACING_ENV_TOKEN"`
+	OrgName     string        `file:"org_name" env:"PIPELINE_TRACING_ORG_NAME"`
+	ServiceName string        `file:"service_name" env:"PIPELINE_TRACING_SERVICE_NAME" default:"erda-pipeline"`
+	Timeout     time.Duration `file:"timeout" default:"10s"`
+}
+
+// +provider
+type provider struct {
+	Cfg   *config
+	Log   logs.Logger
+	MySQL mysqlxorm.Interface
+
+	dbClient   *dbclient.Client
+	httpClient *http.Client
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.dbClient = &dbclient.Client{Engine: p.MySQL.DB()}
+	p.httpClient = &http.Client{Timeout: p.Cfg.Timeout}
+	if p.Cfg.Endpoint == "" && os.Getenv("COLLECTOR_ADDR") != "" {
+		p.Cfg.Endpoint = fmt.Sprintf("http://%s/api/otlp/v1/traces", os.Getenv("COLLECTOR_ADDR"))
+	}
+	if p.Cfg.Enabled && p.Cfg.Endpoint == "" {
+		p.Log.Warnf("pipeline tracing endpoint is not configured, tracing is disabled")
+		p.Cfg.Enabled = false
+	}
+	return nil
+}
+
+func (p *provider) ExportPipeline(ctx context.Context, pipeline *spec.Pipeline) error {
+	if !p.Cfg.Enabled {
+		return nil
+	}
+	tasks, err := p.dbClient.ListPipelineTasksByPipelineID(pipeline.ID)
+	if err != nil {
+		return err
+	}
+	data := buildTracesData(pipeline, tasks, p.Cfg.ServiceName)
+	if err := p.export(ctx, data); err != nil {
+		return err
+	}
+	p.Log.Infof("pipeline trace exported, pipelineID: %d, traceID: %s", pipeline.ID, TraceID(pipeline.ID))
+	return nil
+}
+
+func init() {
+	interfaceType := reflect.TypeOf((*Interface)(nil)).Elem()
+	servicehub.Register("pipeline-tracing", &servicehub.Spec{
+		Services:    []string{"pipeline-tracing"},
+		Types:       []reflect.Type{interfaceType},
+		Description: "export pipeline executions as opentelemetry traces",
+		ConfigFunc:  func() interface{} { return &config{} },
+		Creator:     func() servicehub.Provider { return &provider{} },
+	})
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/reconciler/taskrun"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+const (
+	instrumentationName    = "erda-pipeline"
+	instrumentationVersion = "1.0"
+
+	// phases of task exported as sub spans
+	phaseQueue    = "queue"
+	phasePrepare  = "prepare"
+	phaseCreate   = "create"
+	phaseStart    = "start"
+	phaseRun      = "run"
+	phaseCallback = "callback"
+)
+
+// TraceID returns the hex trace id of the pipeline.
+func TraceID(pipelineID uint64) string {
+	return hex.EncodeToString(makeTraceID(pipelineID))
+}
+
+func makeTraceID(pipelineID uint64) []byte {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("erda-pipeline/%d", pipelineID)))
+	return sum[:16]
+}
+
+func makeSpanID(pipelineID uint64, parts ...interface{}) []byte {
+	sum := sha256.Sum256([]byte(fmt.Sprint(append([]interface{}{"erda-pipeline", pipelineID}, parts...)...)))
+	return sum[:8]
+}
+
+// buildTracesData makes the pipeline the root span and tasks the child spans,
+// phases of task like queue, prepare and run are sub spans of the task span.
+func buildTracesData(p *spec.Pipeline, tasks []spec.PipelineTask, serviceName string) *tracev1.TracesData {
+	traceID := makeTraceID(p.ID)
+	rootSpanID := makeSpanID(p.ID)
+
+	pipelineBegin, pipelineEnd := pipelineTimeRange(p)
+	labels := p.MergeLabels()
+	root := newSpan(traceID, rootSpanID, nil, pipelineSpanName(p), pipelineBegin, pipelineEnd, p.Status)
+	root.Kind = tracev1.Span_SPAN_KIND_SERVER
+	root.Attributes = append(root.Attributes,
+		stringKV("erda.pipeline.id", strconv.FormatUint(p.ID, 10)),
+		stringKV("erda.pipeline.source", p.PipelineSource.String()),
+		stringKV("erda.pipeline.yml_name", p.PipelineYmlName),
+		stringKV("erda.pipeline.status", p.Status.String()),
+		stringKV("erda.pipeline.trigger_mode", p.TriggerMode.String()),
+		stringKV("erda.pipeline.cluster", p.ClusterName),
+		intKV("erda.pipeline.cost_time_sec", p.CostTimeSec),
+	)
+	for _, label := range []string{
+		apistructs.LabelOrgID, apistructs.LabelOrgName,
+		apistructs.LabelProjectID, apistructs.LabelProjectName,
+		apistructs.LabelAppID, apistructs.LabelAppName,
+		apistructs.LabelBranch,
+	} {
+		if v := labels[label]; v != "" {
+			root.Attributes = append(root.Attributes, stringKV("erda.pipeline."+label, v))
+		}
+	}
+	spans := []*tracev1.Span{root}
+
+	for i := range tasks {
+		spans = append(spans, buildTaskSpans(traceID, rootSpanID, p.ID, &tasks[i])...)
+	}
+
+	return &tracev1.TracesData{
+		ResourceSpans: []*tracev1.ResourceSpans{{
+			Resource: &resourcev1.Resource{Attributes: []*commonv1.KeyValue{
+				stringKV("service.name", serviceName),
+			}},
+			ScopeSpans: []*tracev1.ScopeSpans{{
+				Scope: &commonv1.InstrumentationScope{Name: instrumentationName, Version: instrumentationVersion},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+// buildTaskSpans returns nil if the task never started, e.g. disabled or skipped.
+func buildTaskSpans(traceID, parentSpanID []byte, pipelineID uint64, task *spec.PipelineTask) []*tracev1.Span {
+	phases := taskPhases(task)
+	begin, end := taskTimeRange(task, phases)
+	if begin.IsZero() {
+		return nil
+	}
+	taskSpanID := makeSpanID(pipelineID, "task", task.ID)
+	taskSpan := newSpan(traceID, taskSpanID, parentSpanID, task.Name, begin, end, task.Status)
+	taskSpan.Attributes = append(taskSpan.Attributes,
+		stringKV("erda.pipeline.id", strconv.FormatUint(pipelineID, 10)),
+		stringKV("erda.pipeline.task.id", strconv.FormatUint(task.ID, 10)),
+		stringKV("erda.pipeline.task.type", task.Type),
+		stringKV("erda.pipeline.task.status", task.Status.String()),
+		stringKV("erda.pipeline.task.executor_kind", string(task.ExecutorKind)),
+		stringKV("erda.pipeline.task.executor_name", string(task.Extra.ExecutorName)),
+		stringKV("erda.pipeline.task.cluster", task.Extra.ClusterName),
+		intKV("erda.pipeline.task.queue_time_sec", task.QueueTimeSec),
+		intKV("erda.pipeline.task.cost_time_sec", task.CostTimeSec),
+	)
+	if task.Inspect.ExitCode != nil {
+		taskSpan.Attributes = append(taskSpan.Attributes, intKV("erda.pipeline.task.exit_code", int64(*task.Inspect.ExitCode)))
+	}
+	if task.IsSnippet && task.SnippetPipelineID != nil {
+		// the snippet pipeline is exported as another trace
+		taskSpan.Attributes = append(taskSpan.Attributes,
+			stringKV("erda.pipeline.task.snippet_pipeline_id", strconv.FormatUint(*task.SnippetPipelineID, 10)),
+			stringKV("erda.pipeline.task.snippet_trace_id", TraceID(*task.SnippetPipelineID)),
+		)
+	}
+	spans := []*tracev1.Span{taskSpan}
+
+	for _, phase := range phases {
+		phaseSpan := newSpan(traceID, makeSpanID(pipelineID, "task", task.ID, phase.name), taskSpanID,
+			phase.name, phase.begin, phase.end, "")
+		phaseSpan.Attributes = append(phaseSpan.Attributes,
+			stringKV("erda.pipeline.task.id", strconv.FormatUint(task.ID, 10)),
+			stringKV("erda.pipeline.task.phase", phase.name),
+		)
+		spans = append(spans, phaseSpan)
+	}
+	return spans
+}
+
+type phase struct {
+	name       string
+	begin, end time.Time
+}
+
+// taskPhases returns phases of task in order, phases not happened are left out.
+func taskPhases(task *spec.PipelineTask) []phase {
+	var phases []phase
+	add := func(name string, begin, end time.Time) {
+		if begin.IsZero() || end.IsZero() || end.Before(begin) {
+			return
+		}
+		phases = append(phases, phase{name: name, begin: begin, end: end})
+	}
+	opTimes := task.Extra.OpTimes
+
+	// queue-wait, use the queue time of task if op not recorded
+	if queue, ok := opTimes[string(taskrun.Queue)]; ok {
+		add(phaseQueue, queue.Begin, queue.End)
+	} else {
+		add(phaseQueue, task.Extra.TimeBeginQueue, task.Extra.TimeEndQueue)
+	}
+	for _, op := range []struct {
+		op    taskrun.Op
+		phase string
+	}{
+		{taskrun.Prepare, phasePrepare},
+		{taskrun.Create, phaseCreate},
+		{taskrun.Start, phaseStart},
+	} {
+		if t, ok := opTimes[string(op.op)]; ok {
+			add(op.phase, t.Begin, t.End)
+		}
+	}
+	// run is waiting the executor until the task done, callback is handling the done result
+	if wait, ok := opTimes[string(taskrun.Wait)]; ok {
+		if wait.Processed.IsZero() {
+			add(phaseRun, wait.Begin, wait.End)
+		} else {
+			add(phaseRun, wait.Begin, wait.Processed)
+			add(phaseCallback, wait.Processed, wait.End)
+		}
+	}
+	return phases
+}
+
+func taskTimeRange(task *spec.PipelineTask, phases []phase) (begin, end time.Time) {
+	update := func(b, e time.Time) {
+		if !b.IsZero() && (begin.IsZero() || b.Before(begin)) {
+			begin = b
+		}
+		if e.After(end) {
+			end = e
+		}
+	}
+	update(task.TimeBegin, task.TimeEnd)
+	for _, phase := range phases {
+		update(phase.begin, phase.end)
+	}
+	if end.Before(begin) {
+		end = begin
+	}
+	return
+}
+
+func pipelineTimeRange(p *spec.Pipeline) (begin, end time.Time) {
+	switch {
+	case p.TimeBegin != nil:
+		begin = *p.TimeBegin
+	case p.TimeCreated != nil:
+		begin = *p.TimeCreated
+	}
+	end = begin
+	if p.TimeEnd != nil && p.TimeEnd.After(begin) {
+		end = *p.TimeEnd
+	}
+	return
+}
+
+func pipelineSpanName(p *spec.Pipeline) string {
+	if p.PipelineYmlName != "" {
+		return "pipeline " + p.PipelineYmlName
+	}
+	return "pipeline " + strconv.FormatUint(p.ID, 10)
+}
+
+// newSpan makes a span, status is the pipeline or task status, empty status means no status.
+func newSpan(traceID, spanID, parentSpanID []byte, name string, begin, end time.Time, status apistructs.PipelineStatus) *tracev1.Span {
+	span := &tracev1.Span{
+		TraceId:           traceID,
+		SpanId:            spanID,
+		ParentSpanId:      parentSpanID,
+		Name:              name,
+		Kind:              tracev1.Span_SPAN_KIND_INTERNAL,
+		StartTimeUnixNano: uint64(begin.UnixNano()),
+		EndTimeUnixNano:   uint64(end.UnixNano()),
+	}
+	switch {
+	case status.IsFailedStatus():
+		span.Status = &tracev1.Status{Code: tracev1.Status_STATUS_CODE_ERROR, Message: status.String()}
+		span.Attributes = append(span.Attributes, boolKV("error", true))
+	case status.IsSuccessStatus():
+		span.Status = &tracev1.Status{Code: tracev1.Status_STATUS_CODE_OK}
+	}
+	return span
+}
+
+func stringKV(key, value string) *commonv1.KeyValue {
+	return &commonv1.KeyValue{Key: key, Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intKV(key string, value int64) *commonv1.KeyValue {
+	return &commonv1.KeyValue{Key: key, Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_IntValue{IntValue: value}}}
+}
+
+func boolKV(key string, value bool) *commonv1.KeyValue {
+	return &commonv1.KeyValue{Key: key, Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_BoolValue{BoolValue: value}}}
+}
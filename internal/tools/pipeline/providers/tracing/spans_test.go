@@ -0,0 +1,137 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	tracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+func TestTraceID(t *testing.T) {
+	assert.Equal(t, TraceID(1), TraceID(1))
+	assert.NotEqual(t, TraceID(1), TraceID(2))
+	assert.Len(t, TraceID(1), 32)
+}
+
+func TestTaskPhases(t *testing.T) {
+	base := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return base.Add(time.Duration(sec) * time.Second) }
+
+	task := &spec.PipelineTask{}
+	task.Extra.OpTimes = map[string]spec.TaskOpTime{
+		"prepare": {Begin: at(0), End: at(2)},
+		"create":  {Begin: at(2), End: at(3)},
+		"start":   {Begin: at(3), End: at(4)},
+		"queue":   {Begin: at(4), End: at(10)},
+		"wait":    {Begin: at(10), Processed: at(70), End: at(71)},
+	}
+	phases := taskPhases(task)
+	var names []string
+	for _, phase := range phases {
+		names = append(names, phase.name)
+	}
+	assert.Equal(t, []string{"queue", "prepare", "create", "start", "run", "callback"}, names)
+	assert.Equal(t, at(70), phases[4].end)
+	assert.Equal(t, at(70), phases[5].begin)
+
+	begin, end := taskTimeRange(task, phases)
+	assert.Equal(t, at(0), begin)
+	assert.Equal(t, at(71), end)
+
+	// queue time of task is used if queue op not recorded
+	task = &spec.PipelineTask{}
+	task.Extra.TimeBeginQueue = at(1)
+	task.Extra.TimeEndQueue = at(5)
+	task.Extra.OpTimes = map[string]spec.TaskOpTime{"wait": {Begin: at(5), End: at(8)}}
+	phases = taskPhases(task)
+	assert.Equal(t, []phase{{name: "queue", begin: at(1), end: at(5)}, {name: "run", begin: at(5), end: at(8)}}, phases)
+
+	// task not started
+	assert.Empty(t, taskPhases(&spec.PipelineTask{}))
+}
+
+func TestBuildTracesData(t *testing.T) {
+	begin := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	end := begin.Add(time.Minute)
+	exitCode := 2
+	snippetPipelineID := uint64(3)
+
+	p := &spec.Pipeline{}
+	p.ID = 1
+	p.PipelineYmlName = "pipeline.yml"
+	p.Status = apistructs.PipelineStatusFailed
+	p.TimeBegin = &begin
+	p.TimeEnd = &end
+	p.Labels = map[string]string{apistructs.LabelAppID: "10"}
+
+	tasks := []spec.PipelineTask{
+		{ID: 11, Name: "build", Status: apistructs.PipelineStatusFailed, TimeBegin: begin, TimeEnd: end},
+		{ID: 12, Name: "deploy", Status: apistructs.PipelineStatusNoNeedBySystem},
+		{ID: 13, Name: "snippet", Status: apistructs.PipelineStatusSuccess, TimeBegin: begin, TimeEnd: end,
+			IsSnippet: true, SnippetPipelineID: &snippetPipelineID},
+	}
+	tasks[0].Inspect.ExitCode = &exitCode
+	tasks[0].Extra.OpTimes = map[string]spec.TaskOpTime{"wait": {Begin: begin, Processed: end.Add(-time.Second), End: end}}
+
+	data := buildTracesData(p, tasks, "erda-pipeline")
+	spans := data.ResourceSpans[0].ScopeSpans[0].Spans
+	// root, build with run and callback, snippet; deploy never started
+	assert.Len(t, spans, 5)
+
+	root := spans[0]
+	assert.Equal(t, "pipeline pipeline.yml", root.Name)
+	assert.Nil(t, root.ParentSpanId)
+	assert.Equal(t, tracev1.Status_STATUS_CODE_ERROR, root.Status.Code)
+	assert.Equal(t, "10", attr(root, "erda.pipeline.appID"))
+
+	build := spans[1]
+	assert.Equal(t, root.SpanId, build.ParentSpanId)
+	assert.Equal(t, int64(2), build.Attributes[indexOf(build, "erda.pipeline.task.exit_code")].Value.GetIntValue())
+	assert.Equal(t, "run", spans[2].Name)
+	assert.Equal(t, "callback", spans[3].Name)
+	assert.Equal(t, build.SpanId, spans[2].ParentSpanId)
+
+	snippet := spans[4]
+	assert.Equal(t, TraceID(3), attr(snippet, "erda.pipeline.task.snippet_trace_id"))
+	assert.Equal(t, tracev1.Status_STATUS_CODE_OK, snippet.Status.Code)
+
+	for _, span := range spans {
+		assert.Equal(t, root.TraceId, span.TraceId)
+	}
+	// exported again with the same ids
+	assert.Equal(t, spans[1].SpanId, buildTracesData(p, tasks, "erda-pipeline").ResourceSpans[0].ScopeSpans[0].Spans[1].SpanId)
+}
+
+func indexOf(span *tracev1.Span, key string) int {
+	for i, kv := range span.Attributes {
+		if kv.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func attr(span *tracev1.Span, key string) string {
+	if i := indexOf(span, key); i >= 0 {
+		return span.Attributes[i].Value.GetStringValue()
+	}
+	return ""
+}
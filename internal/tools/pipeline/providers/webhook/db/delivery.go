@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda-proto-go/core/pipeline/webhook/pb"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSuccess  DeliveryStatus = "Success"
+	DeliveryStatusSkipped  DeliveryStatus = "Skipped"
+	DeliveryStatusRejected DeliveryStatus = "Rejected"
+	DeliveryStatusFailed   DeliveryStatus = "Failed"
+)
+
+// PipelineWebhookDelivery records an inbound request of a webhook and its outcome.
+type PipelineWebhookDelivery struct {
+	ID        string    `json:"id" xorm:"pk"`
+	CreatedAt time.Time `json:"timeCreated" xorm:"created_at created"`
+	UpdatedAt time.Time `json:"timeUpdated" xorm:"updated_at updated"`
+
+	DefinitionID string            `json:"definitionID"`
+	WebhookID    string            `json:"webhookID"`
+	Status       DeliveryStatus    `json:"status"`
+	Message      string            `json:"message"`
+	Event        string            `json:"event"`
+	Signature    string            `json:"signature"`
+	RemoteAddr   string            `json:"remoteAddr"`
+	Params       map[string]string `json:"params" xorm:"json"`
+	PipelineID   uint64            `json:"pipelineID"`
+}
+
+func (PipelineWebhookDelivery) TableName() string {
+	return "pipeline_webhook_deliveries"
+}
+
+func (d *PipelineWebhookDelivery) Convert2PB() *pb.Delivery {
+	if d == nil {
+		return nil
+	}
+	return &pb.Delivery{
+		ID:           d.ID,
+		DefinitionID: d.DefinitionID,
+		Status:       string(d.Status),
+		Message:      d.Message,
+		Event:        d.Event,
+		Signature:    d.Signature,
+		RemoteAddr:   d.RemoteAddr,
+		Params:       d.Params,
+		PipelineID:   d.PipelineID,
+		TimeCreated:  timestamppb.New(d.CreatedAt),
+	}
+}
+
+func (client *Client) CreateDelivery(delivery *PipelineWebhookDelivery, ops ...mysqlxorm.SessionOption) error {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	delivery.ID = uuid.New()
+	if _, err := session.InsertOne(delivery); err != nil {
+		return errors.Wrapf(err, "failed to insert webhook delivery, definitionID: %s", delivery.DefinitionID)
+	}
+	return nil
+}
+
+type ListDeliveriesRequest struct {
+	DefinitionID string
+	Status       string
+	PageNo       int
+	PageSize     int
+}
+
+// ListDeliveries lists deliveries of the definition, the latest first.
+func (client *Client) ListDeliveries(req ListDeliveriesRequest, ops ...mysqlxorm.SessionOption) ([]PipelineWebhookDelivery, int64, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	session.Where("definition_id = ?", req.DefinitionID)
+	if req.Status != "" {
+		session.Where("status = ?", req.Status)
+	}
+	if req.PageNo <= 0 {
+		req.PageNo = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	var deliveries []PipelineWebhookDelivery
+	total, err := session.Desc("created_at").Limit(req.PageSize, (req.PageNo-1)*req.PageSize).FindAndCount(&deliveries)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to list webhook deliveries, definitionID: %s", req.DefinitionID)
+	}
+	return deliveries, total, nil
+}
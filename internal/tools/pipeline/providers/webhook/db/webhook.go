@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda-proto-go/core/pipeline/webhook/pb"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+// PipelineWebhook is the inbound webhook of a pipeline definition, at most one per definition.
+type PipelineWebhook struct {
+	ID            string    `json:"id" xorm:"pk"`
+	CreatedAt     time.Time `json:"timeCreated" xorm:"created_at created"`
+	UpdatedAt     time.Time `json:"timeUpdated" xorm:"updated_at updated"`
+	SoftDeletedAt int64     `json:"softDeletedAt"`
+
+	DefinitionID string `json:"definitionID"`
+	AccessKeyID  string `json:"accessKeyID"`
+	SecretKey    string `json:"-"`
+	Creator      string `json:"creator"`
+}
+
+func (PipelineWebhook) TableName() string {
+	return "pipeline_webhooks"
+}
+
+// Convert2PB never returns the secret, it's only returned once when created.
+func (w *PipelineWebhook) Convert2PB(url string) *pb.Webhook {
+	if w == nil {
+		return nil
+	}
+	return &pb.Webhook{
+		ID:           w.ID,
+		DefinitionID: w.DefinitionID,
+		Url:          url,
+		AccessKeyID:  w.AccessKeyID,
+		Creator:      w.Creator,
+		TimeCreated:  timestamppb.New(w.CreatedAt),
+		TimeUpdated:  timestamppb.New(w.UpdatedAt),
+	}
+}
+
+// SaveWebhook creates the webhook, or rotates the key pair if the definition already has one.
+func (client *Client) SaveWebhook(webhook *PipelineWebhook, ops ...mysqlxorm.SessionOption) error {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var exist PipelineWebhook
+	found, err := session.Where("definition_id = ? AND soft_deleted_at = 0", webhook.DefinitionID).Get(&exist)
+	if err != nil {
+		return errors.Wrapf(err, "failed to query webhook, definitionID: %s", webhook.DefinitionID)
+	}
+	if found {
+		webhook.ID = exist.ID
+		webhook.CreatedAt = exist.CreatedAt
+		if _, err := session.ID(webhook.ID).Cols("access_key_id", "secret_key", "creator").Update(webhook); err != nil {
+			return errors.Wrapf(err, "failed to update webhook, id: %s", webhook.ID)
+		}
+		return nil
+	}
+	webhook.ID = uuid.New()
+	if _, err := session.InsertOne(webhook); err != nil {
+		return errors.Wrapf(err, "failed to insert webhook, definitionID: %s", webhook.DefinitionID)
+	}
+	return nil
+}
+
+// GetWebhookByDefinitionID returns nil if the webhook not found.
+func (client *Client) GetWebhookByDefinitionID(definitionID string, ops ...mysqlxorm.SessionOption) (*PipelineWebhook, error) {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	var webhook PipelineWebhook
+	found, err := session.Where("definition_id = ? AND soft_deleted_at = 0", definitionID).Get(&webhook)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get webhook, definitionID: %s", definitionID)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &webhook, nil
+}
+
+func (client *Client) DeleteWebhookByDefinitionID(definitionID string, ops ...mysqlxorm.SessionOption) error {
+	session := client.NewSession(ops...)
+	defer session.Close()
+
+	if _, err := session.Table(PipelineWebhook{}.TableName()).Where("definition_id = ? AND soft_deleted_at = 0", definitionID).
+		Update(map[string]interface{}{"soft_deleted_at": time.Now().UnixNano() / 1e6}); err != nil {
+		return errors.Wrapf(err, "failed to delete webhook, definitionID: %s", definitionID)
+	}
+	return nil
+}
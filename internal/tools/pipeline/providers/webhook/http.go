@@ -0,0 +1,140 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	infrahttpserver "github.com/erda-project/erda-infra/providers/httpserver"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/webhook/db"
+	"github.com/erda-project/erda/internal/tools/pipeline/services/apierrors"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+)
+
+// ReceiveWebhook is invoked by external systems to run the definition, every delivery is recorded with its outcome.
+func (p *provider) ReceiveWebhook(rw http.ResponseWriter, r *http.Request) {
+	definitionID, _ := infrahttpserver.Var(r, "definitionID")
+	webhook, err := p.dbClient.GetWebhookByDefinitionID(definitionID)
+	if err != nil {
+		errorresp.Error(rw, apierrors.ErrReceivePipelineWebhook.InternalError(err))
+		return
+	}
+	if webhook == nil {
+		errorresp.Error(rw, apierrors.ErrReceivePipelineWebhook.NotFound())
+		return
+	}
+
+	delivery := &db.PipelineWebhookDelivery{
+		DefinitionID: definitionID,
+		WebhookID:    webhook.ID,
+		Event:        getEvent(r),
+		RemoteAddr:   getRemoteAddr(r),
+	}
+	deliverErr := p.deliver(rw, r, webhook, delivery)
+	if err := p.dbClient.CreateDelivery(delivery); err != nil {
+		p.Log.Errorf("failed to record webhook delivery, definitionID: %s, status: %s, err: %v", definitionID, delivery.Status, err)
+	}
+	if deliverErr != nil {
+		errorresp.Error(rw, deliverErr)
+		return
+	}
+	httpserver.WriteData(rw, delivery.Convert2PB())
+}
+
+// deliver verifies the delivery and runs the definition if the filter matches, the outcome is set to delivery.
+func (p *provider) deliver(rw http.ResponseWriter, r *http.Request, webhook *db.PipelineWebhook, delivery *db.PipelineWebhookDelivery) error {
+	fail := func(status db.DeliveryStatus, err error, apiErr error) error {
+		delivery.Status = status
+		delivery.Message = err.Error()
+		return apiErr
+	}
+
+	maxSize := p.Cfg.MaxPayloadSizeKB << 10
+	body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxSize))
+	if err != nil {
+		return fail(db.DeliveryStatusFailed, err, apierrors.ErrReceivePipelineWebhook.InvalidParameter(err))
+	}
+
+	extra, err := p.definitionDBClient.GetPipelineDefinitionExtraByDefinitionID(webhook.DefinitionID)
+	if err != nil {
+		return fail(db.DeliveryStatusFailed, err, apierrors.ErrReceivePipelineWebhook.InternalError(err))
+	}
+	if extra == nil || extra.Extra.CreateRequest == nil {
+		err := fmt.Errorf("pipeline definition not found")
+		return fail(db.DeliveryStatusFailed, err, apierrors.ErrReceivePipelineWebhook.NotFound())
+	}
+	trigger, declaredParams, err := getWebhookTrigger(extra.Extra.CreateRequest.PipelineYml)
+	if err != nil {
+		return fail(db.DeliveryStatusFailed, err, apierrors.ErrReceivePipelineWebhook.InternalError(err))
+	}
+	if trigger == nil {
+		err := fmt.Errorf("webhook trigger is not declared in pipeline yml")
+		return fail(db.DeliveryStatusRejected, err, apierrors.ErrReceivePipelineWebhook.InvalidParameter(err))
+	}
+
+	delivery.Signature = trigger.Signature
+	if err := verifySignature(trigger.Signature, r, body, webhook, p.Cfg.SignatureMaxSkew); err != nil {
+		return fail(db.DeliveryStatusRejected, err, apierrors.ErrReceivePipelineWebhook.AccessDenied())
+	}
+
+	var payload interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			err = fmt.Errorf("invalid json payload, err: %v", err)
+			return fail(db.DeliveryStatusFailed, err, apierrors.ErrReceivePipelineWebhook.InvalidParameter(err))
+		}
+	}
+	params, err := mapParams(payload, trigger.Params)
+	if err != nil {
+		return fail(db.DeliveryStatusFailed, err, apierrors.ErrReceivePipelineWebhook.InvalidParameter(err))
+	}
+	delivery.Params = params
+
+	matched, err := matchFilter(trigger.Filter, withParamDefaults(params, declaredParams))
+	if err != nil {
+		return fail(db.DeliveryStatusFailed, err, apierrors.ErrReceivePipelineWebhook.InvalidParameter(err))
+	}
+	if !matched {
+		delivery.Status = db.DeliveryStatusSkipped
+		delivery.Message = fmt.Sprintf("filter not matched: %s", trigger.Filter)
+		return nil
+	}
+
+	req, err := makeCreateRequest(extra.Extra, webhook.DefinitionID, webhook, params)
+	if err != nil {
+		return fail(db.DeliveryStatusFailed, err, apierrors.ErrReceivePipelineWebhook.InternalError(err))
+	}
+	resp, err := p.PipelineSvc.PipelineCreateV2(r.Context(), req)
+	if err != nil {
+		return fail(db.DeliveryStatusFailed, err, apierrors.ErrReceivePipelineWebhook.InternalError(err))
+	}
+	delivery.Status = db.DeliveryStatusSuccess
+	delivery.PipelineID = resp.Data.ID
+	p.Log.Infof("pipeline triggered by webhook, definitionID: %s, pipelineID: %d", webhook.DefinitionID, delivery.PipelineID)
+	return nil
+}
+
+// getRemoteAddr prefers the client address forwarded by gateway.
+func getRemoteAddr(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	return r.RemoteAddr
+}
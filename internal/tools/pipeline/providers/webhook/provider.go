@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-infra/pkg/transport"
+	"github.com/erda-project/erda-infra/providers/mysqlxorm"
+	"github.com/erda-project/erda-proto-go/core/pipeline/webhook/pb"
+	definitiondb "github.com/erda-project/erda/internal/tools/pipeline/providers/definition/db"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/permission"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/pipeline"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/webhook/db"
+	"github.com/erda-project/erda/pkg/common/apis"
+)
+
+const receivePath = "/api/pipeline-webhooks/{definitionID}"
+
+type config struct {
+	// PublicURL is the address external systems use to reach openapi, e.g. https://openapi.erda.cloud
+	PublicURL        string        `file:"public_url" env:"PIPELINE_WEBHOOK_PUBLIC_URL"`
+	SignatureMaxSkew time.Duration `file:"signature_max_skew" env:"PIPELINE_WEBHOOK_SIGNATURE_MAX_SKEW" default:"5m"`
+	MaxPayloadSizeKB int64         `file:"max_payload_size_kb" env:"PIPELINE_WEBHOOK_MAX_PAYLOAD_SIZE_KB" default:"1024"`
+}
+
+// +provider
+type provider struct {
+	Cfg         *config
+	Log         logs.Logger
+	Register    transport.Register
+	MySQL       mysqlxorm.Interface
+	Permission  permission.Interface
+	PipelineSvc pipeline.Interface
+
+	dbClient           *db.Client
+	definitionDBClient *definitiondb.Client
+	webhookService     *webhookService
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.dbClient = &db.Client{Interface: p.MySQL}
+	p.definitionDBClient = &definitiondb.Client{Interface: p.MySQL}
+	p.webhookService = &webhookService{p: p}
+	if p.Register != nil {
+		pb.RegisterWebhookServiceImp(p.Register, p.webhookService, apis.Options())
+		p.Register.Add(http.MethodPost, receivePath, p.ReceiveWebhook)
+	}
+	return nil
+}
+
+// makeWebhookURL returns the url of the definition's webhook, only the path if public url is not configured.
+func (p *provider) makeWebhookURL(definitionID string) string {
+	path := strings.ReplaceAll(receivePath, "{definitionID}", definitionID)
+	return strings.TrimSuffix(p.Cfg.PublicURL, "/") + path
+}
+
+func (p *provider) Provide(ctx servicehub.DependencyContext, args ...interface{}) interface{} {
+	switch {
+	case ctx.Service() == "erda.core.pipeline.webhook.WebhookService" || ctx.Type() == pb.WebhookServiceServerType() || ctx.Type() == pb.WebhookServiceHandlerType():
+		return p.webhookService
+	}
+	return p
+}
+
+func init() {
+	servicehub.Register("erda.core.pipeline.webhook", &servicehub.Spec{
+		Services:             pb.ServiceNames(),
+		Types:                pb.Types(),
+		OptionalDependencies: []string{"service-register"},
+		Description:          "inbound webhooks to trigger pipeline definitions",
+		ConfigFunc: func() interface{} {
+			return &config{}
+		},
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/Knetic/govaluate.v3"
+
+	basepb "github.com/erda-project/erda-proto-go/core/pipeline/base/pb"
+	pipelinepb "github.com/erda-project/erda-proto-go/core/pipeline/pipeline/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/webhook/db"
+	"github.com/erda-project/erda/pkg/encoding/jsonparse"
+	"github.com/erda-project/erda/pkg/encoding/jsonpath"
+	"github.com/erda-project/erda/pkg/expression"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml/pexpr"
+	"github.com/erda-project/erda/pkg/secret"
+	erdahmac "github.com/erda-project/erda/pkg/secret/hmac"
+)
+
+const (
+	headerGithubSignature = "X-Hub-Signature-256"
+	headerGitlabToken     = "X-Gitlab-Token"
+
+	internalClientWebhook = "pipeline-webhook"
+)
+
+// eventHeaders are checked in order to find the event type of a delivery.
+var eventHeaders = []string{"X-Erda-Event", "X-GitHub-Event", "X-Gitlab-Event"}
+
+// getWebhookTrigger parses `on.webhook` and the declared params from the pipeline yml, returns nil trigger if not declared.
+func getWebhookTrigger(pipelineYml string) (*pipelineyml.WebhookTrigger, []*pipelineyml.PipelineParam, error) {
+	y, err := pipelineyml.New([]byte(pipelineYml))
+	if err != nil {
+		return nil, nil, err
+	}
+	if y.Spec().On == nil || y.Spec().On.Webhook == nil {
+		return nil, nil, nil
+	}
+	trigger := y.Spec().On.Webhook
+	if trigger.Signature == "" {
+		trigger.Signature = pipelineyml.WebhookSignatureErda
+	}
+	return trigger, y.Spec().Params, nil
+}
+
+// verifySignature verifies the delivery by the signature style declared in pipeline yml.
+func verifySignature(style string, r *http.Request, body []byte, webhook *db.PipelineWebhook, maxSkew time.Duration) error {
+	switch style {
+	case pipelineyml.WebhookSignatureErda:
+		signer := erdahmac.New(secret.AkSkPair{AccessKeyID: webhook.AccessKeyID, SecretKey: webhook.SecretKey})
+		return signer.Verify(r, body, maxSkew)
+	case pipelineyml.WebhookSignatureGithub:
+		sig := r.Header.Get(headerGithubSignature)
+		if !strings.HasPrefix(sig, "sha256=") {
+			return fmt.Errorf("missing header %s", headerGithubSignature)
+		}
+		h := hmac.New(sha256.New, []byte(webhook.SecretKey))
+		h.Write(body)
+		expected := "sha256=" + hex.EncodeToString(h.Sum(nil))
+		if !hmac.Equal([]byte(expected), []byte(sig)) {
+			return fmt.Errorf("signature mismatch")
+		}
+		return nil
+	case pipelineyml.WebhookSignatureGitlab:
+		token := r.Header.Get(headerGitlabToken)
+		if token == "" {
+			return fmt.Errorf("missing header %s", headerGitlabToken)
+		}
+		if !hmac.Equal([]byte(webhook.SecretKey), []byte(token)) {
+			return fmt.Errorf("token mismatch")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported signature: %s", style)
+	}
+}
+
+// mapParams extracts pipeline params from payload by JSONPath, e.g. `$.repository.name`.
+// Payloads differ by event, so params not found or null in payload are ignored,
+// and the default value declared in pipeline yml works.
+func mapParams(payload interface{}, mapping map[string]string) (map[string]string, error) {
+	params := make(map[string]string, len(mapping))
+	for name, path := range mapping {
+		path = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "$"), ".")
+		if path == "" {
+			return nil, fmt.Errorf("invalid JSONPath of param %s: %s", name, mapping[name])
+		}
+		if payload == nil {
+			continue
+		}
+		v, err := jsonpath.Get(payload, path)
+		if err != nil {
+			continue
+		}
+		switch value := v.(type) {
+		case string:
+			params[name] = value
+		default:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal param %s, err: %v", name, err)
+			}
+			params[name] = string(b)
+		}
+	}
+	return params, nil
+}
+
+// withParamDefaults returns the mapped params with the default values of the declared params not found in payload,
+// so that filter sees the same params as the pipeline runs with.
+func withParamDefaults(params map[string]string, declared []*pipelineyml.PipelineParam) map[string]string {
+	result := make(map[string]string, len(params)+len(declared))
+	for _, param := range declared {
+		if param == nil || param.Default == nil {
+			continue
+		}
+		result[param.Name] = jsonparse.JsonOneLine(param.Default)
+	}
+	for name, value := range params {
+		result[name] = value
+	}
+	return result
+}
+
+// filterParamRe matches a param placeholder in filter, with the optional quotes around it.
+var filterParamRe = regexp.MustCompile(`'\$\{\{ ([^{}\s]+) \}\}'|\$\{\{ ([^{}\s]+) \}\}`)
+
+// matchFilter evaluates the filter with mapped params, empty filter always matches.
+// String params must be quoted in filter, e.g. `'${{ params.branch }}' == 'master'`.
+// Defaults of params should be applied by withParamDefaults before, a param without value is an error.
+// Params come from the untrusted payload, so they are bound as variables of the expression
+// instead of being pasted into it, and the filter is rendered only once.
+func matchFilter(filter string, params map[string]string) (bool, error) {
+	if strings.TrimSpace(filter) == "" {
+		return true, nil
+	}
+	if invalidPhs := pexpr.FindInvalidPlaceholders(filter); len(invalidPhs) > 0 {
+		return false, fmt.Errorf("invalid filter, found invalid placeholders: %s", strings.Join(invalidPhs, ", "))
+	}
+	var notFound []string
+	variables := make(map[string]interface{})
+	exprStr := filterParamRe.ReplaceAllStringFunc(filter, func(ph string) string {
+		subs := filterParamRe.FindStringSubmatch(ph)
+		quoted, inner := true, subs[1]
+		if inner == "" {
+			quoted, inner = false, subs[2]
+		}
+		v, ok := params[strings.TrimPrefix(inner, expression.Params+".")]
+		if !ok || !strings.HasPrefix(inner, expression.Params+".") {
+			notFound = append(notFound, ph)
+			return ph
+		}
+		name := fmt.Sprintf("filter_param_%d", len(variables))
+		variables[name] = filterValue(v, quoted)
+		return "[" + name + "]"
+	})
+	if len(notFound) > 0 {
+		return false, fmt.Errorf("invalid filter, not found placeholders: %s", strings.Join(notFound, ", "))
+	}
+	expr, err := govaluate.NewEvaluableExpression(exprStr)
+	if err != nil {
+		return false, fmt.Errorf("invalid filter: %s, err: %v", filter, err)
+	}
+	result, err := expr.Evaluate(variables)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate filter: %s, err: %v", filter, err)
+	}
+	matched, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("filter result is not bool: %v", result)
+	}
+	return matched, nil
+}
+
+// filterValue returns the quoted param as string, and the unquoted one as number or bool if it is.
+func filterValue(v string, quoted bool) interface{} {
+	if quoted {
+		return v
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	if v == "true" || v == "false" {
+		return v == "true"
+	}
+	return v
+}
+
+// makeCreateRequest makes a request to run the definition with params from the delivery.
+func makeCreateRequest(extra apistructs.PipelineDefinitionExtraValue, definitionID string, webhook *db.PipelineWebhook, params map[string]string) (*pipelinepb.PipelineCreateRequestV2, error) {
+	if extra.CreateRequest == nil {
+		return nil, fmt.Errorf("create request of definition not found")
+	}
+	req := proto.Clone(extra.CreateRequest).(*pipelinepb.PipelineCreateRequestV2)
+
+	// params of the delivery override the ones saved in definition
+	runParams := make([]*basepb.PipelineRunParam, 0, len(extra.RunParams)+len(params))
+	for _, runParam := range extra.RunParams {
+		if _, ok := params[runParam.Name]; ok {
+			continue
+		}
+		runParams = append(runParams, runParam)
+	}
+	for _, name := range sortedKeys(params) {
+		runParams = append(runParams, &basepb.PipelineRunParam{Name: name, Value: structpb.NewStringValue(params[name])})
+	}
+	req.RunParams = runParams
+
+	if req.NormalLabels == nil {
+		req.NormalLabels = make(map[string]string)
+	}
+	req.NormalLabels[apistructs.LabelPipelineTriggerMode] = apistructs.PipelineTriggerModeWebhook.String()
+	req.DefinitionID = definitionID
+	req.UserID = webhook.Creator
+	req.InternalClient = internalClientWebhook
+	req.AutoRun = false
+	req.AutoRunAtOnce = true
+	req.AutoStartCron = false
+	return req, nil
+}
+
+// getEvent returns the event type of the delivery from well-known headers.
+func getEvent(r *http.Request) string {
+	for _, header := range eventHeaders {
+		if event := r.Header.Get(header); event != "" {
+			return event
+		}
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
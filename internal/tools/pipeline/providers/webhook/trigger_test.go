@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	basepb "github.com/erda-project/erda-proto-go/core/pipeline/base/pb"
+	pipelinepb "github.com/erda-project/erda-proto-go/core/pipeline/pipeline/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/webhook/db"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+	"github.com/erda-project/erda/pkg/secret"
+	erdahmac "github.com/erda-project/erda/pkg/secret/hmac"
+)
+
+func TestGetWebhookTrigger(t *testing.T) {
+	trigger, declared, err := getWebhookTrigger(`version: "1.1"
+params:
+  - name: tag
+    default: latest
+on:
+  webhook:
+    params:
+      tag: $.push_data.tag
+    filter: "'${{ params.tag }}' != ''"
+stages:
+  - stage:
+      - custom-script:
+          commands:
+            - echo hello
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, pipelineyml.WebhookSignatureErda, trigger.Signature)
+	assert.Equal(t, "$.push_data.tag", trigger.Params["tag"])
+	assert.Equal(t, "'${{ params.tag }}' != ''", trigger.Filter)
+	assert.Len(t, declared, 1)
+
+	trigger, _, err = getWebhookTrigger(`version: "1.1"
+stages:
+  - stage:
+      - custom-script:
+          commands:
+            - echo hello
+`)
+	assert.NoError(t, err)
+	assert.Nil(t, trigger)
+}
+
+func TestVerifySignature(t *testing.T) {
+	webhook := &db.PipelineWebhook{AccessKeyID: "ak", SecretKey: "sk"}
+	body := []byte(`{"ref":"refs/heads/master"}`)
+
+	// erda
+	r := httptest.NewRequest("POST", "/api/pipeline-webhooks/1", bytes.NewReader(body))
+	erdahmac.New(secret.AkSkPair{AccessKeyID: "ak", SecretKey: "sk"}, erdahmac.WithTimestamp(time.Now()), erdahmac.WithBody(body)).SignCanonicalRequest(r)
+	assert.NoError(t, verifySignature(pipelineyml.WebhookSignatureErda, r, body, webhook, time.Minute))
+	assert.Error(t, verifySignature(pipelineyml.WebhookSignatureErda, r, body, &db.PipelineWebhook{AccessKeyID: "ak", SecretKey: "other"}, time.Minute))
+	assert.Error(t, verifySignature(pipelineyml.WebhookSignatureErda, r, []byte(`{}`), webhook, time.Minute))
+
+	// github
+	h := hmac.New(sha256.New, []byte("sk"))
+	h.Write(body)
+	r = httptest.NewRequest("POST", "/api/pipeline-webhooks/1", bytes.NewReader(body))
+	r.Header.Set(headerGithubSignature, "sha256="+hex.EncodeToString(h.Sum(nil)))
+	assert.NoError(t, verifySignature(pipelineyml.WebhookSignatureGithub, r, body, webhook, 0))
+	assert.Error(t, verifySignature(pipelineyml.WebhookSignatureGithub, r, []byte(`{}`), webhook, 0))
+
+	// gitlab
+	r = httptest.NewRequest("POST", "/api/pipeline-webhooks/1", bytes.NewReader(body))
+	assert.Error(t, verifySignature(pipelineyml.WebhookSignatureGitlab, r, body, webhook, 0))
+	r.Header.Set(headerGitlabToken, "sk")
+	assert.NoError(t, verifySignature(pipelineyml.WebhookSignatureGitlab, r, body, webhook, 0))
+
+	assert.Error(t, verifySignature("unknown", r, body, webhook, 0))
+}
+
+func TestMapParams(t *testing.T) {
+	var payload interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"push_data":{"tag":"v1.0"},"repository":{"name":"erda","private":true},"commits":[{"id":"abc"}]}`), &payload))
+
+	params, err := mapParams(payload, map[string]string{
+		"tag":     "$.push_data.tag",
+		"repo":    "repository.name",
+		"private": "$.repository.private",
+		"commit":  "$.commits[0].id",
+		"missing": "$.not.exist",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"tag": "v1.0", "repo": "erda", "private": "true", "commit": "abc"}, params)
+
+	params, err = mapParams(nil, map[string]string{"tag": "$.push_data.tag"})
+	assert.NoError(t, err)
+	assert.Empty(t, params)
+
+	_, err = mapParams(payload, map[string]string{"tag": "$"})
+	assert.Error(t, err)
+}
+
+func TestMatchFilter(t *testing.T) {
+	params := map[string]string{"branch": "master", "count": "3",
+		"inject": "x' == 'x' || 'a", "nested": "${{ params.nested }}", "other": "${{ params.branch }}"}
+	tests := []struct {
+		filter  string
+		want    bool
+		wantErr bool
+	}{
+		{filter: "", want: true},
+		{filter: "'${{ params.branch }}' == 'master'", want: true},
+		{filter: "'${{ params.branch }}' == 'develop'", want: false},
+		{filter: "${{ params.count }} > 2", want: true},
+		{filter: "'${{ params.tag }}' == 'v1'", wantErr: true},
+		{filter: "'${{ params.branch }}'", wantErr: true},
+		{filter: "'${{ configs.branch }}' == 'master'", wantErr: true},
+		// params are bound as values, never parsed as part of the filter
+		{filter: "'${{ params.inject }}' == 'master'", want: false},
+		{filter: "'${{ params.nested }}' == 'master'", want: false},
+		{filter: "'${{ params.other }}' == 'master'", want: false},
+		{filter: "'${{ params.nested }}' == '${{ params.nested }}'", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := matchFilter(tt.filter, params)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatchFilterWithParamDefaults(t *testing.T) {
+	declared := []*pipelineyml.PipelineParam{
+		{Name: "branch", Default: "master"},
+		{Name: "count", Default: 3},
+		{Name: "tag"},
+	}
+	params := withParamDefaults(map[string]string{"branch": "develop"}, declared)
+	assert.Equal(t, map[string]string{"branch": "develop", "count": "3"}, params)
+
+	// the default applies to the param not found in payload
+	matched, err := matchFilter("${{ params.count }} > 2", params)
+	assert.NoError(t, err)
+	assert.True(t, matched)
+	// the mapped value wins
+	matched, err = matchFilter("'${{ params.branch }}' == 'master'", params)
+	assert.NoError(t, err)
+	assert.False(t, matched)
+	// no value and no default
+	_, err = matchFilter("'${{ params.tag }}' == 'v1'", params)
+	assert.Error(t, err)
+}
+
+func TestMakeCreateRequest(t *testing.T) {
+	extra := apistructs.PipelineDefinitionExtraValue{
+		CreateRequest: &pipelinepb.PipelineCreateRequestV2{
+			PipelineYml:    "version: 1.1",
+			PipelineSource: "dice",
+			Labels:         map[string]string{apistructs.LabelAppID: "1"},
+			AutoStartCron:  true,
+		},
+		RunParams: []*basepb.PipelineRunParam{
+			{Name: "tag", Value: structpb.NewStringValue("latest")},
+			{Name: "env", Value: structpb.NewStringValue("test")},
+		},
+	}
+	req, err := makeCreateRequest(extra, "def-1", &db.PipelineWebhook{Creator: "2"}, map[string]string{"tag": "v1.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "def-1", req.DefinitionID)
+	assert.Equal(t, "2", req.UserID)
+	assert.True(t, req.AutoRunAtOnce)
+	assert.False(t, req.AutoStartCron)
+	assert.Equal(t, apistructs.PipelineTriggerModeWebhook.String(), req.NormalLabels[apistructs.LabelPipelineTriggerMode])
+	assert.Len(t, req.RunParams, 2)
+	assert.Equal(t, "env", req.RunParams[0].Name)
+	assert.Equal(t, "v1.0", req.RunParams[1].Value.GetStringValue())
+	// definition is not changed
+	assert.True(t, extra.CreateRequest.AutoStartCron)
+	assert.Nil(t, extra.CreateRequest.NormalLabels)
+
+	_, err = makeCreateRequest(apistructs.PipelineDefinitionExtraValue{}, "def-1", &db.PipelineWebhook{}, nil)
+	assert.Error(t, err)
+}
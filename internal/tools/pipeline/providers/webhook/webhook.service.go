@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/webhook/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/providers/webhook/db"
+	"github.com/erda-project/erda/internal/tools/pipeline/services/apierrors"
+	"github.com/erda-project/erda/pkg/common/apis"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+	"github.com/erda-project/erda/pkg/secret"
+)
+
+type webhookService struct {
+	p *provider
+}
+
+func (s *webhookService) CreatePipelineWebhook(ctx context.Context, req *pb.CreatePipelineWebhookRequest) (*pb.CreatePipelineWebhookResponse, error) {
+	if err := s.checkPermission(ctx, req.DefinitionID, apistructs.UpdateAction, apierrors.ErrCreatePipelineWebhook); err != nil {
+		return nil, err
+	}
+	keyPair := secret.CreateAkSkPair()
+	webhook := &db.PipelineWebhook{
+		DefinitionID: req.DefinitionID,
+		AccessKeyID:  keyPair.AccessKeyID,
+		SecretKey:    keyPair.SecretKey,
+		Creator:      apis.GetUserID(ctx),
+	}
+	if err := s.p.dbClient.SaveWebhook(webhook); err != nil {
+		return nil, apierrors.ErrCreatePipelineWebhook.InternalError(err)
+	}
+	data := webhook.Convert2PB(s.p.makeWebhookURL(req.DefinitionID))
+	data.Secret = webhook.SecretKey
+	return &pb.CreatePipelineWebhookResponse{Data: data}, nil
+}
+
+func (s *webhookService) GetPipelineWebhook(ctx context.Context, req *pb.GetPipelineWebhookRequest) (*pb.GetPipelineWebhookResponse, error) {
+	if err := s.checkPermission(ctx, req.DefinitionID, apistructs.GetAction, apierrors.ErrGetPipelineWebhook); err != nil {
+		return nil, err
+	}
+	webhook, err := s.p.dbClient.GetWebhookByDefinitionID(req.DefinitionID)
+	if err != nil {
+		return nil, apierrors.ErrGetPipelineWebhook.InternalError(err)
+	}
+	if webhook == nil {
+		return nil, apierrors.ErrGetPipelineWebhook.NotFound()
+	}
+	return &pb.GetPipelineWebhookResponse{Data: webhook.Convert2PB(s.p.makeWebhookURL(req.DefinitionID))}, nil
+}
+
+func (s *webhookService) DeletePipelineWebhook(ctx context.Context, req *pb.DeletePipelineWebhookRequest) (*pb.DeletePipelineWebhookResponse, error) {
+	if err := s.checkPermission(ctx, req.DefinitionID, apistructs.UpdateAction, apierrors.ErrDeletePipelineWebhook); err != nil {
+		return nil, err
+	}
+	if err := s.p.dbClient.DeleteWebhookByDefinitionID(req.DefinitionID); err != nil {
+		return nil, apierrors.ErrDeletePipelineWebhook.InternalError(err)
+	}
+	return &pb.DeletePipelineWebhookResponse{}, nil
+}
+
+func (s *webhookService) ListPipelineWebhookDeliveries(ctx context.Context, req *pb.ListPipelineWebhookDeliveriesRequest) (*pb.ListPipelineWebhookDeliveriesResponse, error) {
+	if err := s.checkPermission(ctx, req.DefinitionID, apistructs.GetAction, apierrors.ErrListPipelineWebhookDeliveries); err != nil {
+		return nil, err
+	}
+	deliveries, total, err := s.p.dbClient.ListDeliveries(db.ListDeliveriesRequest{
+		DefinitionID: req.DefinitionID,
+		Status:       req.Status,
+		PageNo:       int(req.PageNo),
+		PageSize:     int(req.PageSize),
+	})
+	if err != nil {
+		return nil, apierrors.ErrListPipelineWebhookDeliveries.InternalError(err)
+	}
+	data := make([]*pb.Delivery, 0, len(deliveries))
+	for i := range deliveries {
+		data = append(data, deliveries[i].Convert2PB())
+	}
+	return &pb.ListPipelineWebhookDeliveriesResponse{Data: data, Total: total}, nil
+}
+
+// ReceivePipelineWebhook is implemented by provider.ReceiveWebhook as a pure http handler.
+func (s *webhookService) ReceivePipelineWebhook(ctx context.Context, req *pb.ReceivePipelineWebhookRequest) (*pb.ReceivePipelineWebhookResponse, error) {
+	return nil, apierrors.ErrReceivePipelineWebhook.InvalidParameter("only http request is supported")
+}
+
+// checkPermission checks the permission on the app and branch of the definition.
+func (s *webhookService) checkPermission(ctx context.Context, definitionID string, action string, apiErr *errorresp.APIError) error {
+	if definitionID == "" {
+		return apiErr.InvalidParameter("missing definitionID")
+	}
+	extra, err := s.p.definitionDBClient.GetPipelineDefinitionExtraByDefinitionID(definitionID)
+	if err != nil {
+		return apiErr.InternalError(err)
+	}
+	if extra == nil || extra.Extra.CreateRequest == nil {
+		return apiErr.NotFound()
+	}
+	appID, branch := getDefinitionAppAndBranch(extra.Extra.CreateRequest.Labels, extra.Extra.CreateRequest.NormalLabels)
+	if err := s.p.Permission.CheckBranch(apis.GetIdentityInfo(ctx), appID, branch, action); err != nil {
+		return apiErr.AccessDenied()
+	}
+	return nil
+}
+
+// getDefinitionAppAndBranch returns app and branch from labels, labels too long are moved to normal labels.
+func getDefinitionAppAndBranch(labels, normalLabels map[string]string) (appID, branch string) {
+	get := func(key string) string {
+		if v := labels[key]; v != "" {
+			return v
+		}
+		return normalLabels[key]
+	}
+	return get(apistructs.LabelAppID), get(apistructs.LabelBranch)
+}
@@ -31,6 +31,7 @@ var (
 	ErrCreateSnippetPipeline = err("ErrCreateSnippetPipeline", "创建嵌套流水线失败")
 	ErrCreatePipelineTask    = err("ErrCreatePipelineTask", "创建流水线任务失败")
 	ErrBatchCreatePipeline   = err("ErrBatchCreatePipeline", "批量创建流水线失败")
+	ErrDryRunPipeline        = err("ErrDryRunPipeline", "预演流水线失败")
 	ErrListPipeline          = err("ErrListPipeline", "获取流水线列表失败")
 	ErrListInvokedCombos     = err("ErrListInvokedCombos", "获取流水线侧边栏信息失败")
 	ErrGetPipeline           = err("ErrGetPipeline", "获取流水线失败")
@@ -77,6 +78,13 @@ var (
 	ErrDeletePipelineCron   = err("ErrDeletePipelineCron", "删除流水线定时配置失败")
 	ErrNotFoundPipelineCron = err("ErrNotFoundPipelineCron", "未找到流水线定时配置")
 
+	ErrCreatePipelineCronCalendar       = err("ErrCreatePipelineCronCalendar", "创建定时排除日历失败")
+	ErrUpdatePipelineCronCalendar       = err("ErrUpdatePipelineCronCalendar", "更新定时排除日历失败")
+	ErrGetPipelineCronCalendar          = err("ErrGetPipelineCronCalendar", "获取定时排除日历失败")
+	ErrDeletePipelineCronCalendar       = err("ErrDeletePipelineCronCalendar", "删除定时排除日历失败")
+	ErrPagingPipelineCronCalendar       = err("ErrPagingPipelineCronCalendar", "分页获取定时排除日历失败")
+	ErrPagingPipelineCronSkippedTrigger = err("ErrPagingPipelineCronSkippedTrigger", "分页获取定时跳过记录失败")
+
 	ErrCreatePipelineQueue  = err("ErrCreatePipelineQueue", "创建流水线队列失败")
 	ErrGetPipelineQueue     = err("ErrGetPipelineQueue", "查询流水线队列失败")
 	ErrPagingPipelineQueues = err("ErrPagingPipelineQueues", "分页查询流水线队列失败")
@@ -127,6 +135,18 @@ var (
 	ErrGetPipelineAction    = err("ErrGetPipelineAction", "获取流水线任务定义失败")
 	ErrListPipelineAction   = err("ErrListPipelineAction", "列出流水线任务定义失败")
 
+	ErrUploadPipelineArtifact   = err("ErrUploadPipelineArtifact", "上传流水线 artifact 失败")
+	ErrDownloadPipelineArtifact = err("ErrDownloadPipelineArtifact", "下载流水线 artifact 失败")
+	ErrListPipelineArtifact     = err("ErrListPipelineArtifact", "列出流水线 artifact 失败")
+
+	ErrCreatePipelineWebhook         = err("ErrCreatePipelineWebhook", "创建流水线 webhook 失败")
+	ErrGetPipelineWebhook            = err("ErrGetPipelineWebhook", "获取流水线 webhook 失败")
+	ErrDeletePipelineWebhook         = err("ErrDeletePipelineWebhook", "删除流水线 webhook 失败")
+	ErrReceivePipelineWebhook        = err("ErrReceivePipelineWebhook", "接收流水线 webhook 失败")
+	ErrListPipelineWebhookDeliveries = err("ErrListPipelineWebhookDeliveries", "列出流水线 webhook 投递记录失败")
+
+	ErrGetPipelineAnalytics = err("ErrGetPipelineAnalytics", "获取流水线统计分析失败")
+
 	// action-runner-scheduler
 	ErrCreateRunnerTask  = err("ErrCreateRunnerTask", "创建runner任务失败")
 	ErrGetRunnerTask     = err("ErrGetRunnerTask", "获取runner任务失败")
@@ -114,6 +114,8 @@ type PipelineExtraInfo struct {
 
 	QueueInfo *QueueInfo `json:"queueInfo,omitempty"`
 
+	Concurrency *ConcurrencyInfo `json:"concurrency,omitempty"`
+
 	TaskOperates []*pipelinepb.PipelineTaskOperateRequest `json:"taskTaskOperates,omitempty"`
 
 	ContainerInstanceProvider *apistructs.ContainerInstanceProvider `json:"containerInstanceProvider,omitempty"`
@@ -129,6 +131,14 @@ type QueueInfo struct {
 	PriorityChangeHistory []int64 `json:"priorityChangeHistory,omitempty"`
 }
 
+// ConcurrencyInfo is rendered from the concurrency config of pipeline.yml when pipeline run.
+type ConcurrencyInfo struct {
+	Group            string `json:"group"`
+	CancelInProgress bool   `json:"cancelInProgress,omitempty"`
+	// SupersededBy is the newer pipeline of the same group which cancels this pipeline
+	SupersededBy uint64 `json:"supersededBy,omitempty"`
+}
+
 type Snapshot struct {
 	PipelineYml     string            `json:"pipeline_yml,omitempty"` // 对占位符进行渲染
 	Secrets         map[string]string `json:"secrets,omitempty"`
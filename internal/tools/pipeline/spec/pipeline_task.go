@@ -169,6 +169,17 @@ type PipelineTaskExtra struct {
 	ContainerInstanceProvider *apistructs.ContainerInstanceProvider `json:"containerInstanceProvider,omitempty"`
 
 	Breakpoint *basepb.Breakpoint `json:"breakpoint,omitempty"`
+
+	// OpTimes records the time of each task op, key is the op, like prepare, queue, wait
+	OpTimes map[string]TaskOpTime `json:"opTimes,omitempty"`
+}
+
+// TaskOpTime is the time of a task op.
+type TaskOpTime struct {
+	Begin time.Time `json:"begin"`
+	// Processed is the time the op processing returned, before handling the result
+	Processed time.Time `json:"processed,omitempty"`
+	End       time.Time `json:"end"`
 }
 
 type FlinkSparkConf struct {
@@ -252,6 +263,8 @@ func (that PipelineTaskExecutorKind) GetDefaultExecutorName() PipelineTaskExecut
 		return PipelineTaskExecutorNameK8sFlinkDefault
 	case PipelineTaskExecutorKindK8sSpark:
 		return PipelineTaskExecutorNameK8sSparkDefault
+	case PipelineTaskExecutorKindMemory:
+		return PipelineTaskExecutorNameMemoryDefault
 	}
 	return PipelineTaskExecutorNameEmpty
 }
@@ -271,7 +284,8 @@ var (
 	PipelineTaskExecutorNameK8sFlinkDefault  PipelineTaskExecutorName = "k8s-flink"
 	PipelineTaskExecutorNameK8sSparkDefault  PipelineTaskExecutorName = "k8s-spark"
 	PipelineTaskExecutorNameDockerDefault    PipelineTaskExecutorName = "docker"
-	PipelineTaskExecutorNameList                                      = []PipelineTaskExecutorName{PipelineTaskExecutorNameEmpty, PipelineTaskExecutorNameSchedulerDefault, PipelineTaskExecutorNameAPITestDefault, PipelineTaskExecutorNameWaitDefault, PipelineTaskExecutorNameK8sJobDefault}
+	PipelineTaskExecutorNameMemoryDefault    PipelineTaskExecutorName = "memory"
+	PipelineTaskExecutorNameList                                      = []PipelineTaskExecutorName{PipelineTaskExecutorNameEmpty, PipelineTaskExecutorNameSchedulerDefault, PipelineTaskExecutorNameAPITestDefault, PipelineTaskExecutorNameWaitDefault, PipelineTaskExecutorNameK8sJobDefault, PipelineTaskExecutorNameMemoryDefault}
 )
 
 func (that PipelineTaskExecutorName) Check() bool {
@@ -16,6 +16,7 @@ package cloudstorage
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -27,7 +28,10 @@ import (
 type Client interface {
 	UploadFile(bucketName, objectName, file string) (string, error)
 	DownloadFile(bucketName, objectName string) ([]byte, error)
+	// DownloadFileStream returns a reader of the object and its size; caller must close the reader.
+	DownloadFileStream(bucketName, objectName string) (io.ReadCloser, int64, error)
 	GetFileUrl(bucketName, objectName string) (string, error)
+	DeleteFile(bucketName, objectName string) error
 	HealthCheck() error
 }
 
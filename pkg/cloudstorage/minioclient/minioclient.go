@@ -87,6 +87,19 @@ func (c *MinioClient) DownloadFile(bucketName, objectName string) ([]byte, error
 	return data, nil
 }
 
+func (c *MinioClient) DownloadFileStream(bucketName, objectName string) (io.ReadCloser, int64, error) {
+	obj, err := c.client.GetObject(bucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, 0, err
+	}
+	return obj, info.Size, nil
+}
+
 func (c *MinioClient) GetFileUrl(bucketName, objectName string) (string, error) {
 	info, err := c.client.StatObject(bucketName, objectName, minio.StatObjectOptions{})
 	if err != nil {
@@ -98,6 +111,13 @@ func (c *MinioClient) GetFileUrl(bucketName, objectName string) (string, error)
 	return strings.Join([]string{c.endpoint, bucketName, objectName}, "/"), nil
 }
 
+func (c *MinioClient) DeleteFile(bucketName, objectName string) error {
+	if err := c.client.RemoveObject(bucketName, objectName); err != nil {
+		return errors.Wrapf(err, "delete bk=%s file=%s", bucketName, objectName)
+	}
+	return nil
+}
+
 func (c *MinioClient) HealthCheck() error {
 	if _, err := c.client.BucketExists("bucket"); err != nil {
 		return err
@@ -16,6 +16,7 @@ package ossclient
 
 import (
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
@@ -85,6 +86,26 @@ func (c *OssClient) DownloadFile(bucketName, objectName string) ([]byte, error)
 	return data, nil
 }
 
+func (c *OssClient) DownloadFileStream(bucketName, objectName string) (io.ReadCloser, int64, error) {
+	bucket, err := c.client.Bucket(bucketName)
+	if err != nil {
+		return nil, 0, err
+	}
+	meta, err := bucket.GetObjectDetailedMeta(objectName)
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := strconv.ParseInt(meta.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "parse content length")
+	}
+	reader, err := bucket.GetObject(objectName)
+	if err != nil {
+		return nil, 0, err
+	}
+	return reader, size, nil
+}
+
 func (c *OssClient) GetFileUrl(bucketName, objectName string) (string, error) {
 	bucket, err := c.client.Bucket(bucketName)
 	if err != nil {
@@ -100,6 +121,14 @@ func (c *OssClient) GetFileUrl(bucketName, objectName string) (string, error) {
 	return strings.Join([]string{c.endpoint, bucketName, objectName}, "/"), nil
 }
 
+func (c *OssClient) DeleteFile(bucketName, objectName string) error {
+	bucket, err := c.client.Bucket(bucketName)
+	if err != nil {
+		return errors.Wrap(err, "get bucket")
+	}
+	return bucket.DeleteObject(objectName)
+}
+
 func (c *OssClient) HealthCheck() error {
 	panic("not implement")
 }
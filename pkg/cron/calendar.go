@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cron
+
+import (
+	"fmt"
+	"time"
+)
+
+// layouts accepted by exclusion start and end
+const (
+	DateLayout     = "2006-01-02"
+	DateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// Calendar is a named set of exclusions, scheduled runs inside any exclusion are skipped.
+type Calendar struct {
+	Name       string
+	Location   *time.Location
+	Exclusions []Exclusion
+}
+
+// Exclusion is a period in which scheduled runs are skipped.
+// A fixed exclusion covers [Start, End), a recurring exclusion starts at
+// every activation of Schedule and lasts Duration.
+type Exclusion struct {
+	Name string
+
+	Start time.Time
+	End   time.Time
+
+	Schedule Schedule
+	Duration time.Duration
+}
+
+// ParseFixedExclusion parses a fixed exclusion, start and end are in DateLayout or DateTimeLayout.
+// A date-only end includes the whole day.
+func ParseFixedExclusion(name, start, end string, loc *time.Location) (Exclusion, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	startTime, _, err := parseCalendarTime(start, loc)
+	if err != nil {
+		return Exclusion{}, fmt.Errorf("invalid start of exclusion %q: %v", name, err)
+	}
+	if end == "" {
+		end = start
+	}
+	endTime, dateOnly, err := parseCalendarTime(end, loc)
+	if err != nil {
+		return Exclusion{}, fmt.Errorf("invalid end of exclusion %q: %v", name, err)
+	}
+	if dateOnly {
+		endTime = endTime.AddDate(0, 0, 1)
+	}
+	if !endTime.After(startTime) {
+		return Exclusion{}, fmt.Errorf("end of exclusion %q must be after start", name)
+	}
+	return Exclusion{Name: name, Start: startTime, End: endTime}, nil
+}
+
+// ParseRecurringExclusion parses a recurring exclusion, e.g. a freeze window
+// starting at "0 0 18 * * FRI" that lasts "62h".
+func ParseRecurringExclusion(name, spec, duration string, loc *time.Location) (Exclusion, error) {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return Exclusion{}, fmt.Errorf("invalid cron of exclusion %q: %v", name, err)
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return Exclusion{}, fmt.Errorf("invalid duration of exclusion %q: %v", name, err)
+	}
+	if d <= 0 {
+		return Exclusion{}, fmt.Errorf("duration of exclusion %q must be positive", name)
+	}
+	return Exclusion{Name: name, Schedule: InLocation(schedule, loc), Duration: d}, nil
+}
+
+func parseCalendarTime(s string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.ParseInLocation(DateTimeLayout, s, loc); err == nil {
+		return t, false, nil
+	}
+	if t, err = time.ParseInLocation(DateLayout, s, loc); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("expected %q or %q, got %q", DateLayout, DateTimeLayout, s)
+}
+
+// Contains returns true if t is inside the exclusion.
+func (e Exclusion) Contains(t time.Time) bool {
+	if e.Schedule != nil {
+		// any window containing t starts in (t-Duration, t]
+		start := e.Schedule.Next(t.Add(-e.Duration))
+		return !start.IsZero() && !start.After(t)
+	}
+	return !t.Before(e.Start) && t.Before(e.End)
+}
+
+// Match returns the first exclusion containing t.
+func (c Calendar) Match(t time.Time) (*Exclusion, bool) {
+	for i := range c.Exclusions {
+		if c.Exclusions[i].Contains(t) {
+			return &c.Exclusions[i], true
+		}
+	}
+	return nil, false
+}
+
+// MatchCalendars returns the first calendar and exclusion containing t.
+func MatchCalendars(calendars []Calendar, t time.Time) (*Calendar, *Exclusion, bool) {
+	for i := range calendars {
+		if exclusion, ok := calendars[i].Match(t); ok {
+			return &calendars[i], exclusion, true
+		}
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFixedExclusion(t *testing.T) {
+	e, err := ParseFixedExclusion("national day", "2026-10-01", "2026-10-07", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		time     time.Time
+		expected bool
+	}{
+		{time.Date(2026, 9, 30, 23, 59, 59, 0, time.UTC), false},
+		{time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 10, 7, 23, 59, 59, 0, time.UTC), true},
+		{time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if actual := e.Contains(test.time); actual != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.time, test.expected, actual)
+		}
+	}
+
+	if _, err := ParseFixedExclusion("bad", "2026-10-07 10:00:00", "2026-10-07 09:00:00", time.UTC); err == nil {
+		t.Error("expected error when end is before start")
+	}
+	if _, err := ParseFixedExclusion("bad", "10/07/2026", "", time.UTC); err == nil {
+		t.Error("expected error for invalid layout")
+	}
+}
+
+func TestParseRecurringExclusion(t *testing.T) {
+	// freeze from friday 18:00 to monday 08:00
+	e, err := ParseRecurringExclusion("weekend freeze", "0 0 18 * * FRI", "62h", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		time     time.Time
+		expected bool
+	}{
+		{time.Date(2026, 10, 16, 17, 59, 59, 0, time.UTC), false},
+		{time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 10, 19, 7, 59, 59, 0, time.UTC), true},
+		{time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if actual := e.Contains(test.time); actual != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.time, test.expected, actual)
+		}
+	}
+
+	if _, err := ParseRecurringExclusion("bad", "0 0 18 * * FRI", "-1h", time.UTC); err == nil {
+		t.Error("expected error for negative duration")
+	}
+}
+
+func TestMatchCalendars(t *testing.T) {
+	holiday, _ := ParseFixedExclusion("national day", "2026-10-01", "2026-10-07", time.UTC)
+	calendars := []Calendar{
+		{Name: "empty"},
+		{Name: "holidays", Location: time.UTC, Exclusions: []Exclusion{holiday}},
+	}
+	calendar, exclusion, ok := MatchCalendars(calendars, time.Date(2026, 10, 2, 8, 0, 0, 0, time.UTC))
+	if !ok || calendar.Name != "holidays" || exclusion.Name != "national day" {
+		t.Errorf("expected match of holidays/national day, got %v %v %v", calendar, exclusion, ok)
+	}
+	if _, _, ok := MatchCalendars(calendars, time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)); ok {
+		t.Error("expected no match")
+	}
+}
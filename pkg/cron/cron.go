@@ -185,14 +185,22 @@ func (c *Cron) AddJob(spec string, cmd Job, names ...string) error {
 	var name string
 	var schedule Schedule
 	var err error
-	if len(strings.Fields(spec)) == 5 {
-		schedule, err = cron.ParseStandard(spec)
+	tz, expr := SplitTimeZone(spec)
+	if len(strings.Fields(expr)) == 5 {
+		schedule, err = cron.ParseStandard(expr)
 	} else {
-		schedule, err = cron.Parse(spec)
+		schedule, err = cron.Parse(expr)
 	}
 	if err != nil {
 		return err
 	}
+	if tz != "" {
+		loc, err := LoadLocation(tz)
+		if err != nil {
+			return err
+		}
+		schedule = InLocation(schedule, loc)
+	}
 	if len(names) <= 0 {
 		name = fmt.Sprintf("%d", time.Now().Unix())
 	} else {
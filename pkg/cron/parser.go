@@ -89,6 +89,18 @@ func NewParser(options ParseOption) Parser {
 // It returns a descriptive error if the spec is not valid.
 // It accepts crontab specs and features configured by NewParser.
 func (p Parser) Parse(spec string) (Schedule, error) {
+	tz, spec := SplitTimeZone(spec)
+	if tz != "" {
+		loc, err := LoadLocation(tz)
+		if err != nil {
+			return nil, err
+		}
+		schedule, err := p.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		return InLocation(schedule, loc), nil
+	}
 	if len(spec) == 0 {
 		return nil, fmt.Errorf("Empty spec string")
 	}
@@ -0,0 +1,93 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cron
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// time zone prefixes accepted before a cron spec, e.g. "CRON_TZ=Asia/Shanghai 0 0 8 * * ?"
+const (
+	CronTimeZonePrefix = "CRON_TZ="
+	timeZonePrefix     = "TZ="
+)
+
+// SplitTimeZone splits an optional `CRON_TZ=` or `TZ=` prefix from the spec.
+// It returns an empty time zone if the spec has no prefix.
+func SplitTimeZone(spec string) (tz string, expr string) {
+	spec = strings.TrimSpace(spec)
+	for _, prefix := range []string{CronTimeZonePrefix, timeZonePrefix} {
+		if !strings.HasPrefix(spec, prefix) {
+			continue
+		}
+		rest := spec[len(prefix):]
+		i := strings.IndexAny(rest, " \t")
+		if i < 0 {
+			return rest, ""
+		}
+		return rest[:i], strings.TrimSpace(rest[i:])
+	}
+	return "", spec
+}
+
+// WithTimeZone prefixes expr with `CRON_TZ=<tz>`.
+// The expr is returned as-is if tz is empty or expr already carries a time zone.
+func WithTimeZone(expr, tz string) string {
+	if tz == "" || expr == "" {
+		return expr
+	}
+	if existed, _ := SplitTimeZone(expr); existed != "" {
+		return expr
+	}
+	return CronTimeZonePrefix + tz + " " + expr
+}
+
+// LoadLocation loads the IANA time zone, empty tz means local.
+func LoadLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %v", tz, err)
+	}
+	return loc, nil
+}
+
+// locationSchedule evaluates the wrapped schedule in the given location.
+type locationSchedule struct {
+	schedule Schedule
+	location *time.Location
+}
+
+// InLocation returns a schedule whose activation times are calculated in loc.
+func InLocation(schedule Schedule, loc *time.Location) Schedule {
+	if loc == nil {
+		return schedule
+	}
+	return &locationSchedule{schedule: schedule, location: loc}
+}
+
+// Next returns the next activation time in the schedule location,
+// converted back to the location of the given time.
+func (s *locationSchedule) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t.In(s.location))
+	if next.IsZero() {
+		return next
+	}
+	return next.In(t.Location())
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitTimeZone(t *testing.T) {
+	tests := []struct {
+		spec, tz, expr string
+	}{
+		{"0 0 8 * * ?", "", "0 0 8 * * ?"},
+		{"CRON_TZ=Asia/Shanghai 0 0 8 * * ?", "Asia/Shanghai", "0 0 8 * * ?"},
+		{"  TZ=UTC   0 8 * * *", "UTC", "0 8 * * *"},
+		{"CRON_TZ=UTC", "UTC", ""},
+	}
+	for _, test := range tests {
+		tz, expr := SplitTimeZone(test.spec)
+		if tz != test.tz || expr != test.expr {
+			t.Errorf("%q: expected (%q, %q), got (%q, %q)", test.spec, test.tz, test.expr, tz, expr)
+		}
+	}
+}
+
+func TestWithTimeZone(t *testing.T) {
+	tests := []struct {
+		expr, tz, expected string
+	}{
+		{"0 0 8 * * ?", "", "0 0 8 * * ?"},
+		{"0 0 8 * * ?", "UTC", "CRON_TZ=UTC 0 0 8 * * ?"},
+		{"TZ=Asia/Shanghai 0 0 8 * * ?", "UTC", "TZ=Asia/Shanghai 0 0 8 * * ?"},
+	}
+	for _, test := range tests {
+		if actual := WithTimeZone(test.expr, test.tz); actual != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, actual)
+		}
+	}
+}
+
+func TestParseWithTimeZone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	schedule, err := Parse("CRON_TZ=Asia/Shanghai 0 0 8 * * ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	next := schedule.Next(from)
+	expected := time.Date(2026, 10, 18, 8, 0, 0, 0, shanghai)
+	if !next.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, next)
+	}
+	if next.Location() != time.UTC {
+		t.Errorf("expected next in the location of from, got %v", next.Location())
+	}
+
+	if _, err := Parse("CRON_TZ=Nowhere/Land 0 0 8 * * ?"); err == nil {
+		t.Error("expected error for invalid time zone")
+	}
+}
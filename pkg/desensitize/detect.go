@@ -0,0 +1,221 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package desensitize
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kind is a kind of sensitive data.
+type Kind string
+
+const (
+	KindMobile     Kind = "mobile"
+	KindIDCard     Kind = "id_card"
+	KindEmail      Kind = "email"
+	KindBankCard   Kind = "bank_card"
+	KindAccessKey  Kind = "access_key"
+	KindPrivateKey Kind = "private_key"
+	KindJWT        Kind = "jwt"
+)
+
+// Rule detects and masks one kind of sensitive data.
+type Rule struct {
+	Kind    Kind
+	Pattern *regexp.Regexp
+	// Validate filters out false positives of Pattern, optional.
+	Validate func(s string) bool
+	// Digits requires the match not to be part of a longer digit sequence.
+	Digits bool
+	Mask   func(s string) string
+}
+
+// Rules are ordered by priority, overlapped findings are resolved in favor of the earlier rule.
+var Rules = []Rule{
+	{
+		Kind:    KindPrivateKey,
+		Pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----[\s\S]*?-----END (?:[A-Z]+ )*PRIVATE KEY-----`),
+		Mask:    func(string) string { return "-----PRIVATE KEY REDACTED-----" },
+	},
+	{
+		Kind:    KindJWT,
+		Pattern: regexp.MustCompile(`eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`),
+		Mask:    Secret,
+	},
+	{
+		Kind: KindAccessKey,
+		// aws, aliyun, tencent cloud access key ids, and openai-style secret keys
+		Pattern: regexp.MustCompile(`\b(?:(?:AKIA|ASIA)[0-9A-Z]{16}|LTAI[0-9A-Za-z]{12,24}|AKID[0-9A-Za-z]{13,40}|sk-[A-Za-z0-9_-]{20,})\b`),
+		Mask:    Secret,
+	},
+	{
+		Kind:     KindIDCard,
+		Pattern:  regexp.MustCompile(`[1-9]\d{5}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]`),
+		Validate: validIDCard,
+		Digits:   true,
+		Mask:     IDCard,
+	},
+	{
+		Kind:     KindBankCard,
+		Pattern:  regexp.MustCompile(`[1-9]\d{12,18}`),
+		Validate: luhn,
+		Digits:   true,
+		Mask:     BankCard,
+	},
+	{
+		Kind:    KindEmail,
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		Mask:    Email,
+	},
+	{
+		Kind:    KindMobile,
+		Pattern: regexp.MustCompile(`1[3-9]\d{9}`),
+		Digits:  true,
+		Mask:    Mobile,
+	},
+}
+
+// Finding is a piece of sensitive data found in text, [Start, End) are byte offsets.
+type Finding struct {
+	Kind  Kind
+	Start int
+	End   int
+}
+
+// Scan finds sensitive data of the given kinds in text, all kinds if none given.
+// Findings are sorted by Start and never overlap.
+func Scan(text string, kinds ...Kind) []Finding {
+	var findings []Finding
+	for _, rule := range Rules {
+		if !containsKind(kinds, rule.Kind) {
+			continue
+		}
+		for _, loc := range rule.Pattern.FindAllStringIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if rule.Digits && (isDigitAt(text, start-1) || isDigitAt(text, end)) {
+				continue
+			}
+			if rule.Validate != nil && !rule.Validate(text[start:end]) {
+				continue
+			}
+			if overlaps(findings, start, end) {
+				continue
+			}
+			findings = append(findings, Finding{Kind: rule.Kind, Start: start, End: end})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	return findings
+}
+
+// MaskText replaces sensitive data of the given kinds in text, all kinds if none given.
+func MaskText(text string, kinds ...Kind) (string, []Finding) {
+	findings := Scan(text, kinds...)
+	if len(findings) == 0 {
+		return text, nil
+	}
+	var sb strings.Builder
+	last := 0
+	for _, f := range findings {
+		sb.WriteString(text[last:f.Start])
+		sb.WriteString(ruleOf(f.Kind).Mask(text[f.Start:f.End]))
+		last = f.End
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), findings
+}
+
+// IDCard keeps the first 6 (region) and the last 4 characters.
+func IDCard(str string) string {
+	return desensitize(str, 6, 4)
+}
+
+// BankCard keeps the first 6 (issuer) and the last 4 digits.
+func BankCard(str string) string {
+	return desensitize(str, 6, 4)
+}
+
+// Secret keeps the first 4 characters only.
+func Secret(str string) string {
+	return desensitize(str, 4, 0)
+}
+
+func ruleOf(kind Kind) Rule {
+	for _, rule := range Rules {
+		if rule.Kind == kind {
+			return rule
+		}
+	}
+	return Rule{Kind: kind, Mask: func(s string) string { return desensitize(s, 0, 0) }}
+}
+
+func containsKind(kinds []Kind, kind Kind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(findings []Finding, start, end int) bool {
+	for _, f := range findings {
+		if start < f.End && f.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigitAt(s string, i int) bool {
+	return i >= 0 && i < len(s) && s[i] >= '0' && s[i] <= '9'
+}
+
+// validIDCard verifies the check digit of an 18-digit resident identity card number (GB 11643).
+func validIDCard(s string) bool {
+	if len(s) != 18 {
+		return false
+	}
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	const checks = "10X98765432"
+	sum := 0
+	for i := 0; i < 17; i++ {
+		sum += int(s[i]-'0') * weights[i]
+	}
+	return checks[sum%11] == strings.ToUpper(s[17:])[0]
+}
+
+// luhn verifies bank card numbers.
+func luhn(s string) bool {
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
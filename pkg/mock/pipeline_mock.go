@@ -191,6 +191,26 @@ func (mr *MockPipelineServiceClientMockRecorder) PipelineDetail(ctx, in interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PipelineDetail", reflect.TypeOf((*MockPipelineServiceClient)(nil).PipelineDetail), varargs...)
 }
 
+// PipelineDryRun mocks base method.
+func (m *MockPipelineServiceClient) PipelineDryRun(ctx context.Context, in *pb.PipelineCreateRequestV2, opts ...grpc.CallOption) (*pb.PipelineDryRunResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "PipelineDryRun", varargs...)
+	ret0, _ := ret[0].(*pb.PipelineDryRunResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PipelineDryRun indicates an expected call of PipelineDryRun.
+func (mr *MockPipelineServiceClientMockRecorder) PipelineDryRun(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PipelineDryRun", reflect.TypeOf((*MockPipelineServiceClient)(nil).PipelineDryRun), varargs...)
+}
+
 // PipelineOperate mocks base method.
 func (m *MockPipelineServiceClient) PipelineOperate(ctx context.Context, in *pb.PipelineOperateRequest, opts ...grpc.CallOption) (*pb.PipelineOperateResponse, error) {
 	m.ctrl.T.Helper()
@@ -479,6 +499,21 @@ func (mr *MockPipelineServiceServerMockRecorder) PipelineDetail(arg0, arg1 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PipelineDetail", reflect.TypeOf((*MockPipelineServiceServer)(nil).PipelineDetail), arg0, arg1)
 }
 
+// PipelineDryRun mocks base method.
+func (m *MockPipelineServiceServer) PipelineDryRun(arg0 context.Context, arg1 *pb.PipelineCreateRequestV2) (*pb.PipelineDryRunResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PipelineDryRun", arg0, arg1)
+	ret0, _ := ret[0].(*pb.PipelineDryRunResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PipelineDryRun indicates an expected call of PipelineDryRun.
+func (mr *MockPipelineServiceServerMockRecorder) PipelineDryRun(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PipelineDryRun", reflect.TypeOf((*MockPipelineServiceServer)(nil).PipelineDryRun), arg0, arg1)
+}
+
 // PipelineOperate mocks base method.
 func (m *MockPipelineServiceServer) PipelineOperate(arg0 context.Context, arg1 *pb.PipelineOperateRequest) (*pb.PipelineOperateResponse, error) {
 	m.ctrl.T.Helper()
@@ -46,6 +46,10 @@ type Spec struct {
 
 	Cron            string           `yaml:"cron,omitempty"`
 	CronCompensator *CronCompensator `yaml:"cron_compensator,omitempty"`
+	CronTimeZone    string           `yaml:"cron_time_zone,omitempty"` // IANA 时区, cron 中的 CRON_TZ= 前缀优先
+	CronCalendars   []string         `yaml:"cron_calendars,omitempty"` // 引用的排除日历名称
+
+	Concurrency *ConcurrencyConfig `yaml:"concurrency,omitempty"`
 
 	Stages []*Stage `yaml:"stages"`
 
@@ -64,6 +68,9 @@ type Spec struct {
 	// allActions represents all actions from all stages
 	allActions map[ActionAlias]*indexedAction
 
+	// matrixGroups represents the expanded instances of matrix actions, key is the alias of matrix action
+	matrixGroups map[ActionAlias][]ActionAlias
+
 	// defines the breakpoint config for tasks on global pipeline
 	Breakpoint *pb.Breakpoint `yaml:"breakpoint,omitempty"`
 }
@@ -97,8 +104,9 @@ type StorageConfig struct {
 }
 
 type TriggerConfig struct {
-	Push  *PushTrigger  `yaml:"push,omitempty"`
-	Merge *MergeTrigger `yaml:"merge,omitempty"`
+	Push    *PushTrigger    `yaml:"push,omitempty"`
+	Merge   *MergeTrigger   `yaml:"merge,omitempty"`
+	Webhook *WebhookTrigger `yaml:"webhook,omitempty"`
 }
 
 type PushTrigger struct {
@@ -110,6 +118,22 @@ type MergeTrigger struct {
 	Branches []string `yaml:"branches,omitempty"`
 }
 
+// WebhookTrigger starts the pipeline when an external system posts to the definition's webhook url.
+type WebhookTrigger struct {
+	// Signature is the signature style of inbound deliveries: erda (default), github or gitlab.
+	Signature string `yaml:"signature,omitempty"`
+	// Params maps pipeline params to JSONPath expressions on the payload, e.g. `tag: $.push_data.tag`.
+	Params map[string]string `yaml:"params,omitempty"`
+	// Filter is an expression over `${{ params.xxx }}`, deliveries are skipped if it is not true.
+	Filter string `yaml:"filter,omitempty"`
+}
+
+const (
+	WebhookSignatureErda   = "erda"
+	WebhookSignatureGithub = "github"
+	WebhookSignatureGitlab = "gitlab"
+)
+
 type indexedAction struct {
 	*Action
 	stageIndex int
@@ -160,6 +184,9 @@ type Action struct {
 
 	Caches []ActionCache `yaml:"caches,omitempty"` // action 构建缓存
 
+	Artifacts         []ActionArtifact         `yaml:"artifacts,omitempty"`          // files persisted to object storage after the action finished
+	DownloadArtifacts []ActionArtifactDownload `yaml:"download_artifacts,omitempty"` // artifacts downloaded before the action runs
+
 	Policy *Policy `yaml:"policy,omitempty"` // action execution strategy
 
 	SnippetConfig *SnippetConfig `yaml:"snippet_config,omitempty"` // snippet 类型的 action 的配置
@@ -168,6 +195,11 @@ type Action struct {
 
 	Disable bool `yaml:"disable,omitempty"` // make task disable or enable
 
+	Matrix *Matrix `yaml:"matrix,omitempty"` // run the action once per combination of matrix variables
+
+	// MatrixInstance 由 parser 在展开 matrix 时赋值，不开放给用户使用。
+	MatrixInstance *MatrixInstance `yaml:"matrix_instance,omitempty"`
+
 	// TODO 在未来版本中，可能去除 stage，依赖关系则必须通过 Needs 来声明。
 	// 目前不开放给用户使用。由 parser 自动赋值。
 	// Needs 显式声明依赖的 actions。隐式依赖关系是下一个 stage 依赖之前所有 stage 里的 action。
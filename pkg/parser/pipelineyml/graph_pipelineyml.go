@@ -39,6 +39,12 @@ func ConvertGraphPipelineYmlContent(data []byte) ([]byte, error) {
 			StopIfLatterExecuted: frontendYmlSpec.CronCompensator.StopIfLatterExecuted,
 		}
 	}
+	if frontendYmlSpec.Concurrency != nil {
+		s.Concurrency = &ConcurrencyConfig{
+			Group:            frontendYmlSpec.Concurrency.Group,
+			CancelInProgress: frontendYmlSpec.Concurrency.CancelInProgress,
+		}
+	}
 	s.Stages = make([]*Stage, 0)
 	for _, stage := range frontendYmlSpec.Stages {
 		actions := make([]typedActionMap, 0)
@@ -88,6 +94,15 @@ func ConvertGraphPipelineYmlContent(data []byte) ([]byte, error) {
 				}
 			}
 
+			if frontendAction.Matrix != nil {
+				maps[ActionType(frontendAction.Type)].Matrix = &Matrix{
+					Axes:        frontendAction.Matrix.Axes,
+					Include:     frontendAction.Matrix.Include,
+					Exclude:     frontendAction.Matrix.Exclude,
+					MaxParallel: frontendAction.Matrix.MaxParallel,
+				}
+			}
+
 			actions = append(actions, maps)
 		}
 		s.Stages = append(s.Stages, &Stage{Actions: actions})
@@ -121,7 +136,8 @@ func ConvertGraphPipelineYmlContent(data []byte) ([]byte, error) {
 // ConvertToGraphPipelineYml: YAML(Spec) -> pb.PipelineYml
 func ConvertToGraphPipelineYml(data []byte) (*pb.PipelineYml, error) {
 
-	pipelineYml, err := New(data, WithFlatParams(false))
+	// matrix action is shown as a group, instances are listed in the action
+	pipelineYml, err := New(data, WithFlatParams(false), WithMatrixExpansion(false))
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +167,8 @@ func ConvertToGraphPipelineYml(data []byte) (*pb.PipelineYml, error) {
 	if pipelineYml.Spec().On != nil {
 		merge := pipelineYml.Spec().On.Merge
 		push := pipelineYml.Spec().On.Push
-		if merge != nil || push != nil {
+		webhook := pipelineYml.Spec().On.Webhook
+		if merge != nil || push != nil || webhook != nil {
 			on = &pb.TriggerConfig{}
 			if merge != nil {
 				var branches []string
@@ -173,6 +190,13 @@ func ConvertToGraphPipelineYml(data []byte) (*pb.PipelineYml, error) {
 					Tags:     tags,
 				}
 			}
+			if webhook != nil {
+				on.Webhook = &pb.WebhookTrigger{
+					Signature: webhook.Signature,
+					Params:    webhook.Params,
+					Filter:    webhook.Filter,
+				}
+			}
 		}
 	}
 
@@ -207,6 +231,15 @@ func ConvertToGraphPipelineYml(data []byte) (*pb.PipelineYml, error) {
 				StopIfLatterExecuted: pipelineYml.Spec().CronCompensator.StopIfLatterExecuted,
 			}
 		}(),
+		Concurrency: func() *pb.PipelineConcurrency {
+			if pipelineYml.Spec().Concurrency == nil {
+				return nil
+			}
+			return &pb.PipelineConcurrency{
+				Group:            pipelineYml.Spec().Concurrency.Group,
+				CancelInProgress: pipelineYml.Spec().Concurrency.CancelInProgress,
+			}
+		}(),
 	}
 
 	var lifecycle []*pb.NetworkHookInfo
@@ -275,6 +308,25 @@ func ConvertToGraphPipelineYml(data []byte) (*pb.PipelineYml, error) {
 						Type: action.Policy.Type,
 					}
 				}
+
+				if action.Matrix != nil {
+					resultAction.Matrix = &apistructs.PipelineYmlMatrix{
+						Axes:        action.Matrix.Axes,
+						Include:     action.Matrix.Include,
+						Exclude:     action.Matrix.Exclude,
+						MaxParallel: action.Matrix.MaxParallel,
+					}
+					instances, err := expandMatrixAction(action.Type, action)
+					if err != nil {
+						return nil, err
+					}
+					for _, instance := range instances {
+						resultAction.MatrixInstances = append(resultAction.MatrixInstances, apistructs.PipelineYmlMatrixInstance{
+							Alias:  instance.Alias.String(),
+							Values: instance.MatrixInstance.Values,
+						})
+					}
+				}
 				structValue, err := resultAction.Convert2StructValue()
 				if err != nil {
 					return nil, err
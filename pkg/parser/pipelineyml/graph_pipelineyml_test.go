@@ -44,6 +44,38 @@ func TestConvertToGraphPipelineYml(t *testing.T) {
 	fmt.Println(string(b))
 }
 
+func TestGraphPipelineYmlKeepsConcurrency(t *testing.T) {
+	graph, err := ConvertToGraphPipelineYml([]byte(`version: "1.1"
+concurrency:
+  group: ${{ branch }}
+  cancel_in_progress: true
+stages:
+  - stage:
+      - custom-script:
+          commands:
+            - echo hello
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "${{ branch }}", graph.Concurrency.Group)
+	assert.True(t, graph.Concurrency.CancelInProgress)
+
+	// saved from the graph editor
+	b, err := ConvertGraphPipelineYmlContent([]byte(`version: "1.1"
+concurrency:
+  group: ${{ branch }}
+  cancel_in_progress: true
+stages:
+  - - alias: custom-script
+      type: custom-script
+      commands:
+        - echo hello
+`))
+	assert.NoError(t, err)
+	y, err := New(b)
+	assert.NoError(t, err)
+	assert.Equal(t, &ConcurrencyConfig{Group: "${{ branch }}", CancelInProgress: true}, y.Spec().Concurrency)
+}
+
 func Test_cronCompensatorReset(t *testing.T) {
 	type args struct {
 		cronCompensator *pb.CronCompensator
@@ -29,6 +29,7 @@ type PipelineYml struct {
 	envs              map[string]string // 优先级高于 pipeline.yml 中 envs 字段指定的值
 	flatParams        bool              // 是否将 params 扁平化(map[string]interface{} -> map[string]string)
 	actionTypeMapping map[string]string // 只在升级时生效
+	expandMatrix      bool              // 是否展开 matrix action
 
 	// outputs
 	aliasToCheckRefOp               []ActionAlias
@@ -39,6 +40,7 @@ type PipelineYml struct {
 	// snippet
 	globalSnippetConfigLabels map[string]string         // 当前 pipeline 的提交信息, 用作 snippet 的 local 模式查询 gitta 中的文件
 	SnippetCaches             []SnippetPipelineYmlCache // snippet 缓存
+	snippetYmlLoader          SnippetYmlLoader          // 只在 Render 时生效
 
 	// secrets
 	secrets                     map[string]string
@@ -75,6 +77,7 @@ func New(b []byte, ops ...Option) (_ *PipelineYml, err error) {
 
 		flatParams:        false,
 		actionTypeMapping: defaultActionTypeMapping,
+		expandMatrix:      true,
 
 		refs: Refs{},
 
@@ -97,6 +100,18 @@ func New(b []byte, ops ...Option) (_ *PipelineYml, err error) {
 		return nil, err
 	}
 
+	// matrix 需要最先展开，后续的 visitor 以及重新序列化的 yaml 都基于展开后的 actions
+	if y.expandMatrix {
+		matrixVisitor := NewMatrixVisitor()
+		y.s.Accept(matrixVisitor)
+		if matrixVisitor.Expanded() {
+			y.data, err = GenerateYml(y.s)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
 	y.s.Accept(NewVersionVisitor())
 	y.s.Accept(NewEnvVisitor(y.envs))
 	// secretVisitor 需要在 stageVisitor 之前执行，先执行文本替换，再按需 JSON(params)
@@ -156,6 +171,8 @@ func New(b []byte, ops ...Option) (_ *PipelineYml, err error) {
 
 	y.s.Accept(NewCronVisitor())
 	y.s.Accept(NewTimeoutVisitor())
+	y.s.Accept(NewConcurrencyVisitor())
+	y.s.Accept(NewArtifactVisitor())
 
 	if len(y.aliasToCheckRefOp) > 0 {
 		y.s.Accept(NewRefOpVisitor(y.aliasToCheckRefOp, y.refs, y.outputs, y.allowMissingCustomScriptOutputs, y.globalSnippetConfigLabels))
@@ -253,6 +270,13 @@ func WithTriggerLabels(triggerLabels map[string]string) Option {
 	}
 }
 
+// WithMatrixExpansion 设置是否展开 matrix action，默认展开；图形化展示时不展开，matrix action 作为一个分组展示
+func WithMatrixExpansion(expand bool) Option {
+	return func(y *PipelineYml) {
+		y.expandMatrix = expand
+	}
+}
+
 func (y *PipelineYml) Spec() *Spec {
 	return y.s
 }
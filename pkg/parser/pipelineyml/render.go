@@ -0,0 +1,425 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipelineyml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/erda-project/erda-proto-go/core/pipeline/base/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/expression"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml/pexpr"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+const (
+	// SecretMask replaces every secret value in rendered results,
+	// it must stay a valid plain yaml scalar because secrets are rendered as text.
+	SecretMask = "<masked>"
+
+	maxRenderSnippetDepth = 10
+)
+
+// SnippetYmlLoader returns the pipeline.yml content referenced by a snippet action.
+type SnippetYmlLoader func(snippetConfig SnippetConfig) (string, error)
+
+// WithSnippetYmlLoader sets how Render loads snippets, snippets are not expanded without loader.
+func WithSnippetYmlLoader(loader SnippetYmlLoader) Option {
+	return func(y *PipelineYml) { y.snippetYmlLoader = loader }
+}
+
+// RenderResult is the fully resolved plan of a pipeline.yml, nothing is executed to get it.
+type RenderResult struct {
+	// Yml is the rendered pipeline.yml, secret values are masked.
+	Yml   string          `json:"yml"`
+	Graph *pb.PipelineYml `json:"graph,omitempty"`
+	// Stages contains the rendered actions grouped by stage.
+	Stages  [][]*RenderedAction           `json:"stages"`
+	Params  []apistructs.PipelineRunParam `json:"params,omitempty"`
+	Secrets []RenderedSecret              `json:"secrets,omitempty"`
+	Errors  []string                      `json:"errors,omitempty"`
+	Warns   []string                      `json:"warns,omitempty"`
+}
+
+// RenderedAction is an action after params, envs and secrets applied.
+type RenderedAction struct {
+	Alias   string   `json:"alias"`
+	Type    string   `json:"type"`
+	Version string   `json:"version,omitempty"`
+	Needs   []string `json:"needs,omitempty"`
+	Disable bool     `json:"disable,omitempty"`
+
+	If string `json:"if,omitempty"`
+	// IfResult is nil when there is no condition or it can only be evaluated at runtime.
+	IfResult  *bool  `json:"ifResult,omitempty"`
+	IfMessage string `json:"ifMessage,omitempty"`
+
+	Image     string                       `json:"image,omitempty"`
+	Commands  []string                     `json:"commands,omitempty"`
+	Params    map[string]interface{}       `json:"params,omitempty"`
+	Timeout   int64                        `json:"timeout"` // unit: second, -1 means forever, 0 means platform default
+	Loop      *apistructs.PipelineTaskLoop `json:"loop,omitempty"`
+	Resources Resources                    `json:"resources"`
+
+	// Unresolved contains placeholders only known at runtime, such as ${{ outputs.pre.key }}.
+	Unresolved []string `json:"unresolved,omitempty"`
+
+	Snippet *RenderResult `json:"snippet,omitempty"`
+}
+
+// RenderedSecret is a secret placeholder referenced by pipeline.yml.
+type RenderedSecret struct {
+	Name  string `json:"name"`
+	Found bool   `json:"found"`
+}
+
+// Render returns the resolved plan of pipeline.yml without executing anything:
+// snippets expanded, params, envs and secrets applied, `if` conditions evaluated where possible.
+// Problems which would fail the pipeline at runtime are reported in RenderResult.Errors,
+// only an invalid pipeline.yml returns error.
+func Render(b []byte, ops ...Option) (*RenderResult, error) {
+	return render(b, ops, nil)
+}
+
+func render(b []byte, ops []Option, snippetChain []string) (*RenderResult, error) {
+	var o PipelineYml
+	for _, op := range ops {
+		op(&o)
+	}
+	result := &RenderResult{}
+
+	// params
+	raw, err := New(b)
+	if err != nil {
+		return nil, err
+	}
+	runParams, errs := mergeRunParams(raw.Spec().Params, o.runParams)
+	for _, err := range errs {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	result.Params = runParams
+
+	// secrets, missing secrets are kept as placeholders, so they are flagged instead of failing the parse
+	secretNames := findSecretNames(b)
+	realSecrets := make(map[string]string, len(o.secrets)+len(secretNames))
+	maskedSecrets := make(map[string]string, len(o.secrets)+len(secretNames))
+	for k, v := range o.secrets {
+		realSecrets[k] = v
+		maskedSecrets[k] = SecretMask
+	}
+	for _, name := range secretNames {
+		_, found := o.secrets[name]
+		result.Secrets = append(result.Secrets, RenderedSecret{Name: name, Found: found})
+		if !found {
+			realSecrets[name] = "((" + name + "))"
+			maskedSecrets[name] = "((" + name + "))"
+			result.Errors = append(result.Errors, fmt.Sprintf("secret not found: ((%s))", name))
+		}
+	}
+
+	renderOps := func(secrets map[string]string) []Option {
+		return append(append([]Option{}, ops...),
+			WithSecrets(secrets),
+			WithFlatParams(false),
+			func(y *PipelineYml) { y.runParams = runParams },
+		)
+	}
+	masked, err := New(b, renderOps(maskedSecrets)...)
+	if err != nil {
+		return nil, err
+	}
+	unmasked, err := New(b, renderOps(realSecrets)...)
+	if err != nil {
+		return nil, err
+	}
+	realActions := make(map[ActionAlias]*Action)
+	unmasked.Spec().LoopStagesActions(func(stage int, action *Action) {
+		realActions[action.Alias] = action
+	})
+
+	yml, err := GenerateYml(masked.Spec())
+	if err != nil {
+		return nil, err
+	}
+	result.Yml = string(yml)
+	result.Graph, err = ConvertToGraphPipelineYml(yml)
+	if err != nil {
+		return nil, err
+	}
+	result.Warns = masked.Warns()
+
+	result.Stages = make([][]*RenderedAction, len(masked.Spec().Stages))
+	masked.Spec().LoopStagesActions(func(stage int, action *Action) {
+		ra := renderAction(action)
+		if realAction, ok := realActions[action.Alias]; ok {
+			ra.IfResult, ra.IfMessage = evalActionIf(realAction.If)
+			// the message may echo operands of the condition, which are rendered with real secrets
+			ra.IfMessage = maskSecretValues(ra.IfMessage, o.secrets)
+			if action.Type.IsSnippet() && action.SnippetConfig != nil {
+				// snippet params are rendered with real secrets to evaluate the snippet, mask them in the result
+				snippet, err := renderSnippet(realAction, &o, snippetChain)
+				if err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("failed to render snippet action %s, err: %v", action.Alias, err))
+				}
+				ra.Snippet = maskRenderResult(snippet, o.secrets)
+			}
+		}
+		result.Stages[stage] = append(result.Stages[stage], ra)
+	})
+
+	return result, nil
+}
+
+func renderAction(action *Action) *RenderedAction {
+	ra := &RenderedAction{
+		Alias:     action.Alias.String(),
+		Type:      action.Type.String(),
+		Version:   action.Version,
+		Disable:   action.Disable,
+		If:        action.If,
+		Image:     action.Image,
+		Params:    action.Params,
+		Timeout:   action.Timeout,
+		Loop:      NormalizeLoop(nil, action.Loop),
+		Resources: action.Resources,
+	}
+	for _, need := range action.Needs {
+		ra.Needs = append(ra.Needs, need.String())
+	}
+	if action.Commands != nil {
+		ra.Commands, _ = action.GetSliceCommands()
+	}
+	// condition is reported by IfResult
+	withoutIf := *action
+	withoutIf.If = ""
+	if b, err := yaml.Marshal(&withoutIf); err == nil {
+		ra.Unresolved = strutil.DedupSlice(pexpr.LoosePhRe.FindAllString(string(b), -1), true)
+	}
+	return ra
+}
+
+// evalActionIf evaluates the condition like the task prepare does, placeholders left means
+// it depends on something only known at runtime.
+func evalActionIf(condition string) (*bool, string) {
+	if condition == "" {
+		return nil, ""
+	}
+	if phs := pexpr.LoosePhRe.FindAllString(expression.ReplacePlaceholder(strings.TrimSpace(condition)), -1); len(phs) > 0 {
+		return nil, fmt.Sprintf("evaluated at runtime, depends on: %s", strings.Join(phs, ", "))
+	}
+	if secrets := validSecretRegexp.FindAllString(condition, -1); len(secrets) > 0 {
+		return nil, fmt.Sprintf("secret not found: %s", strings.Join(secrets, ", "))
+	}
+	sign := expression.Execute(condition)
+	result := sign.Err == nil && sign.Sign == expression.TaskNotJumpOver
+	if sign.Err != nil {
+		return &result, fmt.Sprintf("%s: %s", sign.Err.Code, sign.Err.Msg)
+	}
+	return &result, ""
+}
+
+// maskSecretValues replaces every secret value in s with SecretMask, longer values first
+// so that a secret containing another one is masked as a whole.
+func maskSecretValues(s string, secrets map[string]string) string {
+	if s == "" {
+		return s
+	}
+	values := make([]string, 0, len(secrets))
+	for _, v := range secrets {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	oldnew := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		oldnew = append(oldnew, v, SecretMask)
+	}
+	return strings.NewReplacer(oldnew...).Replace(s)
+}
+
+// maskRenderResult masks secret values in every string of the result and its nested snippets.
+func maskRenderResult(r *RenderResult, secrets map[string]string) *RenderResult {
+	if r == nil || len(secrets) == 0 {
+		return r
+	}
+	r.Yml = maskSecretValues(r.Yml, secrets)
+	if graph, err := ConvertToGraphPipelineYml([]byte(r.Yml)); err == nil {
+		r.Graph = graph
+	}
+	for i := range r.Params {
+		r.Params[i].Value = maskSecretsInValue(r.Params[i].Value, secrets)
+	}
+	for i := range r.Errors {
+		r.Errors[i] = maskSecretValues(r.Errors[i], secrets)
+	}
+	for i := range r.Warns {
+		r.Warns[i] = maskSecretValues(r.Warns[i], secrets)
+	}
+	for _, stage := range r.Stages {
+		for _, ra := range stage {
+			ra.If = maskSecretValues(ra.If, secrets)
+			ra.IfMessage = maskSecretValues(ra.IfMessage, secrets)
+			ra.Image = maskSecretValues(ra.Image, secrets)
+			for i := range ra.Commands {
+				ra.Commands[i] = maskSecretValues(ra.Commands[i], secrets)
+			}
+			if ra.Params != nil {
+				ra.Params = maskSecretsInValue(ra.Params, secrets).(map[string]interface{})
+			}
+			for i := range ra.Unresolved {
+				ra.Unresolved[i] = maskSecretValues(ra.Unresolved[i], secrets)
+			}
+			ra.Snippet = maskRenderResult(ra.Snippet, secrets)
+		}
+	}
+	return r
+}
+
+// maskSecretsInValue masks secret values in the strings of a param value, maps and slices are copied.
+func maskSecretsInValue(v interface{}, secrets map[string]string) interface{} {
+	switch val := v.(type) {
+	case string:
+		return maskSecretValues(val, secrets)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = maskSecretsInValue(item, secrets)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(val))
+		for k, item := range val {
+			m[k] = maskSecretsInValue(item, secrets)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			l = append(l, maskSecretsInValue(item, secrets))
+		}
+		return l
+	}
+	return v
+}
+
+func renderSnippet(action *Action, parent *PipelineYml, snippetChain []string) (*RenderResult, error) {
+	if parent.snippetYmlLoader == nil {
+		return nil, nil
+	}
+	key := fmt.Sprintf("%s/%s", action.SnippetConfig.Source, action.SnippetConfig.Name)
+	if strutil.Exist(snippetChain, key) {
+		return nil, errors.Errorf("snippet cycle detected: %s -> %s", strings.Join(snippetChain, " -> "), key)
+	}
+	if len(snippetChain) >= maxRenderSnippetDepth {
+		return nil, errors.Errorf("snippet nested too deep, max depth: %d", maxRenderSnippetDepth)
+	}
+	yml, err := parent.snippetYmlLoader(*action.SnippetConfig)
+	if err != nil {
+		return nil, err
+	}
+	// snippet action params are the run params of snippet pipeline
+	var runParams []apistructs.PipelineRunParamWithValue
+	for k, v := range action.Params {
+		runParams = append(runParams, apistructs.PipelineRunParamWithValue{PipelineRunParam: apistructs.PipelineRunParam{Name: k, Value: v}})
+	}
+	ops := []Option{
+		WithEnvs(parent.envs),
+		WithSecrets(parent.secrets),
+		WithRunParams(runParams),
+		WithSnippetYmlLoader(parent.snippetYmlLoader),
+	}
+	return render([]byte(yml), ops, append(append([]string{}, snippetChain...), key))
+}
+
+// mergeRunParams fills params not passed with their default values, the same way as pipeline run does.
+func mergeRunParams(params []*PipelineParam, runParams []apistructs.PipelineRunParam) ([]apistructs.PipelineRunParam, []error) {
+	runParamsMap := make(map[string]interface{}, len(runParams))
+	for _, rp := range runParams {
+		runParamsMap[rp.Name] = rp.Value
+	}
+	var (
+		result []apistructs.PipelineRunParam
+		errs   []error
+	)
+	for _, param := range params {
+		value := runParamsMap[param.Name]
+		if value == nil {
+			value = param.Default
+		}
+		if value == nil {
+			if param.Required {
+				errs = append(errs, errors.Errorf("pipeline param %s value is empty", param.Name))
+			}
+			value = GetParamDefaultValue(param.Type)
+		}
+		result = append(result, apistructs.PipelineRunParam{Name: param.Name, Value: value})
+	}
+	return result, errs
+}
+
+// findSecretNames returns the sorted names of all ((secret)) and ${{ configs.secret }} placeholders.
+func findSecretNames(b []byte) []string {
+	names := make(map[string]struct{})
+	for _, sec := range validSecretRegexp.FindAllString(string(b), -1) {
+		names[unwrapSecret(sec)] = struct{}{}
+	}
+	for _, subs := range pexpr.PhRe.FindAllStringSubmatch(string(b), -1) {
+		if name := strings.TrimPrefix(subs[1], expression.Configs+"."); name != subs[1] {
+			names[name] = struct{}{}
+		}
+	}
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// NormalizeLoop calculates the loop of a task, the loop declared in pipeline.yml takes precedence
+// over the one in action spec, and unset fields are filled with defaults.
+func NormalizeLoop(specLoop, taskLoop *apistructs.PipelineTaskLoop) *apistructs.PipelineTaskLoop {
+	if specLoop == nil && taskLoop == nil {
+		return nil
+	}
+	loop := specLoop.Duplicate()
+	if taskLoop != nil {
+		loop = taskLoop.Duplicate()
+	}
+	if loop.Break == "" {
+		// default break when task succeed
+		loop.Break = `task_status == 'Success'`
+	}
+	if loop.Strategy == nil {
+		defaultStrategy := apistructs.PipelineTaskDefaultLoopStrategy
+		loop.Strategy = &defaultStrategy
+	}
+	if loop.Strategy.IntervalSec == 0 {
+		loop.Strategy.IntervalSec = apistructs.PipelineTaskDefaultLoopStrategy.IntervalSec
+	}
+	if loop.Strategy.DeclineRatio <= 0 {
+		loop.Strategy.DeclineRatio = 1
+	}
+	if loop.Strategy.DeclineLimitSec == 0 {
+		loop.Strategy.DeclineLimitSec = int64(loop.Strategy.IntervalSec)
+	}
+	return loop
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipelineyml
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const renderYml = `version: "1.1"
+params:
+  - name: branch
+    default: master
+  - name: tag
+    required: true
+stages:
+  - stage:
+      - custom-script:
+          alias: build
+          commands:
+            - echo ${{ params.branch }}
+            - docker login -p ((token)) ${{ configs.registry }}
+  - stage:
+      - custom-script:
+          alias: deploy
+          if: ${{ '${{ params.branch }}' == 'master' }}
+          commands:
+            - echo ${{ outputs.build.image }}
+      - custom-script:
+          alias: notify
+          if: ${{ '${{ outputs.build.image }}' != '' }}
+          commands:
+            - echo done
+`
+
+func TestRender(t *testing.T) {
+	result, err := Render([]byte(renderYml),
+		WithSecrets(map[string]string{"token": "s3cr3t"}),
+		WithRunParams([]apistructs.PipelineRunParamWithValue{
+			{PipelineRunParam: apistructs.PipelineRunParam{Name: "tag", Value: "v1"}},
+		}),
+	)
+	assert.NoError(t, err)
+
+	// params
+	assert.Equal(t, []apistructs.PipelineRunParam{{Name: "branch", Value: "master"}, {Name: "tag", Value: "v1"}}, result.Params)
+
+	// secrets
+	assert.Equal(t, []RenderedSecret{{Name: "registry", Found: false}, {Name: "token", Found: true}}, result.Secrets)
+	assert.Contains(t, result.Errors, "secret not found: ((registry))")
+	assert.NotContains(t, result.Yml, "s3cr3t")
+	assert.NotNil(t, result.Graph)
+
+	assert.Len(t, result.Stages, 2)
+	build := result.Stages[0][0]
+	assert.Equal(t, "build", build.Alias)
+	assert.Equal(t, []string{"echo master", "docker login -p " + SecretMask + " ((registry))"}, build.Commands)
+	assert.Empty(t, build.Unresolved)
+	assert.Nil(t, build.IfResult)
+
+	deploy := result.Stages[1][0]
+	assert.Equal(t, "deploy", deploy.Alias)
+	if assert.NotNil(t, deploy.IfResult) {
+		assert.True(t, *deploy.IfResult)
+	}
+	assert.Equal(t, []string{"${{ outputs.build.image }}"}, deploy.Unresolved)
+
+	notify := result.Stages[1][1]
+	assert.Nil(t, notify.IfResult)
+	assert.Contains(t, notify.IfMessage, "evaluated at runtime")
+
+	// invalid yaml
+	_, err = Render([]byte("stages: ["))
+	assert.Error(t, err)
+}
+
+func TestRenderMasksSecretsInIfMessage(t *testing.T) {
+	yml := `version: "1.1"
+stages:
+  - stage:
+      - custom-script:
+          alias: check
+          if: ${{ '((token))' > 1 }}
+          commands:
+            - echo check
+`
+	result, err := Render([]byte(yml), WithSecrets(map[string]string{"token": "s3cr3t"}))
+	assert.NoError(t, err)
+	check := result.Stages[0][0]
+	if assert.NotNil(t, check.IfResult) {
+		assert.False(t, *check.IfResult)
+	}
+	assert.Contains(t, check.IfMessage, SecretMask)
+	assert.NotContains(t, check.IfMessage, "s3cr3t")
+}
+
+func TestMaskSecretValues(t *testing.T) {
+	secrets := map[string]string{"a": "abc", "b": "abcdef", "empty": ""}
+	assert.Equal(t, "x "+SecretMask+" "+SecretMask+" y", maskSecretValues("x abcdef abc y", secrets))
+	assert.Equal(t, "", maskSecretValues("", secrets))
+}
+
+const renderSnippetYml = `version: "1.1"
+stages:
+  - stage:
+      - snippet:
+          alias: shared
+          params:
+            name: erda
+          snippet_config:
+            source: local
+            name: %s
+`
+
+func TestRenderSnippet(t *testing.T) {
+	snippets := map[string]string{
+		"hello": `version: "1.1"
+params:
+  - name: name
+stages:
+  - stage:
+      - custom-script:
+          alias: hello
+          commands:
+            - echo hello ${{ params.name }}
+`,
+		"a": fmt.Sprintf(renderSnippetYml, "b"),
+		"b": fmt.Sprintf(renderSnippetYml, "a"),
+	}
+	loader := WithSnippetYmlLoader(func(snippetConfig SnippetConfig) (string, error) {
+		yml, ok := snippets[snippetConfig.Name]
+		if !ok {
+			return "", fmt.Errorf("snippet %s not found", snippetConfig.Name)
+		}
+		return yml, nil
+	})
+
+	result, err := Render([]byte(fmt.Sprintf(renderSnippetYml, "hello")), loader)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	shared := result.Stages[0][0]
+	if assert.NotNil(t, shared.Snippet) {
+		assert.Equal(t, []string{"echo hello erda"}, shared.Snippet.Stages[0][0].Commands)
+	}
+
+	// snippets are not expanded without loader
+	result, err = Render([]byte(fmt.Sprintf(renderSnippetYml, "hello")))
+	assert.NoError(t, err)
+	assert.Nil(t, result.Stages[0][0].Snippet)
+
+	// a -> b -> a
+	result, err = Render([]byte(fmt.Sprintf(renderSnippetYml, "a")), loader)
+	assert.NoError(t, err)
+	nested := result.Stages[0][0].Snippet
+	if assert.NotNil(t, nested) && assert.Len(t, nested.Errors, 1) {
+		assert.Contains(t, nested.Errors[0], "snippet cycle detected: local/a -> local/b -> local/a")
+	}
+}
+
+func TestRenderSnippetMasksSecrets(t *testing.T) {
+	yml := `version: "1.1"
+stages:
+  - stage:
+      - snippet:
+          alias: shared
+          params:
+            name: ((token))
+          snippet_config:
+            source: local
+            name: hello
+`
+	snippet := `version: "1.1"
+params:
+  - name: name
+stages:
+  - stage:
+      - custom-script:
+          alias: hello
+          params:
+            token: ${{ params.name }}
+          commands:
+            - echo hello ${{ params.name }}
+`
+	loader := WithSnippetYmlLoader(func(snippetConfig SnippetConfig) (string, error) {
+		return snippet, nil
+	})
+	result, err := Render([]byte(yml), loader, WithSecrets(map[string]string{"token": "s3cr3t"}))
+	assert.NoError(t, err)
+	nested := result.Stages[0][0].Snippet
+	if !assert.NotNil(t, nested) {
+		return
+	}
+	hello := nested.Stages[0][0]
+	assert.Equal(t, []string{"echo hello " + SecretMask}, hello.Commands)
+	assert.Equal(t, SecretMask, hello.Params["token"])
+	assert.Equal(t, SecretMask, nested.Params[0].Value)
+	assert.NotContains(t, nested.Yml, "s3cr3t")
+}
+
+func TestMergeRunParams(t *testing.T) {
+	params := []*PipelineParam{
+		{Name: "branch", Default: "master"},
+		{Name: "tag", Required: true},
+		{Name: "debug", Type: apistructs.PipelineParamBoolType},
+	}
+	result, errs := mergeRunParams(params, []apistructs.PipelineRunParam{{Name: "branch", Value: "dev"}})
+	assert.Equal(t, []apistructs.PipelineRunParam{
+		{Name: "branch", Value: "dev"},
+		{Name: "tag", Value: ""},
+		{Name: "debug", Value: "false"},
+	}, result)
+	assert.Len(t, errs, 1)
+}
+
+func TestFindSecretNames(t *testing.T) {
+	assert.Equal(t, []string{"a", "b.c"}, findSecretNames([]byte("((b.c)) ${{ configs.a }} ((b.c)) ${{ params.a }}")))
+	assert.Empty(t, findSecretNames([]byte("echo ${{ params.a }}")))
+}
+
+func TestNormalizeLoop(t *testing.T) {
+	assert.Nil(t, NormalizeLoop(nil, nil))
+
+	specLoop := &apistructs.PipelineTaskLoop{Break: "task_status == 'Failed'"}
+	loop := NormalizeLoop(specLoop, nil)
+	assert.Equal(t, "task_status == 'Failed'", loop.Break)
+	assert.Equal(t, apistructs.PipelineTaskDefaultLoopStrategy, *loop.Strategy)
+	assert.Nil(t, specLoop.Strategy, "spec loop should not be changed")
+
+	// task loop takes precedence
+	loop = NormalizeLoop(specLoop, &apistructs.PipelineTaskLoop{Strategy: &apistructs.LoopStrategy{MaxTimes: 3, IntervalSec: 5}})
+	assert.Equal(t, `task_status == 'Success'`, loop.Break)
+	assert.Equal(t, apistructs.LoopStrategy{MaxTimes: 3, IntervalSec: 5, DeclineRatio: 1, DeclineLimitSec: 5}, *loop.Strategy)
+}
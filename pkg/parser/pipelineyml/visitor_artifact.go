@@ -0,0 +1,189 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipelineyml
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ActionArtifact declares files produced by an action which are persisted to object storage after the action finished, like:
+//
+//	artifacts:
+//	  - name: app-jar
+//	    paths:
+//	      - target/*.jar
+//	    retention: 7d
+//
+// Relative paths are relative to the working directory of the action, glob patterns are supported.
+type ActionArtifact struct {
+	Name      string   `yaml:"name"`
+	Paths     []string `yaml:"paths"`
+	Retention string   `yaml:"retention,omitempty"` // such as "7d", "12h"; default retention is used if empty
+	When      string   `yaml:"when,omitempty"`      // on_success (default), on_failure or always
+}
+
+// ActionArtifactDownload declares an artifact downloaded before the action runs, like:
+//
+//	download_artifacts:
+//	  - name: app-jar
+//	    path: ${{ dirs.build }}
+//
+// The artifact is looked up in the current pipeline if pipeline_id is not specified.
+type ActionArtifactDownload struct {
+	Name       string `yaml:"name"`
+	Path       string `yaml:"path,omitempty"` // directory to extract into, default is the working directory of the action
+	PipelineID uint64 `yaml:"pipeline_id,omitempty"`
+}
+
+// when to upload artifacts
+const (
+	ArtifactWhenOnSuccess = "on_success"
+	ArtifactWhenOnFailure = "on_failure"
+	ArtifactWhenAlways    = "always"
+)
+
+// ShouldUpload returns whether the artifact should be uploaded according to the result of action.
+func (a ActionArtifact) ShouldUpload(success bool) bool {
+	switch a.When {
+	case ArtifactWhenAlways:
+		return true
+	case ArtifactWhenOnFailure:
+		return !success
+	default:
+		return success
+	}
+}
+
+var artifactNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,127}$`)
+
+// ParseArtifactRetention parses retention like "7d", or duration supported by time.ParseDuration like "12h".
+// Returns 0 if retention is empty.
+func ParseArtifactRetention(retention string) (time.Duration, error) {
+	retention = strings.TrimSpace(retention)
+	if retention == "" {
+		return 0, nil
+	}
+	if days := strings.TrimSuffix(retention, "d"); days != retention {
+		n, err := strconv.ParseUint(days, 10, 32)
+		if err != nil || n == 0 {
+			return 0, errors.Errorf("invalid artifact retention: %s", retention)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(retention)
+	if err != nil || d <= 0 {
+		return 0, errors.Errorf("invalid artifact retention: %s", retention)
+	}
+	return d, nil
+}
+
+type ArtifactVisitor struct{}
+
+func NewArtifactVisitor() *ArtifactVisitor {
+	return &ArtifactVisitor{}
+}
+
+func (v *ArtifactVisitor) Visit(s *Spec) {
+	// artifact name -> declaring action, name is unique in a pipeline
+	type declaringAction struct {
+		alias      ActionAlias
+		stageIndex int
+	}
+	declared := make(map[string]declaringAction)
+	for stageIndex, stage := range s.Stages {
+		for _, typedAction := range stage.Actions {
+			for _, action := range typedAction {
+				for _, artifact := range action.Artifacts {
+					if !artifactNameRe.MatchString(artifact.Name) {
+						s.appendError(errors.Errorf("invalid artifact name: %q, only letters, digits, '.', '_' and '-' are allowed", artifact.Name),
+							stageIndex, action.Alias)
+						continue
+					}
+					if declaring, ok := declared[artifact.Name]; ok {
+						err := errors.Errorf("duplicate artifact name: %s, already declared by action %s", artifact.Name, declaring.alias)
+						if action.MatrixInstance != nil {
+							err = errors.Errorf("%v, use matrix variables like ${{ matrix.xxx }} in the name", err)
+						}
+						s.appendError(err, stageIndex, action.Alias)
+						continue
+					}
+					declared[artifact.Name] = declaringAction{alias: action.Alias, stageIndex: stageIndex}
+					if len(artifact.Paths) == 0 {
+						s.appendError(errors.Errorf("no paths declared for artifact: %s", artifact.Name), stageIndex, action.Alias)
+					}
+					for _, path := range artifact.Paths {
+						if strings.TrimSpace(path) == "" {
+							s.appendError(errors.Errorf("empty path of artifact: %s", artifact.Name), stageIndex, action.Alias)
+						}
+					}
+					if _, err := ParseArtifactRetention(artifact.Retention); err != nil {
+						s.appendError(err, stageIndex, action.Alias)
+					}
+					switch artifact.When {
+					case "", ArtifactWhenOnSuccess, ArtifactWhenOnFailure, ArtifactWhenAlways:
+					default:
+						s.appendError(errors.Errorf("invalid artifact when: %s, available: %s, %s, %s",
+							artifact.When, ArtifactWhenOnSuccess, ArtifactWhenOnFailure, ArtifactWhenAlways), stageIndex, action.Alias)
+					}
+				}
+			}
+		}
+	}
+
+	for stageIndex, stage := range s.Stages {
+		for _, typedAction := range stage.Actions {
+			for _, action := range typedAction {
+				for _, download := range action.DownloadArtifacts {
+					if download.Name == "" {
+						s.appendError(errors.New("missing name of download artifact"), stageIndex, action.Alias)
+						continue
+					}
+					// artifacts of other pipelines are checked when downloading
+					if download.PipelineID != 0 {
+						continue
+					}
+					declaring, ok := declared[download.Name]
+					if !ok {
+						s.appendError(errors.Errorf("artifact %s to download is not declared by any action", download.Name), stageIndex, action.Alias)
+						continue
+					}
+					if declaring.alias == action.Alias {
+						s.appendError(errors.Errorf("artifact %s to download is declared by the action itself", download.Name), stageIndex, action.Alias)
+						continue
+					}
+					// the declaring action must be finished before
+					if declaring.stageIndex >= stageIndex && !actionNeeds(action, declaring.alias) {
+						s.appendError(errors.Errorf("artifact %s to download is declared by action %s, which doesn't run before", download.Name, declaring.alias),
+							stageIndex, action.Alias)
+					}
+				}
+			}
+		}
+	}
+}
+
+func actionNeeds(action *Action, alias ActionAlias) bool {
+	for _, need := range action.Needs {
+		if need == alias {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipelineyml
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtifactVisitor(t *testing.T) {
+	y, err := New([]byte(`version: "1.1"
+stages:
+  - stage:
+      - custom-script:
+          alias: build
+          artifacts:
+            - name: app-jar
+              paths:
+                - target/*.jar
+              retention: 7d
+  - stage:
+      - custom-script:
+          alias: test
+          download_artifacts:
+            - name: app-jar
+              path: /tmp/jar
+            - name: report
+              pipeline_id: 100
+`))
+	assert.NoError(t, err)
+	build := y.Spec().Stages[0].Actions[0]["custom-script"]
+	assert.Equal(t, []ActionArtifact{{Name: "app-jar", Paths: []string{"target/*.jar"}, Retention: "7d"}}, build.Artifacts)
+	test := y.Spec().Stages[1].Actions[0]["custom-script"]
+	assert.Equal(t, []ActionArtifactDownload{{Name: "app-jar", Path: "/tmp/jar"}, {Name: "report", PipelineID: 100}}, test.DownloadArtifacts)
+
+	invalids := map[string]string{
+		"invalid name": `
+      - custom-script:
+          artifacts:
+            - name: a/b
+              paths: [a]`,
+		"no paths": `
+      - custom-script:
+          artifacts:
+            - name: a`,
+		"invalid retention": `
+      - custom-script:
+          artifacts:
+            - name: a
+              paths: [a]
+              retention: 1w`,
+		"invalid when": `
+      - custom-script:
+          artifacts:
+            - name: a
+              paths: [a]
+              when: never`,
+		"duplicate name": `
+      - custom-script:
+          alias: a1
+          artifacts:
+            - name: a
+              paths: [a]
+      - custom-script:
+          alias: a2
+          artifacts:
+            - name: a
+              paths: [a]`,
+		"undeclared download": `
+      - custom-script:
+          download_artifacts:
+            - name: a`,
+		"download in same stage": `
+      - custom-script:
+          alias: a1
+          artifacts:
+            - name: a
+              paths: [a]
+      - custom-script:
+          alias: a2
+          download_artifacts:
+            - name: a`,
+	}
+	for name, stage := range invalids {
+		t.Run(name, func(t *testing.T) {
+			_, err := New([]byte(`version: "1.1"
+stages:
+  - stage:` + stage + "\n"))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestArtifactVisitorWithMatrix(t *testing.T) {
+	_, err := New([]byte(`version: "1.1"
+stages:
+  - stage:
+      - custom-script:
+          alias: build
+          matrix:
+            go: ["1.21", "1.22"]
+          artifacts:
+            - name: bin-${{ matrix.go }}
+              paths: [bin]
+`))
+	assert.NoError(t, err)
+
+	_, err = New([]byte(`version: "1.1"
+stages:
+  - stage:
+      - custom-script:
+          alias: build
+          matrix:
+            go: ["1.21", "1.22"]
+          artifacts:
+            - name: bin
+              paths: [bin]
+`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "matrix")
+}
+
+func TestParseArtifactRetention(t *testing.T) {
+	d, err := ParseArtifactRetention("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), d)
+
+	d, err = ParseArtifactRetention("3d")
+	assert.NoError(t, err)
+	assert.Equal(t, 72*time.Hour, d)
+
+	d, err = ParseArtifactRetention("90m")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	for _, invalid := range []string{"0d", "-1h", "xd", "1w"} {
+		_, err = ParseArtifactRetention(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestActionArtifactShouldUpload(t *testing.T) {
+	assert.True(t, ActionArtifact{}.ShouldUpload(true))
+	assert.False(t, ActionArtifact{}.ShouldUpload(false))
+	assert.True(t, ActionArtifact{When: ArtifactWhenOnFailure}.ShouldUpload(false))
+	assert.False(t, ActionArtifact{When: ArtifactWhenOnFailure}.ShouldUpload(true))
+	assert.True(t, ActionArtifact{When: ArtifactWhenAlways}.ShouldUpload(false))
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipelineyml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/pkg/parser/pipelineyml/pexpr"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// ConcurrencyConfig limits pipelines in the same group to run one at a time, like:
+//
+//	concurrency:
+//	  group: ${{ project }}-${{ branch }}
+//	  cancel_in_progress: true
+//
+// A newly queued pipeline supersedes older pending pipelines of the group,
+// and cancels the running one too if cancel_in_progress is true.
+type ConcurrencyConfig struct {
+	Group            string `yaml:"group"`
+	CancelInProgress bool   `yaml:"cancel_in_progress,omitempty"`
+}
+
+// variables can be used in concurrency group
+const (
+	ConcurrencyVarOrg             = "org"
+	ConcurrencyVarProject         = "project"
+	ConcurrencyVarProjectName     = "project_name"
+	ConcurrencyVarApp             = "app"
+	ConcurrencyVarAppName         = "app_name"
+	ConcurrencyVarBranch          = "branch"
+	ConcurrencyVarWorkspace       = "workspace"
+	ConcurrencyVarPipelineYmlName = "pipeline_yml_name"
+)
+
+var concurrencyVars = map[string]struct{}{
+	ConcurrencyVarOrg:             {},
+	ConcurrencyVarProject:         {},
+	ConcurrencyVarProjectName:     {},
+	ConcurrencyVarApp:             {},
+	ConcurrencyVarAppName:         {},
+	ConcurrencyVarBranch:          {},
+	ConcurrencyVarWorkspace:       {},
+	ConcurrencyVarPipelineYmlName: {},
+}
+
+const maxConcurrencyGroupLen = 191
+
+type ConcurrencyVisitor struct{}
+
+func NewConcurrencyVisitor() *ConcurrencyVisitor {
+	return &ConcurrencyVisitor{}
+}
+
+func (v *ConcurrencyVisitor) Visit(s *Spec) {
+	if s.Concurrency == nil {
+		return
+	}
+	if strings.TrimSpace(s.Concurrency.Group) == "" {
+		s.appendError(errors.New("concurrency group is empty"))
+		return
+	}
+	for _, sub := range pexpr.PhRe.FindAllStringSubmatch(s.Concurrency.Group, -1) {
+		if _, ok := concurrencyVars[sub[1]]; !ok {
+			s.appendError(errors.Errorf("invalid concurrency group: %s, unknown variable %q, available: %s",
+				s.Concurrency.Group, sub[1], strutil.Join(listConcurrencyVars(), ", ", true)))
+		}
+	}
+}
+
+// RenderConcurrencyGroup renders variables like `${{ branch }}` in group.
+func RenderConcurrencyGroup(group string, vars map[string]string) (string, error) {
+	var missing []string
+	rendered := strutil.ReplaceAllStringSubmatchFunc(pexpr.PhRe, group, func(sub []string) string {
+		if _, ok := concurrencyVars[sub[1]]; !ok {
+			missing = append(missing, sub[1])
+			return sub[0]
+		}
+		return vars[sub[1]]
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("unknown variables in concurrency group: %s", strutil.Join(missing, ", ", true))
+	}
+	rendered = strings.TrimSpace(rendered)
+	if rendered == "" {
+		return "", fmt.Errorf("concurrency group %q is rendered as empty", group)
+	}
+	if len(rendered) > maxConcurrencyGroupLen {
+		return "", fmt.Errorf("concurrency group is too long (%d > %d): %s", len(rendered), maxConcurrencyGroupLen, rendered)
+	}
+	return rendered, nil
+}
+
+func listConcurrencyVars() []string {
+	var vars []string
+	for k := range concurrencyVars {
+		vars = append(vars, k)
+	}
+	sort.Strings(vars)
+	return vars
+}
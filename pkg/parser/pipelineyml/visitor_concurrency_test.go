@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipelineyml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyVisitor(t *testing.T) {
+	y, err := New([]byte(`version: "1.1"
+concurrency:
+  group: ${{ project }}-${{ branch }}
+  cancel_in_progress: true
+stages:
+  - stage:
+      - git-checkout:
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, &ConcurrencyConfig{Group: "${{ project }}-${{ branch }}", CancelInProgress: true}, y.Spec().Concurrency)
+
+	_, err = New([]byte(`version: "1.1"
+concurrency:
+  group: ${{ unknown }}
+stages:
+  - stage:
+      - git-checkout:
+`))
+	assert.Error(t, err)
+
+	_, err = New([]byte(`version: "1.1"
+concurrency:
+  cancel_in_progress: true
+stages:
+  - stage:
+      - git-checkout:
+`))
+	assert.Error(t, err)
+}
+
+func TestRenderConcurrencyGroup(t *testing.T) {
+	group, err := RenderConcurrencyGroup("${{ project }}-${{ branch }}", map[string]string{
+		ConcurrencyVarProject: "1",
+		ConcurrencyVarBranch:  "feature/a",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1-feature/a", group)
+
+	group, err = RenderConcurrencyGroup("deploy", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy", group)
+
+	_, err = RenderConcurrencyGroup("${{ unknown }}", nil)
+	assert.Error(t, err)
+
+	_, err = RenderConcurrencyGroup("${{ branch }}", nil)
+	assert.Error(t, err)
+}
@@ -74,6 +74,8 @@ func WithListNextScheduleCount(count int) CronVisitorOption {
 func (v *CronVisitor) Visit(s *Spec) {
 	if s.Cron == "" {
 		s.CronCompensator = nil
+		s.CronTimeZone = ""
+		s.CronCalendars = nil
 		v.isCron = false
 		return
 	}
@@ -85,20 +87,35 @@ func (v *CronVisitor) Visit(s *Spec) {
 		err      error
 	)
 
-	switch fields := strings.Fields(s.Cron); len(fields) {
+	// time zone prefix of cron takes precedence over cron_time_zone
+	prefixTZ, expr := cron.SplitTimeZone(s.Cron)
+	tz := prefixTZ
+	if tz == "" {
+		tz = s.CronTimeZone
+	}
+	switch fields := strings.Fields(expr); len(fields) {
 	case 7:
 		fieldsWithoutYear := fields[:len(fields)-1]
-		s.Cron = strings.Join(fieldsWithoutYear, " ")
+		expr = strings.Join(fieldsWithoutYear, " ")
+		s.Cron = cron.WithTimeZone(expr, prefixTZ)
 		fallthrough
 	case 6:
-		schedule, err = cron.Parse(s.Cron)
+		schedule, err = cron.Parse(expr)
 	default:
-		schedule, err = cron.ParseStandard(s.Cron)
+		schedule, err = cron.ParseStandard(expr)
 	}
 	if err != nil {
 		s.appendError(err)
 		return
 	}
+	if tz != "" {
+		loc, err := cron.LoadLocation(tz)
+		if err != nil {
+			s.appendError(err)
+			return
+		}
+		schedule = cron.InLocation(schedule, loc)
+	}
 
 	now := time.Unix(time.Now().Unix(), 0)
 	scheduleFrom := now
@@ -103,3 +103,19 @@ func TestListNextCronTime2(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, len(nextTimes) == 9)
 }
+
+func TestListNextCronTimeWithTimeZone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	cronStartTime := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	nextTimes, err := ListNextCronTime("CRON_TZ=Asia/Shanghai 0 0 8 * * ? *",
+		WithCronStartEndTime(&cronStartTime, nil), WithListNextScheduleCount(1))
+	assert.NoError(t, err)
+	assert.Len(t, nextTimes, 1)
+	assert.True(t, nextTimes[0].Equal(time.Date(2026, 10, 18, 8, 0, 0, 0, shanghai)))
+
+	_, err = ListNextCronTime("CRON_TZ=Nowhere/Land 0 0 8 * * ?")
+	assert.Error(t, err)
+}
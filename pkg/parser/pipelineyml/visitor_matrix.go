@@ -0,0 +1,387 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipelineyml
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// Matrix runs an action once per combination of the axis values, like:
+//
+//	matrix:
+//	  go: [ "1.22", "1.23" ]
+//	  os: [ linux, darwin ]
+//	  exclude:
+//	    - go: "1.22"
+//	      os: darwin
+//	  include:
+//	    - go: "1.24"
+//	      os: linux
+//	  max-parallel: 2
+type Matrix struct {
+	Axes        map[string][]interface{} `yaml:",inline"`
+	Include     []map[string]interface{} `yaml:"include,omitempty"`
+	Exclude     []map[string]interface{} `yaml:"exclude,omitempty"`
+	MaxParallel int                      `yaml:"max-parallel,omitempty"`
+}
+
+// MatrixInstance is set by parser on the actions expanded from a matrix action.
+type MatrixInstance struct {
+	// Group is the alias of the matrix action, it can be used to reference the outputs of all instances.
+	Group  ActionAlias       `yaml:"group"`
+	Index  int               `yaml:"index"`
+	Values map[string]string `yaml:"values"`
+	// MaxParallel limits how many instances of the group run at the same time, enforced by the reconciler when scheduling.
+	MaxParallel int `yaml:"max_parallel,omitempty"`
+}
+
+const (
+	maxMatrixInstances = 256
+)
+
+var (
+	matrixVarRegex        = regexp.MustCompile(`\$\{\{\s*matrix\.([A-Za-z0-9_-]+)\s*\}\}`)
+	matrixVarNameRegex    = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	matrixAliasSanitizeRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// MatrixEnvKey returns the env key of a matrix variable injected into the task, e.g. go-version -> MATRIX_GO_VERSION.
+func MatrixEnvKey(name string) string {
+	return "MATRIX_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// MatrixVisitor expands matrix actions into sibling actions in the same stage.
+type MatrixVisitor struct {
+	expanded bool
+}
+
+func NewMatrixVisitor() *MatrixVisitor {
+	return &MatrixVisitor{}
+}
+
+// Expanded reports whether any matrix action is expanded.
+func (v *MatrixVisitor) Expanded() bool {
+	return v.expanded
+}
+
+func (v *MatrixVisitor) Visit(s *Spec) {
+	for stageIndex, stage := range s.Stages {
+		if stage == nil {
+			continue
+		}
+		var actions []typedActionMap
+		for _, typedAction := range stage.Actions {
+			if len(typedAction) != 1 {
+				actions = append(actions, typedAction)
+				continue
+			}
+			for actionType, action := range typedAction {
+				if action == nil || action.Matrix == nil {
+					actions = append(actions, typedAction)
+					continue
+				}
+				instances, err := expandMatrixAction(actionType, action)
+				if err != nil {
+					s.appendError(err, stageIndex, action.Alias)
+					actions = append(actions, typedAction)
+					continue
+				}
+				for _, instance := range instances {
+					actions = append(actions, typedActionMap{actionType: instance})
+				}
+				v.expanded = true
+			}
+		}
+		stage.Actions = actions
+	}
+}
+
+// expandMatrixAction returns one action per matrix combination.
+func expandMatrixAction(actionType ActionType, action *Action) ([]*Action, error) {
+	group := action.Alias
+	if group == "" {
+		group = ActionAlias(actionType)
+	}
+	combinations, axes, err := action.Matrix.combinations()
+	if err != nil {
+		return nil, err
+	}
+	if len(combinations) == 0 {
+		return nil, errors.Errorf("matrix of action %q has no combination", group)
+	}
+	if len(combinations) > maxMatrixInstances {
+		return nil, errors.Errorf("matrix of action %q has %d combinations, exceeds the limit %d", group, len(combinations), maxMatrixInstances)
+	}
+	if action.Matrix.MaxParallel < 0 {
+		return nil, errors.Errorf("invalid matrix max-parallel: %d", action.Matrix.MaxParallel)
+	}
+
+	tmpl := *action
+	tmpl.Matrix = nil
+	tmplYAML, err := yaml.Marshal(&tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []*Action
+	usedAliases := make(map[ActionAlias]struct{})
+	for i, values := range combinations {
+		var instance Action
+		if err := yaml.Unmarshal(tmplYAML, &instance); err != nil {
+			return nil, err
+		}
+		instance.Type = actionType
+		instance.Alias = matrixInstanceAlias(group, axes, values, i, usedAliases)
+		instance.MatrixInstance = &MatrixInstance{
+			Group:       group,
+			Index:       i,
+			Values:      values,
+			MaxParallel: action.Matrix.MaxParallel,
+		}
+		if err := instance.renderMatrixVars(values); err != nil {
+			return nil, errors.Errorf("failed to render matrix variables of action %q, err: %v", instance.Alias, err)
+		}
+		instances = append(instances, &instance)
+	}
+	return instances, nil
+}
+
+// combinations returns the values of all combinations and the ordered names of all variables.
+// Axes are sorted by name, values of an axis keep the declared order.
+// Exclude is applied before include, same as GitHub Actions.
+func (m *Matrix) combinations() ([]map[string]string, []string, error) {
+	var axes []string
+	for name, values := range m.Axes {
+		if len(values) == 0 {
+			return nil, nil, errors.Errorf("matrix variable %q has no value", name)
+		}
+		axes = append(axes, name)
+	}
+	sort.Strings(axes)
+
+	var combinations []map[string]string
+	if len(axes) > 0 {
+		combinations = []map[string]string{{}}
+		for _, name := range axes {
+			var next []map[string]string
+			for _, combination := range combinations {
+				for _, value := range m.Axes[name] {
+					c := copyStringMap(combination)
+					c[name] = matrixValueString(value)
+					next = append(next, c)
+				}
+			}
+			combinations = next
+		}
+	}
+
+	// exclude
+	for _, exclude := range m.Exclude {
+		for name := range exclude {
+			if _, ok := m.Axes[name]; !ok {
+				return nil, nil, errors.Errorf("matrix exclude has unknown variable %q", name)
+			}
+		}
+		var kept []map[string]string
+		for _, c := range combinations {
+			if !matrixMatches(c, exclude) {
+				kept = append(kept, c)
+			}
+		}
+		combinations = kept
+	}
+
+	// include
+	allNames := make(map[string]struct{})
+	for _, name := range axes {
+		allNames[name] = struct{}{}
+	}
+	for _, include := range m.Include {
+		if len(include) == 0 {
+			continue
+		}
+		original := make(map[string]interface{})
+		extra := make(map[string]interface{})
+		for name, value := range include {
+			if _, ok := m.Axes[name]; ok {
+				original[name] = value
+			} else {
+				extra[name] = value
+			}
+			allNames[name] = struct{}{}
+		}
+		// extend matched combinations if the include only adds new variables to them
+		var extended bool
+		if len(extra) > 0 {
+			for _, c := range combinations {
+				if !matrixMatches(c, original) || matrixOverwrites(c, extra) {
+					continue
+				}
+				for name, value := range extra {
+					c[name] = matrixValueString(value)
+				}
+				extended = true
+			}
+		}
+		if !extended {
+			c := make(map[string]string)
+			for name, value := range include {
+				c[name] = matrixValueString(value)
+			}
+			combinations = append(combinations, c)
+		}
+	}
+
+	var names []string
+	for name := range allNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !matrixVarNameRegex.MatchString(name) {
+			return nil, nil, errors.Errorf("invalid matrix variable name: %s, regex: %s", name, matrixVarNameRegex.String())
+		}
+	}
+	return combinations, axes, nil
+}
+
+// matrixMatches reports whether all variables of cond have the same values in c.
+func matrixMatches(c map[string]string, cond map[string]interface{}) bool {
+	for name, value := range cond {
+		if v, ok := c[name]; !ok || v != matrixValueString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matrixOverwrites reports whether any variable of extra already exists in c with a different value.
+func matrixOverwrites(c map[string]string, extra map[string]interface{}) bool {
+	for name, value := range extra {
+		if v, ok := c[name]; ok && v != matrixValueString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matrixValueString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// matrixInstanceAlias generates alias like `build-1.22-linux`, the index is appended if duplicated.
+func matrixInstanceAlias(group ActionAlias, axes []string, values map[string]string, index int, used map[ActionAlias]struct{}) ActionAlias {
+	parts := []string{group.String()}
+	for _, name := range axes {
+		if v, ok := values[name]; ok {
+			parts = append(parts, v)
+		}
+	}
+	// include-only combinations may not have any axis
+	if len(parts) == 1 {
+		var names []string
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			parts = append(parts, values[name])
+		}
+	}
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.Trim(matrixAliasSanitizeRe.ReplaceAllString(parts[i], "_"), "_")
+	}
+	alias := ActionAlias(strutil.Join(parts, "-", true))
+	if _, ok := used[alias]; ok {
+		alias = ActionAlias(fmt.Sprintf("%s-%d", alias, index))
+	}
+	used[alias] = struct{}{}
+	return alias
+}
+
+// renderMatrixVars replaces `${{ matrix.xxx }}` in the action.
+func (action *Action) renderMatrixVars(values map[string]string) error {
+	var errs []string
+	render := func(s string) string {
+		return matrixVarRegex.ReplaceAllStringFunc(s, func(sub string) string {
+			name := matrixVarRegex.FindStringSubmatch(sub)[1]
+			value, ok := values[name]
+			if !ok {
+				errs = append(errs, fmt.Sprintf("matrix variable %q not found", name))
+				return sub
+			}
+			return value
+		})
+	}
+
+	for k, v := range action.Params {
+		action.Params[k] = renderMatrixValue(v, render)
+	}
+	action.Commands = renderMatrixValue(action.Commands, render)
+	for k, v := range action.Labels {
+		action.Labels[k] = render(v)
+	}
+	for i := range action.Caches {
+		action.Caches[i].Path = render(action.Caches[i].Path)
+		action.Caches[i].Key = render(action.Caches[i].Key)
+	}
+	action.Description = render(action.Description)
+	action.Image = render(action.Image)
+	action.If = render(action.If)
+
+	if len(errs) > 0 {
+		return errors.New(strutil.Join(strutil.DedupSlice(errs), ", ", true))
+	}
+	return nil
+}
+
+// renderMatrixValue renders all strings in v recursively.
+func renderMatrixValue(v interface{}, render func(string) string) interface{} {
+	switch value := v.(type) {
+	case string:
+		return render(value)
+	case []interface{}:
+		for i := range value {
+			value[i] = renderMatrixValue(value[i], render)
+		}
+		return value
+	case map[string]interface{}:
+		for k := range value {
+			value[k] = renderMatrixValue(value[k], render)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	r := make(map[string]string, len(m))
+	for k, v := range m {
+		r[k] = v
+	}
+	return r
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipelineyml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const matrixYml = `version: "1.1"
+stages:
+  - stage:
+      - custom-script:
+          alias: build
+          image: golang:${{ matrix.go }}
+          commands:
+            - GOOS=${{ matrix.os }} go build ./...
+          matrix:
+            go: [ "1.22", "1.23" ]
+            os: [ linux, darwin ]
+            exclude:
+              - go: "1.22"
+                os: darwin
+            include:
+              - go: "1.23"
+                os: linux
+                race: "true"
+              - go: "1.24"
+                os: linux
+            max-parallel: 2
+  - stage:
+      - custom-script:
+          alias: report
+          commands:
+            - echo ${{ outputs.build.result }}
+`
+
+func TestMatrixCombinations(t *testing.T) {
+	m := &Matrix{
+		Axes: map[string][]interface{}{
+			"os": {"linux", "darwin"},
+			"go": {"1.22", "1.23"},
+		},
+		Exclude: []map[string]interface{}{{"go": "1.22", "os": "darwin"}},
+		Include: []map[string]interface{}{
+			{"go": "1.23", "os": "linux", "race": true},
+			{"go": "1.24", "os": "linux"},
+		},
+	}
+	combinations, axes, err := m.combinations()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "os"}, axes)
+	assert.Equal(t, []map[string]string{
+		{"go": "1.22", "os": "linux"},
+		{"go": "1.23", "os": "linux", "race": "true"},
+		{"go": "1.23", "os": "darwin"},
+		{"go": "1.24", "os": "linux"},
+	}, combinations)
+
+	_, _, err = (&Matrix{Axes: map[string][]interface{}{"os": {}}}).combinations()
+	assert.Error(t, err)
+
+	_, _, err = (&Matrix{Axes: map[string][]interface{}{"os": {"linux"}}, Exclude: []map[string]interface{}{{"arch": "amd64"}}}).combinations()
+	assert.Error(t, err)
+}
+
+func TestMatrixInstanceAlias(t *testing.T) {
+	used := make(map[ActionAlias]struct{})
+	assert.Equal(t, ActionAlias("build-1.22-linux"), matrixInstanceAlias("build", []string{"go", "os"}, map[string]string{"go": "1.22", "os": "linux"}, 0, used))
+	assert.Equal(t, ActionAlias("build-1.22-linux-1"), matrixInstanceAlias("build", []string{"go", "os"}, map[string]string{"go": "1.22", "os": "linux"}, 1, used))
+	assert.Equal(t, ActionAlias("build-a_b"), matrixInstanceAlias("build", []string{"x"}, map[string]string{"x": "a/b"}, 2, used))
+}
+
+func TestMatrixVisitor(t *testing.T) {
+	y, err := New([]byte(matrixYml))
+	assert.NoError(t, err)
+
+	actions := y.Spec().Stages[0].Actions
+	assert.Equal(t, 4, len(actions))
+	var aliases []ActionAlias
+	for _, typedAction := range actions {
+		for _, action := range typedAction {
+			aliases = append(aliases, action.Alias)
+			assert.Nil(t, action.Matrix)
+			assert.NotNil(t, action.MatrixInstance)
+			assert.Equal(t, ActionAlias("build"), action.MatrixInstance.Group)
+		}
+	}
+	assert.Equal(t, []ActionAlias{"build-1.22-linux", "build-1.23-linux", "build-1.23-darwin", "build-1.24-linux"}, aliases)
+
+	first := actions[0]["custom-script"]
+	assert.Equal(t, "golang:1.22", first.Image)
+	assert.Equal(t, []interface{}{"GOOS=linux go build ./..."}, first.Commands)
+	assert.Empty(t, first.Needs)
+
+	// max-parallel is enforced by the scheduler, instances don't depend on each other
+	third := actions[2]["custom-script"]
+	assert.Empty(t, third.Needs)
+	assert.Equal(t, 2, third.MatrixInstance.MaxParallel)
+	assert.Equal(t, []ActionAlias{"build-1.22-linux", "build-1.23-linux", "build-1.23-darwin", "build-1.24-linux"}, y.Spec().matrixGroups["build"])
+
+	// unknown matrix variable
+	_, err = New([]byte(`version: "1.1"
+stages:
+  - stage:
+      - custom-script:
+          commands:
+            - echo ${{ matrix.arch }}
+          matrix:
+            os: [ linux ]
+`))
+	assert.Error(t, err)
+
+	// not expanded
+	y, err = New([]byte(matrixYml), WithMatrixExpansion(false))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(y.Spec().Stages[0].Actions))
+	assert.NotNil(t, y.Spec().Stages[0].Actions[0]["custom-script"].Matrix)
+}
+
+func TestRefOpVisitor_MatrixOutputs(t *testing.T) {
+	y, err := New([]byte(matrixYml),
+		WithAliasesToCheckRefOp(nil, "report"),
+		WithRefOpOutputs(Outputs{
+			"build-1.22-linux":  {"result": "a"},
+			"build-1.23-linux":  {"result": "b"},
+			"build-1.23-darwin": {"result": "c"},
+			"build-1.24-linux":  {"result": "d"},
+		}),
+	)
+	assert.NoError(t, err)
+	report := y.Spec().Stages[1].Actions[0]["custom-script"]
+	assert.Equal(t, []interface{}{`echo {"build-1.22-linux":"a","build-1.23-darwin":"c","build-1.23-linux":"b","build-1.24-linux":"d"}`}, report.Commands)
+
+	// missing output of an instance
+	_, err = New([]byte(matrixYml),
+		WithAliasesToCheckRefOp(nil, "report"),
+		WithRefOpOutputs(Outputs{"build-1.22-linux": {"result": "a"}}),
+	)
+	assert.Error(t, err)
+}
+
+func TestMatrixEnvKey(t *testing.T) {
+	assert.Equal(t, "MATRIX_GO_VERSION", MatrixEnvKey("go-version"))
+	assert.Equal(t, "MATRIX_OS", MatrixEnvKey("os"))
+}
@@ -16,6 +16,7 @@ package pipelineyml
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -66,6 +67,7 @@ func (r *HandleResult) AppendWarn(warn string) {
 type RefOpVisitor struct {
 	aliasToCheck              map[ActionAlias]struct{}
 	allActions                map[ActionAlias]*indexedAction
+	matrixGroups              map[ActionAlias][]ActionAlias
 	currentAction             *indexedAction
 	globalSnippetConfigLabels map[string]string
 
@@ -100,6 +102,7 @@ func NewRefOpVisitor(aliases []ActionAlias, availableRefs Refs, availableOutputs
 
 func (v *RefOpVisitor) Visit(s *Spec) {
 	v.allActions = s.allActions
+	v.matrixGroups = s.matrixGroups
 	for _, action := range s.allActions {
 		if _, ok := v.aliasToCheck[action.Alias]; !ok {
 			continue
@@ -338,6 +341,11 @@ func (v *RefOpVisitor) handleOneRefShellFormat(refOp RefOp) (replaced string) {
 func (v *RefOpVisitor) handleOneRefOp(refOp RefOp) (replaced string) {
 	replaced = refOp.Ori
 
+	// matrix action, reference outputs of all instances
+	if instances, ok := v.matrixGroups[ActionAlias(refOp.Ref)]; ok && !refOp.IsAlias && refOp.Op == RefOpOutput {
+		return v.handleOneRefOpMatrixOutput(refOp, instances)
+	}
+
 	// check alias
 	if !refOp.IsAlias {
 		v.result.AppendError(fmt.Errorf("%q, not found alias %q in pipeline", refOp.Ori, refOp.Ref))
@@ -382,6 +390,33 @@ func (v *RefOpVisitor) handleOneRefOpOutput(refOp RefOp) (replaced string) {
 	return
 }
 
+// handleOneRefOpMatrixOutput handle ${{ outputs.matrixAlias.key }},
+// the result is a json object of all instances' output, like: {"build-linux":"xxx","build-darwin":"yyy"}
+func (v *RefOpVisitor) handleOneRefOpMatrixOutput(refOp RefOp, instances []ActionAlias) (replaced string) {
+	replaced = refOp.Ori
+
+	aggregated := make(map[string]string, len(instances))
+	for _, instance := range instances {
+		instanceRefOp := refOp
+		instanceRefOp.Ref = instance.String()
+		instanceRefOp.Ex = ""
+		instanceRefOp.RefStageIndex, instanceRefOp.IsAlias, instanceRefOp.IsNamespace = v.getStageIndex(instance.String())
+		output, ok := v.availableOutputs[instance][refOp.Key]
+		if !ok {
+			// report not found error or warn
+			v.handleOneRefOpOutput(instanceRefOp)
+			return
+		}
+		aggregated[instance.String()] = output
+	}
+	b, err := json.Marshal(aggregated)
+	if err != nil {
+		v.result.AppendError(fmt.Errorf("%q, failed to aggregate outputs of matrix action %q, err: %v", refOp.Ori, refOp.Ref, err))
+		return
+	}
+	return v.handleRefEx(string(b), refOp)
+}
+
 func (v *RefOpVisitor) getStageIndex(namespace string) (stageIndex int, isAlias bool, isNamespace bool) {
 	stageIndex, isAlias, isNamespace = -1, false, false
 	for _, action := range v.allActions {
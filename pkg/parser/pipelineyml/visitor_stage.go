@@ -46,6 +46,7 @@ func (v *StageVisitor) Visit(s *Spec) {
 	}
 	// init or clean original
 	s.allActions = make(map[ActionAlias]*indexedAction)
+	s.matrixGroups = make(map[ActionAlias][]ActionAlias)
 
 	// availableNamespaces 表示遍历到不同 stages 时当前所有前置 stage 的 namespace
 	availableNamespaces := make(map[string]struct{})
@@ -110,6 +111,11 @@ func (v *StageVisitor) Visit(s *Spec) {
 					action.Needs = toList(availableActions)
 				}
 
+				// matrix groups
+				if action.MatrixInstance != nil {
+					s.matrixGroups[action.MatrixInstance.Group] = append(s.matrixGroups[action.MatrixInstance.Group], action.Alias)
+				}
+
 				// needNamespaces
 				if len(action.NeedNamespaces) == 0 {
 					action.NeedNamespaces = toListStr(availableNamespaces)
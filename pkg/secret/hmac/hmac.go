@@ -18,6 +18,7 @@ package hmac
 import (
 	"crypto/hmac"
 	"crypto/sha1" // nolint
+	"crypto/sha256"
 	"encoding/hex"
 	"net/http"
 	"sort"
@@ -33,12 +34,15 @@ const (
 	ErdaSignTimestamp = ErdaHeaderPrefix + "Sign-Timestamp"
 	ErdaAccessKeyID   = ErdaHeaderPrefix + "Ak"
 	ErdaSignature     = ErdaHeaderPrefix + "Signature"
+	// ErdaContentSha256 carries the hex sha256 of the body, it is signed as one of the erda headers
+	ErdaContentSha256 = ErdaHeaderPrefix + "Content-Sha256"
 )
 
 type Signer struct {
 	timestampEnable   bool
 	nowTimestamp      string
 	authInQueryString bool
+	contentSha256     string
 	keyPair           secret.AkSkPair
 }
 
@@ -54,6 +58,9 @@ func New(keyPair secret.AkSkPair, opts ...Option) *Signer {
 
 // Sign HTTP Request
 func (s *Signer) SignCanonicalRequest(r *http.Request) {
+	if s.contentSha256 != "" {
+		r.Header.Set(ErdaContentSha256, s.contentSha256)
+	}
 	authString := s.getAuthString(s.Signature(s.GetSignString(r)))
 	if s.authInQueryString {
 		appendAuthString(r, authString)
@@ -127,6 +134,11 @@ func canonicalResource(r *http.Request) string {
 	return r.URL.Path
 }
 
+func contentSha256(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
 func timestampSecond(t time.Time) int {
 	return int(t.UnixNano() / 1000000000)
 }
@@ -33,3 +33,10 @@ func WithQueryStringMode() Option {
 		s.authInQueryString = false
 	}
 }
+
+// WithBody signs the digest of body by the X-Erda-Content-Sha256 header.
+func WithBody(body []byte) Option {
+	return func(s *Signer) {
+		s.contentSha256 = contentSha256(body)
+	}
+}
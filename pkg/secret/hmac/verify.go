@@ -0,0 +1,94 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hmac
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSkew is used by Verify when maxSkew is not positive.
+const DefaultMaxSkew = 5 * time.Minute
+
+// Verify checks the signature carried by r, either in the Authorization header or in the query string,
+// against the signer's key pair. The signature must contain a timestamp not older or newer than maxSkew,
+// and sign the sha256 of body by the X-Erda-Content-Sha256 header, so that neither a replayed nor a
+// tampered body is accepted.
+func (s *Signer) Verify(r *http.Request, body []byte, maxSkew time.Duration) error {
+	auth := parseAuthString(r)
+	if len(auth) == 0 {
+		return fmt.Errorf("missing signature")
+	}
+	if algo := auth[ErdaSignAlgorithm]; algo != "" && algo != "hmac-sha1" {
+		return fmt.Errorf("unsupported sign algorithm: %s", algo)
+	}
+	if ak := auth[ErdaAccessKeyID]; ak != s.keyPair.AccessKeyID {
+		return fmt.Errorf("invalid access key id: %s", ak)
+	}
+	ts := auth[ErdaSignTimestamp]
+	if ts == "" {
+		return fmt.Errorf("missing sign timestamp")
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid sign timestamp: %s", ts)
+	}
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	if skew := time.Since(time.Unix(sec, 0)); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("sign timestamp expired: %s", ts)
+	}
+	digest := r.Header.Get(ErdaContentSha256)
+	if digest == "" {
+		return fmt.Errorf("missing header %s", ErdaContentSha256)
+	}
+	if !hmac.Equal([]byte(strings.ToLower(digest)), []byte(contentSha256(body))) {
+		return fmt.Errorf("content sha256 mismatch")
+	}
+	verifier := &Signer{keyPair: s.keyPair, timestampEnable: true, nowTimestamp: ts}
+	expected := verifier.Signature(verifier.GetSignString(r))
+	if !hmac.Equal([]byte(expected), []byte(auth[ErdaSignature])) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// parseAuthString extracts the erda auth pairs from the Authorization header, falling back to the query string.
+func parseAuthString(r *http.Request) map[string]string {
+	res := make(map[string]string)
+	if authString := r.Header.Get("Authorization"); strings.Contains(authString, ErdaSignature) {
+		for _, pair := range strings.Split(authString, "&") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) == 2 {
+				res[kv[0]] = kv[1]
+			}
+		}
+		return res
+	}
+	for k, vals := range r.URL.Query() {
+		if strings.HasPrefix(k, ErdaHeaderPrefix) && len(vals) > 0 {
+			res[http.CanonicalHeaderKey(k)] = vals[0]
+		}
+	}
+	if _, ok := res[ErdaSignature]; !ok {
+		return nil
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hmac
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/pkg/secret"
+)
+
+func TestSigner_Verify(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/master"}`)
+
+	// header mode
+	r := mockRequest()
+	New(mockKeyPair, WithTimestamp(time.Now()), WithBody(body)).SignCanonicalRequest(r)
+	assert.NoError(t, New(mockKeyPair).Verify(r, body, time.Minute))
+	assert.NoError(t, New(mockKeyPair).Verify(r, body, 0))
+
+	// tampered body
+	assert.Error(t, New(mockKeyPair).Verify(r, []byte(`{}`), time.Minute))
+
+	// tampered digest header
+	r.Header.Set(ErdaContentSha256, contentSha256([]byte(`{}`)))
+	assert.Error(t, New(mockKeyPair).Verify(r, []byte(`{}`), time.Minute))
+
+	// query string mode
+	r = mockRequest()
+	signer := New(mockKeyPair, WithTimestamp(time.Now()), WithBody(body))
+	signer.authInQueryString = true
+	signer.SignCanonicalRequest(r)
+	assert.NoError(t, New(mockKeyPair).Verify(r, body, time.Minute))
+
+	// expired timestamp
+	r = mockRequest()
+	New(mockKeyPair, WithTimestamp(time.Now().Add(-time.Hour)), WithBody(body)).SignCanonicalRequest(r)
+	assert.Error(t, New(mockKeyPair).Verify(r, body, time.Minute))
+	assert.Error(t, New(mockKeyPair).Verify(r, body, 0))
+
+	// missing timestamp
+	r = mockRequest()
+	New(mockKeyPair, WithBody(body)).SignCanonicalRequest(r)
+	assert.Error(t, New(mockKeyPair).Verify(r, body, time.Minute))
+
+	// missing body digest
+	r = mockRequest()
+	New(mockKeyPair, WithTimestamp(time.Now())).SignCanonicalRequest(r)
+	assert.Error(t, New(mockKeyPair).Verify(r, body, time.Minute))
+
+	// tampered request
+	r = mockRequest()
+	New(mockKeyPair, WithTimestamp(time.Now()), WithBody(body)).SignCanonicalRequest(r)
+	r.URL.RawQuery = "page=2&pageNum=10"
+	assert.Error(t, New(mockKeyPair).Verify(r, body, time.Minute))
+
+	// wrong secret
+	r = mockRequest()
+	New(mockKeyPair, WithTimestamp(time.Now()), WithBody(body)).SignCanonicalRequest(r)
+	assert.Error(t, New(secret.AkSkPair{AccessKeyID: mockKeyPair.AccessKeyID, SecretKey: "other"}).Verify(r, body, time.Minute))
+
+	// missing signature
+	assert.Error(t, New(mockKeyPair).Verify(mockRequest(), body, time.Minute))
+}